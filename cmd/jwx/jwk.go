@@ -53,6 +53,7 @@ func makeJwkCmd() *cli.Command {
 	cmd.Subcommands = []*cli.Command{
 		makeJwkGenerateCmd(),
 		makeJwkFormatCmd(),
+		makeJwkDescribeCmd(),
 	}
 	return &cmd
 }
@@ -288,3 +289,72 @@ func makeJwkFormatCmd() *cli.Command {
 	}
 	return &cmd
 }
+
+func makeJwkDescribeCmd() *cli.Command {
+	var cmd cli.Command
+	cmd.Name = "describe"
+	cmd.Usage = "Describe the keys in a JWK or JWK set"
+	cmd.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:    "input-format",
+			Aliases: []string{"I"},
+			Value:   "json",
+			Usage:   "Input format `INPUT` (json/pem)",
+		},
+		outputFlag(),
+	}
+
+	// jwx jwk describe <file>
+	cmd.Action = func(c *cli.Context) error {
+		if c.Args().Get(0) == "" {
+			cli.ShowCommandHelpAndExit(c, "describe", 1)
+		}
+
+		src, err := getSource(c.Args().Get(0))
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		buf, err := ioutil.ReadAll(src)
+		if err != nil {
+			return errors.Wrap(err, `failed to read data from source`)
+		}
+
+		var options []jwk.ParseOption
+		switch format := c.String("input-format"); format {
+		case "json":
+		case "pem":
+			options = append(options, jwk.WithPEM(true))
+		default:
+			return errors.Errorf(`invalid input format %s`, format)
+		}
+
+		keyset, err := jwk.Parse(buf, options...)
+		if err != nil {
+			return errors.Wrap(err, `failed to parse keyset`)
+		}
+
+		descs := make([]*jwk.Description, keyset.Len())
+		for i := 0; i < keyset.Len(); i++ {
+			key, _ := keyset.Get(i)
+			desc, err := jwk.Describe(key)
+			if err != nil {
+				return errors.Wrapf(err, `failed to describe key #%d`, i)
+			}
+			descs[i] = desc
+		}
+
+		output, err := getOutput(c.String("output"))
+		if err != nil {
+			return err
+		}
+		defer output.Close()
+
+		if len(descs) == 1 {
+			return dumpJSON(output, descs[0])
+		}
+		return dumpJSON(output, descs)
+	}
+	return &cmd
+}
@@ -0,0 +1,13 @@
+module github.com/lestrrat-go/jwx/compat
+
+go 1.16
+
+require (
+	github.com/golang-jwt/jwt/v4 v4.5.2
+	github.com/lestrrat-go/jwx v0.0.0-00010101000000-000000000000
+	github.com/pkg/errors v0.9.1
+	github.com/stretchr/testify v1.6.1
+	gopkg.in/square/go-jose.v2 v2.5.1
+)
+
+replace github.com/lestrrat-go/jwx => ../
@@ -0,0 +1,122 @@
+// Package gojose provides adapters between this library and
+// gopkg.in/square/go-jose.v2, so that a code base can be migrated one
+// call site at a time.
+//
+// Keys are converted through their JSON representation, so all the
+// parameters that both libraries understand (e.g. "kid", "alg", "use"
+// and "x5c") are preserved.
+package gojose
+
+import (
+	"encoding/json"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/pkg/errors"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// JSONWebKey converts a `jwk.Key` to a `jose.JSONWebKey`
+func JSONWebKey(key jwk.Key) (*jose.JSONWebKey, error) {
+	if key == nil {
+		return nil, errors.New(`key must not be nil`)
+	}
+
+	buf, err := json.Marshal(key)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal key`)
+	}
+
+	var ret jose.JSONWebKey
+	if err := ret.UnmarshalJSON(buf); err != nil {
+		return nil, errors.Wrap(err, `failed to convert key`)
+	}
+	return &ret, nil
+}
+
+// JSONWebKeySet converts a `jwk.Set` to a `jose.JSONWebKeySet`
+func JSONWebKeySet(set jwk.Set) (*jose.JSONWebKeySet, error) {
+	if set == nil {
+		return nil, errors.New(`set must not be nil`)
+	}
+
+	ret := jose.JSONWebKeySet{
+		Keys: make([]jose.JSONWebKey, 0, set.Len()),
+	}
+	for i := 0; i < set.Len(); i++ {
+		key, _ := set.Get(i)
+		v, err := JSONWebKey(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to convert key #%d`, i)
+		}
+		ret.Keys = append(ret.Keys, *v)
+	}
+	return &ret, nil
+}
+
+// FromJSONWebKey converts a `jose.JSONWebKey` to a `jwk.Key`
+func FromJSONWebKey(v *jose.JSONWebKey) (jwk.Key, error) {
+	if v == nil {
+		return nil, errors.New(`key must not be nil`)
+	}
+
+	buf, err := v.MarshalJSON()
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal key`)
+	}
+
+	key, err := jwk.ParseKey(buf)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to convert key`)
+	}
+	return key, nil
+}
+
+// FromJSONWebKeySet converts a `jose.JSONWebKeySet` to a `jwk.Set`
+func FromJSONWebKeySet(v *jose.JSONWebKeySet) (jwk.Set, error) {
+	if v == nil {
+		return nil, errors.New(`set must not be nil`)
+	}
+
+	set := jwk.NewSet()
+	for i := range v.Keys {
+		key, err := FromJSONWebKey(&v.Keys[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to convert key #%d`, i)
+		}
+		set.Add(key)
+	}
+	return set, nil
+}
+
+type verifier struct {
+	key jwk.Key
+}
+
+// Verifier returns a `jose.OpaqueVerifier` that verifies signatures
+// using the jws package. Pass it to `(*jose.JSONWebSignature).Verify()`
+// (or any other method that accepts a verification key) in place of
+// the key itself.
+//
+// If the key specifies an algorithm ("alg"), signatures created using
+// any other algorithm are rejected.
+func Verifier(key jwk.Key) jose.OpaqueVerifier {
+	return &verifier{key: key}
+}
+
+func (v *verifier) VerifyPayload(payload []byte, signature []byte, alg jose.SignatureAlgorithm) error {
+	if v.key == nil {
+		return errors.New(`key must not be nil`)
+	}
+
+	if keyalg := v.key.Algorithm(); keyalg != "" && keyalg != string(alg) {
+		return errors.Errorf(`key with algorithm %q can not be used to verify %s signatures`, keyalg, alg)
+	}
+
+	verifier, err := jws.NewVerifier(jwa.SignatureAlgorithm(alg))
+	if err != nil {
+		return errors.Wrapf(err, `failed to create verifier for %s`, alg)
+	}
+	return verifier.Verify(payload, signature, v.key)
+}
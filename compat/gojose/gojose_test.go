@@ -0,0 +1,114 @@
+package gojose_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/compat/gojose"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/stretchr/testify/assert"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+func TestJSONWebKey(t *testing.T) {
+	t.Parallel()
+
+	privkey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.NoError(t, err, `rsa.GenerateKey should succeed`) {
+		return
+	}
+
+	key, err := jwk.New(privkey)
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+	_ = key.Set(jwk.KeyIDKey, "mykey")
+	_ = key.Set(jwk.AlgorithmKey, jwa.RS256)
+	_ = key.Set(jwk.KeyUsageKey, "sig")
+
+	set := jwk.NewSet()
+	set.Add(key)
+
+	joseSet, err := gojose.JSONWebKeySet(set)
+	if !assert.NoError(t, err, `gojose.JSONWebKeySet should succeed`) {
+		return
+	}
+	if !assert.Len(t, joseSet.Keys, 1, `there should be 1 key`) {
+		return
+	}
+
+	joseKey := joseSet.Keys[0]
+	if !assert.Equal(t, "mykey", joseKey.KeyID, `kid should match`) {
+		return
+	}
+	if !assert.Equal(t, "RS256", joseKey.Algorithm, `alg should match`) {
+		return
+	}
+	if !assert.Equal(t, "sig", joseKey.Use, `use should match`) {
+		return
+	}
+	if !assert.Equal(t, privkey.N, joseKey.Key.(*rsa.PrivateKey).N, `key should match`) {
+		return
+	}
+
+	roundtrip, err := gojose.FromJSONWebKeySet(joseSet)
+	if !assert.NoError(t, err, `gojose.FromJSONWebKeySet should succeed`) {
+		return
+	}
+	if !assert.Equal(t, 1, roundtrip.Len(), `there should be 1 key`) {
+		return
+	}
+
+	key2, _ := roundtrip.Get(0)
+	if !assert.Equal(t, key.KeyID(), key2.KeyID(), `kid should match`) {
+		return
+	}
+	var raw rsa.PrivateKey
+	if !assert.NoError(t, key2.Raw(&raw), `key2.Raw should succeed`) {
+		return
+	}
+	if !assert.Equal(t, privkey.D, raw.D, `key should match`) {
+		return
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	t.Parallel()
+
+	privkey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.NoError(t, err, `rsa.GenerateKey should succeed`) {
+		return
+	}
+
+	const payload = "Lorem ipsum"
+	signed, err := jws.Sign([]byte(payload), jwa.RS256, privkey)
+	if !assert.NoError(t, err, `jws.Sign should succeed`) {
+		return
+	}
+
+	object, err := jose.ParseSigned(string(signed))
+	if !assert.NoError(t, err, `jose.ParseSigned should succeed`) {
+		return
+	}
+
+	pubkey, err := jwk.New(&privkey.PublicKey)
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+
+	verified, err := object.Verify(gojose.Verifier(pubkey))
+	if !assert.NoError(t, err, `object.Verify should succeed`) {
+		return
+	}
+	if !assert.Equal(t, payload, string(verified), `payload should match`) {
+		return
+	}
+
+	_ = pubkey.Set(jwk.AlgorithmKey, jwa.PS256)
+	if _, err := object.Verify(gojose.Verifier(pubkey)); !assert.Error(t, err, `object.Verify should fail with mismatching algorithm`) {
+		return
+	}
+}
@@ -0,0 +1,146 @@
+// Package golangjwt provides adapters that allow code written against
+// github.com/golang-jwt/jwt/v4 to use this library for key lookup and
+// signature verification, so that a code base can be migrated one call
+// site at a time.
+package golangjwt
+
+import (
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/pkg/errors"
+)
+
+var algorithms = []jwa.SignatureAlgorithm{
+	jwa.ES256, jwa.ES384, jwa.ES512,
+	jwa.EdDSA,
+	jwa.HS256, jwa.HS384, jwa.HS512,
+	jwa.PS256, jwa.PS384, jwa.PS512,
+	jwa.RS256, jwa.RS384, jwa.RS512,
+}
+
+// Keyfunc returns a `jwt.Keyfunc` that looks up the verification key of
+// a token in `set`.
+//
+// The key is looked up using the "kid" header of the token. If the
+// token does not have a "kid" header, the set must contain exactly one
+// key. If the key specifies an algorithm ("alg"), it must match the
+// algorithm of the token, so that a key can not be used with an
+// algorithm that it was not meant for.
+//
+// The key is returned as a raw key (e.g. *rsa.PublicKey), so it can
+// be used with both golang-jwt's own signing methods, and the ones
+// registered by `RegisterSigningMethods()`.
+func Keyfunc(set jwk.Set) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		key, err := lookupKey(set, token)
+		if err != nil {
+			return nil, err
+		}
+
+		var raw interface{}
+		if err := key.Raw(&raw); err != nil {
+			return nil, errors.Wrap(err, `failed to get raw key`)
+		}
+		return raw, nil
+	}
+}
+
+func lookupKey(set jwk.Set, token *jwt.Token) (jwk.Key, error) {
+	if set == nil {
+		return nil, errors.New(`no key set specified`)
+	}
+
+	var key jwk.Key
+	if kid, ok := token.Header[jwk.KeyIDKey].(string); ok && kid != "" {
+		v, ok := set.LookupKeyID(kid)
+		if !ok {
+			return nil, errors.Errorf(`key with ID %q not found`, kid)
+		}
+		key = v
+	} else {
+		if set.Len() != 1 {
+			return nil, errors.Errorf(`token has no key ID, and the set contains %d keys`, set.Len())
+		}
+		key, _ = set.Get(0)
+	}
+
+	if alg := key.Algorithm(); alg != "" {
+		if token.Method == nil || token.Method.Alg() != alg {
+			return nil, errors.Errorf(`key with algorithm %q can not be used to verify the token`, alg)
+		}
+	}
+	return key, nil
+}
+
+// SigningMethod is a `jwt.SigningMethod` that creates and verifies
+// signatures using the jws package. It accepts the same keys as
+// `jws.Sign()` and `jws.Verify()`, i.e. both raw keys and `jwk.Key`.
+type SigningMethod struct {
+	alg jwa.SignatureAlgorithm
+}
+
+// NewSigningMethod creates a new SigningMethod for the given algorithm
+func NewSigningMethod(alg jwa.SignatureAlgorithm) (*SigningMethod, error) {
+	if _, err := jws.NewVerifier(alg); err != nil {
+		return nil, errors.Wrapf(err, `unsupported algorithm %s`, alg)
+	}
+	return &SigningMethod{alg: alg}, nil
+}
+
+// RegisterSigningMethods replaces the signing methods of golang-jwt with
+// `SigningMethod` for all the algorithms that are supported by both
+// libraries. After calling it, `jwt.Parse()` and `(*jwt.Token).SignedString()`
+// use this library to verify and create signatures.
+//
+// This affects all users of golang-jwt in the process, so it should
+// be called once, from `main()` or `init()`.
+func RegisterSigningMethods() {
+	for _, alg := range algorithms {
+		m, err := NewSigningMethod(alg)
+		if err != nil {
+			continue
+		}
+		jwt.RegisterSigningMethod(m.Alg(), func() jwt.SigningMethod {
+			return m
+		})
+	}
+}
+
+// Alg returns the name of the algorithm
+func (m *SigningMethod) Alg() string {
+	return m.alg.String()
+}
+
+// Verify verifies the base64url encoded `signature` of `signingString`
+func (m *SigningMethod) Verify(signingString, signature string, key interface{}) error {
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return errors.Wrap(err, `failed to decode signature`)
+	}
+
+	verifier, err := jws.NewVerifier(m.alg)
+	if err != nil {
+		return errors.Wrapf(err, `failed to create verifier for %s`, m.alg)
+	}
+
+	if err := verifier.Verify([]byte(signingString), sig, key); err != nil {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
+
+// Sign signs `signingString`, and returns the base64url encoded signature
+func (m *SigningMethod) Sign(signingString string, key interface{}) (string, error) {
+	signer, err := jws.NewSigner(m.alg)
+	if err != nil {
+		return "", errors.Wrapf(err, `failed to create signer for %s`, m.alg)
+	}
+
+	sig, err := signer.Sign([]byte(signingString), key)
+	if err != nil {
+		return "", errors.Wrap(err, `failed to sign`)
+	}
+	return jwt.EncodeSegment(sig), nil
+}
@@ -0,0 +1,125 @@
+package golangjwt_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/lestrrat-go/jwx/compat/golangjwt"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyfunc(t *testing.T) {
+	t.Parallel()
+
+	privkey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.NoError(t, err, `rsa.GenerateKey should succeed`) {
+		return
+	}
+
+	pubkey, err := jwk.New(&privkey.PublicKey)
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+	_ = pubkey.Set(jwk.KeyIDKey, "mykey")
+	_ = pubkey.Set(jwk.AlgorithmKey, jwa.RS256)
+
+	set := jwk.NewSet()
+	set.Add(pubkey)
+
+	sign := func(t *testing.T, method jwt.SigningMethod, kid string) string {
+		t.Helper()
+		token := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "jwx"})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(privkey)
+		if !assert.NoError(t, err, `SignedString should succeed`) {
+			t.FailNow()
+		}
+		return signed
+	}
+
+	t.Run("Key ID", func(t *testing.T) {
+		t.Parallel()
+		token, err := jwt.Parse(sign(t, jwt.SigningMethodRS256, "mykey"), golangjwt.Keyfunc(set))
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+		if !assert.True(t, token.Valid, `token should be valid`) {
+			return
+		}
+	})
+	t.Run("Single key without key ID", func(t *testing.T) {
+		t.Parallel()
+		_, err := jwt.Parse(sign(t, jwt.SigningMethodRS256, ""), golangjwt.Keyfunc(set))
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+	})
+	t.Run("Unknown key ID", func(t *testing.T) {
+		t.Parallel()
+		_, err := jwt.Parse(sign(t, jwt.SigningMethodRS256, "unknown"), golangjwt.Keyfunc(set))
+		if !assert.Error(t, err, `jwt.Parse should fail`) {
+			return
+		}
+	})
+	t.Run("Algorithm mismatch", func(t *testing.T) {
+		t.Parallel()
+		_, err := jwt.Parse(sign(t, jwt.SigningMethodPS256, "mykey"), golangjwt.Keyfunc(set))
+		if !assert.Error(t, err, `jwt.Parse should fail`) {
+			return
+		}
+	})
+}
+
+func TestSigningMethod(t *testing.T) {
+	t.Parallel()
+
+	privkey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.NoError(t, err, `rsa.GenerateKey should succeed`) {
+		return
+	}
+
+	method, err := golangjwt.NewSigningMethod(jwa.RS256)
+	if !assert.NoError(t, err, `golangjwt.NewSigningMethod should succeed`) {
+		return
+	}
+	if !assert.Equal(t, "RS256", method.Alg(), `method.Alg() should match`) {
+		return
+	}
+
+	// Sign using golang-jwt, backed by jws
+	signed, err := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "jwx"}).SignedString(privkey)
+	if !assert.NoError(t, err, `SignedString should succeed`) {
+		return
+	}
+
+	// ...which is verifiable by jws
+	if _, err := jws.Verify([]byte(signed), jwa.RS256, &privkey.PublicKey); !assert.NoError(t, err, `jws.Verify should succeed`) {
+		return
+	}
+
+	// ...and by golang-jwt, using a jwk.Key. This requires golang-jwt
+	// to use SigningMethod in place of its own RS256 implementation
+	golangjwt.RegisterSigningMethods()
+	pubkey, err := jwk.New(&privkey.PublicKey)
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+	_, err = jwt.Parse(signed, func(token *jwt.Token) (interface{}, error) {
+		return pubkey, nil
+	}, jwt.WithValidMethods([]string{"RS256"}))
+	if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+		return
+	}
+
+	// Tampered signatures are rejected
+	if !assert.Error(t, method.Verify("foo.bar", "AAAA", pubkey), `method.Verify should fail`) {
+		return
+	}
+}
@@ -0,0 +1,70 @@
+// Package base58 implements the base58btc encoding, using the Bitcoin
+// alphabet. It is used to decode the multibase encoded keys in DIDs.
+package base58
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+const alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var decodeMap [256]int8
+
+func init() {
+	for i := range decodeMap {
+		decodeMap[i] = -1
+	}
+	for i := 0; i < len(alphabet); i++ {
+		decodeMap[alphabet[i]] = int8(i)
+	}
+}
+
+var radix = big.NewInt(58)
+
+// Encode encodes `src` to base58btc
+func Encode(src []byte) string {
+	var zeros int
+	for zeros < len(src) && src[zeros] == 0 {
+		zeros++
+	}
+
+	n := new(big.Int).SetBytes(src)
+	mod := new(big.Int)
+	var dst []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		dst = append(dst, alphabet[mod.Int64()])
+	}
+	for i := 0; i < zeros; i++ {
+		dst = append(dst, alphabet[0])
+	}
+	for i, j := 0, len(dst)-1; i < j; i, j = i+1, j-1 {
+		dst[i], dst[j] = dst[j], dst[i]
+	}
+	return string(dst)
+}
+
+// Decode decodes the base58btc encoded string `src`
+func Decode(src string) ([]byte, error) {
+	var zeros int
+	for zeros < len(src) && src[zeros] == alphabet[0] {
+		zeros++
+	}
+
+	n := new(big.Int)
+	for i := zeros; i < len(src); i++ {
+		v := decodeMap[src[i]]
+		if v < 0 {
+			return nil, errors.Errorf(`invalid base58 character at offset %d`, i)
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(v)))
+	}
+
+	buf := n.Bytes()
+	dst := make([]byte, zeros+len(buf))
+	copy(dst[zeros:], buf)
+	return dst, nil
+}
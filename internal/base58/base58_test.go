@@ -0,0 +1,43 @@
+package base58_test
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/jwx/internal/base58"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBase58(t *testing.T) {
+	t.Parallel()
+	testcases := []struct {
+		Decoded []byte
+		Encoded string
+	}{
+		{Decoded: []byte{}, Encoded: ""},
+		{Decoded: []byte("Hello World!"), Encoded: "2NEpo7TZRRrLZSi2U"},
+		{Decoded: []byte{0, 0, 0x28, 0x7f, 0xb4, 0xcd}, Encoded: "11233QC4"},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Encoded, func(t *testing.T) {
+			t.Parallel()
+			if !assert.Equal(t, tc.Encoded, base58.Encode(tc.Decoded), `base58.Encode should match`) {
+				return
+			}
+			decoded, err := base58.Decode(tc.Encoded)
+			if !assert.NoError(t, err, `base58.Decode should succeed`) {
+				return
+			}
+			if !assert.Equal(t, tc.Decoded, decoded, `base58.Decode should match`) {
+				return
+			}
+		})
+	}
+	t.Run("Invalid character", func(t *testing.T) {
+		t.Parallel()
+		_, err := base58.Decode("0OIl")
+		if !assert.Error(t, err, `base58.Decode should fail`) {
+			return
+		}
+	})
+}
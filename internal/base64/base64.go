@@ -37,22 +37,35 @@ func EncodeUint64ToString(v uint64) string {
 	return EncodeToString(data[i:])
 }
 
-func Decode(src []byte) ([]byte, error) {
-	var enc *base64.Encoding
+// EncodeBuffer appends the base64url encoded form of src to dst,
+// without allocating an intermediate string
+func EncodeBuffer(dst *bytes.Buffer, src []byte) {
+	enc := base64.RawURLEncoding
+	n := enc.EncodedLen(len(src))
+	dst.Grow(n)
+	l := dst.Len()
+	b := dst.Bytes()[:l+n]
+	enc.Encode(b[l:], src)
+	dst.Write(b[l:])
+}
 
+func guessEncoding(src []byte) *base64.Encoding {
 	var isRaw = !bytes.HasSuffix(src, []byte{'='})
 	var isURL = !bytes.ContainsAny(src, "+/")
 	switch {
 	case isRaw && isURL:
-		enc = base64.RawURLEncoding
+		return base64.RawURLEncoding
 	case isURL:
-		enc = base64.URLEncoding
+		return base64.URLEncoding
 	case isRaw:
-		enc = base64.RawStdEncoding
+		return base64.RawStdEncoding
 	default:
-		enc = base64.StdEncoding
+		return base64.StdEncoding
 	}
+}
 
+func Decode(src []byte) ([]byte, error) {
+	enc := guessEncoding(src)
 	dst := make([]byte, enc.DecodedLen(len(src)))
 	n, err := enc.Decode(dst, src)
 	if err != nil {
@@ -64,3 +77,39 @@ func Decode(src []byte) ([]byte, error) {
 func DecodeString(src string) ([]byte, error) {
 	return Decode([]byte(src))
 }
+
+// DecodeBuffer works like Decode, but uses the storage of dst instead of
+// allocating a new slice. dst is reset before decoding. The returned slice
+// aliases the contents of dst, and is only valid until dst is modified
+func DecodeBuffer(dst *bytes.Buffer, src []byte) ([]byte, error) {
+	enc := guessEncoding(src)
+	dst.Reset()
+	dst.Grow(enc.DecodedLen(len(src)))
+	b := dst.Bytes()[:enc.DecodedLen(len(src))]
+	n, err := enc.Decode(b, src)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to decode source`)
+	}
+	return b[:n], nil
+}
+
+// DecodeStd decodes data encoded using the standard base64 alphabet
+// as described in RFC4648 section 4. Padding is optional, but unlike
+// Decode, the URL safe alphabet is not accepted.
+func DecodeStd(src []byte) ([]byte, error) {
+	enc := base64.StdEncoding
+	if !bytes.HasSuffix(src, []byte{'='}) {
+		enc = base64.RawStdEncoding
+	}
+
+	dst := make([]byte, enc.DecodedLen(len(src)))
+	n, err := enc.Decode(dst, src)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to decode source`)
+	}
+	return dst[:n], nil
+}
+
+func DecodeStdString(src string) ([]byte, error) {
+	return DecodeStd([]byte(src))
+}
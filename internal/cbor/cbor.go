@@ -0,0 +1,286 @@
+// Package cbor implements the small subset of CBOR (RFC 8949) that is
+// required to handle COSE_Key structures: integers, byte strings, text
+// strings, arrays, maps, and the simple values true, false, and null.
+// Only definite length items are supported.
+package cbor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	majorUint   = 0
+	majorNegInt = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorSimple = 7
+)
+
+const (
+	simpleFalse = 20
+	simpleTrue  = 21
+	simpleNull  = 22
+)
+
+// maximum nesting level accepted by Unmarshal
+const maxDepth = 16
+
+// Marshal encodes the given value. Supported types are int, int64,
+// []byte, string, bool, nil, []interface{}, and map[interface{}]interface{}.
+// Map keys are sorted as described in RFC 8949 section 4.2.1, so the
+// output is deterministic.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encode(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeHead(buf *bytes.Buffer, major byte, n uint64) {
+	major <<= 5
+	switch {
+	case n < 24:
+		buf.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(major | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		var b [2]byte
+		binary.BigEndian.PutUint16(b[:], uint16(n))
+		buf.WriteByte(major | 25)
+		buf.Write(b[:])
+	case n <= math.MaxUint32:
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], uint32(n))
+		buf.WriteByte(major | 26)
+		buf.Write(b[:])
+	default:
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], n)
+		buf.WriteByte(major | 27)
+		buf.Write(b[:])
+	}
+}
+
+func encode(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(majorSimple<<5 | simpleNull)
+	case bool:
+		if v {
+			buf.WriteByte(majorSimple<<5 | simpleTrue)
+		} else {
+			buf.WriteByte(majorSimple<<5 | simpleFalse)
+		}
+	case int:
+		return encode(buf, int64(v))
+	case int64:
+		if v >= 0 {
+			writeHead(buf, majorUint, uint64(v))
+		} else {
+			writeHead(buf, majorNegInt, uint64(-(v + 1)))
+		}
+	case []byte:
+		writeHead(buf, majorBytes, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		writeHead(buf, majorText, uint64(len(v)))
+		buf.WriteString(v)
+	case []interface{}:
+		writeHead(buf, majorArray, uint64(len(v)))
+		for i, elem := range v {
+			if err := encode(buf, elem); err != nil {
+				return errors.Wrapf(err, `failed to encode array element #%d`, i)
+			}
+		}
+	case map[interface{}]interface{}:
+		type entry struct {
+			key   []byte
+			value interface{}
+		}
+		entries := make([]entry, 0, len(v))
+		for key, value := range v {
+			var kbuf bytes.Buffer
+			if err := encode(&kbuf, key); err != nil {
+				return errors.Wrap(err, `failed to encode map key`)
+			}
+			entries = append(entries, entry{key: kbuf.Bytes(), value: value})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return bytes.Compare(entries[i].key, entries[j].key) < 0
+		})
+
+		writeHead(buf, majorMap, uint64(len(entries)))
+		for _, e := range entries {
+			buf.Write(e.key)
+			if err := encode(buf, e.value); err != nil {
+				return errors.Wrap(err, `failed to encode map value`)
+			}
+		}
+	default:
+		return errors.Errorf(`unsupported type for cbor encoding: %T`, v)
+	}
+	return nil
+}
+
+// Unmarshal decodes a single CBOR data item. Integers are decoded as
+// int64, byte strings as []byte, text strings as string, arrays as
+// []interface{}, and maps as map[interface{}]interface{}.
+// Trailing data is not allowed.
+func Unmarshal(data []byte) (interface{}, error) {
+	d := decoder{data: data}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, errors.New(`trailing data after cbor item`)
+	}
+	return v, nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) readHead() (byte, uint64, error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, errors.New(`unexpected end of cbor data`)
+	}
+	b := d.data[d.pos]
+	d.pos++
+
+	major := b >> 5
+	info := b & 0x1f
+	if major == majorSimple {
+		return major, uint64(info), nil
+	}
+
+	var size int
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, errors.Errorf(`unsupported cbor additional information %d`, info)
+	}
+
+	if len(d.data)-d.pos < size {
+		return 0, 0, errors.New(`unexpected end of cbor data`)
+	}
+	var n uint64
+	for _, c := range d.data[d.pos : d.pos+size] {
+		n = n<<8 | uint64(c)
+	}
+	d.pos += size
+	return major, n, nil
+}
+
+func (d *decoder) readBytes(n uint64) ([]byte, error) {
+	if uint64(len(d.data)-d.pos) < n {
+		return nil, errors.New(`unexpected end of cbor data`)
+	}
+	b := make([]byte, n)
+	copy(b, d.data[d.pos:])
+	d.pos += int(n)
+	return b, nil
+}
+
+func (d *decoder) decode(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, errors.New(`cbor data is nested too deeply`)
+	}
+
+	major, n, err := d.readHead()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case majorUint:
+		if n > math.MaxInt64 {
+			return nil, errors.New(`cbor integer overflows int64`)
+		}
+		return int64(n), nil
+	case majorNegInt:
+		if n > math.MaxInt64 {
+			return nil, errors.New(`cbor integer overflows int64`)
+		}
+		return -1 - int64(n), nil
+	case majorBytes:
+		return d.readBytes(n)
+	case majorText:
+		b, err := d.readBytes(n)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case majorArray:
+		// each element takes at least one byte
+		if n > uint64(len(d.data)-d.pos) {
+			return nil, errors.New(`unexpected end of cbor data`)
+		}
+		arr := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case majorMap:
+		if n > uint64(len(d.data)-d.pos) {
+			return nil, errors.New(`unexpected end of cbor data`)
+		}
+		m := make(map[interface{}]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, errors.Errorf(`unsupported cbor map key type %T`, key)
+			}
+			if _, ok := m[key]; ok {
+				return nil, errors.Errorf(`duplicate cbor map key %v`, key)
+			}
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+		}
+		return m, nil
+	case majorSimple:
+		switch n {
+		case simpleFalse:
+			return false, nil
+		case simpleTrue:
+			return true, nil
+		case simpleNull:
+			return nil, nil
+		}
+		return nil, errors.Errorf(`unsupported cbor simple value %d`, n)
+	default:
+		return nil, errors.Errorf(`unsupported cbor major type %d`, major)
+	}
+}
@@ -0,0 +1,67 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCBOR(t *testing.T) {
+	// Examples from RFC 8949 Appendix A
+	testcases := []struct {
+		Value   interface{}
+		Encoded string
+	}{
+		{Value: int64(0), Encoded: "00"},
+		{Value: int64(23), Encoded: "17"},
+		{Value: int64(24), Encoded: "1818"},
+		{Value: int64(1000), Encoded: "1903e8"},
+		{Value: int64(1000000), Encoded: "1a000f4240"},
+		{Value: int64(-1), Encoded: "20"},
+		{Value: int64(-1000), Encoded: "3903e7"},
+		{Value: []byte{1, 2, 3, 4}, Encoded: "4401020304"},
+		{Value: "IETF", Encoded: "6449455446"},
+		{Value: false, Encoded: "f4"},
+		{Value: true, Encoded: "f5"},
+		{Value: nil, Encoded: "f6"},
+		{Value: []interface{}{int64(1), int64(2), int64(3)}, Encoded: "83010203"},
+		{Value: map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}, Encoded: "a201020304"},
+		{Value: map[interface{}]interface{}{int64(-1): int64(1), int64(1): int64(2)}, Encoded: "a201022001"},
+	}
+
+	for _, tc := range testcases {
+		buf, err := Marshal(tc.Value)
+		if !assert.NoError(t, err, `Marshal should succeed`) {
+			return
+		}
+		if !assert.Equal(t, tc.Encoded, hex.EncodeToString(buf), `encoded values should match`) {
+			return
+		}
+
+		v, err := Unmarshal(buf)
+		if !assert.NoError(t, err, `Unmarshal should succeed`) {
+			return
+		}
+		if !assert.Equal(t, tc.Value, v, `decoded values should match`) {
+			return
+		}
+	}
+
+	invalid := []string{
+		"",                   // empty
+		"0001",               // trailing data
+		"44010203",           // truncated byte string
+		"a2010201",           // truncated map
+		"a201020102",         // duplicate map key
+		"fb3ff199999999999a", // floats are not supported
+		"5f42010243030405ff", // indefinite length
+	}
+	for _, s := range invalid {
+		buf, _ := hex.DecodeString(s)
+		_, err := Unmarshal(buf)
+		if !assert.Error(t, err, `Unmarshal should fail for %s`, s) {
+			return
+		}
+	}
+}
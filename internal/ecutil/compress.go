@@ -0,0 +1,54 @@
+package ecutil
+
+import (
+	"crypto/elliptic"
+	"math/big"
+)
+
+// curveA returns the coefficient `a` of the curve equation. The curves
+// in crypto/elliptic all use a = -3
+func curveA(curve elliptic.Curve) *big.Int {
+	if c, ok := curve.(*weierstrassCurve); ok {
+		return c.a
+	}
+	return big.NewInt(-3)
+}
+
+// UnmarshalCompressed converts a point in the compressed form of SEC 1
+// section 2.3.3 (0x02 or 0x03, followed by the x coordinate) into its
+// coordinates. It works like elliptic.UnmarshalCompressed, which is not
+// available in all supported versions of Go, but also supports the
+// curves in this package. On error, x = nil.
+func UnmarshalCompressed(curve elliptic.Curve, data []byte) (x, y *big.Int) {
+	params := curve.Params()
+	size := (params.BitSize + 7) / 8
+	if len(data) != 1+size || (data[0] != 2 && data[0] != 3) {
+		return nil, nil
+	}
+
+	p := params.P
+	x = new(big.Int).SetBytes(data[1:])
+	if x.Cmp(p) >= 0 {
+		return nil, nil
+	}
+
+	// y² = x³ + ax + b
+	y2 := new(big.Int).Mul(x, x)
+	y2.Add(y2, curveA(curve))
+	y2.Mul(y2, x)
+	y2.Add(y2, params.B)
+	y2.Mod(y2, p)
+
+	y = new(big.Int).ModSqrt(y2, p)
+	if y == nil {
+		return nil, nil
+	}
+	if byte(y.Bit(0)) != data[0]&1 {
+		y.Sub(p, y)
+		y.Mod(y, p)
+	}
+	if byte(y.Bit(0)) != data[0]&1 || !curve.IsOnCurve(x, y) {
+		return nil, nil
+	}
+	return x, y
+}
@@ -0,0 +1,257 @@
+package ecutil
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"sync"
+)
+
+// weierstrassCurve implements elliptic.Curve for short Weierstrass curves
+// y² = x³ + ax + b with an arbitrary `a`, which the generic implementation
+// in crypto/elliptic does not support (it assumes a = -3).
+//
+// The arithmetic uses affine coordinates and math/big, and is NOT
+// constant time. It is only meant to be used for curves that are not
+// available in the standard library, and only for operations on public
+// keys, such as verifying signatures: operations that involve a private
+// scalar (signing, key generation, ECDH) would leak it through timing.
+// Callers must use IsVariableTime to refuse such operations.
+type weierstrassCurve struct {
+	params *elliptic.CurveParams
+	a      *big.Int
+}
+
+func (c *weierstrassCurve) Params() *elliptic.CurveParams {
+	return c.params
+}
+
+func (c *weierstrassCurve) IsOnCurve(x, y *big.Int) bool {
+	p := c.params.P
+	if x.Sign() < 0 || x.Cmp(p) >= 0 || y.Sign() < 0 || y.Cmp(p) >= 0 {
+		return false
+	}
+
+	// y² = x³ + ax + b
+	lhs := new(big.Int).Mul(y, y)
+	lhs.Mod(lhs, p)
+
+	rhs := new(big.Int).Mul(x, x)
+	rhs.Add(rhs, c.a)
+	rhs.Mul(rhs, x)
+	rhs.Add(rhs, c.params.B)
+	rhs.Mod(rhs, p)
+
+	return lhs.Cmp(rhs) == 0
+}
+
+// The point at infinity is represented as (0, 0), as in crypto/elliptic
+func isInfinity(x, y *big.Int) bool {
+	return x.Sign() == 0 && y.Sign() == 0
+}
+
+func (c *weierstrassCurve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if isInfinity(x1, y1) {
+		return new(big.Int).Set(x2), new(big.Int).Set(y2)
+	}
+	if isInfinity(x2, y2) {
+		return new(big.Int).Set(x1), new(big.Int).Set(y1)
+	}
+
+	p := c.params.P
+	if x1.Cmp(x2) == 0 {
+		if y1.Cmp(y2) == 0 {
+			return c.Double(x1, y1)
+		}
+		// P + (-P)
+		return new(big.Int), new(big.Int)
+	}
+
+	// λ = (y2 - y1) / (x2 - x1)
+	num := new(big.Int).Sub(y2, y1)
+	den := new(big.Int).Sub(x2, x1)
+	den.Mod(den, p)
+	den.ModInverse(den, p)
+	lambda := num.Mul(num, den)
+	lambda.Mod(lambda, p)
+
+	return c.finish(lambda, x1, y1, x2)
+}
+
+func (c *weierstrassCurve) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
+	if isInfinity(x1, y1) || y1.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+
+	p := c.params.P
+
+	// λ = (3x² + a) / 2y
+	num := new(big.Int).Mul(x1, x1)
+	num.Mul(num, big.NewInt(3))
+	num.Add(num, c.a)
+	den := new(big.Int).Lsh(y1, 1)
+	den.Mod(den, p)
+	den.ModInverse(den, p)
+	lambda := num.Mul(num, den)
+	lambda.Mod(lambda, p)
+
+	return c.finish(lambda, x1, y1, x1)
+}
+
+// finish computes x3 = λ² - x1 - x2, y3 = λ(x1 - x3) - y1
+func (c *weierstrassCurve) finish(lambda, x1, y1, x2 *big.Int) (*big.Int, *big.Int) {
+	p := c.params.P
+
+	x3 := new(big.Int).Mul(lambda, lambda)
+	x3.Sub(x3, x1)
+	x3.Sub(x3, x2)
+	x3.Mod(x3, p)
+
+	y3 := new(big.Int).Sub(x1, x3)
+	y3.Mul(y3, lambda)
+	y3.Sub(y3, y1)
+	y3.Mod(y3, p)
+
+	return x3, y3
+}
+
+func (c *weierstrassCurve) ScalarMult(x1, y1 *big.Int, k []byte) (*big.Int, *big.Int) {
+	x, y := new(big.Int), new(big.Int)
+	for _, b := range k {
+		for bit := 0; bit < 8; bit++ {
+			x, y = c.Double(x, y)
+			if b&0x80 == 0x80 {
+				x, y = c.Add(x1, y1, x, y)
+			}
+			b <<= 1
+		}
+	}
+	return x, y
+}
+
+func (c *weierstrassCurve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return c.ScalarMult(c.params.Gx, c.params.Gy, k)
+}
+
+// IsVariableTime reports whether the arithmetic of `curve` is implemented
+// by this package, and is thus not constant time. Keys on such curves may
+// only be used for public key operations.
+func IsVariableTime(curve elliptic.Curve) bool {
+	_, ok := curve.(*weierstrassCurve)
+	return ok
+}
+
+func hexInt(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic(`invalid curve parameter ` + s)
+	}
+	return v
+}
+
+var (
+	secp256k1Once       sync.Once
+	secp256k1           *weierstrassCurve
+	brainpoolP256r1Once sync.Once
+	brainpoolP256r1     *weierstrassCurve
+	brainpoolP320r1Once sync.Once
+	brainpoolP320r1     *weierstrassCurve
+	brainpoolP384r1Once sync.Once
+	brainpoolP384r1     *weierstrassCurve
+	brainpoolP512r1Once sync.Once
+	brainpoolP512r1     *weierstrassCurve
+)
+
+// Secp256k1 returns the secp256k1 curve (SEC 2 section 2.4.1)
+func Secp256k1() elliptic.Curve {
+	secp256k1Once.Do(func() {
+		secp256k1 = &weierstrassCurve{
+			params: &elliptic.CurveParams{
+				Name:    "secp256k1",
+				BitSize: 256,
+				P:       hexInt("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F"),
+				N:       hexInt("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141"),
+				B:       big.NewInt(7),
+				Gx:      hexInt("79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798"),
+				Gy:      hexInt("483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8"),
+			},
+			a: big.NewInt(0),
+		}
+	})
+	return secp256k1
+}
+
+// BrainpoolP256r1 returns the brainpoolP256r1 curve (RFC 5639 section 3.4)
+func BrainpoolP256r1() elliptic.Curve {
+	brainpoolP256r1Once.Do(func() {
+		brainpoolP256r1 = &weierstrassCurve{
+			params: &elliptic.CurveParams{
+				Name:    "brainpoolP256r1",
+				BitSize: 256,
+				P:       hexInt("A9FB57DBA1EEA9BC3E660A909D838D726E3BF623D52620282013481D1F6E5377"),
+				N:       hexInt("A9FB57DBA1EEA9BC3E660A909D838D718C397AA3B561A6F7901E0E82974856A7"),
+				B:       hexInt("26DC5C6CE94A4B44F330B5D9BBD77CBF958416295CF7E1CE6BCCDC18FF8C07B6"),
+				Gx:      hexInt("8BD2AEB9CB7E57CB2C4B482FFC81B7AFB9DE27E1E3BD23C23A4453BD9ACE3262"),
+				Gy:      hexInt("547EF835C3DAC4FD97F8461A14611DC9C27745132DED8E545C1D54C72F046997"),
+			},
+			a: hexInt("7D5A0975FC2C3057EEF67530417AFFE7FB8055C126DC5C6CE94A4B44F330B5D9"),
+		}
+	})
+	return brainpoolP256r1
+}
+
+// BrainpoolP320r1 returns the brainpoolP320r1 curve (RFC 5639 section 3.5)
+func BrainpoolP320r1() elliptic.Curve {
+	brainpoolP320r1Once.Do(func() {
+		brainpoolP320r1 = &weierstrassCurve{
+			params: &elliptic.CurveParams{
+				Name:    "brainpoolP320r1",
+				BitSize: 320,
+				P:       hexInt("D35E472036BC4FB7E13C785ED201E065F98FCFA6F6F40DEF4F92B9EC7893EC28FCD412B1F1B32E27"),
+				N:       hexInt("D35E472036BC4FB7E13C785ED201E065F98FCFA5B68F12A32D482EC7EE8658E98691555B44C59311"),
+				B:       hexInt("520883949DFDBC42D3AD198640688A6FE13F41349554B49ACC31DCCD884539816F5EB4AC8FB1F1A6"),
+				Gx:      hexInt("43BD7E9AFB53D8B85289BCC48EE5BFE6F20137D10A087EB6E7871E2A10A599C710AF8D0D39E20611"),
+				Gy:      hexInt("14FDD05545EC1CC8AB4093247F77275E0743FFED117182EAA9C77877AAAC6AC7D35245D1692E8EE1"),
+			},
+			a: hexInt("3EE30B568FBAB0F883CCEBD46D3F3BB8A2A73513F5EB79DA66190EB085FFA9F492F375A97D860EB4"),
+		}
+	})
+	return brainpoolP320r1
+}
+
+// BrainpoolP384r1 returns the brainpoolP384r1 curve (RFC 5639 section 3.6)
+func BrainpoolP384r1() elliptic.Curve {
+	brainpoolP384r1Once.Do(func() {
+		brainpoolP384r1 = &weierstrassCurve{
+			params: &elliptic.CurveParams{
+				Name:    "brainpoolP384r1",
+				BitSize: 384,
+				P:       hexInt("8CB91E82A3386D280F5D6F7E50E641DF152F7109ED5456B412B1DA197FB71123ACD3A729901D1A71874700133107EC53"),
+				N:       hexInt("8CB91E82A3386D280F5D6F7E50E641DF152F7109ED5456B31F166E6CAC0425A7CF3AB6AF6B7FC3103B883202E9046565"),
+				B:       hexInt("04A8C7DD22CE28268B39B55416F0447C2FB77DE107DCD2A62E880EA53EEB62D57CB4390295DBC9943AB78696FA504C11"),
+				Gx:      hexInt("1D1C64F068CF45FFA2A63A81B7C13F6B8847A3E77EF14FE3DB7FCAFE0CBD10E8E826E03436D646AAEF87B2E247D4AF1E"),
+				Gy:      hexInt("8ABE1D7520F9C2A45CB1EB8E95CFD55262B70B29FEEC5864E19C054FF99129280E4646217791811142820341263C5315"),
+			},
+			a: hexInt("7BC382C63D8C150C3C72080ACE05AFA0C2BEA28E4FB22787139165EFBA91F90F8AA5814A503AD4EB04A8C7DD22CE2826"),
+		}
+	})
+	return brainpoolP384r1
+}
+
+// BrainpoolP512r1 returns the brainpoolP512r1 curve (RFC 5639 section 3.7)
+func BrainpoolP512r1() elliptic.Curve {
+	brainpoolP512r1Once.Do(func() {
+		brainpoolP512r1 = &weierstrassCurve{
+			params: &elliptic.CurveParams{
+				Name:    "brainpoolP512r1",
+				BitSize: 512,
+				P:       hexInt("AADD9DB8DBE9C48B3FD4E6AE33C9FC07CB308DB3B3C9D20ED6639CCA703308717D4D9B009BC66842AECDA12AE6A380E62881FF2F2D82C68528AA6056583A48F3"),
+				N:       hexInt("AADD9DB8DBE9C48B3FD4E6AE33C9FC07CB308DB3B3C9D20ED6639CCA70330870553E5C414CA92619418661197FAC10471DB1D381085DDADDB58796829CA90069"),
+				B:       hexInt("3DF91610A83441CAEA9863BC2DED5D5AA8253AA10A2EF1C98B9AC8B57F1117A72BF2C7B9E7C1AC4D77FC94CADC083E67984050B75EBAE5DD2809BD638016F723"),
+				Gx:      hexInt("81AEE4BDD82ED9645A21322E9C4C6A9385ED9F70B5D916C1B43B62EEF4D0098EFF3B1F78E2D0D48D50D1687B93B97D5F7C6D5047406A5E688B352209BCB9F822"),
+				Gy:      hexInt("7DDE385D566332ECC0EABFA9CF7822FDF209F70024A57B1AA000C55B881F8111B2DCDE494A5F485E5BCA4BD88A2763AED1CA2B2FA8F0540678CD1E0F3AD80892"),
+			},
+			a: hexInt("7830A3318B603B89E2327145AC234CC594CBDD8D3DF91610A83441CAEA9863BC2DED5D5AA8253AA10A2EF1C98B9AC8B57F1117A72BF2C7B9E7C1AC4D77FC94CA"),
+		}
+	})
+	return brainpoolP512r1
+}
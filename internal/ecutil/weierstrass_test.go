@@ -0,0 +1,293 @@
+package ecutil_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/internal/ecutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func hexInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		t.Fatalf(`invalid hex integer %s`, s)
+	}
+	return v
+}
+
+// compress encodes a point in the compressed form of SEC 1 section 2.3.3
+func compress(curve elliptic.Curve, x, y *big.Int) []byte {
+	size := (curve.Params().BitSize + 7) / 8
+	buf := make([]byte, 1+size)
+	buf[0] = 2 | byte(y.Bit(0))
+	xbytes := x.Bytes()
+	copy(buf[1+size-len(xbytes):], xbytes)
+	return buf
+}
+
+// The key pairs and shared secrets of the ECDH test vectors in RFC 7027
+// appendix A. They are checked against ScalarBaseMult (dA·G = QA),
+// ScalarMult (dA·QB = dB·QA = Z), IsOnCurve and UnmarshalCompressed.
+var rfc7027Vectors = []struct {
+	Name  string
+	Curve elliptic.Curve
+	DA    string
+	XA    string
+	YA    string
+	DB    string
+	XB    string
+	YB    string
+	XZ    string
+	YZ    string
+}{
+	{
+		Name:  "brainpoolP256r1 (RFC 7027 A.1)",
+		Curve: ecutil.BrainpoolP256r1(),
+		DA:    "81DB1EE100150FF2EA338D708271BE38300CB54241D79950F77B063039804F1D",
+		XA:    "44106E913F92BC02A1705D9953A8414DB95E1AAA49E81D9E85F929A8E3100BE5",
+		YA:    "8AB4846F11CACCB73CE49CBDD120F5A900A69FD32C272223F789EF10EB089BDC",
+		DB:    "55E40BC41E37E3E2AD25C3C6654511FFA8474A91A0032087593852D3E7D76BD3",
+		XB:    "8D2D688C6CF93E1160AD04CC4429117DC2C41825E1E9FCA0ADDD34E6F1B39F7B",
+		YB:    "990C57520812BE512641E47034832106BC7D3E8DD0E4C7F1136D7006547CEC6A",
+		XZ:    "89AFC39D41D3B327814B80940B042590F96556EC91E6AE7939BCE31F3A18BF2B",
+		YZ:    "49C27868F4ECA2179BFD7D59B1E3BF34C1DBDE61AE12931648F43E59632504DE",
+	},
+	{
+		Name:  "brainpoolP384r1 (RFC 7027 A.2)",
+		Curve: ecutil.BrainpoolP384r1(),
+		DA:    "1E20F5E048A5886F1F157C74E91BDE2B98C8B52D58E5003D57053FC4B0BD65D6F15EB5D1EE1610DF870795143627D042",
+		XA:    "68B665DD91C195800650CDD363C625F4E742E8134667B767B1B476793588F885AB698C852D4A6E77A252D6380FCAF068",
+		YA:    "55BC91A39C9EC01DEE36017B7D673A931236D2F1F5C83942D049E3FA20607493E0D038FF2FD30C2AB67D15C85F7FAA59",
+		DB:    "032640BC6003C59260F7250C3DB58CE647F98E1260ACCE4ACDA3DD869F74E01F8BA5E0324309DB6A9831497ABAC96670",
+		XB:    "4D44326F269A597A5B58BBA565DA5556ED7FD9A8A9EB76C25F46DB69D19DC8CE6AD18E404B15738B2086DF37E71D1EB4",
+		YB:    "62D692136DE56CBE93BF5FA3188EF58BC8A3A0EC6C1E151A21038A42E9185329B5B275903D192F8D4E1F32FE9CC78C48",
+		XZ:    "0BD9D3A7EA0B3D519D09D8E48D0785FB744A6B355E6304BC51C229FBBCE239BBADF6403715C35D4FB2A5444F575D4F42",
+		YZ:    "0DF213417EBE4D8E40A5F76F66C56470C489A3478D146DECF6DF0D94BAE9E598157290F8756066975F1DB34B2324B7BD",
+	},
+	{
+		Name:  "brainpoolP512r1 (RFC 7027 A.3)",
+		Curve: ecutil.BrainpoolP512r1(),
+		DA:    "16302FF0DBBB5A8D733DAB7141C1B45ACBC8715939677F6A56850A38BD87BD59B09E80279609FF333EB9D4C061231FB26F92EEB04982A5F1D1764CAD57665422",
+		XA:    "0A420517E406AAC0ACDCE90FCD71487718D3B953EFD7FBEC5F7F27E28C6149999397E91E029E06457DB2D3E640668B392C2A7E737A7F0BF04436D11640FD09FD",
+		YA:    "72E6882E8DB28AAD36237CD25D580DB23783961C8DC52DFA2EC138AD472A0FCEF3887CF62B623B2A87DE5C588301EA3E5FC269B373B60724F5E82A6AD147FDE7",
+		DB:    "230E18E1BCC88A362FA54E4EA3902009292F7F8033624FD471B5D8ACE49D12CFABBC19963DAB8E2F1EBA00BFFB29E4D72D13F2224562F405CB80503666B25429",
+		XB:    "9D45F66DE5D67E2E6DB6E93A59CE0BB48106097FF78A081DE781CDB31FCE8CCBAAEA8DD4320C4119F1E9CD437A2EAB3731FA9668AB268D871DEDA55A5473199F",
+		YB:    "2FDC313095BCDD5FB3A91636F07A959C8E86B5636A1E930E8396049CB481961D365CC11453A06C719835475B12CB52FC3C383BCE35E27EF194512B71876285FA",
+		XZ:    "A7927098655F1F9976FA50A9D566865DC530331846381C87256BAF3226244B76D36403C024D7BBF0AA0803EAFF405D3D24F11A9B5C0BEF679FE1454B21C4CD1F",
+		YZ:    "7DB71C3DEF63212841C463E881BDCF055523BD368240E6C3143BD8DEF8B3B3223B95E0F53082FF5E412F4222537A43DF1C6D25729DDB51620A832BE6A26680A2",
+	},
+}
+
+func TestRFC7027(t *testing.T) {
+	t.Parallel()
+	for _, tc := range rfc7027Vectors {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			curve := tc.Curve
+			dA, xA, yA := hexInt(t, tc.DA), hexInt(t, tc.XA), hexInt(t, tc.YA)
+			dB, xB, yB := hexInt(t, tc.DB), hexInt(t, tc.XB), hexInt(t, tc.YB)
+			xZ, yZ := hexInt(t, tc.XZ), hexInt(t, tc.YZ)
+
+			for _, pt := range [][2]*big.Int{{xA, yA}, {xB, yB}, {xZ, yZ}} {
+				if !assert.True(t, curve.IsOnCurve(pt[0], pt[1]), `point should be on the curve`) {
+					return
+				}
+			}
+
+			x, y := curve.ScalarBaseMult(dA.Bytes())
+			if !assert.Equal(t, xA, x, `dA·G should match QA (x)`) || !assert.Equal(t, yA, y, `dA·G should match QA (y)`) {
+				return
+			}
+			x, y = curve.ScalarBaseMult(dB.Bytes())
+			if !assert.Equal(t, xB, x, `dB·G should match QB (x)`) || !assert.Equal(t, yB, y, `dB·G should match QB (y)`) {
+				return
+			}
+			x, y = curve.ScalarMult(xB, yB, dA.Bytes())
+			if !assert.Equal(t, xZ, x, `dA·QB should match Z (x)`) || !assert.Equal(t, yZ, y, `dA·QB should match Z (y)`) {
+				return
+			}
+			x, y = curve.ScalarMult(xA, yA, dB.Bytes())
+			if !assert.Equal(t, xZ, x, `dB·QA should match Z (x)`) || !assert.Equal(t, yZ, y, `dB·QA should match Z (y)`) {
+				return
+			}
+
+			for _, pt := range [][2]*big.Int{{xA, yA}, {xB, yB}, {xZ, yZ}} {
+				x, y := ecutil.UnmarshalCompressed(curve, compress(curve, pt[0], pt[1]))
+				if !assert.NotNil(t, x, `ecutil.UnmarshalCompressed should succeed`) {
+					return
+				}
+				if !assert.Equal(t, pt[0], x, `decompressed x should match`) || !assert.Equal(t, pt[1], y, `decompressed y should match`) {
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestSecp256k1(t *testing.T) {
+	t.Parallel()
+
+	// Multiples of the generator, as listed in SEC 2 and widely
+	// reproduced (e.g. the Bitcoin test suites)
+	curve := ecutil.Secp256k1()
+	params := curve.Params()
+	x2, y2 := hexInt(t, "C6047F9441ED7D6D3045406E95C07CD85C778E4B8CEF3CA7ABAC09B95C709EE5"), hexInt(t, "1AE168FEA63DC339A3C58419466CEAEEF7F632653266D0E1236431A950CFE52A")
+	x3, y3 := hexInt(t, "F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9"), hexInt(t, "388F7B0F632DE8140FE337E62A37F3566500A99934C2231B6CB9FD7584B8E672")
+
+	t.Run("IsOnCurve", func(t *testing.T) {
+		t.Parallel()
+		assert.True(t, curve.IsOnCurve(params.Gx, params.Gy), `G should be on the curve`)
+		assert.True(t, curve.IsOnCurve(x2, y2), `2G should be on the curve`)
+		assert.True(t, curve.IsOnCurve(x3, y3), `3G should be on the curve`)
+	})
+	t.Run("Add", func(t *testing.T) {
+		t.Parallel()
+		x, y := curve.Add(params.Gx, params.Gy, params.Gx, params.Gy)
+		if !assert.Equal(t, x2, x, `G+G should match 2G (x)`) || !assert.Equal(t, y2, y, `G+G should match 2G (y)`) {
+			return
+		}
+		x, y = curve.Add(x2, y2, params.Gx, params.Gy)
+		if !assert.Equal(t, x3, x, `2G+G should match 3G (x)`) || !assert.Equal(t, y3, y, `2G+G should match 3G (y)`) {
+			return
+		}
+		x, y = curve.Add(params.Gx, params.Gy, params.Gx, new(big.Int).Sub(params.P, params.Gy))
+		if !assert.Zero(t, x.Sign(), `G+(-G) should be the point at infinity`) || !assert.Zero(t, y.Sign(), `G+(-G) should be the point at infinity`) {
+			return
+		}
+	})
+	t.Run("ScalarBaseMult", func(t *testing.T) {
+		t.Parallel()
+		x, y := curve.ScalarBaseMult([]byte{3})
+		if !assert.Equal(t, x3, x, `3·G should match 3G (x)`) || !assert.Equal(t, y3, y, `3·G should match 3G (y)`) {
+			return
+		}
+		x, y = curve.ScalarBaseMult(new(big.Int).Sub(params.N, big.NewInt(1)).Bytes())
+		if !assert.Equal(t, params.Gx, x, `(n-1)·G should be -G (x)`) || !assert.Equal(t, new(big.Int).Sub(params.P, params.Gy), y, `(n-1)·G should be -G (y)`) {
+			return
+		}
+	})
+	t.Run("Decompression", func(t *testing.T) {
+		t.Parallel()
+		for _, pt := range [][2]*big.Int{{params.Gx, params.Gy}, {x2, y2}, {x3, y3}} {
+			x, y := ecutil.UnmarshalCompressed(curve, compress(curve, pt[0], pt[1]))
+			if !assert.NotNil(t, x, `ecutil.UnmarshalCompressed should succeed`) {
+				return
+			}
+			if !assert.Equal(t, pt[0], x, `decompressed x should match`) || !assert.Equal(t, pt[1], y, `decompressed y should match`) {
+				return
+			}
+		}
+	})
+}
+
+func TestInvalidPoints(t *testing.T) {
+	t.Parallel()
+	curves := []elliptic.Curve{
+		ecutil.Secp256k1(),
+		ecutil.BrainpoolP256r1(),
+		ecutil.BrainpoolP320r1(),
+		ecutil.BrainpoolP384r1(),
+		ecutil.BrainpoolP512r1(),
+	}
+	for _, curve := range curves {
+		curve := curve
+		t.Run(curve.Params().Name, func(t *testing.T) {
+			t.Parallel()
+			params := curve.Params()
+			gx, gy := params.Gx, params.Gy
+			if !assert.True(t, curve.IsOnCurve(gx, gy), `generator should be on the curve`) {
+				return
+			}
+
+			offCurve := new(big.Int).Add(gy, big.NewInt(1))
+			assert.False(t, curve.IsOnCurve(gx, offCurve), `(Gx, Gy+1) should not be on the curve`)
+			assert.False(t, curve.IsOnCurve(new(big.Int).Add(gx, params.P), gy), `coordinates >= p should be rejected`)
+			assert.False(t, curve.IsOnCurve(gx, new(big.Int).Sub(gy, params.P)), `negative coordinates should be rejected`)
+			assert.False(t, curve.IsOnCurve(new(big.Int), new(big.Int)), `the point at infinity should not be on the curve`)
+
+			compressed := compress(curve, gx, gy)
+			x, _ := ecutil.UnmarshalCompressed(curve, compressed[:len(compressed)-1])
+			assert.Nil(t, x, `truncated points should be rejected`)
+
+			bad := append([]byte{0x04}, compressed[1:]...)
+			x, _ = ecutil.UnmarshalCompressed(curve, bad)
+			assert.Nil(t, x, `invalid prefixes should be rejected`)
+
+			bad = compress(curve, params.P, gy)
+			x, _ = ecutil.UnmarshalCompressed(curve, bad)
+			assert.Nil(t, x, `x >= p should be rejected`)
+
+			// About half of the x coordinates do not correspond to a
+			// point on the curve: those must be rejected, and the others
+			// must decompress to a point on the curve
+			var rejected int
+			for i := int64(1); i <= 32; i++ {
+				candidate := new(big.Int).Add(gx, big.NewInt(i))
+				for _, prefix := range []byte{2, 3} {
+					buf := compress(curve, candidate, big.NewInt(int64(prefix&1)))
+					x, y := ecutil.UnmarshalCompressed(curve, buf)
+					if x == nil {
+						rejected++
+						continue
+					}
+					if !assert.Equal(t, candidate, x, `decompressed x should match`) || !assert.True(t, curve.IsOnCurve(x, y), `decompressed point should be on the curve`) {
+						return
+					}
+					if !assert.Equal(t, uint(prefix&1), y.Bit(0), `parity of y should match the prefix`) {
+						return
+					}
+				}
+			}
+			assert.NotZero(t, rejected, `x coordinates that are not on the curve should be rejected`)
+		})
+	}
+}
+
+func TestScalarRange(t *testing.T) {
+	t.Parallel()
+	curves := []elliptic.Curve{
+		ecutil.Secp256k1(),
+		ecutil.BrainpoolP256r1(),
+		ecutil.BrainpoolP384r1(),
+		ecutil.BrainpoolP512r1(),
+	}
+	for _, curve := range curves {
+		curve := curve
+		t.Run(curve.Params().Name, func(t *testing.T) {
+			t.Parallel()
+			params := curve.Params()
+
+			x, y := curve.ScalarBaseMult([]byte{0})
+			assert.True(t, x.Sign() == 0 && y.Sign() == 0, `0·G should be the point at infinity`)
+
+			x, y = curve.ScalarBaseMult(params.N.Bytes())
+			assert.True(t, x.Sign() == 0 && y.Sign() == 0, `n·G should be the point at infinity`)
+
+			// Scalars larger than the order wrap around
+			x, y = curve.ScalarBaseMult(new(big.Int).Add(params.N, big.NewInt(1)).Bytes())
+			assert.Equal(t, params.Gx, x, `(n+1)·G should be G (x)`)
+			assert.Equal(t, params.Gy, y, `(n+1)·G should be G (y)`)
+
+			// Signatures whose r or s are out of [1, n-1] must not verify
+			pub := &ecdsa.PublicKey{Curve: curve, X: params.Gx, Y: params.Gy}
+			digest := sha256.Sum256([]byte(`hello`))
+			one := big.NewInt(1)
+			for _, rs := range [][2]*big.Int{
+				{new(big.Int), one},
+				{one, new(big.Int)},
+				{params.N, one},
+				{one, params.N},
+				{new(big.Int).Add(params.N, one), one},
+				{one, new(big.Int).Add(params.N, one)},
+			} {
+				assert.False(t, ecdsa.Verify(pub, digest[:], rs[0], rs[1]), `signatures with out of range values should not verify`)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package json
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Canonicalize re-encodes the JSON document in `src` in a canonical form:
+//
+//   - The members of all objects, including nested ones, are sorted by
+//     their names in byte order
+//   - There is no whitespace between elements
+//   - Strings are escaped as done by encoding/json, without escaping the
+//     HTML characters "<", ">" and "&"
+//   - Numbers are represented exactly as they appear in `src`
+func Canonicalize(src []byte) ([]byte, error) {
+	dec := NewDecoder(bytes.NewReader(src))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, errors.Wrap(err, `failed to decode JSON`)
+	}
+
+	var dst bytes.Buffer
+	if err := WriteCanonical(&dst, v); err != nil {
+		return nil, err
+	}
+	return dst.Bytes(), nil
+}
+
+// WriteCanonical writes the canonical form of `v` (see `Canonicalize()`)
+// to `dst`. `v` must be a value decoded by a Decoder with UseNumber()
+// turned on, i.e. it may only contain maps, slices, strings, Numbers,
+// booleans and nil.
+func WriteCanonical(dst *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		dst.WriteByte('{')
+		for i, name := range names {
+			if i > 0 {
+				dst.WriteByte(',')
+			}
+			if err := writeCanonicalString(dst, name); err != nil {
+				return err
+			}
+			dst.WriteByte(':')
+			if err := WriteCanonical(dst, v[name]); err != nil {
+				return err
+			}
+		}
+		dst.WriteByte('}')
+	case []interface{}:
+		dst.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				dst.WriteByte(',')
+			}
+			if err := WriteCanonical(dst, elem); err != nil {
+				return err
+			}
+		}
+		dst.WriteByte(']')
+	case string:
+		return writeCanonicalString(dst, v)
+	case Number:
+		dst.WriteString(v.String())
+	case bool:
+		if v {
+			dst.WriteString("true")
+		} else {
+			dst.WriteString("false")
+		}
+	case nil:
+		dst.WriteString("null")
+	default:
+		return errors.Errorf(`unexpected value type %T`, v)
+	}
+	return nil
+}
+
+func writeCanonicalString(dst *bytes.Buffer, s string) error {
+	enc := NewEncoder(dst)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return errors.Wrap(err, `failed to encode string`)
+	}
+	// Encode() appends a newline
+	dst.Truncate(dst.Len() - 1)
+	return nil
+}
@@ -55,3 +55,61 @@ func AssignNextStringToken(dst **string, dec *Decoder) error {
 	*dst = &val
 	return nil
 }
+
+// CheckDuplicateKeys scans the JSON document and returns an error if
+// any of the objects in it, at any depth, contains the same member name
+// more than once. Such documents are not rejected by `Unmarshal`, which
+// silently uses the last value, while other parsers may use the first.
+func CheckDuplicateKeys(data []byte) error {
+	dec := NewDecoder(bytes.NewReader(data))
+	return checkDuplicateKeys(dec)
+}
+
+func checkDuplicateKeys(dec *Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return errors.Wrap(err, `error reading next token`)
+	}
+
+	delim, ok := tok.(Delim)
+	if !ok {
+		return nil
+	}
+
+	switch delim {
+	case '{':
+		seen := make(map[string]struct{})
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return errors.Wrap(err, `error reading object key`)
+			}
+			key, ok := tok.(string)
+			if !ok {
+				return errors.Errorf(`expected object key, got %T`, tok)
+			}
+			if _, ok := seen[key]; ok {
+				return errors.Errorf(`duplicate key %q`, key)
+			}
+			seen[key] = struct{}{}
+
+			if err := checkDuplicateKeys(dec); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for dec.More() {
+			if err := checkDuplicateKeys(dec); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.Errorf(`unexpected delimiter %s`, delim)
+	}
+
+	// consume the closing delimiter
+	if _, err := dec.Token(); err != nil {
+		return errors.Wrap(err, `error reading closing delimiter`)
+	}
+	return nil
+}
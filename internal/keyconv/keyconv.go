@@ -175,3 +175,26 @@ func Ed25519PublicKey(dst, src interface{}) error {
 	}
 	return blackmagic.AssignIfCompatible(dst, ptr)
 }
+
+// PublicJWK returns the public key of `src` as a jwk.Key.
+// `src` may be a raw key (e.g. *ecdsa.PrivateKey) or a jwk.Key.
+// Symmetric keys are rejected, as they do not have a public key.
+func PublicJWK(src interface{}) (jwk.Key, error) {
+	jwkKey, ok := src.(jwk.Key)
+	if !ok {
+		v, err := jwk.New(src)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to create jwk.Key from raw key`)
+		}
+		jwkKey = v
+	}
+	if _, ok := jwkKey.(jwk.SymmetricKey); ok {
+		return nil, errors.New(`symmetric keys do not have a public key`)
+	}
+
+	pubkey, err := jwk.PublicKeyOf(jwkKey)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to get public key`)
+	}
+	return pubkey, nil
+}
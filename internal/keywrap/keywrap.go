@@ -0,0 +1,110 @@
+// Package keywrap implements the AES key wrap algorithm described in
+// RFC 3394
+package keywrap
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+
+	"github.com/lestrrat-go/pdebug/v3"
+	"github.com/pkg/errors"
+)
+
+var keywrapDefaultIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+const keywrapChunkLen = 8
+
+// Wrap encrypts `cek` using the key encryption key `kek`. The length of
+// `cek` must be a multiple of 8 bytes.
+func Wrap(kek cipher.Block, cek []byte) ([]byte, error) {
+	if len(cek)%8 != 0 {
+		return nil, errors.New(`keywrap input must be 8 byte blocks`)
+	}
+
+	n := len(cek) / keywrapChunkLen
+	r := make([][]byte, n)
+
+	for i := 0; i < n; i++ {
+		r[i] = make([]byte, keywrapChunkLen)
+		copy(r[i], cek[i*keywrapChunkLen:])
+	}
+
+	buffer := make([]byte, keywrapChunkLen*2)
+	tBytes := make([]byte, keywrapChunkLen)
+	copy(buffer, keywrapDefaultIV)
+
+	for t := 0; t < 6*n; t++ {
+		copy(buffer[keywrapChunkLen:], r[t%n])
+
+		kek.Encrypt(buffer, buffer)
+
+		binary.BigEndian.PutUint64(tBytes, uint64(t+1))
+
+		for i := 0; i < keywrapChunkLen; i++ {
+			buffer[i] = buffer[i] ^ tBytes[i]
+		}
+		copy(r[t%n], buffer[keywrapChunkLen:])
+	}
+
+	out := make([]byte, (n+1)*keywrapChunkLen)
+	copy(out, buffer[:keywrapChunkLen])
+	for i := range r {
+		copy(out[(i+1)*8:], r[i])
+	}
+
+	return out, nil
+}
+
+// Unwrap decrypts the output of `Wrap()`, and verifies its integrity
+func Unwrap(block cipher.Block, ciphertxt []byte) ([]byte, error) {
+	if pdebug.Enabled {
+		g := pdebug.FuncMarker()
+		defer g.End()
+	}
+
+	if len(ciphertxt)%keywrapChunkLen != 0 {
+		return nil, errors.Errorf(`keyunwrap input must be %d byte blocks`, keywrapChunkLen)
+	}
+
+	n := (len(ciphertxt) / keywrapChunkLen) - 1
+	r := make([][]byte, n)
+
+	for i := range r {
+		r[i] = make([]byte, keywrapChunkLen)
+		copy(r[i], ciphertxt[(i+1)*keywrapChunkLen:])
+	}
+
+	buffer := make([]byte, keywrapChunkLen*2)
+	tBytes := make([]byte, keywrapChunkLen)
+	copy(buffer[:keywrapChunkLen], ciphertxt[:keywrapChunkLen])
+
+	for t := 6*n - 1; t >= 0; t-- {
+		binary.BigEndian.PutUint64(tBytes, uint64(t+1))
+
+		for i := 0; i < keywrapChunkLen; i++ {
+			buffer[i] = buffer[i] ^ tBytes[i]
+		}
+		copy(buffer[keywrapChunkLen:], r[t%n])
+
+		block.Decrypt(buffer, buffer)
+
+		copy(r[t%n], buffer[keywrapChunkLen:])
+	}
+
+	if subtle.ConstantTimeCompare(buffer[:keywrapChunkLen], keywrapDefaultIV) == 0 {
+		if pdebug.Enabled {
+			pdebug.Printf("buffer prefix does not match default iv")
+			pdebug.Printf("prefix  = %x", buffer[:keywrapChunkLen])
+			pdebug.Printf("default = %x", keywrapDefaultIV)
+		}
+		return nil, errors.New("key unwrap: failed to unwrap key")
+	}
+
+	out := make([]byte, n*keywrapChunkLen)
+	for i := range r {
+		copy(out[i*keywrapChunkLen:], r[i])
+	}
+
+	return out, nil
+}
@@ -0,0 +1,215 @@
+// Package ttlcache implements the size-bounded, expiring in-memory cache
+// that backs the various caches of this library
+package ttlcache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache maps keys to values. It is safe for concurrent use.
+//
+// Entries expire after the TTL of the cache has passed since they were
+// stored, or at the expiration time given when they were stored,
+// whichever comes first. Expired entries are removed when they are
+// looked up, or by `Purge()`.
+//
+// At most `maxEntries` entries are retained. When the cache is full,
+// the oldest entries (or for caches created by `NewLRU()`, the least
+// recently used entries) are evicted first.
+type Cache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	lru        bool
+	entries    map[interface{}]*list.Element
+	order      *list.List // entries to be evicted first are at the front
+	evictions  uint64
+}
+
+type entry struct {
+	key     interface{}
+	value   interface{}
+	expires time.Time
+}
+
+// New creates a new Cache that evicts the oldest entries first.
+//
+// If `ttl` is less than or equal to 0, entries only expire at the
+// expiration time given when they are stored. If `maxEntries` is less
+// than or equal to 0, the number of entries is not limited.
+func New(maxEntries int, ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[interface{}]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// NewLRU creates a new Cache that evicts the least recently used
+// entries first. See `New()` for the meaning of the arguments.
+func NewLRU(maxEntries int, ttl time.Duration) *Cache {
+	c := New(maxEntries, ttl)
+	c.lru = true
+	return c
+}
+
+func expired(e *entry, now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// Get returns the value stored under `key`, if it has not expired
+func (c *Cache) Get(key interface{}) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := elem.Value.(*entry)
+	if expired(e, time.Now()) {
+		c.removeElement(elem)
+		c.evictions++
+		return nil, false
+	}
+
+	if c.lru {
+		c.order.MoveToBack(elem)
+	}
+	return e.value, true
+}
+
+// Set stores `value` under `key`, replacing the existing entry if any.
+// `expires` may be the zero time, in which case the entry only expires
+// after the TTL of the cache. Values that would expire immediately are
+// not stored.
+func (c *Cache) Set(key, value interface{}, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value, expires)
+}
+
+// Add stores `value` under `key` unless an entry that has not expired
+// exists. It returns false if such an entry exists.
+func (c *Cache) Add(key, value interface{}, expires time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok && !expired(elem.Value.(*entry), time.Now()) {
+		return false
+	}
+	c.set(key, value, expires)
+	return true
+}
+
+func (c *Cache) set(key, value interface{}, expires time.Time) {
+	now := time.Now()
+	if c.ttl > 0 {
+		if v := now.Add(c.ttl); expires.IsZero() || v.Before(expires) {
+			expires = v
+		}
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		c.removeElement(elem)
+	}
+
+	e := &entry{
+		key:     key,
+		value:   value,
+		expires: expires,
+	}
+	if expired(e, now) {
+		return
+	}
+	c.entries[key] = c.order.PushBack(e)
+
+	if c.maxEntries > 0 {
+		for c.order.Len() > c.maxEntries {
+			c.removeElement(c.order.Front())
+			c.evictions++
+		}
+	}
+}
+
+// Remove removes the entry stored under `key`
+func (c *Cache) Remove(key interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// RemoveFunc removes all entries for which `f` returns true, and
+// returns the number of entries that were removed
+func (c *Cache) RemoveFunc(f func(key, value interface{}) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var removed int
+	var next *list.Element
+	for elem := c.order.Front(); elem != nil; elem = next {
+		next = elem.Next()
+		e := elem.Value.(*entry)
+		if f(e.key, e.value) {
+			c.removeElement(elem)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Purge removes all the entries that have expired
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	var next *list.Element
+	for elem := c.order.Front(); elem != nil; elem = next {
+		next = elem.Next()
+		if expired(elem.Value.(*entry), now) {
+			c.removeElement(elem)
+			c.evictions++
+		}
+	}
+}
+
+// Len returns the number of entries in the cache, including those
+// that have expired but have not been removed yet.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Evictions returns the number of entries that were removed because
+// they expired, or because the cache was full
+func (c *Cache) Evictions() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.evictions
+}
+
+// Clear removes all entries from the cache, and returns the number
+// of entries that were removed
+func (c *Cache) Clear() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.order.Len()
+	c.entries = make(map[interface{}]*list.Element)
+	c.order.Init()
+	return n
+}
+
+func (c *Cache) removeElement(elem *list.Element) {
+	e := c.order.Remove(elem).(*entry)
+	delete(c.entries, e.key)
+}
@@ -0,0 +1,92 @@
+package ttlcache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/internal/ttlcache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Eviction order", func(t *testing.T) {
+		t.Parallel()
+		fifo := ttlcache.New(2, 0)
+		lru := ttlcache.NewLRU(2, 0)
+		for _, c := range []*ttlcache.Cache{fifo, lru} {
+			c.Set("a", 1, time.Time{})
+			c.Set("b", 2, time.Time{})
+			_, _ = c.Get("a")
+			c.Set("c", 3, time.Time{})
+		}
+
+		if _, ok := fifo.Get("a"); !assert.False(t, ok, `oldest entry should be evicted`) {
+			return
+		}
+		if _, ok := lru.Get("b"); !assert.False(t, ok, `least recently used entry should be evicted`) {
+			return
+		}
+		if v, ok := lru.Get("a"); !assert.True(t, ok, `recently used entry should be retained`) || !assert.Equal(t, 1, v, `value should match`) {
+			return
+		}
+		if !assert.Equal(t, uint64(1), lru.Evictions(), `evictions should match`) {
+			return
+		}
+	})
+	t.Run("Expiration", func(t *testing.T) {
+		t.Parallel()
+		c := ttlcache.New(0, time.Hour)
+
+		// The earlier of the TTL and the given expiration time applies
+		c.Set("past", 1, time.Now().Add(-time.Second))
+		c.Set("soon", 2, time.Now().Add(time.Millisecond))
+		c.Set("later", 3, time.Now().Add(2*time.Hour))
+		if !assert.Equal(t, 2, c.Len(), `expired values should not be stored`) {
+			return
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		if _, ok := c.Get("soon"); !assert.False(t, ok, `expired entry should not be returned`) {
+			return
+		}
+		if _, ok := c.Get("later"); !assert.True(t, ok, `entry should not expire before the TTL`) {
+			return
+		}
+	})
+	t.Run("Add", func(t *testing.T) {
+		t.Parallel()
+		c := ttlcache.New(0, 0)
+		if !assert.True(t, c.Add("a", 1, time.Now().Add(time.Millisecond)), `first Add should succeed`) {
+			return
+		}
+		if !assert.False(t, c.Add("a", 2, time.Time{}), `Add should fail while the entry is valid`) {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+		if !assert.True(t, c.Add("a", 3, time.Time{}), `Add should succeed after the entry expired`) {
+			return
+		}
+	})
+	t.Run("Removal", func(t *testing.T) {
+		t.Parallel()
+		c := ttlcache.New(0, 0)
+		for i := 0; i < 4; i++ {
+			c.Set(i, i, time.Time{})
+		}
+		c.Remove(0)
+		n := c.RemoveFunc(func(_, value interface{}) bool {
+			return value.(int)%2 == 1
+		})
+		if !assert.Equal(t, 2, n, `RemoveFunc should remove odd values`) {
+			return
+		}
+		if !assert.Equal(t, 1, c.Clear(), `Clear should remove the remaining entry`) {
+			return
+		}
+		if !assert.Equal(t, 0, c.Len(), `cache should be empty`) {
+			return
+		}
+	})
+}
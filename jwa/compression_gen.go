@@ -4,6 +4,7 @@ package jwa
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -55,6 +56,33 @@ func (v *CompressionAlgorithm) Accept(value interface{}) error {
 	return nil
 }
 
+// ParseCompressionAlgorithm converts `s` to a CompressionAlgorithm. Like Accept, it only
+// accepts the exact values defined in the specifications, unless
+// `jwa.WithLenient(true)` is specified
+func ParseCompressionAlgorithm(s string, options ...ParseOption) (CompressionAlgorithm, error) {
+	tmp := CompressionAlgorithm(s)
+	if isLenient(options) {
+		tmp = lenientCompressionAlgorithm(tmp)
+	}
+
+	var v CompressionAlgorithm
+	if err := v.Accept(tmp); err != nil {
+		return "", err
+	}
+	return v, nil
+}
+
+// lenientCompressionAlgorithm converts case variants and aliases of
+// known CompressionAlgorithm values to their canonical form
+func lenientCompressionAlgorithm(v CompressionAlgorithm) CompressionAlgorithm {
+	for _, known := range allCompressionAlgorithms {
+		if strings.EqualFold(string(known), string(v)) {
+			return known
+		}
+	}
+	return v
+}
+
 // String returns the string representation of a CompressionAlgorithm
 func (v CompressionAlgorithm) String() string {
 	return string(v)
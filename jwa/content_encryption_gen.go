@@ -4,6 +4,7 @@ package jwa
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -63,6 +64,33 @@ func (v *ContentEncryptionAlgorithm) Accept(value interface{}) error {
 	return nil
 }
 
+// ParseContentEncryptionAlgorithm converts `s` to a ContentEncryptionAlgorithm. Like Accept, it only
+// accepts the exact values defined in the specifications, unless
+// `jwa.WithLenient(true)` is specified
+func ParseContentEncryptionAlgorithm(s string, options ...ParseOption) (ContentEncryptionAlgorithm, error) {
+	tmp := ContentEncryptionAlgorithm(s)
+	if isLenient(options) {
+		tmp = lenientContentEncryptionAlgorithm(tmp)
+	}
+
+	var v ContentEncryptionAlgorithm
+	if err := v.Accept(tmp); err != nil {
+		return "", err
+	}
+	return v, nil
+}
+
+// lenientContentEncryptionAlgorithm converts case variants and aliases of
+// known ContentEncryptionAlgorithm values to their canonical form
+func lenientContentEncryptionAlgorithm(v ContentEncryptionAlgorithm) ContentEncryptionAlgorithm {
+	for _, known := range allContentEncryptionAlgorithms {
+		if strings.EqualFold(string(known), string(v)) {
+			return known
+		}
+	}
+	return v
+}
+
 // String returns the string representation of a ContentEncryptionAlgorithm
 func (v ContentEncryptionAlgorithm) String() string {
 	return string(v)
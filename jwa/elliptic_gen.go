@@ -4,31 +4,42 @@ package jwa
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 )
 
-//  EllipticCurveAlgorithm represents the algorithms used for EC keys
+// EllipticCurveAlgorithm represents the algorithms used for EC keys
 type EllipticCurveAlgorithm string
 
 // Supported values for EllipticCurveAlgorithm
 const (
+	BrainpoolP256r1      EllipticCurveAlgorithm = "BP-256" // brainpoolP256r1 (RFC 5639). Keys require the jwx_brainpool build tag
+	BrainpoolP320r1      EllipticCurveAlgorithm = "BP-320" // brainpoolP320r1 (RFC 5639). Keys require the jwx_brainpool build tag
+	BrainpoolP384r1      EllipticCurveAlgorithm = "BP-384" // brainpoolP384r1 (RFC 5639). Keys require the jwx_brainpool build tag
+	BrainpoolP512r1      EllipticCurveAlgorithm = "BP-512" // brainpoolP512r1 (RFC 5639). Keys require the jwx_brainpool build tag
 	Ed25519              EllipticCurveAlgorithm = "Ed25519"
 	Ed448                EllipticCurveAlgorithm = "Ed448"
 	InvalidEllipticCurve EllipticCurveAlgorithm = "P-invalid"
 	P256                 EllipticCurveAlgorithm = "P-256"
 	P384                 EllipticCurveAlgorithm = "P-384"
 	P521                 EllipticCurveAlgorithm = "P-521"
+	Secp256k1            EllipticCurveAlgorithm = "secp256k1" // SECG secp256k1 (RFC 8812). Keys require the jwx_es256k build tag
 	X25519               EllipticCurveAlgorithm = "X25519"
 	X448                 EllipticCurveAlgorithm = "X448"
 )
 
 var allEllipticCurveAlgorithms = []EllipticCurveAlgorithm{
+	BrainpoolP256r1,
+	BrainpoolP320r1,
+	BrainpoolP384r1,
+	BrainpoolP512r1,
 	Ed25519,
 	Ed448,
 	P256,
 	P384,
 	P521,
+	Secp256k1,
 	X25519,
 	X448,
 }
@@ -57,7 +68,7 @@ func (v *EllipticCurveAlgorithm) Accept(value interface{}) error {
 		tmp = EllipticCurveAlgorithm(s)
 	}
 	switch tmp {
-	case Ed25519, Ed448, P256, P384, P521, X25519, X448:
+	case BrainpoolP256r1, BrainpoolP320r1, BrainpoolP384r1, BrainpoolP512r1, Ed25519, Ed448, P256, P384, P521, Secp256k1, X25519, X448:
 	default:
 		return errors.Errorf(`invalid jwa.EllipticCurveAlgorithm value`)
 	}
@@ -66,6 +77,47 @@ func (v *EllipticCurveAlgorithm) Accept(value interface{}) error {
 	return nil
 }
 
+// ParseEllipticCurveAlgorithm converts `s` to a EllipticCurveAlgorithm. Like Accept, it only
+// accepts the exact values defined in the specifications, unless
+// `jwa.WithLenient(true)` is specified
+func ParseEllipticCurveAlgorithm(s string, options ...ParseOption) (EllipticCurveAlgorithm, error) {
+	tmp := EllipticCurveAlgorithm(s)
+	if isLenient(options) {
+		tmp = lenientEllipticCurveAlgorithm(tmp)
+	}
+
+	var v EllipticCurveAlgorithm
+	if err := v.Accept(tmp); err != nil {
+		return "", err
+	}
+	return v, nil
+}
+
+var ellipticCurveAlgorithmAliases = map[string]EllipticCurveAlgorithm{
+	"brainpoolp256r1": BrainpoolP256r1,
+	"brainpoolp320r1": BrainpoolP320r1,
+	"brainpoolp384r1": BrainpoolP384r1,
+	"brainpoolp512r1": BrainpoolP512r1,
+	"secp256r1":       P256,
+	"prime256v1":      P256,
+	"secp384r1":       P384,
+	"secp521r1":       P521,
+}
+
+// lenientEllipticCurveAlgorithm converts case variants and aliases of
+// known EllipticCurveAlgorithm values to their canonical form
+func lenientEllipticCurveAlgorithm(v EllipticCurveAlgorithm) EllipticCurveAlgorithm {
+	for _, known := range allEllipticCurveAlgorithms {
+		if strings.EqualFold(string(known), string(v)) {
+			return known
+		}
+	}
+	if known, ok := ellipticCurveAlgorithmAliases[strings.ToLower(string(v))]; ok {
+		return known
+	}
+	return v
+}
+
 // String returns the string representation of a EllipticCurveAlgorithm
 func (v EllipticCurveAlgorithm) String() string {
 	return string(v)
@@ -11,6 +11,150 @@ import (
 
 func TestEllipticCurveAlgorithm(t *testing.T) {
 	t.Parallel()
+	t.Run(`accept jwa constant BrainpoolP256r1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.EllipticCurveAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.BrainpoolP256r1), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BrainpoolP256r1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string BP-256`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.EllipticCurveAlgorithm
+		if !assert.NoError(t, dst.Accept("BP-256"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BrainpoolP256r1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for BP-256`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.EllipticCurveAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "BP-256"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BrainpoolP256r1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for BP-256`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "BP-256", jwa.BrainpoolP256r1.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant BrainpoolP320r1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.EllipticCurveAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.BrainpoolP320r1), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BrainpoolP320r1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string BP-320`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.EllipticCurveAlgorithm
+		if !assert.NoError(t, dst.Accept("BP-320"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BrainpoolP320r1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for BP-320`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.EllipticCurveAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "BP-320"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BrainpoolP320r1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for BP-320`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "BP-320", jwa.BrainpoolP320r1.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant BrainpoolP384r1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.EllipticCurveAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.BrainpoolP384r1), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BrainpoolP384r1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string BP-384`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.EllipticCurveAlgorithm
+		if !assert.NoError(t, dst.Accept("BP-384"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BrainpoolP384r1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for BP-384`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.EllipticCurveAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "BP-384"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BrainpoolP384r1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for BP-384`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "BP-384", jwa.BrainpoolP384r1.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant BrainpoolP512r1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.EllipticCurveAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.BrainpoolP512r1), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BrainpoolP512r1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string BP-512`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.EllipticCurveAlgorithm
+		if !assert.NoError(t, dst.Accept("BP-512"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BrainpoolP512r1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for BP-512`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.EllipticCurveAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "BP-512"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BrainpoolP512r1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for BP-512`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "BP-512", jwa.BrainpoolP512r1.String(), `stringified value matches`) {
+			return
+		}
+	})
 	t.Run(`accept jwa constant Ed25519`, func(t *testing.T) {
 		t.Parallel()
 		var dst jwa.EllipticCurveAlgorithm
@@ -191,6 +335,42 @@ func TestEllipticCurveAlgorithm(t *testing.T) {
 			return
 		}
 	})
+	t.Run(`accept jwa constant Secp256k1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.EllipticCurveAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.Secp256k1), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.Secp256k1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string secp256k1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.EllipticCurveAlgorithm
+		if !assert.NoError(t, dst.Accept("secp256k1"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.Secp256k1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for secp256k1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.EllipticCurveAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "secp256k1"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.Secp256k1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for secp256k1`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "secp256k1", jwa.Secp256k1.String(), `stringified value matches`) {
+			return
+		}
+	})
 	t.Run(`accept jwa constant X25519`, func(t *testing.T) {
 		t.Parallel()
 		var dst jwa.EllipticCurveAlgorithm
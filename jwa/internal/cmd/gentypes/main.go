@@ -120,16 +120,48 @@ func _main() error {
 					invalid: true,
 				},
 				{
-					name:  `P256`,
-					value: `P-256`,
+					name:    `P256`,
+					value:   `P-256`,
+					aliases: []string{`secp256r1`, `prime256v1`},
 				},
 				{
-					name:  `P384`,
-					value: `P-384`,
+					name:    `P384`,
+					value:   `P-384`,
+					aliases: []string{`secp384r1`},
 				},
 				{
-					name:  `P521`,
-					value: `P-521`,
+					name:    `P521`,
+					value:   `P-521`,
+					aliases: []string{`secp521r1`},
+				},
+				{
+					name:    `Secp256k1`,
+					value:   `secp256k1`,
+					comment: `SECG secp256k1 (RFC 8812). Keys require the jwx_es256k build tag`,
+				},
+				{
+					name:    `BrainpoolP256r1`,
+					value:   `BP-256`,
+					aliases: []string{`brainpoolP256r1`},
+					comment: `brainpoolP256r1 (RFC 5639). Keys require the jwx_brainpool build tag`,
+				},
+				{
+					name:    `BrainpoolP320r1`,
+					value:   `BP-320`,
+					aliases: []string{`brainpoolP320r1`},
+					comment: `brainpoolP320r1 (RFC 5639). Keys require the jwx_brainpool build tag`,
+				},
+				{
+					name:    `BrainpoolP384r1`,
+					value:   `BP-384`,
+					aliases: []string{`brainpoolP384r1`},
+					comment: `brainpoolP384r1 (RFC 5639). Keys require the jwx_brainpool build tag`,
+				},
+				{
+					name:    `BrainpoolP512r1`,
+					value:   `BP-512`,
+					aliases: []string{`brainpoolP512r1`},
+					comment: `brainpoolP512r1 (RFC 5639). Keys require the jwx_brainpool build tag`,
 				},
 				{
 					name:  `Ed25519`,
@@ -203,6 +235,27 @@ func _main() error {
 					value:   "ES512",
 					comment: `ECDSA using P-521 and SHA-512`,
 				},
+				{
+					name:    `ES256K`,
+					value:   `ES256K`,
+					aliases: []string{`secp256k1`},
+					comment: `ECDSA using secp256k1 and SHA-256 (RFC 8812). jws can not sign or verify using this algorithm`,
+				},
+				{
+					name:    `BP256R1`,
+					value:   `BP256R1`,
+					comment: `ECDSA using brainpoolP256r1 and SHA-256. Verification only. Requires the jwx_brainpool build tag`,
+				},
+				{
+					name:    `BP384R1`,
+					value:   `BP384R1`,
+					comment: `ECDSA using brainpoolP384r1 and SHA-384. Verification only. Requires the jwx_brainpool build tag`,
+				},
+				{
+					name:    `BP512R1`,
+					value:   `BP512R1`,
+					comment: `ECDSA using brainpoolP512r1 and SHA-512. Verification only. Requires the jwx_brainpool build tag`,
+				},
 				{
 					name:    `EdDSA`,
 					value:   `EdDSA`,
@@ -285,6 +338,26 @@ func _main() error {
 					value:   "ECDH-ES+A256KW",
 					comment: `ECDH-ES + AES key wrap (256)`,
 				},
+				{
+					name:    `ECDH_1PU`,
+					value:   "ECDH-1PU",
+					comment: `ECDH-1PU (authenticated key agreement)`,
+				},
+				{
+					name:    `ECDH_1PU_A128KW`,
+					value:   "ECDH-1PU+A128KW",
+					comment: `ECDH-1PU + AES key wrap (128)`,
+				},
+				{
+					name:    `ECDH_1PU_A192KW`,
+					value:   "ECDH-1PU+A192KW",
+					comment: `ECDH-1PU + AES key wrap (192)`,
+				},
+				{
+					name:    `ECDH_1PU_A256KW`,
+					value:   "ECDH-1PU+A256KW",
+					comment: `ECDH-1PU + AES key wrap (256)`,
+				},
 				{
 					name:    `A128GCMKW`,
 					value:   "A128GCMKW",
@@ -350,6 +423,7 @@ type element struct {
 	value   string
 	comment string
 	invalid bool
+	aliases []string // accepted in addition to value by Parse* functions when lenient
 }
 
 var isSymmetricKeyEncryption = map[string]struct{}{
@@ -375,6 +449,7 @@ func (t typ) Generate() error {
 	fmt.Fprintf(&buf, "\n\nimport (")
 	pkgs := []string{
 		"fmt",
+		"strings",
 		"github.com/pkg/errors",
 	}
 	for _, pkg := range pkgs {
@@ -451,6 +526,56 @@ func (t typ) Generate() error {
 	fmt.Fprintf(&buf, "\nreturn nil")
 	fmt.Fprintf(&buf, "\n}") // func (v *%s) Accept(v interface{})
 
+	fmt.Fprintf(&buf, "\n\n// Parse%[1]s converts `s` to a %[1]s. Like Accept, it only", t.name)
+	fmt.Fprintf(&buf, "\n// accepts the exact values defined in the specifications, unless")
+	fmt.Fprintf(&buf, "\n// `jwa.WithLenient(true)` is specified")
+	fmt.Fprintf(&buf, "\nfunc Parse%[1]s(s string, options ...ParseOption) (%[1]s, error) {", t.name)
+	fmt.Fprintf(&buf, "\ntmp := %s(s)", t.name)
+	fmt.Fprintf(&buf, "\nif isLenient(options) {")
+	fmt.Fprintf(&buf, "\ntmp = lenient%s(tmp)", t.name)
+	fmt.Fprintf(&buf, "\n}")
+	fmt.Fprintf(&buf, "\n\nvar v %s", t.name)
+	fmt.Fprintf(&buf, "\nif err := v.Accept(tmp); err != nil {")
+	fmt.Fprintf(&buf, "\nreturn \"\", err")
+	fmt.Fprintf(&buf, "\n}")
+	fmt.Fprintf(&buf, "\nreturn v, nil")
+	fmt.Fprintf(&buf, "\n}")
+
+	var hasAliases bool
+	for _, e := range t.elements {
+		if len(e.aliases) > 0 {
+			hasAliases = true
+			break
+		}
+	}
+
+	lcName := strings.ToLower(t.name[:1]) + t.name[1:]
+	if hasAliases {
+		fmt.Fprintf(&buf, "\n\nvar %sAliases = map[string]%s{", lcName, t.name)
+		for _, e := range t.elements {
+			for _, alias := range e.aliases {
+				fmt.Fprintf(&buf, "\n%s: %s,", strconv.Quote(strings.ToLower(alias)), e.name)
+			}
+		}
+		fmt.Fprintf(&buf, "\n}")
+	}
+
+	fmt.Fprintf(&buf, "\n\n// lenient%[1]s converts case variants and aliases of", t.name)
+	fmt.Fprintf(&buf, "\n// known %s values to their canonical form", t.name)
+	fmt.Fprintf(&buf, "\nfunc lenient%[1]s(v %[1]s) %[1]s {", t.name)
+	fmt.Fprintf(&buf, "\nfor _, known := range all%ss {", t.name)
+	fmt.Fprintf(&buf, "\nif strings.EqualFold(string(known), string(v)) {")
+	fmt.Fprintf(&buf, "\nreturn known")
+	fmt.Fprintf(&buf, "\n}")
+	fmt.Fprintf(&buf, "\n}")
+	if hasAliases {
+		fmt.Fprintf(&buf, "\nif known, ok := %sAliases[strings.ToLower(string(v))]; ok {", lcName)
+		fmt.Fprintf(&buf, "\nreturn known")
+		fmt.Fprintf(&buf, "\n}")
+	}
+	fmt.Fprintf(&buf, "\nreturn v")
+	fmt.Fprintf(&buf, "\n}")
+
 	fmt.Fprintf(&buf, "\n\n// String returns the string representation of a %s", t.name)
 	fmt.Fprintf(&buf, "\nfunc (v %s) String() string {", t.name)
 	fmt.Fprintf(&buf, "\nreturn string(v)")
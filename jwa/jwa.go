@@ -2,3 +2,35 @@
 
 // Package jwa defines the various algorithm described in https://tools.ietf.org/html/rfc7518
 package jwa
+
+import "github.com/lestrrat-go/option"
+
+// ParseOption is a type of option that can be passed to the Parse*
+// functions in this package, such as `jwa.ParseSignatureAlgorithm()`
+type ParseOption = option.Interface
+
+type identLenient struct{}
+
+// WithLenient specifies that the Parse* functions in this package should
+// also accept values that only differ from the values defined in the
+// specifications in letter case (e.g. "hs256"), as well as well known
+// aliases (e.g. "secp256r1" for "P-256"). Such values are converted to
+// their canonical form.
+//
+// This is useful when the values come from configuration files written
+// by humans. Values read from JWS, JWE and JWK payloads are always checked
+// using the Accept method, which is strict.
+func WithLenient(v bool) ParseOption {
+	return option.New(identLenient{}, v)
+}
+
+func isLenient(options []ParseOption) bool {
+	var lenient bool
+	for _, option := range options {
+		switch option.Ident() {
+		case identLenient{}:
+			lenient = option.Value().(bool)
+		}
+	}
+	return lenient
+}
@@ -1,5 +1,12 @@
 package jwa_test
 
+import (
+	"testing"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/stretchr/testify/assert"
+)
+
 type stringer struct {
 	src string
 }
@@ -7,3 +14,75 @@ type stringer struct {
 func (s stringer) String() string {
 	return s.src
 }
+
+func TestLenient(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Accept is strict", func(t *testing.T) {
+		t.Parallel()
+		var alg jwa.SignatureAlgorithm
+		if !assert.Error(t, alg.Accept("hs256"), `accept should fail`) {
+			return
+		}
+		var crv jwa.EllipticCurveAlgorithm
+		if !assert.Error(t, crv.Accept("secp256r1"), `accept should fail`) {
+			return
+		}
+	})
+	t.Run("Parse is strict by default", func(t *testing.T) {
+		t.Parallel()
+		if _, err := jwa.ParseSignatureAlgorithm("hs256"); !assert.Error(t, err, `parse should fail`) {
+			return
+		}
+		alg, err := jwa.ParseSignatureAlgorithm("HS256")
+		if !assert.NoError(t, err, `parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HS256, alg, `values should match`) {
+			return
+		}
+	})
+	t.Run("Parse with WithLenient(true)", func(t *testing.T) {
+		t.Parallel()
+		alg, err := jwa.ParseSignatureAlgorithm("hs256", jwa.WithLenient(true))
+		if !assert.NoError(t, err, `parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HS256, alg, `value should be canonicalized`) {
+			return
+		}
+
+		kty, err := jwa.ParseKeyType("OCT", jwa.WithLenient(true))
+		if !assert.NoError(t, err, `parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, jwa.OctetSeq, kty, `value should be canonicalized`) {
+			return
+		}
+
+		crv, err := jwa.ParseEllipticCurveAlgorithm("Prime256v1", jwa.WithLenient(true))
+		if !assert.NoError(t, err, `parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, jwa.P256, crv, `alias should be resolved`) {
+			return
+		}
+
+		for _, name := range []string{"es256k", "secp256k1"} {
+			alg, err := jwa.ParseSignatureAlgorithm(name, jwa.WithLenient(true))
+			if !assert.NoError(t, err, `parse should succeed`) {
+				return
+			}
+			if !assert.Equal(t, jwa.ES256K, alg, `%s should be resolved`, name) {
+				return
+			}
+		}
+		if _, err := jwa.ParseSignatureAlgorithm("secp256k1"); !assert.Error(t, err, `aliases should only be resolved when lenient`) {
+			return
+		}
+
+		if _, err := jwa.ParseKeyEncryptionAlgorithm("RSA-OAEP-512", jwa.WithLenient(true)); !assert.Error(t, err, `parse should fail for unknown values`) {
+			return
+		}
+	})
+}
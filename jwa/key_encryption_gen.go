@@ -4,6 +4,7 @@ package jwa
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -20,6 +21,10 @@ const (
 	A256GCMKW          KeyEncryptionAlgorithm = "A256GCMKW"          // AES-GCM key wrap (256)
 	A256KW             KeyEncryptionAlgorithm = "A256KW"             // AES key wrap (256)
 	DIRECT             KeyEncryptionAlgorithm = "dir"                // Direct encryption
+	ECDH_1PU           KeyEncryptionAlgorithm = "ECDH-1PU"           // ECDH-1PU (authenticated key agreement)
+	ECDH_1PU_A128KW    KeyEncryptionAlgorithm = "ECDH-1PU+A128KW"    // ECDH-1PU + AES key wrap (128)
+	ECDH_1PU_A192KW    KeyEncryptionAlgorithm = "ECDH-1PU+A192KW"    // ECDH-1PU + AES key wrap (192)
+	ECDH_1PU_A256KW    KeyEncryptionAlgorithm = "ECDH-1PU+A256KW"    // ECDH-1PU + AES key wrap (256)
 	ECDH_ES            KeyEncryptionAlgorithm = "ECDH-ES"            // ECDH-ES
 	ECDH_ES_A128KW     KeyEncryptionAlgorithm = "ECDH-ES+A128KW"     // ECDH-ES + AES key wrap (128)
 	ECDH_ES_A192KW     KeyEncryptionAlgorithm = "ECDH-ES+A192KW"     // ECDH-ES + AES key wrap (192)
@@ -40,6 +45,10 @@ var allKeyEncryptionAlgorithms = []KeyEncryptionAlgorithm{
 	A256GCMKW,
 	A256KW,
 	DIRECT,
+	ECDH_1PU,
+	ECDH_1PU_A128KW,
+	ECDH_1PU_A192KW,
+	ECDH_1PU_A256KW,
 	ECDH_ES,
 	ECDH_ES_A128KW,
 	ECDH_ES_A192KW,
@@ -76,7 +85,7 @@ func (v *KeyEncryptionAlgorithm) Accept(value interface{}) error {
 		tmp = KeyEncryptionAlgorithm(s)
 	}
 	switch tmp {
-	case A128GCMKW, A128KW, A192GCMKW, A192KW, A256GCMKW, A256KW, DIRECT, ECDH_ES, ECDH_ES_A128KW, ECDH_ES_A192KW, ECDH_ES_A256KW, PBES2_HS256_A128KW, PBES2_HS384_A192KW, PBES2_HS512_A256KW, RSA1_5, RSA_OAEP, RSA_OAEP_256:
+	case A128GCMKW, A128KW, A192GCMKW, A192KW, A256GCMKW, A256KW, DIRECT, ECDH_1PU, ECDH_1PU_A128KW, ECDH_1PU_A192KW, ECDH_1PU_A256KW, ECDH_ES, ECDH_ES_A128KW, ECDH_ES_A192KW, ECDH_ES_A256KW, PBES2_HS256_A128KW, PBES2_HS384_A192KW, PBES2_HS512_A256KW, RSA1_5, RSA_OAEP, RSA_OAEP_256:
 	default:
 		return errors.Errorf(`invalid jwa.KeyEncryptionAlgorithm value`)
 	}
@@ -85,6 +94,33 @@ func (v *KeyEncryptionAlgorithm) Accept(value interface{}) error {
 	return nil
 }
 
+// ParseKeyEncryptionAlgorithm converts `s` to a KeyEncryptionAlgorithm. Like Accept, it only
+// accepts the exact values defined in the specifications, unless
+// `jwa.WithLenient(true)` is specified
+func ParseKeyEncryptionAlgorithm(s string, options ...ParseOption) (KeyEncryptionAlgorithm, error) {
+	tmp := KeyEncryptionAlgorithm(s)
+	if isLenient(options) {
+		tmp = lenientKeyEncryptionAlgorithm(tmp)
+	}
+
+	var v KeyEncryptionAlgorithm
+	if err := v.Accept(tmp); err != nil {
+		return "", err
+	}
+	return v, nil
+}
+
+// lenientKeyEncryptionAlgorithm converts case variants and aliases of
+// known KeyEncryptionAlgorithm values to their canonical form
+func lenientKeyEncryptionAlgorithm(v KeyEncryptionAlgorithm) KeyEncryptionAlgorithm {
+	for _, known := range allKeyEncryptionAlgorithms {
+		if strings.EqualFold(string(known), string(v)) {
+			return known
+		}
+	}
+	return v
+}
+
 // String returns the string representation of a KeyEncryptionAlgorithm
 func (v KeyEncryptionAlgorithm) String() string {
 	return string(v)
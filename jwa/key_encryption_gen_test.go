@@ -263,6 +263,150 @@ func TestKeyEncryptionAlgorithm(t *testing.T) {
 			return
 		}
 	})
+	t.Run(`accept jwa constant ECDH_1PU`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.ECDH_1PU), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string ECDH-1PU`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("ECDH-1PU"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for ECDH-1PU`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "ECDH-1PU"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for ECDH-1PU`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "ECDH-1PU", jwa.ECDH_1PU.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant ECDH_1PU_A128KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.ECDH_1PU_A128KW), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A128KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string ECDH-1PU+A128KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("ECDH-1PU+A128KW"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A128KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for ECDH-1PU+A128KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "ECDH-1PU+A128KW"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A128KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for ECDH-1PU+A128KW`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "ECDH-1PU+A128KW", jwa.ECDH_1PU_A128KW.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant ECDH_1PU_A192KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.ECDH_1PU_A192KW), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A192KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string ECDH-1PU+A192KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("ECDH-1PU+A192KW"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A192KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for ECDH-1PU+A192KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "ECDH-1PU+A192KW"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A192KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for ECDH-1PU+A192KW`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "ECDH-1PU+A192KW", jwa.ECDH_1PU_A192KW.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant ECDH_1PU_A256KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.ECDH_1PU_A256KW), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A256KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string ECDH-1PU+A256KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("ECDH-1PU+A256KW"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A256KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for ECDH-1PU+A256KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "ECDH-1PU+A256KW"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A256KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for ECDH-1PU+A256KW`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "ECDH-1PU+A256KW", jwa.ECDH_1PU_A256KW.String(), `stringified value matches`) {
+			return
+		}
+	})
 	t.Run(`accept jwa constant ECDH_ES`, func(t *testing.T) {
 		t.Parallel()
 		var dst jwa.KeyEncryptionAlgorithm
@@ -660,6 +804,18 @@ func TestKeyEncryptionAlgorithm(t *testing.T) {
 		t.Run(`DIRECT`, func(t *testing.T) {
 			assert.True(t, jwa.DIRECT.IsSymmetric(), `jwa.DIRECT should be symmetric`)
 		})
+		t.Run(`ECDH_1PU`, func(t *testing.T) {
+			assert.False(t, jwa.ECDH_1PU.IsSymmetric(), `jwa.ECDH_1PU should NOT be symmetric`)
+		})
+		t.Run(`ECDH_1PU_A128KW`, func(t *testing.T) {
+			assert.False(t, jwa.ECDH_1PU_A128KW.IsSymmetric(), `jwa.ECDH_1PU_A128KW should NOT be symmetric`)
+		})
+		t.Run(`ECDH_1PU_A192KW`, func(t *testing.T) {
+			assert.False(t, jwa.ECDH_1PU_A192KW.IsSymmetric(), `jwa.ECDH_1PU_A192KW should NOT be symmetric`)
+		})
+		t.Run(`ECDH_1PU_A256KW`, func(t *testing.T) {
+			assert.False(t, jwa.ECDH_1PU_A256KW.IsSymmetric(), `jwa.ECDH_1PU_A256KW should NOT be symmetric`)
+		})
 		t.Run(`ECDH_ES`, func(t *testing.T) {
 			assert.False(t, jwa.ECDH_ES.IsSymmetric(), `jwa.ECDH_ES should NOT be symmetric`)
 		})
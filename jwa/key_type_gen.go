@@ -4,6 +4,7 @@ package jwa
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -60,6 +61,33 @@ func (v *KeyType) Accept(value interface{}) error {
 	return nil
 }
 
+// ParseKeyType converts `s` to a KeyType. Like Accept, it only
+// accepts the exact values defined in the specifications, unless
+// `jwa.WithLenient(true)` is specified
+func ParseKeyType(s string, options ...ParseOption) (KeyType, error) {
+	tmp := KeyType(s)
+	if isLenient(options) {
+		tmp = lenientKeyType(tmp)
+	}
+
+	var v KeyType
+	if err := v.Accept(tmp); err != nil {
+		return "", err
+	}
+	return v, nil
+}
+
+// lenientKeyType converts case variants and aliases of
+// known KeyType values to their canonical form
+func lenientKeyType(v KeyType) KeyType {
+	for _, known := range allKeyTypes {
+		if strings.EqualFold(string(known), string(v)) {
+			return known
+		}
+	}
+	return v
+}
+
 // String returns the string representation of a KeyType
 func (v KeyType) String() string {
 	return string(v)
@@ -4,6 +4,7 @@ package jwa
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -13,13 +14,17 @@ type SignatureAlgorithm string
 
 // Supported values for SignatureAlgorithm
 const (
-	ES256       SignatureAlgorithm = "ES256" // ECDSA using P-256 and SHA-256
-	ES384       SignatureAlgorithm = "ES384" // ECDSA using P-384 and SHA-384
-	ES512       SignatureAlgorithm = "ES512" // ECDSA using P-521 and SHA-512
-	EdDSA       SignatureAlgorithm = "EdDSA" // EdDSA signature algorithms
-	HS256       SignatureAlgorithm = "HS256" // HMAC using SHA-256
-	HS384       SignatureAlgorithm = "HS384" // HMAC using SHA-384
-	HS512       SignatureAlgorithm = "HS512" // HMAC using SHA-512
+	BP256R1     SignatureAlgorithm = "BP256R1" // ECDSA using brainpoolP256r1 and SHA-256. Verification only. Requires the jwx_brainpool build tag
+	BP384R1     SignatureAlgorithm = "BP384R1" // ECDSA using brainpoolP384r1 and SHA-384. Verification only. Requires the jwx_brainpool build tag
+	BP512R1     SignatureAlgorithm = "BP512R1" // ECDSA using brainpoolP512r1 and SHA-512. Verification only. Requires the jwx_brainpool build tag
+	ES256       SignatureAlgorithm = "ES256"   // ECDSA using P-256 and SHA-256
+	ES256K      SignatureAlgorithm = "ES256K"  // ECDSA using secp256k1 and SHA-256 (RFC 8812). jws can not sign or verify using this algorithm
+	ES384       SignatureAlgorithm = "ES384"   // ECDSA using P-384 and SHA-384
+	ES512       SignatureAlgorithm = "ES512"   // ECDSA using P-521 and SHA-512
+	EdDSA       SignatureAlgorithm = "EdDSA"   // EdDSA signature algorithms
+	HS256       SignatureAlgorithm = "HS256"   // HMAC using SHA-256
+	HS384       SignatureAlgorithm = "HS384"   // HMAC using SHA-384
+	HS512       SignatureAlgorithm = "HS512"   // HMAC using SHA-512
 	NoSignature SignatureAlgorithm = "none"
 	PS256       SignatureAlgorithm = "PS256" // RSASSA-PSS using SHA256 and MGF1-SHA256
 	PS384       SignatureAlgorithm = "PS384" // RSASSA-PSS using SHA384 and MGF1-SHA384
@@ -30,7 +35,11 @@ const (
 )
 
 var allSignatureAlgorithms = []SignatureAlgorithm{
+	BP256R1,
+	BP384R1,
+	BP512R1,
 	ES256,
+	ES256K,
 	ES384,
 	ES512,
 	EdDSA,
@@ -70,7 +79,7 @@ func (v *SignatureAlgorithm) Accept(value interface{}) error {
 		tmp = SignatureAlgorithm(s)
 	}
 	switch tmp {
-	case ES256, ES384, ES512, EdDSA, HS256, HS384, HS512, NoSignature, PS256, PS384, PS512, RS256, RS384, RS512:
+	case BP256R1, BP384R1, BP512R1, ES256, ES256K, ES384, ES512, EdDSA, HS256, HS384, HS512, NoSignature, PS256, PS384, PS512, RS256, RS384, RS512:
 	default:
 		return errors.Errorf(`invalid jwa.SignatureAlgorithm value`)
 	}
@@ -79,6 +88,40 @@ func (v *SignatureAlgorithm) Accept(value interface{}) error {
 	return nil
 }
 
+// ParseSignatureAlgorithm converts `s` to a SignatureAlgorithm. Like Accept, it only
+// accepts the exact values defined in the specifications, unless
+// `jwa.WithLenient(true)` is specified
+func ParseSignatureAlgorithm(s string, options ...ParseOption) (SignatureAlgorithm, error) {
+	tmp := SignatureAlgorithm(s)
+	if isLenient(options) {
+		tmp = lenientSignatureAlgorithm(tmp)
+	}
+
+	var v SignatureAlgorithm
+	if err := v.Accept(tmp); err != nil {
+		return "", err
+	}
+	return v, nil
+}
+
+var signatureAlgorithmAliases = map[string]SignatureAlgorithm{
+	"secp256k1": ES256K,
+}
+
+// lenientSignatureAlgorithm converts case variants and aliases of
+// known SignatureAlgorithm values to their canonical form
+func lenientSignatureAlgorithm(v SignatureAlgorithm) SignatureAlgorithm {
+	for _, known := range allSignatureAlgorithms {
+		if strings.EqualFold(string(known), string(v)) {
+			return known
+		}
+	}
+	if known, ok := signatureAlgorithmAliases[strings.ToLower(string(v))]; ok {
+		return known
+	}
+	return v
+}
+
 // String returns the string representation of a SignatureAlgorithm
 func (v SignatureAlgorithm) String() string {
 	return string(v)
@@ -11,6 +11,114 @@ import (
 
 func TestSignatureAlgorithm(t *testing.T) {
 	t.Parallel()
+	t.Run(`accept jwa constant BP256R1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.SignatureAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.BP256R1), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BP256R1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string BP256R1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.SignatureAlgorithm
+		if !assert.NoError(t, dst.Accept("BP256R1"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BP256R1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for BP256R1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.SignatureAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "BP256R1"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BP256R1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for BP256R1`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "BP256R1", jwa.BP256R1.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant BP384R1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.SignatureAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.BP384R1), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BP384R1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string BP384R1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.SignatureAlgorithm
+		if !assert.NoError(t, dst.Accept("BP384R1"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BP384R1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for BP384R1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.SignatureAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "BP384R1"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BP384R1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for BP384R1`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "BP384R1", jwa.BP384R1.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant BP512R1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.SignatureAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.BP512R1), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BP512R1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string BP512R1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.SignatureAlgorithm
+		if !assert.NoError(t, dst.Accept("BP512R1"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BP512R1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for BP512R1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.SignatureAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "BP512R1"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.BP512R1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for BP512R1`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "BP512R1", jwa.BP512R1.String(), `stringified value matches`) {
+			return
+		}
+	})
 	t.Run(`accept jwa constant ES256`, func(t *testing.T) {
 		t.Parallel()
 		var dst jwa.SignatureAlgorithm
@@ -47,6 +155,42 @@ func TestSignatureAlgorithm(t *testing.T) {
 			return
 		}
 	})
+	t.Run(`accept jwa constant ES256K`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.SignatureAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.ES256K), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ES256K, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string ES256K`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.SignatureAlgorithm
+		if !assert.NoError(t, dst.Accept("ES256K"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ES256K, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for ES256K`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.SignatureAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "ES256K"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ES256K, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for ES256K`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "ES256K", jwa.ES256K.String(), `stringified value matches`) {
+			return
+		}
+	})
 	t.Run(`accept jwa constant ES384`, func(t *testing.T) {
 		t.Parallel()
 		var dst jwa.SignatureAlgorithm
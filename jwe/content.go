@@ -0,0 +1,113 @@
+package jwe
+
+import (
+	"strings"
+
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+)
+
+// ContentTypeJWT is the value of the `cty` header that denotes that the
+// payload of a JWE message is a nested JWT (RFC 7519 section 5.2)
+const ContentTypeJWT = "JWT"
+
+// Content is the decrypted payload of a JWE message, along with the
+// content type declared in the protected `cty` header.
+//
+// Consumers that receive different kinds of payloads can dispatch on
+// the content type:
+//
+//	content, err := jwe.DecryptContent(buf, alg, key)
+//	...
+//	switch {
+//	case content.IsJWT():
+//	  token, err := jwt.ParseContent(content, jwt.WithVerify(...))
+//	case content.IsJSON():
+//	  err := content.Unmarshal(&v)
+//	default:
+//	  raw := content.Payload()
+//	}
+type Content struct {
+	contentType string
+	payload     []byte
+}
+
+// DecryptContent is the same as `jwe.Decrypt()`, but also returns the
+// content type of the payload. Only the protected headers are consulted,
+// so that the content type cannot be modified without invalidating the
+// message.
+func DecryptContent(buf []byte, alg jwa.KeyEncryptionAlgorithm, key interface{}, options ...DecryptOption) (*Content, error) {
+	if jwkKey, ok := key.(jwk.Key); ok {
+		var raw interface{}
+		if err := jwkKey.Raw(&raw); err != nil {
+			return nil, errors.Wrapf(err, `failed to retrieve raw key from %T`, key)
+		}
+		key = raw
+	}
+
+	msg, err := Parse(buf, parseOptions(options)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse buffer for DecryptContent")
+	}
+
+	payload, err := msg.Decrypt(alg, key, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Content{
+		contentType: msg.ProtectedHeaders().ContentType(),
+		payload:     payload,
+	}, nil
+}
+
+// ContentType returns the value of the `cty` header, which may be empty
+func (c *Content) ContentType() string {
+	return c.contentType
+}
+
+// Payload returns the decrypted payload
+func (c *Content) Payload() []byte {
+	return c.payload
+}
+
+// IsJWT returns true if the payload is a nested JWT, that is, if the
+// content type is "JWT" or "application/jwt"
+func (c *Content) IsJWT() bool {
+	return strings.EqualFold(mediaSubtype(c.contentType), ContentTypeJWT)
+}
+
+// IsJSON returns true if the content type is "application/json", or
+// a structured syntax type such as "application/jose+json"
+func (c *Content) IsJSON() bool {
+	subtype := strings.ToLower(mediaSubtype(c.contentType))
+	return subtype == "json" || strings.HasSuffix(subtype, "+json")
+}
+
+// Unmarshal decodes the JSON payload into `dst`. An error is returned
+// if the content type does not denote JSON.
+func (c *Content) Unmarshal(dst interface{}) error {
+	if !c.IsJSON() {
+		return errors.Errorf(`content type %q is not JSON`, c.contentType)
+	}
+
+	if err := json.Unmarshal(c.payload, dst); err != nil {
+		return errors.Wrap(err, `failed to unmarshal payload`)
+	}
+	return nil
+}
+
+// mediaSubtype removes parameters and the "application/" prefix from a
+// `cty` value, which RFC 7516 section 4.1.12 allows senders to omit
+func mediaSubtype(v string) string {
+	if i := strings.IndexByte(v, ';'); i >= 0 {
+		v = v[:i]
+	}
+	v = strings.TrimSpace(v)
+	if len(v) > 12 && strings.EqualFold(v[:12], "application/") {
+		v = v[12:]
+	}
+	return v
+}
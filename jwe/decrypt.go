@@ -31,6 +31,7 @@ type Decrypter struct {
 	computedAad []byte
 	ctalg       jwa.ContentEncryptionAlgorithm
 	iv          []byte
+	keycache    *DerivedKeyCache
 	keyalg      jwa.KeyEncryptionAlgorithm
 	keycount    int
 	keyiv       []byte
@@ -38,6 +39,7 @@ type Decrypter struct {
 	keytag      []byte
 	privkey     interface{}
 	pubkey      interface{}
+	senderkey   interface{}
 	tag         []byte
 }
 
@@ -87,6 +89,13 @@ func (d *Decrypter) InitializationVector(iv []byte) *Decrypter {
 	return d
 }
 
+// DerivedKeyCache sets the cache to be used for keys derived via
+// ECDH-ES key agreement
+func (d *Decrypter) DerivedKeyCache(c *DerivedKeyCache) *Decrypter {
+	d.keycache = c
+	return d
+}
+
 func (d *Decrypter) KeyCount(keycount int) *Decrypter {
 	d.keycount = keycount
 	return d
@@ -114,6 +123,14 @@ func (d *Decrypter) PublicKey(pubkey interface{}) *Decrypter {
 	return d
 }
 
+// SenderPublicKey sets the static public key of the sender, which is
+// used in decoding ECDH-1PU based encryptions. The key must be in its
+// "raw" format (i.e. *ecdsa.PublicKey, instead of jwk.Key)
+func (d *Decrypter) SenderPublicKey(pubkey interface{}) *Decrypter {
+	d.senderkey = pubkey
+	return d
+}
+
 func (d *Decrypter) Tag(tag []byte) *Decrypter {
 	d.tag = tag
 	return d
@@ -129,7 +146,7 @@ func (d *Decrypter) ContentCipher() (content_crypt.Cipher, error) {
 			}
 			d.cipher = cipher
 		default:
-			return nil, errors.Errorf(`invalid content cipher algorithm (%s)`, d.ctalg)
+			return nil, unsupported(errors.Errorf(`invalid content cipher algorithm (%s)`, d.ctalg))
 		}
 	}
 
@@ -165,7 +182,12 @@ func (d *Decrypter) Decrypt(recipientKey, ciphertext []byte) (plaintext []byte,
 
 	plaintext, err = cipher.Decrypt(cek, d.iv, ciphertext, d.tag, computedAad)
 	if err != nil {
-		err = errors.Wrap(err, `failed to decrypt payload`)
+		// Do not let the cause of the failure (e.g. invalid padding vs
+		// tag mismatch) leak to the caller
+		if pdebug.Enabled {
+			pdebug.Printf("failed to decrypt payload: %s", err)
+		}
+		err = ErrDecryptFailed
 		return
 	}
 
@@ -213,7 +235,10 @@ func (d *Decrypter) decryptSymmetricKey(recipientKey, cek []byte) ([]byte, error
 
 		jek, err := keyenc.Unwrap(block, recipientKey)
 		if err != nil {
-			return nil, errors.Wrap(err, `failed to unwrap key`)
+			if pdebug.Enabled {
+				pdebug.Printf("failed to unwrap key: %s", err)
+			}
+			return nil, ErrUnwrapFailed
 		}
 
 		if pdebug.Enabled {
@@ -226,10 +251,10 @@ func (d *Decrypter) decryptSymmetricKey(recipientKey, cek []byte) ([]byte, error
 			pdebug.Printf("cek len = %d", len(cek))
 		}
 		if len(d.keyiv) != 12 {
-			return nil, errors.Errorf("GCM requires 96-bit iv, got %d", len(d.keyiv)*8)
+			return nil, malformed(errors.Errorf("GCM requires 96-bit iv, got %d", len(d.keyiv)*8))
 		}
 		if len(d.keytag) != 16 {
-			return nil, errors.Errorf("GCM requires 128-bit tag, got %d", len(d.keytag)*8)
+			return nil, malformed(errors.Errorf("GCM requires 128-bit tag, got %d", len(d.keytag)*8))
 		}
 		block, err := aes.NewCipher(cek)
 		if err != nil {
@@ -243,11 +268,14 @@ func (d *Decrypter) decryptSymmetricKey(recipientKey, cek []byte) ([]byte, error
 		ciphertext = append(ciphertext, d.keytag...)
 		jek, err := aesgcm.Open(nil, d.keyiv, ciphertext, nil)
 		if err != nil {
-			return nil, errors.Wrap(err, `failed to decode key`)
+			if pdebug.Enabled {
+				pdebug.Printf("failed to decode key: %s", err)
+			}
+			return nil, ErrUnwrapFailed
 		}
 		return jek, nil
 	default:
-		return nil, errors.Errorf("decrypt key: unsupported algorithm %s", d.keyalg)
+		return nil, unsupported(errors.Errorf("decrypt key: unsupported algorithm %s", d.keyalg))
 	}
 }
 
@@ -273,7 +301,10 @@ func (d *Decrypter) DecryptKey(recipientKey []byte) (cek []byte, err error) {
 
 	cek, err = k.Decrypt(recipientKey)
 	if err != nil {
-		return nil, errors.Wrap(err, `failed to decrypt key`)
+		if pdebug.Enabled {
+			pdebug.Printf("failed to decrypt key: %s", err)
+		}
+		return nil, ErrUnwrapFailed
 	}
 
 	if pdebug.Enabled {
@@ -312,23 +343,53 @@ func (d *Decrypter) BuildKeyDecrypter() (keyenc.Decrypter, error) {
 
 		return keyenc.NewAES(alg, sharedkey)
 	case jwa.ECDH_ES, jwa.ECDH_ES_A128KW, jwa.ECDH_ES_A192KW, jwa.ECDH_ES_A256KW:
+		var kd *keyenc.ECDHESDecrypt
+		switch d.pubkey.(type) {
+		case x25519.PublicKey:
+			kd = keyenc.NewECDHESDecrypt(alg, d.ctalg, d.pubkey, d.apu, d.apv, d.privkey)
+		default:
+			var pubkey ecdsa.PublicKey
+			if err := keyconv.ECDSAPublicKey(&pubkey, d.pubkey); err != nil {
+				return nil, errors.Wrapf(err, "*ecdsa.PublicKey is required as the key to build %s key decrypter", alg)
+			}
+
+			var privkey ecdsa.PrivateKey
+			if err := keyconv.ECDSAPrivateKey(&privkey, d.privkey); err != nil {
+				return nil, errors.Wrapf(err, "*ecdsa.PrivateKey is required as the key to build %s key decrypter", alg)
+			}
+
+			kd = keyenc.NewECDHESDecrypt(alg, d.ctalg, &pubkey, d.apu, d.apv, &privkey)
+		}
+		if d.keycache != nil {
+			kd.SetKeyCache(d.keycache)
+		}
+		return kd, nil
+	case jwa.ECDH_1PU, jwa.ECDH_1PU_A128KW, jwa.ECDH_1PU_A192KW, jwa.ECDH_1PU_A256KW:
+		if d.senderkey == nil {
+			return nil, errors.Errorf("sender public key is required to build %s key decrypter", alg)
+		}
 		switch d.pubkey.(type) {
 		case x25519.PublicKey:
-			return keyenc.NewECDHESDecrypt(alg, d.ctalg, d.pubkey, d.apu, d.apv, d.privkey), nil
+			return keyenc.NewECDH1PUDecrypt(alg, d.ctalg, d.pubkey, d.senderkey, d.apu, d.apv, d.tag, d.privkey), nil
 		default:
 			var pubkey ecdsa.PublicKey
 			if err := keyconv.ECDSAPublicKey(&pubkey, d.pubkey); err != nil {
 				return nil, errors.Wrapf(err, "*ecdsa.PublicKey is required as the key to build %s key decrypter", alg)
 			}
 
+			var senderkey ecdsa.PublicKey
+			if err := keyconv.ECDSAPublicKey(&senderkey, d.senderkey); err != nil {
+				return nil, errors.Wrapf(err, "*ecdsa.PublicKey is required as the sender key to build %s key decrypter", alg)
+			}
+
 			var privkey ecdsa.PrivateKey
 			if err := keyconv.ECDSAPrivateKey(&privkey, d.privkey); err != nil {
 				return nil, errors.Wrapf(err, "*ecdsa.PrivateKey is required as the key to build %s key decrypter", alg)
 			}
 
-			return keyenc.NewECDHESDecrypt(alg, d.ctalg, &pubkey, d.apu, d.apv, &privkey), nil
+			return keyenc.NewECDH1PUDecrypt(alg, d.ctalg, &pubkey, &senderkey, d.apu, d.apv, d.tag, &privkey), nil
 		}
 	default:
-		return nil, errors.Errorf(`unsupported algorithm for key decryption (%s)`, alg)
+		return nil, unsupported(errors.Errorf(`unsupported algorithm for key decryption (%s)`, alg))
 	}
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/lestrrat-go/jwx/internal/base64"
 	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwe/internal/keyenc"
 	"github.com/lestrrat-go/pdebug/v3"
 	"github.com/pkg/errors"
 )
@@ -25,6 +26,9 @@ func releaseEncryptCtx(ctx *encryptCtx) {
 	ctx.generator = nil
 	ctx.keyEncrypters = nil
 	ctx.compress = jwa.NoCompress
+	ctx.contentType = ""
+	ctx.senderKeyID = ""
+	ctx.monitor = nil
 	encryptCtxPool.Put(ctx)
 }
 
@@ -35,6 +39,10 @@ func (e encryptCtx) Encrypt(plaintext []byte) (*Message, error) {
 		defer g.End()
 	}
 
+	if err := checkRandomness(e.monitor); err != nil {
+		return nil, errors.Wrap(err, `randomness self-check failed`)
+	}
+
 	bk, err := e.generator.Generate()
 	if err != nil {
 		if pdebug.Enabled {
@@ -43,6 +51,7 @@ func (e encryptCtx) Encrypt(plaintext []byte) (*Message, error) {
 		return nil, errors.Wrap(err, "failed to generate key")
 	}
 	cek := bk.Bytes()
+	reusedKey := false // true if the CEK is the shared key of "dir"
 
 	if pdebug.Enabled {
 		pdebug.Printf("Encrypt: generated cek len = %d", len(cek))
@@ -53,6 +62,18 @@ func (e encryptCtx) Encrypt(plaintext []byte) (*Message, error) {
 		return nil, errors.Wrap(err, `failed to set "enc" in protected header`)
 	}
 
+	if e.contentType != "" {
+		if err := protected.Set(ContentTypeKey, e.contentType); err != nil {
+			return nil, errors.Wrap(err, `failed to set "cty" in protected header`)
+		}
+	}
+
+	if e.senderKeyID != "" {
+		if err := protected.Set(SenderKeyIDKey, e.senderKeyID); err != nil {
+			return nil, errors.Wrap(err, `failed to set "skid" in protected header`)
+		}
+	}
+
 	compression := e.compress
 	if compression != jwa.NoCompress {
 		if err := protected.Set(CompressionKey, compression); err != nil {
@@ -82,11 +103,15 @@ func (e encryptCtx) Encrypt(plaintext []byte) (*Message, error) {
 			}
 			return nil, errors.Wrap(err, `failed to encrypt key`)
 		}
-		if enc.Algorithm() == jwa.ECDH_ES || enc.Algorithm() == jwa.DIRECT {
+		if alg := enc.Algorithm(); alg == jwa.ECDH_ES || alg == jwa.ECDH_1PU || alg == jwa.DIRECT {
 			if len(e.keyEncrypters) > 1 {
-				return nil, errors.Errorf("unable to support multiple recipients for ECDH-ES")
+				return nil, errors.Errorf("unable to support multiple recipients for %s", alg)
 			}
 			cek = enckey.Bytes()
+			reusedKey = alg == jwa.DIRECT
+		} else if _, ok := tagBound(enc); ok {
+			// The encrypted key is computed after the content has
+			// been encrypted, as it depends on the authentication tag
 		} else {
 			if err := r.SetEncryptedKey(enckey.Bytes()); err != nil {
 				return nil, errors.Wrap(err, "failed to set encrypted key")
@@ -132,6 +157,30 @@ func (e encryptCtx) Encrypt(plaintext []byte) (*Message, error) {
 		return nil, errors.Wrap(err, "failed to encrypt payload")
 	}
 
+	for i, enc := range e.keyEncrypters {
+		tb, ok := tagBound(enc)
+		if !ok {
+			continue
+		}
+		enckey, err := tb.WrapWithTag(cek, tag)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to encrypt key`)
+		}
+		if err := recipients[i].SetEncryptedKey(enckey); err != nil {
+			return nil, errors.Wrap(err, "failed to set encrypted key")
+		}
+	}
+
+	if e.monitor != nil {
+		monitored := cek
+		if reusedKey {
+			monitored = nil
+		}
+		if err := e.monitor.record(monitored, iv); err != nil {
+			return nil, errors.Wrap(err, `randomness self-check failed`)
+		}
+	}
+
 	if pdebug.Enabled {
 		pdebug.Printf("Encrypt.Encrypt: cek        = %x (%d)", cek, len(cek))
 		pdebug.Printf("Encrypt.Encrypt: aad        = %x (%d)", aad, len(aad))
@@ -167,3 +216,17 @@ func (e encryptCtx) Encrypt(plaintext []byte) (*Message, error) {
 
 	return msg, nil
 }
+
+// tagBound returns the encrypter as a keyenc.TagBoundEncrypter, if the
+// key wrapping depends on the authentication tag
+func tagBound(enc keyenc.Encrypter) (keyenc.TagBoundEncrypter, bool) {
+	if enc.Algorithm() == jwa.ECDH_1PU {
+		// Direct key agreement does not wrap the key
+		return nil, false
+	}
+	if w, ok := enc.(*keyIDEncrypter); ok {
+		enc = w.Encrypter
+	}
+	tb, ok := enc.(keyenc.TagBoundEncrypter)
+	return tb, ok
+}
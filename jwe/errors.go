@@ -0,0 +1,72 @@
+package jwe
+
+import (
+	"github.com/pkg/errors"
+)
+
+// The following errors categorize the failures of parsing, decrypting
+// and encrypting JWE messages. Errors returned from this package can be
+// tested against them using `errors.Is()`, so that callers can decide
+// whether it makes sense to retry the operation (e.g. with a different
+// key) or fall back to something else.
+//
+// In order to avoid creating a padding or decryption oracle, failures of
+// the key unwrapping and content decryption steps are reported using
+// ErrUnwrapFailed and ErrDecryptFailed themselves, without the details
+// of the underlying failure: for example, an authentication tag mismatch
+// and an invalid padding both result in the same error message. The
+// other errors are never returned as is, and carry the message of the
+// underlying failure.
+var (
+	// ErrUnwrapFailed is the category of errors returned when the content
+	// encryption key could not be unwrapped (decrypted) using the given key
+	ErrUnwrapFailed = errors.New(`failed to unwrap content encryption key`)
+
+	// ErrDecryptFailed is the category of errors returned when the content
+	// could not be decrypted, which includes authentication tag mismatches
+	ErrDecryptFailed = errors.New(`failed to decrypt content`)
+
+	// ErrMalformed is the category of errors returned when the message,
+	// or one of its headers, is not in a valid format
+	ErrMalformed = errors.New(`malformed JWE message`)
+
+	// ErrUnsupportedAlgorithm is the category of errors returned when the
+	// key encryption or content encryption algorithm is not supported
+	ErrUnsupportedAlgorithm = errors.New(`unsupported algorithm`)
+
+	// ErrRandomnessFailure is the category of errors returned when a
+	// `jwe.RandomnessMonitor` detects repeated random values
+	ErrRandomnessFailure = errors.New(`random number generator failure`)
+)
+
+// categoryError attaches one of the error categories to an error,
+// without changing its message
+type categoryError struct {
+	category error
+	err      error
+}
+
+func (e *categoryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *categoryError) Unwrap() error {
+	return e.err
+}
+
+func (e *categoryError) Is(target error) bool {
+	return e.category == target
+}
+
+func malformed(err error) error {
+	return &categoryError{category: ErrMalformed, err: err}
+}
+
+func isMalformed(err error) bool {
+	cerr, ok := err.(*categoryError)
+	return ok && cerr.category == ErrMalformed
+}
+
+func unsupported(err error) error {
+	return &categoryError{category: ErrUnsupportedAlgorithm, err: err}
+}
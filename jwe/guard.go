@@ -0,0 +1,116 @@
+package jwe
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/pkg/errors"
+)
+
+// DefaultGCMInvocationLimit is the maximum number of messages that
+// should be encrypted with a single AES-GCM key when random 96 bit
+// IVs are used, as recommended by NIST SP 800-38D section 8.3.
+// Beyond this limit the probability of an IV collision, which
+// completely breaks the security of AES-GCM, becomes unacceptable.
+const DefaultGCMInvocationLimit uint64 = 1 << 32
+
+// KeyUsageGuard counts the number of messages encrypted with each
+// content encryption key when the "dir" key management algorithm is used
+// along with AES-GCM (jwa.A128GCM, jwa.A192GCM, jwa.A256GCM).
+//
+// With "dir", the same key is used for every message, and because
+// IVs are chosen at random, the probability of reusing an IV grows
+// with each message (the birthday bound). Once the limit is reached,
+// `jwe.Encrypt()` refuses to use the key, and the key should be rotated.
+//
+// Pass the guard to `jwe.Encrypt()` using the `jwe.WithKeyUsageGuard()`
+// option. Keys are identified by their SHA-256 digest, so the guard does
+// not retain the keys themselves. Counters are kept in memory only: if
+// the same key is used by multiple processes, the limit must be lowered
+// accordingly. KeyUsageGuard is safe for concurrent use.
+type KeyUsageGuard struct {
+	mu        sync.Mutex
+	limit     uint64
+	threshold uint64
+	warn      func(uint64)
+	counts    map[[sha256.Size]byte]uint64
+}
+
+// NewKeyUsageGuard creates a new KeyUsageGuard that allows at most
+// `limit` messages to be encrypted with each key. If `limit` is 0,
+// `jwe.DefaultGCMInvocationLimit` is used.
+func NewKeyUsageGuard(limit uint64) *KeyUsageGuard {
+	if limit == 0 {
+		limit = DefaultGCMInvocationLimit
+	}
+	return &KeyUsageGuard{
+		limit:  limit,
+		counts: make(map[[sha256.Size]byte]uint64),
+	}
+}
+
+// SetWarningHandler specifies a function that is called when the usage
+// of a key reaches `threshold`, so that applications can rotate keys
+// before the hard limit is reached. The function is called once per key,
+// with the current usage count, while the guard is locked: it should
+// return quickly, and must not call methods on the guard.
+func (g *KeyUsageGuard) SetWarningHandler(threshold uint64, fn func(usage uint64)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.threshold = threshold
+	g.warn = fn
+}
+
+// Limit returns the maximum number of messages that may be encrypted
+// with each key
+func (g *KeyUsageGuard) Limit() uint64 {
+	return g.limit
+}
+
+// Usage returns the number of messages that have been encrypted with the key
+func (g *KeyUsageGuard) Usage(key []byte) uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.counts[sha256.Sum256(key)]
+}
+
+// Reset clears the usage counter for the key
+func (g *KeyUsageGuard) Reset(key []byte) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.counts, sha256.Sum256(key))
+}
+
+// acquire records one more use of the key, or returns an error if
+// the key has reached its limit
+func (g *KeyUsageGuard) acquire(key []byte) error {
+	digest := sha256.Sum256(key)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	count := g.counts[digest]
+	if count >= g.limit {
+		return errors.Errorf(`key has been used to encrypt %d messages, which is the maximum allowed. rotate the key`, count)
+	}
+	count++
+	g.counts[digest] = count
+
+	if g.warn != nil && count == g.threshold {
+		g.warn(count)
+	}
+	return nil
+}
+
+func guardedByKeyUsage(keyalg jwa.KeyEncryptionAlgorithm, contentalg jwa.ContentEncryptionAlgorithm) bool {
+	if keyalg != jwa.DIRECT {
+		return false
+	}
+	switch contentalg {
+	case jwa.A128GCM, jwa.A192GCM, jwa.A256GCM:
+		return true
+	default:
+		return false
+	}
+}
@@ -44,6 +44,9 @@ type encryptCtx struct {
 	generator        keygen.Generator
 	keyEncrypters    []keyenc.Encrypter
 	compress         jwa.CompressionAlgorithm
+	contentType      string
+	senderKeyID      string
+	monitor          *RandomnessMonitor
 }
 
 // populater is an interface for things that may modify the
@@ -1,9 +1,13 @@
 package cipher_test
 
 import (
+	"crypto/aes"
+	stdcipher "crypto/cipher"
+	"crypto/rand"
 	"testing"
 
 	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwe/internal/aescbc"
 	"github.com/lestrrat-go/jwx/jwe/internal/cipher"
 	"github.com/stretchr/testify/assert"
 )
@@ -25,3 +29,69 @@ func TestAES(t *testing.T) {
 		t.Logf("keysize = %d", c.KeySize())
 	}
 }
+
+func TestRetag(t *testing.T) {
+	algs := []jwa.ContentEncryptionAlgorithm{
+		jwa.A128GCM,
+		jwa.A192GCM,
+		jwa.A256GCM,
+		jwa.A128CBC_HS256,
+		jwa.A192CBC_HS384,
+		jwa.A256CBC_HS512,
+	}
+	for _, alg := range algs {
+		alg := alg
+		t.Run(alg.String(), func(t *testing.T) {
+			t.Parallel()
+			c, err := cipher.NewAES(alg)
+			if !assert.NoError(t, err, `cipher.NewAES should succeed`) {
+				return
+			}
+
+			cek := make([]byte, c.KeySize())
+			_, _ = rand.Read(cek)
+
+			var aead stdcipher.AEAD
+			switch alg {
+			case jwa.A128GCM, jwa.A192GCM, jwa.A256GCM:
+				block, err := aes.NewCipher(cek)
+				if !assert.NoError(t, err, `aes.NewCipher should succeed`) {
+					return
+				}
+				aead, err = stdcipher.NewGCM(block)
+				if !assert.NoError(t, err, `cipher.NewGCM should succeed`) {
+					return
+				}
+			default:
+				aead, err = aescbc.New(cek, aes.NewCipher)
+				if !assert.NoError(t, err, `aescbc.New should succeed`) {
+					return
+				}
+			}
+
+			iv := make([]byte, aead.NonceSize())
+			_, _ = rand.Read(iv)
+			plaintext := []byte(`Lorem ipsum dolor sit amet, consectetur adipiscing elit`)
+			aad := []byte(`eyJhbGciOiJSU0EtT0FFUCIsImVuYyI6IkEyNTZHQ00ifQ`)
+			newAAD := []byte(`eyJlbmMiOiJBMjU2R0NNIn0`)
+
+			sealed := aead.Seal(nil, iv, plaintext, aad)
+			resealed := aead.Seal(nil, iv, plaintext, newAAD)
+			tagoffset := len(sealed) - c.TagSize()
+			ciphertext := sealed[:tagoffset]
+
+			tag, err := c.Retag(cek, iv, ciphertext, sealed[tagoffset:], aad, newAAD)
+			if !assert.NoError(t, err, `Retag should succeed`) {
+				return
+			}
+			if !assert.Equal(t, resealed[tagoffset:], tag, `tag should match the one computed over the new aad`) {
+				return
+			}
+
+			_, err = c.Retag(cek, iv, ciphertext, sealed[tagoffset:], newAAD, aad)
+			if !assert.Error(t, err, `Retag should fail for the wrong aad`) {
+				return
+			}
+		})
+	}
+}
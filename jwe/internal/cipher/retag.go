@@ -0,0 +1,121 @@
+package cipher
+
+import (
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/binary"
+
+	"github.com/lestrrat-go/jwx/jwe/internal/aescbc"
+	"github.com/pkg/errors"
+)
+
+// Retag verifies the authentication tag computed over the ciphertext and
+// the additional authenticated data `aad`, and returns the tag that
+// authenticates the same ciphertext under `newAAD`.
+//
+// The content is never decrypted: for AES-CBC-HMAC-SHA2 the tag is
+// simply the (truncated) HMAC, while for AES-GCM the tag is obtained
+// by re-computing GHASH over the new additional authenticated data.
+func (c AesContentCipher) Retag(cek, iv, ciphertext, tag, aad, newAAD []byte) ([]byte, error) {
+	if c.fetch == gcm {
+		return retagGCM(cek, iv, ciphertext, tag, aad, newAAD)
+	}
+
+	aead, err := aescbc.New(cek, aes.NewCipher)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create AES-CBC-HMAC cipher`)
+	}
+
+	expected, err := aead.ComputeAuthTag(aad, iv, ciphertext)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to compute authentication tag`)
+	}
+	if !hmac.Equal(expected, tag) {
+		return nil, errors.New(`authentication tag mismatch`)
+	}
+	return aead.ComputeAuthTag(newAAD, iv, ciphertext)
+}
+
+func retagGCM(cek, iv, ciphertext, tag, aad, newAAD []byte) ([]byte, error) {
+	if len(iv) != 12 {
+		return nil, errors.Errorf(`invalid initialization vector size %d for AES-GCM`, len(iv))
+	}
+	if len(tag) != TagSize {
+		return nil, errors.Errorf(`invalid tag size %d for AES-GCM`, len(tag))
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create AES cipher for GCM`)
+	}
+
+	var h, mask [16]byte
+	block.Encrypt(h[:], h[:])
+
+	// mask = E(K, J0), where J0 = IV || 0^31 || 1
+	var j0 [16]byte
+	copy(j0[:], iv)
+	j0[15] = 1
+	block.Encrypt(mask[:], j0[:])
+
+	expected := ghash(h, aad, ciphertext)
+	for i := range expected {
+		expected[i] ^= mask[i]
+	}
+	if subtle.ConstantTimeCompare(expected[:], tag) != 1 {
+		return nil, errors.New(`authentication tag mismatch`)
+	}
+
+	computed := ghash(h, newAAD, ciphertext)
+	for i := range computed {
+		computed[i] ^= mask[i]
+	}
+	return computed[:], nil
+}
+
+// ghash computes GHASH as defined in NIST SP 800-38D, section 6.4
+func ghash(h [16]byte, aad, ciphertext []byte) [16]byte {
+	hk := [2]uint64{binary.BigEndian.Uint64(h[:8]), binary.BigEndian.Uint64(h[8:])}
+
+	var y [2]uint64
+	update := func(data []byte) {
+		for len(data) > 0 {
+			var block [16]byte
+			n := copy(block[:], data)
+			data = data[n:]
+			y[0] ^= binary.BigEndian.Uint64(block[:8])
+			y[1] ^= binary.BigEndian.Uint64(block[8:])
+			y = gfmul(y, hk)
+		}
+	}
+	update(aad)
+	update(ciphertext)
+
+	y[0] ^= uint64(len(aad)) * 8
+	y[1] ^= uint64(len(ciphertext)) * 8
+	y = gfmul(y, hk)
+
+	var ret [16]byte
+	binary.BigEndian.PutUint64(ret[:8], y[0])
+	binary.BigEndian.PutUint64(ret[8:], y[1])
+	return ret
+}
+
+// gfmul multiplies x and y in GF(2^128), using the bit ordering
+// defined for GCM. It does not branch on secret data.
+func gfmul(x, y [2]uint64) [2]uint64 {
+	var z [2]uint64
+	v := y
+	for i := 0; i < 128; i++ {
+		bit := (x[i/64] >> (63 - uint(i%64))) & 1
+		mask := -bit
+		z[0] ^= v[0] & mask
+		z[1] ^= v[1] & mask
+
+		lsb := v[1] & 1
+		v[1] = v[1]>>1 | v[0]<<63
+		v[0] = v[0]>>1 ^ (0xe100000000000000 & -lsb)
+	}
+	return z
+}
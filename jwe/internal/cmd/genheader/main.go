@@ -148,6 +148,13 @@ func generateHeaders() error {
 			key:    `kid`,
 			//			comment: `https://tools.ietf.org/html/rfc7515#section-4.1.4`,
 		},
+		{
+			name:   `senderKeyID`,
+			method: `SenderKeyID`,
+			typ:    `string`,
+			key:    `skid`,
+			//			comment: `https://tools.ietf.org/html/draft-madden-jose-ecdh-1pu-04#section-2.2.1`,
+		},
 		{
 			name:   `typ`,
 			method: `Type`,
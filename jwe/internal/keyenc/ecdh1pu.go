@@ -0,0 +1,262 @@
+package keyenc
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/binary"
+
+	"github.com/lestrrat-go/jwx/internal/ecutil"
+	"github.com/lestrrat-go/jwx/jwa"
+	contentcipher "github.com/lestrrat-go/jwx/jwe/internal/cipher"
+	"github.com/lestrrat-go/jwx/jwe/internal/concatkdf"
+	"github.com/lestrrat-go/jwx/jwe/internal/keygen"
+	"github.com/lestrrat-go/jwx/x25519"
+	"github.com/pkg/errors"
+)
+
+// ECDH-1PU is implemented as described in
+// https://tools.ietf.org/html/draft-madden-jose-ecdh-1pu-04
+
+// ecdh1puKeySize returns the size of the key that is derived for the
+// given algorithms, and checks that they can be used together
+func ecdh1puKeySize(alg jwa.KeyEncryptionAlgorithm, enc jwa.ContentEncryptionAlgorithm) (int, error) {
+	switch alg {
+	case jwa.ECDH_1PU:
+		c, err := contentcipher.NewAES(enc)
+		if err != nil {
+			return 0, errors.Wrapf(err, `failed to create content cipher for %s`, enc)
+		}
+		return c.KeySize(), nil
+	case jwa.ECDH_1PU_A128KW, jwa.ECDH_1PU_A192KW, jwa.ECDH_1PU_A256KW:
+		// The key wrapping variants bind the authentication tag of the
+		// content encryption to the key agreement, which is only secure
+		// for AES_CBC_HMAC_SHA2 (draft-madden-jose-ecdh-1pu-04 section 2.1)
+		switch enc {
+		case jwa.A128CBC_HS256, jwa.A192CBC_HS384, jwa.A256CBC_HS512:
+		default:
+			return 0, errors.Errorf(`%s requires an AES_CBC_HMAC_SHA2 content encryption algorithm, got %s`, alg, enc)
+		}
+		switch alg {
+		case jwa.ECDH_1PU_A128KW:
+			return 16, nil
+		case jwa.ECDH_1PU_A192KW:
+			return 24, nil
+		default:
+			return 32, nil
+		}
+	default:
+		return 0, errors.Errorf(`invalid ECDH-1PU key wrap algorithm (%s)`, alg)
+	}
+}
+
+// NewECDH1PUEncrypt creates a new key encrypter using ECDH-1PU. `sender`
+// is the static private key of the sender, and `recipient` is the public
+// key of the recipient. Both must be either ECDSA keys on the same
+// curve, or X25519 keys.
+func NewECDH1PUEncrypt(alg jwa.KeyEncryptionAlgorithm, enc jwa.ContentEncryptionAlgorithm, sender, recipient interface{}) (*ECDH1PUEncrypt, error) {
+	keysize, err := ecdh1puKeySize(alg, enc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch recipient.(type) {
+	case *ecdsa.PublicKey:
+		if _, ok := sender.(*ecdsa.PrivateKey); !ok {
+			return nil, errors.Errorf(`sender key must be *ecdsa.PrivateKey, was: %T`, sender)
+		}
+	case x25519.PublicKey:
+		if _, ok := sender.(x25519.PrivateKey); !ok {
+			return nil, errors.Errorf(`sender key must be x25519.PrivateKey, was: %T`, sender)
+		}
+	default:
+		return nil, errors.Errorf(`unexpected key type %T`, recipient)
+	}
+
+	return &ECDH1PUEncrypt{
+		algorithm: alg,
+		enc:       enc,
+		keysize:   keysize,
+		sender:    sender,
+		recipient: recipient,
+	}, nil
+}
+
+// Algorithm returns the key encryption algorithm being used
+func (kw *ECDH1PUEncrypt) Algorithm() jwa.KeyEncryptionAlgorithm {
+	return kw.algorithm
+}
+
+// KeyID returns the key ID associated with this encrypter
+func (kw *ECDH1PUEncrypt) KeyID() string {
+	return kw.keyID
+}
+
+// Encrypt performs the key agreement using a newly generated ephemeral
+// key. For jwa.ECDH_1PU the derived key is returned, and is used as the
+// content encryption key. For the key wrapping variants the key is
+// wrapped later by WrapWithTag, and the returned key is empty.
+func (kw *ECDH1PUEncrypt) Encrypt(_ []byte) (keygen.ByteSource, error) {
+	var ephemeral, ephemeralPub interface{}
+	switch recipient := kw.recipient.(type) {
+	case *ecdsa.PublicKey:
+		if ecutil.IsVariableTime(recipient.Curve) {
+			return nil, errors.Errorf(`ECDH-1PU using keys on %s is not supported`, recipient.Curve.Params().Name)
+		}
+		priv, err := ecdsa.GenerateKey(recipient.Curve, rand.Reader)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to generate key for ECDH-1PU`)
+		}
+		ephemeral = priv
+		ephemeralPub = &priv.PublicKey
+	case x25519.PublicKey:
+		pub, priv, err := x25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to generate key for ECDH-1PU`)
+		}
+		ephemeral = priv
+		ephemeralPub = pub
+	}
+
+	z, err := deriveZ1PU(ephemeral, kw.recipient, kw.sender, kw.recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	if kw.algorithm != jwa.ECDH_1PU {
+		kw.z = z
+		return keygen.ByteWithECPublicKey{PublicKey: ephemeralPub}, nil
+	}
+
+	key, err := DeriveECDH1PU([]byte(kw.enc.String()), nil, nil, z, nil, uint32(kw.keysize))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to derive ECDH-1PU key`)
+	}
+	return keygen.ByteWithECPublicKey{
+		PublicKey: ephemeralPub,
+		ByteKey:   keygen.ByteKey(key),
+	}, nil
+}
+
+// WrapWithTag wraps the content encryption key using the key derived
+// from the key agreement performed by Encrypt and the authentication
+// tag of the content encryption.
+func (kw *ECDH1PUEncrypt) WrapWithTag(cek, tag []byte) ([]byte, error) {
+	if kw.z == nil {
+		return nil, errors.New(`key agreement has not been performed`)
+	}
+
+	kek, err := DeriveECDH1PU([]byte(kw.algorithm.String()), nil, nil, kw.z, tag, uint32(kw.keysize))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to derive ECDH-1PU key`)
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to generate cipher from derived key`)
+	}
+
+	jek, err := Wrap(block, cek)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to wrap data`)
+	}
+	return jek, nil
+}
+
+// NewECDH1PUDecrypt creates a new key decrypter using ECDH-1PU. `pubkey`
+// is the ephemeral public key ("epk"), `sender` is the static public key
+// of the sender, and `tag` is the authentication tag of the content
+// encryption, which is only used by the key wrapping variants.
+func NewECDH1PUDecrypt(keyalg jwa.KeyEncryptionAlgorithm, contentalg jwa.ContentEncryptionAlgorithm, pubkey, sender interface{}, apu, apv, tag []byte, privkey interface{}) *ECDH1PUDecrypt {
+	return &ECDH1PUDecrypt{
+		keyalg:     keyalg,
+		contentalg: contentalg,
+		apu:        apu,
+		apv:        apv,
+		tag:        tag,
+		privkey:    privkey,
+		pubkey:     pubkey,
+		sender:     sender,
+	}
+}
+
+// Algorithm returns the key encryption algorithm being used
+func (kw ECDH1PUDecrypt) Algorithm() jwa.KeyEncryptionAlgorithm {
+	return kw.keyalg
+}
+
+// Decrypt decrypts the encrypted key using ECDH-1PU. The sender is
+// authenticated by the fact that the key can be derived at all: a
+// message that was not created using the sender's private key fails
+// to decrypt.
+func (kw ECDH1PUDecrypt) Decrypt(enckey []byte) ([]byte, error) {
+	keysize, err := ecdh1puKeySize(kw.keyalg, kw.contentalg)
+	if err != nil {
+		return nil, err
+	}
+
+	z, err := deriveZ1PU(kw.privkey, kw.pubkey, kw.privkey, kw.sender)
+	if err != nil {
+		return nil, err
+	}
+
+	if kw.keyalg == jwa.ECDH_1PU {
+		key, err := DeriveECDH1PU([]byte(kw.contentalg.String()), kw.apu, kw.apv, z, nil, uint32(keysize))
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to derive ECDH-1PU key`)
+		}
+		return key, nil
+	}
+
+	if len(kw.tag) == 0 {
+		return nil, errors.New(`authentication tag is required for ECDH-1PU key wrap`)
+	}
+	kek, err := DeriveECDH1PU([]byte(kw.keyalg.String()), kw.apu, kw.apv, z, kw.tag, uint32(keysize))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to derive ECDH-1PU key`)
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create cipher for ECDH-1PU key wrap`)
+	}
+	return Unwrap(block, enckey)
+}
+
+// deriveZ1PU computes the shared secret Z = Ze || Zs of ECDH-1PU, where
+// Ze is the result of the key agreement between the ephemeral key and
+// the recipient's key, and Zs between the sender's and the recipient's
+// static keys
+func deriveZ1PU(epriv, epub, spriv, spub interface{}) ([]byte, error) {
+	ze, err := DeriveZ(epriv, epub)
+	if err != nil {
+		return nil, errors.Wrap(err, `unable to determine Ze`)
+	}
+	zs, err := DeriveZ(spriv, spub)
+	if err != nil {
+		return nil, errors.Wrap(err, `unable to determine Zs`)
+	}
+	return append(ze, zs...), nil
+}
+
+// DeriveECDH1PU derives a key from the shared secret `z` using the Concat
+// KDF. If `tag` is not empty, it is appended to the SuppPubInfo, as
+// required by the key wrapping variants of ECDH-1PU.
+func DeriveECDH1PU(alg, apu, apv, z, tag []byte, keysize uint32) ([]byte, error) {
+	pubinfo := make([]byte, 4, 8+len(tag))
+	binary.BigEndian.PutUint32(pubinfo, keysize*8)
+	if len(tag) > 0 {
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(tag)))
+		pubinfo = append(pubinfo, l[:]...)
+		pubinfo = append(pubinfo, tag...)
+	}
+
+	kdf := concatkdf.New(crypto.SHA256, alg, z, apu, apv, pubinfo, []byte{})
+	key := make([]byte, keysize)
+	if _, err := kdf.Read(key); err != nil {
+		return nil, errors.Wrap(err, `failed to read kdf`)
+	}
+	return key, nil
+}
@@ -60,6 +60,48 @@ type ECDHESDecrypt struct {
 	apv        []byte
 	privkey    interface{}
 	pubkey     interface{}
+	cache      KeyCache
+}
+
+// TagBoundEncrypter is an Encrypter whose key wrapping depends on the
+// authentication tag of the content encryption, as is the case for the
+// key wrapping variants of ECDH-1PU. Encrypt only performs the key
+// agreement, and the encrypted key is computed by WrapWithTag once the
+// content has been encrypted.
+type TagBoundEncrypter interface {
+	Encrypter
+	WrapWithTag(cek, tag []byte) ([]byte, error)
+}
+
+// ECDH1PUEncrypt encrypts content encryption keys using ECDH-1PU.
+type ECDH1PUEncrypt struct {
+	algorithm jwa.KeyEncryptionAlgorithm
+	enc       jwa.ContentEncryptionAlgorithm
+	keysize   int
+	sender    interface{}
+	recipient interface{}
+	z         []byte
+	keyID     string
+}
+
+// ECDH1PUDecrypt decrypts keys using ECDH-1PU.
+type ECDH1PUDecrypt struct {
+	keyalg     jwa.KeyEncryptionAlgorithm
+	contentalg jwa.ContentEncryptionAlgorithm
+	apu        []byte
+	apv        []byte
+	tag        []byte
+	privkey    interface{}
+	pubkey     interface{}
+	sender     interface{}
+}
+
+// KeyCache is used to memoize keys derived via ECDH-ES key agreement.
+// The cache keys are opaque strings that identify all of the inputs
+// that were used to derive the key.
+type KeyCache interface {
+	Get(string) ([]byte, bool)
+	Set(string, []byte)
 }
 
 // RSAOAEPEncrypt encrypts keys using RSA OAEP algorithm
@@ -5,12 +5,12 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
-	"crypto/subtle"
 	"encoding/binary"
 	"fmt"
 	"hash"
@@ -20,6 +20,7 @@ import (
 	"golang.org/x/crypto/pbkdf2"
 
 	"github.com/lestrrat-go/jwx/internal/ecutil"
+	"github.com/lestrrat-go/jwx/internal/keywrap"
 	"github.com/lestrrat-go/jwx/jwa"
 	contentcipher "github.com/lestrrat-go/jwx/jwe/internal/cipher"
 	"github.com/lestrrat-go/jwx/jwe/internal/concatkdf"
@@ -269,6 +270,13 @@ func NewECDHESDecrypt(keyalg jwa.KeyEncryptionAlgorithm, contentalg jwa.ContentE
 	}
 }
 
+// SetKeyCache sets the cache that is consulted before performing the
+// key agreement. Derived keys are stored in the cache after a successful
+// derivation
+func (kw *ECDHESDecrypt) SetKeyCache(c KeyCache) {
+	kw.cache = c
+}
+
 // Algorithm returns the key encryption algorithm being used
 func (kw ECDHESDecrypt) Algorithm() jwa.KeyEncryptionAlgorithm {
 	return kw.keyalg
@@ -298,6 +306,9 @@ func DeriveZ(privkeyif interface{}, pubkeyif interface{}) ([]byte, error) {
 		if !privkey.PublicKey.Curve.IsOnCurve(pubkey.X, pubkey.Y) {
 			return nil, errors.New(`public key must be on the same curve as private key`)
 		}
+		if ecutil.IsVariableTime(privkey.Curve) {
+			return nil, errors.Errorf(`key agreement using keys on %s is not supported`, privkey.Curve.Params().Name)
+		}
 
 		z, _ := privkey.PublicKey.Curve.ScalarMult(pubkey.X, pubkey.Y, privkey.D.Bytes())
 		zBytes := ecutil.AllocECPointBuffer(z, privkey.Curve)
@@ -329,6 +340,67 @@ func DeriveECDHES(alg, apu, apv []byte, privkey interface{}, pubkey interface{},
 	return key, nil
 }
 
+func (kw ECDHESDecrypt) deriveKey(algBytes []byte, keysize uint32) ([]byte, error) {
+	if kw.cache == nil {
+		return DeriveECDHES(algBytes, kw.apu, kw.apv, kw.privkey, kw.pubkey, keysize)
+	}
+
+	cacheKey, err := ecdhesCacheKey(algBytes, kw.apu, kw.apv, kw.privkey, kw.pubkey, keysize)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to compute cache key`)
+	}
+
+	if key, ok := kw.cache.Get(cacheKey); ok {
+		if pdebug.Enabled {
+			pdebug.Printf("Using cached ECDH-ES derived key")
+		}
+		return key, nil
+	}
+
+	key, err := DeriveECDHES(algBytes, kw.apu, kw.apv, kw.privkey, kw.pubkey, keysize)
+	if err != nil {
+		return nil, err
+	}
+	kw.cache.Set(cacheKey, key)
+	return key, nil
+}
+
+// ecdhesCacheKey creates a digest of all the inputs to the ECDH-ES key
+// derivation. Only the public portion of the static private key is used.
+func ecdhesCacheKey(alg, apu, apv []byte, privkeyif, pubkeyif interface{}, keysize uint32) (string, error) {
+	var static, ephemeral []byte
+	switch privkey := privkeyif.(type) {
+	case x25519.PrivateKey:
+		pubkey, ok := pubkeyif.(x25519.PublicKey)
+		if !ok {
+			return "", errors.Errorf(`public key must be x25519.PublicKey, was: %T`, pubkeyif)
+		}
+		static = privkey.Public().(x25519.PublicKey)
+		ephemeral = pubkey
+	case *ecdsa.PrivateKey:
+		pubkey, ok := pubkeyif.(*ecdsa.PublicKey)
+		if !ok {
+			return "", errors.Errorf(`public key must be *ecdsa.PublicKey, was: %T`, pubkeyif)
+		}
+		static = elliptic.Marshal(privkey.Curve, privkey.X, privkey.Y)
+		ephemeral = elliptic.Marshal(pubkey.Curve, pubkey.X, pubkey.Y)
+	default:
+		return "", errors.Errorf(`unsupported private key type %T`, privkeyif)
+	}
+
+	h := sha256.New()
+	for _, v := range [][]byte{alg, apu, apv, static, ephemeral} {
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(v)))
+		h.Write(l[:])
+		h.Write(v)
+	}
+	var ks [4]byte
+	binary.BigEndian.PutUint32(ks[:], keysize)
+	h.Write(ks[:])
+	return string(h.Sum(nil)), nil
+}
+
 // Decrypt decrypts the encrypted key using ECDH-ES
 func (kw ECDHESDecrypt) Decrypt(enckey []byte) ([]byte, error) {
 	if pdebug.Enabled {
@@ -365,7 +437,7 @@ func (kw ECDHESDecrypt) Decrypt(enckey []byte) ([]byte, error) {
 		return nil, errors.Errorf("invalid ECDH-ES key wrap algorithm (%s)", kw.keyalg)
 	}
 
-	key, err := DeriveECDHES(algBytes, kw.apu, kw.apv, kw.privkey, kw.pubkey, keysize)
+	key, err := kw.deriveKey(algBytes, keysize)
 	if err != nil {
 		return nil, errors.Wrap(err, `failed to derive ECDHES encryption key`)
 	}
@@ -571,97 +643,12 @@ func (d DirectDecrypt) Decrypt() ([]byte, error) {
 	return cek, nil
 }
 
-var keywrapDefaultIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
-
-const keywrapChunkLen = 8
-
+// Wrap encrypts the content encryption key using AES key wrap (RFC 3394)
 func Wrap(kek cipher.Block, cek []byte) ([]byte, error) {
-	if len(cek)%8 != 0 {
-		return nil, errors.New(`keywrap input must be 8 byte blocks`)
-	}
-
-	n := len(cek) / keywrapChunkLen
-	r := make([][]byte, n)
-
-	for i := 0; i < n; i++ {
-		r[i] = make([]byte, keywrapChunkLen)
-		copy(r[i], cek[i*keywrapChunkLen:])
-	}
-
-	buffer := make([]byte, keywrapChunkLen*2)
-	tBytes := make([]byte, keywrapChunkLen)
-	copy(buffer, keywrapDefaultIV)
-
-	for t := 0; t < 6*n; t++ {
-		copy(buffer[keywrapChunkLen:], r[t%n])
-
-		kek.Encrypt(buffer, buffer)
-
-		binary.BigEndian.PutUint64(tBytes, uint64(t+1))
-
-		for i := 0; i < keywrapChunkLen; i++ {
-			buffer[i] = buffer[i] ^ tBytes[i]
-		}
-		copy(r[t%n], buffer[keywrapChunkLen:])
-	}
-
-	out := make([]byte, (n+1)*keywrapChunkLen)
-	copy(out, buffer[:keywrapChunkLen])
-	for i := range r {
-		copy(out[(i+1)*8:], r[i])
-	}
-
-	return out, nil
+	return keywrap.Wrap(kek, cek)
 }
 
+// Unwrap decrypts a key encrypted using AES key wrap (RFC 3394)
 func Unwrap(block cipher.Block, ciphertxt []byte) ([]byte, error) {
-	if pdebug.Enabled {
-		g := pdebug.FuncMarker()
-		defer g.End()
-	}
-
-	if len(ciphertxt)%keywrapChunkLen != 0 {
-		return nil, errors.Errorf(`keyunwrap input must be %d byte blocks`, keywrapChunkLen)
-	}
-
-	n := (len(ciphertxt) / keywrapChunkLen) - 1
-	r := make([][]byte, n)
-
-	for i := range r {
-		r[i] = make([]byte, keywrapChunkLen)
-		copy(r[i], ciphertxt[(i+1)*keywrapChunkLen:])
-	}
-
-	buffer := make([]byte, keywrapChunkLen*2)
-	tBytes := make([]byte, keywrapChunkLen)
-	copy(buffer[:keywrapChunkLen], ciphertxt[:keywrapChunkLen])
-
-	for t := 6*n - 1; t >= 0; t-- {
-		binary.BigEndian.PutUint64(tBytes, uint64(t+1))
-
-		for i := 0; i < keywrapChunkLen; i++ {
-			buffer[i] = buffer[i] ^ tBytes[i]
-		}
-		copy(buffer[keywrapChunkLen:], r[t%n])
-
-		block.Decrypt(buffer, buffer)
-
-		copy(r[t%n], buffer[keywrapChunkLen:])
-	}
-
-	if subtle.ConstantTimeCompare(buffer[:keywrapChunkLen], keywrapDefaultIV) == 0 {
-		if pdebug.Enabled {
-			pdebug.Printf("buffer prefix does not match default iv")
-			pdebug.Printf("prefix  = %x", buffer[:keywrapChunkLen])
-			pdebug.Printf("default = %x", keywrapDefaultIV)
-		}
-		return nil, errors.New("key unwrap: failed to unwrap key")
-	}
-
-	out := make([]byte, n*keywrapChunkLen)
-	for i := range r {
-		copy(out[i*keywrapChunkLen:], r[i])
-	}
-
-	return out, nil
+	return keywrap.Unwrap(block, ciphertxt)
 }
@@ -128,6 +128,69 @@ func TestDeriveECDHES(t *testing.T) {
 	}
 }
 
+func TestDeriveECDH1PU(t *testing.T) {
+	// Example from draft-madden-jose-ecdh-1pu-04, Appendix A
+	parse := func(t *testing.T, src string) *ecdsa.PrivateKey {
+		key, err := jwk.ParseKey([]byte(src))
+		if !assert.NoError(t, err, `jwk.ParseKey should succeed`) {
+			return nil
+		}
+		var raw ecdsa.PrivateKey
+		if !assert.NoError(t, key.Raw(&raw), `key.Raw should succeed`) {
+			return nil
+		}
+		return &raw
+	}
+
+	aliceKey := parse(t, `{"kty":"EC",
+      "crv":"P-256",
+      "x":"WKn-ZIGevcwGIyyrzFoZNBdaq9_TsqzGl96oc0CWuis",
+      "y":"y77t-RvAHRKTsSGdIYUfweuOvwrvDD-Q3Hv5J0fSKbE",
+      "d":"Hndv7ZZjs_ke8o9zXYo3iq-Yr8SewI5vrqd0pAvEPqg"
+     }`)
+	bobKey := parse(t, `{"kty":"EC",
+      "crv":"P-256",
+      "x":"weNJy2HscCSM6AEDTDg04biOvhFhyyWvOHQfeF_PxMQ",
+      "y":"e8lnCO-AlStT-NJVX-crhB7QRYhiix03illJOVAOyck",
+      "d":"VEmDZpDXXK8p8N0Cndsxs924q6nS1RXFASRl6BfUqdw"
+     }`)
+	ephemeralKey := parse(t, `{"kty":"EC",
+      "crv":"P-256",
+      "x":"gI0GAILBdu7T53akrFmMyGcsF3n5dO7MmwNBHKW5SV0",
+      "y":"SLW_xSffzlPWrHEVI30DHM_4egVwt3NQqeUD7nMFpps",
+      "d":"0_NxaRPUMQoAJt50Gz8YiTr8gRTwyEaCumd-MToTmIo"
+     }`)
+	if aliceKey == nil || bobKey == nil || ephemeralKey == nil {
+		return
+	}
+
+	// Z = Ze || Zs, as computed by the recipient
+	ze, err := keyenc.DeriveZ(bobKey, &ephemeralKey.PublicKey)
+	if !assert.NoError(t, err, `keyenc.DeriveZ should succeed`) {
+		return
+	}
+	zs, err := keyenc.DeriveZ(bobKey, &aliceKey.PublicKey)
+	if !assert.NoError(t, err, `keyenc.DeriveZ should succeed`) {
+		return
+	}
+	if !assert.Equal(t, mustHexDecode("9e56d91d817135d372834283bf84269cfb316ea3da806a48f6daa7798cfe90c4"), ze, `Ze should match`) {
+		return
+	}
+	if !assert.Equal(t, mustHexDecode("e3ca3474384c9f62b30bfd4c688b3e7d4110a1b4badc3cc54ef7b81241efd50d"), zs, `Zs should match`) {
+		return
+	}
+
+	output, err := keyenc.DeriveECDH1PU([]byte("A256GCM"), []byte("Alice"), []byte("Bob"), append(ze, zs...), nil, 32)
+	if !assert.NoError(t, err, `keyenc.DeriveECDH1PU should succeed`) {
+		return
+	}
+
+	expected := mustHexDecode("6caf13723d14850ad4b42cd6dde935bffd2fff00a9ba70de05c203a5e1722ca7")
+	if !assert.Equal(t, expected, output, `result should match`) {
+		return
+	}
+}
+
 func TestKeyWrap(t *testing.T) {
 	// stolen from go-jose
 	// Test vectors from: http://csrc.nist.gov/groups/ST/toolkit/documents/kms/key-wrap.pdf
@@ -71,6 +71,10 @@ func (g Ecdhes) Size() int {
 
 // Generate generates new keys using ECDH-ES
 func (g Ecdhes) Generate() (ByteSource, error) {
+	if ecutil.IsVariableTime(g.pubkey.Curve) {
+		return nil, errors.Errorf(`ECDH-ES using keys on %s is not supported`, g.pubkey.Curve.Params().Name)
+	}
+
 	priv, err := ecdsa.GenerateKey(g.pubkey.Curve, rand.Reader)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to generate key for ECDH-ES")
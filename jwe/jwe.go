@@ -27,54 +27,135 @@ import (
 // Encrypt takes the plaintext payload and encrypts it in JWE compact format.
 //
 // `key` should be a public key, and it may be a raw key (e.g. rsa.PublicKey) or a jwk.Key
-func Encrypt(payload []byte, keyalg jwa.KeyEncryptionAlgorithm, key interface{}, contentalg jwa.ContentEncryptionAlgorithm, compressalg jwa.CompressionAlgorithm) ([]byte, error) {
+//
+// The options currently accepted are `jwe.WithKeyUsageGuard()`,
+// `jwe.WithRandomnessMonitor()`, `jwe.WithContentType()`,
+// `jwe.WithKeyID()` and `jwe.WithSenderKey()`
+func Encrypt(payload []byte, keyalg jwa.KeyEncryptionAlgorithm, key interface{}, contentalg jwa.ContentEncryptionAlgorithm, compressalg jwa.CompressionAlgorithm, options ...EncryptOption) ([]byte, error) {
 	if pdebug.Enabled {
 		g := pdebug.FuncMarker()
 		defer g.End()
 	}
 
+	var guard *KeyUsageGuard
+	var monitor *RandomnessMonitor
+	var contentType string
+	var kid string
+	var sender interface{}
+	for _, option := range options {
+		switch option.Ident() {
+		case identKeyUsageGuard{}:
+			guard = option.Value().(*KeyUsageGuard)
+		case identRandomnessMonitor{}:
+			monitor = option.Value().(*RandomnessMonitor)
+		case identContentType{}:
+			contentType = option.Value().(string)
+		case identKeyID{}:
+			kid = option.Value().(string)
+		case identSenderKey{}:
+			sender = option.Value()
+		}
+	}
+
+	return encrypt(payload, keyalg, key, contentalg, compressalg, contentType, kid, sender, guard, monitor)
+}
+
+func encrypt(payload []byte, keyalg jwa.KeyEncryptionAlgorithm, key interface{}, contentalg jwa.ContentEncryptionAlgorithm, compressalg jwa.CompressionAlgorithm, contentType, kid string, sender interface{}, guard *KeyUsageGuard, monitor *RandomnessMonitor) ([]byte, error) {
+
 	contentcrypt, err := content_crypt.NewGeneric(contentalg)
 	if err != nil {
-		return nil, errors.Wrap(err, `failed to create AES encrypter`)
+		return nil, unsupported(errors.Wrap(err, `failed to create AES encrypter`))
+	}
+
+	var skid string
+	if jwkKey, ok := sender.(jwk.Key); ok {
+		var raw interface{}
+		if err := jwkKey.Raw(&raw); err != nil {
+			return nil, errors.Wrapf(err, `failed to retrieve raw key out of %T`, sender)
+		}
+
+		sender = raw
+		skid = jwkKey.KeyID()
+	}
+
+	enc, _, err := newKeyEncrypter(keyalg, key, contentalg, contentcrypt.KeySize(), sender, guard)
+	if err != nil {
+		return nil, err
+	}
+	if kid != "" {
+		enc = &keyIDEncrypter{Encrypter: enc, kid: kid}
+	}
+
+	keysize := contentcrypt.KeySize()
+	if pdebug.Enabled {
+		pdebug.Printf("Encrypt: keysize = %d", keysize)
+	}
+	encctx := getEncryptCtx()
+	defer releaseEncryptCtx(encctx)
+
+	encctx.contentEncrypter = contentcrypt
+	encctx.generator = keygen.NewRandom(keysize)
+	encctx.keyEncrypters = []keyenc.Encrypter{enc}
+	encctx.compress = compressalg
+	encctx.contentType = contentType
+	encctx.senderKeyID = skid
+	encctx.monitor = monitor
+	msg, err := encctx.Encrypt(payload)
+	if err != nil {
+		if pdebug.Enabled {
+			pdebug.Printf("Encrypt: failed to encrypt: %s", err)
+		}
+		return nil, errors.Wrap(err, "failed to encrypt payload")
 	}
 
+	return Compact(msg)
+}
+
+// newKeyEncrypter creates the keyenc.Encrypter that encrypts the CEK
+// for the recipient identified by key, and returns it along with the
+// key ID of key, if it is a jwk.Key. cekSize is the size of the CEK
+// that the content encryption algorithm requires.
+func newKeyEncrypter(keyalg jwa.KeyEncryptionAlgorithm, key interface{}, contentalg jwa.ContentEncryptionAlgorithm, cekSize int, sender interface{}, guard *KeyUsageGuard) (keyenc.Encrypter, string, error) {
+	var kid string
 	if jwkKey, ok := key.(jwk.Key); ok {
 		var raw interface{}
 		if err := jwkKey.Raw(&raw); err != nil {
-			return nil, errors.Wrapf(err, `failed to retrieve raw key out of %T`, key)
+			return nil, "", errors.Wrapf(err, `failed to retrieve raw key out of %T`, key)
 		}
 
 		key = raw
+		kid = jwkKey.KeyID()
 	}
 
+	var err error
 	var enc keyenc.Encrypter
 	switch keyalg {
 	case jwa.RSA1_5:
 		var pubkey rsa.PublicKey
 		if err := keyconv.RSAPublicKey(&pubkey, key); err != nil {
-			return nil, errors.Wrapf(err, "failed to generate public key from key (%T)", key)
+			return nil, "", errors.Wrapf(err, "failed to generate public key from key (%T)", key)
 		}
 
 		enc, err = keyenc.NewRSAPKCSEncrypt(keyalg, &pubkey)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to create RSA PKCS encrypter")
+			return nil, "", errors.Wrap(err, "failed to create RSA PKCS encrypter")
 		}
 	case jwa.RSA_OAEP, jwa.RSA_OAEP_256:
 		var pubkey rsa.PublicKey
 		if err := keyconv.RSAPublicKey(&pubkey, key); err != nil {
-			return nil, errors.Wrapf(err, "failed to generate public key from key (%T)", key)
+			return nil, "", errors.Wrapf(err, "failed to generate public key from key (%T)", key)
 		}
 
 		enc, err = keyenc.NewRSAOAEPEncrypt(keyalg, &pubkey)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to create RSA OAEP encrypter")
+			return nil, "", errors.Wrap(err, "failed to create RSA OAEP encrypter")
 		}
 	case jwa.A128KW, jwa.A192KW, jwa.A256KW,
 		jwa.A128GCMKW, jwa.A192GCMKW, jwa.A256GCMKW,
 		jwa.PBES2_HS256_A128KW, jwa.PBES2_HS384_A192KW, jwa.PBES2_HS512_A256KW:
 		sharedkey, ok := key.([]byte)
 		if !ok {
-			return nil, errors.New("invalid key: []byte required")
+			return nil, "", errors.New("invalid key: []byte required")
 		}
 		switch keyalg {
 		case jwa.A128KW, jwa.A192KW, jwa.A256KW:
@@ -85,7 +166,7 @@ func Encrypt(payload []byte, keyalg jwa.KeyEncryptionAlgorithm, key interface{},
 			enc, err = keyenc.NewAESGCMEncrypt(keyalg, sharedkey)
 		}
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to create key wrap encrypter")
+			return nil, "", errors.Wrap(err, "failed to create key wrap encrypter")
 		}
 		// NOTE: there was formerly a restriction, introduced
 		// in PR #26, which disallowed certain key/content
@@ -98,7 +179,7 @@ func Encrypt(payload []byte, keyalg jwa.KeyEncryptionAlgorithm, key interface{},
 			// https://tools.ietf.org/html/rfc7518#page-15
 			// In Direct Key Agreement mode, the output of the Concat KDF MUST be a
 			// key of the same length as that used by the "enc" algorithm.
-			keysize = contentcrypt.KeySize()
+			keysize = cekSize
 		case jwa.ECDH_ES_A128KW:
 			keysize = 16
 		case jwa.ECDH_ES_A192KW:
@@ -113,46 +194,63 @@ func Encrypt(payload []byte, keyalg jwa.KeyEncryptionAlgorithm, key interface{},
 		default:
 			var pubkey ecdsa.PublicKey
 			if err := keyconv.ECDSAPublicKey(&pubkey, key); err != nil {
-				return nil, errors.Wrapf(err, "failed to generate public key from key (%T)", key)
+				return nil, "", errors.Wrapf(err, "failed to generate public key from key (%T)", key)
 			}
 			enc, err = keyenc.NewECDHESEncrypt(keyalg, contentalg, keysize, &pubkey)
 		}
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to create ECDHS key wrap encrypter")
+			return nil, "", errors.Wrap(err, "failed to create ECDHS key wrap encrypter")
+		}
+	case jwa.ECDH_1PU, jwa.ECDH_1PU_A128KW, jwa.ECDH_1PU_A192KW, jwa.ECDH_1PU_A256KW:
+		if sender == nil {
+			return nil, "", errors.Errorf(`sender key is required for %s (see jwe.WithSenderKey)`, keyalg)
+		}
+
+		switch key := key.(type) {
+		case x25519.PublicKey:
+			enc, err = keyenc.NewECDH1PUEncrypt(keyalg, contentalg, sender, key)
+		default:
+			var pubkey ecdsa.PublicKey
+			if err := keyconv.ECDSAPublicKey(&pubkey, key); err != nil {
+				return nil, "", errors.Wrapf(err, "failed to generate public key from key (%T)", key)
+			}
+			var privkey ecdsa.PrivateKey
+			if err := keyconv.ECDSAPrivateKey(&privkey, sender); err != nil {
+				return nil, "", errors.Wrapf(err, "failed to generate private key from sender key (%T)", sender)
+			}
+			enc, err = keyenc.NewECDH1PUEncrypt(keyalg, contentalg, &privkey, &pubkey)
+		}
+		if err != nil {
+			return nil, "", errors.Wrap(err, "failed to create ECDH-1PU key wrap encrypter")
 		}
 	case jwa.DIRECT:
 		sharedkey, ok := key.([]byte)
 		if !ok {
-			return nil, errors.New("invalid key: []byte required")
+			return nil, "", errors.New("invalid key: []byte required")
+		}
+		if guard != nil && guardedByKeyUsage(keyalg, contentalg) {
+			if err := guard.acquire(sharedkey); err != nil {
+				return nil, "", errors.Wrap(err, `key usage limit reached`)
+			}
 		}
 		enc, _ = keyenc.NewNoop(keyalg, sharedkey)
 	default:
 		if pdebug.Enabled {
 			pdebug.Printf("Encrypt: unknown key encryption algorithm: %s", keyalg)
 		}
-		return nil, errors.Errorf(`invalid key encryption algorithm (%s)`, keyalg)
+		return nil, "", unsupported(errors.Errorf(`invalid key encryption algorithm (%s)`, keyalg))
 	}
 
-	keysize := contentcrypt.KeySize()
-	if pdebug.Enabled {
-		pdebug.Printf("Encrypt: keysize = %d", keysize)
-	}
-	encctx := getEncryptCtx()
-	defer releaseEncryptCtx(encctx)
+	return enc, kid, nil
+}
 
-	encctx.contentEncrypter = contentcrypt
-	encctx.generator = keygen.NewRandom(keysize)
-	encctx.keyEncrypters = []keyenc.Encrypter{enc}
-	encctx.compress = compressalg
-	msg, err := encctx.Encrypt(payload)
-	if err != nil {
-		if pdebug.Enabled {
-			pdebug.Printf("Encrypt: failed to encrypt: %s", err)
-		}
-		return nil, errors.Wrap(err, "failed to encrypt payload")
-	}
+type keyIDEncrypter struct {
+	keyenc.Encrypter
+	kid string
+}
 
-	return Compact(msg)
+func (e *keyIDEncrypter) KeyID() string {
+	return e.kid
 }
 
 // Decrypt takes the key encryption algorithm and the corresponding
@@ -160,7 +258,7 @@ func Encrypt(payload []byte, keyalg jwa.KeyEncryptionAlgorithm, key interface{},
 // The JWE message can be either compact or full JSON format.
 //
 // `key` must be a private key. It can be either in its raw format (e.g. *rsa.PrivateKey) or a jwk.Key
-func Decrypt(buf []byte, alg jwa.KeyEncryptionAlgorithm, key interface{}) ([]byte, error) {
+func Decrypt(buf []byte, alg jwa.KeyEncryptionAlgorithm, key interface{}, options ...DecryptOption) ([]byte, error) {
 	if jwkKey, ok := key.(jwk.Key); ok {
 		var raw interface{}
 		if err := jwkKey.Raw(&raw); err != nil {
@@ -169,20 +267,34 @@ func Decrypt(buf []byte, alg jwa.KeyEncryptionAlgorithm, key interface{}) ([]byt
 		key = raw
 	}
 
-	msg, err := Parse(buf)
+	msg, err := Parse(buf, parseOptions(options)...)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse buffer for Decrypt")
 	}
 
-	return msg.Decrypt(alg, key)
+	return msg.Decrypt(alg, key, options...)
 }
 
 // Parse parses the JWE message into a Message object. The JWE message
 // can be either compact or full JSON format.
-func Parse(buf []byte) (*Message, error) {
+//
+// The only option currently accepted is `jwe.WithMaxMessageSize()`
+func Parse(buf []byte, options ...ParseOption) (*Message, error) {
+	var maxSize int64
+	for _, o := range options {
+		switch o.Ident() {
+		case identMaxMessageSize{}:
+			maxSize = o.Value().(int64)
+		}
+	}
+
+	if maxSize > 0 && int64(len(buf)) > maxSize {
+		return nil, malformed(errors.Errorf(`message exceeds maximum size (%d bytes)`, maxSize))
+	}
+
 	buf = bytes.TrimSpace(buf)
 	if len(buf) == 0 {
-		return nil, errors.New("empty buffer")
+		return nil, malformed(errors.New("empty buffer"))
 	}
 
 	if buf[0] == '{' {
@@ -192,23 +304,36 @@ func Parse(buf []byte) (*Message, error) {
 }
 
 // ParseString is the same as Parse, but takes a string.
-func ParseString(s string) (*Message, error) {
-	return Parse([]byte(s))
+func ParseString(s string, options ...ParseOption) (*Message, error) {
+	return Parse([]byte(s), options...)
 }
 
 // ParseReader is the same as Parse, but takes an io.Reader.
-func ParseReader(src io.Reader) (*Message, error) {
+// If `jwe.WithMaxMessageSize()` is specified, reading stops as soon
+// as the limit is exceeded.
+func ParseReader(src io.Reader, options ...ParseOption) (*Message, error) {
+	for _, o := range options {
+		switch o.Ident() {
+		case identMaxMessageSize{}:
+			if maxSize := o.Value().(int64); maxSize > 0 {
+				// read at most one byte more than the limit, so that
+				// Parse can tell if the message is too large
+				src = io.LimitReader(src, maxSize+1)
+			}
+		}
+	}
+
 	buf, err := ioutil.ReadAll(src)
 	if err != nil {
 		return nil, errors.Wrap(err, `failed to read from io.Reader`)
 	}
-	return Parse(buf)
+	return Parse(buf, options...)
 }
 
 func parseJSON(buf []byte) (*Message, error) {
 	m := NewMessage()
 	if err := json.Unmarshal(buf, &m); err != nil {
-		return nil, errors.Wrap(err, "failed to parse JSON")
+		return nil, malformed(errors.Wrap(err, "failed to parse JSON"))
 	}
 	return m, nil
 }
@@ -219,12 +344,12 @@ func parseCompact(buf []byte) (*Message, error) {
 	}
 	parts := bytes.Split(buf, []byte{'.'})
 	if len(parts) != 5 {
-		return nil, errors.Errorf(`compact JWE format must have five parts (%d)`, len(parts))
+		return nil, malformed(errors.Errorf(`compact JWE format must have five parts (%d)`, len(parts)))
 	}
 
 	hdrbuf, err := base64.Decode(parts[0])
 	if err != nil {
-		return nil, errors.Wrap(err, `failed to parse first part of compact form`)
+		return nil, malformed(errors.Wrap(err, `failed to parse first part of compact form`))
 	}
 	if pdebug.Enabled {
 		pdebug.Printf("hdrbuf = %s", hdrbuf)
@@ -232,22 +357,22 @@ func parseCompact(buf []byte) (*Message, error) {
 
 	protected := NewHeaders()
 	if err := json.Unmarshal(hdrbuf, protected); err != nil {
-		return nil, errors.Wrap(err, "failed to parse header JSON")
+		return nil, malformed(errors.Wrap(err, "failed to parse header JSON"))
 	}
 
 	ivbuf, err := base64.Decode(parts[2])
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to base64 decode iv")
+		return nil, malformed(errors.Wrap(err, "failed to base64 decode iv"))
 	}
 
 	ctbuf, err := base64.Decode(parts[3])
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to base64 decode content")
+		return nil, malformed(errors.Wrap(err, "failed to base64 decode content"))
 	}
 
 	tagbuf, err := base64.Decode(parts[4])
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to base64 decode tag")
+		return nil, malformed(errors.Wrap(err, "failed to base64 decode tag"))
 	}
 
 	m := NewMessage()
@@ -262,7 +387,7 @@ func parseCompact(buf []byte) (*Message, error) {
 	}
 
 	if err := m.makeDummyRecipient(string(parts[1]), protected); err != nil {
-		return nil, errors.Wrap(err, `failed to setup recipient`)
+		return nil, malformed(errors.Wrap(err, `failed to setup recipient`))
 	}
 
 	if err := m.Set(TagKey, tagbuf); err != nil {
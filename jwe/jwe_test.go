@@ -1,16 +1,19 @@
 package jwe_test
 
 import (
+	"context"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/lestrrat-go/jwx/internal/json"
 
@@ -442,6 +445,65 @@ func TestEncode_X25519(t *testing.T) {
 	testEncodeECDHWithKey(t, privkey, pubkey)
 }
 
+func TestDerivedKeyCache(t *testing.T) {
+	t.Parallel()
+
+	privkey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		return
+	}
+
+	t.Run("Decrypt", func(t *testing.T) {
+		t.Parallel()
+		plaintext := []byte("Lorem ipsum")
+		cache := jwe.NewDerivedKeyCache(10, time.Minute)
+		for _, alg := range []jwa.KeyEncryptionAlgorithm{jwa.ECDH_ES, jwa.ECDH_ES_A128KW} {
+			encrypted, err := jwe.Encrypt(plaintext, alg, &privkey.PublicKey, jwa.A128GCM, jwa.NoCompress)
+			if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+				return
+			}
+
+			for i := 0; i < 2; i++ {
+				decrypted, err := jwe.Decrypt(encrypted, alg, privkey, jwe.WithDerivedKeyCache(cache))
+				if !assert.NoError(t, err, `jwe.Decrypt should succeed`) {
+					return
+				}
+				if !assert.Equal(t, plaintext, decrypted, `decrypted payload should match`) {
+					return
+				}
+			}
+		}
+		if !assert.Equal(t, 2, cache.Len(), `cache should contain one entry per message`) {
+			return
+		}
+	})
+	t.Run("Eviction", func(t *testing.T) {
+		t.Parallel()
+		cache := jwe.NewDerivedKeyCache(2, 0)
+		cache.Set("a", []byte("a"))
+		cache.Set("b", []byte("b"))
+		cache.Set("c", []byte("c"))
+		if !assert.Equal(t, 2, cache.Len(), `cache should be capped`) {
+			return
+		}
+		if _, ok := cache.Get("a"); !assert.False(t, ok, `oldest entry should be evicted`) {
+			return
+		}
+		if v, ok := cache.Get("c"); !assert.True(t, ok, `newest entry should exist`) || !assert.Equal(t, []byte("c"), v) {
+			return
+		}
+	})
+	t.Run("Expiration", func(t *testing.T) {
+		t.Parallel()
+		cache := jwe.NewDerivedKeyCache(0, time.Millisecond)
+		cache.Set("a", []byte("a"))
+		time.Sleep(5 * time.Millisecond)
+		if _, ok := cache.Get("a"); !assert.False(t, ok, `expired entry should not be returned`) {
+			return
+		}
+	})
+}
+
 func Test_GHIssue207(t *testing.T) {
 	const plaintext = "hi\n"
 	var testcases = []struct {
@@ -682,3 +744,1207 @@ func TestReadFile(t *testing.T) {
 		return
 	}
 }
+
+func TestVerifyIntegrity(t *testing.T) {
+	t.Parallel()
+
+	// RFC7516 Appendix A.3
+	const compact = `eyJhbGciOiJBMTI4S1ciLCJlbmMiOiJBMTI4Q0JDLUhTMjU2In0.6KB707dM9YTIgHtLvtgWQ8mKwboJW3of9locizkDTHzBC2IlrT1oOQ.AxY8DCtDaGlsbGljb3RoZQ.KDlTtXchhZTGufMYmOYGS4HffxPSUrfmqCHXaI9wOGY.U0m_YmjN04DJvceFICbCVQ`
+	cek := []byte{
+		4, 211, 31, 197, 84, 157, 252, 254, 11, 100, 157, 250, 63, 170, 106,
+		206, 107, 124, 212, 45, 111, 107, 9, 219, 200, 177, 0, 240, 143, 156,
+		44, 207,
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		t.Parallel()
+		msg, err := jwe.ParseString(compact)
+		if !assert.NoError(t, err, `jwe.ParseString should succeed`) {
+			return
+		}
+		if !assert.Len(t, msg.Tag(), 16, `msg.Tag should return the authentication tag`) {
+			return
+		}
+		if !assert.NoError(t, msg.VerifyIntegrity(cek), `msg.VerifyIntegrity should succeed`) {
+			return
+		}
+	})
+	t.Run("Tampered", func(t *testing.T) {
+		t.Parallel()
+		msg, err := jwe.ParseString(compact)
+		if !assert.NoError(t, err, `jwe.ParseString should succeed`) {
+			return
+		}
+		ciphertext := msg.CipherText()
+		ciphertext[0] ^= 0xff
+		if !assert.Error(t, msg.VerifyIntegrity(cek), `msg.VerifyIntegrity should fail`) {
+			return
+		}
+	})
+	t.Run("Wrong key", func(t *testing.T) {
+		t.Parallel()
+		msg, err := jwe.ParseString(compact)
+		if !assert.NoError(t, err, `jwe.ParseString should succeed`) {
+			return
+		}
+		wrong := make([]byte, len(cek))
+		if !assert.Error(t, msg.VerifyIntegrity(wrong), `msg.VerifyIntegrity should fail`) {
+			return
+		}
+	})
+}
+
+func TestNested(t *testing.T) {
+	t.Parallel()
+
+	const payload = "Lorem ipsum"
+	innerKey := []byte("0123456789abcdef")
+	outerKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.NoError(t, err, `rsa.GenerateKey should succeed`) {
+		return
+	}
+
+	inner, err := jwe.Encrypt([]byte(payload), jwa.A128KW, innerKey, jwa.A128GCM, jwa.NoCompress)
+	if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+		return
+	}
+
+	outer, err := jwe.Wrap(inner, jwa.RSA_OAEP, &outerKey.PublicKey, jwa.A256GCM, jwa.NoCompress)
+	if !assert.NoError(t, err, `jwe.Wrap should succeed`) {
+		return
+	}
+
+	resolver := jwe.KeyResolverFunc(func(depth int, _ *jwe.Message) (jwa.KeyEncryptionAlgorithm, interface{}, error) {
+		switch depth {
+		case 1:
+			return jwa.RSA_OAEP, outerKey, nil
+		case 2:
+			return jwa.A128KW, innerKey, nil
+		default:
+			return "", nil, fmt.Errorf(`unexpected depth %d`, depth)
+		}
+	})
+
+	decrypted, layers, err := jwe.Unwrap(outer, 0, resolver)
+	if !assert.NoError(t, err, `jwe.Unwrap should succeed`) {
+		return
+	}
+	if !assert.Equal(t, []byte(payload), decrypted, `payloads should match`) {
+		return
+	}
+	if !assert.Len(t, layers, 2, `there should be 2 layers`) {
+		return
+	}
+	if !assert.Equal(t, jwe.ContentTypeJWE, layers[0].ProtectedHeaders().ContentType(), `outer layer should have cty`) {
+		return
+	}
+
+	_, _, err = jwe.Unwrap(outer, 1, resolver)
+	if !assert.Error(t, err, `jwe.Unwrap should fail when the depth is exceeded`) {
+		return
+	}
+
+	_, err = jwe.Wrap([]byte(payload), jwa.RSA_OAEP, &outerKey.PublicKey, jwa.A256GCM, jwa.NoCompress)
+	if !assert.Error(t, err, `jwe.Wrap should fail for non-JWE payloads`) {
+		return
+	}
+}
+
+func TestKeyUsageGuard(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); !assert.NoError(t, err, `rand.Read should succeed`) {
+		return
+	}
+
+	var warned uint64
+	guard := jwe.NewKeyUsageGuard(3)
+	guard.SetWarningHandler(2, func(usage uint64) {
+		warned = usage
+	})
+
+	for i := 0; i < 3; i++ {
+		encrypted, err := jwe.Encrypt([]byte("Lorem ipsum"), jwa.DIRECT, key, jwa.A128GCM, jwa.NoCompress, jwe.WithKeyUsageGuard(guard))
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+		if _, err := jwe.Decrypt(encrypted, jwa.DIRECT, key); !assert.NoError(t, err, `jwe.Decrypt should succeed`) {
+			return
+		}
+	}
+	if !assert.Equal(t, uint64(3), guard.Usage(key), `usage should be counted`) {
+		return
+	}
+	if !assert.Equal(t, uint64(2), warned, `warning handler should be called`) {
+		return
+	}
+
+	_, err := jwe.Encrypt([]byte("Lorem ipsum"), jwa.DIRECT, key, jwa.A128GCM, jwa.NoCompress, jwe.WithKeyUsageGuard(guard))
+	if !assert.Error(t, err, `jwe.Encrypt should fail when the limit is reached`) {
+		return
+	}
+
+	// AES-CBC keys and other keys are not affected
+	cbcKey := make([]byte, 32)
+	if _, err := rand.Read(cbcKey); !assert.NoError(t, err, `rand.Read should succeed`) {
+		return
+	}
+	for i := 0; i < 4; i++ {
+		_, err := jwe.Encrypt([]byte("Lorem ipsum"), jwa.DIRECT, cbcKey, jwa.A128CBC_HS256, jwa.NoCompress, jwe.WithKeyUsageGuard(guard))
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+	}
+
+	guard.Reset(key)
+	if !assert.Equal(t, uint64(0), guard.Usage(key), `usage should be reset`) {
+		return
+	}
+	_, err = jwe.Encrypt([]byte("Lorem ipsum"), jwa.DIRECT, key, jwa.A128GCM, jwa.NoCompress, jwe.WithKeyUsageGuard(guard))
+	if !assert.NoError(t, err, `jwe.Encrypt should succeed after reset`) {
+		return
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.NoError(t, err, `rsa.GenerateKey should succeed`) {
+		return
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		return
+	}
+	xPub, xPriv, err := x25519.GenerateKey(rand.Reader)
+	if !assert.NoError(t, err, `x25519.GenerateKey should succeed`) {
+		return
+	}
+	ecJwk, err := jwk.New(ecKey)
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+	ecPubJwk, err := jwk.PublicKeyOf(ecJwk)
+	if !assert.NoError(t, err, `jwk.PublicKeyOf should succeed`) {
+		return
+	}
+
+	testcases := []struct {
+		Name    string
+		Public  interface{}
+		Private interface{}
+		Alg     jwa.KeyEncryptionAlgorithm
+	}{
+		{Name: "RSA", Public: &rsaKey.PublicKey, Private: rsaKey, Alg: jwa.RSA_OAEP_256},
+		{Name: "ECDSA", Public: &ecKey.PublicKey, Private: ecKey, Alg: jwa.ECDH_ES},
+		{Name: "X25519", Public: xPub, Private: xPriv, Alg: jwa.ECDH_ES},
+		{Name: "jwk.Key", Public: ecPubJwk, Private: ecJwk, Alg: jwa.ECDH_ES},
+	}
+
+	payload := []byte("Lorem ipsum")
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			sealed, err := jwe.Seal(payload, tc.Public)
+			if !assert.NoError(t, err, `jwe.Seal should succeed`) {
+				return
+			}
+
+			msg, err := jwe.Parse(sealed)
+			if !assert.NoError(t, err, `jwe.Parse should succeed`) {
+				return
+			}
+			if !assert.Equal(t, tc.Alg, msg.ProtectedHeaders().Algorithm(), `algorithm should match`) {
+				return
+			}
+			if !assert.Equal(t, jwa.A256GCM, msg.ProtectedHeaders().ContentEncryption(), `content encryption should match`) {
+				return
+			}
+
+			opened, err := jwe.Open(sealed, tc.Private)
+			if !assert.NoError(t, err, `jwe.Open should succeed`) {
+				return
+			}
+			if !assert.Equal(t, payload, opened, `payload should match`) {
+				return
+			}
+		})
+	}
+
+	t.Run("Unexpected algorithms", func(t *testing.T) {
+		t.Parallel()
+		encrypted, err := jwe.Encrypt(payload, jwa.RSA1_5, &rsaKey.PublicKey, jwa.A256GCM, jwa.NoCompress)
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+		_, err = jwe.Open(encrypted, rsaKey)
+		if !assert.Error(t, err, `jwe.Open should fail`) {
+			return
+		}
+
+		encrypted, err = jwe.Encrypt(payload, jwa.RSA_OAEP_256, &rsaKey.PublicKey, jwa.A128CBC_HS256, jwa.NoCompress)
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+		_, err = jwe.Open(encrypted, rsaKey)
+		if !assert.Error(t, err, `jwe.Open should fail`) {
+			return
+		}
+	})
+	t.Run("Unsupported keys", func(t *testing.T) {
+		t.Parallel()
+		_, err := jwe.Seal(payload, []byte("abracadabra"))
+		if !assert.Error(t, err, `jwe.Seal should fail for symmetric keys`) {
+			return
+		}
+		_, err = jwe.Seal(payload, rsaKey)
+		if !assert.Error(t, err, `jwe.Seal should fail for private keys`) {
+			return
+		}
+
+		smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+		if !assert.NoError(t, err, `rsa.GenerateKey should succeed`) {
+			return
+		}
+		_, err = jwe.Seal(payload, &smallKey.PublicKey)
+		if !assert.Error(t, err, `jwe.Seal should fail for small RSA keys`) {
+			return
+		}
+	})
+}
+
+func TestDecryptContent(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		return
+	}
+
+	testcases := []struct {
+		ContentType string
+		Payload     string
+		IsJWT       bool
+		IsJSON      bool
+	}{
+		{ContentType: "", Payload: "Lorem ipsum"},
+		{ContentType: "text/plain", Payload: "Lorem ipsum"},
+		{ContentType: "JWT", Payload: "eyJhbGciOiJub25lIn0.e30.", IsJWT: true},
+		{ContentType: "application/jwt", Payload: "eyJhbGciOiJub25lIn0.e30.", IsJWT: true},
+		{ContentType: "json", Payload: `{"foo":"bar"}`, IsJSON: true},
+		{ContentType: "application/json; charset=utf-8", Payload: `{"foo":"bar"}`, IsJSON: true},
+		{ContentType: "application/jwk-set+json", Payload: `{"foo":"bar"}`, IsJSON: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.ContentType, func(t *testing.T) {
+			t.Parallel()
+			encrypted, err := jwe.Encrypt([]byte(tc.Payload), jwa.ECDH_ES, &key.PublicKey, jwa.A128GCM, jwa.NoCompress, jwe.WithContentType(tc.ContentType))
+			if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+				return
+			}
+
+			content, err := jwe.DecryptContent(encrypted, jwa.ECDH_ES, key)
+			if !assert.NoError(t, err, `jwe.DecryptContent should succeed`) {
+				return
+			}
+			if !assert.Equal(t, tc.ContentType, content.ContentType(), `content type should match`) {
+				return
+			}
+			if !assert.Equal(t, tc.Payload, string(content.Payload()), `payload should match`) {
+				return
+			}
+			if !assert.Equal(t, tc.IsJWT, content.IsJWT(), `IsJWT should match`) {
+				return
+			}
+			if !assert.Equal(t, tc.IsJSON, content.IsJSON(), `IsJSON should match`) {
+				return
+			}
+
+			var v map[string]interface{}
+			err = content.Unmarshal(&v)
+			if !tc.IsJSON {
+				assert.Error(t, err, `Unmarshal should fail for non-JSON content`)
+				return
+			}
+			if !assert.NoError(t, err, `Unmarshal should succeed`) {
+				return
+			}
+			assert.Equal(t, map[string]interface{}{"foo": "bar"}, v, `unmarshaled value should match`)
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Malformed", func(t *testing.T) {
+		t.Parallel()
+		for _, src := range []string{``, `a.b.c`, `{"protected":`, `!!!.e30.e30.e30.e30`} {
+			_, err := jwe.Parse([]byte(src))
+			if !assert.True(t, errors.Is(err, jwe.ErrMalformed), `jwe.Parse(%q) should fail with ErrMalformed (got %v)`, src, err) {
+				return
+			}
+			_, err = jwe.Decrypt([]byte(src), jwa.A128KW, []byte("0123456789abcdef"))
+			if !assert.True(t, errors.Is(err, jwe.ErrMalformed), `jwe.Decrypt(%q) should fail with ErrMalformed (got %v)`, src, err) {
+				return
+			}
+		}
+	})
+	t.Run("UnsupportedAlgorithm", func(t *testing.T) {
+		t.Parallel()
+		_, err := jwe.Encrypt([]byte(examplePayload), jwa.KeyEncryptionAlgorithm("bogus"), []byte("0123456789abcdef"), jwa.A128GCM, jwa.NoCompress)
+		if !assert.True(t, errors.Is(err, jwe.ErrUnsupportedAlgorithm), `jwe.Encrypt should fail with ErrUnsupportedAlgorithm (got %v)`, err) {
+			return
+		}
+		_, err = jwe.Encrypt([]byte(examplePayload), jwa.A128KW, []byte("0123456789abcdef"), jwa.ContentEncryptionAlgorithm("bogus"), jwa.NoCompress)
+		if !assert.True(t, errors.Is(err, jwe.ErrUnsupportedAlgorithm), `jwe.Encrypt should fail with ErrUnsupportedAlgorithm (got %v)`, err) {
+			return
+		}
+	})
+	t.Run("UnwrapFailed", func(t *testing.T) {
+		t.Parallel()
+		encrypted, err := jwe.Encrypt([]byte(examplePayload), jwa.A128KW, []byte("0123456789abcdef"), jwa.A128GCM, jwa.NoCompress)
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+		_, err = jwe.Decrypt(encrypted, jwa.A128KW, []byte("fedcba9876543210"))
+		if !assert.True(t, errors.Is(err, jwe.ErrUnwrapFailed), `jwe.Decrypt should fail with ErrUnwrapFailed (got %v)`, err) {
+			return
+		}
+		if !assert.False(t, errors.Is(err, jwe.ErrMalformed), `jwe.Decrypt should not fail with ErrMalformed`) {
+			return
+		}
+
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if !assert.NoError(t, err, `rsa.GenerateKey should succeed`) {
+			return
+		}
+		encrypted, err = jwe.Encrypt([]byte(examplePayload), jwa.RSA_OAEP, &rsaPrivKey.PublicKey, jwa.A128GCM, jwa.NoCompress)
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+		_, err = jwe.Decrypt(encrypted, jwa.RSA_OAEP, key)
+		if !assert.True(t, errors.Is(err, jwe.ErrUnwrapFailed), `jwe.Decrypt should fail with ErrUnwrapFailed (got %v)`, err) {
+			return
+		}
+	})
+	t.Run("DecryptFailed", func(t *testing.T) {
+		t.Parallel()
+		key := []byte("0123456789abcdef0123456789abcdef")
+		encrypted, err := jwe.Encrypt([]byte(examplePayload), jwa.DIRECT, key, jwa.A128CBC_HS256, jwa.NoCompress)
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+
+		parts := strings.Split(string(encrypted), ".")
+		tamper := func(i int) []byte {
+			buf, err := base64.RawURLEncoding.DecodeString(parts[i])
+			if !assert.NoError(t, err, `base64 decode should succeed`) {
+				return nil
+			}
+			buf[len(buf)-1] ^= 0x01
+			tampered := make([]string, len(parts))
+			copy(tampered, parts)
+			tampered[i] = base64.RawURLEncoding.EncodeToString(buf)
+			return []byte(strings.Join(tampered, "."))
+		}
+
+		var messages []string
+		for _, i := range []int{3, 4} { // ciphertext, tag
+			_, err := jwe.Decrypt(tamper(i), jwa.DIRECT, key)
+			if !assert.True(t, errors.Is(err, jwe.ErrDecryptFailed), `jwe.Decrypt should fail with ErrDecryptFailed (got %v)`, err) {
+				return
+			}
+			messages = append(messages, err.Error())
+		}
+		if !assert.Equal(t, messages[0], messages[1], `error messages should not reveal the cause of the failure`) {
+			return
+		}
+	})
+}
+
+func TestRandomnessMonitor(t *testing.T) {
+	t.Parallel()
+
+	m := jwe.NewRandomnessMonitor(0)
+	sharedkey := []byte("0123456789abcdef")
+	for _, keyalg := range []jwa.KeyEncryptionAlgorithm{jwa.A128KW, jwa.DIRECT} {
+		for i := 0; i < 10; i++ {
+			encrypted, err := jwe.Encrypt([]byte(examplePayload), keyalg, sharedkey, jwa.A128GCM, jwa.NoCompress, jwe.WithRandomnessMonitor(m))
+			if !assert.NoError(t, err, `jwe.Encrypt should succeed (alg = %s)`, keyalg) {
+				return
+			}
+			decrypted, err := jwe.Decrypt(encrypted, keyalg, sharedkey)
+			if !assert.NoError(t, err, `jwe.Decrypt should succeed (alg = %s)`, keyalg) {
+				return
+			}
+			if !assert.Equal(t, examplePayload, string(decrypted), `payload should match`) {
+				return
+			}
+		}
+	}
+}
+
+func TestKeyResolver(t *testing.T) {
+	t.Parallel()
+
+	secrets := map[string][]byte{
+		"key-1": []byte("0123456789abcdef0123456789abcdef"),
+		"key-2": []byte("fedcba9876543210fedcba9876543210"),
+	}
+
+	var calls int
+	resolver := jwe.DecryptionKeyResolverFunc(func(_ context.Context, h jwe.Headers) (interface{}, error) {
+		calls++
+		secret, ok := secrets[h.KeyID()]
+		if !ok {
+			return nil, fmt.Errorf(`unknown key %q`, h.KeyID())
+		}
+		return secret, nil
+	})
+
+	encrypt := func(t *testing.T, kid string) []byte {
+		t.Helper()
+		encrypted, err := jwe.Encrypt([]byte(examplePayload), jwa.DIRECT, secrets[kid], jwa.A256GCM, jwa.NoCompress, jwe.WithKeyID(kid))
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			t.FailNow()
+		}
+		return encrypted
+	}
+
+	t.Run("kid is not set by default", func(t *testing.T) {
+		key, err := jwk.New(secrets["key-1"])
+		if !assert.NoError(t, err, `jwk.New should succeed`) {
+			return
+		}
+		_ = key.Set(jwk.KeyIDKey, "key-1")
+		encrypted, err := jwe.Encrypt([]byte(examplePayload), jwa.DIRECT, key, jwa.A256GCM, jwa.NoCompress)
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+		parsed, err := jwe.Parse(encrypted)
+		if !assert.NoError(t, err, `jwe.Parse should succeed`) {
+			return
+		}
+		if !assert.Empty(t, parsed.ProtectedHeaders().KeyID(), `kid should not be set`) {
+			return
+		}
+	})
+
+	msg1 := encrypt(t, "key-1")
+	msg2 := encrypt(t, "key-2")
+
+	parsed, err := jwe.Parse(msg1)
+	if !assert.NoError(t, err, `jwe.Parse should succeed`) {
+		return
+	}
+	if !assert.Equal(t, "key-1", parsed.ProtectedHeaders().KeyID(), `kid should be set`) {
+		return
+	}
+
+	for _, msg := range [][]byte{msg1, msg2} {
+		decrypted, err := jwe.Decrypt(msg, jwa.DIRECT, nil, jwe.WithKeyResolver(resolver), jwe.WithContext(context.Background()))
+		if !assert.NoError(t, err, `jwe.Decrypt should succeed`) {
+			return
+		}
+		if !assert.Equal(t, examplePayload, string(decrypted), `payload should match`) {
+			return
+		}
+	}
+
+	// Keys are cached by kid
+	calls = 0
+	cache := jwe.NewCachingKeyResolver(resolver, 10, time.Minute)
+	for i := 0; i < 3; i++ {
+		if _, err := jwe.Decrypt(msg1, jwa.DIRECT, nil, jwe.WithKeyResolver(cache)); !assert.NoError(t, err, `jwe.Decrypt should succeed`) {
+			return
+		}
+	}
+	if !assert.Equal(t, 1, calls, `resolver should be called once`) {
+		return
+	}
+	if !assert.Equal(t, 1, cache.Len(), `cache should have 1 entry`) {
+		return
+	}
+
+	cache.Invalidate("key-1")
+	if _, err := jwe.Decrypt(msg1, jwa.DIRECT, nil, jwe.WithKeyResolver(cache)); !assert.NoError(t, err, `jwe.Decrypt should succeed`) {
+		return
+	}
+	if !assert.Equal(t, 2, calls, `resolver should be called after invalidation`) {
+		return
+	}
+
+	// Unknown keys fail
+	delete(secrets, "key-2")
+	if _, err := jwe.Decrypt(msg2, jwa.DIRECT, nil, jwe.WithKeyResolver(cache)); !assert.Error(t, err, `jwe.Decrypt should fail`) {
+		return
+	}
+	if !assert.Equal(t, 1, cache.Len(), `errors should not be cached`) {
+		return
+	}
+}
+
+func TestManifest(t *testing.T) {
+	t.Parallel()
+
+	plaintext := []byte(strings.Repeat(examplePayload, 10))
+	const chunkSize = 100
+
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		return
+	}
+
+	m, err := jwe.NewManifest(strings.NewReader(string(plaintext)), crypto.SHA256, chunkSize)
+	if !assert.NoError(t, err, `jwe.NewManifest should succeed`) {
+		return
+	}
+	if !assert.Equal(t, int64(len(plaintext)), m.Size(), `m.Size should match`) {
+		return
+	}
+	if !assert.Equal(t, (len(plaintext)+chunkSize-1)/chunkSize, m.ChunkCount(), `m.ChunkCount should match`) {
+		return
+	}
+
+	signed, err := jwe.SignManifest(m, jwa.ES256, signingKey)
+	if !assert.NoError(t, err, `jwe.SignManifest should succeed`) {
+		return
+	}
+
+	verified, err := jwe.VerifyManifest(signed, jwa.ES256, &signingKey.PublicKey)
+	if !assert.NoError(t, err, `jwe.VerifyManifest should succeed`) {
+		return
+	}
+	if !assert.Equal(t, m, verified, `manifests should match`) {
+		return
+	}
+
+	t.Run("Chunks", func(t *testing.T) {
+		t.Parallel()
+		for i := 0; i < verified.ChunkCount(); i++ {
+			end := (i + 1) * chunkSize
+			if end > len(plaintext) {
+				end = len(plaintext)
+			}
+			if !assert.NoError(t, verified.VerifyChunk(i, plaintext[i*chunkSize:end]), `VerifyChunk(%d) should succeed`, i) {
+				return
+			}
+		}
+
+		err := verified.VerifyChunk(1, plaintext[:chunkSize])
+		if !assert.True(t, errors.Is(err, jwe.ErrManifestMismatch), `VerifyChunk should fail with ErrManifestMismatch`) {
+			return
+		}
+		err = verified.VerifyChunk(0, plaintext[:chunkSize-1])
+		if !assert.True(t, errors.Is(err, jwe.ErrManifestMismatch), `VerifyChunk should fail with ErrManifestMismatch`) {
+			return
+		}
+	})
+	t.Run("Decrypted plaintext", func(t *testing.T) {
+		t.Parallel()
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); !assert.NoError(t, err, `rand.Read should succeed`) {
+			return
+		}
+
+		encrypted, err := jwe.Encrypt(plaintext, jwa.DIRECT, key, jwa.A256GCM, jwa.NoCompress)
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+		decrypted, err := jwe.Decrypt(encrypted, jwa.DIRECT, key)
+		if !assert.NoError(t, err, `jwe.Decrypt should succeed`) {
+			return
+		}
+		if !assert.NoError(t, verified.Verify(strings.NewReader(string(decrypted))), `Verify should succeed`) {
+			return
+		}
+
+		for _, tampered := range []string{string(decrypted[:len(decrypted)-1]), string(decrypted) + "x", "x" + string(decrypted[1:])} {
+			err := verified.Verify(strings.NewReader(tampered))
+			if !assert.True(t, errors.Is(err, jwe.ErrManifestMismatch), `Verify should fail with ErrManifestMismatch`) {
+				return
+			}
+		}
+	})
+	t.Run("Tampered manifest", func(t *testing.T) {
+		t.Parallel()
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+			return
+		}
+		_, err = jwe.VerifyManifest(signed, jwa.ES256, &otherKey.PublicKey)
+		if !assert.Error(t, err, `jwe.VerifyManifest should fail`) {
+			return
+		}
+	})
+}
+
+func TestECDH1PU(t *testing.T) {
+	t.Parallel()
+
+	type keypair struct {
+		sender           interface{}
+		senderPublic     interface{}
+		recipient        interface{}
+		recipientPrivate interface{}
+	}
+
+	ecKeys := func(t *testing.T) *keypair {
+		sender, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+			return nil
+		}
+		recipient, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+			return nil
+		}
+		return &keypair{sender: sender, senderPublic: &sender.PublicKey, recipient: &recipient.PublicKey, recipientPrivate: recipient}
+	}
+	x25519Keys := func(t *testing.T) *keypair {
+		senderPublic, sender, err := x25519.GenerateKey(rand.Reader)
+		if !assert.NoError(t, err, `x25519.GenerateKey should succeed`) {
+			return nil
+		}
+		recipient, recipientPrivate, err := x25519.GenerateKey(rand.Reader)
+		if !assert.NoError(t, err, `x25519.GenerateKey should succeed`) {
+			return nil
+		}
+		return &keypair{sender: sender, senderPublic: senderPublic, recipient: recipient, recipientPrivate: recipientPrivate}
+	}
+
+	payload := []byte("Lorem ipsum")
+	keyalgs := []jwa.KeyEncryptionAlgorithm{jwa.ECDH_1PU, jwa.ECDH_1PU_A128KW, jwa.ECDH_1PU_A192KW, jwa.ECDH_1PU_A256KW}
+	for _, curve := range []string{"P-256", "X25519"} {
+		curve := curve
+		for _, keyalg := range keyalgs {
+			keyalg := keyalg
+			t.Run(curve+"/"+keyalg.String(), func(t *testing.T) {
+				t.Parallel()
+				keys := ecKeys
+				if curve == "X25519" {
+					keys = x25519Keys
+				}
+				kp := keys(t)
+				other := keys(t)
+				if kp == nil || other == nil {
+					return
+				}
+
+				encrypted, err := jwe.Encrypt(payload, keyalg, kp.recipient, jwa.A256CBC_HS512, jwa.NoCompress, jwe.WithSenderKey(kp.sender))
+				if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+					return
+				}
+
+				decrypted, err := jwe.Decrypt(encrypted, keyalg, kp.recipientPrivate, jwe.WithSenderPublicKey(kp.senderPublic))
+				if !assert.NoError(t, err, `jwe.Decrypt should succeed`) {
+					return
+				}
+				if !assert.Equal(t, payload, decrypted, `payload should match`) {
+					return
+				}
+
+				_, err = jwe.Decrypt(encrypted, keyalg, kp.recipientPrivate, jwe.WithSenderPublicKey(other.senderPublic))
+				if !assert.Error(t, err, `jwe.Decrypt with the wrong sender key should fail`) {
+					return
+				}
+				_, err = jwe.Decrypt(encrypted, keyalg, kp.recipientPrivate)
+				if !assert.Error(t, err, `jwe.Decrypt without a sender key should fail`) {
+					return
+				}
+			})
+		}
+	}
+
+	t.Run("Sender key ID", func(t *testing.T) {
+		t.Parallel()
+		kp := ecKeys(t)
+		if kp == nil {
+			return
+		}
+		sender, err := jwk.New(kp.sender)
+		if !assert.NoError(t, err, `jwk.New should succeed`) {
+			return
+		}
+		if !assert.NoError(t, sender.Set(jwk.KeyIDKey, "alice"), `sender.Set should succeed`) {
+			return
+		}
+
+		encrypted, err := jwe.Encrypt(payload, jwa.ECDH_1PU_A256KW, kp.recipient, jwa.A128CBC_HS256, jwa.NoCompress, jwe.WithSenderKey(sender))
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+
+		msg, err := jwe.Parse(encrypted)
+		if !assert.NoError(t, err, `jwe.Parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, "alice", msg.ProtectedHeaders().SenderKeyID(), `"skid" should be set`) {
+			return
+		}
+
+		senderPublic, err := jwk.PublicKeyOf(sender)
+		if !assert.NoError(t, err, `jwk.PublicKeyOf should succeed`) {
+			return
+		}
+		set := jwk.NewSet()
+		set.Add(senderPublic)
+
+		decrypted, err := jwe.Decrypt(encrypted, jwa.ECDH_1PU_A256KW, kp.recipientPrivate, jwe.WithSenderPublicKey(set))
+		if !assert.NoError(t, err, `jwe.Decrypt should succeed`) {
+			return
+		}
+		if !assert.Equal(t, payload, decrypted, `payload should match`) {
+			return
+		}
+
+		// A set that does not contain the sender's key
+		_, err = jwe.Decrypt(encrypted, jwa.ECDH_1PU_A256KW, kp.recipientPrivate, jwe.WithSenderPublicKey(jwk.NewSet()))
+		if !assert.Error(t, err, `jwe.Decrypt should fail`) {
+			return
+		}
+	})
+	t.Run("Invalid combinations", func(t *testing.T) {
+		t.Parallel()
+		kp := ecKeys(t)
+		if kp == nil {
+			return
+		}
+
+		_, err := jwe.Encrypt(payload, jwa.ECDH_1PU_A256KW, kp.recipient, jwa.A256GCM, jwa.NoCompress, jwe.WithSenderKey(kp.sender))
+		if !assert.Error(t, err, `key wrap with AES-GCM should be rejected`) {
+			return
+		}
+		_, err = jwe.Encrypt(payload, jwa.ECDH_1PU, kp.recipient, jwa.A256GCM, jwa.NoCompress)
+		if !assert.Error(t, err, `jwe.Encrypt without a sender key should fail`) {
+			return
+		}
+
+		// Direct key agreement works with AES-GCM
+		encrypted, err := jwe.Encrypt(payload, jwa.ECDH_1PU, kp.recipient, jwa.A256GCM, jwa.NoCompress, jwe.WithSenderKey(kp.sender))
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+		decrypted, err := jwe.Decrypt(encrypted, jwa.ECDH_1PU, kp.recipientPrivate, jwe.WithSenderPublicKey(kp.senderPublic))
+		if !assert.NoError(t, err, `jwe.Decrypt should succeed`) {
+			return
+		}
+		if !assert.Equal(t, payload, decrypted, `payload should match`) {
+			return
+		}
+	})
+}
+
+func TestRewrap(t *testing.T) {
+	payload := []byte(examplePayload)
+
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		return
+	}
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.NoError(t, err, `rsa.GenerateKey should succeed`) {
+		return
+	}
+	sharedKey := func(n int) []byte {
+		buf := make([]byte, n)
+		_, _ = rand.Read(buf)
+		return buf
+	}
+
+	testcases := []struct {
+		Name       string
+		Alg        jwa.KeyEncryptionAlgorithm
+		EncryptKey interface{}
+		DecryptKey interface{}
+		ContentAlg jwa.ContentEncryptionAlgorithm
+		NewAlg     jwa.KeyEncryptionAlgorithm
+		NewKey     interface{}
+		NewPrivKey interface{}
+	}{
+		{
+			Name:       "RSA-OAEP to ECDH-ES+A256KW",
+			Alg:        jwa.RSA_OAEP,
+			EncryptKey: &rsaPrivKey.PublicKey,
+			DecryptKey: &rsaPrivKey,
+			ContentAlg: jwa.A256GCM,
+			NewAlg:     jwa.ECDH_ES_A256KW,
+			NewKey:     &ecPriv.PublicKey,
+			NewPrivKey: ecPriv,
+		},
+		{
+			Name:       "A128KW to RSA-OAEP-256",
+			Alg:        jwa.A128KW,
+			EncryptKey: sharedKey(16),
+			ContentAlg: jwa.A128CBC_HS256,
+			NewAlg:     jwa.RSA_OAEP_256,
+			NewKey:     &rsaPriv.PublicKey,
+			NewPrivKey: rsaPriv,
+		},
+		{
+			Name:       "dir to A256GCMKW",
+			Alg:        jwa.DIRECT,
+			EncryptKey: sharedKey(32),
+			ContentAlg: jwa.A128CBC_HS256,
+			NewAlg:     jwa.A256GCMKW,
+			NewKey:     sharedKey(32),
+		},
+		{
+			Name:       "ECDH-ES to PBES2-HS256+A128KW",
+			Alg:        jwa.ECDH_ES,
+			EncryptKey: &ecPriv.PublicKey,
+			DecryptKey: ecPriv,
+			ContentAlg: jwa.A192GCM,
+			NewAlg:     jwa.PBES2_HS256_A128KW,
+			NewKey:     []byte(`correct horse battery staple`),
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			decryptKey := tc.DecryptKey
+			if decryptKey == nil {
+				decryptKey = tc.EncryptKey
+			}
+			newPrivKey := tc.NewPrivKey
+			if newPrivKey == nil {
+				newPrivKey = tc.NewKey
+			}
+
+			encrypted, err := jwe.Encrypt(payload, tc.Alg, tc.EncryptKey, tc.ContentAlg, jwa.NoCompress)
+			if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+				return
+			}
+
+			msg, err := jwe.Parse(encrypted)
+			if !assert.NoError(t, err, `jwe.Parse should succeed`) {
+				return
+			}
+
+			rewrapped, err := jwe.Rewrap(msg, decryptKey, tc.NewKey, tc.NewAlg)
+			if !assert.NoError(t, err, `jwe.Rewrap should succeed`) {
+				return
+			}
+			if !assert.Equal(t, msg.CipherText(), rewrapped.CipherText(), `ciphertext should be preserved`) {
+				return
+			}
+			if !assert.Equal(t, msg.InitializationVector(), rewrapped.InitializationVector(), `initialization vector should be preserved`) {
+				return
+			}
+			if !assert.Equal(t, tc.NewAlg, rewrapped.ProtectedHeaders().Algorithm(), `"alg" should be replaced`) {
+				return
+			}
+
+			// The original message must be left untouched
+			decrypted, err := jwe.Decrypt(encrypted, tc.Alg, decryptKey)
+			if !assert.NoError(t, err, `jwe.Decrypt should succeed for the original message`) {
+				return
+			}
+			if !assert.Equal(t, payload, decrypted, `payload should match`) {
+				return
+			}
+
+			compact, err := jwe.Compact(rewrapped)
+			if !assert.NoError(t, err, `jwe.Compact should succeed`) {
+				return
+			}
+			decrypted, err = jwe.Decrypt(compact, tc.NewAlg, newPrivKey)
+			if !assert.NoError(t, err, `jwe.Decrypt should succeed for the new recipient`) {
+				return
+			}
+			if !assert.Equal(t, payload, decrypted, `payload should match`) {
+				return
+			}
+
+			_, err = jwe.Decrypt(compact, tc.Alg, decryptKey)
+			if !assert.Error(t, err, `jwe.Decrypt should fail for the previous recipient`) {
+				return
+			}
+
+			serialized, err := json.Marshal(rewrapped)
+			if !assert.NoError(t, err, `json.Marshal should succeed`) {
+				return
+			}
+			decrypted, err = jwe.Decrypt(serialized, tc.NewAlg, newPrivKey)
+			if !assert.NoError(t, err, `jwe.Decrypt should succeed for JSON serialization`) {
+				return
+			}
+			if !assert.Equal(t, payload, decrypted, `payload should match`) {
+				return
+			}
+		})
+	}
+	t.Run("jwk.Key", func(t *testing.T) {
+		t.Parallel()
+		newKey, err := jwk.New(&ecPriv.PublicKey)
+		if !assert.NoError(t, err, `jwk.New should succeed`) {
+			return
+		}
+		_ = newKey.Set(jwk.KeyIDKey, `new-recipient`)
+
+		encrypted, err := jwe.Encrypt(payload, jwa.RSA_OAEP, &rsaPrivKey.PublicKey, jwa.A128GCM, jwa.NoCompress)
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+		msg, err := jwe.Parse(encrypted)
+		if !assert.NoError(t, err, `jwe.Parse should succeed`) {
+			return
+		}
+		rewrapped, err := jwe.Rewrap(msg, &rsaPrivKey, newKey, jwa.ECDH_ES_A128KW)
+		if !assert.NoError(t, err, `jwe.Rewrap should succeed`) {
+			return
+		}
+		if !assert.Equal(t, `new-recipient`, rewrapped.ProtectedHeaders().KeyID(), `"kid" should be set`) {
+			return
+		}
+	})
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+		key := sharedKey(16)
+		encrypted, err := jwe.Encrypt(payload, jwa.A128KW, key, jwa.A128GCM, jwa.NoCompress)
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+		msg, err := jwe.Parse(encrypted)
+		if !assert.NoError(t, err, `jwe.Parse should succeed`) {
+			return
+		}
+
+		_, err = jwe.Rewrap(msg, sharedKey(16), &rsaPriv.PublicKey, jwa.RSA_OAEP)
+		if !assert.Error(t, err, `jwe.Rewrap with the wrong key should fail`) {
+			return
+		}
+		_, err = jwe.RewrapWithCEK(msg, sharedKey(16), &rsaPriv.PublicKey, jwa.RSA_OAEP)
+		if !assert.Error(t, err, `jwe.RewrapWithCEK with the wrong CEK should fail`) {
+			return
+		}
+		for _, alg := range []jwa.KeyEncryptionAlgorithm{jwa.DIRECT, jwa.ECDH_ES, jwa.ECDH_1PU_A128KW} {
+			_, err = jwe.Rewrap(msg, key, &ecPriv.PublicKey, alg)
+			if !assert.True(t, errors.Is(err, jwe.ErrUnsupportedAlgorithm), `jwe.Rewrap to %s should fail`, alg) {
+				return
+			}
+		}
+	})
+}
+
+func TestWithMaxMessageSize(t *testing.T) {
+	t.Parallel()
+	payload := []byte(examplePayload)
+	encrypted, err := jwe.Encrypt(payload, jwa.RSA_OAEP, &rsaPrivKey.PublicKey, jwa.A128GCM, jwa.NoCompress)
+	if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+		return
+	}
+
+	size := int64(len(encrypted))
+	_, err = jwe.Parse(encrypted, jwe.WithMaxMessageSize(size-1))
+	if !assert.True(t, errors.Is(err, jwe.ErrMalformed), `jwe.Parse should fail`) {
+		return
+	}
+	_, err = jwe.ParseString(string(encrypted), jwe.WithMaxMessageSize(size-1))
+	if !assert.Error(t, err, `jwe.ParseString should fail`) {
+		return
+	}
+	_, err = jwe.ParseReader(ioutil.NopCloser(strings.NewReader(string(encrypted))), jwe.WithMaxMessageSize(size-1))
+	if !assert.Error(t, err, `jwe.ParseReader should fail`) {
+		return
+	}
+	_, err = jwe.Decrypt(encrypted, jwa.RSA_OAEP, &rsaPrivKey, jwe.WithMaxMessageSize(size-1))
+	if !assert.Error(t, err, `jwe.Decrypt should fail`) {
+		return
+	}
+
+	_, err = jwe.ParseReader(ioutil.NopCloser(strings.NewReader(string(encrypted))), jwe.WithMaxMessageSize(size))
+	if !assert.NoError(t, err, `jwe.ParseReader should succeed`) {
+		return
+	}
+	decrypted, err := jwe.Decrypt(encrypted, jwa.RSA_OAEP, &rsaPrivKey, jwe.WithMaxMessageSize(size))
+	if !assert.NoError(t, err, `jwe.Decrypt should succeed`) {
+		return
+	}
+	if !assert.Equal(t, payload, decrypted, `payload should match`) {
+		return
+	}
+}
+
+// recordingKeyOrder records the keys that it was asked to order
+type recordingKeyOrder struct {
+	next jwe.KeyOrder
+	keys [][]jwk.Key
+}
+
+func (o *recordingKeyOrder) Order(h jwe.Headers, keys []jwk.Key) []jwk.Key {
+	o.keys = append(o.keys, keys)
+	return o.next.Order(h, keys)
+}
+
+func TestDecryptWithKeySet(t *testing.T) {
+	t.Parallel()
+
+	newKey := func(raw interface{}, kid string) jwk.Key {
+		key, err := jwk.New(raw)
+		if err != nil {
+			panic(err)
+		}
+		if kid != "" {
+			_ = key.Set(jwk.KeyIDKey, kid)
+		}
+		return key
+	}
+	newSymmetricKey := func(size int) []byte {
+		buf := make([]byte, size)
+		_, _ = rand.Read(buf)
+		return buf
+	}
+
+	rsaKey1, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.NoError(t, err, `rsa.GenerateKey should succeed`) {
+		return
+	}
+	rsaKey2, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.NoError(t, err, `rsa.GenerateKey should succeed`) {
+		return
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		return
+	}
+	aes128 := newSymmetricKey(16)
+	aes256 := newSymmetricKey(32)
+
+	set := jwk.NewSet()
+	set.Add(newKey(rsaKey1, `rsa-1`))
+	set.Add(newKey(ecKey, `ec`))
+	set.Add(newKey(aes256, `aes-256`))
+	set.Add(newKey(rsaKey2, `rsa-2`))
+	set.Add(newKey(aes128, `aes-128`))
+
+	t.Run("Key ID match first", func(t *testing.T) {
+		t.Parallel()
+		encrypted, err := jwe.Encrypt([]byte(examplePayload), jwa.RSA_OAEP, newKey(&rsaKey2.PublicKey, `rsa-2`), jwa.A128GCM, jwa.NoCompress)
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+
+		order := &recordingKeyOrder{next: jwe.KeyIDMatchFirst}
+		decrypted, err := jwe.DecryptWithKeySet(encrypted, set, jwe.WithKeyOrder(order))
+		if !assert.NoError(t, err, `jwe.DecryptWithKeySet should succeed`) {
+			return
+		}
+		if !assert.Equal(t, examplePayload, string(decrypted), `payload should match`) {
+			return
+		}
+
+		// only the RSA keys are compatible with RSA-OAEP
+		if !assert.Len(t, order.keys, 1, `keys should be ordered once`) {
+			return
+		}
+		var kids []string
+		for _, key := range order.keys[0] {
+			kids = append(kids, key.KeyID())
+		}
+		if !assert.Equal(t, []string{`rsa-1`, `rsa-2`}, kids, `only compatible keys should be tried`) {
+			return
+		}
+
+		h := jwe.NewHeaders()
+		_ = h.Set(jwe.KeyIDKey, `rsa-2`)
+		sorted := jwe.KeyIDMatchFirst.Order(h, order.keys[0])
+		if !assert.Equal(t, `rsa-2`, sorted[0].KeyID(), `matching key should be tried first`) {
+			return
+		}
+	})
+	t.Run("Key size mismatch is skipped", func(t *testing.T) {
+		t.Parallel()
+		encrypted, err := jwe.Encrypt([]byte(examplePayload), jwa.A128KW, aes128, jwa.A128GCM, jwa.NoCompress)
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+
+		order := &recordingKeyOrder{next: jwe.KeyIDMatchFirst}
+		decrypted, err := jwe.DecryptWithKeySet(encrypted, set, jwe.WithKeyOrder(order))
+		if !assert.NoError(t, err, `jwe.DecryptWithKeySet should succeed`) {
+			return
+		}
+		if !assert.Equal(t, examplePayload, string(decrypted), `payload should match`) {
+			return
+		}
+		if !assert.Len(t, order.keys[0], 1, `only the 128 bit key should be tried`) {
+			return
+		}
+		if !assert.Equal(t, `aes-128`, order.keys[0][0].KeyID(), `only the 128 bit key should be tried`) {
+			return
+		}
+	})
+	t.Run("Algorithm match first", func(t *testing.T) {
+		t.Parallel()
+		k1 := newKey(rsaKey1, `rsa-1`)
+		k2 := newKey(rsaKey2, `rsa-2`)
+		_ = k2.Set(jwk.AlgorithmKey, jwa.RSA_OAEP_256)
+
+		h := jwe.NewHeaders()
+		_ = h.Set(jwe.AlgorithmKey, jwa.RSA_OAEP_256)
+		sorted := jwe.AlgorithmMatchFirst.Order(h, []jwk.Key{k1, k2})
+		if !assert.Equal(t, []jwk.Key{k2, k1}, sorted, `key with matching "alg" should be tried first`) {
+			return
+		}
+	})
+	t.Run("Last success first", func(t *testing.T) {
+		t.Parallel()
+		// keys without key IDs are remembered by their thumbprint
+		k1 := newKey(newSymmetricKey(16), ``)
+		k2 := newKey(newSymmetricKey(16), ``)
+		keys := jwk.NewSet()
+		keys.Add(k1)
+		keys.Add(k2)
+
+		var raw interface{}
+		_ = k2.Raw(&raw)
+		encrypted, err := jwe.Encrypt([]byte(examplePayload), jwa.A128KW, raw, jwa.A128GCM, jwa.NoCompress)
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+
+		order := jwe.NewLastSuccessFirst(nil, 4)
+		if !assert.Equal(t, []jwk.Key{k1, k2}, order.Order(jwe.NewHeaders(), []jwk.Key{k1, k2}), `keys should be in the order of the set`) {
+			return
+		}
+
+		if _, err := jwe.DecryptWithKeySet(encrypted, keys, jwe.WithKeyOrder(order)); !assert.NoError(t, err, `jwe.DecryptWithKeySet should succeed`) {
+			return
+		}
+		if !assert.Equal(t, []jwk.Key{k2, k1}, order.Order(jwe.NewHeaders(), []jwk.Key{k1, k2}), `last successful key should be tried first`) {
+			return
+		}
+	})
+	t.Run("No compatible key", func(t *testing.T) {
+		t.Parallel()
+		encrypted, err := jwe.Encrypt([]byte(examplePayload), jwa.A192KW, newSymmetricKey(24), jwa.A128GCM, jwa.NoCompress)
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+		if _, err := jwe.DecryptWithKeySet(encrypted, set); !assert.Error(t, err, `jwe.DecryptWithKeySet should fail`) {
+			return
+		}
+	})
+	t.Run("Wrong key", func(t *testing.T) {
+		t.Parallel()
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		if !assert.NoError(t, err, `rsa.GenerateKey should succeed`) {
+			return
+		}
+		encrypted, err := jwe.Encrypt([]byte(examplePayload), jwa.RSA_OAEP, &other.PublicKey, jwa.A128GCM, jwa.NoCompress)
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+		if _, err := jwe.DecryptWithKeySet(encrypted, set); !assert.Error(t, err, `jwe.DecryptWithKeySet should fail`) {
+			return
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package jwe
+
+import (
+	"time"
+
+	"github.com/lestrrat-go/jwx/internal/ttlcache"
+)
+
+// DerivedKeyCache stores keys derived via ECDH-ES key agreement, so that
+// decrypting messages that use the same combination of ephemeral public key,
+// static private key, algorithm, and agreement party info (apu/apv) does not
+// require repeating the expensive scalar multiplication.
+//
+// The cache is opt-in: pass it to `jwe.Decrypt()` or `(*jwe.Message).Decrypt()`
+// using the `jwe.WithDerivedKeyCache()` option. It is safe for concurrent use.
+//
+// Note that the cache holds key material in memory. Use the TTL and
+// the maximum number of entries to limit how long and how many
+// keys are retained.
+type DerivedKeyCache struct {
+	cache *ttlcache.Cache
+}
+
+// NewDerivedKeyCache creates a new DerivedKeyCache that holds each
+// derived key for `ttl`, and at most `maxEntries` keys, dropping the
+// oldest keys first. Pass 0 to disable either limit.
+func NewDerivedKeyCache(maxEntries int, ttl time.Duration) *DerivedKeyCache {
+	return &DerivedKeyCache{
+		cache: ttlcache.New(maxEntries, ttl),
+	}
+}
+
+// Get returns a copy of the key stored under the given cache key.
+func (c *DerivedKeyCache) Get(key string) ([]byte, bool) {
+	v, ok := c.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	value := v.([]byte)
+	ret := make([]byte, len(value))
+	copy(ret, value)
+	return ret, true
+}
+
+// Set stores a copy of the given key.
+func (c *DerivedKeyCache) Set(key string, value []byte) {
+	v := make([]byte, len(value))
+	copy(v, value)
+	c.cache.Set(key, v, time.Time{})
+}
+
+// Len returns the number of entries in the cache, including those
+// that have expired but have not been evicted yet.
+func (c *DerivedKeyCache) Len() int {
+	return c.cache.Len()
+}
+
+// Clear removes all entries from the cache.
+func (c *DerivedKeyCache) Clear() {
+	c.cache.Clear()
+}
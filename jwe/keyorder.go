@@ -0,0 +1,318 @@
+package jwe
+
+import (
+	"context"
+	"crypto"
+	"sync"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+)
+
+// KeyOrder determines the order in which the keys of a jwk.Set are tried
+// by `jwe.DecryptWithKeySet()`, for each recipient of a message. Keys that
+// cannot be used with the "alg" header of the recipient are removed
+// before the KeyOrder is consulted. See `jwe.WithKeyOrder()`.
+type KeyOrder interface {
+	// Order returns the keys in the order they should be tried for the
+	// recipient whose (merged) headers are `h`.
+	Order(h Headers, keys []jwk.Key) []jwk.Key
+}
+
+// KeyOrderFunc is a KeyOrder represented by a function
+type KeyOrderFunc func(Headers, []jwk.Key) []jwk.Key
+
+func (f KeyOrderFunc) Order(h Headers, keys []jwk.Key) []jwk.Key {
+	return f(h, keys)
+}
+
+// KeyIDMatchFirst tries the keys whose key ID matches the "kid" header
+// first, followed by the other keys in the order of the set. This is the
+// default KeyOrder.
+var KeyIDMatchFirst KeyOrder = KeyOrderFunc(func(h Headers, keys []jwk.Key) []jwk.Key {
+	kid := h.KeyID()
+	if kid == "" {
+		return keys
+	}
+	return partitionKeys(keys, func(key jwk.Key) bool {
+		return key.KeyID() == kid
+	})
+})
+
+// AlgorithmMatchFirst tries the keys whose "alg" matches the "alg" header
+// first, followed by the keys that do not specify an algorithm, in the
+// order of the set.
+var AlgorithmMatchFirst KeyOrder = KeyOrderFunc(func(h Headers, keys []jwk.Key) []jwk.Key {
+	alg := h.Algorithm().String()
+	return partitionKeys(keys, func(key jwk.Key) bool {
+		return key.Algorithm() == alg
+	})
+})
+
+// partitionKeys returns the keys for which `first` returns true, followed
+// by the others, preserving their relative order
+func partitionKeys(keys []jwk.Key, first func(jwk.Key) bool) []jwk.Key {
+	sorted := make([]jwk.Key, 0, len(keys))
+	for _, key := range keys {
+		if first(key) {
+			sorted = append(sorted, key)
+		}
+	}
+	for _, key := range keys {
+		if !first(key) {
+			sorted = append(sorted, key)
+		}
+	}
+	return sorted
+}
+
+// keyOrderRecorder is implemented by the KeyOrders that learn from the
+// keys that successfully decrypted a message
+type keyOrderRecorder interface {
+	remember(jwk.Key)
+}
+
+// LastSuccessFirst is a KeyOrder that remembers the keys that most
+// recently decrypted a message, and tries them first. This works well
+// when most messages are addressed to the same few keys, but their
+// recipients do not carry a "kid" header. Keys are remembered by their
+// key ID or, if they do not have one, by their thumbprint.
+//
+// A LastSuccessFirst must be reused across calls to be effective, and is
+// safe for concurrent use.
+type LastSuccessFirst struct {
+	next   KeyOrder
+	size   int
+	mu     sync.Mutex
+	recent []string // most recent first
+}
+
+// NewLastSuccessFirst creates a new LastSuccessFirst that remembers up to
+// `size` keys. The keys that it does not remember are tried in the order
+// given by `next`, or in the order of the set if `next` is nil.
+func NewLastSuccessFirst(next KeyOrder, size int) *LastSuccessFirst {
+	if size <= 0 {
+		size = 1
+	}
+	return &LastSuccessFirst{
+		next: next,
+		size: size,
+	}
+}
+
+func (o *LastSuccessFirst) Order(h Headers, keys []jwk.Key) []jwk.Key {
+	if o.next != nil {
+		keys = o.next.Order(h, keys)
+	}
+
+	o.mu.Lock()
+	recent := make([]string, len(o.recent))
+	copy(recent, o.recent)
+	o.mu.Unlock()
+
+	sorted := make([]jwk.Key, 0, len(keys))
+	used := make([]bool, len(keys))
+	for _, id := range recent {
+		for i, key := range keys {
+			if !used[i] && keyIdentity(key) == id {
+				sorted = append(sorted, key)
+				used[i] = true
+			}
+		}
+	}
+	for i, key := range keys {
+		if !used[i] {
+			sorted = append(sorted, key)
+		}
+	}
+	return sorted
+}
+
+func (o *LastSuccessFirst) remember(key jwk.Key) {
+	id := keyIdentity(key)
+	if id == "" {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	recent := make([]string, 0, o.size)
+	recent = append(recent, id)
+	for _, v := range o.recent {
+		if v != id && len(recent) < o.size {
+			recent = append(recent, v)
+		}
+	}
+	o.recent = recent
+}
+
+// keyIdentity returns a value that identifies the key without
+// disclosing it
+func keyIdentity(key jwk.Key) string {
+	if kid := key.KeyID(); kid != "" {
+		return "kid:" + kid
+	}
+	tp, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return ""
+	}
+	return "jkt:" + base64.EncodeToString(tp)
+}
+
+// compatibleKey reports if the key may be used to decrypt the key of a
+// recipient whose "alg" header is `alg`, so that keys that cannot
+// possibly succeed are not tried
+func compatibleKey(alg jwa.KeyEncryptionAlgorithm, key jwk.Key) bool {
+	if v := key.Algorithm(); v != "" && v != alg.String() {
+		return false
+	}
+	if v := key.KeyUsage(); v != "" && v != jwk.ForEncryption.String() {
+		return false
+	}
+
+	switch alg {
+	case jwa.RSA1_5, jwa.RSA_OAEP, jwa.RSA_OAEP_256:
+		_, ok := key.(jwk.RSAPrivateKey)
+		return ok
+	case jwa.A128KW, jwa.A128GCMKW:
+		return symmetricKeySize(key) == 16
+	case jwa.A192KW, jwa.A192GCMKW:
+		return symmetricKeySize(key) == 24
+	case jwa.A256KW, jwa.A256GCMKW:
+		return symmetricKeySize(key) == 32
+	case jwa.DIRECT, jwa.PBES2_HS256_A128KW, jwa.PBES2_HS384_A192KW, jwa.PBES2_HS512_A256KW:
+		return symmetricKeySize(key) > 0
+	case jwa.ECDH_ES, jwa.ECDH_ES_A128KW, jwa.ECDH_ES_A192KW, jwa.ECDH_ES_A256KW,
+		jwa.ECDH_1PU, jwa.ECDH_1PU_A128KW, jwa.ECDH_1PU_A192KW, jwa.ECDH_1PU_A256KW:
+		switch key.(type) {
+		case jwk.ECDSAPrivateKey, jwk.OKPPrivateKey:
+			return true
+		}
+		return false
+	default:
+		// Let the decrypter decide
+		return true
+	}
+}
+
+func symmetricKeySize(key jwk.Key) int {
+	if v, ok := key.(jwk.SymmetricKey); ok {
+		return len(v.Octets())
+	}
+	return 0
+}
+
+// DecryptWithKeySet decrypts the JWE message using the keys in `set`.
+// The message can be either in compact or full JSON format.
+//
+// For each recipient of the message, the keys that are compatible with
+// its "alg" header are tried in the order given by `jwe.WithKeyOrder()`,
+// which defaults to `jwe.KeyIDMatchFirst`. Keys whose type, size, "alg"
+// or "use" cannot match the recipient are skipped without attempting to
+// decrypt the key.
+//
+// The other options are passed to `(*jwe.Message).Decrypt()`.
+func DecryptWithKeySet(buf []byte, set jwk.Set, options ...DecryptOption) ([]byte, error) {
+	msg, err := Parse(buf, parseOptions(options)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse buffer for DecryptWithKeySet")
+	}
+	return msg.DecryptWithKeySet(set, options...)
+}
+
+// DecryptWithKeySet decrypts the message using the keys in `set`. See
+// `jwe.DecryptWithKeySet()` for details.
+func (m *Message) DecryptWithKeySet(set jwk.Set, options ...DecryptOption) ([]byte, error) {
+	order := KeyIDMatchFirst
+	for _, option := range options {
+		switch option.Ident() {
+		case identKeyOrder{}:
+			order = option.Value().(KeyOrder)
+		}
+	}
+
+	if m.protectedHeaders == nil {
+		return nil, errors.New(`message does not contain protected headers`)
+	}
+
+	ctx := context.TODO()
+	h, err := m.protectedHeaders.Clone(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to copy protected headers`)
+	}
+	h, err = h.Merge(ctx, m.unprotectedHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to merge unprotected headers`)
+	}
+
+	keys := make([]jwk.Key, 0, set.Len())
+	for i := 0; i < set.Len(); i++ {
+		key, _ := set.Get(i)
+		keys = append(keys, key)
+	}
+
+	recipients := m.recipients
+	if len(recipients) == 0 {
+		r := NewRecipient()
+		if err := r.SetHeaders(m.protectedHeaders); err != nil {
+			return nil, errors.Wrap(err, `failed to set headers to recipient`)
+		}
+		recipients = append(recipients, r)
+	}
+
+	var lastError error
+	for _, recipient := range recipients {
+		h2, err := h.Merge(ctx, recipient.Headers())
+		if err != nil {
+			lastError = errors.Wrap(err, `failed to merge recipient headers`)
+			continue
+		}
+
+		alg := h2.Algorithm()
+		candidates := make([]jwk.Key, 0, len(keys))
+		for _, key := range keys {
+			if compatibleKey(alg, key) {
+				candidates = append(candidates, key)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		// Only decrypt the key of this recipient
+		single := *m
+		if len(m.recipients) > 0 {
+			single.recipients = []Recipient{recipient}
+		}
+
+		for _, key := range order.Order(h2, candidates) {
+			var raw interface{}
+			if err := key.Raw(&raw); err != nil {
+				lastError = errors.Wrapf(err, `failed to retrieve raw key from %T`, key)
+				continue
+			}
+
+			plaintext, err := single.Decrypt(alg, raw, options...)
+			if err != nil {
+				if isMalformed(err) {
+					return nil, err
+				}
+				lastError = err
+				continue
+			}
+
+			if r, ok := order.(keyOrderRecorder); ok {
+				r.remember(key)
+			}
+			return plaintext, nil
+		}
+	}
+
+	if lastError != nil {
+		return nil, errors.Wrap(lastError, `failed to decrypt message with any of the keys in the jwk.Set object`)
+	}
+	return nil, errors.New(`none of the keys in the jwk.Set object can be used for the recipients of the message`)
+}
@@ -0,0 +1,292 @@
+package jwe
+
+import (
+	"crypto"
+	"crypto/subtle"
+	"io"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/pkg/errors"
+)
+
+// ContentTypeManifest is the value of the `cty` header of the JWS
+// messages created by `jwe.SignManifest()`
+const ContentTypeManifest = "jwe-manifest+json"
+
+// ErrManifestMismatch is returned when the plaintext, or a chunk of it,
+// does not match the digests recorded in a `jwe.Manifest`
+var ErrManifestMismatch = errors.New(`plaintext does not match manifest`)
+
+var manifestHashes = map[crypto.Hash]string{
+	crypto.SHA256: "SHA-256",
+	crypto.SHA384: "SHA-384",
+	crypto.SHA512: "SHA-512",
+}
+
+// Manifest describes a plaintext that is split into chunks of a fixed
+// size, by recording the digest of the whole plaintext and of each chunk.
+//
+// When large files are encrypted, the manifest can be signed using
+// `jwe.SignManifest()` and shipped next to the JWE message as a sidecar.
+// The recipient can then use `VerifyChunk()` to check each chunk as it
+// is received (e.g. when a download is resumed), and `Verify()` to
+// check the whole plaintext after decryption.
+type Manifest struct {
+	hash      crypto.Hash
+	size      int64
+	chunkSize int64
+	digest    []byte
+	chunks    [][]byte
+}
+
+type manifestJSON struct {
+	Algorithm string   `json:"alg"`
+	Size      int64    `json:"size"`
+	ChunkSize int64    `json:"chunk_size"`
+	Digest    string   `json:"digest"`
+	Chunks    []string `json:"chunks"`
+}
+
+// NewManifest reads the plaintext from `src` until EOF, and creates a
+// manifest using the hash function `h` (SHA-256, SHA-384 or SHA-512)
+// with chunks of `chunkSize` bytes. The last chunk may be shorter.
+func NewManifest(src io.Reader, h crypto.Hash, chunkSize int64) (*Manifest, error) {
+	if _, ok := manifestHashes[h]; !ok || !h.Available() {
+		return nil, errors.Errorf(`unsupported hash function %s`, h)
+	}
+	if chunkSize <= 0 {
+		return nil, errors.Errorf(`chunk size must be positive, got %d`, chunkSize)
+	}
+
+	m := &Manifest{
+		hash:      h,
+		chunkSize: chunkSize,
+	}
+
+	whole := h.New()
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(src, buf)
+		if n > 0 {
+			chunk := h.New()
+			chunk.Write(buf[:n])
+			m.chunks = append(m.chunks, chunk.Sum(nil))
+			whole.Write(buf[:n])
+			m.size += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to read plaintext`)
+		}
+	}
+	m.digest = whole.Sum(nil)
+	return m, nil
+}
+
+// Hash returns the hash function used to compute the digests
+func (m *Manifest) Hash() crypto.Hash {
+	return m.hash
+}
+
+// Size returns the size of the plaintext in bytes
+func (m *Manifest) Size() int64 {
+	return m.size
+}
+
+// ChunkSize returns the size of the chunks in bytes
+func (m *Manifest) ChunkSize() int64 {
+	return m.chunkSize
+}
+
+// ChunkCount returns the number of chunks
+func (m *Manifest) ChunkCount() int {
+	return len(m.chunks)
+}
+
+// Digest returns the digest of the whole plaintext
+func (m *Manifest) Digest() []byte {
+	return m.digest
+}
+
+// ChunkDigest returns the digest of the `i`-th chunk, starting from 0
+func (m *Manifest) ChunkDigest(i int) ([]byte, error) {
+	if i < 0 || i >= len(m.chunks) {
+		return nil, errors.Errorf(`chunk index %d out of range [0, %d)`, i, len(m.chunks))
+	}
+	return m.chunks[i], nil
+}
+
+// chunkLength returns the expected length of the `i`-th chunk
+func (m *Manifest) chunkLength(i int) int64 {
+	if i == len(m.chunks)-1 {
+		return m.size - int64(i)*m.chunkSize
+	}
+	return m.chunkSize
+}
+
+// VerifyChunk checks that `data` is the `i`-th chunk of the plaintext,
+// starting from 0. The chunk starts at offset `i * ChunkSize()`.
+func (m *Manifest) VerifyChunk(i int, data []byte) error {
+	expected, err := m.ChunkDigest(i)
+	if err != nil {
+		return err
+	}
+
+	if int64(len(data)) != m.chunkLength(i) {
+		return errors.Wrapf(ErrManifestMismatch, `chunk %d: expected %d bytes, got %d`, i, m.chunkLength(i), len(data))
+	}
+
+	h := m.hash.New()
+	h.Write(data)
+	if subtle.ConstantTimeCompare(h.Sum(nil), expected) != 1 {
+		return errors.Wrapf(ErrManifestMismatch, `chunk %d: digest mismatch`, i)
+	}
+	return nil
+}
+
+// Verify reads the plaintext from `src` until EOF, and checks that it
+// matches the manifest. If it does not, the error reports the first
+// chunk that did not match.
+func (m *Manifest) Verify(src io.Reader) error {
+	whole := m.hash.New()
+	buf := make([]byte, m.chunkSize)
+	var size int64
+	for i := 0; ; i++ {
+		n, err := io.ReadFull(src, buf)
+		if n > 0 {
+			if i >= len(m.chunks) {
+				return errors.Wrapf(ErrManifestMismatch, `plaintext is longer than %d bytes`, m.size)
+			}
+			if err := m.VerifyChunk(i, buf[:n]); err != nil {
+				return err
+			}
+			whole.Write(buf[:n])
+			size += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, `failed to read plaintext`)
+		}
+	}
+
+	if size != m.size {
+		return errors.Wrapf(ErrManifestMismatch, `expected %d bytes, got %d`, m.size, size)
+	}
+	if subtle.ConstantTimeCompare(whole.Sum(nil), m.digest) != 1 {
+		return errors.Wrap(ErrManifestMismatch, `digest mismatch`)
+	}
+	return nil
+}
+
+func (m *Manifest) MarshalJSON() ([]byte, error) {
+	proxy := manifestJSON{
+		Algorithm: manifestHashes[m.hash],
+		Size:      m.size,
+		ChunkSize: m.chunkSize,
+		Digest:    base64.EncodeToString(m.digest),
+		Chunks:    make([]string, len(m.chunks)),
+	}
+	for i, chunk := range m.chunks {
+		proxy.Chunks[i] = base64.EncodeToString(chunk)
+	}
+	return json.Marshal(proxy)
+}
+
+func (m *Manifest) UnmarshalJSON(buf []byte) error {
+	var proxy manifestJSON
+	if err := json.Unmarshal(buf, &proxy); err != nil {
+		return errors.Wrap(err, `failed to decode manifest`)
+	}
+
+	var h crypto.Hash
+	for candidate, name := range manifestHashes {
+		if name == proxy.Algorithm {
+			h = candidate
+			break
+		}
+	}
+	if h == 0 || !h.Available() {
+		return errors.Errorf(`unsupported hash function %q`, proxy.Algorithm)
+	}
+	if proxy.ChunkSize <= 0 || proxy.Size < 0 {
+		return errors.Errorf(`invalid size (%d) or chunk size (%d)`, proxy.Size, proxy.ChunkSize)
+	}
+	if expected := (proxy.Size + proxy.ChunkSize - 1) / proxy.ChunkSize; int64(len(proxy.Chunks)) != expected {
+		return errors.Errorf(`expected %d chunks, got %d`, expected, len(proxy.Chunks))
+	}
+
+	digest, err := decodeManifestDigest(proxy.Digest, h)
+	if err != nil {
+		return errors.Wrap(err, `invalid digest`)
+	}
+	chunks := make([][]byte, len(proxy.Chunks))
+	for i, s := range proxy.Chunks {
+		chunks[i], err = decodeManifestDigest(s, h)
+		if err != nil {
+			return errors.Wrapf(err, `invalid digest for chunk %d`, i)
+		}
+	}
+
+	*m = Manifest{
+		hash:      h,
+		size:      proxy.Size,
+		chunkSize: proxy.ChunkSize,
+		digest:    digest,
+		chunks:    chunks,
+	}
+	return nil
+}
+
+func decodeManifestDigest(s string, h crypto.Hash) ([]byte, error) {
+	buf, err := base64.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to base64 decode digest`)
+	}
+	if len(buf) != h.Size() {
+		return nil, errors.Errorf(`expected %d bytes, got %d`, h.Size(), len(buf))
+	}
+	return buf, nil
+}
+
+// SignManifest signs the manifest using jws, so that it can be shipped
+// alongside the JWE message that carries the (encrypted) plaintext. The
+// `cty` header of the result is set to `jwe.ContentTypeManifest`.
+func SignManifest(m *Manifest, alg jwa.SignatureAlgorithm, key interface{}) ([]byte, error) {
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal manifest`)
+	}
+
+	hdrs := jws.NewHeaders()
+	if err := hdrs.Set(jws.ContentTypeKey, ContentTypeManifest); err != nil {
+		return nil, errors.Wrap(err, `failed to set content type`)
+	}
+
+	signed, err := jws.Sign(payload, alg, key, jws.WithHeaders(hdrs))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to sign manifest`)
+	}
+	return signed, nil
+}
+
+// VerifyManifest verifies a manifest created by `jwe.SignManifest()`,
+// and returns it.
+func VerifyManifest(buf []byte, alg jwa.SignatureAlgorithm, key interface{}) (*Manifest, error) {
+	payload, err := jws.Verify(buf, alg, key)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to verify manifest`)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return nil, errors.Wrap(err, `failed to unmarshal manifest`)
+	}
+	return &m, nil
+}
@@ -93,10 +93,14 @@ func (m *Message) CipherText() []byte {
 	return m.cipherText
 }
 
+// InitializationVector returns the initialization vector used to
+// encrypt the content
 func (m *Message) InitializationVector() []byte {
 	return m.initializationVector
 }
 
+// Tag returns the authentication tag computed over the ciphertext
+// and the additional authenticated data
 func (m *Message) Tag() []byte {
 	return m.tag
 }
@@ -429,12 +433,29 @@ func (m *Message) makeDummyRecipient(enckeybuf string, protected Headers) error
 //
 // `key` must be a private key in its "raw" format (i.e. something like
 // *rsa.PrivateKey, instead of jwk.Key)
-func (m *Message) Decrypt(alg jwa.KeyEncryptionAlgorithm, key interface{}) ([]byte, error) {
+func (m *Message) Decrypt(alg jwa.KeyEncryptionAlgorithm, key interface{}, options ...DecryptOption) ([]byte, error) {
 	if pdebug.Enabled {
 		g := pdebug.FuncMarker()
 		defer g.End()
 	}
 
+	var keycache *DerivedKeyCache
+	var resolver DecryptionKeyResolver
+	var senderKey interface{}
+	resolveCtx := context.Background()
+	for _, option := range options {
+		switch option.Ident() {
+		case identDerivedKeyCache{}:
+			keycache = option.Value().(*DerivedKeyCache)
+		case identKeyResolver{}:
+			resolver = option.Value().(DecryptionKeyResolver)
+		case identContext{}:
+			resolveCtx = option.Value().(context.Context)
+		case identSenderKey{}:
+			senderKey = option.Value()
+		}
+	}
+
 	var err error
 	ctx := context.TODO()
 	h, err := m.protectedHeaders.Clone(ctx)
@@ -465,6 +486,9 @@ func (m *Message) Decrypt(alg jwa.KeyEncryptionAlgorithm, key interface{}) ([]by
 		ComputedAuthenticatedData(computedAad).
 		InitializationVector(m.initializationVector).
 		Tag(m.tag)
+	if keycache != nil {
+		dec.DerivedKeyCache(keycache)
+	}
 
 	var plaintext []byte
 	var lastError error
@@ -510,87 +534,27 @@ func (m *Message) Decrypt(alg jwa.KeyEncryptionAlgorithm, key interface{}) ([]by
 			continue
 		}
 
-		switch alg {
-		case jwa.ECDH_ES, jwa.ECDH_ES_A128KW, jwa.ECDH_ES_A192KW, jwa.ECDH_ES_A256KW:
-			epkif, ok := h2.Get(EphemeralPublicKeyKey)
-			if !ok {
-				return nil, errors.New("failed to get 'epk' field")
-			}
-			switch epk := epkif.(type) {
-			case jwk.ECDSAPublicKey:
-				var pubkey ecdsa.PublicKey
-				if err := epk.Raw(&pubkey); err != nil {
-					return nil, errors.Wrap(err, "failed to get public key")
-				}
-				dec.PublicKey(&pubkey)
-			case jwk.OKPPublicKey:
-				var pubkey interface{}
-				if err := epk.Raw(&pubkey); err != nil {
-					return nil, errors.Wrap(err, "failed to get public key")
-				}
-				dec.PublicKey(pubkey)
-			default:
-				return nil, errors.Errorf("unexpected 'epk' type %T for alg %s", epkif, alg)
-			}
-
-			if apu := h2.AgreementPartyUInfo(); len(apu) > 0 {
-				dec.AgreementPartyUInfo(apu)
-			}
-
-			if apv := h2.AgreementPartyVInfo(); len(apv) > 0 {
-				dec.AgreementPartyVInfo(apv)
-			}
-		case jwa.A128GCMKW, jwa.A192GCMKW, jwa.A256GCMKW:
-			ivB64, ok := h2.Get(InitializationVectorKey)
-			if !ok {
-				return nil, errors.New("failed to get 'iv' field")
-			}
-			ivB64Str, ok := ivB64.(string)
-			if !ok {
-				return nil, errors.Errorf("unexpected type for 'iv': %T", ivB64)
-			}
-			tagB64, ok := h2.Get(TagKey)
-			if !ok {
-				return nil, errors.New("failed to get 'tag' field")
-			}
-			tagB64Str, ok := tagB64.(string)
-			if !ok {
-				return nil, errors.Errorf("unexpected type for 'tag': %T", tagB64)
-			}
-			iv, err := base64.DecodeString(ivB64Str)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to b64-decode 'iv'")
-			}
-			tag, err := base64.DecodeString(tagB64Str)
+		if resolver != nil {
+			key, err := resolveDecryptionKey(resolveCtx, resolver, h2)
 			if err != nil {
-				return nil, errors.Wrap(err, "failed to b64-decode 'tag'")
-			}
-			dec.KeyInitializationVector(iv)
-			dec.KeyTag(tag)
-		case jwa.PBES2_HS256_A128KW, jwa.PBES2_HS384_A192KW, jwa.PBES2_HS512_A256KW:
-			saltB64, ok := h2.Get(SaltKey)
-			if !ok {
-				return nil, errors.New("failed to get 'p2s' field")
-			}
-			saltB64Str, ok := saltB64.(string)
-			if !ok {
-				return nil, errors.Errorf("unexpected type for 'p2s': %T", saltB64)
+				lastError = err
+				if pdebug.Enabled {
+					pdebug.Printf(`%s`, lastError)
+				}
+				continue
 			}
+			dec.privkey = key
+		}
 
-			count, ok := h2.Get(CountKey)
-			if !ok {
-				return nil, errors.New("failed to get 'p2c' field")
-			}
-			countFlt, ok := count.(float64)
-			if !ok {
-				return nil, errors.Errorf("unexpected type for 'p2c': %T", count)
+		if err := setKeyParameters(dec, alg, h2, senderKey); err != nil {
+			if isMalformed(err) {
+				return nil, err
 			}
-			salt, err := base64.DecodeString(saltB64Str)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to b64-decode 'salt'")
+			lastError = err
+			if pdebug.Enabled {
+				pdebug.Printf(`%s`, lastError)
 			}
-			dec.KeySalt(salt)
-			dec.KeyCount(int(countFlt))
+			continue
 		}
 
 		plaintext, err = dec.Decrypt(recipient.EncryptedKey(), m.cipherText)
@@ -613,7 +577,7 @@ func (m *Message) Decrypt(alg jwa.KeyEncryptionAlgorithm, key interface{}) ([]by
 			}
 			buf, err := uncompress(plaintext)
 			if err != nil {
-				lastError = errors.Wrap(err, `failed to uncompress payload`)
+				lastError = &categoryError{category: ErrDecryptFailed, err: errors.Wrap(err, `failed to uncompress payload`)}
 				if pdebug.Enabled {
 					pdebug.Printf(`%s`, lastError)
 				}
@@ -626,10 +590,163 @@ func (m *Message) Decrypt(alg jwa.KeyEncryptionAlgorithm, key interface{}) ([]by
 
 	if plaintext == nil {
 		if lastError != nil {
-			return nil, errors.Errorf(`failed to find matching recipient to decrypt key (last error = %s)`, lastError)
+			return nil, errors.Wrap(lastError, `failed to find matching recipient to decrypt key`)
 		}
 		return nil, errors.New("failed to find matching recipient")
 	}
 
 	return plaintext, nil
 }
+
+// setKeyParameters configures the decrypter with the parameters that
+// the key decryption algorithm reads from the recipient headers h,
+// such as the ephemeral public key or the PBES2 salt.
+func setKeyParameters(dec *Decrypter, alg jwa.KeyEncryptionAlgorithm, h Headers, senderKey interface{}) error {
+	switch alg {
+	case jwa.ECDH_ES, jwa.ECDH_ES_A128KW, jwa.ECDH_ES_A192KW, jwa.ECDH_ES_A256KW,
+		jwa.ECDH_1PU, jwa.ECDH_1PU_A128KW, jwa.ECDH_1PU_A192KW, jwa.ECDH_1PU_A256KW:
+		epkif, ok := h.Get(EphemeralPublicKeyKey)
+		if !ok {
+			return malformed(errors.New("failed to get 'epk' field"))
+		}
+		switch epk := epkif.(type) {
+		case jwk.ECDSAPublicKey:
+			var pubkey ecdsa.PublicKey
+			if err := epk.Raw(&pubkey); err != nil {
+				return malformed(errors.Wrap(err, "failed to get public key"))
+			}
+			dec.PublicKey(&pubkey)
+		case jwk.OKPPublicKey:
+			var pubkey interface{}
+			if err := epk.Raw(&pubkey); err != nil {
+				return malformed(errors.Wrap(err, "failed to get public key"))
+			}
+			dec.PublicKey(pubkey)
+		default:
+			return malformed(errors.Errorf("unexpected 'epk' type %T for alg %s", epkif, alg))
+		}
+
+		if apu := h.AgreementPartyUInfo(); len(apu) > 0 {
+			dec.AgreementPartyUInfo(apu)
+		}
+
+		if apv := h.AgreementPartyVInfo(); len(apv) > 0 {
+			dec.AgreementPartyVInfo(apv)
+		}
+
+		switch alg {
+		case jwa.ECDH_1PU, jwa.ECDH_1PU_A128KW, jwa.ECDH_1PU_A192KW, jwa.ECDH_1PU_A256KW:
+			sender, err := resolveSenderKey(senderKey, h.SenderKeyID())
+			if err != nil {
+				return err
+			}
+			dec.SenderPublicKey(sender)
+		}
+	case jwa.A128GCMKW, jwa.A192GCMKW, jwa.A256GCMKW:
+		ivB64, ok := h.Get(InitializationVectorKey)
+		if !ok {
+			return malformed(errors.New("failed to get 'iv' field"))
+		}
+		ivB64Str, ok := ivB64.(string)
+		if !ok {
+			return malformed(errors.Errorf("unexpected type for 'iv': %T", ivB64))
+		}
+		tagB64, ok := h.Get(TagKey)
+		if !ok {
+			return malformed(errors.New("failed to get 'tag' field"))
+		}
+		tagB64Str, ok := tagB64.(string)
+		if !ok {
+			return malformed(errors.Errorf("unexpected type for 'tag': %T", tagB64))
+		}
+		iv, err := base64.DecodeString(ivB64Str)
+		if err != nil {
+			return malformed(errors.Wrap(err, "failed to b64-decode 'iv'"))
+		}
+		tag, err := base64.DecodeString(tagB64Str)
+		if err != nil {
+			return malformed(errors.Wrap(err, "failed to b64-decode 'tag'"))
+		}
+		dec.KeyInitializationVector(iv)
+		dec.KeyTag(tag)
+	case jwa.PBES2_HS256_A128KW, jwa.PBES2_HS384_A192KW, jwa.PBES2_HS512_A256KW:
+		saltB64, ok := h.Get(SaltKey)
+		if !ok {
+			return malformed(errors.New("failed to get 'p2s' field"))
+		}
+		saltB64Str, ok := saltB64.(string)
+		if !ok {
+			return malformed(errors.Errorf("unexpected type for 'p2s': %T", saltB64))
+		}
+
+		count, ok := h.Get(CountKey)
+		if !ok {
+			return malformed(errors.New("failed to get 'p2c' field"))
+		}
+		countFlt, ok := count.(float64)
+		if !ok {
+			return malformed(errors.Errorf("unexpected type for 'p2c': %T", count))
+		}
+		salt, err := base64.DecodeString(saltB64Str)
+		if err != nil {
+			return malformed(errors.Wrap(err, "failed to b64-decode 'salt'"))
+		}
+		dec.KeySalt(salt)
+		dec.KeyCount(int(countFlt))
+	}
+	return nil
+}
+
+// ComputedAuthenticatedData returns the additional authenticated data that
+// is used to compute the authentication tag. This is the encoded protected
+// headers, followed by a '.' and the base64 encoded value of the "aad"
+// field if it exists.
+func (m *Message) ComputedAuthenticatedData() ([]byte, error) {
+	if m.protectedHeaders == nil {
+		return nil, errors.New(`message does not contain protected headers`)
+	}
+
+	computedAad, err := m.protectedHeaders.Encode()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode protected headers")
+	}
+
+	if aad := m.authenticatedData; aad != nil {
+		computedAad = append(append(computedAad, '.'), base64.Encode(aad)...)
+	}
+	return computedAad, nil
+}
+
+// VerifyIntegrity checks that the authentication tag of the message
+// matches the ciphertext, initialization vector and additional authenticated
+// data, using the given content encryption key (CEK). This allows you
+// to periodically check the integrity of archived messages without
+// having to release the plaintext: the decrypted content, if any, is
+// discarded before returning.
+//
+// Note that the CEK must be obtained separately, as this method
+// does not perform key decryption.
+func (m *Message) VerifyIntegrity(cek []byte) error {
+	if m.protectedHeaders == nil {
+		return errors.New(`message does not contain protected headers`)
+	}
+
+	cipher, err := NewDecrypter("", m.protectedHeaders.ContentEncryption(), nil).ContentCipher()
+	if err != nil {
+		return errors.Wrap(err, `failed to create content cipher`)
+	}
+
+	computedAad, err := m.ComputedAuthenticatedData()
+	if err != nil {
+		return errors.Wrap(err, `failed to compute authenticated data`)
+	}
+
+	plaintext, err := cipher.Decrypt(cek, m.initializationVector, m.cipherText, m.tag, computedAad)
+	for i := range plaintext {
+		plaintext[i] = 0
+	}
+	if err != nil {
+		return errors.Wrap(err, `failed to verify authentication tag`)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package jwe
+
+import (
+	"strings"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+)
+
+// ContentTypeJWE is the value of the `cty` header that denotes that the
+// payload of a JWE message is another JWE message.
+const ContentTypeJWE = "JWE"
+
+// DefaultMaxUnwrapDepth is the maximum number of layers that `jwe.Unwrap()`
+// peels off when a non-positive maximum depth is given.
+const DefaultMaxUnwrapDepth = 5
+
+// KeyResolver is used by `jwe.Unwrap()` to determine the algorithm and key
+// to decrypt each layer of a nested JWE message. `depth` starts at 1 for the
+// outermost layer.
+type KeyResolver interface {
+	ResolveKey(depth int, msg *Message) (jwa.KeyEncryptionAlgorithm, interface{}, error)
+}
+
+// KeyResolverFunc is a function that implements the KeyResolver interface.
+type KeyResolverFunc func(int, *Message) (jwa.KeyEncryptionAlgorithm, interface{}, error)
+
+func (f KeyResolverFunc) ResolveKey(depth int, msg *Message) (jwa.KeyEncryptionAlgorithm, interface{}, error) {
+	return f(depth, msg)
+}
+
+// Wrap encrypts an existing JWE message inside another JWE message,
+// which is useful when the message passes through intermediaries that
+// must each remove their own layer of encryption (e.g. broker mediated
+// delivery). The `cty` header of the outer layer is set to "JWE".
+//
+// `inner` must be a valid JWE message, in either compact or JSON format.
+// The remaining parameters are the same as those for `jwe.Encrypt()`.
+func Wrap(inner []byte, keyalg jwa.KeyEncryptionAlgorithm, key interface{}, contentalg jwa.ContentEncryptionAlgorithm, compressalg jwa.CompressionAlgorithm, options ...EncryptOption) ([]byte, error) {
+	var guard *KeyUsageGuard
+	var monitor *RandomnessMonitor
+	var kid string
+	var sender interface{}
+	for _, option := range options {
+		switch option.Ident() {
+		case identKeyUsageGuard{}:
+			guard = option.Value().(*KeyUsageGuard)
+		case identRandomnessMonitor{}:
+			monitor = option.Value().(*RandomnessMonitor)
+		case identKeyID{}:
+			kid = option.Value().(string)
+		case identSenderKey{}:
+			sender = option.Value()
+		}
+	}
+
+	if _, err := Parse(inner); err != nil {
+		return nil, errors.Wrap(err, `inner payload is not a valid JWE message`)
+	}
+
+	return encrypt(inner, keyalg, key, contentalg, compressalg, ContentTypeJWE, kid, sender, guard, monitor)
+}
+
+// Unwrap decrypts a nested JWE message created by `jwe.Wrap()`. Layers
+// are peeled off as long as the protected `cty` header of the current
+// layer is "JWE", and the innermost plaintext is returned along with the
+// messages that were decrypted, outermost first.
+//
+// At most `maxDepth` layers are decrypted: if the message is nested any
+// deeper, an error is returned. If `maxDepth` is less than or equal to 0,
+// `jwe.DefaultMaxUnwrapDepth` is used.
+func Unwrap(buf []byte, maxDepth int, resolver KeyResolver, options ...DecryptOption) ([]byte, []*Message, error) {
+	if resolver == nil {
+		return nil, nil, errors.New(`key resolver must be specified`)
+	}
+
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxUnwrapDepth
+	}
+
+	var layers []*Message
+	for depth := 1; ; depth++ {
+		msg, err := Parse(buf, parseOptions(options)...)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, `failed to parse layer %d`, depth)
+		}
+
+		alg, key, err := resolver.ResolveKey(depth, msg)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, `failed to resolve key for layer %d`, depth)
+		}
+
+		if jwkKey, ok := key.(jwk.Key); ok {
+			var raw interface{}
+			if err := jwkKey.Raw(&raw); err != nil {
+				return nil, nil, errors.Wrapf(err, `failed to retrieve raw key from %T`, key)
+			}
+			key = raw
+		}
+
+		payload, err := msg.Decrypt(alg, key, options...)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, `failed to decrypt layer %d`, depth)
+		}
+		layers = append(layers, msg)
+
+		if !strings.EqualFold(msg.ProtectedHeaders().ContentType(), ContentTypeJWE) {
+			return payload, layers, nil
+		}
+
+		if depth >= maxDepth {
+			return nil, nil, errors.Errorf(`nested JWE message exceeds maximum depth (%d)`, maxDepth)
+		}
+		buf = payload
+	}
+}
@@ -1,9 +1,23 @@
 package jwe
 
-import "github.com/lestrrat-go/option"
+import (
+	"context"
+
+	"github.com/lestrrat-go/option"
+)
 
 type Option = option.Interface
 type identPrettyFormat struct{}
+type identDerivedKeyCache struct{}
+type identKeyUsageGuard struct{}
+type identContentType struct{}
+type identKeyID struct{}
+type identRandomnessMonitor struct{}
+type identKeyResolver struct{}
+type identKeyOrder struct{}
+type identContext struct{}
+type identSenderKey struct{}
+type identMaxMessageSize struct{}
 type SerializerOption interface {
 	Option
 	serializerOption()
@@ -20,3 +34,144 @@ func (*serializerOption) serializerOption() {}
 func WithPrettyFormat(b bool) SerializerOption {
 	return &serializerOption{option.New(identPrettyFormat{}, b)}
 }
+
+// DecryptOption describes options that can be passed to `jwe.Decrypt()`
+// and `(*jwe.Message).Decrypt()`
+type DecryptOption interface {
+	Option
+	decryptOption()
+}
+
+type decryptOption struct {
+	Option
+}
+
+func (*decryptOption) decryptOption() {}
+
+// WithDerivedKeyCache specifies the cache to use for keys derived
+// via ECDH-ES key agreement (jwa.ECDH_ES, jwa.ECDH_ES_A128KW, etc).
+// See `jwe.DerivedKeyCache` for details.
+func WithDerivedKeyCache(c *DerivedKeyCache) DecryptOption {
+	return &decryptOption{option.New(identDerivedKeyCache{}, c)}
+}
+
+// WithKeyResolver specifies the resolver that is used to look up the
+// decryption key for each recipient of the message, based on its headers.
+// When specified, the key passed to `jwe.Decrypt()` is ignored, and may
+// be nil. See `jwe.CachingKeyResolver` for caching the resolved keys.
+func WithKeyResolver(r DecryptionKeyResolver) DecryptOption {
+	return &decryptOption{option.New(identKeyResolver{}, r)}
+}
+
+// WithContext specifies the context that is passed to the resolver
+// given by `jwe.WithKeyResolver()`. If not specified, `context.Background()`
+// is used.
+func WithContext(ctx context.Context) DecryptOption {
+	return &decryptOption{option.New(identContext{}, ctx)}
+}
+
+// WithSenderPublicKey specifies the public key of the sender, which is
+// required to decrypt messages encrypted with ECDH-1PU (jwa.ECDH_1PU,
+// jwa.ECDH_1PU_A128KW, etc). The key may be a raw key, a jwk.Key, or a
+// jwk.Set, in which case the key is looked up using the "skid" header
+// of the message.
+//
+// A message that was not created using the private key of the sender
+// fails to decrypt, so a successful decryption authenticates the sender.
+func WithSenderPublicKey(key interface{}) DecryptOption {
+	return &decryptOption{option.New(identSenderKey{}, key)}
+}
+
+// WithKeyOrder specifies the order in which `jwe.DecryptWithKeySet()`
+// tries the keys of the set for each recipient. See `jwe.KeyIDMatchFirst`,
+// `jwe.AlgorithmMatchFirst` and `jwe.LastSuccessFirst`.
+func WithKeyOrder(o KeyOrder) DecryptOption {
+	return &decryptOption{option.New(identKeyOrder{}, o)}
+}
+
+// ParseOption describes options that can be passed to `jwe.Parse()`.
+// All ParseOptions are also DecryptOptions, so that they can be
+// passed to `jwe.Decrypt()`, which parses the message first.
+type ParseOption interface {
+	DecryptOption
+	parseOption()
+}
+
+type parseOption struct {
+	Option
+}
+
+func (*parseOption) decryptOption() {}
+func (*parseOption) parseOption()   {}
+
+// WithMaxMessageSize specifies the maximum size in bytes of the serialized
+// message accepted by `jwe.Parse()` and its variants, as well as
+// `jwe.Decrypt()`. Larger messages are rejected before any of their
+// contents are decoded. By default there is no limit.
+func WithMaxMessageSize(n int64) ParseOption {
+	return &parseOption{option.New(identMaxMessageSize{}, n)}
+}
+
+// parseOptions extracts the ParseOptions from options
+func parseOptions(options []DecryptOption) []ParseOption {
+	var ret []ParseOption
+	for _, o := range options {
+		if po, ok := o.(ParseOption); ok {
+			ret = append(ret, po)
+		}
+	}
+	return ret
+}
+
+// EncryptOption describes options that can be passed to `jwe.Encrypt()`
+type EncryptOption interface {
+	Option
+	encryptOption()
+}
+
+type encryptOption struct {
+	Option
+}
+
+func (*encryptOption) encryptOption() {}
+
+// WithKeyUsageGuard specifies the guard that counts the number of
+// messages encrypted with the same key when the "dir" key management
+// algorithm is used along with AES-GCM. See `jwe.KeyUsageGuard` for details.
+// The guard has no effect on other combinations of algorithms.
+func WithKeyUsageGuard(g *KeyUsageGuard) EncryptOption {
+	return &encryptOption{option.New(identKeyUsageGuard{}, g)}
+}
+
+// WithContentType specifies the value of the `cty` header of the message
+// created by `jwe.Encrypt()`, e.g. `jwe.ContentTypeJWT` for nested JWTs.
+// See `jwe.DecryptContent()` for the receiving side.
+func WithContentType(cty string) EncryptOption {
+	return &encryptOption{option.New(identContentType{}, cty)}
+}
+
+// WithKeyID specifies the value of the `kid` header of the message
+// created by `jwe.Encrypt()`, so that the recipient can look up the key
+// (see `jwe.WithKeyResolver()`). The header is not set by default, even
+// if the key is a jwk.Key with a key ID.
+func WithKeyID(kid string) EncryptOption {
+	return &encryptOption{option.New(identKeyID{}, kid)}
+}
+
+// WithRandomnessMonitor specifies the monitor that checks the randomness
+// used by `jwe.Encrypt()`. See `jwe.RandomnessMonitor` for details.
+func WithRandomnessMonitor(m *RandomnessMonitor) EncryptOption {
+	return &encryptOption{option.New(identRandomnessMonitor{}, m)}
+}
+
+// WithSenderKey specifies the private key of the sender, which is
+// required to encrypt messages with ECDH-1PU (jwa.ECDH_1PU,
+// jwa.ECDH_1PU_A128KW, etc). Unlike ECDH-ES (anonymous encryption), the
+// recipient can then authenticate the sender using the corresponding
+// public key. See `jwe.WithSenderPublicKey()`.
+//
+// If a jwk.Key with a key ID is given, the "skid" header is set to its
+// key ID, so that the recipient can look up the sender's public key.
+func WithSenderKey(key interface{}) EncryptOption {
+	return &encryptOption{option.New(identSenderKey{}, key)}
+}
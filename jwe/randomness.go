@@ -0,0 +1,143 @@
+package jwe
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultRandomnessMonitorWindow is the number of recent messages that
+// a RandomnessMonitor remembers when no window size is specified
+const DefaultRandomnessMonitorWindow = 4096
+
+// RandomnessMonitor is an opt-in ("paranoid mode") self-check for the
+// randomness used by `jwe.Encrypt()`. It asserts that the content
+// encryption keys (CEK) and initialization vectors (IV) of recently
+// encrypted messages are unique, and that the system random number
+// generator does not return repeated values.
+//
+// All of these values are generated at random, so a repeated value
+// means that the random number generator is broken, e.g. because a
+// virtual machine or container was cloned along with the state of its
+// random number generator. Reusing a CEK/IV pair completely breaks the
+// security of AES-GCM, so when a repetition is detected `jwe.Encrypt()`
+// fails with an error that matches `jwe.ErrRandomnessFailure`, instead
+// of returning the message.
+//
+// Pass the monitor to `jwe.Encrypt()` using the `jwe.WithRandomnessMonitor()`
+// option. Values are remembered by their SHA-256 digest, so the monitor
+// does not retain key material. RandomnessMonitor is safe for
+// concurrent use, and should be shared by all encryptions in a process.
+type RandomnessMonitor struct {
+	mu      sync.Mutex
+	window  int
+	seen    map[[sha256.Size]byte]struct{}
+	recent  [][sha256.Size]byte // ring buffer of the digests in `seen`
+	next    int
+	sample  [32]byte
+	sampled bool
+}
+
+// NewRandomnessMonitor creates a new RandomnessMonitor that remembers the
+// CEKs and IVs of the last `window` messages. If `window` is less than
+// or equal to 0, `jwe.DefaultRandomnessMonitorWindow` is used.
+func NewRandomnessMonitor(window int) *RandomnessMonitor {
+	if window <= 0 {
+		window = DefaultRandomnessMonitorWindow
+	}
+	return &RandomnessMonitor{
+		window: window,
+		seen:   make(map[[sha256.Size]byte]struct{}),
+		// each message records up to two values
+		recent: make([][sha256.Size]byte, 0, window*2),
+	}
+}
+
+// checkSource reads a sample from the random number generator, and
+// makes sure that it differs from the previous sample and is not a
+// single repeated byte
+func (m *RandomnessMonitor) checkSource(src io.Reader) error {
+	var sample [32]byte
+	if _, err := io.ReadFull(src, sample[:]); err != nil {
+		return errors.Wrap(err, `failed to read from random number generator`)
+	}
+
+	stuck := true
+	for _, b := range sample[1:] {
+		if b != sample[0] {
+			stuck = false
+			break
+		}
+	}
+	if stuck {
+		return &categoryError{category: ErrRandomnessFailure, err: errors.New(`random number generator returned a constant sample`)}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sampled && sample == m.sample {
+		return &categoryError{category: ErrRandomnessFailure, err: errors.New(`random number generator returned the same sample twice`)}
+	}
+	m.sample = sample
+	m.sampled = true
+	return nil
+}
+
+// record remembers the CEK (which may be nil, e.g. for "dir" where the
+// same key is used on purpose) and the IV of a message, and returns an
+// error if either has been seen recently
+func (m *RandomnessMonitor) record(cek, iv []byte) error {
+	var digests [][sha256.Size]byte
+	if cek != nil {
+		digests = append(digests, digest('k', cek))
+	}
+	if iv != nil {
+		digests = append(digests, digest('i', iv))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, d := range digests {
+		if _, ok := m.seen[d]; ok {
+			name := "IV"
+			if i == 0 && cek != nil {
+				name = "content encryption key"
+			}
+			return &categoryError{category: ErrRandomnessFailure, err: errors.Errorf(`%s was reused within the last %d messages`, name, m.window)}
+		}
+	}
+
+	for _, d := range digests {
+		if len(m.recent) < cap(m.recent) {
+			m.recent = append(m.recent, d)
+		} else {
+			delete(m.seen, m.recent[m.next])
+			m.recent[m.next] = d
+			m.next = (m.next + 1) % len(m.recent)
+		}
+		m.seen[d] = struct{}{}
+	}
+	return nil
+}
+
+// digest computes the digest of a value, prefixed by its kind so that
+// a CEK and an IV with the same bytes are not confused
+func digest(kind byte, v []byte) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte{kind})
+	h.Write(v)
+	var ret [sha256.Size]byte
+	copy(ret[:], h.Sum(nil))
+	return ret
+}
+
+func checkRandomness(m *RandomnessMonitor) error {
+	if m == nil {
+		return nil
+	}
+	return m.checkSource(rand.Reader)
+}
@@ -0,0 +1,70 @@
+package jwe
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandomnessMonitor(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Repeated values", func(t *testing.T) {
+		t.Parallel()
+		m := NewRandomnessMonitor(2)
+
+		if !assert.NoError(t, m.record([]byte("cek-1"), []byte("iv-1")), `first message should pass`) {
+			return
+		}
+		if !assert.NoError(t, m.record([]byte("cek-2"), []byte("iv-2")), `second message should pass`) {
+			return
+		}
+
+		err := m.record([]byte("cek-1"), []byte("iv-3"))
+		if !assert.True(t, errors.Is(err, ErrRandomnessFailure), `reused CEK should be detected (got %v)`, err) {
+			return
+		}
+		err = m.record([]byte("cek-3"), []byte("iv-2"))
+		if !assert.True(t, errors.Is(err, ErrRandomnessFailure), `reused IV should be detected (got %v)`, err) {
+			return
+		}
+
+		// CEK and IV are not confused with each other
+		if !assert.NoError(t, m.record([]byte("iv-1-cek"), []byte("cek-2-iv")), `distinct message should pass`) {
+			return
+		}
+		if !assert.NoError(t, m.record(nil, []byte("cek-1")), `IV equal to an earlier CEK should pass`) {
+			return
+		}
+
+		// "cek-1"/"iv-1" fell out of the window
+		if !assert.NoError(t, m.record([]byte("cek-1"), []byte("iv-1")), `values outside of the window should pass`) {
+			return
+		}
+	})
+	t.Run("Random source", func(t *testing.T) {
+		t.Parallel()
+		m := NewRandomnessMonitor(0)
+
+		random := make([]byte, 64)
+		for i := range random {
+			random[i] = byte(i * 7)
+		}
+		if !assert.NoError(t, m.checkSource(bytes.NewReader(random[:32])), `first sample should pass`) {
+			return
+		}
+		err := m.checkSource(bytes.NewReader(random[:32]))
+		if !assert.True(t, errors.Is(err, ErrRandomnessFailure), `repeated sample should be detected (got %v)`, err) {
+			return
+		}
+		if !assert.NoError(t, m.checkSource(bytes.NewReader(random[32:])), `different sample should pass`) {
+			return
+		}
+		err = m.checkSource(bytes.NewReader(make([]byte, 32)))
+		if !assert.True(t, errors.Is(err, ErrRandomnessFailure), `constant sample should be detected (got %v)`, err) {
+			return
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package jwe
+
+import (
+	"context"
+	"time"
+
+	"github.com/lestrrat-go/jwx/internal/ttlcache"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+)
+
+// DecryptionKeyResolver is used to look up the key to decrypt a message
+// based on its headers, e.g. to fetch the symmetric key for the "dir"
+// algorithm from a secrets manager using the "kid" header. Senders
+// using `jwe.Encrypt()` set the header using `jwe.WithKeyID()`.
+//
+// `h` contains the protected, unprotected and per-recipient headers of
+// the message, merged. The key may be either a raw key or a jwk.Key.
+type DecryptionKeyResolver interface {
+	ResolveDecryptionKey(ctx context.Context, h Headers) (interface{}, error)
+}
+
+// DecryptionKeyResolverFunc is a function that implements the
+// DecryptionKeyResolver interface.
+type DecryptionKeyResolverFunc func(context.Context, Headers) (interface{}, error)
+
+func (f DecryptionKeyResolverFunc) ResolveDecryptionKey(ctx context.Context, h Headers) (interface{}, error) {
+	return f(ctx, h)
+}
+
+func resolveDecryptionKey(ctx context.Context, r DecryptionKeyResolver, h Headers) (interface{}, error) {
+	key, err := r.ResolveDecryptionKey(ctx, h)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to resolve decryption key`)
+	}
+
+	if jwkKey, ok := key.(jwk.Key); ok {
+		var raw interface{}
+		if err := jwkKey.Raw(&raw); err != nil {
+			return nil, errors.Wrapf(err, `failed to retrieve raw key from %T`, key)
+		}
+		key = raw
+	}
+	return key, nil
+}
+
+// CachingKeyResolver caches the keys returned by another
+// DecryptionKeyResolver by the "kid" header, so that the secrets
+// manager (or any other backend) is not consulted for every message.
+// Keys of messages that do not have a "kid" header are never cached,
+// and neither are errors.
+//
+// It is safe for concurrent use. Note that the cache holds key material
+// in memory: use the TTL and the maximum number of entries to limit how
+// long and how many keys are retained, and `Invalidate()` to drop a key
+// that has been revoked.
+type CachingKeyResolver struct {
+	resolver DecryptionKeyResolver
+	cache    *ttlcache.Cache
+}
+
+// NewCachingKeyResolver creates a new CachingKeyResolver that wraps `r`.
+// A resolved key is reused for `ttl` before `r` is consulted again, and
+// the keys of at most `maxEntries` key IDs are kept, the oldest being
+// dropped first. Pass 0 to disable either limit.
+func NewCachingKeyResolver(r DecryptionKeyResolver, maxEntries int, ttl time.Duration) *CachingKeyResolver {
+	return &CachingKeyResolver{
+		resolver: r,
+		cache:    ttlcache.New(maxEntries, ttl),
+	}
+}
+
+// ResolveDecryptionKey returns the cached key for the "kid" header of
+// `h`, or resolves it using the underlying resolver.
+func (c *CachingKeyResolver) ResolveDecryptionKey(ctx context.Context, h Headers) (interface{}, error) {
+	kid := h.KeyID()
+	if kid == "" {
+		return c.resolver.ResolveDecryptionKey(ctx, h)
+	}
+
+	if key, ok := c.cache.Get(kid); ok {
+		return key, nil
+	}
+
+	key, err := c.resolver.ResolveDecryptionKey(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.Set(kid, key, time.Time{})
+	return key, nil
+}
+
+// Invalidate removes the key with the given key ID from the cache.
+func (c *CachingKeyResolver) Invalidate(kid string) {
+	c.cache.Remove(kid)
+}
+
+// Len returns the number of entries in the cache, including those
+// that have expired but have not been evicted yet.
+func (c *CachingKeyResolver) Len() int {
+	return c.cache.Len()
+}
+
+// Clear removes all entries from the cache.
+func (c *CachingKeyResolver) Clear() {
+	c.cache.Clear()
+}
+
+// resolveSenderKey returns the raw public key of the sender given by
+// `jwe.WithSenderPublicKey()`. If it is a jwk.Set, the key is looked up
+// using `skid`, the "skid" header of the message
+func resolveSenderKey(key interface{}, skid string) (interface{}, error) {
+	if key == nil {
+		return nil, errors.New(`sender public key is required for ECDH-1PU (see jwe.WithSenderPublicKey)`)
+	}
+
+	if set, ok := key.(jwk.Set); ok {
+		if skid == "" {
+			return nil, errors.New(`"skid" header is required to look up the sender public key`)
+		}
+		found, ok := set.LookupKeyID(skid)
+		if !ok {
+			return nil, errors.Errorf(`sender public key %q not found`, skid)
+		}
+		key = found
+	}
+
+	if jwkKey, ok := key.(jwk.Key); ok {
+		pubkey, err := jwk.PublicKeyOf(jwkKey)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to obtain public key of sender`)
+		}
+		var raw interface{}
+		if err := pubkey.Raw(&raw); err != nil {
+			return nil, errors.Wrapf(err, `failed to retrieve raw key from %T`, key)
+		}
+		key = raw
+	}
+	return key, nil
+}
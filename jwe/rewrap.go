@@ -0,0 +1,290 @@
+package jwe
+
+import (
+	"context"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwe/internal/cipher"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+)
+
+// recipientHeaderKeys lists the header parameters that describe how the
+// CEK was encrypted for a particular recipient. These are replaced
+// when a message is re-addressed using `jwe.Rewrap()`
+var recipientHeaderKeys = []string{
+	AlgorithmKey,
+	KeyIDKey,
+	EphemeralPublicKeyKey,
+	AgreementPartyUInfoKey,
+	AgreementPartyVInfoKey,
+	InitializationVectorKey,
+	TagKey,
+	SaltKey,
+	CountKey,
+	SenderKeyIDKey,
+	JWKKey,
+	JWKSetURLKey,
+	X509CertChainKey,
+	X509CertThumbprintKey,
+	X509CertThumbprintS256Key,
+	X509URLKey,
+}
+
+// Rewrap re-addresses the message to a new recipient, without decrypting
+// its content. The content encryption key (CEK) is decrypted using `key`,
+// the private (or shared) key of one of the current recipients, and
+// is then encrypted for `newKey` using the key encryption algorithm `alg`.
+//
+// The returned message has a single recipient, and contains the same
+// ciphertext and initialization vector as the original message. Use
+// `jwe.Compact()` or `json.Marshal()` to serialize it.
+//
+// Messages encrypted using ECDH-1PU require the sender's public key to
+// decrypt the CEK, and cannot be re-addressed using this function. Use
+// `jwe.RewrapWithCEK()` instead, which also describes the details.
+func Rewrap(msg *Message, key interface{}, newKey interface{}, alg jwa.KeyEncryptionAlgorithm) (*Message, error) {
+	if jwkKey, ok := key.(jwk.Key); ok {
+		var raw interface{}
+		if err := jwkKey.Raw(&raw); err != nil {
+			return nil, errors.Wrapf(err, `failed to retrieve raw key from %T`, key)
+		}
+		key = raw
+	}
+
+	cek, err := msg.decryptKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to decrypt content encryption key`)
+	}
+	defer func() {
+		for i := range cek {
+			cek[i] = 0
+		}
+	}()
+
+	return RewrapWithCEK(msg, cek, newKey, alg)
+}
+
+// RewrapWithCEK re-addresses the message to a new recipient, given its
+// content encryption key (CEK). The CEK is encrypted for `newKey`
+// using the key encryption algorithm `alg`, which must be an algorithm
+// that wraps the CEK: "dir", "ECDH-ES" and the "ECDH-1PU" family
+// cannot be used, as they determine the CEK themselves.
+//
+// The CEK is checked against the authentication tag before it is
+// encrypted for the new recipient. Header parameters that were specific
+// to the previous recipients (such as "alg", "kid" or "epk") are removed
+// from the message. When those parameters were part of the protected
+// header, as in messages produced by `jwe.Encrypt()`, the protected
+// header is rewritten and the authentication tag is recomputed
+// using the CEK. The content itself is never decrypted. If `newKey` is
+// a jwk.Key with a key ID, the "kid" header of the new recipient is set
+// to its key ID.
+//
+// Note that if the message was encrypted using "dir", the CEK is the
+// shared key, which will then be disclosed to the new recipient.
+func RewrapWithCEK(msg *Message, cek []byte, newKey interface{}, alg jwa.KeyEncryptionAlgorithm) (*Message, error) {
+	switch alg {
+	case jwa.DIRECT, jwa.ECDH_ES, jwa.ECDH_1PU, jwa.ECDH_1PU_A128KW, jwa.ECDH_1PU_A192KW, jwa.ECDH_1PU_A256KW:
+		return nil, unsupported(errors.Errorf(`%s cannot be used to re-address a message`, alg))
+	}
+
+	if msg.protectedHeaders == nil {
+		return nil, errors.New(`message does not contain protected headers`)
+	}
+
+	contentalg := msg.protectedHeaders.ContentEncryption()
+	contentcipher, err := cipher.NewAES(contentalg)
+	if err != nil {
+		return nil, unsupported(errors.Wrapf(err, `failed to create content cipher for %s`, contentalg))
+	}
+
+	if len(cek) != contentcipher.KeySize() {
+		return nil, errors.Errorf(`invalid content encryption key size %d for %s`, len(cek), contentalg)
+	}
+
+	enc, kid, err := newKeyEncrypter(alg, newKey, contentalg, contentcipher.KeySize(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	recipient := NewRecipient()
+	if err := recipient.Headers().Set(AlgorithmKey, enc.Algorithm()); err != nil {
+		return nil, errors.Wrap(err, `failed to set header`)
+	}
+	if kid != "" {
+		if err := recipient.Headers().Set(KeyIDKey, kid); err != nil {
+			return nil, errors.Wrap(err, `failed to set header`)
+		}
+	}
+
+	enckey, err := enc.Encrypt(cek)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to encrypt key`)
+	}
+	if err := recipient.SetEncryptedKey(enckey.Bytes()); err != nil {
+		return nil, errors.Wrap(err, `failed to set encrypted key`)
+	}
+	if hp, ok := enckey.(populater); ok {
+		if err := hp.Populate(recipient.Headers()); err != nil {
+			return nil, errors.Wrap(err, `failed to populate`)
+		}
+	}
+
+	ctx := context.TODO()
+	protected, err := msg.protectedHeaders.Clone(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to copy protected headers`)
+	}
+	readdressed, err := removeRecipientHeaders(protected)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to remove recipient headers from protected headers`)
+	}
+
+	var unprotected Headers
+	if msg.unprotectedHeaders != nil {
+		unprotected, err = msg.unprotectedHeaders.Clone(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to copy unprotected headers`)
+		}
+		if _, err := removeRecipientHeaders(unprotected); err != nil {
+			return nil, errors.Wrap(err, `failed to remove recipient headers from unprotected headers`)
+		}
+	}
+
+	// Keep the layout that `jwe.Encrypt()` produces for a single
+	// recipient: if the recipient headers were protected, so are
+	// the new ones.
+	if readdressed {
+		protected, err = protected.Merge(ctx, recipient.Headers())
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to merge protected headers`)
+		}
+	}
+
+	computedAad, err := msg.ComputedAuthenticatedData()
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to compute authenticated data`)
+	}
+
+	newAad, err := protected.Encode()
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to encode protected headers`)
+	}
+	if aad := msg.authenticatedData; aad != nil {
+		newAad = append(append(newAad, '.'), base64.Encode(aad)...)
+	}
+
+	tag, err := contentcipher.Retag(cek, msg.initializationVector, msg.cipherText, msg.tag, computedAad, newAad)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to verify authentication tag`)
+	}
+
+	ret := NewMessage()
+	ret.authenticatedData = msg.authenticatedData
+	ret.cipherText = msg.cipherText
+	ret.initializationVector = msg.initializationVector
+	ret.protectedHeaders = protected
+	ret.unprotectedHeaders = unprotected
+	ret.recipients = []Recipient{recipient}
+	ret.tag = tag
+	return ret, nil
+}
+
+// removeRecipientHeaders removes the recipient specific parameters
+// from h, and reports if any of them were present
+func removeRecipientHeaders(h Headers) (bool, error) {
+	var removed bool
+	for _, key := range recipientHeaderKeys {
+		if _, ok := h.Get(key); !ok {
+			continue
+		}
+		if err := h.Remove(key); err != nil {
+			return false, errors.Wrapf(err, `failed to remove %#v`, key)
+		}
+		removed = true
+	}
+	return removed, nil
+}
+
+// decryptKey decrypts the content encryption key using the first
+// recipient that the key can be used for.
+func (m *Message) decryptKey(key interface{}) ([]byte, error) {
+	if m.protectedHeaders == nil {
+		return nil, errors.New(`message does not contain protected headers`)
+	}
+
+	ctx := context.TODO()
+	h, err := m.protectedHeaders.Clone(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to copy protected headers`)
+	}
+	h, err = h.Merge(ctx, m.unprotectedHeaders)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to merge unprotected headers`)
+	}
+
+	contentalg := m.protectedHeaders.ContentEncryption()
+	contentcipher, err := cipher.NewAES(contentalg)
+	if err != nil {
+		return nil, unsupported(errors.Wrapf(err, `failed to create content cipher for %s`, contentalg))
+	}
+
+	computedAad, err := m.ComputedAuthenticatedData()
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to compute authenticated data`)
+	}
+
+	recipients := m.recipients
+	if len(recipients) == 0 {
+		r := NewRecipient()
+		if err := r.SetHeaders(m.protectedHeaders); err != nil {
+			return nil, errors.Wrap(err, `failed to set headers to recipient`)
+		}
+		recipients = append(recipients, r)
+	}
+
+	var lastError error
+	for _, recipient := range recipients {
+		h2, err := h.Merge(ctx, recipient.Headers())
+		if err != nil {
+			lastError = errors.Wrap(err, `failed to merge recipient headers`)
+			continue
+		}
+
+		alg := h2.Algorithm()
+		dec := NewDecrypter(alg, contentalg, key).
+			InitializationVector(m.initializationVector).
+			Tag(m.tag)
+		if err := setKeyParameters(dec, alg, h2, nil); err != nil {
+			if isMalformed(err) {
+				return nil, err
+			}
+			lastError = err
+			continue
+		}
+
+		cek, err := dec.DecryptKey(recipient.EncryptedKey())
+		if err != nil {
+			lastError = errors.Wrapf(err, `failed to decrypt key for %s`, alg)
+			continue
+		}
+
+		// Some algorithms (e.g. RSA1_5) return a random key instead of
+		// failing, so check that the key matches the authentication tag
+		if _, err := contentcipher.Retag(cek, m.initializationVector, m.cipherText, m.tag, computedAad, computedAad); err != nil {
+			lastError = ErrDecryptFailed
+			continue
+		}
+
+		// For "dir", the CEK is the key that was passed to us. Return a
+		// copy, so that the caller may clear it after use
+		return append([]byte(nil), cek...), nil
+	}
+
+	if lastError != nil {
+		return nil, errors.Wrap(lastError, `failed to find matching recipient to decrypt key`)
+	}
+	return nil, errors.New(`failed to find matching recipient`)
+}
@@ -0,0 +1,135 @@
+package jwe
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/x25519"
+	"github.com/pkg/errors"
+)
+
+// SealContentEncryption is the content encryption algorithm used by `jwe.Seal()`
+const SealContentEncryption = jwa.A256GCM
+
+// minSealRSAKeySize is the minimum size of RSA keys accepted by `jwe.Seal()`
+// and `jwe.Open()`, in bits
+const minSealRSAKeySize = 2048
+
+// Seal encrypts the payload for the owner of `key`, choosing the
+// algorithms automatically based on the type of the key:
+//
+//   - RSA keys (at least 2048 bits) use RSA-OAEP-256
+//   - EC and X25519 keys use ECDH-ES
+//
+// In both cases the content is encrypted using A256GCM. The result is a
+// JWE message in compact format, which should be decrypted using `jwe.Open()`.
+//
+// `key` must be a public key, either raw (e.g. *rsa.PublicKey) or a jwk.Key.
+// Use `jwe.Encrypt()` if you need to choose the algorithms yourself.
+func Seal(payload []byte, key interface{}) ([]byte, error) {
+	raw, err := rawSealKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyalg jwa.KeyEncryptionAlgorithm
+	switch raw := raw.(type) {
+	case *rsa.PublicKey:
+		if err := checkSealRSAKeySize(raw); err != nil {
+			return nil, err
+		}
+		keyalg = jwa.RSA_OAEP_256
+	case *ecdsa.PublicKey, x25519.PublicKey:
+		keyalg = jwa.ECDH_ES
+	default:
+		return nil, errors.Errorf(`jwe.Seal requires an RSA, EC, or X25519 public key, got %T`, raw)
+	}
+
+	return Encrypt(payload, keyalg, raw, SealContentEncryption, jwa.NoCompress)
+}
+
+// Open decrypts a message created by `jwe.Seal()`.
+//
+// `key` must be the private key corresponding to the public key that was
+// passed to `jwe.Seal()`, either raw (e.g. *rsa.PrivateKey) or a jwk.Key.
+// The algorithms in the message must be exactly those that `jwe.Seal()`
+// would have chosen for the key: messages using any other algorithm
+// (e.g. RSA1_5) are rejected before decryption is attempted.
+func Open(buf []byte, key interface{}) ([]byte, error) {
+	raw, err := rawSealKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyalg jwa.KeyEncryptionAlgorithm
+	switch raw := raw.(type) {
+	case *rsa.PrivateKey:
+		if err := checkSealRSAKeySize(&raw.PublicKey); err != nil {
+			return nil, err
+		}
+		keyalg = jwa.RSA_OAEP_256
+	case *ecdsa.PrivateKey, x25519.PrivateKey:
+		keyalg = jwa.ECDH_ES
+	default:
+		return nil, errors.Errorf(`jwe.Open requires an RSA, EC, or X25519 private key, got %T`, raw)
+	}
+
+	msg, err := Parse(buf)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to parse message`)
+	}
+
+	recipients := msg.Recipients()
+	if len(recipients) != 1 {
+		return nil, errors.Errorf(`expected exactly 1 recipient, got %d`, len(recipients))
+	}
+
+	h, err := msg.ProtectedHeaders().Merge(context.TODO(), recipients[0].Headers())
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to merge headers`)
+	}
+	if alg := h.Algorithm(); alg != keyalg {
+		return nil, errors.Errorf(`unexpected key encryption algorithm %q (expected %q)`, alg, keyalg)
+	}
+	if enc := msg.ProtectedHeaders().ContentEncryption(); enc != SealContentEncryption {
+		return nil, errors.Errorf(`unexpected content encryption algorithm %q (expected %q)`, enc, SealContentEncryption)
+	}
+	if zip := h.Compression(); zip != jwa.NoCompress {
+		return nil, errors.Errorf(`unexpected compression algorithm %q`, zip)
+	}
+
+	return msg.Decrypt(keyalg, raw)
+}
+
+// rawSealKey converts the key into the pointer form of the raw key
+func rawSealKey(key interface{}) (interface{}, error) {
+	if jwkKey, ok := key.(jwk.Key); ok {
+		var raw interface{}
+		if err := jwkKey.Raw(&raw); err != nil {
+			return nil, errors.Wrapf(err, `failed to retrieve raw key from %T`, key)
+		}
+		key = raw
+	}
+
+	switch v := key.(type) {
+	case rsa.PublicKey:
+		return &v, nil
+	case rsa.PrivateKey:
+		return &v, nil
+	case ecdsa.PublicKey:
+		return &v, nil
+	case ecdsa.PrivateKey:
+		return &v, nil
+	}
+	return key, nil
+}
+
+func checkSealRSAKeySize(key *rsa.PublicKey) error {
+	if size := key.N.BitLen(); size < minSealRSAKeySize {
+		return errors.Errorf(`RSA key must be at least %d bits, got %d`, minSealRSAKeySize, size)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build jwx_brainpool
+// +build jwx_brainpool
+
+package jwk
+
+import (
+	"github.com/lestrrat-go/jwx/internal/ecutil"
+	"github.com/lestrrat-go/jwx/jwa"
+)
+
+func init() {
+	registerCurve(jwa.BrainpoolP256r1, ecutil.BrainpoolP256r1())
+	registerCurve(jwa.BrainpoolP320r1, ecutil.BrainpoolP320r1())
+	registerCurve(jwa.BrainpoolP384r1, ecutil.BrainpoolP384r1())
+	registerCurve(jwa.BrainpoolP512r1, ecutil.BrainpoolP512r1())
+}
@@ -0,0 +1,43 @@
+//go:build jwx_brainpool
+// +build jwx_brainpool
+
+package jwk_test
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/jwx/jwa"
+)
+
+func TestBrainpool(t *testing.T) {
+	t.Parallel()
+	testcases := []struct {
+		Curve      jwa.EllipticCurveAlgorithm
+		Key        string
+		Thumbprint string
+	}{
+		{
+			Curve:      jwa.BrainpoolP256r1,
+			Key:        `{"kty":"EC","crv":"BP-256","x":"BnwW6W7ynoghsK92FH1zkM5-iBBo02BKnJL8lC3oCAg","y":"P9L3D5gNZcwBKlWcqnNKR14usagcstZod5HDdbBjJ_k","d":"RNYd9ZK3wlHcH-FiNeIaFqFqxpeaeU8h7uVdfnRHAzQ"}`,
+			Thumbprint: `6_PQvcql1wYZDhJZiSuI_sh-aIIkVId1IsSDYOIpKP4`,
+		},
+		{
+			Curve:      jwa.BrainpoolP320r1,
+			Key:        `{"kty":"EC","crv":"BP-320","x":"Bxzfg5O_eHaFW-tcfj42DgqoXJzxma4w1bzW8Hvu74Nkccbf3cFOQg","y":"v1RxO3FeL9NKqcJ3hvu1KM4vO-_nRkEUuWonMK4CsQcz0i3Vf2ceeg","d":"F9FE7Ph4AA6PpA8Ygf42R3mPdojRlCwaOhXZwYx0wm2lD9c0VRtKmQ"}`,
+			Thumbprint: `fohTcDdL3JEqX6wyYitjlBGXFtxt4rMdJGZwbWhC0Ts`,
+		},
+		{
+			Curve:      jwa.BrainpoolP384r1,
+			Key:        `{"kty":"EC","crv":"BP-384","x":"fe17FM6I9MwIiScLNg9ThBrAJipkD60vUQl0aZSXngDOknqmFEEJPrduCYKH2egu","y":"MC3mdtgReVIpKcC4L5SF55NHjytqNK_9aAeepCEAe1P-v1WKDLdfSj5magr4PiOy","d":"U43o-nnvE_5o7oHObRYsP20Ch4HOO_l4LvtyKjVny5KDwD2AGCMaTgji6UTNoQCv"}`,
+			Thumbprint: `lSHvFgYLMHRelvCSNdmiQHZaxDF5mBGJU8JNoBwVy50`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Curve.String(), func(t *testing.T) {
+			t.Parallel()
+			testCurveKey(t, tc.Curve, tc.Key, tc.Thumbprint)
+		})
+	}
+}
@@ -54,6 +54,13 @@ func (c *CertificateChain) Accept(v interface{}) error {
 		}
 	case []string:
 		list = x
+	case []*x509.Certificate:
+		certs := make([]*x509.Certificate, len(x))
+		copy(certs, x)
+		*c = CertificateChain{
+			certs: certs,
+		}
+		return nil
 	case CertificateChain:
 		certs := make([]*x509.Certificate, len(x.certs))
 		copy(certs, x.certs)
@@ -65,15 +72,17 @@ func (c *CertificateChain) Accept(v interface{}) error {
 		return errors.Errorf(`invalid type for CertificateChain: %T`, v)
 	}
 
+	// RFC7517 section 4.7: each string in the array is a base64-encoded
+	// (not base64url-encoded) DER PKIX certificate value
 	certs := make([]*x509.Certificate, len(list))
 	for i, e := range list {
-		buf, err := base64.DecodeString(e)
+		buf, err := base64.DecodeStdString(e)
 		if err != nil {
-			return errors.Wrap(err, `failed to base64 decode list element`)
+			return errors.Wrapf(err, `failed to base64 decode list element #%d (x5c must use the standard base64 alphabet; use jwk.WithLenientCertificateChain(true) to accept base64url)`, i)
 		}
 		cert, err := x509.ParseCertificate(buf)
 		if err != nil {
-			return errors.Wrap(err, `failed to parse certificate`)
+			return errors.Wrapf(err, `failed to parse certificate at element #%d`, i)
 		}
 		certs[i] = cert
 	}
@@ -83,3 +92,38 @@ func (c *CertificateChain) Accept(v interface{}) error {
 	}
 	return nil
 }
+
+// normalizeCertificateChain rewrites the "x5c" field of the given JSON
+// object so that certificates that were wrongly encoded using base64url
+// are re-encoded using the standard base64 alphabet.
+func normalizeCertificateChain(data []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, errors.Wrap(err, `failed to unmarshal JSON`)
+	}
+
+	raw, ok := fields[X509CertChainKey]
+	if !ok {
+		return data, nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, errors.Wrapf(err, `failed to unmarshal %s`, X509CertChainKey)
+	}
+
+	for i, e := range list {
+		buf, err := base64.DecodeString(e)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to base64 decode list element #%d`, i)
+		}
+		list[i] = base64.EncodeToStringStd(buf)
+	}
+
+	normalized, err := json.Marshal(list)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to marshal %s`, X509CertChainKey)
+	}
+	fields[X509CertChainKey] = normalized
+	return json.Marshal(fields)
+}
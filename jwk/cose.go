@@ -0,0 +1,318 @@
+package jwk
+
+import (
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/internal/cbor"
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/pkg/errors"
+)
+
+// COSE_Key common parameter labels (RFC 8152 section 7.1)
+const (
+	coseKeyType   = 1
+	coseKeyID     = 2
+	coseAlgorithm = 3
+	coseKeyOps    = 4
+)
+
+var coseKeyTypes = map[jwa.KeyType]int64{
+	jwa.OKP:      1,
+	jwa.EC:       2,
+	jwa.RSA:      3,
+	jwa.OctetSeq: 4,
+}
+
+// coseKeyParams maps the names of the JWK parameters to the labels of
+// the corresponding COSE_Key parameters for each key type
+// (RFC 8152 section 13, RFC 8230 section 4)
+var coseKeyParams = map[jwa.KeyType]map[string]int64{
+	jwa.OKP: {
+		"crv": -1,
+		"x":   -2,
+		"d":   -4,
+	},
+	jwa.EC: {
+		"crv": -1,
+		"x":   -2,
+		"y":   -3,
+		"d":   -4,
+	},
+	jwa.RSA: {
+		"n":  -1,
+		"e":  -2,
+		"d":  -3,
+		"p":  -4,
+		"q":  -5,
+		"dp": -6,
+		"dq": -7,
+		"qi": -8,
+	},
+	jwa.OctetSeq: {
+		"k": -1,
+	},
+}
+
+var coseCurves = map[jwa.EllipticCurveAlgorithm]int64{
+	jwa.P256:    1,
+	jwa.P384:    2,
+	jwa.P521:    3,
+	jwa.X25519:  4,
+	jwa.X448:    5,
+	jwa.Ed25519: 6,
+	jwa.Ed448:   7,
+}
+
+var coseAlgorithms = map[string]int64{
+	jwa.ES256.String():  -7,
+	jwa.EdDSA.String():  -8,
+	jwa.ES384.String():  -35,
+	jwa.ES512.String():  -36,
+	jwa.PS256.String():  -37,
+	jwa.PS384.String():  -38,
+	jwa.PS512.String():  -39,
+	jwa.RS256.String():  -257,
+	jwa.RS384.String():  -258,
+	jwa.RS512.String():  -259,
+	jwa.HS256.String():  5,
+	jwa.HS384.String():  6,
+	jwa.HS512.String():  7,
+	jwa.A128KW.String(): -3,
+	jwa.A192KW.String(): -4,
+	jwa.A256KW.String(): -5,
+	jwa.DIRECT.String(): -6,
+}
+
+var coseKeyOperations = map[KeyOperation]int64{
+	KeyOpSign:       1,
+	KeyOpVerify:     2,
+	KeyOpEncrypt:    3,
+	KeyOpDecrypt:    4,
+	KeyOpWrapKey:    5,
+	KeyOpUnwrapKey:  6,
+	KeyOpDeriveKey:  7,
+	KeyOpDeriveBits: 8,
+}
+
+// MarshalCOSEKey serializes the key as a CBOR encoded COSE_Key structure,
+// as described in RFC 8152 (and RFC 8230 for RSA keys).
+//
+// Fields that do not have a COSE_Key equivalent, such as "use" and "x5c",
+// are not included in the output. An error is returned if the "alg" or
+// "key_ops" fields contain values that are not registered for COSE.
+func MarshalCOSEKey(key Key) ([]byte, error) {
+	buf, err := json.Marshal(key)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal key into JSON`)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(buf, &fields); err != nil {
+		return nil, errors.Wrap(err, `failed to unmarshal key into map`)
+	}
+
+	kty := key.KeyType()
+	label, ok := coseKeyTypes[kty]
+	if !ok {
+		return nil, errors.Errorf(`unsupported key type %q`, kty)
+	}
+
+	cose := map[interface{}]interface{}{
+		int64(coseKeyType): label,
+	}
+
+	if kid := key.KeyID(); kid != "" {
+		cose[int64(coseKeyID)] = []byte(kid)
+	}
+
+	if alg := key.Algorithm(); alg != "" {
+		v, ok := coseAlgorithms[alg]
+		if !ok {
+			return nil, errors.Errorf(`algorithm %q cannot be represented in COSE_Key`, alg)
+		}
+		cose[int64(coseAlgorithm)] = v
+	}
+
+	if ops := key.KeyOps(); len(ops) > 0 {
+		list := make([]interface{}, len(ops))
+		for i, op := range ops {
+			v, ok := coseKeyOperations[op]
+			if !ok {
+				return nil, errors.Errorf(`key operation %q cannot be represented in COSE_Key`, op)
+			}
+			list[i] = v
+		}
+		cose[int64(coseKeyOps)] = list
+	}
+
+	for name, label := range coseKeyParams[kty] {
+		v, ok := fields[name]
+		if !ok {
+			continue
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.Errorf(`invalid value for %q: %T`, name, v)
+		}
+
+		if name == "crv" {
+			crv, ok := coseCurves[jwa.EllipticCurveAlgorithm(s)]
+			if !ok {
+				return nil, errors.Errorf(`unsupported curve %q`, s)
+			}
+			cose[label] = crv
+			continue
+		}
+
+		decoded, err := base64.DecodeString(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to decode %q`, name)
+		}
+		cose[label] = decoded
+	}
+
+	return cbor.Marshal(cose)
+}
+
+// ParseCOSEKey parses a CBOR encoded COSE_Key structure, as described in
+// RFC 8152 (and RFC 8230 for RSA keys), and creates a jwk.Key.
+//
+// Only the parameters that have a JWK equivalent are imported. Compressed
+// EC points are not supported.
+func ParseCOSEKey(data []byte) (Key, error) {
+	v, err := cbor.Unmarshal(data)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to decode CBOR`)
+	}
+
+	cose, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.Errorf(`COSE_Key must be a map, got %T`, v)
+	}
+
+	ktyLabel, ok := cose[int64(coseKeyType)].(int64)
+	if !ok {
+		return nil, errors.New(`COSE_Key is missing a valid "kty" parameter`)
+	}
+
+	var kty jwa.KeyType
+	for k, label := range coseKeyTypes {
+		if label == ktyLabel {
+			kty = k
+			break
+		}
+	}
+	if kty == jwa.InvalidKeyType {
+		return nil, errors.Errorf(`unsupported COSE key type %d`, ktyLabel)
+	}
+
+	fields := map[string]interface{}{
+		KeyTypeKey: kty.String(),
+	}
+
+	if v, ok := cose[int64(coseKeyID)]; ok {
+		kid, ok := v.([]byte)
+		if !ok {
+			return nil, errors.Errorf(`invalid value for "kid": %T`, v)
+		}
+		fields[KeyIDKey] = string(kid)
+	}
+
+	if v, ok := cose[int64(coseAlgorithm)]; ok {
+		alg, ok := v.(int64)
+		if !ok {
+			return nil, errors.Errorf(`invalid value for "alg": %T`, v)
+		}
+		var found bool
+		for name, label := range coseAlgorithms {
+			if label == alg {
+				fields[AlgorithmKey] = name
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf(`unsupported COSE algorithm %d`, alg)
+		}
+	}
+
+	if v, ok := cose[int64(coseKeyOps)]; ok {
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, errors.Errorf(`invalid value for "key_ops": %T`, v)
+		}
+		ops := make([]string, 0, len(list))
+		for _, elem := range list {
+			label, ok := elem.(int64)
+			if !ok {
+				return nil, errors.Errorf(`invalid element in "key_ops": %T`, elem)
+			}
+			// MAC create (9) and MAC verify (10) have no dedicated
+			// counterparts in JWK, and map to "sign" and "verify"
+			switch label {
+			case 9:
+				label = coseKeyOperations[KeyOpSign]
+			case 10:
+				label = coseKeyOperations[KeyOpVerify]
+			}
+			var found bool
+			for op, l := range coseKeyOperations {
+				if l == label {
+					ops = append(ops, string(op))
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, errors.Errorf(`unsupported COSE key operation %d`, label)
+			}
+		}
+		fields[KeyOpsKey] = ops
+	}
+
+	for name, label := range coseKeyParams[kty] {
+		v, ok := cose[label]
+		if !ok {
+			continue
+		}
+
+		if name == "crv" {
+			crvLabel, ok := v.(int64)
+			if !ok {
+				return nil, errors.Errorf(`invalid value for "crv": %T`, v)
+			}
+			var crv jwa.EllipticCurveAlgorithm
+			for k, l := range coseCurves {
+				if l == crvLabel {
+					crv = k
+					break
+				}
+			}
+			if crv == "" {
+				return nil, errors.Errorf(`unsupported COSE curve %d`, crvLabel)
+			}
+			fields[name] = crv.String()
+			continue
+		}
+
+		b, ok := v.([]byte)
+		if !ok {
+			if _, isBool := v.(bool); isBool && name == "y" {
+				return nil, errors.New(`compressed EC points are not supported`)
+			}
+			return nil, errors.Errorf(`invalid value for %q: %T`, name, v)
+		}
+		fields[name] = base64.EncodeToString(b)
+	}
+
+	buf, err := json.Marshal(fields)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal key parameters`)
+	}
+
+	key, err := ParseKey(buf)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to parse key`)
+	}
+	return key, nil
+}
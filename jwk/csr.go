@@ -0,0 +1,30 @@
+package jwk
+
+import (
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+)
+
+// FromCSR creates a jwk.Key from the public key contained in the given
+// ASN.1 DER encoded X.509 certificate signing request (CSR).
+//
+// The signature of the CSR is verified against the public key contained
+// in it before the key is returned, which proves that the requester
+// possesses the corresponding private key.
+func FromCSR(der []byte) (Key, error) {
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to parse certificate request`)
+	}
+
+	if err := csr.CheckSignature(); err != nil {
+		return nil, errors.Wrap(err, `failed to verify certificate request signature`)
+	}
+
+	key, err := New(csr.PublicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create jwk.Key from certificate request`)
+	}
+	return key, nil
+}
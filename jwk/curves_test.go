@@ -0,0 +1,88 @@
+package jwk_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwe"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCurveKey is used by the tests for the curves that are only enabled
+// via build tags (see es256k_test.go and brainpool_test.go)
+func testCurveKey(t *testing.T, crv jwa.EllipticCurveAlgorithm, src, thumbprint string) {
+	t.Helper()
+
+	key, err := jwk.ParseKey([]byte(src))
+	if !assert.NoError(t, err, `jwk.ParseKey should succeed`) {
+		return
+	}
+
+	ecKey, ok := key.(jwk.ECDSAPrivateKey)
+	if !assert.True(t, ok, `key should be a jwk.ECDSAPrivateKey`) {
+		return
+	}
+	if !assert.Equal(t, crv, ecKey.Crv(), `crv should match`) {
+		return
+	}
+
+	var raw ecdsa.PrivateKey
+	if !assert.NoError(t, key.Raw(&raw), `key.Raw should succeed`) {
+		return
+	}
+	if !assert.True(t, raw.Curve.IsOnCurve(raw.X, raw.Y), `public key should be on the curve`) {
+		return
+	}
+	x, y := raw.Curve.ScalarBaseMult(raw.D.Bytes())
+	if !assert.True(t, x.Cmp(raw.X) == 0 && y.Cmp(raw.Y) == 0, `public key should be derived from the private key`) {
+		return
+	}
+
+	tp, err := key.Thumbprint(crypto.SHA256)
+	if !assert.NoError(t, err, `key.Thumbprint should succeed`) {
+		return
+	}
+	if !assert.Equal(t, thumbprint, base64.EncodeToString(tp), `thumbprint should match`) {
+		return
+	}
+
+	// jwk.New() should map the curve back to the same "crv"
+	rebuilt, err := jwk.New(&raw)
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+	buf, err := json.Marshal(rebuilt)
+	if !assert.NoError(t, err, `json.Marshal should succeed`) {
+		return
+	}
+	if !assert.JSONEq(t, src, string(buf), `serialized key should match`) {
+		return
+	}
+
+	pubkey, err := jwk.PublicKeyOf(key)
+	if !assert.NoError(t, err, `jwk.PublicKeyOf should succeed`) {
+		return
+	}
+	pubtp, err := pubkey.Thumbprint(crypto.SHA256)
+	if !assert.NoError(t, err, `pubkey.Thumbprint should succeed`) {
+		return
+	}
+	if !assert.Equal(t, tp, pubtp, `public key thumbprint should match`) {
+		return
+	}
+
+	// The arithmetic for these curves is not constant time, so operations
+	// that involve the private key are refused
+	if _, err := jws.Sign([]byte(`Lorem ipsum`), jwa.ES256, key); !assert.Error(t, err, `jws.Sign should fail`) {
+		return
+	}
+	if _, err := jwe.Encrypt([]byte(`Lorem ipsum`), jwa.ECDH_ES, pubkey, jwa.A128GCM, jwa.NoCompress); !assert.Error(t, err, `jwe.Encrypt should fail`) {
+		return
+	}
+}
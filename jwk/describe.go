@@ -0,0 +1,120 @@
+package jwk
+
+import (
+	"crypto"
+	_ "crypto/sha1" // for thumbprints
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"math/big"
+	"time"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/pkg/errors"
+)
+
+// describeHashes lists the hash functions used to compute the
+// thumbprints reported by `jwk.Describe()`, along with their names
+var describeHashes = []struct {
+	hash crypto.Hash
+	name string
+}{
+	{crypto.SHA1, "SHA-1"},
+	{crypto.SHA256, "SHA-256"},
+	{crypto.SHA384, "SHA-384"},
+	{crypto.SHA512, "SHA-512"},
+}
+
+// Description is a report on a key created by `jwk.Describe()`. It
+// does not contain any secret material, and can be serialized to JSON
+// to be displayed by command line tools or dashboards.
+type Description struct {
+	// KeyType is the "kty" of the key
+	KeyType jwa.KeyType `json:"kty"`
+
+	// Private is true if the key contains private (or secret) material
+	Private bool `json:"private"`
+
+	// Curve is the "crv" of "EC" and "OKP" keys
+	Curve jwa.EllipticCurveAlgorithm `json:"crv,omitempty"`
+
+	// Bits is the size of the modulus of "RSA" keys, and the size of
+	// the secret of "oct" keys
+	Bits int `json:"bits,omitempty"`
+
+	Algorithm string           `json:"alg,omitempty"`
+	KeyUsage  string           `json:"use,omitempty"`
+	KeyOps    KeyOperationList `json:"key_ops,omitempty"`
+	KeyID     string           `json:"kid,omitempty"`
+
+	// Thumbprints maps the names of hash functions (e.g. "SHA-256") to
+	// the base64url encoded RFC7638 thumbprints of the key
+	Thumbprints map[string]string `json:"thumbprints"`
+
+	// Certificates describes the certificates in the "x5c" member, in
+	// the same order
+	Certificates []*CertificateDescription `json:"certificates,omitempty"`
+}
+
+// CertificateDescription is a report on a certificate in the "x5c"
+// member of a key
+type CertificateDescription struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SerialNumber string    `json:"serial_number"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+}
+
+// Describe creates a report on the given key, which includes its type,
+// size, metadata, thumbprints, and the certificates in its "x5c" member.
+func Describe(key Key) (*Description, error) {
+	desc := &Description{
+		KeyType:     key.KeyType(),
+		Algorithm:   key.Algorithm(),
+		KeyUsage:    key.KeyUsage(),
+		KeyOps:      key.KeyOps(),
+		KeyID:       key.KeyID(),
+		Thumbprints: make(map[string]string),
+	}
+
+	switch key := key.(type) {
+	case RSAPrivateKey:
+		desc.Private = true
+		desc.Bits = new(big.Int).SetBytes(key.N()).BitLen()
+	case RSAPublicKey:
+		desc.Bits = new(big.Int).SetBytes(key.N()).BitLen()
+	case ECDSAPrivateKey:
+		desc.Private = true
+		desc.Curve = key.Crv()
+	case ECDSAPublicKey:
+		desc.Curve = key.Crv()
+	case OKPPrivateKey:
+		desc.Private = true
+		desc.Curve = key.Crv()
+	case OKPPublicKey:
+		desc.Curve = key.Crv()
+	case SymmetricKey:
+		desc.Private = true
+		desc.Bits = len(key.Octets()) * 8
+	}
+
+	for _, h := range describeHashes {
+		tp, err := key.Thumbprint(h.hash)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to compute %s thumbprint`, h.name)
+		}
+		desc.Thumbprints[h.name] = base64.EncodeToString(tp)
+	}
+
+	for _, cert := range key.X509CertChain() {
+		desc.Certificates = append(desc.Certificates, &CertificateDescription{
+			Subject:      cert.Subject.String(),
+			Issuer:       cert.Issuer.String(),
+			SerialNumber: cert.SerialNumber.String(),
+			NotBefore:    cert.NotBefore,
+			NotAfter:     cert.NotAfter,
+		})
+	}
+	return desc, nil
+}
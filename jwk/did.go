@@ -0,0 +1,377 @@
+package jwk
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/internal/base58"
+	"github.com/lestrrat-go/jwx/internal/ecutil"
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/x25519"
+	"github.com/pkg/errors"
+)
+
+// Multicodec codes of the public keys supported in multibase encoded
+// keys, see https://github.com/multiformats/multicodec/blob/master/table.csv
+const (
+	multicodecSecp256k1 = 0xe7
+	multicodecX25519    = 0xec
+	multicodecEd25519   = 0xed
+	multicodecP256      = 0x1200
+	multicodecP384      = 0x1201
+	multicodecP521      = 0x1202
+)
+
+// maxDIDDocumentSize is the maximum size of the "did:web" documents
+// that are read by ResolveDID
+const maxDIDDocumentSize = 1 << 20
+
+// didRelationships lists the verification relationships of a DID
+// document that may embed verification methods
+var didRelationships = []string{
+	"authentication",
+	"assertionMethod",
+	"keyAgreement",
+	"capabilityInvocation",
+	"capabilityDelegation",
+}
+
+// ResolveDID resolves the DID `did` to the set of keys listed in the
+// verification methods of its DID document. The "kid" of each key is set
+// to the absolute identifier of its verification method (e.g.
+// "did:web:example.com#key-1"), so the set can be used to verify
+// messages whose "kid" header refers to a verification method.
+//
+// The "did:key" and "did:web" methods are supported. "did:web" documents
+// are fetched over HTTPS, and the options are used to configure the
+// request (e.g. `jwk.WithHTTPClient()`). The document's "id" must match
+// the DID, and documents larger than 1MB are rejected.
+//
+// As the host of a "did:web" DID is taken from the DID itself, resolving
+// DIDs that come from untrusted input (e.g. the "iss" or "kid" of a
+// token that has not been verified yet) makes requests to arbitrary
+// hosts. In that case, pass an HTTP client that has a timeout, and that
+// refuses to connect to internal network addresses (e.g. using the
+// Control function of a net.Dialer).
+//
+// If `did` is a DID URL with a fragment, the set only contains the
+// key of the verification method it refers to.
+func ResolveDID(ctx context.Context, did string, options ...FetchOption) (Set, error) {
+	did, fragment := splitDIDURL(did)
+
+	var set Set
+	switch {
+	case strings.HasPrefix(did, "did:key:"):
+		key, err := ParseDIDKey(did)
+		if err != nil {
+			return nil, err
+		}
+		set = NewSet()
+		set.Add(key)
+	case strings.HasPrefix(did, "did:web:"):
+		u, err := didWebURL(did)
+		if err != nil {
+			return nil, err
+		}
+		res, err := fetch(ctx, u, options...)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to fetch DID document for %s`, did)
+		}
+		defer res.Body.Close()
+		data, err := ioutil.ReadAll(io.LimitReader(res.Body, maxDIDDocumentSize+1))
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to read DID document for %s`, did)
+		}
+		if len(data) > maxDIDDocumentSize {
+			return nil, errors.Errorf(`DID document for %s is too large`, did)
+		}
+		doc, err := parseDIDDocument(data)
+		if err != nil {
+			return nil, err
+		}
+		if doc.ID != did {
+			return nil, errors.Errorf(`DID document id %q does not match %q`, doc.ID, did)
+		}
+		set, err = doc.keys()
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf(`unsupported DID method: %s`, did)
+	}
+
+	if fragment == "" {
+		return set, nil
+	}
+
+	kid := did + "#" + fragment
+	key, ok := set.LookupKeyID(kid)
+	if !ok {
+		return nil, errors.Errorf(`verification method %s not found`, kid)
+	}
+	filtered := NewSet()
+	filtered.Add(key)
+	return filtered, nil
+}
+
+// ParseDIDKey decodes the public key encoded in the "did:key" DID `did`.
+// The "kid" of the key is set to the identifier of its verification
+// method, which is the DID followed by the multibase encoded key as the
+// fragment.
+//
+// Ed25519, X25519, P-256, P-384 and P-521 keys are supported. secp256k1
+// keys are supported when built with the `jwx_es256k` build tag.
+func ParseDIDKey(did string) (Key, error) {
+	did, _ = splitDIDURL(did)
+	const prefix = "did:key:"
+	if !strings.HasPrefix(did, prefix) {
+		return nil, errors.Errorf(`invalid did:key %s`, did)
+	}
+	encoded := strings.TrimPrefix(did, prefix)
+
+	raw, err := parseMultibaseKey(encoded)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to decode %s`, did)
+	}
+	key, err := New(raw)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to create key from %s`, did)
+	}
+	if err := key.Set(KeyIDKey, did+"#"+encoded); err != nil {
+		return nil, errors.Wrapf(err, `failed to set %s`, KeyIDKey)
+	}
+	return key, nil
+}
+
+// ParseDIDDocument parses the DID document `data`, and returns the set of
+// keys listed in its verification methods. See `jwk.ResolveDID()` for
+// the "kid" of the keys.
+//
+// Verification methods with "publicKeyJwk", "publicKeyMultibase" and
+// "publicKeyBase58" (for the "Ed25519VerificationKey2018" and
+// "X25519KeyAgreementKey2019" types) are supported. Verification methods
+// without any of these are ignored.
+func ParseDIDDocument(data []byte) (Set, error) {
+	doc, err := parseDIDDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	return doc.keys()
+}
+
+type didVerificationMethod struct {
+	ID                 string          `json:"id"`
+	Type               string          `json:"type"`
+	Controller         string          `json:"controller"`
+	PublicKeyJwk       json.RawMessage `json:"publicKeyJwk,omitempty"`
+	PublicKeyMultibase string          `json:"publicKeyMultibase,omitempty"`
+	PublicKeyBase58    string          `json:"publicKeyBase58,omitempty"`
+}
+
+type didDocument struct {
+	ID                  string
+	VerificationMethods []didVerificationMethod
+}
+
+func parseDIDDocument(data []byte) (*didDocument, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, errors.Wrap(err, `failed to unmarshal DID document`)
+	}
+
+	var doc didDocument
+	if err := json.Unmarshal(fields["id"], &doc.ID); err != nil {
+		return nil, errors.Wrap(err, `failed to unmarshal DID document id`)
+	}
+
+	if v, ok := fields["verificationMethod"]; ok {
+		if err := json.Unmarshal(v, &doc.VerificationMethods); err != nil {
+			return nil, errors.Wrap(err, `failed to unmarshal verificationMethod`)
+		}
+	}
+
+	// Verification relationships contain either references to the
+	// verification methods above, or embedded verification methods
+	for _, name := range didRelationships {
+		v, ok := fields[name]
+		if !ok {
+			continue
+		}
+		var list []json.RawMessage
+		if err := json.Unmarshal(v, &list); err != nil {
+			return nil, errors.Wrapf(err, `failed to unmarshal %s`, name)
+		}
+		for _, elem := range list {
+			if len(elem) == 0 || elem[0] != '{' {
+				continue
+			}
+			var vm didVerificationMethod
+			if err := json.Unmarshal(elem, &vm); err != nil {
+				return nil, errors.Wrapf(err, `failed to unmarshal %s`, name)
+			}
+			doc.VerificationMethods = append(doc.VerificationMethods, vm)
+		}
+	}
+	return &doc, nil
+}
+
+func (doc *didDocument) keys() (Set, error) {
+	set := NewSet()
+	for _, vm := range doc.VerificationMethods {
+		kid := vm.ID
+		if strings.HasPrefix(kid, "#") {
+			kid = doc.ID + kid
+		}
+
+		key, err := vm.key()
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to parse verification method %s`, kid)
+		}
+		if key == nil {
+			continue
+		}
+		if err := key.Set(KeyIDKey, kid); err != nil {
+			return nil, errors.Wrapf(err, `failed to set %s`, KeyIDKey)
+		}
+		set.Add(key)
+	}
+	return set, nil
+}
+
+func (vm *didVerificationMethod) key() (Key, error) {
+	var raw interface{}
+	switch {
+	case len(vm.PublicKeyJwk) > 0:
+		key, err := ParseKey(vm.PublicKeyJwk)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to parse publicKeyJwk`)
+		}
+		if _, ok := key.(SymmetricKey); ok {
+			return nil, errors.New(`publicKeyJwk must be a public key`)
+		}
+		return PublicKeyOf(key)
+	case vm.PublicKeyMultibase != "":
+		v, err := parseMultibaseKey(vm.PublicKeyMultibase)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to decode publicKeyMultibase`)
+		}
+		raw = v
+	case vm.PublicKeyBase58 != "":
+		buf, err := base58.Decode(vm.PublicKeyBase58)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to decode publicKeyBase58`)
+		}
+		switch vm.Type {
+		case "Ed25519VerificationKey2018":
+			if len(buf) != ed25519.PublicKeySize {
+				return nil, errors.Errorf(`invalid Ed25519 public key size %d`, len(buf))
+			}
+			raw = ed25519.PublicKey(buf)
+		case "X25519KeyAgreementKey2019":
+			if len(buf) != x25519.PublicKeySize {
+				return nil, errors.Errorf(`invalid X25519 public key size %d`, len(buf))
+			}
+			raw = x25519.PublicKey(buf)
+		default:
+			return nil, errors.Errorf(`unsupported verification method type %s for publicKeyBase58`, vm.Type)
+		}
+	default:
+		return nil, nil
+	}
+
+	key, err := New(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create key`)
+	}
+	return key, nil
+}
+
+// parseMultibaseKey decodes a base58btc multibase encoded public key,
+// prefixed with its multicodec code
+func parseMultibaseKey(s string) (interface{}, error) {
+	if !strings.HasPrefix(s, "z") {
+		return nil, errors.New(`only base58btc multibase encoding is supported`)
+	}
+	buf, err := base58.Decode(s[1:])
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to decode base58btc`)
+	}
+
+	code, n := binary.Uvarint(buf)
+	if n <= 0 {
+		return nil, errors.New(`invalid multicodec prefix`)
+	}
+	buf = buf[n:]
+
+	var crv jwa.EllipticCurveAlgorithm
+	switch code {
+	case multicodecEd25519:
+		if len(buf) != ed25519.PublicKeySize {
+			return nil, errors.Errorf(`invalid Ed25519 public key size %d`, len(buf))
+		}
+		return ed25519.PublicKey(buf), nil
+	case multicodecX25519:
+		if len(buf) != x25519.PublicKeySize {
+			return nil, errors.Errorf(`invalid X25519 public key size %d`, len(buf))
+		}
+		return x25519.PublicKey(buf), nil
+	case multicodecSecp256k1:
+		crv = jwa.Secp256k1
+	case multicodecP256:
+		crv = jwa.P256
+	case multicodecP384:
+		crv = jwa.P384
+	case multicodecP521:
+		crv = jwa.P521
+	default:
+		return nil, errors.Errorf(`unsupported multicodec 0x%x`, code)
+	}
+
+	curve, ok := ecdsaCurves[crv]
+	if !ok {
+		return nil, errors.Errorf(`unsupported curve %s`, crv)
+	}
+	x, y := ecutil.UnmarshalCompressed(curve, buf)
+	if x == nil {
+		return nil, errors.Wrapf(ErrInvalidCompressedPoint, `failed to decompress point for curve %s`, crv)
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// splitDIDURL splits the DID URL `s` into the DID and the fragment
+func splitDIDURL(s string) (string, string) {
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// didWebURL returns the URL of the DID document of a "did:web" DID, as
+// described in https://w3c-ccg.github.io/did-method-web/
+func didWebURL(did string) (string, error) {
+	segments := strings.Split(strings.TrimPrefix(did, "did:web:"), ":")
+	for i, segment := range segments {
+		v, err := url.PathUnescape(segment)
+		if err != nil {
+			return "", errors.Wrapf(err, `invalid did:web %s`, did)
+		}
+		if v == "" || strings.ContainsAny(v, "/?#") {
+			return "", errors.Errorf(`invalid did:web %s`, did)
+		}
+		segments[i] = v
+	}
+
+	path := "/.well-known"
+	if len(segments) > 1 {
+		path = "/" + strings.Join(segments[1:], "/")
+	}
+	u := url.URL{Scheme: "https", Host: segments[0], Path: path + "/did.json"}
+	return u.String(), nil
+}
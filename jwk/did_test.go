@@ -0,0 +1,232 @@
+package jwk_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/internal/base58"
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDIDKey(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Ed25519", func(t *testing.T) {
+		t.Parallel()
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		if !assert.NoError(t, err, `ed25519.GenerateKey should succeed`) {
+			return
+		}
+		encoded := "z" + base58.Encode(append([]byte{0xed, 0x01}, pub...))
+		did := "did:key:" + encoded
+
+		key, err := jwk.ParseDIDKey(did)
+		if !assert.NoError(t, err, `jwk.ParseDIDKey should succeed`) {
+			return
+		}
+		if !assert.Equal(t, did+"#"+encoded, key.KeyID(), `kid should match`) {
+			return
+		}
+		var raw interface{}
+		if !assert.NoError(t, key.Raw(&raw), `key.Raw should succeed`) {
+			return
+		}
+		if !assert.Equal(t, pub, raw, `public keys should match`) {
+			return
+		}
+	})
+	t.Run("P-256", func(t *testing.T) {
+		t.Parallel()
+		// Test vector from https://w3c-ccg.github.io/did-method-key/
+		key, err := jwk.ParseDIDKey("did:key:zDnaerDaTF5BXEavCrfRZEk316dpbLsfPDZ3WJ5hRTPFU2169")
+		if !assert.NoError(t, err, `jwk.ParseDIDKey should succeed`) {
+			return
+		}
+		ecKey, ok := key.(jwk.ECDSAPublicKey)
+		if !assert.True(t, ok, `key should be an ECDSA public key`) {
+			return
+		}
+		if !assert.Equal(t, jwa.P256, ecKey.Crv(), `crv should match`) {
+			return
+		}
+		if !assert.NoError(t, jwk.ValidatePoint(key), `point should be on the curve`) {
+			return
+		}
+	})
+	t.Run("P-384", func(t *testing.T) {
+		t.Parallel()
+		priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+			return
+		}
+		compressed := elliptic.MarshalCompressed(elliptic.P384(), priv.X, priv.Y)
+		key, err := jwk.ParseDIDKey("did:key:z" + base58.Encode(append([]byte{0x81, 0x24}, compressed...)))
+		if !assert.NoError(t, err, `jwk.ParseDIDKey should succeed`) {
+			return
+		}
+		var raw ecdsa.PublicKey
+		if !assert.NoError(t, key.Raw(&raw), `key.Raw should succeed`) {
+			return
+		}
+		if !assert.True(t, raw.X.Cmp(priv.X) == 0 && raw.Y.Cmp(priv.Y) == 0, `public keys should match`) {
+			return
+		}
+	})
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+		for _, did := range []string{
+			"did:web:example.com",
+			"did:key:6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK",
+			"did:key:z0OIl",
+			"did:key:z" + base58.Encode([]byte{0xed, 0x01, 0x00}),
+			"did:key:z" + base58.Encode([]byte{0x85, 0x24, 0x00}),
+		} {
+			_, err := jwk.ParseDIDKey(did)
+			if !assert.Error(t, err, `jwk.ParseDIDKey(%q) should fail`, did) {
+				return
+			}
+		}
+	})
+}
+
+func TestResolveDID(t *testing.T) {
+	t.Parallel()
+
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		return
+	}
+	ecKey, err := jwk.New(&ecPriv.PublicKey)
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+	jwkJSON, err := json.Marshal(ecKey)
+	if !assert.NoError(t, err, `json.Marshal should succeed`) {
+		return
+	}
+
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if !assert.NoError(t, err, `ed25519.GenerateKey should succeed`) {
+		return
+	}
+
+	var did string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/did.json") {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/users/huge/") {
+			fmt.Fprintf(w, `{"id": %q, "padding": "%s"}`, strings.Replace(did, ":alice", ":huge", 1), strings.Repeat("x", 1<<20))
+			return
+		}
+		fmt.Fprintf(w, `{
+  "@context": ["https://www.w3.org/ns/did/v1"],
+  "id": %[1]q,
+  "verificationMethod": [
+    {"id": "#key-1", "type": "JsonWebKey2020", "controller": %[1]q, "publicKeyJwk": %[2]s},
+    {"id": "%[1]s#key-2", "type": "Ed25519VerificationKey2018", "controller": %[1]q, "publicKeyBase58": %[3]q}
+  ],
+  "keyAgreement": [
+    "#key-1",
+    {"id": "#key-3", "type": "Multikey", "controller": %[1]q, "publicKeyMultibase": %[4]q}
+  ],
+  "service": []
+}`, did, jwkJSON, base58.Encode(edPub), "z"+base58.Encode(append([]byte{0xed, 0x01}, edPub...)))
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if !assert.NoError(t, err, `url.Parse should succeed`) {
+		return
+	}
+	did = "did:web:" + strings.Replace(u.Host, ":", "%3A", 1) + ":users:alice"
+
+	t.Run("did:web", func(t *testing.T) {
+		set, err := jwk.ResolveDID(context.Background(), did, jwk.WithHTTPClient(srv.Client()))
+		if !assert.NoError(t, err, `jwk.ResolveDID should succeed`) {
+			return
+		}
+		if !assert.Equal(t, 3, set.Len(), `set should contain 3 keys`) {
+			return
+		}
+		key, ok := set.LookupKeyID(did + "#key-1")
+		if !assert.True(t, ok, `key-1 should be found`) {
+			return
+		}
+		if !assert.Equal(t, jwa.EC, key.KeyType(), `key-1 should be an EC key`) {
+			return
+		}
+		for _, kid := range []string{"#key-2", "#key-3"} {
+			key, ok := set.LookupKeyID(did + kid)
+			if !assert.True(t, ok, `%s should be found`, kid) {
+				return
+			}
+			var raw interface{}
+			if !assert.NoError(t, key.Raw(&raw), `key.Raw should succeed`) {
+				return
+			}
+			if !assert.Equal(t, edPub, raw, `public keys should match`) {
+				return
+			}
+		}
+	})
+	t.Run("DID URL", func(t *testing.T) {
+		set, err := jwk.ResolveDID(context.Background(), did+"#key-2", jwk.WithHTTPClient(srv.Client()))
+		if !assert.NoError(t, err, `jwk.ResolveDID should succeed`) {
+			return
+		}
+		if !assert.Equal(t, 1, set.Len(), `set should contain 1 key`) {
+			return
+		}
+
+		_, err = jwk.ResolveDID(context.Background(), did+"#key-4", jwk.WithHTTPClient(srv.Client()))
+		if !assert.Error(t, err, `jwk.ResolveDID should fail for unknown fragments`) {
+			return
+		}
+	})
+	t.Run("Mismatched id", func(t *testing.T) {
+		other := strings.Replace(did, ":alice", ":bob", 1)
+		_, err := jwk.ResolveDID(context.Background(), other, jwk.WithHTTPClient(srv.Client()))
+		if !assert.Error(t, err, `jwk.ResolveDID should fail`) {
+			return
+		}
+	})
+	t.Run("Document too large", func(t *testing.T) {
+		huge := strings.Replace(did, ":alice", ":huge", 1)
+		_, err := jwk.ResolveDID(context.Background(), huge, jwk.WithHTTPClient(srv.Client()))
+		if !assert.Error(t, err, `jwk.ResolveDID should fail`) {
+			return
+		}
+		if !assert.Contains(t, err.Error(), `too large`, `error should mention the size`) {
+			return
+		}
+	})
+	t.Run("did:key", func(t *testing.T) {
+		set, err := jwk.ResolveDID(context.Background(), "did:key:zDnaerDaTF5BXEavCrfRZEk316dpbLsfPDZ3WJ5hRTPFU2169")
+		if !assert.NoError(t, err, `jwk.ResolveDID should succeed`) {
+			return
+		}
+		if !assert.Equal(t, 1, set.Len(), `set should contain 1 key`) {
+			return
+		}
+	})
+	t.Run("Unsupported method", func(t *testing.T) {
+		_, err := jwk.ResolveDID(context.Background(), "did:example:123")
+		if !assert.Error(t, err, `jwk.ResolveDID should fail`) {
+			return
+		}
+	})
+}
@@ -0,0 +1,128 @@
+//go:build go1.20
+// +build go1.20
+
+package jwk
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"math/big"
+
+	"github.com/lestrrat-go/jwx/internal/blackmagic"
+	"github.com/lestrrat-go/jwx/x25519"
+	"github.com/pkg/errors"
+)
+
+// fromECDHKey converts keys from the crypto/ecdh package to the types
+// that jwk.New() understands. The second return value is false if the
+// key is not a crypto/ecdh key.
+func fromECDHKey(key interface{}) (interface{}, bool, error) {
+	switch key := key.(type) {
+	case *ecdh.PrivateKey:
+		if key.Curve() == ecdh.X25519() {
+			v, err := x25519.NewKeyFromSeed(key.Bytes())
+			if err != nil {
+				return nil, true, errors.Wrap(err, `failed to create x25519 private key`)
+			}
+			return v, true, nil
+		}
+
+		pub, err := ecdhToECDSAPublicKey(key.PublicKey())
+		if err != nil {
+			return nil, true, err
+		}
+		return &ecdsa.PrivateKey{
+			PublicKey: *pub,
+			D:         new(big.Int).SetBytes(key.Bytes()),
+		}, true, nil
+	case *ecdh.PublicKey:
+		if key.Curve() == ecdh.X25519() {
+			return x25519.PublicKey(key.Bytes()), true, nil
+		}
+
+		pub, err := ecdhToECDSAPublicKey(key)
+		if err != nil {
+			return nil, true, err
+		}
+		return pub, true, nil
+	}
+	return nil, false, nil
+}
+
+func ecdhToECDSAPublicKey(key *ecdh.PublicKey) (*ecdsa.PublicKey, error) {
+	var crv elliptic.Curve
+	switch key.Curve() {
+	case ecdh.P256():
+		crv = elliptic.P256()
+	case ecdh.P384():
+		crv = elliptic.P384()
+	case ecdh.P521():
+		crv = elliptic.P521()
+	default:
+		return nil, errors.Errorf(`unsupported curve %s`, key.Curve())
+	}
+
+	// uncompressed point: 0x04 || X || Y
+	buf := key.Bytes()
+	size := (crv.Params().BitSize + 7) / 8
+	if len(buf) != 1+2*size || buf[0] != 4 {
+		return nil, errors.New(`invalid public key encoding`)
+	}
+	return &ecdsa.PublicKey{
+		Curve: crv,
+		X:     new(big.Int).SetBytes(buf[1 : 1+size]),
+		Y:     new(big.Int).SetBytes(buf[1+size:]),
+	}, nil
+}
+
+// assignECDHKey assigns the raw key (as built by the Raw() methods) to
+// `dst` if it is a pointer to a crypto/ecdh key. The first return value
+// is false if `dst` is not a pointer to a crypto/ecdh key.
+func assignECDHKey(dst interface{}, key interface{}) (bool, error) {
+	switch dst.(type) {
+	case *ecdh.PrivateKey, **ecdh.PrivateKey:
+		var v *ecdh.PrivateKey
+		var err error
+		switch key := key.(type) {
+		case *ecdsa.PrivateKey:
+			v, err = key.ECDH()
+		case x25519.PrivateKey:
+			v, err = ecdh.X25519().NewPrivateKey(key.Seed())
+		default:
+			return true, errors.Errorf(`cannot convert %T to *ecdh.PrivateKey`, key)
+		}
+		if err != nil {
+			return true, errors.Wrap(err, `failed to convert key to *ecdh.PrivateKey`)
+		}
+		return true, assignECDH(dst, v)
+	case *ecdh.PublicKey, **ecdh.PublicKey:
+		var v *ecdh.PublicKey
+		var err error
+		switch key := key.(type) {
+		case *ecdsa.PublicKey:
+			v, err = key.ECDH()
+		case x25519.PublicKey:
+			v, err = ecdh.X25519().NewPublicKey(key)
+		default:
+			return true, errors.Errorf(`cannot convert %T to *ecdh.PublicKey`, key)
+		}
+		if err != nil {
+			return true, errors.Wrap(err, `failed to convert key to *ecdh.PublicKey`)
+		}
+		return true, assignECDH(dst, v)
+	}
+	return false, nil
+}
+
+func assignECDH(dst interface{}, v interface{}) error {
+	switch dst := dst.(type) {
+	case **ecdh.PrivateKey:
+		*dst = v.(*ecdh.PrivateKey)
+	case **ecdh.PublicKey:
+		*dst = v.(*ecdh.PublicKey)
+	default:
+		return blackmagic.AssignIfCompatible(dst, v)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !go1.20
+// +build !go1.20
+
+package jwk
+
+// crypto/ecdh is only available in go1.20 and later
+
+func fromECDHKey(_ interface{}) (interface{}, bool, error) {
+	return nil, false, nil
+}
+
+func assignECDHKey(_ interface{}, _ interface{}) (bool, error) {
+	return false, nil
+}
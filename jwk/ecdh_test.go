@@ -0,0 +1,81 @@
+//go:build go1.20
+// +build go1.20
+
+package jwk_test
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestECDH(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		Curve   ecdh.Curve
+		KeyType jwa.KeyType
+		Crv     jwa.EllipticCurveAlgorithm
+	}{
+		{Curve: ecdh.P256(), KeyType: jwa.EC, Crv: jwa.P256},
+		{Curve: ecdh.P384(), KeyType: jwa.EC, Crv: jwa.P384},
+		{Curve: ecdh.P521(), KeyType: jwa.EC, Crv: jwa.P521},
+		{Curve: ecdh.X25519(), KeyType: jwa.OKP, Crv: jwa.X25519},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Crv.String(), func(t *testing.T) {
+			t.Parallel()
+			priv, err := tc.Curve.GenerateKey(rand.Reader)
+			if !assert.NoError(t, err, `GenerateKey should succeed`) {
+				return
+			}
+
+			privJwk, err := jwk.New(priv)
+			if !assert.NoError(t, err, `jwk.New should succeed`) {
+				return
+			}
+			if !assert.Equal(t, tc.KeyType, privJwk.KeyType(), `key type should match`) {
+				return
+			}
+			crv, ok := privJwk.Get("crv")
+			if !assert.True(t, ok, `crv should exist`) {
+				return
+			}
+			if !assert.Equal(t, tc.Crv, crv, `crv should match`) {
+				return
+			}
+
+			var rawPriv *ecdh.PrivateKey
+			if !assert.NoError(t, privJwk.Raw(&rawPriv), `Raw should succeed`) {
+				return
+			}
+			if !assert.True(t, priv.Equal(rawPriv), `private keys should be equal`) {
+				return
+			}
+
+			pubJwk, err := jwk.New(priv.PublicKey())
+			if !assert.NoError(t, err, `jwk.New should succeed`) {
+				return
+			}
+
+			var rawPub *ecdh.PublicKey
+			if !assert.NoError(t, pubJwk.Raw(&rawPub), `Raw should succeed`) {
+				return
+			}
+			if !assert.True(t, priv.PublicKey().Equal(rawPub), `public keys should be equal`) {
+				return
+			}
+
+			var wrongType *ecdh.PrivateKey
+			if !assert.Error(t, pubJwk.Raw(&wrongType), `Raw should fail for mismatched key types`) {
+				return
+			}
+		})
+	}
+}
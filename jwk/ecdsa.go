@@ -29,15 +29,8 @@ func (k *ecdsaPublicKey) FromRaw(rawKey *ecdsa.PublicKey) error {
 	k.y = make([]byte, len(ybuf))
 	copy(k.y, ybuf)
 
-	var crv jwa.EllipticCurveAlgorithm
-	switch rawKey.Curve {
-	case elliptic.P256():
-		crv = jwa.P256
-	case elliptic.P384():
-		crv = jwa.P384
-	case elliptic.P521():
-		crv = jwa.P521
-	default:
+	crv, ok := curveAlgorithm(rawKey.Curve)
+	if !ok {
 		return errors.Errorf(`invalid elliptic curve %s`, rawKey.Curve)
 	}
 	k.crv = &crv
@@ -63,15 +56,8 @@ func (k *ecdsaPrivateKey) FromRaw(rawKey *ecdsa.PrivateKey) error {
 	k.d = make([]byte, len(dbuf))
 	copy(k.d, dbuf)
 
-	var crv jwa.EllipticCurveAlgorithm
-	switch rawKey.Curve {
-	case elliptic.P256():
-		crv = jwa.P256
-	case elliptic.P384():
-		crv = jwa.P384
-	case elliptic.P521():
-		crv = jwa.P521
-	default:
+	crv, ok := curveAlgorithm(rawKey.Curve)
+	if !ok {
 		return errors.Errorf(`invalid elliptic curve %s`, rawKey.Curve)
 	}
 	k.crv = &crv
@@ -79,16 +65,32 @@ func (k *ecdsaPrivateKey) FromRaw(rawKey *ecdsa.PrivateKey) error {
 	return nil
 }
 
+// ecdsaCurves maps the "crv" values to the curves that are supported.
+// Curves that are not part of the standard library are registered by
+// files that are only compiled when the corresponding build tag
+// (jwx_es256k, jwx_brainpool) is specified
+var ecdsaCurves = map[jwa.EllipticCurveAlgorithm]elliptic.Curve{
+	jwa.P256: elliptic.P256(),
+	jwa.P384: elliptic.P384(),
+	jwa.P521: elliptic.P521(),
+}
+
+func registerCurve(crv jwa.EllipticCurveAlgorithm, curve elliptic.Curve) {
+	ecdsaCurves[crv] = curve
+}
+
+func curveAlgorithm(curve elliptic.Curve) (jwa.EllipticCurveAlgorithm, bool) {
+	for crv, c := range ecdsaCurves {
+		if c == curve {
+			return crv, true
+		}
+	}
+	return jwa.InvalidEllipticCurve, false
+}
+
 func buildECDSAPublicKey(alg jwa.EllipticCurveAlgorithm, xbuf, ybuf []byte) (*ecdsa.PublicKey, error) {
-	var curve elliptic.Curve
-	switch alg {
-	case jwa.P256:
-		curve = elliptic.P256()
-	case jwa.P384:
-		curve = elliptic.P384()
-	case jwa.P521:
-		curve = elliptic.P521()
-	default:
+	curve, ok := ecdsaCurves[alg]
+	if !ok {
 		return nil, errors.Errorf(`invalid curve algorithm %s`, alg)
 	}
 
@@ -109,6 +111,9 @@ func (k *ecdsaPublicKey) Raw(v interface{}) error {
 		return errors.Wrap(err, `failed to build public key`)
 	}
 
+	if ok, err := assignECDHKey(v, pubk); ok {
+		return err
+	}
 	return blackmagic.AssignIfCompatible(v, pubk)
 }
 
@@ -127,6 +132,9 @@ func (k *ecdsaPrivateKey) Raw(v interface{}) error {
 	key.D = &d
 	key.PublicKey = *pubk
 
+	if ok, err := assignECDHKey(v, &key); ok {
+		return err
+	}
 	return blackmagic.AssignIfCompatible(v, &key)
 }
 
@@ -187,9 +195,16 @@ func (k ecdsaPublicKey) Thumbprint(hash crypto.Hash) ([]byte, error) {
 	defer ecutil.ReleaseECPointBuffer(xbuf)
 	defer ecutil.ReleaseECPointBuffer(ybuf)
 
+	// The "crv" value is not necessarily the same as the name of the curve
+	// (e.g. "BP-256" vs "brainpoolP256r1")
+	crv, ok := curveAlgorithm(key.Curve)
+	if !ok {
+		return nil, errors.Errorf(`invalid elliptic curve %s`, key.Curve.Params().Name)
+	}
+
 	return ecdsaThumbprint(
 		hash,
-		key.Curve.Params().Name,
+		crv.String(),
 		base64.EncodeToString(xbuf),
 		base64.EncodeToString(ybuf),
 	), nil
@@ -211,9 +226,16 @@ func (k ecdsaPrivateKey) Thumbprint(hash crypto.Hash) ([]byte, error) {
 	defer ecutil.ReleaseECPointBuffer(xbuf)
 	defer ecutil.ReleaseECPointBuffer(ybuf)
 
+	// The "crv" value is not necessarily the same as the name of the curve
+	// (e.g. "BP-256" vs "brainpoolP256r1")
+	crv, ok := curveAlgorithm(key.Curve)
+	if !ok {
+		return nil, errors.Errorf(`invalid elliptic curve %s`, key.Curve.Params().Name)
+	}
+
 	return ecdsaThumbprint(
 		hash,
-		key.Curve.Params().Name,
+		crv.String(),
 		base64.EncodeToString(xbuf),
 		base64.EncodeToString(ybuf),
 	), nil
@@ -27,6 +27,10 @@ const (
 	ECDSAYKey   = "y"
 )
 
+// ECDSAPrivateKey is the interface for keys of type jwa.EC that hold
+// a raw key of type *ecdsa.PrivateKey. Keys returned by New() and the
+// parsing functions may be type-switched against this interface to
+// access the key type specific fields.
 type ECDSAPrivateKey interface {
 	Key
 	FromRaw(*ecdsa.PrivateKey) error
@@ -53,6 +57,8 @@ type ecdsaPrivateKey struct {
 	mu                     *sync.RWMutex
 }
 
+var _ ECDSAPrivateKey = &ecdsaPrivateKey{}
+
 func NewECDSAPrivateKey() ECDSAPrivateKey {
 	return newECDSAPrivateKey()
 }
@@ -408,6 +414,24 @@ func (k *ecdsaPrivateKey) Clone() (Key, error) {
 	return cloneKey(k)
 }
 
+func (k *ecdsaPrivateKey) SetPrivateField(name string, value interface{}) error {
+	return setPrivateField(k, name, value)
+}
+
+func (k *ecdsaPrivateKey) PrivateField(name string, dst interface{}) error {
+	return getPrivateField(k, name, dst)
+}
+
+func (k *ecdsaPrivateKey) MarshalRedacted() ([]byte, error) {
+	return marshalRedacted(k)
+}
+
+// String returns the redacted JSON representation of the key.
+// See `MarshalRedacted()`
+func (k *ecdsaPrivateKey) String() string {
+	return redactedString(k)
+}
+
 func (h *ecdsaPrivateKey) UnmarshalJSON(buf []byte) error {
 	h.algorithm = nil
 	h.crv = nil
@@ -596,6 +620,10 @@ func (h *ecdsaPrivateKey) AsMap(ctx context.Context) (map[string]interface{}, er
 	return iter.AsMap(ctx, h)
 }
 
+// ECDSAPublicKey is the interface for keys of type jwa.EC that hold
+// a raw key of type *ecdsa.PublicKey. Keys returned by New() and the
+// parsing functions may be type-switched against this interface to
+// access the key type specific fields.
 type ECDSAPublicKey interface {
 	Key
 	FromRaw(*ecdsa.PublicKey) error
@@ -620,6 +648,8 @@ type ecdsaPublicKey struct {
 	mu                     *sync.RWMutex
 }
 
+var _ ECDSAPublicKey = &ecdsaPublicKey{}
+
 func NewECDSAPublicKey() ECDSAPublicKey {
 	return newECDSAPublicKey()
 }
@@ -955,6 +985,18 @@ func (k *ecdsaPublicKey) Clone() (Key, error) {
 	return cloneKey(k)
 }
 
+func (k *ecdsaPublicKey) SetPrivateField(name string, value interface{}) error {
+	return setPrivateField(k, name, value)
+}
+
+func (k *ecdsaPublicKey) PrivateField(name string, dst interface{}) error {
+	return getPrivateField(k, name, dst)
+}
+
+func (k *ecdsaPublicKey) MarshalRedacted() ([]byte, error) {
+	return marshalRedacted(k)
+}
+
 func (h *ecdsaPublicKey) UnmarshalJSON(buf []byte) error {
 	h.algorithm = nil
 	h.crv = nil
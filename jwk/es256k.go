@@ -0,0 +1,13 @@
+//go:build jwx_es256k
+// +build jwx_es256k
+
+package jwk
+
+import (
+	"github.com/lestrrat-go/jwx/internal/ecutil"
+	"github.com/lestrrat-go/jwx/jwa"
+)
+
+func init() {
+	registerCurve(jwa.Secp256k1, ecutil.Secp256k1())
+}
@@ -0,0 +1,19 @@
+//go:build jwx_es256k
+// +build jwx_es256k
+
+package jwk_test
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/jwx/jwa"
+)
+
+func TestSecp256k1(t *testing.T) {
+	t.Parallel()
+	testCurveKey(t,
+		jwa.Secp256k1,
+		`{"kty":"EC","crv":"secp256k1","x":"qHzw37BuIj6iGdA2uFZLz-syRH4gz6wohVe928lxzeo","y":"r6zV_1ojgF339XfTQrhzEJaMplgKXDw4st24_PxSbE0","d":"piW076eOY2EGzS2dIlW07YGnlVcZinRxDf29ygwqyUw"}`,
+		`_N-DQO3UStnLOVqWNYiJxPtprXQTrU0oWu3QLiuwIr8`,
+	)
+}
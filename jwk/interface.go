@@ -77,11 +77,23 @@ type Set interface {
 
 	// Iterate creates an iterator to iterate through all keys in the set.
 	Iterate(context.Context) KeyIterator
+
+	// Subscribe registers a channel that receives a `jwk.SetChange` whenever
+	// keys are added to or removed from the set using `Add()`, `Remove()`
+	// or `Clear()`. Notifications are sent without blocking, and are
+	// dropped if the channel is not ready to receive them, so the
+	// channel should be buffered.
+	Subscribe(chan<- SetChange)
+
+	// Unsubscribe stops sending notifications to a channel that was
+	// registered using `Subscribe()`
+	Unsubscribe(chan<- SetChange)
 }
 
 type set struct {
 	keys []Key
 	mu   sync.RWMutex
+	subs subscribers
 }
 
 type HeaderVisitor = iter.MapVisitor
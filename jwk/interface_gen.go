@@ -74,6 +74,23 @@ type Key interface {
 	// AsMap is a utility tool that returns a new map that contains the same fields as the source
 	AsMap(context.Context) (map[string]interface{}, error)
 
+	// SetPrivateField sets an application specific field. The name must be
+	// namespaced as described in `jwk.ValidatePrivateFieldName()`, and the value
+	// must be serializable to JSON. Private fields are included when the key
+	// is serialized, but never in its thumbprint.
+	SetPrivateField(string, interface{}) error
+
+	// PrivateField assigns the value of an application specific field to `dst`,
+	// which must be a pointer. The value is converted through its JSON
+	// representation, so it can be retrieved in its original type (e.g. time.Time)
+	// after the key has been serialized and parsed again.
+	PrivateField(string, interface{}) error
+
+	// MarshalRedacted returns the JSON representation of the key, with the
+	// values of private members (e.g. "d") replaced by `jwk.RedactedValue`,
+	// so that the key can be logged without leaking secrets.
+	MarshalRedacted() ([]byte, error)
+
 	// PrivateParams returns the non-standard elements in the source structure
 	// WARNING: DO NOT USE PrivateParams() IF YOU HAVE CONCURRENT CODE ACCESSING THEM.
 	// Use `AsMap()` to get a copy of the entire header, or use `Iterate()` instead
@@ -476,6 +476,20 @@ func generateGenericHeaders() error {
 	fmt.Fprintf(&buf, "\nWalk(context.Context, HeaderVisitor) error")
 	fmt.Fprintf(&buf, "\n\n// AsMap is a utility tool that returns a new map that contains the same fields as the source")
 	fmt.Fprintf(&buf, "\nAsMap(context.Context) (map[string]interface{}, error)")
+	fmt.Fprintf(&buf, "\n\n// SetPrivateField sets an application specific field. The name must be")
+	fmt.Fprintf(&buf, "\n// namespaced as described in `jwk.ValidatePrivateFieldName()`, and the value")
+	fmt.Fprintf(&buf, "\n// must be serializable to JSON. Private fields are included when the key")
+	fmt.Fprintf(&buf, "\n// is serialized, but never in its thumbprint.")
+	fmt.Fprintf(&buf, "\nSetPrivateField(string, interface{}) error")
+	fmt.Fprintf(&buf, "\n\n// PrivateField assigns the value of an application specific field to `dst`,")
+	fmt.Fprintf(&buf, "\n// which must be a pointer. The value is converted through its JSON")
+	fmt.Fprintf(&buf, "\n// representation, so it can be retrieved in its original type (e.g. time.Time)")
+	fmt.Fprintf(&buf, "\n// after the key has been serialized and parsed again.")
+	fmt.Fprintf(&buf, "\nPrivateField(string, interface{}) error")
+	fmt.Fprintf(&buf, "\n\n// MarshalRedacted returns the JSON representation of the key, with the")
+	fmt.Fprintf(&buf, "\n// values of private members (e.g. \"d\") replaced by `jwk.RedactedValue`,")
+	fmt.Fprintf(&buf, "\n// so that the key can be logged without leaking secrets.")
+	fmt.Fprintf(&buf, "\nMarshalRedacted() ([]byte, error)")
 	fmt.Fprintf(&buf, "\n\n// PrivateParams returns the non-standard elements in the source structure")
 	fmt.Fprintf(&buf, "\n// WARNING: DO NOT USE PrivateParams() IF YOU HAVE CONCURRENT CODE ACCESSING THEM.")
 	fmt.Fprintf(&buf, "\n// Use `AsMap()` to get a copy of the entire header, or use `Iterate()` instead")
@@ -588,7 +602,15 @@ func generateHeader(kt keyType) error {
 			ifName = kt.prefix + ht.name
 		}
 
-		fmt.Fprintf(&buf, "\n\ntype %s interface {", ifName)
+		rawKeyDesc := "a raw key of type " + ht.rawKeyType
+		if ht.rawKeyType == "interface{}" {
+			rawKeyDesc = "a raw Ed25519 or X25519 key"
+		}
+		fmt.Fprintf(&buf, "\n\n// %s is the interface for keys of type %s that hold", ifName, kt.keyType)
+		fmt.Fprintf(&buf, "\n// %s. Keys returned by New() and the", rawKeyDesc)
+		fmt.Fprintf(&buf, "\n// parsing functions may be type-switched against this interface to")
+		fmt.Fprintf(&buf, "\n// access the key type specific fields.")
+		fmt.Fprintf(&buf, "\ntype %s interface {", ifName)
 		fmt.Fprintf(&buf, "\nKey")
 		fmt.Fprintf(&buf, "\nFromRaw(%s) error", ht.rawKeyType)
 		for _, header := range ht.headers {
@@ -607,6 +629,8 @@ func generateHeader(kt keyType) error {
 		fmt.Fprintf(&buf, "\nmu *sync.RWMutex")
 		fmt.Fprintf(&buf, "\n}")
 
+		fmt.Fprintf(&buf, "\n\nvar _ %s = &%s{}", ifName, structName)
+
 		fmt.Fprintf(&buf, "\n\nfunc New%[1]s() %[1]s {", ifName)
 		fmt.Fprintf(&buf, "\nreturn new%s()", ifName)
 		fmt.Fprintf(&buf, "\n}")
@@ -808,6 +832,26 @@ func generateHeader(kt keyType) error {
 		fmt.Fprintf(&buf, "\nreturn cloneKey(k)")
 		fmt.Fprintf(&buf, "\n}")
 
+		fmt.Fprintf(&buf, "\n\nfunc (k *%s) SetPrivateField(name string, value interface{}) error {", structName)
+		fmt.Fprintf(&buf, "\nreturn setPrivateField(k, name, value)")
+		fmt.Fprintf(&buf, "\n}")
+
+		fmt.Fprintf(&buf, "\n\nfunc (k *%s) PrivateField(name string, dst interface{}) error {", structName)
+		fmt.Fprintf(&buf, "\nreturn getPrivateField(k, name, dst)")
+		fmt.Fprintf(&buf, "\n}")
+
+		fmt.Fprintf(&buf, "\n\nfunc (k *%s) MarshalRedacted() ([]byte, error) {", structName)
+		fmt.Fprintf(&buf, "\nreturn marshalRedacted(k)")
+		fmt.Fprintf(&buf, "\n}")
+
+		if ht.name != "PublicKey" {
+			fmt.Fprintf(&buf, "\n\n// String returns the redacted JSON representation of the key.")
+			fmt.Fprintf(&buf, "\n// See `MarshalRedacted()`")
+			fmt.Fprintf(&buf, "\nfunc (k *%s) String() string {", structName)
+			fmt.Fprintf(&buf, "\nreturn redactedString(k)")
+			fmt.Fprintf(&buf, "\n}")
+		}
+
 		fmt.Fprintf(&buf, "\n\nfunc (h *%s) UnmarshalJSON(buf []byte) error {", structName)
 		for _, f := range ht.allHeaders {
 			fmt.Fprintf(&buf, "\nh.%s = nil", f.name)
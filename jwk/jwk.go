@@ -10,6 +10,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/rsa"
+	"crypto/subtle"
 	"crypto/x509"
 	"encoding/pem"
 	"io"
@@ -33,6 +34,8 @@ import (
 //   * "crypto/ecdsa".PrivateKey and "crypto/ecdsa".PublicKey creates an EC based key
 //   * "crypto/ed25519".PrivateKey and "crypto/ed25519".PublicKey creates an OKP based key
 //   * []byte creates a symmetric key
+//   * "crypto/ecdh".PrivateKey and "crypto/ecdh".PublicKey creates an EC
+//     or OKP based key, depending on the curve (go1.20 and later)
 func New(key interface{}) (Key, error) {
 	if key == nil {
 		return nil, errors.New(`jwk.New requires a non-nil key`)
@@ -108,7 +111,14 @@ func New(key interface{}) (Key, error) {
 		}
 		return k, nil
 	default:
-		return nil, errors.Errorf(`invalid key type '%T' for jwk.New`, key)
+		converted, ok, err := fromECDHKey(rawKey)
+		if !ok {
+			return nil, errors.Errorf(`invalid key type '%T' for jwk.New`, key)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to convert %T`, key)
+		}
+		return New(converted)
 	}
 }
 
@@ -336,10 +346,22 @@ func parsePEMEncodedRawKey(src []byte) (interface{}, []byte, error) {
 // Note that a successful parsing does NOT necessarily guarantee a valid key.
 func ParseKey(data []byte, options ...ParseOption) (Key, error) {
 	var parsePEM bool
+	var lenientCertChain bool
+	var compressedPoints bool
+	var validatePoint bool
+	var kidThumbprintHash crypto.Hash
 	for _, option := range options {
 		switch option.Ident() {
 		case identPEM{}:
 			parsePEM = option.Value().(bool)
+		case identLenientCertificateChain{}:
+			lenientCertChain = option.Value().(bool)
+		case identCompressedPoints{}:
+			compressedPoints = option.Value().(bool)
+		case identPointValidation{}:
+			validatePoint = option.Value().(bool)
+		case identRequireKidThumbprint{}:
+			kidThumbprintHash = option.Value().(crypto.Hash)
 		}
 	}
 
@@ -348,7 +370,21 @@ func ParseKey(data []byte, options ...ParseOption) (Key, error) {
 		if err != nil {
 			return nil, errors.Wrap(err, `failed to parse PEM encoded key`)
 		}
-		return New(raw)
+		key, err := New(raw)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to create jwk.Key from %T`, raw)
+		}
+		if validatePoint {
+			if err := ValidatePoint(key); err != nil {
+				return nil, errors.Wrap(err, `failed to validate point`)
+			}
+		}
+		if kidThumbprintHash != 0 {
+			if err := VerifyKidThumbprint(key, kidThumbprintHash); err != nil {
+				return nil, errors.Wrap(err, `failed to verify "kid"`)
+			}
+		}
+		return key, nil
 	}
 
 	var hint struct {
@@ -386,10 +422,38 @@ func ParseKey(data []byte, options ...ParseOption) (Key, error) {
 		return nil, errors.Errorf(`invalid key type from JSON (%s)`, hint.Kty)
 	}
 
+	if lenientCertChain {
+		normalized, err := normalizeCertificateChain(data)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to normalize certificate chain`)
+		}
+		data = normalized
+	}
+
+	if compressedPoints && jwa.KeyType(hint.Kty) == jwa.EC {
+		decompressed, err := decompressECPoint(data)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to decompress point`)
+		}
+		data = decompressed
+	}
+
 	if err := json.Unmarshal(data, key); err != nil {
 		return nil, errors.Wrapf(err, `failed to unmarshal JSON into key (%T)`, key)
 	}
 
+	if validatePoint {
+		if err := ValidatePoint(key); err != nil {
+			return nil, errors.Wrap(err, `failed to validate point`)
+		}
+	}
+
+	if kidThumbprintHash != 0 {
+		if err := VerifyKidThumbprint(key, kidThumbprintHash); err != nil {
+			return nil, errors.Wrap(err, `failed to verify "kid"`)
+		}
+	}
+
 	return key, nil
 }
 
@@ -408,10 +472,13 @@ func ParseKey(data []byte, options ...ParseOption) (Key, error) {
 // even if the data only contains a single JWK key
 func Parse(src []byte, options ...ParseOption) (Set, error) {
 	var parsePEM bool
+	var kidThumbprintHash crypto.Hash
 	for _, option := range options {
 		switch option.Ident() {
 		case identPEM{}:
 			parsePEM = option.Value().(bool)
+		case identRequireKidThumbprint{}:
+			kidThumbprintHash = option.Value().(crypto.Hash)
 		}
 	}
 
@@ -427,13 +494,18 @@ func Parse(src []byte, options ...ParseOption) (Set, error) {
 			if err != nil {
 				return nil, errors.Wrapf(err, `failed to create jwk.Key from %T`, raw)
 			}
+			if kidThumbprintHash != 0 {
+				if err := VerifyKidThumbprint(key, kidThumbprintHash); err != nil {
+					return nil, errors.Wrap(err, `failed to verify "kid"`)
+				}
+			}
 			s.Add(key)
 			src = bytes.TrimSpace(rest)
 		}
 		return s, nil
 	}
 
-	if err := json.Unmarshal(src, s); err != nil {
+	if err := s.(*set).unmarshalJSON(src, options...); err != nil {
 		return nil, errors.Wrap(err, "failed to unmarshal JWK set")
 	}
 	return s, nil
@@ -577,3 +649,23 @@ func asnEncode(key Key) (string, []byte, error) {
 		return "", nil, errors.Errorf(`unsupported key type %T`, key)
 	}
 }
+
+// VerifyKidThumbprint checks that the "kid" field of the key is the
+// base64url encoded RFC7638 thumbprint of the key, computed using the
+// given hash.
+func VerifyKidThumbprint(key Key, h crypto.Hash) error {
+	kid := key.KeyID()
+	if kid == "" {
+		return errors.New(`"kid" field is not set`)
+	}
+
+	tp, err := key.Thumbprint(h)
+	if err != nil {
+		return errors.Wrap(err, `failed to compute thumbprint`)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(kid), []byte(base64.EncodeToString(tp))) != 1 {
+		return errors.New(`"kid" does not match the thumbprint of the key`)
+	}
+	return nil
+}
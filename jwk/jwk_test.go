@@ -6,10 +6,16 @@ import (
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
 	"fmt"
+	"math/big"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/lestrrat-go/jwx/internal/jose"
 	"github.com/lestrrat-go/jwx/internal/json"
@@ -1257,3 +1263,601 @@ func TestOKP(t *testing.T) {
 		})
 	})
 }
+
+func TestFromCSR(t *testing.T) {
+	t.Parallel()
+
+	privkey, err := jwxtest.GenerateEcdsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`) {
+		return
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "example.com"},
+	}, privkey)
+	if !assert.NoError(t, err, `x509.CreateCertificateRequest should succeed`) {
+		return
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		t.Parallel()
+		key, err := jwk.FromCSR(der)
+		if !assert.NoError(t, err, `jwk.FromCSR should succeed`) {
+			return
+		}
+
+		if !assert.Implements(t, (*jwk.ECDSAPublicKey)(nil), key, `key should be jwk.ECDSAPublicKey`) {
+			return
+		}
+
+		var pubkey ecdsa.PublicKey
+		if !assert.NoError(t, key.Raw(&pubkey), `key.Raw should succeed`) {
+			return
+		}
+		if !assert.True(t, pubkey.X.Cmp(privkey.X) == 0 && pubkey.Y.Cmp(privkey.Y) == 0, `public keys should match`) {
+			return
+		}
+	})
+	t.Run("Tampered", func(t *testing.T) {
+		t.Parallel()
+		tampered := make([]byte, len(der))
+		copy(tampered, der)
+		tampered[len(tampered)-1] ^= 0xff
+
+		_, err := jwk.FromCSR(tampered)
+		if !assert.Error(t, err, `jwk.FromCSR should fail`) {
+			return
+		}
+	})
+}
+
+func TestRequireKidThumbprint(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwxtest.GenerateEcdsaPublicJwk()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaPublicJwk should succeed`) {
+		return
+	}
+
+	tp, err := key.Thumbprint(crypto.SHA256)
+	if !assert.NoError(t, err, `key.Thumbprint should succeed`) {
+		return
+	}
+
+	testcases := []struct {
+		Name  string
+		Kid   string
+		Error bool
+	}{
+		{Name: "kid matches thumbprint", Kid: base64.EncodeToString(tp)},
+		{Name: "kid does not match thumbprint", Kid: "my-key", Error: true},
+		{Name: "no kid", Error: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			clone, err := key.Clone()
+			if !assert.NoError(t, err, `key.Clone should succeed`) {
+				return
+			}
+			if tc.Kid != "" {
+				_ = clone.Set(jwk.KeyIDKey, tc.Kid)
+			}
+
+			buf, err := json.Marshal(clone)
+			if !assert.NoError(t, err, `json.Marshal should succeed`) {
+				return
+			}
+
+			_, err = jwk.ParseKey(buf, jwk.WithRequireKidThumbprint(crypto.SHA256))
+			if tc.Error {
+				if !assert.Error(t, err, `jwk.ParseKey should fail`) {
+					return
+				}
+			} else {
+				if !assert.NoError(t, err, `jwk.ParseKey should succeed`) {
+					return
+				}
+			}
+
+			_, err = jwk.Parse(buf, jwk.WithRequireKidThumbprint(crypto.SHA256))
+			if tc.Error {
+				if !assert.Error(t, err, `jwk.Parse should fail`) {
+					return
+				}
+			} else {
+				if !assert.NoError(t, err, `jwk.Parse should succeed`) {
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestCOSEKey(t *testing.T) {
+	t.Parallel()
+
+	generators := map[string]func() (jwk.Key, error){
+		"RSA private key": jwxtest.GenerateRsaJwk,
+		"RSA public key":  jwxtest.GenerateRsaPublicJwk,
+		"EC private key":  jwxtest.GenerateEcdsaJwk,
+		"EC public key":   jwxtest.GenerateEcdsaPublicJwk,
+		"Ed25519 key":     jwxtest.GenerateEd25519Jwk,
+		"X25519 key":      jwxtest.GenerateX25519Jwk,
+		"Symmetric key":   jwxtest.GenerateSymmetricJwk,
+	}
+
+	for name, generate := range generators {
+		generate := generate
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			key, err := generate()
+			if !assert.NoError(t, err, `generating key should succeed`) {
+				return
+			}
+			_ = key.Set(jwk.KeyIDKey, "cose-key")
+			_ = key.Set(jwk.KeyOpsKey, jwk.KeyOperationList{jwk.KeyOpSign, jwk.KeyOpVerify})
+
+			buf, err := jwk.MarshalCOSEKey(key)
+			if !assert.NoError(t, err, `jwk.MarshalCOSEKey should succeed`) {
+				return
+			}
+
+			parsed, err := jwk.ParseCOSEKey(buf)
+			if !assert.NoError(t, err, `jwk.ParseCOSEKey should succeed`) {
+				return
+			}
+
+			expected, err := json.Marshal(key)
+			if !assert.NoError(t, err, `json.Marshal should succeed`) {
+				return
+			}
+			actual, err := json.Marshal(parsed)
+			if !assert.NoError(t, err, `json.Marshal should succeed`) {
+				return
+			}
+			if !assert.JSONEq(t, string(expected), string(actual), `keys should match`) {
+				return
+			}
+		})
+	}
+
+	t.Run("Algorithm", func(t *testing.T) {
+		t.Parallel()
+
+		key, err := jwxtest.GenerateEcdsaPublicJwk()
+		if !assert.NoError(t, err, `generating key should succeed`) {
+			return
+		}
+		_ = key.Set(jwk.AlgorithmKey, jwa.ES256)
+
+		buf, err := jwk.MarshalCOSEKey(key)
+		if !assert.NoError(t, err, `jwk.MarshalCOSEKey should succeed`) {
+			return
+		}
+		parsed, err := jwk.ParseCOSEKey(buf)
+		if !assert.NoError(t, err, `jwk.ParseCOSEKey should succeed`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ES256.String(), parsed.Algorithm(), `algorithm should match`) {
+			return
+		}
+
+		_ = key.Set(jwk.AlgorithmKey, jwa.RSA1_5)
+		_, err = jwk.MarshalCOSEKey(key)
+		if !assert.Error(t, err, `jwk.MarshalCOSEKey should fail for algorithms not registered for COSE`) {
+			return
+		}
+
+		_ = key.Remove(jwk.AlgorithmKey)
+		if !assert.NoError(t, key.Set(jwk.KeyOpsKey, jwk.KeyOperationList{jwk.KeyOpSign, `x-frobnicate`}), `key.Set should succeed`) {
+			return
+		}
+		_, err = jwk.MarshalCOSEKey(key)
+		if !assert.Error(t, err, `jwk.MarshalCOSEKey should fail for key operations not registered for COSE`) {
+			return
+		}
+	})
+}
+
+func TestPrivateField(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwxtest.GenerateEcdsaJwk()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaJwk should succeed`) {
+		return
+	}
+
+	before, err := key.Thumbprint(crypto.SHA256)
+	if !assert.NoError(t, err, `key.Thumbprint should succeed`) {
+		return
+	}
+
+	rotatedAt := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	if !assert.NoError(t, key.SetPrivateField("x-myco-rotated-at", rotatedAt), `key.SetPrivateField should succeed`) {
+		return
+	}
+
+	for _, name := range []string{"rotated-at", "x-rotated", "x--rotated", "x-myco-"} {
+		if !assert.Error(t, key.SetPrivateField(name, rotatedAt), `key.SetPrivateField(%q) should fail`, name) {
+			return
+		}
+	}
+	if !assert.Error(t, key.SetPrivateField("x-myco-channel", make(chan int)), `key.SetPrivateField should fail for values that cannot be serialized`) {
+		return
+	}
+
+	after, err := key.Thumbprint(crypto.SHA256)
+	if !assert.NoError(t, err, `key.Thumbprint should succeed`) {
+		return
+	}
+	if !assert.Equal(t, before, after, `private fields should not affect the thumbprint`) {
+		return
+	}
+
+	buf, err := json.Marshal(key)
+	if !assert.NoError(t, err, `json.Marshal should succeed`) {
+		return
+	}
+
+	parsed, err := jwk.ParseKey(buf)
+	if !assert.NoError(t, err, `jwk.ParseKey should succeed`) {
+		return
+	}
+
+	var got time.Time
+	if !assert.NoError(t, parsed.PrivateField("x-myco-rotated-at", &got), `parsed.PrivateField should succeed`) {
+		return
+	}
+	if !assert.True(t, rotatedAt.Equal(got), `private field should round trip`) {
+		return
+	}
+	if !assert.Error(t, parsed.PrivateField("x-myco-missing", &got), `parsed.PrivateField should fail for missing fields`) {
+		return
+	}
+}
+
+func TestMarshalRedacted(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := jwxtest.GenerateRsaJwk()
+	if !assert.NoError(t, err, `jwxtest.GenerateRsaJwk should succeed`) {
+		return
+	}
+	ecKey, err := jwxtest.GenerateEcdsaJwk()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaJwk should succeed`) {
+		return
+	}
+	okpKey, err := jwxtest.GenerateEd25519Jwk()
+	if !assert.NoError(t, err, `jwxtest.GenerateEd25519Jwk should succeed`) {
+		return
+	}
+	symmetricKey, err := jwxtest.GenerateSymmetricJwk()
+	if !assert.NoError(t, err, `jwxtest.GenerateSymmetricJwk should succeed`) {
+		return
+	}
+
+	testcases := []struct {
+		Name    string
+		Key     jwk.Key
+		Private []string
+		Public  []string
+	}{
+		{Name: "RSA", Key: rsaKey, Private: []string{"d", "p", "q", "dp", "dq", "qi"}, Public: []string{"n", "e"}},
+		{Name: "ECDSA", Key: ecKey, Private: []string{"d"}, Public: []string{"x", "y"}},
+		{Name: "OKP", Key: okpKey, Private: []string{"d"}, Public: []string{"x"}},
+		{Name: "Symmetric", Key: symmetricKey, Private: []string{"k"}},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			if !assert.NoError(t, tc.Key.Set(jwk.KeyIDKey, "my-key"), `key.Set should succeed`) {
+				return
+			}
+
+			var original map[string]interface{}
+			buf, err := json.Marshal(tc.Key)
+			if !assert.NoError(t, err, `json.Marshal should succeed`) {
+				return
+			}
+			if !assert.NoError(t, json.Unmarshal(buf, &original), `json.Unmarshal should succeed`) {
+				return
+			}
+
+			buf, err = tc.Key.MarshalRedacted()
+			if !assert.NoError(t, err, `key.MarshalRedacted should succeed`) {
+				return
+			}
+			var redacted map[string]interface{}
+			if !assert.NoError(t, json.Unmarshal(buf, &redacted), `json.Unmarshal should succeed`) {
+				return
+			}
+
+			if !assert.Equal(t, "my-key", redacted[jwk.KeyIDKey], `kid should be preserved`) {
+				return
+			}
+			for _, name := range tc.Public {
+				if !assert.Equal(t, original[name], redacted[name], `%q should be preserved`, name) {
+					return
+				}
+			}
+			for _, name := range tc.Private {
+				if !assert.Equal(t, jwk.RedactedValue, redacted[name], `%q should be redacted`, name) {
+					return
+				}
+			}
+
+			s := fmt.Sprintf("%v", tc.Key)
+			if !assert.Equal(t, string(buf), s, `String() should return the redacted representation`) {
+				return
+			}
+			for _, name := range tc.Private {
+				if !assert.NotContains(t, s, original[name].(string), `%q should not be printed`, name) {
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestPointValidation(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Compressed EC point", func(t *testing.T) {
+		t.Parallel()
+
+		raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+			return
+		}
+
+		compressed := append([]byte{byte(2 + raw.Y.Bit(0))}, padBytes(raw.X.Bytes(), 32)...)
+		src := fmt.Sprintf(`{"kty":"EC","crv":"P-256","x":%q}`, base64.EncodeToString(compressed))
+
+		_, err = jwk.ParseKey([]byte(src))
+		if !assert.Error(t, err, `jwk.ParseKey should fail without jwk.WithCompressedPoints`) {
+			return
+		}
+
+		key, err := jwk.ParseKey([]byte(src), jwk.WithCompressedPoints(true), jwk.WithPointValidation(true))
+		if !assert.NoError(t, err, `jwk.ParseKey should succeed`) {
+			return
+		}
+
+		var pubkey ecdsa.PublicKey
+		if !assert.NoError(t, key.Raw(&pubkey), `key.Raw should succeed`) {
+			return
+		}
+		if !assert.Equal(t, raw.X, pubkey.X, `x should match`) {
+			return
+		}
+		if !assert.Equal(t, raw.Y, pubkey.Y, `y should match`) {
+			return
+		}
+
+		buf, err := json.Marshal(key)
+		if !assert.NoError(t, err, `json.Marshal should succeed`) {
+			return
+		}
+		var m map[string]interface{}
+		if !assert.NoError(t, json.Unmarshal(buf, &m), `json.Unmarshal should succeed`) {
+			return
+		}
+		if !assert.Contains(t, m, jwk.ECDSAYKey, `key should be serialized with "y"`) {
+			return
+		}
+
+		compressed[0] = 0x04
+		src = fmt.Sprintf(`{"kty":"EC","crv":"P-256","x":%q}`, base64.EncodeToString(compressed))
+		_, err = jwk.ParseKey([]byte(src), jwk.WithCompressedPoints(true))
+		if !assert.True(t, errors.Is(err, jwk.ErrInvalidCompressedPoint), `jwk.ParseKey should fail with ErrInvalidCompressedPoint`) {
+			return
+		}
+	})
+	t.Run("EC point not on curve", func(t *testing.T) {
+		t.Parallel()
+
+		raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+			return
+		}
+
+		y := new(big.Int).Add(raw.Y, big.NewInt(1))
+		src := fmt.Sprintf(`{"kty":"EC","crv":"P-256","x":%q,"y":%q}`,
+			base64.EncodeToString(padBytes(raw.X.Bytes(), 32)),
+			base64.EncodeToString(padBytes(y.Bytes(), 32)),
+		)
+
+		_, err = jwk.ParseKey([]byte(src))
+		if !assert.NoError(t, err, `jwk.ParseKey should succeed without jwk.WithPointValidation`) {
+			return
+		}
+
+		_, err = jwk.ParseKey([]byte(src), jwk.WithPointValidation(true))
+		if !assert.True(t, errors.Is(err, jwk.ErrPointNotOnCurve), `jwk.ParseKey should fail with ErrPointNotOnCurve`) {
+			return
+		}
+	})
+	t.Run("OKP small order points", func(t *testing.T) {
+		t.Parallel()
+
+		points := map[jwa.EllipticCurveAlgorithm][]string{
+			jwa.Ed25519: {
+				"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+				"7P_______________________________________38",
+				"7P________________________________________8",
+			},
+			jwa.X25519: {
+				"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+				"AQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+			},
+		}
+		for crv, list := range points {
+			for _, x := range list {
+				src := fmt.Sprintf(`{"kty":"OKP","crv":%q,"x":%q}`, crv, x)
+				_, err := jwk.ParseKey([]byte(src), jwk.WithPointValidation(true))
+				if !assert.True(t, errors.Is(err, jwk.ErrSmallOrderPoint), `jwk.ParseKey should fail with ErrSmallOrderPoint (%s, %s): %v`, crv, x, err) {
+					return
+				}
+			}
+		}
+	})
+	t.Run("Valid keys", func(t *testing.T) {
+		t.Parallel()
+
+		ecKey, err := jwxtest.GenerateEcdsaJwk()
+		if !assert.NoError(t, err, `jwxtest.GenerateEcdsaJwk should succeed`) {
+			return
+		}
+		edKey, err := jwxtest.GenerateEd25519Jwk()
+		if !assert.NoError(t, err, `jwxtest.GenerateEd25519Jwk should succeed`) {
+			return
+		}
+		xKey, err := jwxtest.GenerateX25519Jwk()
+		if !assert.NoError(t, err, `jwxtest.GenerateX25519Jwk should succeed`) {
+			return
+		}
+
+		for _, key := range []jwk.Key{ecKey, edKey, xKey} {
+			pubkey, err := jwk.PublicKeyOf(key)
+			if !assert.NoError(t, err, `jwk.PublicKeyOf should succeed`) {
+				return
+			}
+			if !assert.NoError(t, jwk.ValidatePoint(key), `jwk.ValidatePoint should succeed for private key`) {
+				return
+			}
+			buf, err := json.Marshal(pubkey)
+			if !assert.NoError(t, err, `json.Marshal should succeed`) {
+				return
+			}
+			if _, err := jwk.ParseKey(buf, jwk.WithPointValidation(true), jwk.WithCompressedPoints(true)); !assert.NoError(t, err, `jwk.ParseKey should succeed`) {
+				return
+			}
+		}
+	})
+}
+
+func padBytes(buf []byte, size int) []byte {
+	if len(buf) >= size {
+		return buf
+	}
+	return append(make([]byte, size-len(buf)), buf...)
+}
+
+func TestDescribe(t *testing.T) {
+	t.Parallel()
+
+	t.Run("RSA", func(t *testing.T) {
+		t.Parallel()
+		raw, err := jwxtest.GenerateRsaKey()
+		if !assert.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`) {
+			return
+		}
+		key, err := jwk.New(raw)
+		if !assert.NoError(t, err, `jwk.New should succeed`) {
+			return
+		}
+		_ = key.Set(jwk.KeyIDKey, "my-key")
+		_ = key.Set(jwk.AlgorithmKey, jwa.RS256)
+		_ = key.Set(jwk.KeyUsageKey, jwk.ForSignature)
+		_ = key.Set(jwk.X509CertChainKey, certChainSrc)
+
+		desc, err := jwk.Describe(key)
+		if !assert.NoError(t, err, `jwk.Describe should succeed`) {
+			return
+		}
+		if !assert.Equal(t, jwa.RSA, desc.KeyType, `kty should match`) {
+			return
+		}
+		if !assert.True(t, desc.Private, `key should be private`) {
+			return
+		}
+		if !assert.Equal(t, raw.N.BitLen(), desc.Bits, `bits should match`) {
+			return
+		}
+		if !assert.Equal(t, "my-key", desc.KeyID, `kid should match`) {
+			return
+		}
+		if !assert.Equal(t, "RS256", desc.Algorithm, `alg should match`) {
+			return
+		}
+		if !assert.Equal(t, "sig", desc.KeyUsage, `use should match`) {
+			return
+		}
+
+		tp, err := key.Thumbprint(crypto.SHA256)
+		if !assert.NoError(t, err, `key.Thumbprint should succeed`) {
+			return
+		}
+		if !assert.Equal(t, base64.EncodeToString(tp), desc.Thumbprints["SHA-256"], `SHA-256 thumbprint should match`) {
+			return
+		}
+		for _, name := range []string{"SHA-1", "SHA-384", "SHA-512"} {
+			if !assert.NotEmpty(t, desc.Thumbprints[name], `%s thumbprint should be present`, name) {
+				return
+			}
+		}
+
+		if !assert.Len(t, desc.Certificates, len(certChainSrc), `certificates should be described`) {
+			return
+		}
+		if !assert.Contains(t, desc.Certificates[0].Subject, "Go Daddy Secure Certification Authority", `subject should match`) {
+			return
+		}
+		if !assert.Equal(t, key.X509CertChain()[0].NotAfter, desc.Certificates[0].NotAfter, `expiry should match`) {
+			return
+		}
+
+		// The description must not leak secrets
+		buf, err := json.Marshal(desc)
+		if !assert.NoError(t, err, `json.Marshal should succeed`) {
+			return
+		}
+		if !assert.NotContains(t, string(buf), base64.EncodeToString(raw.D.Bytes()), `d should not be present`) {
+			return
+		}
+	})
+	t.Run("EC public key", func(t *testing.T) {
+		t.Parallel()
+		key, err := jwxtest.GenerateEcdsaPublicJwk()
+		if !assert.NoError(t, err, `jwxtest.GenerateEcdsaPublicJwk should succeed`) {
+			return
+		}
+		desc, err := jwk.Describe(key)
+		if !assert.NoError(t, err, `jwk.Describe should succeed`) {
+			return
+		}
+		if !assert.False(t, desc.Private, `key should not be private`) {
+			return
+		}
+		if !assert.Equal(t, key.(jwk.ECDSAPublicKey).Crv(), desc.Curve, `crv should match`) {
+			return
+		}
+		if !assert.Empty(t, desc.Certificates, `there should be no certificates`) {
+			return
+		}
+	})
+	t.Run("Symmetric", func(t *testing.T) {
+		t.Parallel()
+		key, err := jwk.New(make([]byte, 32))
+		if !assert.NoError(t, err, `jwk.New should succeed`) {
+			return
+		}
+		desc, err := jwk.Describe(key)
+		if !assert.NoError(t, err, `jwk.Describe should succeed`) {
+			return
+		}
+		if !assert.Equal(t, 256, desc.Bits, `bits should match`) {
+			return
+		}
+		if !assert.True(t, desc.Private, `key should be private`) {
+			return
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package jwktest
+
+import (
+	"crypto"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+)
+
+// Fixture is a test vector that describes how a JWK or a JWK set,
+// typically produced by another implementation, is expected to be
+// handled by the jwk package. Fixtures are stored as JSON files:
+//
+//     {
+//       "description": "RSA public key with a key ID",
+//       "source": "RFC 7638 section 3.1",
+//       "input": { "kty": "RSA", "n": "...", "e": "AQAB" },
+//       "expect": {
+//         "keys": 1,
+//         "thumbprints": [ "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" ]
+//       }
+//     }
+//
+// "input" is either a single JWK or a JWK set. "source" records where the
+// vector came from, e.g. the library and version that generated it.
+type Fixture struct {
+	// Name identifies the fixture in error messages. `LoadFixtures()`
+	// uses the name of the file.
+	Name        string             `json:"-"`
+	Description string             `json:"description,omitempty"`
+	Source      string             `json:"source,omitempty"`
+	Input       json.RawMessage    `json:"input"`
+	Expect      FixtureExpectation `json:"expect"`
+}
+
+// FixtureExpectation describes the expected outcome of a Fixture
+type FixtureExpectation struct {
+	// Error is true if parsing the input must fail. No other
+	// expectations are checked in that case.
+	Error bool `json:"error,omitempty"`
+
+	// Keys is the expected number of keys. It is not checked if 0.
+	Keys int `json:"keys,omitempty"`
+
+	// Thumbprints are the base64url encoded SHA-256 thumbprints
+	// (RFC 7638) of the keys, in order. They are not checked if empty.
+	Thumbprints []string `json:"thumbprints,omitempty"`
+
+	// Output is the expected serialization of the parsed input. Members
+	// are compared regardless of their order. If not specified, the
+	// input must survive a round trip unchanged.
+	Output json.RawMessage `json:"output,omitempty"`
+}
+
+// TestingT is the subset of *testing.T that is used by `RunFixtures()`
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// ParseFixture parses a single fixture
+func ParseFixture(name string, src []byte) (*Fixture, error) {
+	var f Fixture
+	if err := json.Unmarshal(src, &f); err != nil {
+		return nil, errors.Wrapf(err, `failed to parse fixture %s`, name)
+	}
+	if len(f.Input) == 0 {
+		return nil, errors.Errorf(`fixture %s has no input`, name)
+	}
+	f.Name = name
+	return &f, nil
+}
+
+// LoadFixtures loads all files with the ".json" extension in `dir` as
+// fixtures, sorted by their names
+func LoadFixtures(dir string) ([]*Fixture, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to list fixtures`)
+	}
+	sort.Strings(files)
+
+	fixtures := make([]*Fixture, 0, len(files))
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to read fixture %s`, file)
+		}
+		f, err := ParseFixture(filepath.Base(file), src)
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, f)
+	}
+	return fixtures, nil
+}
+
+// RunFixtures checks all fixtures, reports the ones that fail via `t`,
+// and returns true if all of them passed
+func RunFixtures(t TestingT, fixtures []*Fixture) bool {
+	t.Helper()
+	ok := true
+	for _, f := range fixtures {
+		if err := f.Check(); err != nil {
+			t.Errorf(`fixture %s: %s`, f.Name, err)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// Check parses the input of the fixture, and compares the result
+// against the expectations
+func (f *Fixture) Check() error {
+	set, err := jwk.Parse(f.Input)
+	if f.Expect.Error {
+		if err == nil {
+			return errors.New(`expected parsing to fail, but it succeeded`)
+		}
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, `failed to parse input`)
+	}
+
+	if f.Expect.Keys > 0 && set.Len() != f.Expect.Keys {
+		return errors.Errorf(`expected %d keys, got %d`, f.Expect.Keys, set.Len())
+	}
+
+	if len(f.Expect.Thumbprints) > 0 {
+		thumbprints := make([]string, set.Len())
+		for i := range thumbprints {
+			key, _ := set.Get(i)
+			tp, err := key.Thumbprint(crypto.SHA256)
+			if err != nil {
+				return errors.Wrapf(err, `failed to compute thumbprint of key #%d`, i)
+			}
+			thumbprints[i] = base64.EncodeToString(tp)
+		}
+		if !reflect.DeepEqual(f.Expect.Thumbprints, thumbprints) {
+			return errors.Errorf(`expected thumbprints [%s], got [%s]`, strings.Join(f.Expect.Thumbprints, ", "), strings.Join(thumbprints, ", "))
+		}
+	}
+
+	expected := f.Expect.Output
+	if len(expected) == 0 {
+		expected = f.Input
+	}
+
+	// A single JWK is serialized as such, not as a set
+	var serialized interface{} = set
+	if !isSet(f.Input) && set.Len() == 1 {
+		serialized, _ = set.Get(0)
+	}
+	actual, err := json.Marshal(serialized)
+	if err != nil {
+		return errors.Wrap(err, `failed to serialize parsed input`)
+	}
+
+	equal, err := jsonEqual(expected, actual)
+	if err != nil {
+		return err
+	}
+	if !equal {
+		return errors.Errorf("serialization does not match\nexpected: %s\nactual:   %s", compact(expected), actual)
+	}
+	return nil
+}
+
+func isSet(src []byte) bool {
+	var v struct {
+		Keys json.RawMessage `json:"keys"`
+	}
+	return json.Unmarshal(src, &v) == nil && v.Keys != nil
+}
+
+func jsonEqual(expected, actual []byte) (bool, error) {
+	var e, a interface{}
+	if err := json.Unmarshal(expected, &e); err != nil {
+		return false, errors.Wrap(err, `failed to parse expected output`)
+	}
+	if err := json.Unmarshal(actual, &a); err != nil {
+		return false, errors.Wrap(err, `failed to parse actual output`)
+	}
+	return reflect.DeepEqual(e, a), nil
+}
+
+func compact(src []byte) []byte {
+	var v interface{}
+	if err := json.Unmarshal(src, &v); err != nil {
+		return src
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return src
+	}
+	return buf
+}
@@ -0,0 +1,339 @@
+// Package jwktest generates deterministic JWKS documents for tests.
+//
+// The documents can be of arbitrary size and shape, which is useful for
+// load testing verifiers, measuring the performance of key lookups in
+// large sets, and exercising the error paths of code that consumes
+// JWKS documents:
+//
+//     jwks, err := jwktest.NewGenerator(1).
+//       RSA(10).
+//       EC(10).
+//       DuplicateKeyIDs(2).
+//       Broken(3).
+//       Generate()
+//
+// The same seed and configuration always produce the same keys, so
+// the documents can be compared against golden files. The keys are
+// derived from a predictable random number generator, and MUST NOT be
+// used outside of tests.
+//
+// The package also provides a fixture format and runner for checking
+// how JWKs produced by other implementations are parsed and serialized.
+// See `Fixture` for the format. The fixtures that are checked as part of
+// this package's tests live in testdata/fixtures: contributions of
+// vectors that expose interoperability problems are welcome there.
+package jwktest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"math/big"
+	"math/rand"
+
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+)
+
+// Generator creates JWKS documents. Use the methods to configure the
+// shape of the document, and call `Generate()` to create it.
+type Generator struct {
+	seed          int64
+	rsaCount      int
+	rsaBits       int
+	ecCount       int
+	curve         jwa.EllipticCurveAlgorithm
+	duplicateKids int
+	brokenCount   int
+	kidPrefix     string
+}
+
+// NewGenerator creates a new Generator that derives keys from `seed`.
+// By default no keys are generated, RSA keys are 2048 bits, EC keys
+// use P-256, and key IDs start with "test".
+func NewGenerator(seed int64) *Generator {
+	return &Generator{
+		seed:      seed,
+		rsaBits:   2048,
+		curve:     jwa.P256,
+		kidPrefix: "test",
+	}
+}
+
+// RSA specifies the number of RSA keys
+func (g *Generator) RSA(n int) *Generator {
+	g.rsaCount = n
+	return g
+}
+
+// RSABits specifies the size of the RSA keys. It must be a multiple
+// of 16, and at least 1024.
+func (g *Generator) RSABits(bits int) *Generator {
+	g.rsaBits = bits
+	return g
+}
+
+// EC specifies the number of EC keys
+func (g *Generator) EC(n int) *Generator {
+	g.ecCount = n
+	return g
+}
+
+// Curve specifies the curve of the EC keys. jwa.P256, jwa.P384, and
+// jwa.P521 are supported.
+func (g *Generator) Curve(crv jwa.EllipticCurveAlgorithm) *Generator {
+	g.curve = crv
+	return g
+}
+
+// DuplicateKeyIDs specifies the number of keys that share their key ID
+// with another key in the set. The last `n` keys are given the key IDs
+// of the first `n` keys, so `n` must not be more than half of the keys.
+func (g *Generator) DuplicateKeyIDs(n int) *Generator {
+	g.duplicateKids = n
+	return g
+}
+
+// Broken specifies the number of malformed entries that are appended to
+// the "keys" array of the document. The entries cycle through a key
+// without a required member, a key with a member that is not valid
+// base64url, and a key with an unknown "kty".
+func (g *Generator) Broken(n int) *Generator {
+	g.brokenCount = n
+	return g
+}
+
+// KeyIDPrefix specifies the prefix of the key IDs. The key IDs are of the
+// form "<prefix>-rsa-0001" and "<prefix>-ec-0001".
+func (g *Generator) KeyIDPrefix(prefix string) *Generator {
+	g.kidPrefix = prefix
+	return g
+}
+
+// JWKS is a generated JWKS document
+type JWKS struct {
+	private jwk.Set
+	public  jwk.Set
+	broken  []json.RawMessage
+}
+
+// PrivateKeys returns the private keys, which can be used to sign
+// tokens that are verified using the document
+func (j *JWKS) PrivateKeys() jwk.Set {
+	return j.private
+}
+
+// PublicKeys returns the public keys in the document. The broken
+// entries are not included.
+func (j *JWKS) PublicKeys() jwk.Set {
+	return j.public
+}
+
+// MarshalJSON serializes the document, including the broken entries
+func (j *JWKS) MarshalJSON() ([]byte, error) {
+	buf, err := json.Marshal(j.public)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal public keys`)
+	}
+
+	var doc struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return nil, errors.Wrap(err, `failed to unmarshal public keys`)
+	}
+	doc.Keys = append(doc.Keys, j.broken...)
+	return json.Marshal(doc)
+}
+
+// Generate creates the document
+func (g *Generator) Generate() (*JWKS, error) {
+	total := g.rsaCount + g.ecCount
+	if g.duplicateKids < 0 || g.duplicateKids*2 > total {
+		return nil, errors.Errorf(`cannot duplicate %d key IDs in a set of %d keys`, g.duplicateKids, total)
+	}
+
+	rng := rand.New(rand.NewSource(g.seed))
+
+	keys := make([]jwk.Key, 0, total)
+	for i := 0; i < g.rsaCount; i++ {
+		raw, err := generateRSAKey(rng, g.rsaBits)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to generate RSA key`)
+		}
+		key, err := newKey(raw, fmt.Sprintf("%s-rsa-%04d", g.kidPrefix, i+1), jwa.RS256)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	curve, alg, err := curveFor(g.curve)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < g.ecCount; i++ {
+		raw, err := generateECDSAKey(rng, curve)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to generate EC key`)
+		}
+		key, err := newKey(raw, fmt.Sprintf("%s-ec-%04d", g.kidPrefix, i+1), alg)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	for i := 0; i < g.duplicateKids; i++ {
+		if err := keys[total-1-i].Set(jwk.KeyIDKey, keys[i].KeyID()); err != nil {
+			return nil, errors.Wrap(err, `failed to set key ID`)
+		}
+	}
+
+	private := jwk.NewSet()
+	for _, key := range keys {
+		private.Add(key)
+	}
+	public, err := jwk.PublicSetOf(private)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create public key set`)
+	}
+
+	broken := make([]json.RawMessage, g.brokenCount)
+	for i := range broken {
+		kid := fmt.Sprintf("%s-broken-%04d", g.kidPrefix, i+1)
+		switch i % 3 {
+		case 0:
+			broken[i] = json.RawMessage(fmt.Sprintf(`{"kty":"RSA","kid":%q,"n":"sXchDaQebHnPiGvyDOAT4saGEUetSyo9MKLOoWFsueri23bOdgWp4Dy1WlUzewbgBHod5pcM9H95GQRV3JDXboIRROSBigeC5yjU1hGzHHyXss8UDprecbAYxknTcQkhslANGRUZmdTOQ5qTRsLAt6BTYuyvVRdhS8exSZEy_c4gs_7svlJJQ4H9_NxsiIoLwAEk7-Q3UXERGYw_75IDrGA84-lA_-Ct4eTlXHBIY2EaV7t7LjJaynVJCpkv4LKjTTAumiGUIuQhrNhZLuF_RJLqHpM2kgWFLU7-VTdL1VbC2tejvcI2BlMkEpk1BzBZI0KQB0GaDWFLN-aEAw3vRw"}`, kid))
+		case 1:
+			broken[i] = json.RawMessage(fmt.Sprintf(`{"kty":"EC","kid":%q,"crv":"P-256","x":"not base64!","y":"not base64!"}`, kid))
+		default:
+			broken[i] = json.RawMessage(fmt.Sprintf(`{"kty":"unknown","kid":%q}`, kid))
+		}
+	}
+
+	return &JWKS{
+		private: private,
+		public:  public,
+		broken:  broken,
+	}, nil
+}
+
+func newKey(raw interface{}, kid string, alg jwa.SignatureAlgorithm) (jwk.Key, error) {
+	key, err := jwk.New(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create jwk.Key`)
+	}
+	if err := key.Set(jwk.KeyIDKey, kid); err != nil {
+		return nil, errors.Wrap(err, `failed to set key ID`)
+	}
+	if err := key.Set(jwk.AlgorithmKey, alg); err != nil {
+		return nil, errors.Wrap(err, `failed to set algorithm`)
+	}
+	if err := key.Set(jwk.KeyUsageKey, jwk.ForSignature); err != nil {
+		return nil, errors.Wrap(err, `failed to set key usage`)
+	}
+	return key, nil
+}
+
+func curveFor(crv jwa.EllipticCurveAlgorithm) (elliptic.Curve, jwa.SignatureAlgorithm, error) {
+	switch crv {
+	case jwa.P256:
+		return elliptic.P256(), jwa.ES256, nil
+	case jwa.P384:
+		return elliptic.P384(), jwa.ES384, nil
+	case jwa.P521:
+		return elliptic.P521(), jwa.ES512, nil
+	default:
+		return nil, "", errors.Errorf(`unsupported curve %s`, crv)
+	}
+}
+
+// generateRSAKey is used instead of rsa.GenerateKey(), which does not
+// produce the same key for the same random stream
+func generateRSAKey(rng io.Reader, bits int) (*rsa.PrivateKey, error) {
+	if bits < 1024 || bits%16 != 0 {
+		return nil, errors.Errorf(`invalid RSA key size %d`, bits)
+	}
+
+	e := big.NewInt(65537)
+	one := big.NewInt(1)
+	for {
+		p, err := generatePrime(rng, bits/2)
+		if err != nil {
+			return nil, err
+		}
+		q, err := generatePrime(rng, bits/2)
+		if err != nil {
+			return nil, err
+		}
+		if p.Cmp(q) == 0 {
+			continue
+		}
+
+		n := new(big.Int).Mul(p, q)
+		if n.BitLen() != bits {
+			continue
+		}
+
+		pminus1 := new(big.Int).Sub(p, one)
+		qminus1 := new(big.Int).Sub(q, one)
+		totient := new(big.Int).Mul(pminus1, qminus1)
+		d := new(big.Int).ModInverse(e, totient)
+		if d == nil {
+			continue
+		}
+
+		key := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: n, E: int(e.Int64())},
+			D:         d,
+			Primes:    []*big.Int{p, q},
+		}
+		key.Precompute()
+		if err := key.Validate(); err != nil {
+			return nil, errors.Wrap(err, `generated invalid RSA key`)
+		}
+		return key, nil
+	}
+}
+
+// generatePrime returns the first prime that is larger than a random
+// number of the given size, whose two most significant bits are set
+func generatePrime(rng io.Reader, bits int) (*big.Int, error) {
+	buf := make([]byte, bits/8)
+	if _, err := io.ReadFull(rng, buf); err != nil {
+		return nil, errors.Wrap(err, `failed to read random bytes`)
+	}
+	buf[0] |= 0xc0
+	buf[len(buf)-1] |= 1
+
+	two := big.NewInt(2)
+	p := new(big.Int).SetBytes(buf)
+	for !p.ProbablyPrime(20) {
+		p.Add(p, two)
+	}
+	return p, nil
+}
+
+func generateECDSAKey(rng io.Reader, curve elliptic.Curve) (*ecdsa.PrivateKey, error) {
+	params := curve.Params()
+	buf := make([]byte, (params.N.BitLen()+7)/8+8)
+	if _, err := io.ReadFull(rng, buf); err != nil {
+		return nil, errors.Wrap(err, `failed to read random bytes`)
+	}
+
+	// d = (random mod (N - 1)) + 1, which is in [1, N-1]
+	nminus1 := new(big.Int).Sub(params.N, big.NewInt(1))
+	d := new(big.Int).SetBytes(buf)
+	d.Mod(d, nminus1)
+	d.Add(d, big.NewInt(1))
+
+	key := &ecdsa.PrivateKey{D: d}
+	key.Curve = curve
+	key.X, key.Y = curve.ScalarBaseMult(d.Bytes())
+	return key, nil
+}
@@ -0,0 +1,194 @@
+package jwktest_test
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jwk/jwktest"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerator(t *testing.T) {
+	t.Parallel()
+
+	generate := func() *jwktest.JWKS {
+		jwks, err := jwktest.NewGenerator(42).
+			RSA(2).
+			RSABits(1024).
+			EC(3).
+			Curve(jwa.P384).
+			DuplicateKeyIDs(1).
+			Broken(3).
+			KeyIDPrefix("load").
+			Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %s", err)
+		}
+		return jwks
+	}
+
+	jwks := generate()
+	buf, err := json.Marshal(jwks)
+	if !assert.NoError(t, err, `json.Marshal should succeed`) {
+		return
+	}
+
+	t.Run("Deterministic", func(t *testing.T) {
+		t.Parallel()
+		again, err := json.Marshal(generate())
+		if !assert.NoError(t, err, `json.Marshal should succeed`) {
+			return
+		}
+		if !assert.True(t, bytes.Equal(buf, again), `documents should be identical`) {
+			return
+		}
+	})
+	t.Run("Shape", func(t *testing.T) {
+		t.Parallel()
+		var doc struct {
+			Keys []json.RawMessage `json:"keys"`
+		}
+		if !assert.NoError(t, json.Unmarshal(buf, &doc), `json.Unmarshal should succeed`) {
+			return
+		}
+		if !assert.Len(t, doc.Keys, 8, `document should contain 5 keys and 3 broken entries`) {
+			return
+		}
+
+		var broken int
+		for _, raw := range doc.Keys {
+			if _, err := jwk.ParseKey(raw); err != nil {
+				broken++
+			}
+		}
+		if !assert.Equal(t, 3, broken, `broken entries should fail to parse`) {
+			return
+		}
+
+		public := jwks.PublicKeys()
+		if !assert.Equal(t, 5, public.Len(), `public key set should contain 5 keys`) {
+			return
+		}
+		first, _ := public.Get(0)
+		last, _ := public.Get(4)
+		if !assert.Equal(t, "load-rsa-0001", first.KeyID(), `key ID should match`) {
+			return
+		}
+		if !assert.Equal(t, first.KeyID(), last.KeyID(), `last key should duplicate the key ID of the first key`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ES384.String(), last.Algorithm(), `algorithm should match`) {
+			return
+		}
+		if _, ok := last.(jwk.ECDSAPublicKey); !assert.True(t, ok, `last key should be a public EC key`) {
+			return
+		}
+	})
+	t.Run("Sign and verify", func(t *testing.T) {
+		t.Parallel()
+		private := jwks.PrivateKeys()
+		public := jwks.PublicKeys()
+		for i := 0; i < private.Len(); i++ {
+			key, _ := private.Get(i)
+			signed, err := jws.Sign([]byte(`Lorem ipsum`), jwa.SignatureAlgorithm(key.Algorithm()), key)
+			if !assert.NoError(t, err, `jws.Sign should succeed`) {
+				return
+			}
+			pubkey, _ := public.Get(i)
+			_, err = jws.Verify(signed, jwa.SignatureAlgorithm(pubkey.Algorithm()), pubkey)
+			if !assert.NoError(t, err, `jws.Verify should succeed`) {
+				return
+			}
+		}
+	})
+	t.Run("Invalid configuration", func(t *testing.T) {
+		t.Parallel()
+		_, err := jwktest.NewGenerator(1).EC(2).DuplicateKeyIDs(2).Generate()
+		if !assert.Error(t, err, `Generate should fail`) {
+			return
+		}
+		_, err = jwktest.NewGenerator(1).RSA(1).RSABits(1000).Generate()
+		if !assert.Error(t, err, `Generate should fail`) {
+			return
+		}
+	})
+}
+
+type recorder struct {
+	messages []string
+}
+
+func (r *recorder) Helper() {}
+func (r *recorder) Errorf(f string, args ...interface{}) {
+	r.messages = append(r.messages, fmt.Sprintf(f, args...))
+}
+
+func TestFixtures(t *testing.T) {
+	t.Parallel()
+
+	fixtures, err := jwktest.LoadFixtures(filepath.Join("testdata", "fixtures"))
+	if !assert.NoError(t, err, `jwktest.LoadFixtures should succeed`) {
+		return
+	}
+	if !assert.NotEmpty(t, fixtures, `fixtures should be loaded`) {
+		return
+	}
+	jwktest.RunFixtures(t, fixtures)
+
+	t.Run("Mismatch", func(t *testing.T) {
+		t.Parallel()
+		testcases := []struct {
+			Name     string
+			Fixture  string
+			Expected string
+		}{
+			{
+				Name:     "Unexpected success",
+				Fixture:  `{"input":{"kty":"oct","k":"AAAA"},"expect":{"error":true}}`,
+				Expected: `expected parsing to fail`,
+			},
+			{
+				Name:     "Unexpected failure",
+				Fixture:  `{"input":{"kty":"oct"},"expect":{}}`,
+				Expected: `failed to parse input`,
+			},
+			{
+				Name:     "Number of keys",
+				Fixture:  `{"input":{"kty":"oct","k":"AAAA"},"expect":{"keys":2}}`,
+				Expected: `expected 2 keys, got 1`,
+			},
+			{
+				Name:     "Thumbprint",
+				Fixture:  `{"input":{"kty":"oct","k":"AAAA"},"expect":{"thumbprints":["bogus"]}}`,
+				Expected: `expected thumbprints [bogus]`,
+			},
+			{
+				Name:     "Output",
+				Fixture:  `{"input":{"kty":"oct","k":"AAAA"},"expect":{"output":{"kty":"oct","k":"AAAB"}}}`,
+				Expected: `serialization does not match`,
+			},
+		}
+		for _, tc := range testcases {
+			f, err := jwktest.ParseFixture(tc.Name, []byte(tc.Fixture))
+			if !assert.NoError(t, err, `jwktest.ParseFixture should succeed`) {
+				return
+			}
+			var r recorder
+			if !assert.False(t, jwktest.RunFixtures(&r, []*jwktest.Fixture{f}), `jwktest.RunFixtures should fail (%s)`, tc.Name) {
+				return
+			}
+			if !assert.Len(t, r.messages, 1, `one failure should be reported (%s)`, tc.Name) {
+				return
+			}
+			if !assert.Contains(t, r.messages[0], tc.Expected, `failure should be reported (%s)`, tc.Name) {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,424 @@
+package jwk
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/internal/keywrap"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Parameters of the PBES2 key derivation used for key files that are
+// encrypted with a passphrase
+const (
+	keyFilePBES2Count    = 600000
+	keyFileMaxPBES2Count = 10000000
+	keyFileSaltSize      = 16
+	keyFileContentType   = "jwk+json"
+)
+
+// LoadOrGenerate loads the private key stored in the file `path`. If the
+// file does not exist, a new key suitable for `alg` is generated and
+// stored in it, so that the key survives restarts: this gives services
+// that do not have a separate provisioning step a stable signing key.
+//
+// Generated keys have their "alg" and "use" fields set, and their key ID
+// is the SHA-256 thumbprint of the key. The following keys are generated:
+//
+//   - RS* and PS*: 2048 bit RSA keys
+//   - ES256, ES384 and ES512: P-256, P-384 and P-521 keys
+//   - EdDSA: Ed25519 keys
+//   - HS256, HS384 and HS512: 32, 48 and 64 byte symmetric keys
+//
+// The file is created with 0600 permissions, and appears atomically: if
+// several processes race to create it, all of them end up using the key
+// of the one that won. An existing file is never overwritten.
+//
+// The key is stored as a JSON encoded JWK. If `jwk.WithPassphrase()` is
+// specified, it is stored as a JWE in compact serialization instead,
+// encrypted using PBES2-HS256+A128KW and A128GCM, which can also be
+// decrypted using `jwe.Decrypt()`, and existing keys that are not
+// encrypted are rejected. An existing key must be a private key that
+// can be used with `alg`, and if it has an "alg" field, it must match
+// `alg`.
+func LoadOrGenerate(path string, alg jwa.SignatureAlgorithm, options ...PersistOption) (Key, error) {
+	var passphrase []byte
+	for _, option := range options {
+		switch option.Ident() {
+		case identPassphrase{}:
+			passphrase = option.Value().([]byte)
+		}
+	}
+
+	key, err := loadKeyFile(path, passphrase)
+	if err == nil {
+		return checkKeyFileAlgorithm(key, alg)
+	}
+	if !os.IsNotExist(errors.Cause(err)) {
+		return nil, errors.Wrapf(err, `failed to load key from %s`, path)
+	}
+
+	key, err = generateKey(alg)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to generate key for %s`, alg)
+	}
+
+	buf, err := json.Marshal(key)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal key`)
+	}
+	if passphrase != nil {
+		buf, err = encryptKeyFile(buf, passphrase)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to encrypt key`)
+		}
+	}
+
+	created, err := createFileExclusive(path, buf)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to store key in %s`, path)
+	}
+	if created {
+		return key, nil
+	}
+
+	// Another process created the file first: use its key instead
+	key, err = loadKeyFile(path, passphrase)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to load key from %s`, path)
+	}
+	return checkKeyFileAlgorithm(key, alg)
+}
+
+// checkKeyFileAlgorithm checks that the stored key can be used to sign
+// using `alg`. Keys without an "alg" field are checked by their type,
+// so that e.g. an RSA key is not used with ES256.
+func checkKeyFileAlgorithm(key Key, alg jwa.SignatureAlgorithm) (Key, error) {
+	if v := key.Algorithm(); v != "" && v != alg.String() {
+		return nil, errors.Errorf(`algorithm of stored key (%s) does not match %s`, v, alg)
+	}
+
+	var ok bool
+	switch alg {
+	case jwa.RS256, jwa.RS384, jwa.RS512, jwa.PS256, jwa.PS384, jwa.PS512:
+		_, ok = key.(RSAPrivateKey)
+	case jwa.ES256, jwa.ES384, jwa.ES512:
+		if v, isECDSA := key.(ECDSAPrivateKey); isECDSA {
+			ok = v.Crv() == keyFileCurves[alg]
+		}
+	case jwa.EdDSA:
+		if v, isOKP := key.(OKPPrivateKey); isOKP {
+			ok = v.Crv() == jwa.Ed25519
+		}
+	case jwa.HS256, jwa.HS384, jwa.HS512:
+		_, ok = key.(SymmetricKey)
+	}
+	if !ok {
+		return nil, errors.Errorf(`stored key (%T) can not be used with %s`, key, alg)
+	}
+	return key, nil
+}
+
+var keyFileCurves = map[jwa.SignatureAlgorithm]jwa.EllipticCurveAlgorithm{
+	jwa.ES256: jwa.P256,
+	jwa.ES384: jwa.P384,
+	jwa.ES512: jwa.P521,
+}
+
+func loadKeyFile(path string, passphrase []byte) (Key, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	buf = bytes.TrimSpace(buf)
+	encrypted := len(buf) > 0 && buf[0] != '{'
+	switch {
+	case encrypted && passphrase == nil:
+		return nil, errors.New(`key is encrypted, but no passphrase was specified`)
+	case !encrypted && passphrase != nil:
+		// Do not let a plaintext file replace a key that is expected
+		// to be protected by the passphrase
+		return nil, errors.New(`key is not encrypted, but a passphrase was specified`)
+	case encrypted:
+		buf, err = decryptKeyFile(buf, passphrase)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to decrypt key`)
+		}
+	}
+
+	key, err := ParseKey(buf)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to parse key`)
+	}
+	return key, nil
+}
+
+// createFileExclusive atomically creates the file `path` containing
+// `buf`, unless it already exists. It returns false if the file exists.
+func createFileExclusive(path string, buf []byte) (bool, error) {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	// The contents are written to a temporary file first, so that other
+	// processes never see a partially written file
+	f, err := ioutil.TempFile(dir, "."+name+".tmp")
+	if err != nil {
+		return false, errors.Wrap(err, `failed to create temporary file`)
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return false, errors.Wrap(err, `failed to change permissions of temporary file`)
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return false, errors.Wrap(err, `failed to write temporary file`)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return false, errors.Wrap(err, `failed to sync temporary file`)
+	}
+	if err := f.Close(); err != nil {
+		return false, errors.Wrap(err, `failed to close temporary file`)
+	}
+
+	// Unlike rename, link fails if the destination exists
+	if err := os.Link(tmp, path); err != nil {
+		if os.IsExist(err) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, `failed to link temporary file`)
+	}
+	return true, nil
+}
+
+func generateKey(alg jwa.SignatureAlgorithm) (Key, error) {
+	var raw interface{}
+	var err error
+	switch alg {
+	case jwa.RS256, jwa.RS384, jwa.RS512, jwa.PS256, jwa.PS384, jwa.PS512:
+		raw, err = rsa.GenerateKey(rand.Reader, 2048)
+	case jwa.ES256:
+		raw, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case jwa.ES384:
+		raw, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case jwa.ES512:
+		raw, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case jwa.EdDSA:
+		_, raw, err = ed25519.GenerateKey(rand.Reader)
+	case jwa.HS256:
+		raw, err = randomBytes(32)
+	case jwa.HS384:
+		raw, err = randomBytes(48)
+	case jwa.HS512:
+		raw, err = randomBytes(64)
+	default:
+		return nil, errors.Errorf(`unsupported algorithm %s`, alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := New(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create jwk.Key`)
+	}
+	if err := key.Set(AlgorithmKey, alg.String()); err != nil {
+		return nil, errors.Wrapf(err, `failed to set %q`, AlgorithmKey)
+	}
+	if err := key.Set(KeyUsageKey, ForSignature); err != nil {
+		return nil, errors.Wrapf(err, `failed to set %q`, KeyUsageKey)
+	}
+	if err := AssignKeyID(key); err != nil {
+		return nil, errors.Wrap(err, `failed to assign key ID`)
+	}
+	return key, nil
+}
+
+func randomBytes(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+		return nil, errors.Wrap(err, `failed to read random bytes`)
+	}
+	return buf, nil
+}
+
+// keyFileHeader is the protected header of encrypted key files. The
+// fields are sorted by name, as `jwe.Decrypt()` computes the authenticated
+// data from the re-encoded header rather than from the original one.
+type keyFileHeader struct {
+	Algorithm   string `json:"alg"`
+	ContentType string `json:"cty,omitempty"`
+	Encryption  string `json:"enc"`
+	Count       int    `json:"p2c"`
+	Salt        string `json:"p2s"`
+}
+
+// encryptKeyFile encrypts `plaintext` into a JWE in compact serialization
+func encryptKeyFile(plaintext, passphrase []byte) ([]byte, error) {
+	salt, err := randomBytes(keyFileSaltSize)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := randomBytes(16)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := randomBytes(12)
+	if err != nil {
+		return nil, err
+	}
+
+	hdr := keyFileHeader{
+		Algorithm:   jwa.PBES2_HS256_A128KW.String(),
+		Encryption:  jwa.A128GCM.String(),
+		ContentType: keyFileContentType,
+		Salt:        base64.EncodeToString(salt),
+		Count:       keyFilePBES2Count,
+	}
+	hdrbuf, err := json.Marshal(hdr)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal header`)
+	}
+	protected := base64.Encode(hdrbuf)
+
+	kek, err := aes.NewCipher(deriveKeyFileKey(jwa.PBES2_HS256_A128KW, passphrase, salt, keyFilePBES2Count))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create key encryption cipher`)
+	}
+	encryptedKey, err := keywrap.Wrap(kek, cek)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to wrap key`)
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create content cipher`)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create AES-GCM`)
+	}
+	sealed := aead.Seal(nil, iv, plaintext, protected)
+	ciphertext, tag := sealed[:len(sealed)-aead.Overhead()], sealed[len(sealed)-aead.Overhead():]
+
+	return bytes.Join([][]byte{
+		protected,
+		base64.Encode(encryptedKey),
+		base64.Encode(iv),
+		base64.Encode(ciphertext),
+		base64.Encode(tag),
+	}, []byte{'.'}), nil
+}
+
+// decryptKeyFile decrypts a JWE in compact serialization that was
+// encrypted using a passphrase
+func decryptKeyFile(buf, passphrase []byte) ([]byte, error) {
+	parts := bytes.Split(buf, []byte{'.'})
+	if len(parts) != 5 {
+		return nil, errors.New(`key file is not a JWE in compact serialization`)
+	}
+
+	decoded := make([][]byte, len(parts))
+	for i, part := range parts {
+		v, err := base64.Decode(part)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to decode key file`)
+		}
+		decoded[i] = v
+	}
+
+	var hdr keyFileHeader
+	if err := json.Unmarshal(decoded[0], &hdr); err != nil {
+		return nil, errors.Wrap(err, `failed to parse header`)
+	}
+
+	alg := jwa.KeyEncryptionAlgorithm(hdr.Algorithm)
+	switch alg {
+	case jwa.PBES2_HS256_A128KW, jwa.PBES2_HS384_A192KW, jwa.PBES2_HS512_A256KW:
+	default:
+		return nil, errors.Errorf(`unsupported key encryption algorithm %s`, hdr.Algorithm)
+	}
+	switch jwa.ContentEncryptionAlgorithm(hdr.Encryption) {
+	case jwa.A128GCM, jwa.A192GCM, jwa.A256GCM:
+	default:
+		return nil, errors.Errorf(`unsupported content encryption algorithm %s`, hdr.Encryption)
+	}
+	if hdr.Count <= 0 || hdr.Count > keyFileMaxPBES2Count {
+		return nil, errors.Errorf(`invalid iteration count %d`, hdr.Count)
+	}
+	salt, err := base64.DecodeString(hdr.Salt)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to decode salt`)
+	}
+
+	kek, err := aes.NewCipher(deriveKeyFileKey(alg, passphrase, salt, hdr.Count))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create key encryption cipher`)
+	}
+	cek, err := keywrap.Unwrap(kek, decoded[1])
+	if err != nil {
+		return nil, errors.New(`failed to unwrap key (wrong passphrase?)`)
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create content cipher`)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create AES-GCM`)
+	}
+	if len(decoded[2]) != aead.NonceSize() {
+		return nil, errors.New(`invalid initialization vector`)
+	}
+	plaintext, err := aead.Open(nil, decoded[2], append(decoded[3], decoded[4]...), parts[0])
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to decrypt key`)
+	}
+	return plaintext, nil
+}
+
+// deriveKeyFileKey derives the key encryption key as described in
+// RFC 7518 section 4.8.1.1
+func deriveKeyFileKey(alg jwa.KeyEncryptionAlgorithm, passphrase, salt []byte, count int) []byte {
+	var hashFunc func() hash.Hash
+	var keylen int
+	switch alg {
+	case jwa.PBES2_HS384_A192KW:
+		hashFunc = sha512.New384
+		keylen = 24
+	case jwa.PBES2_HS512_A256KW:
+		hashFunc = sha512.New
+		keylen = 32
+	default:
+		hashFunc = sha256.New
+		keylen = 16
+	}
+
+	fullsalt := append([]byte(alg), 0)
+	fullsalt = append(fullsalt, salt...)
+	return pbkdf2.Key(passphrase, fullsalt, count, keylen, hashFunc)
+}
@@ -0,0 +1,210 @@
+package jwk_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/internal/jwxtest"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwe"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadOrGenerate(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "jwx-keyfile")
+	if !assert.NoError(t, err, `ioutil.TempDir should succeed`) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("Generate and load", func(t *testing.T) {
+		algorithms := []jwa.SignatureAlgorithm{jwa.RS256, jwa.PS384, jwa.ES256, jwa.ES512, jwa.EdDSA, jwa.HS256}
+		for _, alg := range algorithms {
+			alg := alg
+			t.Run(alg.String(), func(t *testing.T) {
+				path := filepath.Join(dir, alg.String()+".json")
+				key, err := jwk.LoadOrGenerate(path, alg)
+				if !assert.NoError(t, err, `jwk.LoadOrGenerate should succeed`) {
+					return
+				}
+				if !assert.Equal(t, alg.String(), key.Algorithm(), `"alg" should be set`) {
+					return
+				}
+				if !assert.NotEmpty(t, key.KeyID(), `"kid" should be set`) {
+					return
+				}
+
+				if runtime.GOOS != "windows" {
+					fi, err := os.Stat(path)
+					if !assert.NoError(t, err, `os.Stat should succeed`) {
+						return
+					}
+					if !assert.Equal(t, os.FileMode(0600), fi.Mode().Perm(), `file should only be readable by the owner`) {
+						return
+					}
+				}
+
+				loaded, err := jwk.LoadOrGenerate(path, alg)
+				if !assert.NoError(t, err, `jwk.LoadOrGenerate should succeed`) {
+					return
+				}
+				if !assert.Equal(t, key.KeyID(), loaded.KeyID(), `the same key should be loaded`) {
+					return
+				}
+
+				signed, err := jws.Sign([]byte(`Lorem ipsum`), alg, loaded)
+				if !assert.NoError(t, err, `jws.Sign should succeed`) {
+					return
+				}
+				pubkey, err := jwk.PublicKeyOf(key)
+				if !assert.NoError(t, err, `jwk.PublicKeyOf should succeed`) {
+					return
+				}
+				if _, err := jws.Verify(signed, alg, pubkey); !assert.NoError(t, err, `jws.Verify should succeed`) {
+					return
+				}
+			})
+		}
+	})
+	t.Run("Passphrase", func(t *testing.T) {
+		path := filepath.Join(dir, "encrypted.jwe")
+		passphrase := []byte(`correct horse battery staple`)
+		key, err := jwk.LoadOrGenerate(path, jwa.ES256, jwk.WithPassphrase(passphrase))
+		if !assert.NoError(t, err, `jwk.LoadOrGenerate should succeed`) {
+			return
+		}
+
+		buf, err := ioutil.ReadFile(path)
+		if !assert.NoError(t, err, `ioutil.ReadFile should succeed`) {
+			return
+		}
+		// The file can be decrypted using the jwe package as well
+		decrypted, err := jwe.Decrypt(buf, jwa.PBES2_HS256_A128KW, passphrase)
+		if !assert.NoError(t, err, `jwe.Decrypt should succeed`) {
+			return
+		}
+		decryptedKey, err := jwk.ParseKey(decrypted)
+		if !assert.NoError(t, err, `jwk.ParseKey should succeed`) {
+			return
+		}
+		if !assert.Equal(t, key.KeyID(), decryptedKey.KeyID(), `decrypted key should match`) {
+			return
+		}
+
+		loaded, err := jwk.LoadOrGenerate(path, jwa.ES256, jwk.WithPassphrase(passphrase))
+		if !assert.NoError(t, err, `jwk.LoadOrGenerate should succeed`) {
+			return
+		}
+		if !assert.Equal(t, key.KeyID(), loaded.KeyID(), `the same key should be loaded`) {
+			return
+		}
+
+		if _, err := jwk.LoadOrGenerate(path, jwa.ES256, jwk.WithPassphrase([]byte(`wrong`))); !assert.Error(t, err, `jwk.LoadOrGenerate with wrong passphrase should fail`) {
+			return
+		}
+		if _, err := jwk.LoadOrGenerate(path, jwa.ES256); !assert.Error(t, err, `jwk.LoadOrGenerate without passphrase should fail`) {
+			return
+		}
+
+		plaintext := filepath.Join(dir, "plaintext.json")
+		if _, err := jwk.LoadOrGenerate(plaintext, jwa.ES256); !assert.NoError(t, err, `jwk.LoadOrGenerate should succeed`) {
+			return
+		}
+		if _, err := jwk.LoadOrGenerate(plaintext, jwa.ES256, jwk.WithPassphrase(passphrase)); !assert.Error(t, err, `jwk.LoadOrGenerate should fail for unencrypted keys when a passphrase is given`) {
+			return
+		}
+	})
+	t.Run("Concurrent creation", func(t *testing.T) {
+		path := filepath.Join(dir, "concurrent.json")
+
+		kids := make([]string, 8)
+		var wg sync.WaitGroup
+		for i := range kids {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				key, err := jwk.LoadOrGenerate(path, jwa.ES256)
+				if err == nil {
+					kids[i] = key.KeyID()
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		for _, kid := range kids {
+			if !assert.Equal(t, kids[0], kid, `all callers should use the same key`) {
+				return
+			}
+		}
+		if !assert.NotEmpty(t, kids[0], `key should be loaded`) {
+			return
+		}
+
+		matches, err := filepath.Glob(filepath.Join(dir, ".concurrent.json.tmp*"))
+		if !assert.NoError(t, err, `filepath.Glob should succeed`) {
+			return
+		}
+		if !assert.Empty(t, matches, `temporary files should be removed`) {
+			return
+		}
+	})
+	t.Run("Errors", func(t *testing.T) {
+		path := filepath.Join(dir, "mismatch.json")
+		if _, err := jwk.LoadOrGenerate(path, jwa.ES256); !assert.NoError(t, err, `jwk.LoadOrGenerate should succeed`) {
+			return
+		}
+		if _, err := jwk.LoadOrGenerate(path, jwa.RS256); !assert.Error(t, err, `jwk.LoadOrGenerate with another algorithm should fail`) {
+			return
+		}
+		if _, err := jwk.LoadOrGenerate(filepath.Join(dir, "none.json"), jwa.NoSignature); !assert.Error(t, err, `jwk.LoadOrGenerate with "none" should fail`) {
+			return
+		}
+
+		// Keys without "alg" are checked by their type
+		rsaPath := filepath.Join(dir, "noalg.json")
+		rsaKey, err := jwxtest.GenerateRsaJwk()
+		if !assert.NoError(t, err, `jwxtest.GenerateRsaJwk should succeed`) {
+			return
+		}
+		buf, err := json.Marshal(rsaKey)
+		if !assert.NoError(t, err, `json.Marshal should succeed`) {
+			return
+		}
+		if !assert.NoError(t, ioutil.WriteFile(rsaPath, buf, 0600), `ioutil.WriteFile should succeed`) {
+			return
+		}
+		if _, err := jwk.LoadOrGenerate(rsaPath, jwa.PS256); !assert.NoError(t, err, `jwk.LoadOrGenerate should succeed for a matching key type`) {
+			return
+		}
+		for _, alg := range []jwa.SignatureAlgorithm{jwa.ES256, jwa.EdDSA, jwa.HS256} {
+			if _, err := jwk.LoadOrGenerate(rsaPath, alg); !assert.Error(t, err, `jwk.LoadOrGenerate with %s should fail for an RSA key`, alg) {
+				return
+			}
+		}
+
+		pubPath := filepath.Join(dir, "public.json")
+		pubKey, err := jwk.PublicKeyOf(rsaKey)
+		if !assert.NoError(t, err, `jwk.PublicKeyOf should succeed`) {
+			return
+		}
+		buf, err = json.Marshal(pubKey)
+		if !assert.NoError(t, err, `json.Marshal should succeed`) {
+			return
+		}
+		if !assert.NoError(t, ioutil.WriteFile(pubPath, buf, 0600), `ioutil.WriteFile should succeed`) {
+			return
+		}
+		if _, err := jwk.LoadOrGenerate(pubPath, jwa.RS256); !assert.Error(t, err, `jwk.LoadOrGenerate should fail for a public key`) {
+			return
+		}
+	})
+}
@@ -79,6 +79,9 @@ func (k *okpPublicKey) Raw(v interface{}) error {
 		return errors.Wrap(err, `failed to build public key`)
 	}
 
+	if ok, err := assignECDHKey(v, pubk); ok {
+		return err
+	}
 	return blackmagic.AssignIfCompatible(v, pubk)
 }
 
@@ -113,6 +116,9 @@ func (k *okpPrivateKey) Raw(v interface{}) error {
 		return errors.Wrap(err, `failed to build public key`)
 	}
 
+	if ok, err := assignECDHKey(v, privk); ok {
+		return err
+	}
 	return blackmagic.AssignIfCompatible(v, privk)
 }
 
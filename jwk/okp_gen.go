@@ -25,6 +25,10 @@ const (
 	OKPXKey   = "x"
 )
 
+// OKPPrivateKey is the interface for keys of type jwa.OKP that hold
+// a raw Ed25519 or X25519 key. Keys returned by New() and the
+// parsing functions may be type-switched against this interface to
+// access the key type specific fields.
 type OKPPrivateKey interface {
 	Key
 	FromRaw(interface{}) error
@@ -49,6 +53,8 @@ type okpPrivateKey struct {
 	mu                     *sync.RWMutex
 }
 
+var _ OKPPrivateKey = &okpPrivateKey{}
+
 func NewOKPPrivateKey() OKPPrivateKey {
 	return newOKPPrivateKey()
 }
@@ -384,6 +390,24 @@ func (k *okpPrivateKey) Clone() (Key, error) {
 	return cloneKey(k)
 }
 
+func (k *okpPrivateKey) SetPrivateField(name string, value interface{}) error {
+	return setPrivateField(k, name, value)
+}
+
+func (k *okpPrivateKey) PrivateField(name string, dst interface{}) error {
+	return getPrivateField(k, name, dst)
+}
+
+func (k *okpPrivateKey) MarshalRedacted() ([]byte, error) {
+	return marshalRedacted(k)
+}
+
+// String returns the redacted JSON representation of the key.
+// See `MarshalRedacted()`
+func (k *okpPrivateKey) String() string {
+	return redactedString(k)
+}
+
 func (h *okpPrivateKey) UnmarshalJSON(buf []byte) error {
 	h.algorithm = nil
 	h.crv = nil
@@ -564,6 +588,10 @@ func (h *okpPrivateKey) AsMap(ctx context.Context) (map[string]interface{}, erro
 	return iter.AsMap(ctx, h)
 }
 
+// OKPPublicKey is the interface for keys of type jwa.OKP that hold
+// a raw Ed25519 or X25519 key. Keys returned by New() and the
+// parsing functions may be type-switched against this interface to
+// access the key type specific fields.
 type OKPPublicKey interface {
 	Key
 	FromRaw(interface{}) error
@@ -586,6 +614,8 @@ type okpPublicKey struct {
 	mu                     *sync.RWMutex
 }
 
+var _ OKPPublicKey = &okpPublicKey{}
+
 func NewOKPPublicKey() OKPPublicKey {
 	return newOKPPublicKey()
 }
@@ -901,6 +931,18 @@ func (k *okpPublicKey) Clone() (Key, error) {
 	return cloneKey(k)
 }
 
+func (k *okpPublicKey) SetPrivateField(name string, value interface{}) error {
+	return setPrivateField(k, name, value)
+}
+
+func (k *okpPublicKey) PrivateField(name string, dst interface{}) error {
+	return getPrivateField(k, name, dst)
+}
+
+func (k *okpPublicKey) MarshalRedacted() ([]byte, error) {
+	return marshalRedacted(k)
+}
+
 func (h *okpPublicKey) UnmarshalJSON(buf []byte) error {
 	h.algorithm = nil
 	h.crv = nil
@@ -16,6 +16,11 @@ type identRefreshInterval struct{}
 type identMinRefreshInterval struct{}
 type identFetchBackoff struct{}
 type identPEM struct{}
+type identLenientCertificateChain struct{}
+type identRequireKidThumbprint struct{}
+type identCompressedPoints struct{}
+type identPointValidation struct{}
+type identPassphrase struct{}
 
 // AutoRefreshOption is a type of Option that can be passed to the
 // AutoRefresh object.
@@ -58,6 +63,19 @@ type parseOption struct {
 func (*parseOption) parseOption()    {}
 func (*parseOption) readFileOption() {}
 
+// PersistOption is a type of Option that can be passed to
+// `jwk.LoadOrGenerate()`
+type PersistOption interface {
+	Option
+	persistOption()
+}
+
+type persistOption struct {
+	Option
+}
+
+func (*persistOption) persistOption() {}
+
 // WithHTTPClient allows users to specify the "net/http".Client object that
 // is used when fetching jwk.Set objects.
 func WithHTTPClient(cl HTTPClient) FetchOption {
@@ -120,3 +138,57 @@ func WithPEM(v bool) ParseOption {
 		option.New(identPEM{}, v),
 	}
 }
+
+// WithLenientCertificateChain specifies that `Parse()` and `ParseKey()`
+// should accept certificates in the "x5c" field that are encoded using
+// the base64url alphabet. RFC7517 requires the standard base64 alphabet,
+// but some issuers wrongly use base64url. The certificates are re-encoded
+// using the standard base64 alphabet when the key is serialized.
+func WithLenientCertificateChain(v bool) ParseOption {
+	return &parseOption{
+		option.New(identLenientCertificateChain{}, v),
+	}
+}
+
+// WithRequireKidThumbprint specifies that `Parse()` and `ParseKey()`
+// should reject keys whose "kid" field is not the base64url encoded
+// RFC7638 thumbprint of the key, computed using the given hash.
+// Keys without a "kid" field are rejected as well.
+//
+// Some ecosystems (e.g. DPoP, OpenID Federation) rely on this convention
+// to pin keys. See also `jwk.VerifyKidThumbprint()`
+func WithRequireKidThumbprint(h crypto.Hash) ParseOption {
+	return &parseOption{
+		option.New(identRequireKidThumbprint{}, h),
+	}
+}
+
+// WithCompressedPoints specifies that `Parse()` and `ParseKey()` should
+// accept "EC" keys whose "x" field holds a point in the compressed form
+// of SEC 1 (0x02 or 0x03, followed by the x coordinate), and that do not
+// have a "y" field, as emitted by some hardware. The point is decompressed
+// when the key is parsed, so the key is always serialized with both
+// coordinates, as required by RFC7518.
+func WithCompressedPoints(v bool) ParseOption {
+	return &parseOption{
+		option.New(identCompressedPoints{}, v),
+	}
+}
+
+// WithPointValidation specifies that `Parse()` and `ParseKey()` should
+// reject "EC" and "OKP" keys whose public point is not valid. See
+// `jwk.ValidatePoint()` for the checks that are performed.
+func WithPointValidation(v bool) ParseOption {
+	return &parseOption{
+		option.New(identPointValidation{}, v),
+	}
+}
+
+// WithPassphrase specifies that the key file used by `jwk.LoadOrGenerate()`
+// is encrypted using the given passphrase. Key files that are not
+// encrypted are rejected when this option is specified.
+func WithPassphrase(v []byte) PersistOption {
+	return &persistOption{
+		option.New(identPassphrase{}, v),
+	}
+}
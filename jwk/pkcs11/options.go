@@ -0,0 +1,20 @@
+package pkcs11
+
+import "github.com/lestrrat-go/option"
+
+type Option = option.Interface
+
+type identPIN struct{}
+type identPINFunc struct{}
+
+// WithPIN specifies the PIN used to log in to the token
+func WithPIN(pin string) Option {
+	return option.New(identPIN{}, pin)
+}
+
+// WithPINFunc specifies a function that is called to obtain the PIN
+// whenever the Provider needs to log in to the token, for example to
+// prompt the user. The PIN is not retained by the Provider.
+func WithPINFunc(f PINFunc) Option {
+	return option.New(identPINFunc{}, f)
+}
@@ -0,0 +1,255 @@
+// Package pkcs11 exposes keys held in PKCS#11 tokens, such as smartcards
+// and hardware security modules, as jwk.Keys and crypto.Signers that
+// can be used to sign JWS messages and JWTs directly.
+//
+// The package does not depend on a particular PKCS#11 binding. Instead,
+// the binding of your choice (e.g. github.com/miekg/pkcs11) is adapted
+// to the `pkcs11.Token` interface, which describes the handful of
+// operations that are required:
+//
+//     provider := pkcs11.New(token, pkcs11.WithPIN(pin))
+//     defer provider.Close()
+//
+//     signer, err := provider.Signer("my-signing-key")
+//     ...
+//     signed, err := jws.Sign(payload, jwa.ES256, signer)
+//
+// The private keys never leave the token: signing operations are
+// delegated to the token, and only the public keys are exported.
+package pkcs11
+
+import (
+	"crypto"
+	"encoding/hex"
+	"sync"
+
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+)
+
+// ErrLoginRequired should be returned by a Token when the operation
+// failed because the session is not (or no longer) logged in, for
+// example after the card was removed and re-inserted. The Provider
+// then logs in again, and retries the operation once.
+var ErrLoginRequired = errors.New(`pkcs11: login required`)
+
+// MechanismType identifies the signing mechanism, using the values
+// defined by the PKCS#11 specification
+type MechanismType uint
+
+const (
+	// CKM_RSA_PKCS signs the DER encoded DigestInfo of the payload
+	// using RSASSA-PKCS1-v1_5
+	CKM_RSA_PKCS MechanismType = 0x00000001 //nolint:golint,stylecheck
+	// CKM_RSA_PKCS_PSS signs the digest of the payload using RSASSA-PSS
+	CKM_RSA_PKCS_PSS MechanismType = 0x0000000d //nolint:golint,stylecheck
+	// CKM_ECDSA signs the digest of the payload using ECDSA. The
+	// signature is the concatenation of r and s
+	CKM_ECDSA MechanismType = 0x00001041 //nolint:golint,stylecheck
+	// CKM_EDDSA signs the payload using EdDSA
+	CKM_EDDSA MechanismType = 0x00001057 //nolint:golint,stylecheck
+)
+
+// Mechanism describes how the token should sign the data
+type Mechanism struct {
+	Type MechanismType
+	// Hash and SaltLength are only used by CKM_RSA_PKCS_PSS. The same
+	// hash function is used for MGF1.
+	Hash       crypto.Hash
+	SaltLength int
+}
+
+// Object describes a private key held by the token
+type Object struct {
+	// ID is the value of the CKA_ID attribute
+	ID []byte
+	// Label is the value of the CKA_LABEL attribute
+	Label string
+	// PublicKey is the corresponding public key, which must be one of
+	// *rsa.PublicKey, *ecdsa.PublicKey, or ed25519.PublicKey
+	PublicKey crypto.PublicKey
+}
+
+// Token is the interface to a PKCS#11 token, to be implemented by
+// an adapter for the PKCS#11 binding of your choice. The Provider
+// never calls the methods of a Token concurrently.
+type Token interface {
+	// Login logs the user in using the given PIN
+	Login(pin string) error
+	// Logout logs the user out
+	Logout() error
+	// Objects lists the private keys that are available for signing.
+	// It may be called before logging in, if the token allows it.
+	Objects() ([]Object, error)
+	// Sign signs data using the private key identified by id
+	Sign(id []byte, mechanism Mechanism, data []byte) ([]byte, error)
+}
+
+// PINFunc is called to obtain the PIN whenever the Provider needs to
+// log in to the token
+type PINFunc func() (string, error)
+
+// Provider manages the session to a Token, and exposes the keys it holds.
+// Use `pkcs11.New()` to create one.
+type Provider struct {
+	mu       sync.Mutex
+	token    Token
+	pin      PINFunc
+	loggedIn bool
+}
+
+// New creates a Provider for the given token. Use `pkcs11.WithPIN()` or
+// `pkcs11.WithPINFunc()` to specify the PIN. The Provider logs in to the
+// token the first time a signature is requested, and stays logged in
+// until `Close()` is called.
+func New(token Token, options ...Option) *Provider {
+	p := &Provider{
+		token: token,
+	}
+	for _, option := range options {
+		switch option.Ident() {
+		case identPIN{}:
+			pin := option.Value().(string)
+			p.pin = func() (string, error) { return pin, nil }
+		case identPINFunc{}:
+			p.pin = option.Value().(PINFunc)
+		}
+	}
+	return p
+}
+
+// Close logs out of the token, if logged in. The Provider may still be
+// used afterwards, in which case it logs in again.
+func (p *Provider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.loggedIn {
+		return nil
+	}
+	p.loggedIn = false
+	if err := p.token.Logout(); err != nil {
+		return errors.Wrap(err, `failed to log out`)
+	}
+	return nil
+}
+
+// login logs in to the token, if not already. Must be called with the
+// lock held
+func (p *Provider) login() error {
+	if p.loggedIn {
+		return nil
+	}
+	if p.pin == nil {
+		return errors.New(`pkcs11: no PIN specified (use pkcs11.WithPIN() or pkcs11.WithPINFunc())`)
+	}
+	pin, err := p.pin()
+	if err != nil {
+		return errors.Wrap(err, `failed to obtain PIN`)
+	}
+	if err := p.token.Login(pin); err != nil {
+		return errors.Wrap(err, `failed to log in`)
+	}
+	p.loggedIn = true
+	return nil
+}
+
+func (p *Provider) sign(id []byte, mechanism Mechanism, data []byte) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.login(); err != nil {
+		return nil, err
+	}
+
+	sig, err := p.token.Sign(id, mechanism, data)
+	if err == ErrLoginRequired {
+		p.loggedIn = false
+		if err := p.login(); err != nil {
+			return nil, err
+		}
+		sig, err = p.token.Sign(id, mechanism, data)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to sign using token`)
+	}
+	return sig, nil
+}
+
+func (p *Provider) objects() ([]Object, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	objects, err := p.token.Objects()
+	if err == ErrLoginRequired {
+		p.loggedIn = false
+		if err := p.login(); err != nil {
+			return nil, err
+		}
+		objects, err = p.token.Objects()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to list token objects`)
+	}
+	return objects, nil
+}
+
+// keyIDOf returns the key ID used for the object: its label, or the
+// hex encoded CKA_ID if it has no label
+func keyIDOf(obj Object) string {
+	if obj.Label != "" {
+		return obj.Label
+	}
+	return hex.EncodeToString(obj.ID)
+}
+
+// Keys returns the public keys of the signing keys held by the token.
+// The key ID of each key is the label of the object, or its hex encoded
+// CKA_ID if it has no label.
+func (p *Provider) Keys() (jwk.Set, error) {
+	objects, err := p.objects()
+	if err != nil {
+		return nil, err
+	}
+
+	set := jwk.NewSet()
+	for _, obj := range objects {
+		key, err := jwk.New(obj.PublicKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to create jwk.Key for object %q`, keyIDOf(obj))
+		}
+		if err := key.Set(jwk.KeyIDKey, keyIDOf(obj)); err != nil {
+			return nil, errors.Wrap(err, `failed to set key ID`)
+		}
+		if err := key.Set(jwk.KeyUsageKey, jwk.ForSignature); err != nil {
+			return nil, errors.Wrap(err, `failed to set key usage`)
+		}
+		set.Add(key)
+	}
+	return set, nil
+}
+
+// Signer returns the Signer for the key with the given key ID (see
+// `Keys()`)
+func (p *Provider) Signer(kid string) (*Signer, error) {
+	objects, err := p.objects()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, obj := range objects {
+		if keyIDOf(obj) != kid {
+			continue
+		}
+		if err := checkPublicKey(obj.PublicKey); err != nil {
+			return nil, errors.Wrapf(err, `invalid public key for object %q`, kid)
+		}
+		return &Signer{
+			provider: p,
+			id:       obj.ID,
+			kid:      kid,
+			pubkey:   obj.PublicKey,
+		}, nil
+	}
+	return nil, errors.Errorf(`pkcs11: key %q not found`, kid)
+}
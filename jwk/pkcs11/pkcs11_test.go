@@ -0,0 +1,349 @@
+package pkcs11_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jwk/pkcs11"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// softToken emulates a PKCS#11 token using software keys, performing
+// the same raw operations that a token would
+type softToken struct {
+	mu       sync.Mutex
+	pin      string
+	keys     map[string]crypto.Signer
+	labels   map[string]string
+	loggedIn bool
+	logins   int
+	expire   bool
+}
+
+func newSoftToken(pin string) *softToken {
+	return &softToken{
+		pin:    pin,
+		keys:   make(map[string]crypto.Signer),
+		labels: make(map[string]string),
+	}
+}
+
+func (t *softToken) add(id, label string, key crypto.Signer) {
+	t.keys[id] = key
+	t.labels[id] = label
+}
+
+func (t *softToken) Login(pin string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if pin != t.pin {
+		return errors.New(`CKR_PIN_INCORRECT`)
+	}
+	t.loggedIn = true
+	t.logins++
+	return nil
+}
+
+func (t *softToken) Logout() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loggedIn = false
+	return nil
+}
+
+func (t *softToken) Objects() ([]pkcs11.Object, error) {
+	var objects []pkcs11.Object
+	for id, key := range t.keys {
+		objects = append(objects, pkcs11.Object{
+			ID:        []byte(id),
+			Label:     t.labels[id],
+			PublicKey: key.Public(),
+		})
+	}
+	return objects, nil
+}
+
+func (t *softToken) Sign(id []byte, mechanism pkcs11.Mechanism, data []byte) ([]byte, error) {
+	t.mu.Lock()
+	if t.expire {
+		// emulate a card that was removed and re-inserted
+		t.expire = false
+		t.loggedIn = false
+	}
+	loggedIn := t.loggedIn
+	t.mu.Unlock()
+
+	if !loggedIn {
+		return nil, pkcs11.ErrLoginRequired
+	}
+
+	key, ok := t.keys[string(id)]
+	if !ok {
+		return nil, errors.New(`CKR_KEY_HANDLE_INVALID`)
+	}
+
+	switch mechanism.Type {
+	case pkcs11.CKM_RSA_PKCS:
+		// Unwrap the DigestInfo to use the software implementation
+		var info struct {
+			Algorithm struct {
+				OID    asn1.ObjectIdentifier
+				Params asn1.RawValue
+			}
+			Digest []byte
+		}
+		if _, err := asn1.Unmarshal(data, &info); err != nil {
+			return nil, err
+		}
+		hash := map[int]crypto.Hash{1: crypto.SHA256, 2: crypto.SHA384, 3: crypto.SHA512}[info.Algorithm.OID[len(info.Algorithm.OID)-1]]
+		return key.Sign(rand.Reader, info.Digest, hash)
+	case pkcs11.CKM_RSA_PKCS_PSS:
+		return key.Sign(rand.Reader, data, &rsa.PSSOptions{SaltLength: mechanism.SaltLength, Hash: mechanism.Hash})
+	case pkcs11.CKM_ECDSA:
+		priv := key.(*ecdsa.PrivateKey)
+		r, s, err := ecdsa.Sign(rand.Reader, priv, data)
+		if err != nil {
+			return nil, err
+		}
+		size := (priv.Curve.Params().BitSize + 7) / 8
+		sig := make([]byte, 2*size)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[size-len(rb):size], rb)
+		copy(sig[2*size-len(sb):], sb)
+		return sig, nil
+	case pkcs11.CKM_EDDSA:
+		return key.Sign(rand.Reader, data, crypto.Hash(0))
+	default:
+		return nil, errors.New(`CKR_MECHANISM_INVALID`)
+	}
+}
+
+func TestProvider(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.NoError(t, err, `rsa.GenerateKey should succeed`) {
+		return
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		return
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if !assert.NoError(t, err, `ed25519.GenerateKey should succeed`) {
+		return
+	}
+
+	newToken := func() *softToken {
+		token := newSoftToken(`1234`)
+		token.add("\x01", `rsa-key`, rsaKey)
+		token.add("\x02", `ec-key`, ecKey)
+		token.add("\x03", ``, edKey)
+		return token
+	}
+
+	t.Run("Keys", func(t *testing.T) {
+		t.Parallel()
+		provider := pkcs11.New(newToken(), pkcs11.WithPIN(`1234`))
+		set, err := provider.Keys()
+		if !assert.NoError(t, err, `provider.Keys should succeed`) {
+			return
+		}
+		if !assert.Equal(t, 3, set.Len(), `set should contain 3 keys`) {
+			return
+		}
+
+		for _, kid := range []string{`rsa-key`, `ec-key`, `03`} {
+			key, ok := set.LookupKeyID(kid)
+			if !assert.True(t, ok, `key %q should be found`, kid) {
+				return
+			}
+			if !assert.Equal(t, string(jwk.ForSignature), key.KeyUsage(), `"use" should be "sig"`) {
+				return
+			}
+			var raw interface{}
+			if !assert.NoError(t, key.Raw(&raw), `key.Raw should succeed`) {
+				return
+			}
+			if _, ok := raw.(crypto.Signer); !assert.False(t, ok, `key should be a public key`) {
+				return
+			}
+		}
+	})
+	t.Run("Sign", func(t *testing.T) {
+		t.Parallel()
+		testcases := []struct {
+			Kid       string
+			Algorithm jwa.SignatureAlgorithm
+			PublicKey interface{}
+		}{
+			{Kid: `rsa-key`, Algorithm: jwa.RS256, PublicKey: &rsaKey.PublicKey},
+			{Kid: `rsa-key`, Algorithm: jwa.RS512, PublicKey: &rsaKey.PublicKey},
+			{Kid: `rsa-key`, Algorithm: jwa.PS256, PublicKey: &rsaKey.PublicKey},
+			{Kid: `rsa-key`, Algorithm: jwa.PS384, PublicKey: &rsaKey.PublicKey},
+			{Kid: `ec-key`, Algorithm: jwa.ES384, PublicKey: &ecKey.PublicKey},
+			{Kid: `03`, Algorithm: jwa.EdDSA, PublicKey: edKey.Public()},
+		}
+
+		provider := pkcs11.New(newToken(), pkcs11.WithPIN(`1234`))
+		for _, tc := range testcases {
+			tc := tc
+			t.Run(tc.Algorithm.String(), func(t *testing.T) {
+				signer, err := provider.Signer(tc.Kid)
+				if !assert.NoError(t, err, `provider.Signer should succeed`) {
+					return
+				}
+
+				payload := []byte(`Lorem ipsum`)
+				signed, err := jws.Sign(payload, tc.Algorithm, signer)
+				if !assert.NoError(t, err, `jws.Sign should succeed`) {
+					return
+				}
+
+				verified, err := jws.Verify(signed, tc.Algorithm, tc.PublicKey)
+				if !assert.NoError(t, err, `jws.Verify should succeed`) {
+					return
+				}
+				if !assert.Equal(t, payload, verified, `payload should match`) {
+					return
+				}
+
+				msg, err := jws.Parse(signed)
+				if !assert.NoError(t, err, `jws.Parse should succeed`) {
+					return
+				}
+				if !assert.Equal(t, tc.Kid, msg.Signatures()[0].ProtectedHeaders().KeyID(), `"kid" should be set`) {
+					return
+				}
+			})
+		}
+	})
+	t.Run("JWT", func(t *testing.T) {
+		t.Parallel()
+		provider := pkcs11.New(newToken(), pkcs11.WithPIN(`1234`))
+		signer, err := provider.Signer(`ec-key`)
+		if !assert.NoError(t, err, `provider.Signer should succeed`) {
+			return
+		}
+
+		token := jwt.New()
+		_ = token.Set(jwt.IssuerKey, `github.com/lestrrat-go/jwx`)
+		_ = token.Set(jwt.IssuedAtKey, time.Now())
+
+		signed, err := jwt.Sign(token, jwa.ES384, signer)
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return
+		}
+
+		keys, err := provider.Keys()
+		if !assert.NoError(t, err, `provider.Keys should succeed`) {
+			return
+		}
+
+		parsed, err := jwt.Parse(signed, jwt.WithKeySet(keys), jwt.WithValidate(true))
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, token.Issuer(), parsed.Issuer(), `"iss" should match`) {
+			return
+		}
+	})
+	t.Run("Key provider for signing", func(t *testing.T) {
+		t.Parallel()
+		provider := pkcs11.New(newToken(), pkcs11.WithPIN(`1234`))
+		signer, err := provider.Signer(`rsa-key`)
+		if !assert.NoError(t, err, `provider.Signer should succeed`) {
+			return
+		}
+
+		signed, err := jws.Sign([]byte(`Lorem ipsum`), jwa.RS256, nil, jws.WithKeyProviderForSigning(signer))
+		if !assert.NoError(t, err, `jws.Sign should succeed`) {
+			return
+		}
+		if _, err := jws.Verify(signed, jwa.RS256, &rsaKey.PublicKey); !assert.NoError(t, err, `jws.Verify should succeed`) {
+			return
+		}
+	})
+	t.Run("PIN management", func(t *testing.T) {
+		t.Parallel()
+		token := newToken()
+
+		var calls int
+		provider := pkcs11.New(token, pkcs11.WithPINFunc(func() (string, error) {
+			calls++
+			return `1234`, nil
+		}))
+		signer, err := provider.Signer(`ec-key`)
+		if !assert.NoError(t, err, `provider.Signer should succeed`) {
+			return
+		}
+		if !assert.Equal(t, 0, calls, `PIN should not be requested before signing`) {
+			return
+		}
+
+		for i := 0; i < 3; i++ {
+			if _, err := jws.Sign([]byte(`Lorem ipsum`), jwa.ES384, signer); !assert.NoError(t, err, `jws.Sign should succeed`) {
+				return
+			}
+		}
+		if !assert.Equal(t, 1, calls, `PIN should be requested once`) {
+			return
+		}
+
+		// The session is lost: the provider should log in again
+		token.expire = true
+		if _, err := jws.Sign([]byte(`Lorem ipsum`), jwa.ES384, signer); !assert.NoError(t, err, `jws.Sign should succeed`) {
+			return
+		}
+		if !assert.Equal(t, 2, token.logins, `provider should log in again`) {
+			return
+		}
+
+		if !assert.NoError(t, provider.Close(), `provider.Close should succeed`) {
+			return
+		}
+		if !assert.False(t, token.loggedIn, `provider should log out`) {
+			return
+		}
+	})
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+		provider := pkcs11.New(newToken(), pkcs11.WithPIN(`0000`))
+		signer, err := provider.Signer(`rsa-key`)
+		if !assert.NoError(t, err, `provider.Signer should succeed`) {
+			return
+		}
+		if _, err := jws.Sign([]byte(`Lorem ipsum`), jwa.RS256, signer); !assert.Error(t, err, `jws.Sign with wrong PIN should fail`) {
+			return
+		}
+
+		provider = pkcs11.New(newToken())
+		signer, err = provider.Signer(`rsa-key`)
+		if !assert.NoError(t, err, `provider.Signer should succeed`) {
+			return
+		}
+		if _, err := jws.Sign([]byte(`Lorem ipsum`), jwa.RS256, signer); !assert.Error(t, err, `jws.Sign without PIN should fail`) {
+			return
+		}
+
+		if _, err := provider.Signer(`no-such-key`); !assert.Error(t, err, `provider.Signer should fail`) {
+			return
+		}
+		if _, err := jws.Sign([]byte(`Lorem ipsum`), jwa.ES256, signer); !assert.Error(t, err, `jws.Sign with mismatched algorithm should fail`) {
+			return
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package pkcs11
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/asn1"
+	"io"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// Signer is a crypto.Signer backed by a private key held in a token.
+// It can be passed to `jws.Sign()` and `jwt.Sign()` in place of a
+// private key, in which case its key ID is placed in the "kid" header.
+type Signer struct {
+	provider *Provider
+	id       []byte
+	kid      string
+	pubkey   crypto.PublicKey
+}
+
+// Public returns the public key corresponding to the private key
+func (s *Signer) Public() crypto.PublicKey {
+	return s.pubkey
+}
+
+// KeyID returns the key ID of the key
+func (s *Signer) KeyID() string {
+	return s.kid
+}
+
+// SigningKey implements `jws.SigningKeyProvider`, so that the Signer can
+// be passed to `jws.WithKeyProviderForSigning()`
+func (s *Signer) SigningKey() (interface{}, string, error) {
+	return s, s.kid, nil
+}
+
+// Sign signs digest using the token. As with other crypto.Signers, the
+// digest is the hash of the message for RSA and ECDSA keys, and the
+// message itself for Ed25519 keys. The random source is not used, as
+// the token provides its own.
+func (s *Signer) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	switch pubkey := s.pubkey.(type) {
+	case *rsa.PublicKey:
+		return s.signRSA(digest, opts)
+	case *ecdsa.PublicKey:
+		return s.signECDSA(pubkey, digest, opts)
+	case ed25519.PublicKey:
+		if opts.HashFunc() != crypto.Hash(0) {
+			return nil, errors.New(`pkcs11: ed25519 keys sign the message, not its digest`)
+		}
+		return s.provider.sign(s.id, Mechanism{Type: CKM_EDDSA}, digest)
+	default:
+		return nil, errors.Errorf(`pkcs11: unsupported public key type %T`, s.pubkey)
+	}
+}
+
+// digestInfoPrefixes are the DER encoded DigestInfo structures that
+// precede the digest for RSASSA-PKCS1-v1_5 (RFC 8017, section 9.2)
+var digestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+func (s *Signer) signRSA(digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	hash := opts.HashFunc()
+	if len(digest) != hash.Size() {
+		return nil, errors.Errorf(`pkcs11: invalid digest size %d`, len(digest))
+	}
+
+	if pssopts, ok := opts.(*rsa.PSSOptions); ok {
+		saltLength := pssopts.SaltLength
+		if saltLength == rsa.PSSSaltLengthEqualsHash || saltLength == rsa.PSSSaltLengthAuto {
+			saltLength = hash.Size()
+		}
+		return s.provider.sign(s.id, Mechanism{Type: CKM_RSA_PKCS_PSS, Hash: hash, SaltLength: saltLength}, digest)
+	}
+
+	prefix, ok := digestInfoPrefixes[hash]
+	if !ok {
+		return nil, errors.Errorf(`pkcs11: unsupported hash function %d`, hash)
+	}
+	data := make([]byte, 0, len(prefix)+len(digest))
+	data = append(append(data, prefix...), digest...)
+	return s.provider.sign(s.id, Mechanism{Type: CKM_RSA_PKCS}, data)
+}
+
+func (s *Signer) signECDSA(pubkey *ecdsa.PublicKey, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if len(digest) != opts.HashFunc().Size() {
+		return nil, errors.Errorf(`pkcs11: invalid digest size %d`, len(digest))
+	}
+
+	sig, err := s.provider.sign(s.id, Mechanism{Type: CKM_ECDSA}, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	// The token returns r || s, while crypto.Signer returns ASN.1 DER
+	size := (pubkey.Curve.Params().BitSize + 7) / 8
+	if len(sig) != 2*size {
+		return nil, errors.Errorf(`pkcs11: invalid ecdsa signature size %d`, len(sig))
+	}
+	return asn1.Marshal(struct {
+		R, S *big.Int
+	}{
+		R: new(big.Int).SetBytes(sig[:size]),
+		S: new(big.Int).SetBytes(sig[size:]),
+	})
+}
+
+func checkPublicKey(key crypto.PublicKey) error {
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return nil
+	default:
+		return errors.Errorf(`unsupported public key type %T`, key)
+	}
+}
@@ -0,0 +1,164 @@
+package jwk
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/internal/ecutil"
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/curve25519"
+)
+
+// The following errors are reported by `jwk.ValidatePoint()`, and by
+// `jwk.Parse()` and `jwk.ParseKey()` when the `jwk.WithPointValidation()`
+// or `jwk.WithCompressedPoints()` options are specified. Use `errors.Is()`
+// to test for them.
+var (
+	// ErrPointNotOnCurve is reported when the public point of an "EC"
+	// key is not on its curve
+	ErrPointNotOnCurve = errors.New(`point is not on the curve`)
+
+	// ErrSmallOrderPoint is reported when the public key of an "OKP" key
+	// is a point of small order, which would let an attacker confine the
+	// result of a key agreement or forge signatures
+	ErrSmallOrderPoint = errors.New(`point has small order`)
+
+	// ErrInvalidCompressedPoint is reported when a compressed "EC" point
+	// can not be decompressed
+	ErrInvalidCompressedPoint = errors.New(`invalid compressed point`)
+)
+
+// ed25519SmallOrder lists the encodings of the y coordinates of the
+// Ed25519 points of order 1, 2, 4 and 8, including the non-canonical
+// encodings of 0 and 1 (p and p+1). The sign bit is ignored
+var ed25519SmallOrder = [][]byte{
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	{0xec, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f},
+	{0xc7, 0x17, 0x6a, 0x70, 0x3d, 0x4d, 0xd8, 0x4f, 0xba, 0x3c, 0x0b, 0x76, 0x0d, 0x10, 0x67, 0x0f, 0x2a, 0x20, 0x53, 0xfa, 0x2c, 0x39, 0xcc, 0xc6, 0x4e, 0xc7, 0xfd, 0x77, 0x92, 0xac, 0x03, 0x7a},
+	{0x26, 0xe8, 0x95, 0x8f, 0xc2, 0xb2, 0x27, 0xb0, 0x45, 0xc3, 0xf4, 0x89, 0xf2, 0xef, 0x98, 0xf0, 0xd5, 0xdf, 0xac, 0x05, 0xd3, 0xc6, 0x33, 0x39, 0xb1, 0x38, 0x02, 0x88, 0x6d, 0x53, 0xfc, 0x05},
+	{0xed, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f},
+	{0xee, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f},
+}
+
+// ValidatePoint checks the public point of "EC" and "OKP" keys. Keys
+// of other types are not checked.
+//
+// For "EC" keys, the point must be on the curve. All of the supported
+// curves have a cofactor of 1, so this also guarantees that the point
+// is in the prime order subgroup. For "OKP" keys, the point must not be
+// of small order.
+func ValidatePoint(key Key) error {
+	switch key := key.(type) {
+	case ECDSAPublicKey:
+		return validateECPoint(key.Crv(), key.X(), key.Y())
+	case ECDSAPrivateKey:
+		return validateECPoint(key.Crv(), key.X(), key.Y())
+	case OKPPublicKey:
+		return validateOKPPoint(key.Crv(), key.X())
+	case OKPPrivateKey:
+		return validateOKPPoint(key.Crv(), key.X())
+	}
+	return nil
+}
+
+func validateECPoint(crv jwa.EllipticCurveAlgorithm, xbuf, ybuf []byte) error {
+	curve, ok := ecdsaCurves[crv]
+	if !ok {
+		return errors.Errorf(`invalid curve algorithm %s`, crv)
+	}
+
+	size := (curve.Params().BitSize + 7) / 8
+	if len(xbuf) > size || len(ybuf) > size {
+		return errors.Wrapf(ErrPointNotOnCurve, `coordinates are longer than %d bytes`, size)
+	}
+
+	x := new(big.Int).SetBytes(xbuf)
+	y := new(big.Int).SetBytes(ybuf)
+	p := curve.Params().P
+	if x.Cmp(p) >= 0 || y.Cmp(p) >= 0 || !curve.IsOnCurve(x, y) {
+		return errors.Wrapf(ErrPointNotOnCurve, `invalid point for curve %s`, crv)
+	}
+	return nil
+}
+
+func validateOKPPoint(crv jwa.EllipticCurveAlgorithm, xbuf []byte) error {
+	if len(xbuf) != 32 {
+		return errors.Errorf(`invalid public key length for %s: %d`, crv, len(xbuf))
+	}
+
+	switch crv {
+	case jwa.Ed25519:
+		y := make([]byte, len(xbuf))
+		copy(y, xbuf)
+		y[31] &= 0x7f
+		for _, v := range ed25519SmallOrder {
+			if bytes.Equal(y, v) {
+				return errors.Wrapf(ErrSmallOrderPoint, `invalid point for curve %s`, crv)
+			}
+		}
+	case jwa.X25519:
+		// Any scalar is a multiple of the cofactor once clamped, so the
+		// result is all zeros if and only if the point has small order
+		scalar := make([]byte, 32)
+		scalar[0] = 1
+		if _, err := curve25519.X25519(scalar, xbuf); err != nil {
+			return errors.Wrapf(ErrSmallOrderPoint, `invalid point for curve %s`, crv)
+		}
+	default:
+		return errors.Errorf(`invalid curve algorithm %s`, crv)
+	}
+	return nil
+}
+
+// decompressECPoint rewrites an "EC" key whose "x" member holds a point
+// in the compressed form of SEC 1, and that does not have a "y" member,
+// into a key with both coordinates. Other keys are returned as is.
+func decompressECPoint(data []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, errors.Wrap(err, `failed to unmarshal JSON`)
+	}
+
+	if _, ok := fields[ECDSAYKey]; ok {
+		return data, nil
+	}
+
+	var crv jwa.EllipticCurveAlgorithm
+	var xs string
+	if err := json.Unmarshal(fields[ECDSACrvKey], &crv); err != nil {
+		return nil, errors.Wrapf(err, `failed to unmarshal %s`, ECDSACrvKey)
+	}
+	if err := json.Unmarshal(fields[ECDSAXKey], &xs); err != nil {
+		return nil, errors.Wrapf(err, `failed to unmarshal %s`, ECDSAXKey)
+	}
+
+	curve, ok := ecdsaCurves[crv]
+	if !ok {
+		return nil, errors.Errorf(`invalid curve algorithm %s`, crv)
+	}
+
+	compressed, err := base64.DecodeString(xs)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to base64 decode %s`, ECDSAXKey)
+	}
+
+	x, y := ecutil.UnmarshalCompressed(curve, compressed)
+	if x == nil {
+		return nil, errors.Wrapf(ErrInvalidCompressedPoint, `failed to decompress point for curve %s`, crv)
+	}
+
+	for name, v := range map[string]*big.Int{ECDSAXKey: x, ECDSAYKey: y} {
+		buf := ecutil.AllocECPointBuffer(v, curve)
+		encoded, err := json.Marshal(base64.EncodeToString(buf))
+		ecutil.ReleaseECPointBuffer(buf)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to marshal %s`, name)
+		}
+		fields[name] = encoded
+	}
+	return json.Marshal(fields)
+}
@@ -0,0 +1,62 @@
+package jwk
+
+import (
+	"strings"
+
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/pkg/errors"
+)
+
+// PrivateFieldPrefix is the prefix of the names of application specific
+// fields set via `SetPrivateField()`
+const PrivateFieldPrefix = "x-"
+
+// ValidatePrivateFieldName checks that `name` is acceptable as the name of
+// an application specific field. The name must be in the form
+// "x-<namespace>-<field>" (e.g. "x-myco-rotated-at"), where the namespace
+// identifies the application or organization, so that the field does not
+// collide with parameters registered now or in the future, nor with
+// fields set by other applications.
+func ValidatePrivateFieldName(name string) error {
+	if !strings.HasPrefix(name, PrivateFieldPrefix) {
+		return errors.Errorf(`private field name %q must start with %q`, name, PrivateFieldPrefix)
+	}
+
+	rest := name[len(PrivateFieldPrefix):]
+	i := strings.IndexByte(rest, '-')
+	if i <= 0 || i == len(rest)-1 {
+		return errors.Errorf(`private field name %q must be in the form "%s<namespace>-<field>"`, name, PrivateFieldPrefix)
+	}
+	return nil
+}
+
+func setPrivateField(k Key, name string, value interface{}) error {
+	if err := ValidatePrivateFieldName(name); err != nil {
+		return err
+	}
+
+	if _, err := json.Marshal(value); err != nil {
+		return errors.Wrapf(err, `value for private field %q cannot be serialized`, name)
+	}
+	return k.Set(name, value)
+}
+
+func getPrivateField(k Key, name string, dst interface{}) error {
+	if err := ValidatePrivateFieldName(name); err != nil {
+		return err
+	}
+
+	v, ok := k.Get(name)
+	if !ok {
+		return errors.Errorf(`private field %q not found`, name)
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrapf(err, `failed to serialize private field %q`, name)
+	}
+	if err := json.Unmarshal(buf, dst); err != nil {
+		return errors.Wrapf(err, `failed to assign private field %q`, name)
+	}
+	return nil
+}
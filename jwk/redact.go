@@ -0,0 +1,54 @@
+package jwk
+
+import (
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/pkg/errors"
+)
+
+// RedactedValue is the placeholder used by `MarshalRedacted()` in place
+// of the values of private key members
+const RedactedValue = "***"
+
+// redactedMembers lists the members that contain secret material:
+// "d" for RSA, EC and OKP keys, the remaining RSA private key members,
+// and "k" for symmetric keys
+var redactedMembers = []string{
+	"d", "p", "q", "dp", "dq", "qi", "oth",
+	SymmetricOctetsKey,
+}
+
+func marshalRedacted(k Key) ([]byte, error) {
+	buf, err := json.Marshal(k)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal key`)
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &members); err != nil {
+		return nil, errors.Wrap(err, `failed to unmarshal key`)
+	}
+
+	placeholder, _ := json.Marshal(RedactedValue)
+	for _, name := range redactedMembers {
+		if _, ok := members[name]; ok {
+			members[name] = placeholder
+		}
+	}
+
+	buf, err = json.Marshal(members)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal redacted key`)
+	}
+	return buf, nil
+}
+
+// redactedString is used to implement String() for keys that contain
+// secret material, so that they can be passed to loggers and fmt
+// functions as is
+func redactedString(k Key) string {
+	buf, err := marshalRedacted(k)
+	if err != nil {
+		return `failed to marshal key: ` + err.Error()
+	}
+	return string(buf)
+}
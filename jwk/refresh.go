@@ -34,6 +34,7 @@ type AutoRefresh struct {
 	muRegistry   sync.RWMutex
 	registry     map[string]*target
 	resetTimerCh chan *resetTimerReq
+	subs         subscribers
 }
 
 type target struct {
@@ -449,8 +450,12 @@ func (af *AutoRefresh) doRefreshRequest(ctx context.Context, url string, enableB
 		if parseErr == nil {
 			// Got a new key set. replace the keyset in the target
 			af.muCache.Lock()
+			oldset := af.cache[url]
 			af.cache[url] = keyset
 			af.muCache.Unlock()
+
+			added, removed := diffSets(oldset, keyset)
+			af.subs.notify(SetChange{URL: url, Added: added, Removed: removed})
 			nextInterval := calculateRefreshDuration(res, t.refreshInterval, t.minRefreshInterval)
 			rtr := &resetTimerReq{
 				t: t,
@@ -527,6 +532,26 @@ func calculateRefreshDuration(res *http.Response, refreshInterval *time.Duration
 	return minRefreshInterval
 }
 
+// Subscribe registers a channel that receives a `jwk.SetChange` whenever
+// a refresh changes the keys of one of the registered URLs. This allows
+// consumers such as verifier caches to react to key rotations immediately.
+//
+// The first successful fetch of a URL reports all of its keys as added.
+// Keys are compared by their JSON representation, so a key whose
+// parameters (e.g. "kid") change is reported as removed and added.
+//
+// Notifications are sent without blocking, and are dropped if the
+// channel is not ready to receive them, so the channel should be buffered.
+func (af *AutoRefresh) Subscribe(ch chan<- SetChange) {
+	af.subs.subscribe(ch)
+}
+
+// Unsubscribe stops sending notifications to a channel that was
+// registered using `Subscribe()`
+func (af *AutoRefresh) Unsubscribe(ch chan<- SetChange) {
+	af.subs.unsubscribe(ch)
+}
+
 // TargetSnapshot is the structure returned by the Snapshot method.
 // It contains information about a url that has been configured
 // in AutoRefresh.
@@ -13,6 +13,7 @@ import (
 	"github.com/lestrrat-go/backoff/v2"
 	"github.com/lestrrat-go/iter/arrayiter"
 	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/internal/jwxtest"
 	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/stretchr/testify/assert"
 )
@@ -232,6 +233,111 @@ func TestAutoRefresh(t *testing.T) {
 	})
 }
 
+func TestAutoRefreshSubscribe(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var keys []jwk.Key
+	for i := 0; i < 3; i++ {
+		key, err := jwxtest.GenerateEcdsaJwk()
+		if !assert.NoError(t, err, `key generation should succeed`) {
+			return
+		}
+		pubkey, err := jwk.PublicKeyOf(key)
+		if !assert.NoError(t, err, `jwk.PublicKeyOf should succeed`) {
+			return
+		}
+		keys = append(keys, pubkey)
+	}
+
+	var mu sync.Mutex
+	served := keys[:2]
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		set := jwk.NewSet()
+		for _, key := range served {
+			set.Add(key)
+		}
+		w.Header().Set(`Content-Type`, `application/json`)
+		json.NewEncoder(w).Encode(set)
+	}))
+	defer srv.Close()
+
+	af := jwk.NewAutoRefresh(ctx)
+	af.Configure(srv.URL, jwk.WithRefreshInterval(time.Hour))
+
+	ch := make(chan jwk.SetChange, 10)
+	af.Subscribe(ch)
+
+	ids := func(keys []jwk.Key) []string {
+		var list []string
+		for _, key := range keys {
+			list = append(list, keyIdentity(t, key))
+		}
+		return list
+	}
+
+	if _, err := af.Fetch(ctx, srv.URL); !assert.NoError(t, err, `af.Fetch should succeed`) {
+		return
+	}
+	change := <-ch
+	if !assert.Equal(t, srv.URL, change.URL, `URL should match`) {
+		return
+	}
+	if !assert.Equal(t, ids(keys[:2]), ids(change.Added), `all keys should be added`) {
+		return
+	}
+	if !assert.Len(t, change.Removed, 0, `no keys should be removed`) {
+		return
+	}
+
+	// refreshing without changes should not produce a notification
+	if _, err := af.Refresh(ctx, srv.URL); !assert.NoError(t, err, `af.Refresh should succeed`) {
+		return
+	}
+	if !assert.Len(t, ch, 0, `there should be no changes`) {
+		return
+	}
+
+	// rotate keys[0] out, and keys[2] in
+	mu.Lock()
+	served = keys[1:]
+	mu.Unlock()
+	if _, err := af.Refresh(ctx, srv.URL); !assert.NoError(t, err, `af.Refresh should succeed`) {
+		return
+	}
+	change = <-ch
+	if !assert.Equal(t, ids(keys[2:]), ids(change.Added), `keys[2] should be added`) {
+		return
+	}
+	if !assert.Equal(t, ids(keys[:1]), ids(change.Removed), `keys[0] should be removed`) {
+		return
+	}
+
+	af.Unsubscribe(ch)
+	mu.Lock()
+	served = keys[:1]
+	mu.Unlock()
+	if _, err := af.Refresh(ctx, srv.URL); !assert.NoError(t, err, `af.Refresh should succeed`) {
+		return
+	}
+	if !assert.Len(t, ch, 0, `unsubscribed channel should not receive changes`) {
+		return
+	}
+}
+
+func keyIdentity(t *testing.T, key jwk.Key) string {
+	t.Helper()
+	buf, err := json.Marshal(key)
+	if !assert.NoError(t, err, `json.Marshal should succeed`) {
+		return ""
+	}
+	return string(buf)
+}
+
 func TestRefreshSnapshot(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 	defer cancel()
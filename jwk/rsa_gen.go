@@ -31,6 +31,10 @@ const (
 	RSAQIKey = "qi"
 )
 
+// RSAPrivateKey is the interface for keys of type jwa.RSA that hold
+// a raw key of type *rsa.PrivateKey. Keys returned by New() and the
+// parsing functions may be type-switched against this interface to
+// access the key type specific fields.
 type RSAPrivateKey interface {
 	Key
 	FromRaw(*rsa.PrivateKey) error
@@ -65,6 +69,8 @@ type rsaPrivateKey struct {
 	mu                     *sync.RWMutex
 }
 
+var _ RSAPrivateKey = &rsaPrivateKey{}
+
 func NewRSAPrivateKey() RSAPrivateKey {
 	return newRSAPrivateKey()
 }
@@ -497,6 +503,24 @@ func (k *rsaPrivateKey) Clone() (Key, error) {
 	return cloneKey(k)
 }
 
+func (k *rsaPrivateKey) SetPrivateField(name string, value interface{}) error {
+	return setPrivateField(k, name, value)
+}
+
+func (k *rsaPrivateKey) PrivateField(name string, dst interface{}) error {
+	return getPrivateField(k, name, dst)
+}
+
+func (k *rsaPrivateKey) MarshalRedacted() ([]byte, error) {
+	return marshalRedacted(k)
+}
+
+// String returns the redacted JSON representation of the key.
+// See `MarshalRedacted()`
+func (k *rsaPrivateKey) String() string {
+	return redactedString(k)
+}
+
 func (h *rsaPrivateKey) UnmarshalJSON(buf []byte) error {
 	h.algorithm = nil
 	h.d = nil
@@ -706,6 +730,10 @@ func (h *rsaPrivateKey) AsMap(ctx context.Context) (map[string]interface{}, erro
 	return iter.AsMap(ctx, h)
 }
 
+// RSAPublicKey is the interface for keys of type jwa.RSA that hold
+// a raw key of type *rsa.PublicKey. Keys returned by New() and the
+// parsing functions may be type-switched against this interface to
+// access the key type specific fields.
 type RSAPublicKey interface {
 	Key
 	FromRaw(*rsa.PublicKey) error
@@ -728,6 +756,8 @@ type rsaPublicKey struct {
 	mu                     *sync.RWMutex
 }
 
+var _ RSAPublicKey = &rsaPublicKey{}
+
 func NewRSAPublicKey() RSAPublicKey {
 	return newRSAPublicKey()
 }
@@ -1040,6 +1070,18 @@ func (k *rsaPublicKey) Clone() (Key, error) {
 	return cloneKey(k)
 }
 
+func (k *rsaPublicKey) SetPrivateField(name string, value interface{}) error {
+	return setPrivateField(k, name, value)
+}
+
+func (k *rsaPublicKey) PrivateField(name string, dst interface{}) error {
+	return getPrivateField(k, name, dst)
+}
+
+func (k *rsaPublicKey) MarshalRedacted() ([]byte, error) {
+	return marshalRedacted(k)
+}
+
 func (h *rsaPublicKey) UnmarshalJSON(buf []byte) error {
 	h.algorithm = nil
 	h.e = nil
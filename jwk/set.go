@@ -50,29 +50,32 @@ func (s *set) Index(key Key) int {
 
 func (s *set) Add(key Key) bool {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	if i := s.indexNL(key); i > -1 {
+		s.mu.Unlock()
 		return false
 	}
 	s.keys = append(s.keys, key)
+	s.mu.Unlock()
+
+	s.subs.notify(SetChange{Added: []Key{key}})
 	return true
 }
 
 func (s *set) Remove(key Key) bool {
+	if !s.remove(key) {
+		return false
+	}
+	s.subs.notify(SetChange{Removed: []Key{key}})
+	return true
+}
+
+func (s *set) remove(key Key) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	for i, k := range s.keys {
 		if k == key {
-			switch i {
-			case 0:
-				s.keys = s.keys[1:]
-			case len(s.keys) - 1:
-				s.keys = s.keys[:i-1]
-			default:
-				s.keys = append(s.keys[:i-1], s.keys[i+1:]...)
-			}
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
 			return true
 		}
 	}
@@ -81,9 +84,19 @@ func (s *set) Remove(key Key) bool {
 
 func (s *set) Clear() {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
+	removed := s.keys
 	s.keys = nil
+	s.mu.Unlock()
+
+	s.subs.notify(SetChange{Removed: removed})
+}
+
+func (s *set) Subscribe(ch chan<- SetChange) {
+	s.subs.subscribe(ch)
+}
+
+func (s *set) Unsubscribe(ch chan<- SetChange) {
+	s.subs.unsubscribe(ch)
 }
 
 func (s *set) Iterate(ctx context.Context) KeyIterator {
@@ -134,6 +147,10 @@ func (s *set) MarshalJSON() ([]byte, error) {
 }
 
 func (s *set) UnmarshalJSON(data []byte) error {
+	return s.unmarshalJSON(data)
+}
+
+func (s *set) unmarshalJSON(data []byte, options ...ParseOption) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -143,14 +160,14 @@ func (s *set) UnmarshalJSON(data []byte) error {
 	}
 
 	if len(proxy.Keys) == 0 {
-		k, err := ParseKey(data)
+		k, err := ParseKey(data, options...)
 		if err != nil {
 			return errors.Wrap(err, `failed to unmarshal key from JSON headers`)
 		}
 		s.keys = append(s.keys, k)
 	} else {
 		for i, buf := range proxy.Keys {
-			k, err := ParseKey([]byte(buf))
+			k, err := ParseKey([]byte(buf), options...)
 			if err != nil {
 				return errors.Wrapf(err, `failed to unmarshal key #%d (total %d) from multi-key JWK set`, i+1, len(proxy.Keys))
 			}
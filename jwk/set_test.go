@@ -70,3 +70,99 @@ func TestSet(t *testing.T) {
 		return
 	}
 }
+
+func TestSetSubscribe(t *testing.T) {
+	t.Parallel()
+
+	set := jwk.NewSet()
+	ch := make(chan jwk.SetChange, 10)
+	set.Subscribe(ch)
+
+	k1, err := jwxtest.GenerateSymmetricJwk()
+	if !assert.NoError(t, err, `key generation should succeed`) {
+		return
+	}
+	k2, err := jwxtest.GenerateEcdsaJwk()
+	if !assert.NoError(t, err, `key generation should succeed`) {
+		return
+	}
+
+	set.Add(k1)
+	set.Add(k1) // already exists, no notification
+	set.Add(k2)
+	set.Remove(k1)
+	set.Clear()
+
+	expected := []jwk.SetChange{
+		{Added: []jwk.Key{k1}},
+		{Added: []jwk.Key{k2}},
+		{Removed: []jwk.Key{k1}},
+		{Removed: []jwk.Key{k2}},
+	}
+	for i, e := range expected {
+		select {
+		case change := <-ch:
+			if !assert.Equal(t, e, change, `change #%d should match`, i) {
+				return
+			}
+		default:
+			assert.Fail(t, `expected change #%d`, i)
+			return
+		}
+	}
+	if !assert.Len(t, ch, 0, `there should be no more changes`) {
+		return
+	}
+
+	set.Unsubscribe(ch)
+	set.Add(k1)
+	if !assert.Len(t, ch, 0, `unsubscribed channel should not receive changes`) {
+		return
+	}
+}
+
+func TestSetRemove(t *testing.T) {
+	t.Parallel()
+
+	set := jwk.NewSet()
+	keys := make([]jwk.Key, 4)
+	for i := range keys {
+		k, err := jwxtest.GenerateSymmetricJwk()
+		if !assert.NoError(t, err, `key generation should succeed`) {
+			return
+		}
+		set.Add(k)
+		keys[i] = k
+	}
+
+	ch := make(chan jwk.SetChange, 10)
+	set.Subscribe(ch)
+
+	contents := func() []jwk.Key {
+		var list []jwk.Key
+		for i := 0; i < set.Len(); i++ {
+			k, _ := set.Get(i)
+			list = append(list, k)
+		}
+		return list
+	}
+
+	for _, tc := range []struct {
+		remove   jwk.Key
+		expected []jwk.Key
+	}{
+		{remove: keys[2], expected: []jwk.Key{keys[0], keys[1], keys[3]}},
+		{remove: keys[3], expected: []jwk.Key{keys[0], keys[1]}},
+		{remove: keys[0], expected: []jwk.Key{keys[1]}},
+	} {
+		if !assert.True(t, set.Remove(tc.remove), `set.Remove should succeed`) {
+			return
+		}
+		if !assert.Equal(t, tc.expected, contents(), `only the removed key should be removed`) {
+			return
+		}
+		if !assert.Equal(t, jwk.SetChange{Removed: []jwk.Key{tc.remove}}, <-ch, `change should match`) {
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package jwk
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/lestrrat-go/jwx/internal/json"
+)
+
+// SetChange describes a change in the keys of a `jwk.Set`. It is delivered
+// to the channels registered using `Subscribe()` on either a `jwk.Set`
+// or a `jwk.AutoRefresh` object.
+type SetChange struct {
+	// URL is the URL of the key set that changed. It is only populated
+	// for changes delivered by `jwk.AutoRefresh`
+	URL string
+
+	// Added contains the keys that were added to the set
+	Added []Key
+
+	// Removed contains the keys that were removed from the set
+	Removed []Key
+}
+
+// subscribers keeps track of the channels that SetChange notifications
+// are delivered to. The zero value is ready to use.
+type subscribers struct {
+	mu  sync.RWMutex
+	chs map[chan<- SetChange]struct{}
+}
+
+func (s *subscribers) subscribe(ch chan<- SetChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.chs == nil {
+		s.chs = make(map[chan<- SetChange]struct{})
+	}
+	s.chs[ch] = struct{}{}
+}
+
+func (s *subscribers) unsubscribe(ch chan<- SetChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.chs, ch)
+}
+
+// notify delivers the change to all subscribers. Delivery never blocks:
+// if a channel is not ready to receive, the change is dropped for
+// that channel.
+func (s *subscribers) notify(change SetChange) {
+	if len(change.Added) == 0 && len(change.Removed) == 0 {
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for ch := range s.chs {
+		select {
+		case ch <- change:
+		default:
+		}
+	}
+}
+
+// diffSets computes the keys that were added to and removed from `oldset`
+// to create `newset`. As key sets that are fetched anew contain new
+// `jwk.Key` objects, keys are compared by their JSON representation.
+// `oldset` may be nil.
+func diffSets(oldset, newset Set) (added, removed []Key) {
+	oldkeys := make(map[string]Key)
+	if oldset != nil {
+		for i := 0; i < oldset.Len(); i++ {
+			key, _ := oldset.Get(i)
+			oldkeys[keyIdentity(key)] = key
+		}
+	}
+
+	newkeys := make(map[string]struct{})
+	for i := 0; i < newset.Len(); i++ {
+		key, _ := newset.Get(i)
+		id := keyIdentity(key)
+		newkeys[id] = struct{}{}
+		if _, ok := oldkeys[id]; !ok {
+			added = append(added, key)
+		}
+	}
+
+	if oldset != nil {
+		for i := 0; i < oldset.Len(); i++ {
+			key, _ := oldset.Get(i)
+			if _, ok := newkeys[keyIdentity(key)]; !ok {
+				removed = append(removed, key)
+			}
+		}
+	}
+	return added, removed
+}
+
+func keyIdentity(key Key) string {
+	buf, err := json.Marshal(key)
+	if err != nil {
+		// should not happen for keys that were successfully parsed.
+		// fall back to the identity of the object
+		return fmt.Sprintf("%p", key)
+	}
+	return string(buf)
+}
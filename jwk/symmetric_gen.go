@@ -23,6 +23,10 @@ const (
 	SymmetricOctetsKey = "k"
 )
 
+// SymmetricKey is the interface for keys of type jwa.OctetSeq that hold
+// a raw key of type []byte. Keys returned by New() and the
+// parsing functions may be type-switched against this interface to
+// access the key type specific fields.
 type SymmetricKey interface {
 	Key
 	FromRaw([]byte) error
@@ -43,6 +47,8 @@ type symmetricKey struct {
 	mu                     *sync.RWMutex
 }
 
+var _ SymmetricKey = &symmetricKey{}
+
 func NewSymmetricKey() SymmetricKey {
 	return newSymmetricKey()
 }
@@ -335,6 +341,24 @@ func (k *symmetricKey) Clone() (Key, error) {
 	return cloneKey(k)
 }
 
+func (k *symmetricKey) SetPrivateField(name string, value interface{}) error {
+	return setPrivateField(k, name, value)
+}
+
+func (k *symmetricKey) PrivateField(name string, dst interface{}) error {
+	return getPrivateField(k, name, dst)
+}
+
+func (k *symmetricKey) MarshalRedacted() ([]byte, error) {
+	return marshalRedacted(k)
+}
+
+// String returns the redacted JSON representation of the key.
+// See `MarshalRedacted()`
+func (k *symmetricKey) String() string {
+	return redactedString(k)
+}
+
 func (h *symmetricKey) UnmarshalJSON(buf []byte) error {
 	h.algorithm = nil
 	h.keyID = nil
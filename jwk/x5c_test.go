@@ -1,6 +1,8 @@
 package jwk_test
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/lestrrat-go/jwx/internal/json"
@@ -125,3 +127,51 @@ func Test_X5CHeader(t *testing.T) {
 		})
 	}
 }
+
+func TestX5CBase64URL(t *testing.T) {
+	t.Parallel()
+
+	// certChainSrc[2] contains both '+' and '/', so its base64url
+	// representation is different from the standard one
+	urlEncoded := strings.NewReplacer("+", "-", "/", "_").Replace(strings.TrimRight(certChainSrc[2], "="))
+	src := fmt.Sprintf(`{"kty":"oct","k":"c2VjcmV0","x5c":[%q]}`, urlEncoded)
+
+	t.Run("Strict", func(t *testing.T) {
+		t.Parallel()
+		_, err := jwk.ParseKey([]byte(src))
+		if !assert.Error(t, err, `jwk.ParseKey should fail`) {
+			return
+		}
+
+		_, err = jwk.Parse([]byte(src))
+		if !assert.Error(t, err, `jwk.Parse should fail`) {
+			return
+		}
+	})
+	t.Run("Lenient", func(t *testing.T) {
+		t.Parallel()
+		key, err := jwk.ParseKey([]byte(src), jwk.WithLenientCertificateChain(true))
+		if !assert.NoError(t, err, `jwk.ParseKey should succeed`) {
+			return
+		}
+		if !assert.Len(t, key.X509CertChain(), 1, `key should contain 1 certificate`) {
+			return
+		}
+
+		set, err := jwk.Parse([]byte(`{"keys":[`+src+`]}`), jwk.WithLenientCertificateChain(true))
+		if !assert.NoError(t, err, `jwk.Parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, 1, set.Len(), `set should contain 1 key`) {
+			return
+		}
+
+		buf, err := json.Marshal(key)
+		if !assert.NoError(t, err, `json.Marshal should succeed`) {
+			return
+		}
+		if !assert.Contains(t, string(buf), certChainSrc[2], `x5c should be re-encoded using standard base64`) {
+			return
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package jws
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+)
+
+// minimum key sizes accepted by `jws.Attest()` and `jws.Check()`
+const (
+	minAttestRSAKeySize  = 2048 // bits
+	minAttestHMACKeySize = 32   // bytes
+)
+
+// Attest signs the payload using `key`, choosing the algorithm
+// automatically based on the type of the key:
+//
+//   - RSA keys (at least 2048 bits) use PS256
+//   - EC keys use ES256, ES384, or ES512, depending on the curve
+//   - Ed25519 keys use EdDSA
+//   - symmetric keys ([]byte, at least 32 bytes) use HS256
+//
+// The result is a JWS message in compact format, which should be
+// verified using `jws.Check()`.
+//
+// `key` must be a private key (or a symmetric key), either raw
+// (e.g. *rsa.PrivateKey) or a jwk.Key. Use `jws.Sign()` if you need to
+// choose the algorithm yourself.
+func Attest(payload []byte, key interface{}) ([]byte, error) {
+	raw, err := rawAttestKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var alg jwa.SignatureAlgorithm
+	switch raw := raw.(type) {
+	case *rsa.PrivateKey:
+		alg, err = attestAlgorithm(&raw.PublicKey)
+	case *ecdsa.PrivateKey:
+		alg, err = attestAlgorithm(&raw.PublicKey)
+	case ed25519.PrivateKey:
+		alg, err = attestAlgorithm(raw.Public())
+	case []byte:
+		alg, err = attestAlgorithm(raw)
+	default:
+		return nil, errors.Errorf(`jws.Attest requires an RSA, EC, Ed25519 private key or a symmetric key, got %T`, raw)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return Sign(payload, alg, raw)
+}
+
+// Check verifies a message created by `jws.Attest()`, and returns the payload.
+//
+// `key` must be the public key corresponding to the private key that was
+// passed to `jws.Attest()` (or the same symmetric key), either raw
+// (e.g. *rsa.PublicKey) or a jwk.Key.
+//
+// The message must be in compact format, and its "alg" header must be
+// exactly the algorithm that `jws.Attest()` would have chosen for the key.
+// Messages with critical ("crit") headers or detached payloads are rejected.
+func Check(buf []byte, key interface{}) ([]byte, error) {
+	raw, err := rawAttestKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var alg jwa.SignatureAlgorithm
+	switch raw.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey, []byte:
+		alg, err = attestAlgorithm(raw)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf(`jws.Check requires an RSA, EC, Ed25519 public key or a symmetric key, got %T`, raw)
+	}
+
+	buf = bytes.TrimSpace(buf)
+	if len(buf) == 0 || buf[0] == '{' {
+		return nil, errors.New(`message must be in compact serialization format`)
+	}
+
+	_, payload, _, err := SplitCompact(buf)
+	if err != nil {
+		return nil, errors.Wrap(err, `invalid compact serialization format`)
+	}
+	if len(payload) == 0 {
+		return nil, errors.New(`detached payloads are not allowed`)
+	}
+
+	msg, err := Parse(buf)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to parse message`)
+	}
+	sigs := msg.Signatures()
+	if len(sigs) != 1 {
+		return nil, errors.Errorf(`message must have exactly one signature (got %d)`, len(sigs))
+	}
+	hdr := sigs[0].ProtectedHeaders()
+	if hdr == nil {
+		return nil, errors.New(`message signature does not have protected headers`)
+	}
+	if v := hdr.Algorithm(); v != alg {
+		return nil, errors.Errorf(`unexpected signature algorithm %q (expected %q)`, v, alg)
+	}
+	if len(hdr.Critical()) > 0 {
+		return nil, errors.New(`critical headers are not allowed`)
+	}
+
+	return Verify(buf, alg, raw)
+}
+
+// attestAlgorithm returns the algorithm for the given public (or
+// symmetric) key, after checking that the key is strong enough
+func attestAlgorithm(key interface{}) (jwa.SignatureAlgorithm, error) {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if size := key.N.BitLen(); size < minAttestRSAKeySize {
+			return "", errors.Errorf(`RSA key must be at least %d bits, got %d`, minAttestRSAKeySize, size)
+		}
+		return jwa.PS256, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return jwa.ES256, nil
+		case elliptic.P384():
+			return jwa.ES384, nil
+		case elliptic.P521():
+			return jwa.ES512, nil
+		default:
+			return "", errors.Errorf(`unsupported curve %s`, key.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		return jwa.EdDSA, nil
+	case []byte:
+		if size := len(key); size < minAttestHMACKeySize {
+			return "", errors.Errorf(`symmetric key must be at least %d bytes, got %d`, minAttestHMACKeySize, size)
+		}
+		return jwa.HS256, nil
+	default:
+		return "", errors.Errorf(`unsupported key type %T`, key)
+	}
+}
+
+// rawAttestKey converts the key into the pointer form of the raw key
+func rawAttestKey(key interface{}) (interface{}, error) {
+	if jwkKey, ok := key.(jwk.Key); ok {
+		var raw interface{}
+		if err := jwkKey.Raw(&raw); err != nil {
+			return nil, errors.Wrapf(err, `failed to retrieve raw key from %T`, key)
+		}
+		key = raw
+	}
+
+	switch v := key.(type) {
+	case rsa.PublicKey:
+		return &v, nil
+	case rsa.PrivateKey:
+		return &v, nil
+	case ecdsa.PublicKey:
+		return &v, nil
+	case ecdsa.PrivateKey:
+		return &v, nil
+	}
+	return key, nil
+}
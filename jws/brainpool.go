@@ -0,0 +1,51 @@
+//go:build jwx_brainpool
+// +build jwx_brainpool
+
+package jws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+
+	"github.com/lestrrat-go/jwx/internal/ecutil"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/pkg/errors"
+)
+
+// Unlike ES256/ES384/ES512, the Brainpool algorithms are bound to a
+// specific curve, so keys on other curves are rejected.
+//
+// Only verification is supported: the arithmetic for the Brainpool
+// curves is not constant time, so signing would leak the private key
+// through timing side channels. `jws.NewSigner()` and `jws.Sign()`
+// return an error for these algorithms.
+func init() {
+	algs := map[jwa.SignatureAlgorithm]struct {
+		hash  crypto.Hash
+		curve elliptic.Curve
+	}{
+		jwa.BP256R1: {hash: crypto.SHA256, curve: ecutil.BrainpoolP256r1()},
+		jwa.BP384R1: {hash: crypto.SHA384, curve: ecutil.BrainpoolP384r1()},
+		jwa.BP512R1: {hash: crypto.SHA512, curve: ecutil.BrainpoolP512r1()},
+	}
+
+	for alg, def := range algs {
+		ecdsaVerifyFuncs[alg] = makeBrainpoolVerifyFunc(def.curve, makeECDSAVerifyFunc(def.hash))
+
+		RegisterVerifier(alg, func(alg jwa.SignatureAlgorithm) VerifierFactory {
+			return VerifierFactoryFn(func() (Verifier, error) {
+				return newECDSAVerifier(alg), nil
+			})
+		}(alg))
+	}
+}
+
+func makeBrainpoolVerifyFunc(curve elliptic.Curve, verify ecdsaVerifyFunc) ecdsaVerifyFunc {
+	return func(payload []byte, signature []byte, key *ecdsa.PublicKey, enc ECDSASignatureEncoding) error {
+		if key.Curve != curve {
+			return errors.Errorf(`expected key on %s, got %s`, curve.Params().Name, key.Curve.Params().Name)
+		}
+		return verify(payload, signature, key, enc)
+	}
+}
@@ -0,0 +1,103 @@
+//go:build jwx_brainpool
+// +build jwx_brainpool
+
+package jws_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrainpool(t *testing.T) {
+	t.Parallel()
+
+	// The keys and signatures were produced by OpenSSL 3.0.17, and not by
+	// this package (which can not sign using these curves): the keys were
+	// generated using `openssl ecparam -name brainpoolP256r1 -genkey`, and
+	// the signing input (`{"alg":"BP256R1"}` and "Lorem ipsum") was signed
+	// using `openssl dgst -sha256 -sign`, after which the DER encoded
+	// signature was converted to the fixed length R || S form of RFC 7518
+	// section 3.4. The same was done for brainpoolP384r1 (SHA-384) and
+	// brainpoolP512r1 (SHA-512).
+	testcases := []struct {
+		Algorithm jwa.SignatureAlgorithm
+		Key       string
+		Signed    string
+	}{
+		{
+			Algorithm: jwa.BP256R1,
+			Key:       `{"crv":"BP-256","kty":"EC","x":"irWRaB9x6YhaKe2pvpjCubtFQsiXBcvdiLpVJmk6Hr8","y":"MPx4nriwB_j5l_UbN5vSuWwuF-_fA5Bhj75TwevdSP4"}`,
+			Signed:    `eyJhbGciOiJCUDI1NlIxIn0.TG9yZW0gaXBzdW0.CLv2_RmCu0-lIsey6yin0GRQIgORGOS5oNt_ypkVhhKEr2gp2XRCHeyoEwLZTFkK7UFl_HW0uMq4Kczi1aabpg`,
+		},
+		{
+			Algorithm: jwa.BP384R1,
+			Key:       `{"crv":"BP-384","kty":"EC","x":"BPRTM3bLJihc4ltCREH1u7Nw_1qqB4yK4oA0HIvu-mXAFCP9fKeBVR7tAAJMNfXu","y":"DBx1XyfRYNJWbDzkxCxjSTxBG_DuiPxjaeo-skFb85m3L_nDF2OAPXDazmNgrPWf"}`,
+			Signed:    `eyJhbGciOiJCUDM4NFIxIn0.TG9yZW0gaXBzdW0.KrxxcVHGs-SRk6REZ7u9pBfm4aYMWvowEh8mI62ZnWqWo7uIXxa4f5NLf6FrB5iVW_MpZggZrgnd12Zgtl4vbw9wuKjL0ksrxayJY3r6Sh-eAT23HiAJKmMkacM8RqaK`,
+		},
+		{
+			Algorithm: jwa.BP512R1,
+			Key:       `{"crv":"BP-512","kty":"EC","x":"e_WdVp7MQW3dDbwkYIR0n0miiCBaNUD9xH1d_ywtM5w4feF3fvgbEF6WceSG0CoabiHbHcuaglCArpkAmckODA","y":"AWL0ho4D_aqwUKDZ-KPm2e26-Vw5YSQ5EPk5HKCsaU09irQXIQjst7biZoeL1qwQ_conhE2Xi1-dbVn7fIwdpA"}`,
+			Signed:    `eyJhbGciOiJCUDUxMlIxIn0.TG9yZW0gaXBzdW0.oKS7hhJv7CDeEqE2mt6Z1Rm1N-4bJTelpuqc-wuFf4cyrRgZOYzZYhlsbtE8S5thBOKTi3sIVNDr4j3d2l7ZF5dNAXwfcL7NsSmChlCf0ZT8aSvhMF5aiiKUsgn1IHgyFvCYQlOyJb9-Minsc97snFzgGA_TxJrx3favUGoUWEQ`,
+		},
+	}
+
+	payload := []byte(`Lorem ipsum`)
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Algorithm.String(), func(t *testing.T) {
+			t.Parallel()
+			pubkey, err := jwk.ParseKey([]byte(tc.Key))
+			if !assert.NoError(t, err, `jwk.ParseKey should succeed`) {
+				return
+			}
+
+			verified, err := jws.Verify([]byte(tc.Signed), tc.Algorithm, pubkey)
+			if !assert.NoError(t, err, `jws.Verify should succeed`) {
+				return
+			}
+			if !assert.Equal(t, payload, verified, `payload should match`) {
+				return
+			}
+
+			// The raw ecdsa keys should work as well
+			var rawkey ecdsa.PublicKey
+			if !assert.NoError(t, pubkey.Raw(&rawkey), `pubkey.Raw should succeed`) {
+				return
+			}
+			_, err = jws.Verify([]byte(tc.Signed), tc.Algorithm, &rawkey)
+			if !assert.NoError(t, err, `jws.Verify with raw key should succeed`) {
+				return
+			}
+
+			// Signing is not supported
+			_, err = jws.NewSigner(tc.Algorithm)
+			if !assert.Error(t, err, `jws.NewSigner should fail`) {
+				return
+			}
+		})
+	}
+
+	t.Run("Key on a different curve", func(t *testing.T) {
+		t.Parallel()
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+			return
+		}
+
+		signed, err := jws.Sign([]byte(`Lorem ipsum`), jwa.ES256, key)
+		if !assert.NoError(t, err, `jws.Sign should succeed`) {
+			return
+		}
+		_, err = jws.Verify(signed, jwa.BP256R1, &key.PublicKey)
+		if !assert.Error(t, err, `jws.Verify should fail`) {
+			return
+		}
+	})
+}
@@ -0,0 +1,268 @@
+// Package conformance runs JWS test vectors against the jws package.
+//
+// A test vector consists of a key, a signed message, the algorithm that
+// the message should be verified with, and the expected payload. The
+// built-in corpus (see `conformance.Builtin()`) contains a selection of
+// the examples from RFC 7515, RFC 7520 and RFC 8037, and additional vectors can be loaded
+// from JSON files using the same format. This allows users that register
+// their own signers and verifiers (see `jws.RegisterSigner()` and
+// `jws.RegisterVerifier()`) to check them against known good messages.
+//
+//	vectors, err := conformance.ReadFile(`my-vectors.json`)
+//	...
+//	results := conformance.Run(append(conformance.Builtin(), vectors...))
+//	conformance.WriteReport(os.Stdout, results)
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/pkg/errors"
+)
+
+// Vector describes a single test case. In JSON it is represented as
+//
+//	{
+//	  "name": "RFC 7515 A.1",
+//	  "alg": "HS256",
+//	  "key": { "kty": "oct", "k": "..." },
+//	  "payload": "...",
+//	  "message": "eyJ0eXAiOiJKV1Qi...",
+//	  "deterministic": true
+//	}
+//
+// "message" may be either a string containing a compact serialization,
+// or an object containing a JSON serialization.
+type Vector struct {
+	// Name identifies the test case in reports
+	Name string `json:"name"`
+
+	// Reference optionally points to the source of the test case
+	// (e.g. "RFC 7520 section 4.4")
+	Reference string `json:"reference,omitempty"`
+
+	// Algorithm is the algorithm used to verify the message
+	Algorithm jwa.SignatureAlgorithm `json:"alg"`
+
+	// Key is the JWK used to verify the message. If it is a private
+	// key, the corresponding public key is used for verification.
+	Key json.RawMessage `json:"key"`
+
+	// Payload is the payload that verification is expected to produce
+	Payload string `json:"payload"`
+
+	// Message is the signed message
+	Message json.RawMessage `json:"message"`
+
+	// Deterministic specifies that signing the message again using Key
+	// must produce exactly the same signature. This only makes sense for
+	// algorithms such as HMAC, RSASSA-PKCS1-v1_5 and EdDSA, and is only
+	// supported for messages in compact serialization.
+	Deterministic bool `json:"deterministic,omitempty"`
+
+	// DetachedPayload specifies that the message is a compact
+	// serialization whose payload has been removed (RFC 7515 appendix F).
+	// The encoded Payload is put back into the message before verifying it.
+	DetachedPayload bool `json:"detached_payload,omitempty"`
+
+	// ExpectFailure specifies that verification must fail
+	ExpectFailure bool `json:"expect_failure,omitempty"`
+}
+
+// Result is the outcome of running a single Vector
+type Result struct {
+	Vector *Vector
+
+	// Passed is true if the vector behaved as expected
+	Passed bool
+
+	// Err describes why the vector did not pass. It is nil if Passed is true
+	Err error
+}
+
+// ParseVectors reads a JSON array of test vectors
+func ParseVectors(src io.Reader) ([]*Vector, error) {
+	var vectors []*Vector
+	if err := json.NewDecoder(src).Decode(&vectors); err != nil {
+		return nil, errors.Wrap(err, `failed to decode test vectors`)
+	}
+
+	for i, v := range vectors {
+		if v.Name == "" {
+			return nil, errors.Errorf(`test vector #%d does not have a name`, i)
+		}
+	}
+	return vectors, nil
+}
+
+// ReadFile reads a JSON array of test vectors from the file `path`
+func ReadFile(path string) ([]*Vector, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to open %s`, path)
+	}
+	defer f.Close()
+
+	return ParseVectors(f)
+}
+
+// Run runs each of the test vectors, and returns the results in the same order
+func Run(vectors []*Vector) []*Result {
+	results := make([]*Result, len(vectors))
+	for i, v := range vectors {
+		results[i] = RunVector(v)
+	}
+	return results
+}
+
+// RunVector runs a single test vector.
+//
+// A vector whose key or message cannot be decoded never passes, even
+// if it expects verification to fail.
+func RunVector(v *Vector) *Result {
+	result := &Result{Vector: v}
+
+	signed, err := v.signedMessage()
+	if err != nil {
+		result.Err = errors.Wrap(err, `invalid test vector`)
+		return result
+	}
+
+	key, err := jwk.ParseKey(v.Key)
+	if err != nil {
+		result.Err = errors.Wrap(err, `invalid test vector: failed to parse key`)
+		return result
+	}
+
+	var raw interface{}
+	if err := key.Raw(&raw); err != nil {
+		result.Err = errors.Wrap(err, `invalid test vector: failed to retrieve raw key`)
+		return result
+	}
+
+	err = verify(v, signed, raw)
+	switch {
+	case v.ExpectFailure && err == nil:
+		result.Err = errors.New(`verification succeeded, but was expected to fail`)
+	case !v.ExpectFailure && err != nil:
+		result.Err = err
+	default:
+		result.Passed = true
+	}
+	return result
+}
+
+// WriteReport writes one line per result to `dst`, followed by the
+// number of vectors that passed and failed
+func WriteReport(dst io.Writer, results []*Result) error {
+	var passed, failed int
+	for _, r := range results {
+		var err error
+		if r.Passed {
+			passed++
+			_, err = fmt.Fprintf(dst, "PASS %s\n", r.Vector.Name)
+		} else {
+			failed++
+			_, err = fmt.Fprintf(dst, "FAIL %s: %s\n", r.Vector.Name, r.Err)
+		}
+		if err != nil {
+			return errors.Wrap(err, `failed to write report`)
+		}
+	}
+
+	if _, err := fmt.Fprintf(dst, "%d passed, %d failed\n", passed, failed); err != nil {
+		return errors.Wrap(err, `failed to write report`)
+	}
+	return nil
+}
+
+// signedMessage returns the message as it should be passed to jws.Verify()
+func (v *Vector) signedMessage() ([]byte, error) {
+	msg := bytes.TrimSpace(v.Message)
+	if len(msg) == 0 {
+		return nil, errors.New(`message is empty`)
+	}
+
+	if msg[0] == '{' {
+		if v.DetachedPayload {
+			return nil, errors.New(`detached payloads are only supported for compact serialization`)
+		}
+		return msg, nil
+	}
+
+	var compact string
+	if err := json.Unmarshal(msg, &compact); err != nil {
+		return nil, errors.Wrap(err, `message must be a string or an object`)
+	}
+
+	if v.DetachedPayload {
+		parts := strings.Split(compact, ".")
+		if len(parts) != 3 || parts[1] != "" {
+			return nil, errors.New(`message with a detached payload must have an empty payload segment`)
+		}
+		compact = parts[0] + "." + base64.EncodeToString([]byte(v.Payload)) + "." + parts[2]
+	}
+	return []byte(compact), nil
+}
+
+func verify(v *Vector, signed []byte, raw interface{}) error {
+	pubkey, err := jwk.PublicRawKeyOf(raw)
+	if err != nil {
+		return errors.Wrap(err, `failed to retrieve public key`)
+	}
+
+	payload, err := jws.Verify(signed, v.Algorithm, pubkey)
+	if err != nil {
+		return errors.Wrap(err, `failed to verify message`)
+	}
+
+	if string(payload) != v.Payload {
+		return errors.Errorf(`payload does not match: got %q`, payload)
+	}
+
+	if v.Deterministic {
+		if err := resign(v, signed, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resign signs the signing input of a compact message again, and checks
+// that the result is identical to the original signature
+func resign(v *Vector, signed []byte, raw interface{}) error {
+	if signed[0] == '{' {
+		return errors.New(`deterministic signing is only supported for compact serialization`)
+	}
+
+	protected, payload, signature, err := jws.SplitCompact(signed)
+	if err != nil {
+		return errors.Wrap(err, `failed to split compact message`)
+	}
+
+	signer, err := jws.NewSigner(v.Algorithm)
+	if err != nil {
+		return errors.Wrap(err, `failed to create signer`)
+	}
+
+	input := make([]byte, 0, len(protected)+len(payload)+1)
+	input = append(append(append(input, protected...), '.'), payload...)
+	computed, err := signer.Sign(input, raw)
+	if err != nil {
+		return errors.Wrap(err, `failed to sign message`)
+	}
+
+	if !bytes.Equal(base64.Encode(computed), signature) {
+		return errors.New(`signature differs from the one produced by signing the message again`)
+	}
+	return nil
+}
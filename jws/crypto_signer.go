@@ -0,0 +1,128 @@
+package jws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"math/big"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/pkg/errors"
+)
+
+// opaqueSigner returns the key as a crypto.Signer if it is a private key
+// whose key material is not available, such as a key held in a hardware
+// token (see `jwk/pkcs11`). Raw private keys that happen to implement
+// crypto.Signer are signed with directly, and are not reported.
+func opaqueSigner(key interface{}) (crypto.Signer, bool) {
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return nil, false
+	}
+	signer, ok := key.(crypto.Signer)
+	return signer, ok
+}
+
+// keyIDOf returns the key ID carried by the key, if any. This includes
+// jwk.Key, as well as opaque signers that know their key ID.
+func keyIDOf(key interface{}) string {
+	if v, ok := key.(interface{ KeyID() string }); ok {
+		return v.KeyID()
+	}
+	return ""
+}
+
+// keyIDSigner attaches a key ID to an opaque signer, for keys obtained
+// from a SigningKeyProvider
+type keyIDSigner struct {
+	crypto.Signer
+	kid string
+}
+
+func (s *keyIDSigner) KeyID() string {
+	return s.kid
+}
+
+func digestOf(hash crypto.Hash, payload []byte) ([]byte, error) {
+	h := hash.New()
+	if _, err := h.Write(payload); err != nil {
+		return nil, errors.Wrap(err, `failed to write payload`)
+	}
+	return h.Sum(nil), nil
+}
+
+func signRSAWithSigner(alg jwa.SignatureAlgorithm, signer crypto.Signer, payload []byte) ([]byte, error) {
+	if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+		return nil, errors.Errorf(`expected signer for an RSA key, got %T`, signer.Public())
+	}
+
+	var hash crypto.Hash
+	var opts crypto.SignerOpts
+	switch alg {
+	case jwa.RS256, jwa.RS384, jwa.RS512:
+		hash = map[jwa.SignatureAlgorithm]crypto.Hash{jwa.RS256: crypto.SHA256, jwa.RS384: crypto.SHA384, jwa.RS512: crypto.SHA512}[alg]
+		opts = hash
+	case jwa.PS256, jwa.PS384, jwa.PS512:
+		hash = map[jwa.SignatureAlgorithm]crypto.Hash{jwa.PS256: crypto.SHA256, jwa.PS384: crypto.SHA384, jwa.PS512: crypto.SHA512}[alg]
+		// RFC 7518 requires the salt to be as long as the hash
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash}
+	default:
+		return nil, errors.Errorf(`unsupported rsa signature algorithm "%s"`, alg)
+	}
+
+	digest, err := digestOf(hash, payload)
+	if err != nil {
+		return nil, err
+	}
+	return signer.Sign(rand.Reader, digest, opts)
+}
+
+func signECDSAWithSigner(alg jwa.SignatureAlgorithm, signer crypto.Signer, payload []byte, enc ECDSASignatureEncoding) ([]byte, error) {
+	pubkey, ok := signer.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.Errorf(`expected signer for an ECDSA key, got %T`, signer.Public())
+	}
+
+	var hash crypto.Hash
+	switch alg {
+	case jwa.ES256:
+		hash = crypto.SHA256
+	case jwa.ES384:
+		hash = crypto.SHA384
+	case jwa.ES512:
+		hash = crypto.SHA512
+	default:
+		return nil, errors.Errorf(`unsupported ecdsa signature algorithm "%s"`, alg)
+	}
+
+	digest, err := digestOf(hash, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// crypto.Signer implementations return ASN.1 DER encoded signatures
+	der, err := signer.Sign(rand.Reader, digest, hash)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to sign payload using ecdsa`)
+	}
+
+	var r, s big.Int
+	if err := ECDSASignatureASN1.Decode(&r, &s, der, pubkey.Curve); err != nil {
+		return nil, errors.Wrap(err, `failed to decode ecdsa signature`)
+	}
+	out, err := enc.Encode(&r, &s, pubkey.Curve)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to encode ecdsa signature`)
+	}
+	return out, nil
+}
+
+func signEdDSAWithSigner(signer crypto.Signer, payload []byte) ([]byte, error) {
+	if _, ok := signer.Public().(ed25519.PublicKey); !ok {
+		return nil, errors.Errorf(`expected signer for an Ed25519 key, got %T`, signer.Public())
+	}
+	// Ed25519 signs the message itself, not a digest
+	return signer.Sign(rand.Reader, payload, crypto.Hash(0))
+}
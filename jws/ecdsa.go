@@ -5,6 +5,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/rand"
 
+	"github.com/lestrrat-go/jwx/internal/ecutil"
 	"github.com/lestrrat-go/jwx/internal/keyconv"
 	"github.com/lestrrat-go/jwx/internal/pool"
 	"github.com/lestrrat-go/jwx/jwa"
@@ -28,13 +29,11 @@ func init() {
 }
 
 func makeECDSASignFunc(hash crypto.Hash) ecdsaSignFunc {
-	return func(payload []byte, key *ecdsa.PrivateKey) ([]byte, error) {
-		curveBits := key.Curve.Params().BitSize
-		keyBytes := curveBits / 8
-		// Curve bits do not need to be a multiple of 8.
-		if curveBits%8 > 0 {
-			keyBytes++
+	return func(payload []byte, key *ecdsa.PrivateKey, enc ECDSASignatureEncoding) ([]byte, error) {
+		if ecutil.IsVariableTime(key.Curve) {
+			return nil, errors.Errorf(`signing using keys on %s is not supported`, key.Curve.Params().Name)
 		}
+
 		h := hash.New()
 		if _, err := h.Write(payload); err != nil {
 			return nil, errors.Wrap(err, "failed to write payload using ecdsa")
@@ -44,24 +43,44 @@ func makeECDSASignFunc(hash crypto.Hash) ecdsaSignFunc {
 			return nil, errors.Wrap(err, "failed to sign payload using ecdsa")
 		}
 
-		rBytes := r.Bytes()
-		rBytesPadded := make([]byte, keyBytes)
-		copy(rBytesPadded[keyBytes-len(rBytes):], rBytes)
-
-		sBytes := s.Bytes()
-		sBytesPadded := make([]byte, keyBytes)
-		copy(sBytesPadded[keyBytes-len(sBytes):], sBytes)
-
-		out := append(rBytesPadded, sBytesPadded...)
+		out, err := enc.Encode(r, s, key.Curve)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to encode ecdsa signature")
+		}
 		return out, nil
 	}
 }
 
 func newECDSASigner(alg jwa.SignatureAlgorithm) Signer {
 	return &ECDSASigner{
-		alg:  alg,
-		sign: ecdsaSignFuncs[alg], // we know this will succeed
+		alg:      alg,
+		sign:     ecdsaSignFuncs[alg], // we know this will succeed
+		encoding: ECDSASignatureP1363,
+	}
+}
+
+// NewECDSASigner creates a Signer for the given ECDSA algorithm
+// (jwa.ES256, jwa.ES384, or jwa.ES512), which serializes signatures
+// using the given encoding. If `enc` is nil, the encoding mandated by
+// JWS (`jws.ECDSASignatureP1363`) is used.
+//
+// This is useful when bridging to formats that use different conventions,
+// such as WebAuthn, which uses ASN.1 DER encoded signatures. Note that
+// signatures that are not encoded using `jws.ECDSASignatureP1363`
+// are not valid JWS signatures.
+func NewECDSASigner(alg jwa.SignatureAlgorithm, enc ECDSASignatureEncoding) (Signer, error) {
+	f, ok := ecdsaSignFuncs[alg]
+	if !ok {
+		return nil, errors.Errorf(`unsupported ecdsa signature algorithm "%s"`, alg)
+	}
+	if enc == nil {
+		enc = ECDSASignatureP1363
 	}
+	return &ECDSASigner{
+		alg:      alg,
+		sign:     f,
+		encoding: enc,
+	}, nil
 }
 
 func (s ECDSASigner) Algorithm() jwa.SignatureAlgorithm {
@@ -73,24 +92,28 @@ func (s ECDSASigner) Sign(payload []byte, key interface{}) ([]byte, error) {
 		return nil, errors.New(`missing private key while signing payload`)
 	}
 
+	if signer, ok := opaqueSigner(key); ok {
+		return signECDSAWithSigner(s.alg, signer, payload, s.encoding)
+	}
+
 	var privkey ecdsa.PrivateKey
 	if err := keyconv.ECDSAPrivateKey(&privkey, key); err != nil {
 		return nil, errors.Wrapf(err, `failed to retrieve ecdsa.PrivateKey out of %T`, key)
 	}
 
-	return s.sign(payload, &privkey)
+	return s.sign(payload, &privkey, s.encoding)
 }
 
 func makeECDSAVerifyFunc(hash crypto.Hash) ecdsaVerifyFunc {
-	return func(payload []byte, signature []byte, key *ecdsa.PublicKey) error {
+	return func(payload []byte, signature []byte, key *ecdsa.PublicKey, enc ECDSASignatureEncoding) error {
 		r := pool.GetBigInt()
 		s := pool.GetBigInt()
 		defer pool.ReleaseBigInt(r)
 		defer pool.ReleaseBigInt(s)
 
-		n := len(signature) / 2
-		r.SetBytes(signature[:n])
-		s.SetBytes(signature[n:])
+		if err := enc.Decode(r, s, signature, key.Curve); err != nil {
+			return errors.Wrap(err, "failed to decode ecdsa signature")
+		}
 
 		h := hash.New()
 		if _, err := h.Write(payload); err != nil {
@@ -106,10 +129,29 @@ func makeECDSAVerifyFunc(hash crypto.Hash) ecdsaVerifyFunc {
 
 func newECDSAVerifier(alg jwa.SignatureAlgorithm) Verifier {
 	return &ECDSAVerifier{
-		verify: ecdsaVerifyFuncs[alg], // we know this will succeed
+		verify:   ecdsaVerifyFuncs[alg], // we know this will succeed
+		encoding: ECDSASignatureP1363,
 	}
 }
 
+// NewECDSAVerifier creates a Verifier for the given ECDSA algorithm
+// (jwa.ES256, jwa.ES384, or jwa.ES512), which expects signatures to be
+// serialized using the given encoding. If `enc` is nil, the encoding
+// mandated by JWS (`jws.ECDSASignatureP1363`) is used.
+func NewECDSAVerifier(alg jwa.SignatureAlgorithm, enc ECDSASignatureEncoding) (Verifier, error) {
+	f, ok := ecdsaVerifyFuncs[alg]
+	if !ok {
+		return nil, errors.Errorf(`unsupported ecdsa signature algorithm "%s"`, alg)
+	}
+	if enc == nil {
+		enc = ECDSASignatureP1363
+	}
+	return &ECDSAVerifier{
+		verify:   f,
+		encoding: enc,
+	}, nil
+}
+
 func (v ECDSAVerifier) Verify(payload []byte, signature []byte, key interface{}) error {
 	if key == nil {
 		return errors.New(`missing public key while verifying payload`)
@@ -120,5 +162,5 @@ func (v ECDSAVerifier) Verify(payload []byte, signature []byte, key interface{})
 		return errors.Wrapf(err, `failed to retrieve ecdsa.PublicKey out of %T`, key)
 	}
 
-	return v.verify(payload, signature, &pubkey)
+	return v.verify(payload, signature, &pubkey, v.encoding)
 }
@@ -0,0 +1,89 @@
+package jws
+
+import (
+	"crypto/elliptic"
+	"encoding/asn1"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// ECDSASignatureEncoding controls how the (r, s) pair of an ECDSA
+// signature is serialized. Use `jws.NewECDSASigner()` and
+// `jws.NewECDSAVerifier()` to create signers and verifiers that use
+// a specific encoding.
+type ECDSASignatureEncoding interface {
+	// Encode serializes the signature (r, s) created using a key on `curve`
+	Encode(r, s *big.Int, curve elliptic.Curve) ([]byte, error)
+	// Decode parses the serialized signature, and stores the result in `r` and `s`
+	Decode(r, s *big.Int, signature []byte, curve elliptic.Curve) error
+}
+
+// ECDSASignatureP1363 is the encoding used by JWS (RFC7518 section 3.4)
+// and COSE: r and s are left-padded to the byte length of the curve order,
+// and concatenated.
+var ECDSASignatureP1363 ECDSASignatureEncoding = ecdsaP1363Encoding{}
+
+// ECDSASignatureASN1 is the ASN.1 DER encoding of the Ecdsa-Sig-Value
+// structure (RFC3279), as used by X.509 and WebAuthn.
+var ECDSASignatureASN1 ECDSASignatureEncoding = ecdsaASN1Encoding{}
+
+type ecdsaP1363Encoding struct{}
+
+func (ecdsaP1363Encoding) Encode(r, s *big.Int, curve elliptic.Curve) ([]byte, error) {
+	curveBits := curve.Params().BitSize
+	keyBytes := curveBits / 8
+	// Curve bits do not need to be a multiple of 8.
+	if curveBits%8 > 0 {
+		keyBytes++
+	}
+
+	rBytes := r.Bytes()
+	sBytes := s.Bytes()
+	if len(rBytes) > keyBytes || len(sBytes) > keyBytes {
+		return nil, errors.New(`signature values are too large for the curve`)
+	}
+
+	out := make([]byte, 2*keyBytes)
+	copy(out[keyBytes-len(rBytes):keyBytes], rBytes)
+	copy(out[2*keyBytes-len(sBytes):], sBytes)
+	return out, nil
+}
+
+func (ecdsaP1363Encoding) Decode(r, s *big.Int, signature []byte, _ elliptic.Curve) error {
+	n := len(signature) / 2
+	r.SetBytes(signature[:n])
+	s.SetBytes(signature[n:])
+	return nil
+}
+
+type ecdsaASN1Signature struct {
+	R, S *big.Int
+}
+
+type ecdsaASN1Encoding struct{}
+
+func (ecdsaASN1Encoding) Encode(r, s *big.Int, _ elliptic.Curve) ([]byte, error) {
+	buf, err := asn1.Marshal(ecdsaASN1Signature{R: r, S: s})
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal ASN.1 signature`)
+	}
+	return buf, nil
+}
+
+func (ecdsaASN1Encoding) Decode(r, s *big.Int, signature []byte, _ elliptic.Curve) error {
+	var sig ecdsaASN1Signature
+	rest, err := asn1.Unmarshal(signature, &sig)
+	if err != nil {
+		return errors.Wrap(err, `failed to unmarshal ASN.1 signature`)
+	}
+	if len(rest) > 0 {
+		return errors.New(`trailing data after ASN.1 signature`)
+	}
+	if sig.R == nil || sig.S == nil {
+		return errors.New(`invalid ASN.1 signature`)
+	}
+	r.Set(sig.R)
+	s.Set(sig.S)
+	return nil
+}
@@ -21,6 +21,10 @@ func (s EdDSASigner) Sign(payload []byte, key interface{}) ([]byte, error) {
 		return nil, errors.New(`missing private key while signing payload`)
 	}
 
+	if signer, ok := opaqueSigner(key); ok {
+		return signEdDSAWithSigner(signer, payload)
+	}
+
 	var privkey ed25519.PrivateKey
 	if err := keyconv.Ed25519PrivateKey(&privkey, key); err != nil {
 		return nil, errors.Wrapf(err, `failed to retrieve ed25519.PrivateKey out of %T`, key)
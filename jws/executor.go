@@ -0,0 +1,161 @@
+package jws
+
+import (
+	"context"
+	"sync"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/pkg/errors"
+)
+
+// Executor runs the verifications started by `jws.VerifyAsync()`.
+//
+// Execute must either arrange for `fn` to be called exactly once and
+// return nil, or return an error without ever calling `fn`. It should
+// block while the executor is at capacity, until `ctx` is done, so that
+// callers are slowed down instead of queueing an unbounded amount of work.
+type Executor interface {
+	Execute(ctx context.Context, fn func()) error
+}
+
+// WorkerPool is an Executor that runs functions on a fixed number of
+// goroutines, so that CPU heavy verifications (e.g. RSA) do not run on,
+// and block, the goroutines of a latency sensitive event loop.
+type WorkerPool struct {
+	mu     sync.RWMutex
+	closed bool
+	tasks  chan func()
+	wg     sync.WaitGroup
+}
+
+// NewWorkerPool creates a new WorkerPool with `workers` goroutines, and
+// a queue that holds up to `queueSize` pending functions. When the queue
+// is full, `Execute()` blocks until there is room or the context is done.
+//
+// If `workers` is less than or equal to 0, one worker is started. If
+// `queueSize` is negative, the queue size is 0, i.e. `Execute()` blocks
+// until a worker is ready to run the function.
+func NewWorkerPool(workers, queueSize int) *WorkerPool {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	p := &WorkerPool{
+		tasks: make(chan func(), queueSize),
+	}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.run()
+	}
+	return p
+}
+
+func (p *WorkerPool) run() {
+	defer p.wg.Done()
+	for fn := range p.tasks {
+		fn()
+	}
+}
+
+// Execute queues `fn` to be run by one of the workers. It returns an
+// error if `ctx` is done before the function could be queued, or if the
+// pool has been closed.
+func (p *WorkerPool) Execute(ctx context.Context, fn func()) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return errors.New(`worker pool is closed`)
+	}
+
+	select {
+	case p.tasks <- fn:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), `failed to queue task`)
+	}
+}
+
+// Close stops accepting new functions, and waits for the queued
+// functions to finish.
+func (p *WorkerPool) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+// VerifyFuture is the result of `jws.VerifyAsync()`
+type VerifyFuture struct {
+	done    chan struct{}
+	payload []byte
+	err     error
+}
+
+func (f *VerifyFuture) complete(payload []byte, err error) {
+	f.payload = payload
+	f.err = err
+	close(f.done)
+}
+
+// Done returns a channel that is closed when the verification has completed
+func (f *VerifyFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait waits for the verification to complete, and returns its result
+// as `jws.Verify()` would. If `ctx` is done first, its error is returned,
+// and the verification may still complete in the background.
+func (f *VerifyFuture) Wait(ctx context.Context) ([]byte, error) {
+	select {
+	case <-f.done:
+		return f.payload, f.err
+	case <-ctx.Done():
+		return nil, errors.Wrap(ctx.Err(), `failed to wait for verification`)
+	}
+}
+
+// VerifyAsync starts verifying the JWS message in `buf` in the background,
+// and returns a future that holds the result. The parameters and options
+// are the same as those for `jws.Verify()`, and `buf` must not be modified
+// until the verification has completed.
+//
+// The verification is run by the Executor given via `jws.WithExecutor()`,
+// or in a new goroutine if no executor is specified. If `ctx` is done
+// before the verification could be started (e.g. because the executor
+// is at capacity), the future completes with the context's error.
+func VerifyAsync(ctx context.Context, buf []byte, alg jwa.SignatureAlgorithm, key interface{}, options ...Option) *VerifyFuture {
+	var executor Executor
+	for _, o := range options {
+		switch o.Ident() {
+		case identExecutor{}:
+			executor = o.Value().(Executor)
+		}
+	}
+
+	f := &VerifyFuture{done: make(chan struct{})}
+	task := func() {
+		// Don't spend time on a verification that nobody is waiting for
+		if err := ctx.Err(); err != nil {
+			f.complete(nil, errors.Wrap(err, `verification was canceled`))
+			return
+		}
+		f.complete(Verify(buf, alg, key, options...))
+	}
+
+	if executor == nil {
+		go task()
+		return f
+	}
+
+	if err := executor.Execute(ctx, task); err != nil {
+		f.complete(nil, errors.Wrap(err, `failed to start verification`))
+	}
+	return f
+}
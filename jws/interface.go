@@ -85,12 +85,13 @@ type RSASigner struct {
 	sign rsaSignFunc
 }
 
-type ecdsaSignFunc func([]byte, *ecdsa.PrivateKey) ([]byte, error)
+type ecdsaSignFunc func([]byte, *ecdsa.PrivateKey, ECDSASignatureEncoding) ([]byte, error)
 
 // ECDSASigner uses crypto/ecdsa to sign the payloads.
 type ECDSASigner struct {
-	alg  jwa.SignatureAlgorithm
-	sign ecdsaSignFunc
+	alg      jwa.SignatureAlgorithm
+	sign     ecdsaSignFunc
+	encoding ECDSASignatureEncoding
 }
 
 type hmacSignFunc func([]byte, []byte) ([]byte, error)
@@ -121,10 +122,11 @@ type RSAVerifier struct {
 	verify rsaVerifyFunc
 }
 
-type ecdsaVerifyFunc func([]byte, []byte, *ecdsa.PublicKey) error
+type ecdsaVerifyFunc func([]byte, []byte, *ecdsa.PublicKey, ECDSASignatureEncoding) error
 
 type ECDSAVerifier struct {
-	verify ecdsaVerifyFunc
+	verify   ecdsaVerifyFunc
+	encoding ECDSASignatureEncoding
 }
 
 type HMACVerifier struct {
@@ -77,22 +77,93 @@ func (s *payloadSigner) PublicHeader() Headers {
 // the type of key you provided, otherwise an error is returned.
 //
 // If you would like to pass custom headers, use the WithHeaders option.
+// If you are signing many payloads with the same headers, consider using
+// the WithHeaderTemplate option instead.
+//
+// If the signing key is rotated over time, use the WithKeyProviderForSigning
+// option to obtain the current key each time a payload is signed.
+//
+// Key attestation evidence may be attached using the WithKeyAttestation
+// option.
 func Sign(payload []byte, alg jwa.SignatureAlgorithm, key interface{}, options ...Option) ([]byte, error) {
 	var hdrs Headers
+	var template *HeaderTemplate
+	var provider SigningKeyProvider
+	var evidence []byte
+	var bufpool BufferPool = defaultBufferPool{}
+	var logger Logger = nopLogger{}
 	for _, o := range options {
 		switch o.Ident() {
+		case identLogger{}:
+			logger = o.Value().(Logger)
+		case identBufferPool{}:
+			bufpool = o.Value().(BufferPool)
 		case identHeaders{}:
 			hdrs = o.Value().(Headers)
+		case identHeaderTemplate{}:
+			template = o.Value().(*HeaderTemplate)
+		case identKeyProviderForSigning{}:
+			provider = o.Value().(SigningKeyProvider)
+		case identKeyAttestation{}:
+			evidence = o.Value().([]byte)
 		}
 	}
 
+	if evidence != nil {
+		v, err := withKeyAttestation(hdrs, evidence)
+		if err != nil {
+			return nil, err
+		}
+		hdrs = v
+	}
+
+	if provider != nil {
+		v, err := keyFromProvider(provider)
+		if err != nil {
+			logger.Warn("jws: failed to obtain signing key from provider", "alg", alg.String(), "error", err)
+			return nil, err
+		}
+		key = v
+		logger.Debug("jws: obtained signing key from provider", logKeyvals(alg, key)...)
+	}
+
+	signed, err := sign(payload, alg, key, hdrs, template, bufpool)
+	if err != nil {
+		logger.Warn("jws: signing failed", logKeyvals(alg, key, "error", err)...)
+		return nil, err
+	}
+	logger.Debug("jws: signed payload", logKeyvals(alg, key, "template", template != nil)...)
+	return signed, nil
+}
+
+func sign(payload []byte, alg jwa.SignatureAlgorithm, key interface{}, hdrs Headers, template *HeaderTemplate, bufpool BufferPool) ([]byte, error) {
 	signer, err := NewSigner(alg)
 	if err != nil {
 		return nil, errors.Wrap(err, `failed to create signer`)
 	}
 
+	if template != nil {
+		if hdrs == nil {
+			encoded, err := template.encode(signer.Algorithm(), key)
+			if err != nil {
+				return nil, errors.Wrap(err, `failed to encode header template`)
+			}
+			_, signature, err := signCompact(encoded, payload, signer, key, bufpool)
+			if err != nil {
+				return nil, errors.Wrap(err, `failed sign payload`)
+			}
+			return signature, nil
+		}
+
+		merged, err := mergeHeaders(context.TODO(), template.headers, hdrs)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to merge header template`)
+		}
+		hdrs = merged
+	}
+
 	sig := &Signature{protected: hdrs}
-	_, signature, err := sig.Sign(payload, signer, key)
+	_, signature, err := sig.sign(payload, signer, key, bufpool)
 	if err != nil {
 		return nil, errors.Wrap(err, `failed sign payload`)
 	}
@@ -105,13 +176,17 @@ func Sign(payload []byte, alg jwa.SignatureAlgorithm, key interface{}, options .
 // signatures from applying aforementioned signers.
 //
 // Use `jws.WithSigner(...)` to specify values how to generate
-// each signature in the `"signatures": [ ... ]` field.
+// each signature in the `"signatures": [ ... ]` field, and
+// `jws.WithCanonicalJSON(true)` to emit byte-stable output.
 func SignMulti(payload []byte, options ...Option) ([]byte, error) {
 	var signers []*payloadSigner
+	var canonical bool
 	for _, o := range options {
 		switch o.Ident() {
 		case identPayloadSigner{}:
 			signers = append(signers, o.Value().(*payloadSigner))
+		case identCanonicalJSON{}:
+			canonical = o.Value().(bool)
 		}
 	}
 
@@ -137,8 +212,21 @@ func SignMulti(payload []byte, options ...Option) ([]byte, error) {
 			return nil, errors.Wrap(err, `failed to set header`)
 		}
 
+		public := signer.PublicHeader()
+		if canonical {
+			var err error
+			if protected, err = canonicalHeaders(protected); err != nil {
+				return nil, errors.Wrapf(err, `failed to normalize protected headers for signer #%d`, i)
+			}
+			if public != nil {
+				if public, err = canonicalHeaders(public); err != nil {
+					return nil, errors.Wrapf(err, `failed to normalize public headers for signer #%d`, i)
+				}
+			}
+		}
+
 		sig := &Signature{
-			headers:   signer.PublicHeader(),
+			headers:   public,
 			protected: protected,
 		}
 		_, _, err := sig.Sign(payload, signer.signer, signer.key)
@@ -149,7 +237,33 @@ func SignMulti(payload []byte, options ...Option) ([]byte, error) {
 		result.signatures = append(result.signatures, sig)
 	}
 
-	return json.Marshal(result)
+	serialized, err := json.Marshal(result)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal message`)
+	}
+	if !canonical {
+		return serialized, nil
+	}
+	return json.Canonicalize(serialized)
+}
+
+// canonicalHeaders returns a copy of the headers whose values have been
+// normalized by a round trip through the canonical JSON serialization,
+// so that nested objects are serialized with their members sorted
+func canonicalHeaders(h Headers) (Headers, error) {
+	buf, err := json.Marshal(h)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal headers`)
+	}
+	buf, err = json.Canonicalize(buf)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to canonicalize headers`)
+	}
+	normalized := NewHeaders()
+	if err := json.Unmarshal(buf, normalized); err != nil {
+		return nil, errors.Wrap(err, `failed to unmarshal headers`)
+	}
+	return normalized, nil
 }
 
 // Verify checks if the given JWS message is verifiable using `alg` and `key`.
@@ -161,16 +275,107 @@ func SignMulti(payload []byte, options ...Option) ([]byte, error) {
 // `Verifier` in `verify` subpackage, and call `Verify` method on it.
 // If you need to access signatures and JOSE headers in a JWS message,
 // use `Parse` function to get `Message` object.
-func Verify(buf []byte, alg jwa.SignatureAlgorithm, key interface{}) ([]byte, error) {
+//
+// The options currently accepted are `jws.WithBufferPool()`,
+// `jws.WithVerificationCache()`, `jws.WithKeyAttestationPolicy()`,
+// `jws.WithMaxMessageSize()` and `jws.WithLogger()`
+func Verify(buf []byte, alg jwa.SignatureAlgorithm, key interface{}, options ...Option) ([]byte, error) {
+	var bufpool BufferPool = defaultBufferPool{}
+	var cache *VerificationCache
+	var policy KeyAttestationPolicy
+	var maxSize int64
+	var logger Logger = nopLogger{}
+	for _, o := range options {
+		switch o.Ident() {
+		case identLogger{}:
+			logger = o.Value().(Logger)
+		case identMaxMessageSize{}:
+			maxSize = o.Value().(int64)
+		case identBufferPool{}:
+			bufpool = o.Value().(BufferPool)
+		case identVerificationCache{}:
+			cache = o.Value().(*VerificationCache)
+		case identKeyAttestationPolicy{}:
+			policy = o.Value().(KeyAttestationPolicy)
+		}
+	}
+
+	payload, err := verify(buf, alg, key, bufpool, cache, policy, maxSize, logger)
+	if err != nil {
+		logger.Warn("jws: verification failed", logKeyvals(alg, key, "error", err)...)
+		return nil, err
+	}
+	logger.Debug("jws: verified message", logKeyvals(alg, key)...)
+	return payload, nil
+}
+
+func verify(buf []byte, alg jwa.SignatureAlgorithm, key interface{}, bufpool BufferPool, cache *VerificationCache, policy KeyAttestationPolicy, maxSize int64, logger Logger) ([]byte, error) {
+	if err := checkMessageSize(buf, maxSize); err != nil {
+		return nil, err
+	}
+
 	buf = bytes.TrimSpace(buf)
 	if len(buf) == 0 {
 		return nil, errors.New(`attempt to verify empty buffer`)
 	}
 
+	if policy != nil {
+		// The decision of the policy may change over time (e.g. when
+		// certificates expire), so it can not be cached
+		if cache != nil {
+			return nil, errors.New(`jws.WithVerificationCache() can not be used together with jws.WithKeyAttestationPolicy()`)
+		}
+		payload, hdr, err := verifyBuffer(buf, alg, key, bufpool, logger)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkKeyAttestation(policy, alg, key, hdr); err != nil {
+			return nil, err
+		}
+		return payload, nil
+	}
+
+	if cache == nil {
+		payload, _, err := verifyBuffer(buf, alg, key, bufpool, logger)
+		return payload, err
+	}
+
+	ckey, err := verificationCacheKeyFor(buf, alg, key)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to compute verification cache key`)
+	}
+	if payload, ok := cache.lookup(ckey); ok {
+		logger.Debug("jws: verification cache hit", logKeyvals(alg, key)...)
+		return payload, nil
+	}
+
+	payload, _, err := verifyBuffer(buf, alg, key, bufpool, logger)
+	if err != nil {
+		return nil, err
+	}
+	cache.store(ckey, payload)
+	return payload, nil
+}
+
+// logAlgorithmMismatch reports when the "alg" header of a signature differs
+// from the algorithm used to verify it, which is a frequent cause of
+// verification failures
+func logAlgorithmMismatch(logger Logger, hdr Headers, alg jwa.SignatureAlgorithm, index int) {
+	if hdr == nil {
+		return
+	}
+	if v := hdr.Algorithm(); v != "" && v != alg {
+		logger.Debug("jws: algorithm in header differs from verification algorithm", "signature", index, "header_alg", v.String(), "alg", alg.String())
+	}
+}
+
+// verifyBuffer verifies the message, and returns its payload and the
+// protected headers of the signature that was verified
+func verifyBuffer(buf []byte, alg jwa.SignatureAlgorithm, key interface{}, bufpool BufferPool, logger Logger) ([]byte, Headers, error) {
 	if buf[0] == '{' {
-		return verifyJSON(buf, alg, key)
+		return verifyJSON(buf, alg, key, bufpool, logger)
 	}
-	return verifyCompact(buf, alg, key)
+	return verifyCompact(buf, alg, key, bufpool, logger)
 }
 
 // VerifySet uses keys store in a jwk.Set to verify the payload in `buf`.
@@ -181,22 +386,27 @@ func Verify(buf []byte, alg jwa.SignatureAlgorithm, key interface{}) ([]byte, er
 //
 // Furthermore if the JWS signature asks for a spefici "kid", the
 // `jwk.Key` must have the same "kid" as the signature.
-func VerifySet(buf []byte, set jwk.Set) ([]byte, error) {
+//
+// The options are passed to `jws.Verify()` for each key.
+func VerifySet(buf []byte, set jwk.Set, options ...Option) ([]byte, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	logger := lookupLogger(options)
 	for iter := set.Iterate(ctx); iter.Next(ctx); {
 		pair := iter.Pair()
 		key := pair.Value.(jwk.Key)
 		if key.Algorithm() == "" { // algorithm is not
+			logger.Debug("jws: skipping key without algorithm", "key_type", key.KeyType().String(), "kid", key.KeyID())
 			continue
 		}
 
 		if usage := key.KeyUsage(); usage != "" && usage != jwk.ForSignature.String() {
+			logger.Debug("jws: skipping key not intended for signatures", "key_type", key.KeyType().String(), "kid", key.KeyID(), "use", usage)
 			continue
 		}
 
-		buf, err := Verify(buf, jwa.SignatureAlgorithm(key.Algorithm()), key)
+		buf, err := Verify(buf, jwa.SignatureAlgorithm(key.Algorithm()), key, options...)
 		if err != nil {
 			continue
 		}
@@ -204,101 +414,120 @@ func VerifySet(buf []byte, set jwk.Set) ([]byte, error) {
 		return buf, nil
 	}
 
-	return nil, errors.New(`failed to verify message with any of the keys in the jwk.Set object`)
+	err := errors.New(`failed to verify message with any of the keys in the jwk.Set object`)
+	logger.Warn("jws: verification failed", "keys", set.Len(), "error", err)
+	return nil, err
 }
 
-func verifyJSON(signed []byte, alg jwa.SignatureAlgorithm, key interface{}) ([]byte, error) {
+func verifyJSON(signed []byte, alg jwa.SignatureAlgorithm, key interface{}, bufpool BufferPool, logger Logger) ([]byte, Headers, error) {
 	verifier, err := NewVerifier(alg)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create verifier")
+		return nil, nil, errors.Wrap(err, "failed to create verifier")
 	}
 
 	var m Message
 	if err := json.Unmarshal(signed, &m); err != nil {
-		return nil, errors.Wrap(err, `failed to unmarshal JSON message`)
+		return nil, nil, errors.Wrap(err, `failed to unmarshal JSON message`)
 	}
 
 	// Pre-compute the base64 encoded version of payload
 	payload := base64.EncodeToString(m.payload)
 
-	buf := pool.GetBytesBuffer()
-	defer pool.ReleaseBytesBuffer(buf)
+	buf := bufpool.Get()
+	defer releaseBuffer(bufpool, buf)
 
 	for i, sig := range m.signatures {
 		buf.Reset()
 		if hdr := sig.headers; hdr != nil && hdr.KeyID() != "" {
 			if jwkKey, ok := key.(jwk.Key); ok {
 				if jwkKey.KeyID() != hdr.KeyID() {
+					logger.Debug("jws: skipping signature for another key", "signature", i, "header_kid", hdr.KeyID(), "kid", jwkKey.KeyID())
 					continue
 				}
 			}
 		}
-
-		protected, err := json.Marshal(sig.protected)
-		if err != nil {
-			return nil, errors.Wrapf(err, `failed to marshal "protected" for signature #%d`, i+1)
+		logAlgorithmMismatch(logger, sig.protected, alg, i)
+
+		// Signatures without protected headers are computed over an
+		// empty string in place of the encoded headers (RFC 7515 section 5.2)
+		if sig.protected != nil {
+			protected, err := json.Marshal(sig.protected)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, `failed to marshal "protected" for signature #%d`, i+1)
+			}
+			buf.WriteString(base64.EncodeToString(protected))
 		}
-
-		buf.WriteString(base64.EncodeToString(protected))
 		buf.WriteByte('.')
 		buf.WriteString(payload)
 
-		if err := verifier.Verify(buf.Bytes(), sig.signature, key); err == nil {
-			return m.payload, nil
+		err = verifier.Verify(buf.Bytes(), sig.signature, key)
+		if err == nil {
+			return m.payload, sig.protected, nil
 		}
+		logger.Debug("jws: signature did not verify", "signature", i, "error", err)
 	}
-	return nil, errors.New(`could not verify with any of the signatures`)
+	return nil, nil, errors.New(`could not verify with any of the signatures`)
 }
 
-func verifyCompact(signed []byte, alg jwa.SignatureAlgorithm, key interface{}) ([]byte, error) {
+func verifyCompact(signed []byte, alg jwa.SignatureAlgorithm, key interface{}, bufpool BufferPool, logger Logger) ([]byte, Headers, error) {
 	protected, payload, signature, err := SplitCompact(signed)
 	if err != nil {
-		return nil, errors.Wrap(err, `failed extract from compact serialization format`)
+		return nil, nil, errors.Wrap(err, `failed extract from compact serialization format`)
 	}
 
 	verifier, err := NewVerifier(alg)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create verifier")
+		return nil, nil, errors.Wrap(err, "failed to create verifier")
 	}
 
-	verifyBuf := pool.GetBytesBuffer()
-	defer pool.ReleaseBytesBuffer(verifyBuf)
+	verifyBuf := bufpool.Get()
+	defer releaseBuffer(bufpool, verifyBuf)
 
 	verifyBuf.Write(protected)
 	verifyBuf.WriteByte('.')
 	verifyBuf.Write(payload)
 
-	decodedSignature, err := base64.Decode(signature)
+	// The decoded signature and headers are only needed until the
+	// verification is done, so they are decoded into scratch buffers
+	signatureBuf := bufpool.Get()
+	defer releaseBuffer(bufpool, signatureBuf)
+
+	decodedSignature, err := base64.DecodeBuffer(signatureBuf, signature)
 	if err != nil {
-		return nil, errors.Wrap(err, `failed to decode signature`)
+		return nil, nil, errors.Wrap(err, `failed to decode signature`)
 	}
 
+	protectedBuf := bufpool.Get()
+	defer releaseBuffer(bufpool, protectedBuf)
+
 	hdr := NewHeaders()
-	decodedProtected, err := base64.Decode(protected)
+	decodedProtected, err := base64.DecodeBuffer(protectedBuf, protected)
 	if err != nil {
-		return nil, errors.Wrap(err, `failed to decode headers`)
+		return nil, nil, errors.Wrap(err, `failed to decode headers`)
 	}
 
 	if err := json.Unmarshal(decodedProtected, hdr); err != nil {
-		return nil, errors.Wrap(err, `failed to decode headers`)
+		return nil, nil, errors.Wrap(err, `failed to decode headers`)
 	}
 
 	if hdr.KeyID() != "" {
 		if jwkKey, ok := key.(jwk.Key); ok {
 			if jwkKey.KeyID() != hdr.KeyID() {
-				return nil, errors.New(`"kid" fields do not match`)
+				logger.Debug("jws: key ID does not match", "header_kid", hdr.KeyID(), "kid", jwkKey.KeyID())
+				return nil, nil, errors.New(`"kid" fields do not match`)
 			}
 		}
 	}
+	logAlgorithmMismatch(logger, hdr, alg, 0)
 	if err := verifier.Verify(verifyBuf.Bytes(), decodedSignature, key); err != nil {
-		return nil, errors.Wrap(err, `failed to verify message`)
+		return nil, nil, errors.Wrap(err, `failed to verify message`)
 	}
 
 	decodedPayload, err := base64.Decode(payload)
 	if err != nil {
-		return nil, errors.Wrap(err, `message verified, failed to decode payload`)
+		return nil, nil, errors.Wrap(err, `message verified, failed to decode payload`)
 	}
-	return decodedPayload, nil
+	return decodedPayload, hdr, nil
 }
 
 // This is an "optimized" ioutil.ReadAll(). It will attempt to read
@@ -319,7 +548,46 @@ func readAll(rdr io.Reader) ([]byte, bool) {
 
 // Parse parses contents from the given source and creates a jws.Message
 // struct. The input can be in either compact or full JSON serialization.
-func Parse(src []byte) (*Message, error) {
+//
+// The options currently accepted are `jws.WithNormalizationReport()`
+// and `jws.WithMaxMessageSize()`
+func Parse(src []byte, options ...Option) (*Message, error) {
+	var report *NormalizationReport
+	var maxSize int64
+	for _, o := range options {
+		switch o.Ident() {
+		case identNormalizationReport{}:
+			report = o.Value().(*NormalizationReport)
+		case identMaxMessageSize{}:
+			maxSize = o.Value().(int64)
+		}
+	}
+
+	if err := checkMessageSize(src, maxSize); err != nil {
+		return nil, err
+	}
+
+	msg, err := parseBytes(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if report != nil {
+		report.inspect(src)
+	}
+	return msg, nil
+}
+
+// checkMessageSize returns an error if the message is larger than
+// maxSize bytes. A maxSize <= 0 means that there is no limit
+func checkMessageSize(src []byte, maxSize int64) error {
+	if maxSize > 0 && int64(len(src)) > maxSize {
+		return errors.Errorf(`message exceeds maximum size (%d bytes)`, maxSize)
+	}
+	return nil
+}
+
+func parseBytes(src []byte) (*Message, error) {
 	for i := 0; i < len(src); i++ {
 		r := rune(src[i])
 		if r >= utf8.RuneSelf {
@@ -337,15 +605,42 @@ func Parse(src []byte) (*Message, error) {
 
 // Parse parses contents from the given source and creates a jws.Message
 // struct. The input can be in either compact or full JSON serialization.
-func ParseString(src string) (*Message, error) {
-	return Parse([]byte(src))
+func ParseString(src string, options ...Option) (*Message, error) {
+	return Parse([]byte(src), options...)
 }
 
 // Parse parses contents from the given source and creates a jws.Message
 // struct. The input can be in either compact or full JSON serialization.
-func ParseReader(src io.Reader) (*Message, error) {
+func ParseReader(src io.Reader, options ...Option) (*Message, error) {
+	var rawInput bool
+	var maxSize int64
+	for _, o := range options {
+		switch o.Ident() {
+		case identNormalizationReport{}:
+			// the report needs to look at the raw input
+			rawInput = true
+		case identMaxMessageSize{}:
+			maxSize = o.Value().(int64)
+		}
+	}
+
+	// The limit is applied before anything is read from the source.
+	// Read at most one byte more than the limit, so that Parse can tell
+	// if the message is too large
+	if maxSize > 0 {
+		src = io.LimitReader(src, maxSize+1)
+	}
+
 	if data, ok := readAll(src); ok {
-		return Parse(data)
+		return Parse(data, options...)
+	}
+
+	if rawInput || maxSize > 0 {
+		data, err := ioutil.ReadAll(src)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to read from source`)
+		}
+		return Parse(data, options...)
 	}
 
 	rdr := bufio.NewReader(src)
@@ -398,26 +693,101 @@ func parseJSON(data []byte) (result *Message, err error) {
 
 // SplitCompact splits a JWT and returns its three parts
 // separately: protected headers, payload and signature.
+//
+// The input must consist of exactly three segments, and each segment
+// may only contain characters from the base64url alphabet (RFC 4648
+// section 5, without padding). The protected headers may not be empty,
+// but the payload (detached content) and the signature (unsecured JWS)
+// may. Leading and trailing whitespace around the whole input is ignored.
+// The returned slices refer to the input, so passing them to
+// `jws.JoinCompact()` reproduces the (trimmed) input byte for byte.
 func SplitCompact(src []byte) ([]byte, []byte, []byte, error) {
-	parts := bytes.Split(src, []byte("."))
-	if len(parts) < 3 {
+	src = bytes.TrimSpace(src)
+	first := bytes.IndexByte(src, '.')
+	if first < 0 {
+		return nil, nil, nil, errors.New(`invalid number of segments`)
+	}
+	second := bytes.IndexByte(src[first+1:], '.')
+	if second < 0 {
 		return nil, nil, nil, errors.New(`invalid number of segments`)
 	}
-	return parts[0], parts[1], parts[2], nil
+	second += first + 1
+
+	protected := src[:first]
+	payload := src[first+1 : second]
+	signature := src[second+1:]
+	if err := validateCompact(protected, payload, signature); err != nil {
+		return nil, nil, nil, err
+	}
+	return protected, payload, signature, nil
 }
 
 // SplitCompactString splits a JWT and returns its three parts
 // separately: protected headers, payload and signature.
+// See `jws.SplitCompact()` for the validation rules.
 func SplitCompactString(src string) ([]byte, []byte, []byte, error) {
-	parts := strings.Split(src, ".")
-	if len(parts) < 3 {
-		return nil, nil, nil, errors.New(`invalid number of segments`)
+	return SplitCompact([]byte(src))
+}
+
+// JoinCompact assembles the three parts of a JWS message in compact
+// serialization format: protected headers, payload and signature. The
+// parts must already be base64url encoded, and are validated using the
+// same rules as `jws.SplitCompact()`.
+func JoinCompact(protected, payload, signature []byte) ([]byte, error) {
+	if err := validateCompact(protected, payload, signature); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 0, len(protected)+len(payload)+len(signature)+2)
+	buf = append(buf, protected...)
+	buf = append(buf, '.')
+	buf = append(buf, payload...)
+	buf = append(buf, '.')
+	buf = append(buf, signature...)
+	return buf, nil
+}
+
+// JoinCompactString is the same as `jws.JoinCompact()`, but works on strings
+func JoinCompactString(protected, payload, signature string) (string, error) {
+	buf, err := JoinCompact([]byte(protected), []byte(payload), []byte(signature))
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func validateCompact(protected, payload, signature []byte) error {
+	if len(protected) == 0 {
+		return errors.New(`empty protected headers`)
 	}
-	return []byte(parts[0]), []byte(parts[1]), []byte(parts[2]), nil
+	if err := validateCompactSegment(protected); err != nil {
+		return errors.Wrap(err, `invalid protected headers`)
+	}
+	if err := validateCompactSegment(payload); err != nil {
+		return errors.Wrap(err, `invalid payload`)
+	}
+	if err := validateCompactSegment(signature); err != nil {
+		return errors.Wrap(err, `invalid signature`)
+	}
+	return nil
+}
+
+func validateCompactSegment(segment []byte) error {
+	for i, c := range segment {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_':
+		case c == '.':
+			return errors.New(`invalid number of segments`)
+		default:
+			return errors.Errorf(`invalid character %q at offset %d`, c, i)
+		}
+	}
+	return nil
 }
 
 // SplitCompactReader splits a JWT and returns its three parts
 // separately: protected headers, payload and signature.
+// See `jws.SplitCompact()` for the validation rules.
 func SplitCompactReader(rdr io.Reader) ([]byte, []byte, []byte, error) {
 	if data, ok := readAll(rdr); ok {
 		return SplitCompact(data)
@@ -483,6 +853,11 @@ func SplitCompactReader(rdr io.Reader) ([]byte, []byte, []byte, error) {
 		return nil, nil, nil, errors.New(`invalid number of segments`)
 	}
 
+	protected = bytes.TrimLeftFunc(protected, unicode.IsSpace)
+	signature = bytes.TrimRightFunc(signature, unicode.IsSpace)
+	if err := validateCompact(protected, payload, signature); err != nil {
+		return nil, nil, nil, err
+	}
 	return protected, payload, signature, nil
 }
 
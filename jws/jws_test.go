@@ -3,15 +3,22 @@ package jws_test
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/big"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/lestrrat-go/jwx/internal/base64"
 	"github.com/lestrrat-go/jwx/internal/json"
@@ -1042,3 +1049,1273 @@ func TestVerifySet(t *testing.T) {
 		})
 	}
 }
+
+func TestHeaderTemplate(t *testing.T) {
+	t.Parallel()
+
+	const payload = "Lorem ipsum"
+	key, err := jwk.New([]byte("abracadabra"))
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+	_ = key.Set(jwk.KeyIDKey, "my-key")
+
+	hdrs := jws.NewHeaders()
+	_ = hdrs.Set(jws.TypeKey, "JWT")
+	_ = hdrs.Set("custom", "value")
+
+	tmpl, err := jws.NewHeaderTemplate(hdrs)
+	if !assert.NoError(t, err, `jws.NewHeaderTemplate should succeed`) {
+		return
+	}
+
+	// modifying the source headers should not affect the template
+	_ = hdrs.Set("custom", "modified")
+
+	expectedHeaders := jws.NewHeaders()
+	_ = expectedHeaders.Set(jws.TypeKey, "JWT")
+	_ = expectedHeaders.Set("custom", "value")
+	expected, err := jws.Sign([]byte(payload), jwa.HS256, key, jws.WithHeaders(expectedHeaders))
+	if !assert.NoError(t, err, `jws.Sign should succeed`) {
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		signed, err := jws.Sign([]byte(payload), jwa.HS256, key, jws.WithHeaderTemplate(tmpl))
+		if !assert.NoError(t, err, `jws.Sign should succeed`) {
+			return
+		}
+		if !assert.Equal(t, expected, signed, `signed messages should match`) {
+			return
+		}
+	}
+
+	override := jws.NewHeaders()
+	_ = override.Set("custom", "override")
+	signed, err := jws.Sign([]byte(payload), jwa.HS256, key, jws.WithHeaderTemplate(tmpl), jws.WithHeaders(override))
+	if !assert.NoError(t, err, `jws.Sign should succeed`) {
+		return
+	}
+
+	m, err := jws.Parse(signed)
+	if !assert.NoError(t, err, `jws.Parse should succeed`) {
+		return
+	}
+	protected := m.Signatures()[0].ProtectedHeaders()
+	if v, _ := protected.Get("custom"); !assert.Equal(t, "override", v, `custom header should be overridden`) {
+		return
+	}
+	if !assert.Equal(t, "JWT", protected.Type(), `typ should come from the template`) {
+		return
+	}
+}
+
+type countingBufferPool struct {
+	jws.BufferPool
+	gets int
+	puts int
+}
+
+func (p *countingBufferPool) Get() *bytes.Buffer {
+	p.gets++
+	return p.BufferPool.Get()
+}
+
+func (p *countingBufferPool) Put(b *bytes.Buffer) {
+	p.puts++
+	if b.Len() != 0 {
+		panic("buffer was not reset")
+	}
+	p.BufferPool.Put(b)
+}
+
+func TestWithBufferPool(t *testing.T) {
+	t.Parallel()
+
+	const payload = "Lorem ipsum"
+	key := []byte("abracadabra")
+
+	bufpool := &countingBufferPool{BufferPool: jws.NewFixedBufferPool(2)}
+	signed, err := jws.Sign([]byte(payload), jwa.HS256, key, jws.WithBufferPool(bufpool))
+	if !assert.NoError(t, err, `jws.Sign should succeed`) {
+		return
+	}
+	if !assert.True(t, bufpool.gets > 0, `buffers should be taken from the pool`) {
+		return
+	}
+
+	expected, err := jws.Sign([]byte(payload), jwa.HS256, key)
+	if !assert.NoError(t, err, `jws.Sign should succeed`) {
+		return
+	}
+	if !assert.Equal(t, expected, signed, `signed messages should match`) {
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		verified, err := jws.Verify(signed, jwa.HS256, key, jws.WithBufferPool(bufpool))
+		if !assert.NoError(t, err, `jws.Verify should succeed`) {
+			return
+		}
+		if !assert.Equal(t, []byte(payload), verified, `payloads should match`) {
+			return
+		}
+	}
+
+	_, err = jws.Verify(signed, jwa.HS256, []byte("wrong key"), jws.WithBufferPool(bufpool))
+	if !assert.Error(t, err, `jws.Verify should fail`) {
+		return
+	}
+
+	if !assert.Equal(t, bufpool.gets, bufpool.puts, `all buffers should be returned to the pool`) {
+		return
+	}
+}
+
+func TestECDSASignatureEncoding(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwxtest.GenerateEcdsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`) {
+		return
+	}
+
+	payload := []byte("Lorem ipsum")
+
+	signer, err := jws.NewECDSASigner(jwa.ES256, jws.ECDSASignatureASN1)
+	if !assert.NoError(t, err, `jws.NewECDSASigner should succeed`) {
+		return
+	}
+	signature, err := signer.Sign(payload, key)
+	if !assert.NoError(t, err, `signer.Sign should succeed`) {
+		return
+	}
+	// DER encoded signatures start with a SEQUENCE tag
+	if !assert.Equal(t, byte(0x30), signature[0], `signature should be DER encoded`) {
+		return
+	}
+
+	verifier, err := jws.NewECDSAVerifier(jwa.ES256, jws.ECDSASignatureASN1)
+	if !assert.NoError(t, err, `jws.NewECDSAVerifier should succeed`) {
+		return
+	}
+	if !assert.NoError(t, verifier.Verify(payload, signature, &key.PublicKey), `verifier.Verify should succeed`) {
+		return
+	}
+
+	defaultVerifier, err := jws.NewVerifier(jwa.ES256)
+	if !assert.NoError(t, err, `jws.NewVerifier should succeed`) {
+		return
+	}
+	if !assert.Error(t, defaultVerifier.Verify(payload, signature, &key.PublicKey), `default verifier should reject DER signatures`) {
+		return
+	}
+
+	p1363Verifier, err := jws.NewECDSAVerifier(jwa.ES256, nil)
+	if !assert.NoError(t, err, `jws.NewECDSAVerifier should succeed`) {
+		return
+	}
+	defaultSigner, err := jws.NewSigner(jwa.ES256)
+	if !assert.NoError(t, err, `jws.NewSigner should succeed`) {
+		return
+	}
+	signature, err = defaultSigner.Sign(payload, key)
+	if !assert.NoError(t, err, `signer.Sign should succeed`) {
+		return
+	}
+	keyBytes := (key.Curve.Params().BitSize + 7) / 8
+	if !assert.Len(t, signature, 2*keyBytes, `P1363 signature should have a fixed length`) {
+		return
+	}
+	if !assert.NoError(t, p1363Verifier.Verify(payload, signature, &key.PublicKey), `verifier.Verify should succeed`) {
+		return
+	}
+
+	_, err = jws.NewECDSASigner(jwa.HS256, nil)
+	if !assert.Error(t, err, `jws.NewECDSASigner should fail for non-ECDSA algorithms`) {
+		return
+	}
+}
+
+func TestSplitJoinCompact(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("abracadabra")
+	signed, err := jws.Sign([]byte("Lorem ipsum"), jwa.HS256, key)
+	if !assert.NoError(t, err, `jws.Sign should succeed`) {
+		return
+	}
+
+	t.Run("Round trip", func(t *testing.T) {
+		t.Parallel()
+		for _, method := range []int{0, 1, 2} {
+			var protected, payload, signature []byte
+			var err error
+			switch method {
+			case 0:
+				protected, payload, signature, err = jws.SplitCompact(signed)
+			case 1:
+				protected, payload, signature, err = jws.SplitCompactString(string(signed) + "\n")
+			default:
+				protected, payload, signature, err = jws.SplitCompactReader(bufio.NewReader(bytes.NewReader(signed)))
+			}
+			if !assert.NoError(t, err, `SplitCompact should succeed`) {
+				return
+			}
+
+			joined, err := jws.JoinCompact(protected, payload, signature)
+			if !assert.NoError(t, err, `jws.JoinCompact should succeed`) {
+				return
+			}
+			if !assert.Equal(t, signed, joined, `joined message should match`) {
+				return
+			}
+		}
+
+		protected, payload, signature, err := jws.SplitCompactString(string(signed))
+		if !assert.NoError(t, err, `jws.SplitCompactString should succeed`) {
+			return
+		}
+		joined, err := jws.JoinCompactString(string(protected), string(payload), string(signature))
+		if !assert.NoError(t, err, `jws.JoinCompactString should succeed`) {
+			return
+		}
+		if !assert.Equal(t, string(signed), joined, `joined message should match`) {
+			return
+		}
+	})
+	t.Run("Invalid input", func(t *testing.T) {
+		t.Parallel()
+		protected, payload, signature, err := jws.SplitCompact(signed)
+		if !assert.NoError(t, err, `jws.SplitCompact should succeed`) {
+			return
+		}
+
+		testcases := []struct {
+			Name  string
+			Input string
+		}{
+			{Name: "Two segments", Input: string(protected) + "." + string(payload)},
+			{Name: "Four segments", Input: string(signed) + ".AAAA"},
+			{Name: "Empty protected headers", Input: "." + string(payload) + "." + string(signature)},
+			{Name: "Padding", Input: string(protected) + "=." + string(payload) + "." + string(signature)},
+			{Name: "Standard base64 alphabet", Input: string(protected) + "." + string(payload) + "+/." + string(signature)},
+			{Name: "Embedded whitespace", Input: string(protected) + ".\n" + string(payload) + "." + string(signature)},
+		}
+		for _, tc := range testcases {
+			tc := tc
+			t.Run(tc.Name, func(t *testing.T) {
+				t.Parallel()
+				if _, _, _, err := jws.SplitCompactString(tc.Input); !assert.Error(t, err, `jws.SplitCompactString should fail`) {
+					return
+				}
+				if _, _, _, err := jws.SplitCompactReader(strings.NewReader(tc.Input)); !assert.Error(t, err, `jws.SplitCompactReader should fail`) {
+					return
+				}
+			})
+		}
+
+		if _, err := jws.JoinCompact(protected, []byte("a.b"), signature); !assert.Error(t, err, `jws.JoinCompact should fail`) {
+			return
+		}
+		if _, err := jws.JoinCompact(nil, payload, signature); !assert.Error(t, err, `jws.JoinCompact should fail`) {
+			return
+		}
+	})
+}
+
+func TestAttestCheck(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := jwxtest.GenerateRsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`) {
+		return
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		return
+	}
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if !assert.NoError(t, err, `ed25519.GenerateKey should succeed`) {
+		return
+	}
+	hmacKey := make([]byte, 32)
+	if _, err := rand.Read(hmacKey); !assert.NoError(t, err, `rand.Read should succeed`) {
+		return
+	}
+	ecJwk, err := jwk.New(ecKey)
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+
+	testcases := []struct {
+		Name    string
+		Private interface{}
+		Public  interface{}
+		Alg     jwa.SignatureAlgorithm
+	}{
+		{Name: "RSA", Private: rsaKey, Public: &rsaKey.PublicKey, Alg: jwa.PS256},
+		{Name: "ECDSA", Private: ecKey, Public: &ecKey.PublicKey, Alg: jwa.ES384},
+		{Name: "Ed25519", Private: edPriv, Public: edPub, Alg: jwa.EdDSA},
+		{Name: "HMAC", Private: hmacKey, Public: hmacKey, Alg: jwa.HS256},
+		{Name: "jwk.Key", Private: ecJwk, Public: ecKey.PublicKey, Alg: jwa.ES384},
+	}
+
+	payload := []byte("Lorem ipsum")
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			signed, err := jws.Attest(payload, tc.Private)
+			if !assert.NoError(t, err, `jws.Attest should succeed`) {
+				return
+			}
+
+			msg, err := jws.Parse(signed)
+			if !assert.NoError(t, err, `jws.Parse should succeed`) {
+				return
+			}
+			if !assert.Equal(t, tc.Alg, msg.Signatures()[0].ProtectedHeaders().Algorithm(), `algorithm should match`) {
+				return
+			}
+
+			verified, err := jws.Check(signed, tc.Public)
+			if !assert.NoError(t, err, `jws.Check should succeed`) {
+				return
+			}
+			if !assert.Equal(t, payload, verified, `payload should match`) {
+				return
+			}
+		})
+	}
+
+	t.Run("Unexpected algorithm", func(t *testing.T) {
+		t.Parallel()
+		signed, err := jws.Sign(payload, jwa.RS256, rsaKey)
+		if !assert.NoError(t, err, `jws.Sign should succeed`) {
+			return
+		}
+		_, err = jws.Check(signed, &rsaKey.PublicKey)
+		if !assert.Error(t, err, `jws.Check should fail`) {
+			return
+		}
+	})
+	t.Run("Weak keys", func(t *testing.T) {
+		t.Parallel()
+		_, err := jws.Attest(payload, []byte("abracadabra"))
+		if !assert.Error(t, err, `jws.Attest should fail for short symmetric keys`) {
+			return
+		}
+		_, err = jws.Attest(payload, &rsaKey.PublicKey)
+		if !assert.Error(t, err, `jws.Attest should fail for public keys`) {
+			return
+		}
+	})
+}
+
+func TestNormalizationReport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Pristine", func(t *testing.T) {
+		t.Parallel()
+		var report jws.NormalizationReport
+		_, err := jws.ParseString(exampleCompactSerialization, jws.WithNormalizationReport(&report))
+		if !assert.NoError(t, err, `jws.ParseString should succeed`) {
+			return
+		}
+		if !assert.True(t, report.Pristine(), `report should be empty`) {
+			return
+		}
+	})
+	t.Run("Whitespace", func(t *testing.T) {
+		t.Parallel()
+		var report jws.NormalizationReport
+		_, err := jws.ParseReader(strings.NewReader("\n"+exampleCompactSerialization+"\r\n"), jws.WithNormalizationReport(&report))
+		if !assert.NoError(t, err, `jws.ParseReader should succeed`) {
+			return
+		}
+		normalizations := report.Normalizations()
+		if !assert.Len(t, normalizations, 1, `there should be 1 normalization`) {
+			return
+		}
+		if !assert.Equal(t, jws.NormalizedWhitespace, normalizations[0].Kind, `kind should match`) {
+			return
+		}
+
+		// the report is reset when it is reused
+		_, err = jws.ParseString(exampleCompactSerialization, jws.WithNormalizationReport(&report))
+		if !assert.NoError(t, err, `jws.ParseString should succeed`) {
+			return
+		}
+		if !assert.True(t, report.Pristine(), `report should be empty`) {
+			return
+		}
+	})
+	t.Run("JSON", func(t *testing.T) {
+		t.Parallel()
+		const src = `{"payload":"TG9yZW0gaXBzdW0=","protected":"eyJhbGciOiJoczI1NiJ9","signature":"+/8="}`
+		var report jws.NormalizationReport
+		msg, err := jws.ParseString(src, jws.WithNormalizationReport(&report))
+		if !assert.NoError(t, err, `jws.ParseString should succeed`) {
+			return
+		}
+		if !assert.Equal(t, jwa.SignatureAlgorithm("hs256"), msg.Signatures()[0].ProtectedHeaders().Algorithm(), `algorithm should not be modified`) {
+			return
+		}
+
+		var found []string
+		for _, n := range report.Normalizations() {
+			found = append(found, n.Kind+" "+n.Field)
+		}
+		expected := []string{
+			jws.NormalizedPadding + " payload",
+			jws.NormalizedAlgorithm + " protected.alg",
+			jws.NormalizedPadding + " signature",
+			jws.NormalizedAlphabet + " signature",
+		}
+		if !assert.Equal(t, expected, found, `normalizations should match`) {
+			return
+		}
+	})
+}
+
+func TestVerificationCache(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwxtest.GenerateEcdsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`) {
+		return
+	}
+	other, err := jwxtest.GenerateEcdsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`) {
+		return
+	}
+
+	payload := []byte(`{"event":"delivered"}`)
+	signed, err := jws.Sign(payload, jwa.ES256, key)
+	if !assert.NoError(t, err, `jws.Sign should succeed`) {
+		return
+	}
+
+	t.Run("Hits and misses", func(t *testing.T) {
+		t.Parallel()
+		cache := jws.NewVerificationCache(10, time.Minute)
+		for i := 0; i < 3; i++ {
+			verified, err := jws.Verify(signed, jwa.ES256, &key.PublicKey, jws.WithVerificationCache(cache))
+			if !assert.NoError(t, err, `jws.Verify should succeed`) {
+				return
+			}
+			if !assert.Equal(t, payload, verified, `payload should match`) {
+				return
+			}
+		}
+
+		// The cache is keyed by the key material, so an equivalent jwk.Key
+		// hits the same entry
+		pubkey, err := jwk.New(&key.PublicKey)
+		if !assert.NoError(t, err, `jwk.New should succeed`) {
+			return
+		}
+		_, err = jws.Verify(signed, jwa.ES256, pubkey, jws.WithVerificationCache(cache))
+		if !assert.NoError(t, err, `jws.Verify should succeed`) {
+			return
+		}
+
+		_, err = jws.Verify(signed, jwa.ES256, &other.PublicKey, jws.WithVerificationCache(cache))
+		if !assert.Error(t, err, `jws.Verify with another key should fail`) {
+			return
+		}
+
+		stats := cache.Stats()
+		if !assert.Equal(t, uint64(3), stats.Hits, `hits should match`) {
+			return
+		}
+		if !assert.Equal(t, uint64(2), stats.Misses, `misses should match`) {
+			return
+		}
+		if !assert.Equal(t, 0.6, stats.HitRate(), `hit rate should match`) {
+			return
+		}
+		if !assert.Equal(t, 1, cache.Len(), `only successful verifications should be cached`) {
+			return
+		}
+	})
+	t.Run("Retain", func(t *testing.T) {
+		t.Parallel()
+		cache := jws.NewVerificationCache(10, time.Minute)
+
+		pubkey, err := jwk.New(&key.PublicKey)
+		if !assert.NoError(t, err, `jwk.New should succeed`) {
+			return
+		}
+		_ = pubkey.Set(jwk.AlgorithmKey, jwa.ES256)
+		set := jwk.NewSet()
+		set.Add(pubkey)
+
+		_, err = jws.VerifySet(signed, set, jws.WithVerificationCache(cache))
+		if !assert.NoError(t, err, `jws.VerifySet should succeed`) {
+			return
+		}
+
+		if !assert.NoError(t, cache.Retain(set), `cache.Retain should succeed`) {
+			return
+		}
+		if !assert.Equal(t, 1, cache.Len(), `entries for keys in the set should be retained`) {
+			return
+		}
+
+		// The key set was refreshed, and the key was rotated out
+		otherkey, err := jwk.New(&other.PublicKey)
+		if !assert.NoError(t, err, `jwk.New should succeed`) {
+			return
+		}
+		refreshed := jwk.NewSet()
+		refreshed.Add(otherkey)
+		if !assert.NoError(t, cache.Retain(refreshed), `cache.Retain should succeed`) {
+			return
+		}
+		if !assert.Equal(t, 0, cache.Len(), `entries for removed keys should be discarded`) {
+			return
+		}
+		if !assert.Equal(t, uint64(1), cache.Stats().Invalidations, `invalidations should match`) {
+			return
+		}
+	})
+	t.Run("InvalidateOnChange", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cache := jws.NewVerificationCache(10, time.Minute)
+
+		pubkey, err := jwk.New(&key.PublicKey)
+		if !assert.NoError(t, err, `jwk.New should succeed`) {
+			return
+		}
+		_ = pubkey.Set(jwk.AlgorithmKey, jwa.ES256)
+		set := jwk.NewSet()
+		set.Add(pubkey)
+		cache.InvalidateOnChange(ctx, set)
+
+		_, err = jws.VerifySet(signed, set, jws.WithVerificationCache(cache))
+		if !assert.NoError(t, err, `jws.VerifySet should succeed`) {
+			return
+		}
+		if !assert.Equal(t, 1, cache.Len(), `cache should contain one entry`) {
+			return
+		}
+
+		// The key is rotated out of the set
+		set.Remove(pubkey)
+		if !assert.Eventually(t, func() bool { return cache.Len() == 0 }, time.Second, 10*time.Millisecond, `entries for removed keys should be discarded`) {
+			return
+		}
+		if !assert.Equal(t, uint64(1), cache.Stats().Invalidations, `invalidations should match`) {
+			return
+		}
+	})
+	t.Run("InvalidateOnChange with dropped notifications", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cache := jws.NewVerificationCache(10, time.Minute)
+		_, err := jws.Verify(signed, jwa.ES256, &key.PublicKey, jws.WithVerificationCache(cache))
+		if !assert.NoError(t, err, `jws.Verify should succeed`) {
+			return
+		}
+
+		pubkey, err := jwk.New(&key.PublicKey)
+		if !assert.NoError(t, err, `jwk.New should succeed`) {
+			return
+		}
+		other, err := jwxtest.GenerateSymmetricJwk()
+		if !assert.NoError(t, err, `jwxtest.GenerateSymmetricJwk should succeed`) {
+			return
+		}
+		// The removal of the key is lost because the channel is full
+		cache.InvalidateOnChange(ctx, &overflowingNotifier{
+			filler:  jwk.SetChange{Added: []jwk.Key{other}},
+			dropped: jwk.SetChange{Removed: []jwk.Key{pubkey}},
+		})
+		if !assert.Eventually(t, func() bool { return cache.Len() == 0 }, time.Second, 10*time.Millisecond, `cache should be cleared`) {
+			return
+		}
+	})
+	t.Run("Key attestation policy", func(t *testing.T) {
+		t.Parallel()
+		cache := jws.NewVerificationCache(10, time.Minute)
+		policy := jws.KeyAttestationPolicyFunc(func(*jws.KeyAttestation) error {
+			return nil
+		})
+		_, err := jws.Verify(signed, jwa.ES256, &key.PublicKey, jws.WithVerificationCache(cache), jws.WithKeyAttestationPolicy(policy))
+		if !assert.Error(t, err, `jws.Verify should fail`) {
+			return
+		}
+	})
+	t.Run("Size limit", func(t *testing.T) {
+		t.Parallel()
+		cache := jws.NewVerificationCache(1, 0)
+		for _, s := range []string{`{"n":1}`, `{"n":2}`} {
+			signed, err := jws.Sign([]byte(s), jwa.ES256, key)
+			if !assert.NoError(t, err, `jws.Sign should succeed`) {
+				return
+			}
+			_, err = jws.Verify(signed, jwa.ES256, &key.PublicKey, jws.WithVerificationCache(cache))
+			if !assert.NoError(t, err, `jws.Verify should succeed`) {
+				return
+			}
+		}
+		if !assert.Equal(t, 1, cache.Len(), `cache should contain one entry`) {
+			return
+		}
+		if !assert.Equal(t, uint64(1), cache.Stats().Evictions, `evictions should match`) {
+			return
+		}
+	})
+}
+
+func TestKeyProviderForSigning(t *testing.T) {
+	t.Parallel()
+
+	var keys []*ecdsa.PrivateKey
+	for i := 0; i < 2; i++ {
+		key, err := jwxtest.GenerateEcdsaKey()
+		if !assert.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`) {
+			return
+		}
+		keys = append(keys, key)
+	}
+
+	var current int
+	provider := jws.SigningKeyProviderFunc(func() (interface{}, string, error) {
+		return keys[current], fmt.Sprintf("key-%d", current), nil
+	})
+
+	template, err := jws.NewHeaderTemplate(jws.NewHeaders())
+	if !assert.NoError(t, err, `jws.NewHeaderTemplate should succeed`) {
+		return
+	}
+
+	for _, options := range [][]jws.Option{
+		{jws.WithKeyProviderForSigning(provider)},
+		{jws.WithKeyProviderForSigning(provider), jws.WithHeaderTemplate(template)},
+	} {
+		for i := range keys {
+			current = i
+			signed, err := jws.Sign([]byte(`Lorem ipsum`), jwa.ES256, nil, options...)
+			if !assert.NoError(t, err, `jws.Sign should succeed`) {
+				return
+			}
+
+			msg, err := jws.Parse(signed)
+			if !assert.NoError(t, err, `jws.Parse should succeed`) {
+				return
+			}
+			if !assert.Equal(t, fmt.Sprintf("key-%d", i), msg.Signatures()[0].ProtectedHeaders().KeyID(), `"kid" should match`) {
+				return
+			}
+
+			_, err = jws.Verify(signed, jwa.ES256, &keys[i].PublicKey)
+			if !assert.NoError(t, err, `jws.Verify should succeed`) {
+				return
+			}
+			_, err = jws.Verify(signed, jwa.ES256, &keys[1-i].PublicKey)
+			if !assert.Error(t, err, `jws.Verify with the other key should fail`) {
+				return
+			}
+		}
+	}
+
+	t.Run("jwk.Key without explicit key ID", func(t *testing.T) {
+		t.Parallel()
+		key, err := jwk.New(keys[0])
+		if !assert.NoError(t, err, `jwk.New should succeed`) {
+			return
+		}
+		if !assert.NoError(t, key.Set(jwk.KeyIDKey, "from-jwk"), `key.Set should succeed`) {
+			return
+		}
+
+		signed, err := jws.Sign([]byte(`Lorem ipsum`), jwa.ES256, nil, jws.WithKeyProviderForSigning(jws.SigningKeyProviderFunc(func() (interface{}, string, error) {
+			return key, "", nil
+		})))
+		if !assert.NoError(t, err, `jws.Sign should succeed`) {
+			return
+		}
+		msg, err := jws.Parse(signed)
+		if !assert.NoError(t, err, `jws.Parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, "from-jwk", msg.Signatures()[0].ProtectedHeaders().KeyID(), `"kid" should match`) {
+			return
+		}
+	})
+	t.Run("Provider error", func(t *testing.T) {
+		t.Parallel()
+		_, err := jws.Sign([]byte(`Lorem ipsum`), jwa.ES256, keys[0], jws.WithKeyProviderForSigning(jws.SigningKeyProviderFunc(func() (interface{}, string, error) {
+			return nil, "", fmt.Errorf(`key is not available`)
+		})))
+		if !assert.Error(t, err, `jws.Sign should fail`) {
+			return
+		}
+		if !assert.Contains(t, err.Error(), `key is not available`, `error should contain the provider error`) {
+			return
+		}
+	})
+}
+
+func TestVerifyAsync(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwxtest.GenerateRsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`) {
+		return
+	}
+
+	signed, err := jws.Sign([]byte(examplePayload), jwa.RS256, key)
+	if !assert.NoError(t, err, `jws.Sign should succeed`) {
+		return
+	}
+
+	t.Run("Without executor", func(t *testing.T) {
+		t.Parallel()
+		payload, err := jws.VerifyAsync(context.Background(), signed, jwa.RS256, &key.PublicKey).Wait(context.Background())
+		if !assert.NoError(t, err, `Wait should succeed`) {
+			return
+		}
+		if !assert.Equal(t, examplePayload, string(payload), `payload should match`) {
+			return
+		}
+	})
+	t.Run("Worker pool", func(t *testing.T) {
+		t.Parallel()
+		pool := jws.NewWorkerPool(2, 4)
+		defer pool.Close()
+
+		futures := make([]*jws.VerifyFuture, 8)
+		for i := range futures {
+			verifyKey := &key.PublicKey
+			if i%2 == 1 {
+				verifyKey = &rsa.PublicKey{N: big.NewInt(0).Add(key.N, big.NewInt(2)), E: key.E}
+			}
+			futures[i] = jws.VerifyAsync(context.Background(), signed, jwa.RS256, verifyKey, jws.WithExecutor(pool))
+		}
+
+		for i, f := range futures {
+			<-f.Done()
+			payload, err := f.Wait(context.Background())
+			if i%2 == 1 {
+				if !assert.Error(t, err, `verification with the wrong key should fail`) {
+					return
+				}
+				continue
+			}
+			if !assert.NoError(t, err, `verification should succeed`) {
+				return
+			}
+			if !assert.Equal(t, examplePayload, string(payload), `payload should match`) {
+				return
+			}
+		}
+	})
+	t.Run("Backpressure", func(t *testing.T) {
+		t.Parallel()
+		pool := jws.NewWorkerPool(1, 0)
+		defer pool.Close()
+
+		// Occupy the only worker
+		block := make(chan struct{})
+		if !assert.NoError(t, pool.Execute(context.Background(), func() { <-block }), `Execute should succeed`) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := jws.VerifyAsync(ctx, signed, jwa.RS256, &key.PublicKey, jws.WithExecutor(pool)).Wait(context.Background())
+		close(block)
+		if !assert.Error(t, err, `verification should not start while the pool is busy`) {
+			return
+		}
+	})
+	t.Run("Closed pool", func(t *testing.T) {
+		t.Parallel()
+		pool := jws.NewWorkerPool(1, 1)
+		pool.Close()
+		_, err := jws.VerifyAsync(context.Background(), signed, jwa.RS256, &key.PublicKey, jws.WithExecutor(pool)).Wait(context.Background())
+		if !assert.Error(t, err, `verification should fail on a closed pool`) {
+			return
+		}
+	})
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("canonical-json-golden-test-secret")
+	signer, err := jws.NewSigner(jwa.HS256)
+	if !assert.NoError(t, err, `jws.NewSigner should succeed`) {
+		return
+	}
+
+	// The same headers, set in different orders and with nested objects
+	// whose members are in different orders
+	makeHeaders := func(reverse bool) (jws.Headers, jws.Headers) {
+		protected := jws.NewHeaders()
+		public := jws.NewHeaders()
+		nested := map[string]interface{}{"z": "<&>", "a": []interface{}{1, "b"}}
+		if reverse {
+			protected.Set("ext", nested)
+			protected.Set(jws.KeyIDKey, "golden")
+		} else {
+			protected.Set(jws.KeyIDKey, "golden")
+			protected.Set("ext", json.RawMessage(`{ "a" : [1, "b"], "z" : "<&>" }`))
+		}
+		public.Set("note", map[string]interface{}{"y": true, "x": nil})
+		return protected, public
+	}
+
+	sign := func(reverse bool, withPublic bool) ([]byte, error) {
+		protected, public := makeHeaders(reverse)
+		if !withPublic {
+			public = nil
+		}
+		return jws.SignMulti([]byte(examplePayload),
+			jws.WithSigner(signer, key, public, protected),
+			jws.WithCanonicalJSON(true),
+		)
+	}
+
+	t.Run("Byte-stable output", func(t *testing.T) {
+		t.Parallel()
+		first, err := sign(false, true)
+		if !assert.NoError(t, err, `jws.SignMulti should succeed`) {
+			return
+		}
+		second, err := sign(true, true)
+		if !assert.NoError(t, err, `jws.SignMulti should succeed`) {
+			return
+		}
+		if !assert.Equal(t, string(first), string(second), `output should not depend on the order of the headers`) {
+			return
+		}
+
+		canonical, err := json.Canonicalize(first)
+		if !assert.NoError(t, err, `json.Canonicalize should succeed`) {
+			return
+		}
+		if !assert.Equal(t, string(canonical), string(first), `output should be canonical`) {
+			return
+		}
+		if !assert.Contains(t, string(first), `"header":{"note":{"x":null,"y":true}}`, `public headers should be canonical`) {
+			return
+		}
+	})
+	t.Run("Canonical protected headers", func(t *testing.T) {
+		t.Parallel()
+		signed, err := sign(false, false)
+		if !assert.NoError(t, err, `jws.SignMulti should succeed`) {
+			return
+		}
+
+		// A single signature is emitted in the flattened serialization
+		var m struct {
+			Protected string `json:"protected"`
+		}
+		if !assert.NoError(t, json.Unmarshal(signed, &m), `json.Unmarshal should succeed`) {
+			return
+		}
+		protected, err := base64.DecodeString(m.Protected)
+		if !assert.NoError(t, err, `base64.DecodeString should succeed`) {
+			return
+		}
+		if !assert.Contains(t, string(protected), `"ext":{"a":[1,"b"],"z":`, `nested objects should be sorted and compact`) {
+			return
+		}
+
+		payload, err := jws.Verify(signed, jwa.HS256, key)
+		if !assert.NoError(t, err, `jws.Verify should succeed`) {
+			return
+		}
+		if !assert.Equal(t, examplePayload, string(payload), `payload should match`) {
+			return
+		}
+	})
+}
+
+func TestKeyAttestation(t *testing.T) {
+	t.Parallel()
+
+	caKey, err := jwxtest.GenerateEcdsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`) {
+		return
+	}
+	signingKey, err := jwxtest.GenerateEcdsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`) {
+		return
+	}
+	otherKey, err := jwxtest.GenerateEcdsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`) {
+		return
+	}
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "HSM Vendor Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if !assert.NoError(t, err, `x509.CreateCertificate should succeed`) {
+		return
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if !assert.NoError(t, err, `x509.ParseCertificate should succeed`) {
+		return
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "HSM Key"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, caCert, &signingKey.PublicKey, caKey)
+	if !assert.NoError(t, err, `x509.CreateCertificate should succeed`) {
+		return
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+
+	hdrs := jws.NewHeaders()
+	hdrs.Set(jws.X509CertChainKey, []string{base64.EncodeToStringStd(leafDER)})
+
+	const evidence = "eyJhbGciOiJFUzI1NiJ9.eyJrZXlfc3RvcmFnZSI6ImhzbSJ9.c2ln"
+	signed, err := jws.Sign([]byte("payload"), jwa.ES256, signingKey, jws.WithHeaders(hdrs), jws.WithKeyAttestation([]byte(evidence)))
+	if !assert.NoError(t, err, `jws.Sign should succeed`) {
+		return
+	}
+	if _, ok := hdrs.Get(jws.KeyAttestationKey); !assert.False(t, ok, `headers should not be modified`) {
+		return
+	}
+
+	t.Run("Policy callback", func(t *testing.T) {
+		t.Parallel()
+		var attestation *jws.KeyAttestation
+		policy := jws.KeyAttestationPolicyFunc(func(a *jws.KeyAttestation) error {
+			attestation = a
+			return nil
+		})
+		payload, err := jws.Verify(signed, jwa.ES256, &signingKey.PublicKey, jws.WithKeyAttestationPolicy(policy))
+		if !assert.NoError(t, err, `jws.Verify should succeed`) {
+			return
+		}
+		if !assert.Equal(t, []byte("payload"), payload, `payload should match`) {
+			return
+		}
+		if !assert.NotNil(t, attestation, `policy should be called`) {
+			return
+		}
+		if !assert.Equal(t, []byte(evidence), attestation.Evidence, `evidence should match`) {
+			return
+		}
+		if !assert.Len(t, attestation.CertificateChain, 1, `certificate chain should be parsed`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ES256, attestation.Algorithm, `algorithm should match`) {
+			return
+		}
+
+		_, err = jws.Verify(signed, jwa.ES256, &signingKey.PublicKey, jws.WithKeyAttestationPolicy(jws.KeyAttestationPolicyFunc(func(*jws.KeyAttestation) error {
+			return fmt.Errorf(`rejected`)
+		})))
+		if !assert.Error(t, err, `jws.Verify should fail when the policy rejects the evidence`) {
+			return
+		}
+	})
+	t.Run("Policy is not called for invalid signatures", func(t *testing.T) {
+		t.Parallel()
+		var called bool
+		policy := jws.KeyAttestationPolicyFunc(func(*jws.KeyAttestation) error {
+			called = true
+			return nil
+		})
+		_, err := jws.Verify(signed, jwa.ES256, &otherKey.PublicKey, jws.WithKeyAttestationPolicy(policy))
+		if !assert.Error(t, err, `jws.Verify should fail`) {
+			return
+		}
+		if !assert.False(t, called, `policy should not be called`) {
+			return
+		}
+	})
+	t.Run("CertificateChainPolicy", func(t *testing.T) {
+		t.Parallel()
+		_, err := jws.Verify(signed, jwa.ES256, &signingKey.PublicKey, jws.WithKeyAttestationPolicy(jws.CertificateChainPolicy(roots)))
+		if !assert.NoError(t, err, `jws.Verify should succeed`) {
+			return
+		}
+
+		_, err = jws.Verify(signed, jwa.ES256, &signingKey.PublicKey, jws.WithKeyAttestationPolicy(jws.CertificateChainPolicy(x509.NewCertPool())))
+		if !assert.Error(t, err, `jws.Verify should fail for unknown roots`) {
+			return
+		}
+
+		// The chain is valid, but it certifies another key
+		forged := jws.NewHeaders()
+		forged.Set(jws.X509CertChainKey, []string{base64.EncodeToStringStd(leafDER)})
+		other, err := jws.Sign([]byte("payload"), jwa.ES256, otherKey, jws.WithHeaders(forged))
+		if !assert.NoError(t, err, `jws.Sign should succeed`) {
+			return
+		}
+		_, err = jws.Verify(other, jwa.ES256, &otherKey.PublicKey, jws.WithKeyAttestationPolicy(jws.CertificateChainPolicy(roots)))
+		if !assert.Error(t, err, `jws.Verify should fail when the certificate does not certify the key`) {
+			return
+		}
+
+		plain, err := jws.Sign([]byte("payload"), jwa.ES256, signingKey)
+		if !assert.NoError(t, err, `jws.Sign should succeed`) {
+			return
+		}
+		_, err = jws.Verify(plain, jwa.ES256, &signingKey.PublicKey, jws.WithKeyAttestationPolicy(jws.CertificateChainPolicy(roots)))
+		if !assert.Error(t, err, `jws.Verify should fail without x5c`) {
+			return
+		}
+	})
+}
+
+func TestWithMaxMessageSize(t *testing.T) {
+	t.Parallel()
+	key := []byte(`abracadabra`)
+	signed, err := jws.Sign([]byte(examplePayload), jwa.HS256, key)
+	if !assert.NoError(t, err, `jws.Sign should succeed`) {
+		return
+	}
+
+	size := int64(len(signed))
+	_, err = jws.Parse(signed, jws.WithMaxMessageSize(size-1))
+	if !assert.Error(t, err, `jws.Parse should fail`) {
+		return
+	}
+	_, err = jws.ParseString(string(signed), jws.WithMaxMessageSize(size-1))
+	if !assert.Error(t, err, `jws.ParseString should fail`) {
+		return
+	}
+	_, err = jws.ParseReader(ioutil.NopCloser(bytes.NewReader(signed)), jws.WithMaxMessageSize(size-1))
+	if !assert.Error(t, err, `jws.ParseReader should fail`) {
+		return
+	}
+	_, err = jws.Verify(signed, jwa.HS256, key, jws.WithMaxMessageSize(size-1))
+	if !assert.Error(t, err, `jws.Verify should fail`) {
+		return
+	}
+
+	m, err := jws.ParseReader(ioutil.NopCloser(bytes.NewReader(signed)), jws.WithMaxMessageSize(size))
+	if !assert.NoError(t, err, `jws.ParseReader should succeed`) {
+		return
+	}
+	if !assert.Equal(t, []byte(examplePayload), m.Payload(), `payload should match`) {
+		return
+	}
+	payload, err := jws.Verify(signed, jwa.HS256, key, jws.WithMaxMessageSize(size))
+	if !assert.NoError(t, err, `jws.Verify should succeed`) {
+		return
+	}
+	if !assert.Equal(t, []byte(examplePayload), payload, `payload should match`) {
+		return
+	}
+
+	// The limit applies regardless of the order of the options
+	var report jws.NormalizationReport
+	src := &countingReader{rdr: io.MultiReader(bytes.NewReader(signed), strings.NewReader(strings.Repeat(`A`, 1<<20)))}
+	_, err = jws.ParseReader(src, jws.WithNormalizationReport(&report), jws.WithMaxMessageSize(size))
+	if !assert.Error(t, err, `jws.ParseReader should fail`) {
+		return
+	}
+	if !assert.True(t, src.n <= size+1, `jws.ParseReader should not read past the limit (read %d bytes)`, src.n) {
+		return
+	}
+}
+
+type countingReader struct {
+	rdr io.Reader
+	n   int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.rdr.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+type testLogEvent struct {
+	level   string
+	msg     string
+	keyvals map[string]interface{}
+}
+
+type testLogger struct {
+	events []testLogEvent
+}
+
+func (l *testLogger) log(level, msg string, keyvals []interface{}) {
+	m := make(map[string]interface{})
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		m[keyvals[i].(string)] = keyvals[i+1]
+	}
+	l.events = append(l.events, testLogEvent{level: level, msg: msg, keyvals: m})
+}
+
+func (l *testLogger) Debug(msg string, keyvals ...interface{}) { l.log("debug", msg, keyvals) }
+func (l *testLogger) Warn(msg string, keyvals ...interface{})  { l.log("warn", msg, keyvals) }
+
+func (l *testLogger) find(msg string) (testLogEvent, bool) {
+	for _, ev := range l.events {
+		if ev.msg == msg {
+			return ev, true
+		}
+	}
+	return testLogEvent{}, false
+}
+
+func TestWithLogger(t *testing.T) {
+	t.Parallel()
+
+	secret := jwxtest.GenerateSymmetricKey()
+	key, err := jwk.New(secret)
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+	_ = key.Set(jwk.KeyIDKey, `my-key`)
+
+	// checkNoSecrets makes sure that the key is never logged
+	checkNoSecrets := func(t *testing.T, logger *testLogger) bool {
+		for _, ev := range logger.events {
+			s := fmt.Sprintf("%s %v", ev.msg, ev.keyvals)
+			if !assert.NotContains(t, s, base64.EncodeToString(secret), `events should not contain the key`) {
+				return false
+			}
+			if !assert.NotContains(t, s, string(secret), `events should not contain the key`) {
+				return false
+			}
+		}
+		return true
+	}
+
+	logger := &testLogger{}
+	signed, err := jws.Sign([]byte(`Lorem ipsum`), jwa.HS256, key, jws.WithLogger(logger))
+	if !assert.NoError(t, err, `jws.Sign should succeed`) {
+		return
+	}
+	ev, ok := logger.find(`jws: signed payload`)
+	if !assert.True(t, ok, `signing should be logged`) {
+		return
+	}
+	if !assert.Equal(t, map[string]interface{}{"alg": "HS256", "key_type": "oct", "kid": "my-key", "template": false}, ev.keyvals, `event should describe the key`) {
+		return
+	}
+
+	t.Run("Successful verification", func(t *testing.T) {
+		t.Parallel()
+		logger := &testLogger{}
+		if _, err := jws.Verify(signed, jwa.HS256, key, jws.WithLogger(logger)); !assert.NoError(t, err, `jws.Verify should succeed`) {
+			return
+		}
+		ev, ok := logger.find(`jws: verified message`)
+		if !assert.True(t, ok, `verification should be logged`) {
+			return
+		}
+		if !assert.Equal(t, "debug", ev.level, `success should be logged at debug level`) {
+			return
+		}
+		checkNoSecrets(t, logger)
+	})
+	t.Run("Algorithm mismatch", func(t *testing.T) {
+		t.Parallel()
+		logger := &testLogger{}
+		if _, err := jws.Verify(signed, jwa.HS384, key, jws.WithLogger(logger)); !assert.Error(t, err, `jws.Verify should fail`) {
+			return
+		}
+		ev, ok := logger.find(`jws: algorithm in header differs from verification algorithm`)
+		if !assert.True(t, ok, `algorithm mismatch should be logged`) {
+			return
+		}
+		if !assert.Equal(t, "HS256", ev.keyvals["header_alg"], `header algorithm should be logged`) {
+			return
+		}
+		ev, ok = logger.find(`jws: verification failed`)
+		if !assert.True(t, ok, `failure should be logged`) {
+			return
+		}
+		if !assert.Equal(t, "warn", ev.level, `failure should be logged at warn level`) {
+			return
+		}
+		if !assert.Error(t, ev.keyvals["error"].(error), `failure reason should be logged`) {
+			return
+		}
+		checkNoSecrets(t, logger)
+	})
+	t.Run("Key ID mismatch", func(t *testing.T) {
+		t.Parallel()
+		other, err := jwk.New(secret)
+		if !assert.NoError(t, err, `jwk.New should succeed`) {
+			return
+		}
+		_ = other.Set(jwk.KeyIDKey, `other-key`)
+
+		logger := &testLogger{}
+		if _, err := jws.Verify(signed, jwa.HS256, other, jws.WithLogger(logger)); !assert.Error(t, err, `jws.Verify should fail`) {
+			return
+		}
+		ev, ok := logger.find(`jws: key ID does not match`)
+		if !assert.True(t, ok, `key ID mismatch should be logged`) {
+			return
+		}
+		if !assert.Equal(t, map[string]interface{}{"header_kid": "my-key", "kid": "other-key"}, ev.keyvals, `key IDs should be logged`) {
+			return
+		}
+		checkNoSecrets(t, logger)
+	})
+	t.Run("VerifySet", func(t *testing.T) {
+		t.Parallel()
+		set := jwk.NewSet()
+		set.Add(key) // no "alg"
+
+		logger := &testLogger{}
+		if _, err := jws.VerifySet(signed, set, jws.WithLogger(logger)); !assert.Error(t, err, `jws.VerifySet should fail`) {
+			return
+		}
+		if _, ok := logger.find(`jws: skipping key without algorithm`); !assert.True(t, ok, `skipped key should be logged`) {
+			return
+		}
+		checkNoSecrets(t, logger)
+	})
+}
+
+// overflowingNotifier fills the channel of the subscriber with `filler`,
+// so that `dropped` can not be delivered
+type overflowingNotifier struct {
+	filler  jwk.SetChange
+	dropped jwk.SetChange
+}
+
+func (n *overflowingNotifier) Subscribe(ch chan<- jwk.SetChange) {
+	for full := false; !full; {
+		select {
+		case ch <- n.filler:
+		default:
+			full = true
+		}
+	}
+
+	// Delivered the same way as jwk.Set does, which drops the change
+	select {
+	case ch <- n.dropped:
+	default:
+	}
+}
+
+func (n *overflowingNotifier) Unsubscribe(chan<- jwk.SetChange) {}
@@ -0,0 +1,190 @@
+package jws
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/option"
+	"github.com/pkg/errors"
+)
+
+// KeyAttestationKey is the name of the protected header that carries the
+// key attestation evidence attached by `jws.WithKeyAttestation()`
+const KeyAttestationKey = "key_attestation"
+
+// KeyAttestation describes the evidence associated with a signature that
+// has been verified, which is passed to a KeyAttestationPolicy.
+// Only the protected headers are considered, as they are covered by the
+// signature.
+type KeyAttestation struct {
+	// Algorithm is the algorithm that the signature was verified with
+	Algorithm jwa.SignatureAlgorithm
+	// Key is the key that the signature was verified with
+	Key interface{}
+	// Headers are the protected headers of the signature
+	Headers Headers
+	// Evidence is the value of the "key_attestation" header (e.g. an
+	// attestation JWT issued by the vendor of the HSM), or nil if the
+	// header is not present
+	Evidence []byte
+	// CertificateChain is the certificate chain in the "x5c" header, or
+	// nil if the header is not present. The chain is NOT verified.
+	CertificateChain []*x509.Certificate
+}
+
+// KeyAttestationPolicy decides whether the key that a signature was
+// verified with is acceptable, based on the attestation evidence
+// associated with the signature. See `jws.WithKeyAttestationPolicy()`.
+//
+// Accept returns an error if the evidence is missing or insufficient.
+type KeyAttestationPolicy interface {
+	Accept(*KeyAttestation) error
+}
+
+// KeyAttestationPolicyFunc is a KeyAttestationPolicy represented by a
+// function
+type KeyAttestationPolicyFunc func(*KeyAttestation) error
+
+func (f KeyAttestationPolicyFunc) Accept(a *KeyAttestation) error {
+	return f(a)
+}
+
+// WithKeyAttestation is passed to `jws.Sign()` to attach key attestation
+// evidence (e.g. an attestation JWT proving that the signing key lives in
+// an HSM) to the signature, in the "key_attestation" protected header.
+// The headers given to `jws.WithHeaders()` are not modified.
+//
+// To attach a certificate chain instead, set the "x5c" header using
+// `jws.WithHeaders()`.
+func WithKeyAttestation(evidence []byte) Option {
+	return option.New(identKeyAttestation{}, evidence)
+}
+
+// WithKeyAttestationPolicy is passed to `jws.Verify()` and `jws.VerifySet()`
+// to check the key attestation evidence associated with the signature
+// after it has been verified. The message is rejected if the policy
+// returns an error.
+//
+// It can not be used together with `jws.WithVerificationCache()`, as
+// the decision of the policy may change over time (e.g. when certificates
+// expire): the verification fails if both options are specified.
+func WithKeyAttestationPolicy(p KeyAttestationPolicy) Option {
+	return option.New(identKeyAttestationPolicy{}, p)
+}
+
+// CertificateChainPolicy returns a KeyAttestationPolicy that requires the
+// signature to have an "x5c" header, whose certificate chain must be
+// valid for `roots` (e.g. the root certificates of HSM vendors) at the
+// time of verification, and whose leaf certificate must certify the key
+// that the signature was verified with.
+//
+// The key usages of the certificates are not checked.
+func CertificateChainPolicy(roots *x509.CertPool) KeyAttestationPolicy {
+	return KeyAttestationPolicyFunc(func(a *KeyAttestation) error {
+		if len(a.CertificateChain) == 0 {
+			return errors.Errorf(`%s header is required`, X509CertChainKey)
+		}
+
+		leaf := a.CertificateChain[0]
+		intermediates := x509.NewCertPool()
+		for _, cert := range a.CertificateChain[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := leaf.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   time.Now(),
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		if err != nil {
+			return errors.Wrap(err, `failed to verify certificate chain`)
+		}
+
+		expected, err := keyThumbprint(leaf.PublicKey)
+		if err != nil {
+			return errors.Wrap(err, `failed to compute thumbprint of certificate key`)
+		}
+		actual, err := keyThumbprint(a.Key)
+		if err != nil {
+			return errors.Wrap(err, `failed to compute thumbprint of verification key`)
+		}
+		if expected != actual {
+			return errors.New(`certificate does not certify the verification key`)
+		}
+		return nil
+	})
+}
+
+// checkKeyAttestation applies the policy to the signature with the
+// protected headers `hdr`, which has been verified using `alg` and `key`
+func checkKeyAttestation(policy KeyAttestationPolicy, alg jwa.SignatureAlgorithm, key interface{}, hdr Headers) error {
+	if hdr == nil {
+		hdr = NewHeaders()
+	}
+	a := &KeyAttestation{
+		Algorithm: alg,
+		Key:       key,
+		Headers:   hdr,
+	}
+
+	if v, ok := hdr.Get(KeyAttestationKey); ok {
+		s, ok := v.(string)
+		if !ok {
+			return errors.Errorf(`invalid type for %s header: %T`, KeyAttestationKey, v)
+		}
+		a.Evidence = []byte(s)
+	}
+
+	for i, encoded := range hdr.X509CertChain() {
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return errors.Wrapf(err, `failed to decode certificate #%d in %s header`, i+1, X509CertChainKey)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return errors.Wrapf(err, `failed to parse certificate #%d in %s header`, i+1, X509CertChainKey)
+		}
+		a.CertificateChain = append(a.CertificateChain, cert)
+	}
+
+	if err := policy.Accept(a); err != nil {
+		return errors.Wrap(err, `key attestation was not accepted`)
+	}
+	return nil
+}
+
+// withKeyAttestation returns a copy of `hdrs` with the "key_attestation"
+// header set to `evidence`
+func withKeyAttestation(hdrs Headers, evidence []byte) (Headers, error) {
+	dst := NewHeaders()
+	if hdrs != nil {
+		if err := hdrs.Copy(context.TODO(), dst); err != nil {
+			return nil, errors.Wrap(err, `failed to copy headers`)
+		}
+	}
+	if err := dst.Set(KeyAttestationKey, string(evidence)); err != nil {
+		return nil, errors.Wrapf(err, `failed to set %s header`, KeyAttestationKey)
+	}
+	return dst, nil
+}
+
+func keyThumbprint(key interface{}) (string, error) {
+	jwkKey, ok := key.(jwk.Key)
+	if !ok {
+		v, err := jwk.New(key)
+		if err != nil {
+			return "", errors.Wrap(err, `failed to create jwk.Key from raw key`)
+		}
+		jwkKey = v
+	}
+	tp, err := jwkKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", errors.Wrap(err, `failed to compute JWK thumbprint`)
+	}
+	return string(tp), nil
+}
@@ -0,0 +1,72 @@
+package jws
+
+import (
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+)
+
+// SigningKeyProvider returns the key that should be used to sign
+// payloads. It is consulted by `jws.Sign()` each time a payload is
+// signed when passed via `jws.WithKeyProviderForSigning()`, so that
+// long-lived processes can pick up rotated keys without re-creating
+// anything.
+//
+// `SigningKey()` returns the key, either a raw key or a jwk.Key, and
+// the key ID to be placed in the "kid" header. If the key ID is empty
+// and the key is a jwk.Key, the key ID of the jwk.Key is used as usual.
+type SigningKeyProvider interface {
+	SigningKey() (interface{}, string, error)
+}
+
+// SigningKeyProviderFunc is a SigningKeyProvider represented by a function
+type SigningKeyProviderFunc func() (interface{}, string, error)
+
+func (f SigningKeyProviderFunc) SigningKey() (interface{}, string, error) {
+	return f()
+}
+
+// keyFromProvider returns the key to be passed to the signer. When the
+// provider specifies a key ID that the key does not already carry, the key
+// is converted to a jwk.Key with the given key ID, so that it is included
+// in the protected headers.
+func keyFromProvider(p SigningKeyProvider) (interface{}, error) {
+	key, kid, err := p.SigningKey()
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to obtain signing key from provider`)
+	}
+	if key == nil {
+		return nil, errors.New(`signing key provider returned a nil key`)
+	}
+
+	if kid == "" {
+		return key, nil
+	}
+
+	if jwkKey, ok := key.(jwk.Key); ok {
+		if jwkKey.KeyID() == kid {
+			return key, nil
+		}
+
+		// do not modify the key owned by the provider
+		var raw interface{}
+		if err := jwkKey.Raw(&raw); err != nil {
+			return nil, errors.Wrap(err, `failed to retrieve raw key from jwk.Key`)
+		}
+		key = raw
+	}
+
+	// Keys whose private part is not available cannot be converted
+	// to a jwk.Key, so the key ID is attached to the signer instead
+	if signer, ok := opaqueSigner(key); ok {
+		return &keyIDSigner{Signer: signer, kid: kid}, nil
+	}
+
+	jwkKey, err := jwk.New(key)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create jwk.Key from signing key`)
+	}
+	if err := jwkKey.Set(jwk.KeyIDKey, kid); err != nil {
+		return nil, errors.Wrap(err, `failed to set key ID`)
+	}
+	return jwkKey, nil
+}
@@ -0,0 +1,56 @@
+package jws
+
+import (
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+// Logger receives structured events describing the decisions made by
+// `jws.Sign()`, `jws.Verify()` and `jws.VerifySet()`, such as the key
+// that was selected and the reason a verification failed, when specified
+// using `jws.WithLogger()`. `keyvals` are alternating keys and values
+// (e.g. "alg", jwa.RS256), so that `*slog.Logger` and thin adapters for
+// most structured logging libraries satisfy this interface.
+//
+// Events never contain key material, payloads or signatures: keys are
+// only described by their type and key ID.
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Warn(string, ...interface{})  {}
+
+// lookupLogger returns the Logger in options, or a Logger that discards
+// all events
+func lookupLogger(options []Option) Logger {
+	var logger Logger = nopLogger{}
+	for _, o := range options {
+		switch o.Ident() {
+		case identLogger{}:
+			logger = o.Value().(Logger)
+		}
+	}
+	return logger
+}
+
+// logKeyvals returns the alternating keys and values that describe a
+// signing or verification operation, without disclosing the key
+func logKeyvals(alg fmt.Stringer, key interface{}, extra ...interface{}) []interface{} {
+	var keyType string
+	if jwkKey, ok := key.(jwk.Key); ok {
+		keyType = jwkKey.KeyType().String()
+	} else {
+		keyType = fmt.Sprintf("%T", key)
+	}
+
+	keyvals := []interface{}{"alg", alg.String(), "key_type", keyType}
+	if kid := keyIDOf(key); kid != "" {
+		keyvals = append(keyvals, "kid", kid)
+	}
+	return append(keyvals, extra...)
+}
@@ -48,6 +48,10 @@ func (s *Signature) SetSignature(v []byte) *Signature {
 // The second return value s the full three-segment signature
 // (e.g. "eyXXXX.XXXXX.XXXX")
 func (s *Signature) Sign(payload []byte, signer Signer, key interface{}) ([]byte, []byte, error) {
+	return s.sign(payload, signer, key, defaultBufferPool{})
+}
+
+func (s *Signature) sign(payload []byte, signer Signer, key interface{}, bufpool BufferPool) ([]byte, []byte, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -60,13 +64,11 @@ func (s *Signature) Sign(payload []byte, signer Signer, key interface{}) ([]byte
 		return nil, nil, errors.Wrap(err, `failed to set "alg"`)
 	}
 
-	// If the key is a jwk.Key instance, obtain the raw key
-	if jwkKey, ok := key.(jwk.Key); ok {
-		// If we have a key ID specified by this jwk.Key, use that in the header
-		if kid := jwkKey.KeyID(); kid != "" {
-			if err := hdrs.Set(jwk.KeyIDKey, kid); err != nil {
-				return nil, nil, errors.Wrap(err, `set key ID from jwk.Key`)
-			}
+	// If we have a key ID specified by the key (e.g. a jwk.Key), use
+	// that in the header
+	if kid := keyIDOf(key); kid != "" {
+		if err := hdrs.Set(jwk.KeyIDKey, kid); err != nil {
+			return nil, nil, errors.Wrap(err, `set key ID from key`)
 		}
 	}
 	hdrbuf, err := json.Marshal(hdrs)
@@ -74,21 +76,31 @@ func (s *Signature) Sign(payload []byte, signer Signer, key interface{}) ([]byte
 		return nil, nil, errors.Wrap(err, `failed to marshal headers`)
 	}
 
-	buf := pool.GetBytesBuffer()
-	defer pool.ReleaseBytesBuffer(buf)
+	signature, ret, err := signCompact(base64.EncodeToString(hdrbuf), payload, signer, key, bufpool)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.signature = signature
+	return signature, ret, nil
+}
+
+// signCompact signs the payload using the already encoded protected
+// headers, and returns the signature along with the compact serialization
+func signCompact(encodedHeaders string, payload []byte, signer Signer, key interface{}, bufpool BufferPool) ([]byte, []byte, error) {
+	buf := bufpool.Get()
+	defer releaseBuffer(bufpool, buf)
 
-	buf.WriteString(base64.EncodeToString(hdrbuf))
+	buf.WriteString(encodedHeaders)
 	buf.WriteByte('.')
-	buf.WriteString(base64.EncodeToString(payload))
+	base64.EncodeBuffer(buf, payload)
 
 	signature, err := signer.Sign(buf.Bytes(), key)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, `failed to sign payload`)
 	}
-	s.signature = signature
 
 	buf.WriteByte('.')
-	buf.WriteString(base64.EncodeToString(signature))
+	base64.EncodeBuffer(buf, signature)
 	ret := make([]byte, buf.Len())
 	copy(ret, buf.Bytes())
 
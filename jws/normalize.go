@@ -0,0 +1,169 @@
+package jws
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwa"
+)
+
+// Kinds of normalization recorded in a NormalizationReport
+const (
+	// NormalizedWhitespace means that whitespace around a compact
+	// serialization was removed
+	NormalizedWhitespace = "whitespace"
+	// NormalizedPadding means that a base64 value contained padding,
+	// which RFC 7515 does not allow
+	NormalizedPadding = "padding"
+	// NormalizedAlphabet means that a base64 value was encoded using the
+	// standard alphabet instead of the base64url alphabet
+	NormalizedAlphabet = "alphabet"
+	// NormalizedAlgorithm means that the "alg" header only matches a known
+	// algorithm when compared case insensitively (e.g. "hs256"). The value
+	// is accepted by the parser, but it is not converted, so verification
+	// with the canonical algorithm will fail
+	NormalizedAlgorithm = "alg"
+)
+
+// Normalization describes a deviation from the canonical encoding that
+// was tolerated while parsing a message
+type Normalization struct {
+	// Kind is one of the jws.NormalizedXXX constants
+	Kind string
+	// Field is the location of the value in the message, such as
+	// "payload" or "signatures[0].protected"
+	Field string
+	// Detail is a human readable description
+	Detail string
+}
+
+func (n Normalization) String() string {
+	return n.Field + ": " + n.Detail
+}
+
+// NormalizationReport records the normalizations that were applied while
+// parsing a message. Pass it to `jws.Parse()` via `jws.WithNormalizationReport()`.
+//
+// A token produced by a conforming implementation does not need any
+// normalization, so a non-empty report may indicate that the token was
+// created by a buggy client, or that it was tampered with.
+type NormalizationReport struct {
+	normalizations []Normalization
+}
+
+// Normalizations returns the normalizations that were applied
+func (r *NormalizationReport) Normalizations() []Normalization {
+	return append([]Normalization(nil), r.normalizations...)
+}
+
+// Pristine returns true if no normalization was applied
+func (r *NormalizationReport) Pristine() bool {
+	return len(r.normalizations) == 0
+}
+
+func (r *NormalizationReport) add(kind, field, format string, args ...interface{}) {
+	r.normalizations = append(r.normalizations, Normalization{
+		Kind:   kind,
+		Field:  field,
+		Detail: fmt.Sprintf(format, args...),
+	})
+}
+
+// inspect records the normalizations applied to `src`, which must have
+// been successfully parsed
+func (r *NormalizationReport) inspect(src []byte) {
+	r.normalizations = nil
+
+	trimmed := bytes.TrimSpace(src)
+	if len(trimmed) == 0 {
+		return
+	}
+
+	if trimmed[0] != '{' {
+		if len(trimmed) != len(src) {
+			r.add(NormalizedWhitespace, "message", "removed %d whitespace characters", len(src)-len(trimmed))
+		}
+
+		// jws.SplitCompact() only accepts the base64url alphabet without
+		// padding, so only the header needs to be checked
+		protected, _, _, err := SplitCompact(trimmed)
+		if err != nil {
+			return
+		}
+		r.inspectProtected("protected", string(protected))
+		return
+	}
+
+	var proxy messageProxy
+	if err := json.Unmarshal(trimmed, &proxy); err != nil {
+		return
+	}
+
+	r.inspectBase64("payload", proxy.Payload)
+	if proxy.Signature != nil {
+		var sigproxy signatureProxy
+		if hdr := proxy.Header; hdr != nil {
+			sigproxy.Header = *hdr
+		}
+		if hdr := proxy.Protected; hdr != nil {
+			sigproxy.Protected = *hdr
+		}
+		sigproxy.Signature = *proxy.Signature
+		r.inspectSignature("", &sigproxy)
+		return
+	}
+
+	for i, sigproxy := range proxy.Signatures {
+		r.inspectSignature(fmt.Sprintf("signatures[%d].", i), sigproxy)
+	}
+}
+
+func (r *NormalizationReport) inspectSignature(prefix string, sigproxy *signatureProxy) {
+	if len(sigproxy.Protected) > 0 {
+		r.inspectBase64(prefix+"protected", sigproxy.Protected)
+		r.inspectProtected(prefix+"protected", sigproxy.Protected)
+	}
+	if len(sigproxy.Header) > 0 {
+		r.inspectAlgorithm(prefix+"header.alg", sigproxy.Header)
+	}
+	r.inspectBase64(prefix+"signature", sigproxy.Signature)
+}
+
+func (r *NormalizationReport) inspectBase64(field, v string) {
+	if strings.HasSuffix(v, "=") {
+		r.add(NormalizedPadding, field, "removed base64 padding")
+	}
+	if strings.ContainsAny(v, "+/") {
+		r.add(NormalizedAlphabet, field, "decoded using the standard base64 alphabet")
+	}
+}
+
+func (r *NormalizationReport) inspectProtected(field, v string) {
+	buf, err := base64.DecodeString(v)
+	if err != nil {
+		return
+	}
+	r.inspectAlgorithm(field+".alg", buf)
+}
+
+func (r *NormalizationReport) inspectAlgorithm(field string, hdr []byte) {
+	var raw struct {
+		Algorithm *string `json:"alg"`
+	}
+	if err := json.Unmarshal(hdr, &raw); err != nil || raw.Algorithm == nil {
+		return
+	}
+
+	for _, alg := range jwa.SignatureAlgorithms() {
+		if alg.String() == *raw.Algorithm {
+			return
+		}
+		if strings.EqualFold(alg.String(), *raw.Algorithm) {
+			r.add(NormalizedAlgorithm, field, "non-canonical value %q (expected %q)", *raw.Algorithm, alg)
+			return
+		}
+	}
+}
@@ -6,8 +6,19 @@ import (
 
 type Option = option.Interface
 
+type identBufferPool struct{}
+type identCanonicalJSON struct{}
+type identExecutor struct{}
 type identPayloadSigner struct{}
 type identHeaders struct{}
+type identHeaderTemplate struct{}
+type identKeyAttestation struct{}
+type identKeyAttestationPolicy struct{}
+type identKeyProviderForSigning struct{}
+type identLogger struct{}
+type identMaxMessageSize struct{}
+type identNormalizationReport struct{}
+type identVerificationCache struct{}
 
 func WithSigner(signer Signer, key interface{}, public, protected Headers) Option {
 	return option.New(identPayloadSigner{}, &payloadSigner{
@@ -21,3 +32,79 @@ func WithSigner(signer Signer, key interface{}, public, protected Headers) Optio
 func WithHeaders(h Headers) Option {
 	return option.New(identHeaders{}, h)
 }
+
+// WithHeaderTemplate specifies the precompiled protected headers to
+// be used by `jws.Sign()`. If `jws.WithHeaders()` is also specified,
+// the headers given by `jws.WithHeaders()` override those in the template,
+// and the precompiled form of the template is not used.
+func WithHeaderTemplate(t *HeaderTemplate) Option {
+	return option.New(identHeaderTemplate{}, t)
+}
+
+// WithBufferPool specifies the BufferPool that `jws.Sign()` and
+// `jws.Verify()` use to obtain scratch buffers for encoding and decoding.
+// If not specified, a package-wide pool based on `sync.Pool` is used.
+func WithBufferPool(p BufferPool) Option {
+	return option.New(identBufferPool{}, p)
+}
+
+// WithNormalizationReport specifies the report that `jws.Parse()` fills
+// with the normalizations applied to the message. The report is reset
+// each time it is passed to `jws.Parse()`.
+func WithNormalizationReport(r *NormalizationReport) Option {
+	return option.New(identNormalizationReport{}, r)
+}
+
+// WithMaxMessageSize specifies the maximum size in bytes of the serialized
+// message accepted by `jws.Parse()`, `jws.ParseString()`, `jws.ParseReader()`
+// and `jws.Verify()`. Larger messages are rejected before any of their
+// contents are decoded. By default there is no limit.
+func WithMaxMessageSize(n int64) Option {
+	return option.New(identMaxMessageSize{}, n)
+}
+
+// WithVerificationCache specifies the cache that `jws.Verify()` and
+// `jws.VerifySet()` use to skip the verification of messages that have
+// already been verified using the same key. See `jws.VerificationCache`
+// for details. It can not be used together with
+// `jws.WithKeyAttestationPolicy()`.
+func WithVerificationCache(c *VerificationCache) Option {
+	return option.New(identVerificationCache{}, c)
+}
+
+// WithLogger specifies the Logger that receives the events describing the
+// decisions made by `jws.Sign()`, `jws.Verify()` and `jws.VerifySet()`,
+// such as the key that was used and the reason a verification failed.
+// Successful operations are reported at the debug level, and failures
+// at the warn level. See `jws.Logger` for details.
+func WithLogger(l Logger) Option {
+	return option.New(identLogger{}, l)
+}
+
+// WithKeyProviderForSigning specifies the provider that `jws.Sign()` obtains
+// the signing key from, each time it is called. When this option is
+// specified, the `key` argument of `jws.Sign()` is ignored and may be nil.
+// See `jws.SigningKeyProvider` for details.
+func WithKeyProviderForSigning(p SigningKeyProvider) Option {
+	return option.New(identKeyProviderForSigning{}, p)
+}
+
+// WithExecutor specifies the Executor that `jws.VerifyAsync()` uses to
+// run the verification, e.g. a `jws.WorkerPool`.
+func WithExecutor(e Executor) Option {
+	return option.New(identExecutor{}, e)
+}
+
+// WithCanonicalJSON specifies that `jws.SignMulti()` should emit the JSON
+// serialization in a canonical form: the members of all objects are
+// sorted by their names, and there is no insignificant whitespace. The
+// values in the headers are normalized the same way before they are
+// signed, so that the "protected" member is also canonical.
+//
+// The output is then byte-stable across runs and versions of Go, which
+// allows golden-file testing of signing pipelines, provided that the
+// signature algorithm itself is deterministic (e.g. HS256, RS256 or
+// EdDSA, but not ES256 or PS256).
+func WithCanonicalJSON(v bool) Option {
+	return option.New(identCanonicalJSON{}, v)
+}
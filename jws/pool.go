@@ -0,0 +1,71 @@
+package jws
+
+import (
+	"bytes"
+
+	"github.com/lestrrat-go/jwx/internal/pool"
+)
+
+// BufferPool is the interface for objects that supply the scratch
+// buffers used while signing and verifying messages. Use
+// `jws.WithBufferPool()` to pass a BufferPool to `jws.Sign()` or
+// `jws.Verify()`.
+//
+// Buffers passed to Put have already been reset. Implementations must
+// be safe for concurrent use.
+type BufferPool interface {
+	Get() *bytes.Buffer
+	Put(*bytes.Buffer)
+}
+
+type defaultBufferPool struct{}
+
+func (defaultBufferPool) Get() *bytes.Buffer {
+	return pool.GetBytesBuffer()
+}
+
+func (defaultBufferPool) Put(b *bytes.Buffer) {
+	pool.ReleaseBytesBuffer(b)
+}
+
+func releaseBuffer(p BufferPool, b *bytes.Buffer) {
+	b.Reset()
+	p.Put(b)
+}
+
+type fixedBufferPool struct {
+	buffers chan *bytes.Buffer
+}
+
+// NewFixedBufferPool creates a BufferPool that retains at most `size`
+// buffers. Unlike the default pool, which is based on `sync.Pool`, buffers
+// held by this pool are not released when the garbage collector runs.
+// This is useful for short lived processes such as AWS Lambda functions,
+// where every invocation would otherwise start with an empty pool.
+//
+// If all buffers are in use, new buffers are allocated, and buffers
+// returned in excess of `size` are discarded.
+func NewFixedBufferPool(size int) BufferPool {
+	if size < 1 {
+		size = 1
+	}
+	return &fixedBufferPool{
+		buffers: make(chan *bytes.Buffer, size),
+	}
+}
+
+func (p *fixedBufferPool) Get() *bytes.Buffer {
+	select {
+	case b := <-p.buffers:
+		return b
+	default:
+		return &bytes.Buffer{}
+	}
+}
+
+func (p *fixedBufferPool) Put(b *bytes.Buffer) {
+	select {
+	case p.buffers <- b:
+	default:
+	}
+}
@@ -91,12 +91,17 @@ func (s RSASigner) Algorithm() jwa.SignatureAlgorithm {
 }
 
 // Sign creates a signature using crypto/rsa. key must be a non-nil instance of
-// `*"crypto/rsa".PrivateKey`.
+// `*"crypto/rsa".PrivateKey`, or a `crypto.Signer` for an RSA key whose
+// private key is not available, such as a key held in a hardware token.
 func (s RSASigner) Sign(payload []byte, key interface{}) ([]byte, error) {
 	if key == nil {
 		return nil, errors.New(`missing private key while signing payload`)
 	}
 
+	if signer, ok := opaqueSigner(key); ok {
+		return signRSAWithSigner(s.alg, signer, payload)
+	}
+
 	var privkey rsa.PrivateKey
 	if err := keyconv.RSAPrivateKey(&privkey, key); err != nil {
 		return nil, errors.Wrapf(err, `failed to retrieve rsa.PrivateKey out of %T`, key)
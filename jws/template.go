@@ -0,0 +1,91 @@
+package jws
+
+import (
+	"context"
+	"sync"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+)
+
+// HeaderTemplate is a precompiled set of protected headers that can
+// be shared across many calls to `jws.Sign()`. The serialized form of
+// the headers is computed once per combination of signature algorithm and
+// key ID, and is reused in subsequent calls, which removes the need to
+// marshal identical headers over and over again.
+//
+// Use `jws.WithHeaderTemplate()` to pass the template to `jws.Sign()`.
+// HeaderTemplate is safe for concurrent use.
+type HeaderTemplate struct {
+	headers Headers
+	mu      sync.RWMutex
+	encoded map[headerTemplateKey]string
+}
+
+type headerTemplateKey struct {
+	alg jwa.SignatureAlgorithm
+	kid string
+}
+
+// NewHeaderTemplate creates a new HeaderTemplate from the given headers.
+// The headers are copied, so further modifications to `h` do not
+// affect the template.
+func NewHeaderTemplate(h Headers) (*HeaderTemplate, error) {
+	hdrs, err := mergeHeaders(context.TODO(), nil, h)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to copy headers`)
+	}
+
+	return &HeaderTemplate{
+		headers: hdrs,
+		encoded: make(map[headerTemplateKey]string),
+	}, nil
+}
+
+// Headers returns a copy of the headers stored in the template
+func (t *HeaderTemplate) Headers() (Headers, error) {
+	return mergeHeaders(context.TODO(), nil, t.headers)
+}
+
+// encode returns the base64 encoded protected headers for the
+// given algorithm and key
+func (t *HeaderTemplate) encode(alg jwa.SignatureAlgorithm, key interface{}) (string, error) {
+	kid := keyIDOf(key)
+	cacheKey := headerTemplateKey{alg: alg, kid: kid}
+
+	t.mu.RLock()
+	encoded, ok := t.encoded[cacheKey]
+	t.mu.RUnlock()
+	if ok {
+		return encoded, nil
+	}
+
+	hdrs, err := t.Headers()
+	if err != nil {
+		return "", errors.Wrap(err, `failed to copy headers`)
+	}
+
+	if err := hdrs.Set(AlgorithmKey, alg); err != nil {
+		return "", errors.Wrap(err, `failed to set "alg"`)
+	}
+
+	if kid != "" {
+		if err := hdrs.Set(jwk.KeyIDKey, kid); err != nil {
+			return "", errors.Wrap(err, `set key ID from jwk.Key`)
+		}
+	}
+
+	hdrbuf, err := json.Marshal(hdrs)
+	if err != nil {
+		return "", errors.Wrap(err, `failed to marshal headers`)
+	}
+	encoded = base64.EncodeToString(hdrbuf)
+
+	t.mu.Lock()
+	t.encoded[cacheKey] = encoded
+	t.mu.Unlock()
+	return encoded, nil
+}
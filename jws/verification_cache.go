@@ -0,0 +1,244 @@
+package jws
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/internal/ttlcache"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+)
+
+// VerificationCache remembers messages that have been successfully
+// verified, so that verifying the same message again (e.g. webhooks that
+// are redelivered) does not require repeating the signature verification.
+//
+// The cache is opt-in: pass it to `jws.Verify()` or `jws.VerifySet()`
+// using the `jws.WithVerificationCache()` option. It is safe for
+// concurrent use.
+//
+// Entries are keyed by the SHA-256 digest of the message, the algorithm,
+// and the JWK thumbprint (RFC 7638) of the key, so an entry is only used
+// when the exact same message is verified using the same algorithm and
+// key material. Failed verifications are never cached.
+//
+// Entries must be discarded when the keys that they were verified with
+// are revoked. Use `InvalidateOnChange()` to do this automatically
+// whenever keys are removed from a `jwk.Set` or a `jwk.AutoRefresh`
+// key set, or call `Retain()` or `Clear()` manually.
+type VerificationCache struct {
+	cache *ttlcache.Cache
+	mu    sync.Mutex
+	stats VerificationCacheStats
+}
+
+type verificationCacheKey struct {
+	digest     [sha256.Size]byte
+	alg        jwa.SignatureAlgorithm
+	thumbprint string
+}
+
+// VerificationCacheStats contains the counters of a VerificationCache
+type VerificationCacheStats struct {
+	// Hits is the number of verifications that were served from the cache
+	Hits uint64
+	// Misses is the number of verifications that were not in the cache
+	Misses uint64
+	// Evictions is the number of entries that were removed because the
+	// cache was full or the entry expired
+	Evictions uint64
+	// Invalidations is the number of entries that were removed because
+	// their key was removed from the key set, or by `Retain()` or `Clear()`
+	Invalidations uint64
+}
+
+// HitRate returns the ratio of hits to lookups, or 0 if there were
+// no lookups
+func (s VerificationCacheStats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// NewVerificationCache creates a new VerificationCache that remembers
+// up to `maxEntries` verified messages for `ttl` each, evicting the least
+// recently used messages first. A value less than or equal to 0 removes
+// the corresponding limit.
+func NewVerificationCache(maxEntries int, ttl time.Duration) *VerificationCache {
+	return &VerificationCache{
+		cache: ttlcache.NewLRU(maxEntries, ttl),
+	}
+}
+
+// Len returns the number of entries in the cache, including those
+// that have expired but have not been evicted yet.
+func (c *VerificationCache) Len() int {
+	return c.cache.Len()
+}
+
+// Stats returns a snapshot of the counters of the cache
+func (c *VerificationCache) Stats() VerificationCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Evictions = c.cache.Evictions()
+	return stats
+}
+
+// Clear removes all entries from the cache.
+func (c *VerificationCache) Clear() {
+	c.invalidated(c.cache.Clear())
+}
+
+func (c *VerificationCache) invalidated(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Invalidations += uint64(n)
+}
+
+// removeThumbprints removes the entries of the keys whose thumbprints
+// are (or, if `keep` is true, are not) in `thumbprints`
+func (c *VerificationCache) removeThumbprints(thumbprints map[string]struct{}, keep bool) {
+	c.invalidated(c.cache.RemoveFunc(func(key, _ interface{}) bool {
+		_, ok := thumbprints[key.(verificationCacheKey).thumbprint]
+		return ok != keep
+	}))
+}
+
+// Retain removes the entries of all keys that are not in the given
+// key set. Unless `InvalidateOnChange()` is used, call it whenever the
+// key set is refreshed, so that messages signed using keys that have
+// been revoked are no longer accepted from the cache.
+func (c *VerificationCache) Retain(set jwk.Set) error {
+	keep := make(map[string]struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for iter := set.Iterate(ctx); iter.Next(ctx); {
+		key := iter.Pair().Value.(jwk.Key)
+		tp, err := key.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return errors.Wrapf(err, `failed to compute thumbprint of key (key ID=%#v)`, key.KeyID())
+		}
+		keep[string(tp)] = struct{}{}
+	}
+
+	c.removeThumbprints(keep, true)
+	return nil
+}
+
+// KeySetNotifier is implemented by the objects that report changes to
+// key sets, namely `jwk.Set` and `*jwk.AutoRefresh`
+type KeySetNotifier interface {
+	Subscribe(chan<- jwk.SetChange)
+	Unsubscribe(chan<- jwk.SetChange)
+}
+
+// InvalidateOnChange subscribes the cache to the changes reported by
+// `src`, and discards the entries of keys as soon as they are removed
+// from the key set (e.g. when a refresh of a `jwk.AutoRefresh` rotates
+// them out), so that messages signed using revoked keys are no longer
+// accepted from the cache. The notifications are received by a dedicated
+// goroutine, which unsubscribes when `ctx` is canceled.
+//
+// Notifications are dropped while the channel of the subscriber is full
+// (e.g. during a burst of changes), so whenever the channel is found to
+// have been full, the whole cache is cleared, as the removal of a key
+// may have been lost.
+func (c *VerificationCache) InvalidateOnChange(ctx context.Context, src KeySetNotifier) {
+	ch := make(chan jwk.SetChange, 16)
+	src.Subscribe(ch)
+	go func() {
+		defer src.Unsubscribe(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case change := <-ch:
+				// If a notification was dropped, the channel was full
+				// until this receive, in which case it is still at least
+				// cap(ch)-1 long now
+				if len(ch) >= cap(ch)-1 {
+					c.Clear()
+					continue
+				}
+				c.invalidate(change.Removed)
+			}
+		}
+	}()
+}
+
+// invalidate removes the entries of the given keys
+func (c *VerificationCache) invalidate(keys []jwk.Key) {
+	if len(keys) == 0 {
+		return
+	}
+
+	remove := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		tp, err := key.Thumbprint(crypto.SHA256)
+		if err != nil {
+			// A key without a thumbprint can not have been used to
+			// store an entry
+			continue
+		}
+		remove[string(tp)] = struct{}{}
+	}
+
+	c.removeThumbprints(remove, false)
+}
+
+// verificationCacheKeyFor computes the key under which the verification
+// of `buf` using `alg` and `key` is stored
+func verificationCacheKeyFor(buf []byte, alg jwa.SignatureAlgorithm, key interface{}) (verificationCacheKey, error) {
+	jwkKey, ok := key.(jwk.Key)
+	if !ok {
+		v, err := jwk.New(key)
+		if err != nil {
+			return verificationCacheKey{}, errors.Wrap(err, `failed to create jwk.Key from raw key`)
+		}
+		jwkKey = v
+	}
+
+	tp, err := jwkKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return verificationCacheKey{}, errors.Wrap(err, `failed to compute thumbprint of key`)
+	}
+
+	return verificationCacheKey{
+		digest:     sha256.Sum256(buf),
+		alg:        alg,
+		thumbprint: string(tp),
+	}, nil
+}
+
+func (c *VerificationCache) lookup(key verificationCacheKey) ([]byte, bool) {
+	v, ok := c.cache.Get(key)
+
+	c.mu.Lock()
+	if ok {
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+	payload := v.([]byte)
+	ret := make([]byte, len(payload))
+	copy(ret, payload)
+	return ret, true
+}
+
+func (c *VerificationCache) store(key verificationCacheKey, payload []byte) {
+	v := make([]byte, len(payload))
+	copy(v, payload)
+	c.cache.Set(key, v, time.Time{})
+}
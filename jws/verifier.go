@@ -14,7 +14,7 @@ func (fn VerifierFactoryFn) Create() (Verifier, error) {
 	return fn()
 }
 
-var verifierDB map[jwa.SignatureAlgorithm]VerifierFactory
+var verifierDB = make(map[jwa.SignatureAlgorithm]VerifierFactory)
 
 // RegisterVerifier is used to register a factory object that creates
 // Verifier objects based on the given algorithm.
@@ -27,8 +27,6 @@ func RegisterVerifier(alg jwa.SignatureAlgorithm, f VerifierFactory) {
 }
 
 func init() {
-	verifierDB = make(map[jwa.SignatureAlgorithm]VerifierFactory)
-
 	for _, alg := range []jwa.SignatureAlgorithm{jwa.RS256, jwa.RS384, jwa.RS512, jwa.PS256, jwa.PS384, jwa.PS512} {
 		RegisterVerifier(alg, func(alg jwa.SignatureAlgorithm) VerifierFactory {
 			return VerifierFactoryFn(func() (Verifier, error) {
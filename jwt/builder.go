@@ -0,0 +1,122 @@
+package jwt
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Builder is a convenience wrapper around `Token.Set()`, which allows the
+// claims of a token to be specified in a single chain of calls:
+//
+//     tok, err := jwt.NewBuilder().
+//       Issuer(`github.com/lestrrat-go/jwx`).
+//       Subject(`user`).
+//       Claim(`role`, `admin`).
+//       Expiration(time.Now().Add(time.Hour)).
+//       Build()
+//
+// Claims are set in the order they were specified when `Build()` is
+// called, and all of the errors are reported at once. A Builder may be
+// used to build any number of tokens, but is not safe for concurrent use.
+type Builder struct {
+	claims []*ClaimPair
+}
+
+// NewBuilder creates a new Builder
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Claim specifies the value of the claim `name`. Any value that
+// `Token.Set()` accepts may be used.
+func (b *Builder) Claim(name string, value interface{}) *Builder {
+	b.claims = append(b.claims, &ClaimPair{Key: name, Value: value})
+	return b
+}
+
+// Audience specifies the value of the "aud" claim
+func (b *Builder) Audience(v []string) *Builder {
+	return b.Claim(AudienceKey, v)
+}
+
+// Expiration specifies the value of the "exp" claim
+func (b *Builder) Expiration(v time.Time) *Builder {
+	return b.Claim(ExpirationKey, v)
+}
+
+// IssuedAt specifies the value of the "iat" claim
+func (b *Builder) IssuedAt(v time.Time) *Builder {
+	return b.Claim(IssuedAtKey, v)
+}
+
+// Issuer specifies the value of the "iss" claim
+func (b *Builder) Issuer(v string) *Builder {
+	return b.Claim(IssuerKey, v)
+}
+
+// JwtID specifies the value of the "jti" claim
+func (b *Builder) JwtID(v string) *Builder {
+	return b.Claim(JwtIDKey, v)
+}
+
+// NotBefore specifies the value of the "nbf" claim
+func (b *Builder) NotBefore(v time.Time) *Builder {
+	return b.Claim(NotBeforeKey, v)
+}
+
+// Subject specifies the value of the "sub" claim
+func (b *Builder) Subject(v string) *Builder {
+	return b.Claim(SubjectKey, v)
+}
+
+// Build creates a new token containing the claims that were specified.
+// If any of the claims could not be set, the error lists all of them,
+// and `errors.Is()` and `errors.As()` match any of the underlying errors.
+func (b *Builder) Build() (Token, error) {
+	t := New()
+
+	var errs buildErrors
+	for _, pair := range b.claims {
+		name := pair.Key.(string)
+		if err := t.Set(name, pair.Value); err != nil {
+			errs = append(errs, errors.Wrapf(err, `failed to set %q`, name))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return t, nil
+}
+
+// buildErrors lists the claims that `Builder.Build()` failed to set
+type buildErrors []error
+
+func (e buildErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return `failed to build token: ` + strings.Join(msgs, `; `)
+}
+
+// Is returns true if any of the errors matches `target`
+func (e buildErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error that matches `target`
+func (e buildErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
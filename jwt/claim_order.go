@@ -0,0 +1,141 @@
+package jwt
+
+import (
+	"context"
+	"sort"
+
+	"github.com/lestrrat-go/iter/mapiter"
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/internal/pool"
+	"github.com/pkg/errors"
+)
+
+// ClaimOrder specifies the order in which the claims of a token are
+// iterated over and serialized. Both orders are deterministic: the same
+// set of claims always yields the same JSON, regardless of the order in
+// which the claims were set or parsed.
+type ClaimOrder int
+
+const (
+	// SortedClaimOrder sorts all claims by their names in byte order.
+	// This is the order used by `Token.Iterate()` and `json.Marshal()`.
+	SortedClaimOrder ClaimOrder = iota
+
+	// RegisteredFirstClaimOrder lists the registered claims in the order
+	// of RFC 7519 section 4.1 ("iss", "sub", "aud", "exp", "nbf", "iat",
+	// "jti"), followed by all other claims sorted by their names in byte
+	// order.
+	RegisteredFirstClaimOrder
+)
+
+// registeredClaims lists the claims of RFC 7519 section 4.1, in order
+var registeredClaims = []string{
+	IssuerKey,
+	SubjectKey,
+	AudienceKey,
+	ExpirationKey,
+	NotBeforeKey,
+	IssuedAtKey,
+	JwtIDKey,
+}
+
+// sortClaimNames sorts names in place according to the order
+func sortClaimNames(names []string, order ClaimOrder) {
+	rank := func(name string) int {
+		if order == RegisteredFirstClaimOrder {
+			for i, v := range registeredClaims {
+				if v == name {
+					return i
+				}
+			}
+		}
+		return len(registeredClaims)
+	}
+
+	sort.SliceStable(names, func(i, j int) bool {
+		ri, rj := rank(names[i]), rank(names[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+}
+
+// IterateOrdered is the same as `Token.Iterate()`, except that the claims
+// are returned in the given order.
+func IterateOrdered(ctx context.Context, t Token, order ClaimOrder) Iterator {
+	var names []string
+	values := make(map[string]interface{})
+	for iter := t.Iterate(ctx); iter.Next(ctx); {
+		pair := iter.Pair()
+		names = append(names, pair.Key.(string))
+		values[pair.Key.(string)] = pair.Value
+	}
+	sortClaimNames(names, order)
+
+	ch := make(chan *ClaimPair, len(names))
+	for _, name := range names {
+		ch <- &ClaimPair{Key: name, Value: values[name]}
+	}
+	close(ch)
+	return mapiter.New(ch)
+}
+
+// Marshal returns the JSON representation of the token. By default it is
+// the same as `json.Marshal()`, whose output is already deterministic.
+// Use `jwt.WithClaimOrder()` to change the order of the claims, e.g. to
+// produce payloads that match those of another implementation.
+//
+// Only the order of the top-level claims is affected: the members of
+// claims that are objects are always sorted by their names.
+func Marshal(t Token, options ...Option) ([]byte, error) {
+	order := SortedClaimOrder
+	for _, o := range options {
+		switch o.Ident() {
+		case identClaimOrder{}:
+			order = o.Value().(ClaimOrder)
+		}
+	}
+
+	buf, err := json.Marshal(t)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal token`)
+	}
+	if order == SortedClaimOrder {
+		return buf, nil
+	}
+
+	// Reorder the serialized claims, so that their values are exactly
+	// the same as those produced by the token
+	var claims map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &claims); err != nil {
+		return nil, errors.Wrap(err, `failed to decode token`)
+	}
+
+	names := make([]string, 0, len(claims))
+	for name := range claims {
+		names = append(names, name)
+	}
+	sortClaimNames(names, order)
+
+	out := pool.GetBytesBuffer()
+	defer pool.ReleaseBytesBuffer(out)
+	out.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to marshal claim name %s`, name)
+		}
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(claims[name])
+	}
+	out.WriteByte('}')
+
+	ret := make([]byte, out.Len())
+	copy(ret, out.Bytes())
+	return ret, nil
+}
@@ -0,0 +1,150 @@
+package jwt
+
+import (
+	"crypto"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/pkg/errors"
+)
+
+// ClaimsDigestKey is the name of the claim created by `jwt.ProtectClaims()`
+const ClaimsDigestKey = "claims_digest"
+
+// claimsDigest is the payload of the JWS stored in the "claims_digest" claim
+type claimsDigest struct {
+	Claims []string `json:"claims"`
+	Digest string   `json:"digest"`
+}
+
+type protectedClaimsKey struct {
+	alg jwa.SignatureAlgorithm
+	key interface{}
+}
+
+// ProtectClaims protects the claims `claims` of the token from being
+// modified further down a token exchange chain, where the token may be
+// re-signed by intermediaries that the original issuer does not trust.
+//
+// A compact JWS, signed using `key`, is added to the "claims_digest"
+// claim. Its payload contains the names of the claims and their SHA-256
+// thumbprint, as computed by `jwt.Thumbprint()`. Because the digest is
+// signed by the issuer rather than by whoever signs the token, re-signing
+// the token preserves it, but modifying, adding or removing any of the
+// protected claims invalidates it. Other claims may still be changed.
+//
+// The "claims_digest" claim is a list, so that each issuer in a token
+// exchange chain can protect its own claims: digests added by earlier
+// calls are preserved.
+//
+// Use `jwt.VerifyProtectedClaims()` or `jwt.WithProtectedClaims()` with
+// the public key of the issuer to detect tampering.
+func ProtectClaims(t Token, alg jwa.SignatureAlgorithm, key interface{}, claims ...string) error {
+	if len(claims) == 0 {
+		return errors.New(`at least one claim must be protected`)
+	}
+	for _, name := range claims {
+		if name == ClaimsDigestKey {
+			return errors.Errorf(`%q claim cannot be protected`, ClaimsDigestKey)
+		}
+	}
+
+	digests, err := claimsDigests(t)
+	if err != nil {
+		return err
+	}
+
+	digest, err := Thumbprint(t, crypto.SHA256, claims...)
+	if err != nil {
+		return errors.Wrap(err, `failed to compute digest`)
+	}
+
+	payload, err := json.Marshal(claimsDigest{
+		Claims: claims,
+		Digest: base64.EncodeToString(digest),
+	})
+	if err != nil {
+		return errors.Wrap(err, `failed to marshal claims digest`)
+	}
+
+	signed, err := jws.Sign(payload, alg, key)
+	if err != nil {
+		return errors.Wrap(err, `failed to sign claims digest`)
+	}
+
+	if err := t.Set(ClaimsDigestKey, append(digests, string(signed))); err != nil {
+		return errors.Wrapf(err, `failed to set %q claim`, ClaimsDigestKey)
+	}
+	return nil
+}
+
+// claimsDigests returns the signed digests in the "claims_digest" claim
+func claimsDigests(t Token) ([]string, error) {
+	v, ok := t.Get(ClaimsDigestKey)
+	if !ok {
+		return nil, nil
+	}
+
+	switch v := v.(type) {
+	case []string:
+		return append([]string(nil), v...), nil
+	case []interface{}:
+		digests := make([]string, len(v))
+		for i, elem := range v {
+			s, ok := elem.(string)
+			if !ok {
+				return nil, errors.Errorf(`%q claim must be a list of strings, got element of type %T`, ClaimsDigestKey, elem)
+			}
+			digests[i] = s
+		}
+		return digests, nil
+	default:
+		return nil, errors.Errorf(`%q claim must be a list of strings, got %T`, ClaimsDigestKey, v)
+	}
+}
+
+// VerifyProtectedClaims looks for the digest in the "claims_digest" claim
+// that was signed using `key`, which is usually the public key of the
+// original issuer, and checks that the claims it protects have not been
+// modified since. The names of the protected claims are returned.
+//
+// If none of the digests was signed using `key`, or if the protected
+// claims have been modified, the error matches `jwt.ErrClaimsModified`
+// when tested using `errors.Is()`.
+func VerifyProtectedClaims(t Token, alg jwa.SignatureAlgorithm, key interface{}) ([]string, error) {
+	if _, ok := t.Get(ClaimsDigestKey); !ok {
+		return nil, errors.Errorf(`%q claim not found`, ClaimsDigestKey)
+	}
+	digests, err := claimsDigests(t)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, signed := range digests {
+		// Digests signed by other issuers are skipped
+		payload, err := jws.Verify([]byte(signed), alg, key)
+		if err != nil {
+			continue
+		}
+
+		var cd claimsDigest
+		if err := json.Unmarshal(payload, &cd); err != nil {
+			return nil, errors.Wrap(err, `failed to parse claims digest`)
+		}
+		if len(cd.Claims) == 0 {
+			return nil, errors.New(`claims digest does not list any claims`)
+		}
+
+		digest, err := Thumbprint(t, crypto.SHA256, cd.Claims...)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to compute digest`)
+		}
+		if !constantTimeEqual(cd.Digest, base64.EncodeToString(digest)) {
+			return nil, ErrClaimsModified
+		}
+		return cd.Claims, nil
+	}
+	return nil, errors.Wrap(ErrClaimsModified, `no claims digest signed by the given key`)
+}
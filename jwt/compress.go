@@ -0,0 +1,96 @@
+package jwt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/pkg/errors"
+)
+
+// PayloadCompressionKey is the name of the private JWS header that
+// `jwt.Sign()` sets when the claims are compressed via the
+// `jwt.WithCompressPayload()` option. The header is also listed in
+// the "crit" header, so that verifiers that do not understand this
+// extension reject the token instead of misinterpreting its payload.
+const PayloadCompressionKey = "jwx-zip"
+
+// PayloadCompressionGzip is the only supported value for the
+// PayloadCompressionKey header.
+const PayloadCompressionGzip = "gzip"
+
+// setPayloadCompression marks the headers as carrying a compressed payload
+func setPayloadCompression(hdr jws.Headers) error {
+	if err := hdr.Set(PayloadCompressionKey, PayloadCompressionGzip); err != nil {
+		return errors.Wrapf(err, `failed to set %q header`, PayloadCompressionKey)
+	}
+
+	crit := hdr.Critical()
+	for _, v := range crit {
+		if v == PayloadCompressionKey {
+			return nil
+		}
+	}
+	crit = append(append([]string(nil), crit...), PayloadCompressionKey)
+	if err := hdr.Set(jws.CriticalKey, crit); err != nil {
+		return errors.Wrapf(err, `failed to set %q header`, jws.CriticalKey)
+	}
+	return nil
+}
+
+func compressPayload(payload []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(payload); err != nil {
+		return nil, errors.Wrap(err, `failed to write to gzip writer`)
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, `failed to close gzip writer`)
+	}
+	return buf.Bytes(), nil
+}
+
+// lookupPayloadCompression returns the value of the PayloadCompressionKey
+// header in the protected headers of the first signature
+func lookupPayloadCompression(msg *jws.Message) (string, error) {
+	sigs := msg.Signatures()
+	if len(sigs) == 0 {
+		return "", errors.New(`jws message contains no signatures`)
+	}
+
+	hdrs := sigs[0].ProtectedHeaders()
+	if hdrs == nil {
+		return "", nil
+	}
+
+	v, ok := hdrs.Get(PayloadCompressionKey)
+	if !ok {
+		return "", nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", errors.Errorf(`invalid value for %q header: %T`, PayloadCompressionKey, v)
+	}
+	return s, nil
+}
+
+// decompressPayload inflates the payload. An error is returned if the
+// inflated payload exceeds maxSize bytes
+func decompressPayload(payload []byte, maxSize int64) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to create gzip reader`)
+	}
+	defer r.Close()
+
+	buf, err := ioutil.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to decompress payload`)
+	}
+	if int64(len(buf)) > maxSize {
+		return nil, errors.Errorf(`decompressed payload exceeds maximum size (%d bytes)`, maxSize)
+	}
+	return buf, nil
+}
@@ -0,0 +1,266 @@
+package jwt
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/pkg/errors"
+)
+
+// maxDiscoveryDocumentSize is the maximum size of the OpenID Provider
+// configuration documents that are read during discovery
+const maxDiscoveryDocumentSize = 1 << 20
+
+// KeyDiscoveryConfig describes how a KeyDiscovery locates the keys of
+// the issuers it trusts
+type KeyDiscoveryConfig struct {
+	// Issuers is the list of trusted issuers. Each issuer must be an
+	// "https" URL, and is compared to the "iss" claim of tokens as is.
+	// Keys are never discovered for issuers that are not in this list.
+	// It is required.
+	Issuers []string
+
+	// HTTPClient is used to fetch the discovery documents and the key
+	// sets. If not specified, `http.DefaultClient` is used.
+	HTTPClient jwk.HTTPClient
+
+	// RefreshInterval is the interval at which the key sets are
+	// refreshed. If not specified, it is computed from the HTTP response
+	// headers, as described in `jwk.AutoRefresh`.
+	RefreshInterval time.Duration
+
+	// MinRefreshInterval is the minimum interval between two fetches of
+	// the key set of an issuer. This includes the fetches that happen
+	// when a token refers to an unknown key ID, for example after the
+	// issuer rotated its keys. If not specified, 5 minutes is used.
+	MinRefreshInterval time.Duration
+}
+
+// KeyDiscovery locates the keys used to verify tokens, based on their
+// (as of yet unverified) "iss" claim. Use it with `jwt.WithVerifyAuto()`.
+//
+// The key set of an issuer is located using OpenID Connect Discovery:
+// the "jwks_uri" is read from the document at
+// "<issuer>/.well-known/openid-configuration", whose "issuer" must match.
+// If the issuer does not publish such a document, the key set is read
+// from "<issuer>/.well-known/jwks.json" instead. Key sets are cached and
+// refreshed in the background using `jwk.AutoRefresh`.
+//
+// A KeyDiscovery is safe for concurrent use.
+type KeyDiscovery struct {
+	config  KeyDiscoveryConfig
+	issuers map[string]struct{}
+	cache   *jwk.AutoRefresh
+
+	mu        sync.Mutex
+	jwksURIs  map[string]string
+	refreshed map[string]time.Time
+}
+
+// NewKeyDiscovery creates a new KeyDiscovery. The context controls the
+// lifetime of the goroutine that refreshes the key sets. The configuration
+// is copied, so modifying it afterwards does not affect the KeyDiscovery.
+func NewKeyDiscovery(ctx context.Context, config KeyDiscoveryConfig) (*KeyDiscovery, error) {
+	if len(config.Issuers) == 0 {
+		return nil, errors.New(`at least one trusted issuer must be specified`)
+	}
+
+	issuers := make(map[string]struct{}, len(config.Issuers))
+	for _, iss := range config.Issuers {
+		if err := checkHTTPS(iss); err != nil {
+			return nil, errors.Wrapf(err, `invalid issuer %q`, iss)
+		}
+		issuers[iss] = struct{}{}
+	}
+	config.Issuers = append([]string(nil), config.Issuers...)
+
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if config.MinRefreshInterval <= 0 {
+		config.MinRefreshInterval = 5 * time.Minute
+	}
+
+	return &KeyDiscovery{
+		config:    config,
+		issuers:   issuers,
+		cache:     jwk.NewAutoRefresh(ctx),
+		jwksURIs:  make(map[string]string),
+		refreshed: make(map[string]time.Time),
+	}, nil
+}
+
+func checkHTTPS(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return errors.Wrap(err, `failed to parse URL`)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return errors.Errorf(`URL must use the "https" scheme`)
+	}
+	return nil
+}
+
+// lookupKey selects the verification key for a token from the key set
+// of its issuer
+func (d *KeyDiscovery) lookupKey(ctx context.Context, data []byte, useDefault bool, options ...ParseOption) (jwa.SignatureAlgorithm, jwk.Key, error) {
+	// The claims are not trusted at this point: they are only used to
+	// select the issuer, and are verified afterwards
+	unverified, err := parse(nil, data, false, "", nil, false, options...)
+	if err != nil {
+		return "", nil, errors.Wrap(err, `failed to parse token`)
+	}
+
+	iss := unverified.Issuer()
+	if _, ok := d.issuers[iss]; !ok {
+		return "", nil, errors.Errorf(`issuer %q is not trusted`, iss)
+	}
+
+	msg, err := jws.Parse(data)
+	if err != nil {
+		return "", nil, errors.Wrap(err, `failed to parse token data`)
+	}
+	headers, err := protectedHeadersOf(msg)
+	if err != nil {
+		return "", nil, err
+	}
+	alg := headers.Algorithm()
+
+	jwksURI, err := d.jwksURI(ctx, iss)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, `failed to discover key set of issuer %q`, iss)
+	}
+
+	keyset, err := d.cache.Fetch(ctx, jwksURI)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, `failed to fetch key set of issuer %q`, iss)
+	}
+
+	key, err := lookupMatchingJWK(headers, keyset, useDefault)
+	if err != nil {
+		// The issuer may have rotated its keys since the key set was
+		// last fetched
+		if !d.shouldRefresh(iss) {
+			return "", nil, err
+		}
+		keyset, err = d.cache.Refresh(ctx, jwksURI)
+		if err != nil {
+			return "", nil, errors.Wrapf(err, `failed to refresh key set of issuer %q`, iss)
+		}
+		key, err = lookupMatchingJWK(headers, keyset, useDefault)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	if v := key.Algorithm(); v != "" && v != alg.String() {
+		return "", nil, errors.Errorf(`algorithm %q does not match the algorithm of the key (%q)`, alg, v)
+	}
+	return alg, key, nil
+}
+
+// shouldRefresh reports if the key set of the issuer may be refreshed
+// outside of the regular schedule, and if so, records the time
+func (d *KeyDiscovery) shouldRefresh(iss string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := d.refreshed[iss]; ok && now.Sub(last) < d.config.MinRefreshInterval {
+		return false
+	}
+	d.refreshed[iss] = now
+	return true
+}
+
+// jwksURI returns the URL of the key set of the issuer, performing
+// discovery the first time the issuer is seen
+func (d *KeyDiscovery) jwksURI(ctx context.Context, iss string) (string, error) {
+	d.mu.Lock()
+	v, ok := d.jwksURIs[iss]
+	d.mu.Unlock()
+	if ok {
+		return v, nil
+	}
+
+	v, err := d.discover(ctx, iss)
+	if err != nil {
+		return "", err
+	}
+
+	options := []jwk.AutoRefreshOption{
+		jwk.WithHTTPClient(d.config.HTTPClient),
+		jwk.WithMinRefreshInterval(d.config.MinRefreshInterval),
+	}
+	if d.config.RefreshInterval > 0 {
+		options = append(options, jwk.WithRefreshInterval(d.config.RefreshInterval))
+	}
+	d.cache.Configure(v, options...)
+
+	d.mu.Lock()
+	d.jwksURIs[iss] = v
+	// The key set is about to be fetched for the first time
+	d.refreshed[iss] = time.Now()
+	d.mu.Unlock()
+	return v, nil
+}
+
+// discover reads the OpenID Provider configuration of the issuer, and
+// returns its "jwks_uri". If the issuer does not have one, the well-known
+// location of the key set is returned.
+func (d *KeyDiscovery) discover(ctx context.Context, iss string) (string, error) {
+	base := strings.TrimSuffix(iss, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return "", errors.Wrap(err, `failed to create discovery request`)
+	}
+	res, err := d.config.HTTPClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, `failed to fetch discovery document`)
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return base + "/.well-known/jwks.json", nil
+	default:
+		return "", errors.Errorf(`failed to fetch discovery document (status = %d)`, res.StatusCode)
+	}
+
+	buf, err := ioutil.ReadAll(io.LimitReader(res.Body, maxDiscoveryDocumentSize))
+	if err != nil {
+		return "", errors.Wrap(err, `failed to read discovery document`)
+	}
+
+	var doc struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return "", errors.Wrap(err, `failed to parse discovery document`)
+	}
+
+	// OpenID Connect Discovery 1.0, section 4.3
+	if doc.Issuer != iss {
+		return "", errors.Errorf(`issuer in discovery document (%q) does not match`, doc.Issuer)
+	}
+	if doc.JWKSURI == "" {
+		return "", errors.New(`discovery document does not contain "jwks_uri"`)
+	}
+	if err := checkHTTPS(doc.JWKSURI); err != nil {
+		return "", errors.Wrapf(err, `invalid "jwks_uri" %q`, doc.JWKSURI)
+	}
+	return doc.JWKSURI, nil
+}
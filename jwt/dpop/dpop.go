@@ -0,0 +1,391 @@
+// Package dpop implements the creation and the validation of DPoP proofs,
+// as described in RFC 9449 (OAuth 2.0 Demonstrating Proof of Possession).
+//
+// A client creates a proof for each HTTP request using `dpop.NewProof()`,
+// and sends it in the "DPoP" header:
+//
+//    proof, err := dpop.NewProof(http.MethodGet, uri, jwa.ES256, key, dpop.WithAccessToken(token))
+//    req.Header.Set(dpop.HeaderName, string(proof))
+//    req.Header.Set("Authorization", "DPoP "+token)
+//
+// A server validates the proof using `dpop.ValidateRequest()`, and checks
+// that the access token is bound to the key of the proof:
+//
+//    token, err := jwt.ParseString(strings.TrimPrefix(req.Header.Get("Authorization"), "DPoP "), jwt.WithVerify(jwa.ES256, serverKey))
+//    ...
+//    key, err := dpop.ValidateRequest(req, dpop.WithBoundToken(token), dpop.WithJtiStore(store))
+//
+// Authorization servers bind the access tokens that they issue to the
+// key of the proof using `jwt.BindToKey()`.
+package dpop
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/internal/keyconv"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/pkg/errors"
+)
+
+// Names of the HTTP headers and of the claims of DPoP proofs
+const (
+	HeaderName      = "DPoP"
+	NonceHeaderName = "DPoP-Nonce"
+
+	HTTPMethodKey      = "htm"
+	HTTPURIKey         = "htu"
+	AccessTokenHashKey = "ath"
+	NonceKey           = "nonce"
+)
+
+const defaultMaxAge = 5 * time.Minute
+
+// NewProof creates a DPoP proof for an HTTP request with the method
+// `method` to the URI `uri`, signed using the private key `key`. The
+// query and the fragment of `uri` are not included in the proof.
+//
+// `key` may be a raw key (e.g. *ecdsa.PrivateKey) or a jwk.Key. The
+// corresponding public key is embedded in the "jwk" header.
+func NewProof(method, uri string, alg jwa.SignatureAlgorithm, key interface{}, options ...ProofOption) ([]byte, error) {
+	var clock jwt.Clock = jwt.ClockFunc(time.Now)
+	var accessToken, nonce, jti string
+	var hasAccessToken bool
+	for _, o := range options {
+		switch o.Ident() {
+		case identClock{}:
+			clock = o.Value().(jwt.Clock)
+		case identAccessToken{}:
+			accessToken = o.Value().(string)
+			hasAccessToken = true
+		case identNonce{}:
+			nonce = o.Value().(string)
+		case identJwtID{}:
+			jti = o.Value().(string)
+		}
+	}
+
+	if !isAsymmetric(alg) {
+		return nil, errors.Errorf(`algorithm %s can not be used for DPoP proofs`, alg)
+	}
+
+	pubkey, err := keyconv.PublicJWK(key)
+	if err != nil {
+		return nil, errors.Wrap(err, `invalid key for DPoP proofs`)
+	}
+
+	htu, err := normalizeURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if jti == "" {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, errors.Wrap(err, `failed to generate jti`)
+		}
+		jti = base64.EncodeToString(buf)
+	}
+
+	t := jwt.New()
+	claims := map[string]interface{}{
+		jwt.JwtIDKey:    jti,
+		HTTPMethodKey:   method,
+		HTTPURIKey:      htu,
+		jwt.IssuedAtKey: clock.Now(),
+	}
+	if hasAccessToken {
+		claims[AccessTokenHashKey] = AccessTokenHash(accessToken)
+	}
+	if nonce != "" {
+		claims[NonceKey] = nonce
+	}
+	for k, v := range claims {
+		if err := t.Set(k, v); err != nil {
+			return nil, errors.Wrapf(err, `failed to set %s`, k)
+		}
+	}
+
+	hdrs := jws.NewHeaders()
+	if err := hdrs.Set(jws.JWKKey, pubkey); err != nil {
+		return nil, errors.Wrapf(err, `failed to set %s header`, jws.JWKKey)
+	}
+
+	signed, err := jwt.Sign(t, alg, key, jwt.WithHeaders(hdrs), jwt.WithTokenType(jwt.TokenTypeDPoP))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to sign proof`)
+	}
+	return signed, nil
+}
+
+// Validate validates the DPoP proof `proof` for an HTTP request with the
+// method `method` to the URI `uri`, as described in RFC 9449 section 4.3,
+// and returns the public key of the proof.
+//
+// The proof must be signed using an asymmetric algorithm by the key in
+// its "jwk" header, and must have the "jti", "htm", "htu" and "iat"
+// claims. See the options for the additional checks.
+func Validate(proof []byte, method, uri string, options ...ValidateOption) (jwk.Key, error) {
+	return validate(context.Background(), proof, method, uri, options)
+}
+
+// ValidateRequest validates the DPoP proof in the "DPoP" header of the
+// HTTP request. See `dpop.Validate()`.
+//
+// If the request has an "Authorization" header with the "DPoP" scheme,
+// and `dpop.WithAccessToken()` is not specified, the proof must have an
+// "ath" claim that matches the access token in the header.
+func ValidateRequest(req *http.Request, options ...ValidateOption) (jwk.Key, error) {
+	values := req.Header[http.CanonicalHeaderKey(HeaderName)]
+	if len(values) != 1 {
+		return nil, errors.Errorf(`request must have exactly one %s header`, HeaderName)
+	}
+
+	var uri string
+	var hasAccessToken bool
+	for _, o := range options {
+		switch o.Ident() {
+		case identRequestURI{}:
+			uri = o.Value().(string)
+		case identAccessToken{}:
+			hasAccessToken = true
+		}
+	}
+
+	if uri == "" {
+		scheme := "http"
+		if req.TLS != nil {
+			scheme = "https"
+		}
+		u := url.URL{Scheme: scheme, Host: req.Host, Path: req.URL.Path, RawPath: req.URL.RawPath}
+		uri = u.String()
+	}
+
+	if !hasAccessToken {
+		if token, ok := accessTokenFromRequest(req); ok {
+			options = append(append([]ValidateOption(nil), options...), WithAccessToken(token))
+		}
+	}
+	return validate(req.Context(), []byte(values[0]), req.Method, uri, options)
+}
+
+// AccessTokenHash returns the value of the "ath" claim for the access
+// token, which is the base64url encoded SHA-256 hash of the token
+func AccessTokenHash(accessToken string) string {
+	h := sha256.Sum256([]byte(accessToken))
+	return base64.EncodeToString(h[:])
+}
+
+func validate(ctx context.Context, proof []byte, method, uri string, options []ValidateOption) (jwk.Key, error) {
+	var clock jwt.Clock = jwt.ClockFunc(time.Now)
+	var skew time.Duration
+	var accessToken, nonce string
+	var hasAccessToken bool
+	var algs []jwa.SignatureAlgorithm
+	var store jwt.JtiStore
+	var bound jwt.Token
+	maxAge := defaultMaxAge
+	for _, o := range options {
+		switch o.Ident() {
+		case identClock{}:
+			clock = o.Value().(jwt.Clock)
+		case identAcceptableSkew{}:
+			skew = o.Value().(time.Duration)
+		case identMaxAge{}:
+			maxAge = o.Value().(time.Duration)
+		case identAccessToken{}:
+			accessToken = o.Value().(string)
+			hasAccessToken = true
+		case identNonce{}:
+			nonce = o.Value().(string)
+		case identAcceptableAlgorithms{}:
+			algs = o.Value().([]jwa.SignatureAlgorithm)
+		case identJtiStore{}:
+			store = o.Value().(jwt.JtiStore)
+		case identBoundToken{}:
+			bound = o.Value().(jwt.Token)
+		}
+	}
+
+	msg, err := jws.Parse(proof)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to parse proof`)
+	}
+	sigs := msg.Signatures()
+	if len(sigs) != 1 {
+		return nil, errors.New(`proof must have exactly one signature`)
+	}
+	hdrs := sigs[0].ProtectedHeaders()
+
+	alg := hdrs.Algorithm()
+	if !isAsymmetric(alg) {
+		return nil, errors.Errorf(`algorithm %s can not be used for DPoP proofs`, alg)
+	}
+	if len(algs) > 0 && !containsAlgorithm(algs, alg) {
+		return nil, errors.Errorf(`algorithm %s is not acceptable`, alg)
+	}
+
+	key := hdrs.JWK()
+	if key == nil {
+		return nil, errors.Errorf(`proof does not have a %s header`, jws.JWKKey)
+	}
+	if isPrivateKey(key) {
+		return nil, errors.Errorf(`%s header must not contain a private key`, jws.JWKKey)
+	}
+
+	htu, err := normalizeURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := jwt.Parse(proof,
+		jwt.WithVerify(alg, key),
+		jwt.WithTokenType(jwt.TokenTypeDPoP),
+		jwt.WithValidate(true),
+		jwt.WithClock(clock),
+		jwt.WithAcceptableSkew(skew),
+		jwt.WithRequiredClaim(jwt.JwtIDKey),
+		jwt.WithRequiredClaim(jwt.IssuedAtKey),
+		jwt.WithRequiredClaim(HTTPMethodKey),
+		jwt.WithRequiredClaim(HTTPURIKey),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to validate proof`)
+	}
+
+	if v, _ := t.Get(HTTPMethodKey); v != method {
+		return nil, errors.Errorf(`%s not satisfied: expected %q`, HTTPMethodKey, method)
+	}
+
+	v, _ := t.Get(HTTPURIKey)
+	s, ok := v.(string)
+	if !ok {
+		return nil, errors.Errorf(`invalid type for %s: %T`, HTTPURIKey, v)
+	}
+	if normalized, err := normalizeURI(s); err != nil || normalized != htu {
+		return nil, errors.Errorf(`%s not satisfied: expected %q`, HTTPURIKey, htu)
+	}
+
+	if now := clock.Now(); now.Sub(t.IssuedAt()) > maxAge+skew {
+		return nil, errors.Errorf(`%s not satisfied: proof is older than %s`, jwt.IssuedAtKey, maxAge)
+	}
+
+	if nonce != "" {
+		if err := compareClaim(t, NonceKey, nonce); err != nil {
+			return nil, err
+		}
+	}
+
+	if hasAccessToken {
+		if err := compareClaim(t, AccessTokenHashKey, AccessTokenHash(accessToken)); err != nil {
+			return nil, err
+		}
+	}
+
+	if bound != nil {
+		if err := jwt.Validate(bound, jwt.WithKeyBinding(key), jwt.WithClock(clock), jwt.WithAcceptableSkew(skew)); err != nil {
+			return nil, errors.Wrap(err, `access token is not bound to the key of the proof`)
+		}
+	}
+
+	// Record the proof only after all other checks have passed, so that
+	// proofs that are rejected do not use up their "jti"
+	if store != nil {
+		seen, err := store.Seen(ctx, t.JwtID(), t.IssuedAt().Add(maxAge+skew))
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to check %s`, jwt.JwtIDKey)
+		}
+		if seen {
+			return nil, errors.Errorf(`%s %q has already been used`, jwt.JwtIDKey, t.JwtID())
+		}
+	}
+	return key, nil
+}
+
+func compareClaim(t jwt.Token, name, expected string) error {
+	v, ok := t.Get(name)
+	if !ok {
+		return errors.Errorf(`required claim %s is missing`, name)
+	}
+	s, _ := v.(string)
+	if subtle.ConstantTimeCompare([]byte(s), []byte(expected)) != 1 {
+		return errors.Errorf(`%s not satisfied`, name)
+	}
+	return nil
+}
+
+// accessTokenFromRequest extracts the access token from the
+// "Authorization" header, if it uses the "DPoP" scheme
+func accessTokenFromRequest(req *http.Request) (string, bool) {
+	v := req.Header.Get("Authorization")
+	const scheme = "dpop "
+	if len(v) <= len(scheme) || !strings.EqualFold(v[:len(scheme)], scheme) {
+		return "", false
+	}
+	return strings.TrimSpace(v[len(scheme):]), true
+}
+
+// normalizeURI returns the URI without its query and fragment, with the
+// scheme and the host in lower case, and without the default port, so
+// that it can be compared to the "htu" claim (RFC 9449 section 4.3)
+func normalizeURI(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", errors.Wrapf(err, `failed to parse URI %q`, uri)
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return "", errors.Errorf(`URI %q must be absolute`, uri)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "https" && port == "443") || (u.Scheme == "http" && port == "80") {
+		u.Host = u.Hostname()
+		if strings.Contains(u.Host, ":") {
+			u.Host = "[" + u.Host + "]"
+		}
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+func isPrivateKey(key jwk.Key) bool {
+	if key.KeyType() == jwa.OctetSeq {
+		return true
+	}
+	_, ok := key.Get("d")
+	return ok
+}
+
+func isAsymmetric(alg jwa.SignatureAlgorithm) bool {
+	switch alg {
+	case "", jwa.NoSignature, jwa.HS256, jwa.HS384, jwa.HS512:
+		return false
+	}
+	return true
+}
+
+func containsAlgorithm(algs []jwa.SignatureAlgorithm, alg jwa.SignatureAlgorithm) bool {
+	for _, v := range algs {
+		if v == alg {
+			return true
+		}
+	}
+	return false
+}
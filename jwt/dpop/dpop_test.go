@@ -0,0 +1,252 @@
+package dpop_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/lestrrat-go/jwx/jwt/dpop"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessTokenHash(t *testing.T) {
+	t.Parallel()
+	// Example from RFC 9449 section 4.2
+	if !assert.Equal(t, "fUHyO2r2Z3DZ53EsNrWBb0xWXoaNy59IiKCAqksmQEo", dpop.AccessTokenHash("Kz~8mXK1EalYznwH-LC-1fBAo.4Ljp~zsPE_NeO.gxU"), `ath should match`) {
+		return
+	}
+}
+
+func TestProof(t *testing.T) {
+	t.Parallel()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		return
+	}
+	const uri = "https://resource.example.org/protected"
+	const accessToken = "Kz~8mXK1EalYznwH-LC-1fBAo.4Ljp~zsPE_NeO.gxU"
+
+	t.Run("Roundtrip", func(t *testing.T) {
+		t.Parallel()
+		proof, err := dpop.NewProof(http.MethodGet, uri+"?q=1#frag", jwa.ES256, priv, dpop.WithAccessToken(accessToken), dpop.WithNonce("eyJ7S_zG.eyJH0-Z.HX4w-7v"))
+		if !assert.NoError(t, err, `dpop.NewProof should succeed`) {
+			return
+		}
+
+		msg, err := jws.Parse(proof)
+		if !assert.NoError(t, err, `jws.Parse should succeed`) {
+			return
+		}
+		hdrs := msg.Signatures()[0].ProtectedHeaders()
+		if !assert.Equal(t, jwt.TokenTypeDPoP, hdrs.Type(), `typ should match`) {
+			return
+		}
+		if !assert.NotNil(t, hdrs.JWK(), `jwk header should exist`) {
+			return
+		}
+		if _, ok := hdrs.JWK().Get("d"); !assert.False(t, ok, `jwk header should not contain the private key`) {
+			return
+		}
+
+		key, err := dpop.Validate(proof, http.MethodGet, "HTTPS://Resource.Example.org:443/protected", dpop.WithAccessToken(accessToken), dpop.WithNonce("eyJ7S_zG.eyJH0-Z.HX4w-7v"))
+		if !assert.NoError(t, err, `dpop.Validate should succeed`) {
+			return
+		}
+		var raw ecdsa.PublicKey
+		if !assert.NoError(t, key.Raw(&raw), `key.Raw should succeed`) {
+			return
+		}
+		if !assert.True(t, raw.X.Cmp(priv.X) == 0 && raw.Y.Cmp(priv.Y) == 0, `public keys should match`) {
+			return
+		}
+	})
+	t.Run("Validation failures", func(t *testing.T) {
+		t.Parallel()
+		now := time.Now()
+		proof, err := dpop.NewProof(http.MethodPost, uri, jwa.ES256, priv, dpop.WithAccessToken(accessToken), dpop.WithClock(jwt.ClockFunc(func() time.Time { return now })))
+		if !assert.NoError(t, err, `dpop.NewProof should succeed`) {
+			return
+		}
+
+		testcases := []struct {
+			Name    string
+			Method  string
+			URI     string
+			Options []dpop.ValidateOption
+		}{
+			{Name: "htm", Method: http.MethodGet, URI: uri},
+			{Name: "htu", Method: http.MethodPost, URI: "https://resource.example.org/other"},
+			{Name: "ath", Method: http.MethodPost, URI: uri, Options: []dpop.ValidateOption{dpop.WithAccessToken("other")}},
+			{Name: "nonce", Method: http.MethodPost, URI: uri, Options: []dpop.ValidateOption{dpop.WithNonce("nonce")}},
+			{Name: "iat too old", Method: http.MethodPost, URI: uri, Options: []dpop.ValidateOption{dpop.WithClock(jwt.ClockFunc(func() time.Time { return now.Add(10 * time.Minute) }))}},
+			{Name: "iat in the future", Method: http.MethodPost, URI: uri, Options: []dpop.ValidateOption{dpop.WithClock(jwt.ClockFunc(func() time.Time { return now.Add(-time.Minute) }))}},
+			{Name: "algorithm", Method: http.MethodPost, URI: uri, Options: []dpop.ValidateOption{dpop.WithAcceptableAlgorithms(jwa.EdDSA)}},
+		}
+		for _, tc := range testcases {
+			_, err := dpop.Validate(proof, tc.Method, tc.URI, tc.Options...)
+			if !assert.Error(t, err, `dpop.Validate should fail (%s)`, tc.Name) {
+				return
+			}
+		}
+
+		_, err = dpop.Validate(proof, http.MethodPost, uri, dpop.WithClock(jwt.ClockFunc(func() time.Time { return now.Add(2 * time.Minute) })))
+		if !assert.NoError(t, err, `dpop.Validate should succeed within max age`) {
+			return
+		}
+	})
+	t.Run("Symmetric keys", func(t *testing.T) {
+		t.Parallel()
+		_, err := dpop.NewProof(http.MethodGet, uri, jwa.HS256, []byte("abracadabra"))
+		if !assert.Error(t, err, `dpop.NewProof should fail`) {
+			return
+		}
+
+		// A proof signed using a symmetric key embedded in the header
+		key, err := jwk.New([]byte("abracadabra"))
+		if !assert.NoError(t, err, `jwk.New should succeed`) {
+			return
+		}
+		hdrs := jws.NewHeaders()
+		hdrs.Set(jws.JWKKey, key)
+		tok := jwt.New()
+		tok.Set(jwt.JwtIDKey, "jti")
+		tok.Set(jwt.IssuedAtKey, time.Now())
+		tok.Set(dpop.HTTPMethodKey, http.MethodGet)
+		tok.Set(dpop.HTTPURIKey, uri)
+		proof, err := jwt.Sign(tok, jwa.HS256, []byte("abracadabra"), jwt.WithHeaders(hdrs), jwt.WithTokenType(jwt.TokenTypeDPoP))
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return
+		}
+		_, err = dpop.Validate(proof, http.MethodGet, uri)
+		if !assert.Error(t, err, `dpop.Validate should fail`) {
+			return
+		}
+	})
+	t.Run("Wrong typ", func(t *testing.T) {
+		t.Parallel()
+		pubkey, err := jwk.New(&priv.PublicKey)
+		if !assert.NoError(t, err, `jwk.New should succeed`) {
+			return
+		}
+		hdrs := jws.NewHeaders()
+		hdrs.Set(jws.JWKKey, pubkey)
+		tok := jwt.New()
+		tok.Set(jwt.JwtIDKey, "jti")
+		tok.Set(jwt.IssuedAtKey, time.Now())
+		tok.Set(dpop.HTTPMethodKey, http.MethodGet)
+		tok.Set(dpop.HTTPURIKey, uri)
+		proof, err := jwt.Sign(tok, jwa.ES256, priv, jwt.WithHeaders(hdrs))
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return
+		}
+		_, err = dpop.Validate(proof, http.MethodGet, uri)
+		if !assert.Error(t, err, `dpop.Validate should fail`) {
+			return
+		}
+	})
+	t.Run("Replay", func(t *testing.T) {
+		t.Parallel()
+		store := jwt.NewMemoryJtiStore(0, 0)
+		proof, err := dpop.NewProof(http.MethodGet, uri, jwa.ES256, priv)
+		if !assert.NoError(t, err, `dpop.NewProof should succeed`) {
+			return
+		}
+		_, err = dpop.Validate(proof, http.MethodGet, uri, dpop.WithJtiStore(store))
+		if !assert.NoError(t, err, `dpop.Validate should succeed`) {
+			return
+		}
+		_, err = dpop.Validate(proof, http.MethodGet, uri, dpop.WithJtiStore(store))
+		if !assert.Error(t, err, `dpop.Validate should fail for replayed proofs`) {
+			return
+		}
+	})
+}
+
+func TestValidateRequest(t *testing.T) {
+	t.Parallel()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		return
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err, `ecdsa.GenerateKey should succeed`) {
+		return
+	}
+
+	bound := jwt.New()
+	if !assert.NoError(t, jwt.BindToKey(bound, &priv.PublicKey), `jwt.BindToKey should succeed`) {
+		return
+	}
+	const accessToken = "access-token"
+
+	newRequest := func(t *testing.T, key interface{}, options ...dpop.ProofOption) *http.Request {
+		t.Helper()
+		proof, err := dpop.NewProof(http.MethodGet, "https://resource.example.org/protected", jwa.ES256, key, options...)
+		if !assert.NoError(t, err, `dpop.NewProof should succeed`) {
+			t.FailNow()
+		}
+		req := httptest.NewRequest(http.MethodGet, "https://resource.example.org/protected?x=y", nil)
+		req.TLS = &tls.ConnectionState{}
+		req.Header.Set(dpop.HeaderName, string(proof))
+		req.Header.Set("Authorization", "DPoP "+accessToken)
+		return req
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		t.Parallel()
+		req := newRequest(t, priv, dpop.WithAccessToken(accessToken))
+		_, err := dpop.ValidateRequest(req, dpop.WithBoundToken(bound))
+		if !assert.NoError(t, err, `dpop.ValidateRequest should succeed`) {
+			return
+		}
+	})
+	t.Run("Missing ath", func(t *testing.T) {
+		t.Parallel()
+		req := newRequest(t, priv)
+		_, err := dpop.ValidateRequest(req)
+		if !assert.Error(t, err, `dpop.ValidateRequest should fail`) {
+			return
+		}
+	})
+	t.Run("Key is not bound", func(t *testing.T) {
+		t.Parallel()
+		req := newRequest(t, other, dpop.WithAccessToken(accessToken))
+		_, err := dpop.ValidateRequest(req, dpop.WithBoundToken(bound))
+		if !assert.Error(t, err, `dpop.ValidateRequest should fail`) {
+			return
+		}
+	})
+	t.Run("Multiple headers", func(t *testing.T) {
+		t.Parallel()
+		req := newRequest(t, priv, dpop.WithAccessToken(accessToken))
+		req.Header.Add(dpop.HeaderName, req.Header.Get(dpop.HeaderName))
+		_, err := dpop.ValidateRequest(req)
+		if !assert.Error(t, err, `dpop.ValidateRequest should fail`) {
+			return
+		}
+	})
+	t.Run("Request URI", func(t *testing.T) {
+		t.Parallel()
+		req := newRequest(t, priv, dpop.WithAccessToken(accessToken))
+		req.TLS = nil
+		_, err := dpop.ValidateRequest(req)
+		if !assert.Error(t, err, `dpop.ValidateRequest should fail for mismatched scheme`) {
+			return
+		}
+		_, err = dpop.ValidateRequest(req, dpop.WithRequestURI("https://resource.example.org/protected"))
+		if !assert.NoError(t, err, `dpop.ValidateRequest should succeed`) {
+			return
+		}
+	})
+}
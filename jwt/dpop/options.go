@@ -0,0 +1,140 @@
+package dpop
+
+import (
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/lestrrat-go/option"
+)
+
+type identAcceptableAlgorithms struct{}
+type identAcceptableSkew struct{}
+type identAccessToken struct{}
+type identBoundToken struct{}
+type identClock struct{}
+type identJtiStore struct{}
+type identJwtID struct{}
+type identMaxAge struct{}
+type identNonce struct{}
+type identRequestURI struct{}
+
+// ProofOption describes an option that can be passed to `dpop.NewProof()`
+type ProofOption interface {
+	option.Interface
+	proofOption()
+}
+
+// ValidateOption describes an option that can be passed to
+// `dpop.Validate()` and `dpop.ValidateRequest()`
+type ValidateOption interface {
+	option.Interface
+	validateOption()
+}
+
+// Option describes an option that can be passed to both
+// `dpop.NewProof()` and `dpop.Validate()`
+type Option interface {
+	option.Interface
+	proofOption()
+	validateOption()
+}
+
+type proofOption struct {
+	option.Interface
+}
+
+func (*proofOption) proofOption() {}
+
+type validateOption struct {
+	option.Interface
+}
+
+func (*validateOption) validateOption() {}
+
+type sharedOption struct {
+	option.Interface
+}
+
+func (*sharedOption) proofOption()    {}
+func (*sharedOption) validateOption() {}
+
+// WithAccessToken specifies the access token that is presented along
+// with the proof.
+//
+// When passed to `dpop.NewProof()`, the "ath" claim is set to the hash
+// of the access token.
+//
+// When passed to `dpop.Validate()`, the proof must have an "ath" claim
+// that matches the hash of the access token.
+func WithAccessToken(token string) Option {
+	return &sharedOption{option.New(identAccessToken{}, token)}
+}
+
+// WithNonce specifies the nonce provided by the server in the
+// "DPoP-Nonce" HTTP header.
+//
+// When passed to `dpop.NewProof()`, the "nonce" claim is set to the
+// given value.
+//
+// When passed to `dpop.Validate()`, the proof must have a "nonce" claim
+// that matches the given value.
+func WithNonce(nonce string) Option {
+	return &sharedOption{option.New(identNonce{}, nonce)}
+}
+
+// WithClock specifies the clock used to set the "iat" claim by
+// `dpop.NewProof()`, and to check it by `dpop.Validate()`
+func WithClock(c jwt.Clock) Option {
+	return &sharedOption{option.New(identClock{}, c)}
+}
+
+// WithJwtID specifies the value of the "jti" claim. By default, a
+// random value is generated. As the server may reject proofs whose
+// "jti" has already been used, this option should only be used for
+// testing.
+func WithJwtID(jti string) ProofOption {
+	return &proofOption{option.New(identJwtID{}, jti)}
+}
+
+// WithMaxAge specifies how long the proof is accepted after it has been
+// issued, according to its "iat" claim. The default is 5 minutes.
+func WithMaxAge(d time.Duration) ValidateOption {
+	return &validateOption{option.New(identMaxAge{}, d)}
+}
+
+// WithAcceptableSkew specifies the clock skew that is tolerated when
+// checking the "iat" claim
+func WithAcceptableSkew(d time.Duration) ValidateOption {
+	return &validateOption{option.New(identAcceptableSkew{}, d)}
+}
+
+// WithAcceptableAlgorithms restricts the signature algorithms that the
+// proof may be signed with. By default, all asymmetric algorithms are
+// accepted. Symmetric algorithms and "none" are always rejected.
+func WithAcceptableAlgorithms(algs ...jwa.SignatureAlgorithm) ValidateOption {
+	return &validateOption{option.New(identAcceptableAlgorithms{}, algs)}
+}
+
+// WithJtiStore specifies a store that records the "jti" claims of the
+// proofs, so that proofs that are replayed are rejected. The records
+// are kept until the proof is no longer accepted (see `dpop.WithMaxAge()`).
+func WithJtiStore(store jwt.JtiStore) ValidateOption {
+	return &validateOption{option.New(identJtiStore{}, store)}
+}
+
+// WithBoundToken specifies the access token that is presented along with
+// the proof, after it has been parsed and verified. The "jkt" member of
+// its "cnf" claim must match the thumbprint of the key of the proof (see
+// `jwt.BindToKey()`).
+func WithBoundToken(t jwt.Token) ValidateOption {
+	return &validateOption{option.New(identBoundToken{}, t)}
+}
+
+// WithRequestURI specifies the URI of the request that is compared to
+// the "htu" claim by `dpop.ValidateRequest()`. By default, the URI is
+// computed from the request, which may not match the URI used by the
+// client when the server is behind a reverse proxy.
+func WithRequestURI(uri string) ValidateOption {
+	return &validateOption{option.New(identRequestURI{}, uri)}
+}
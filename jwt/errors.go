@@ -0,0 +1,179 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The following errors are the categories of validation failures reported
+// by `jwt.Validate()`, and by `jwt.Parse()` when validation is enabled.
+// Use `errors.Is()` to test for them:
+//
+//     if errors.Is(err, jwt.ErrTokenExpired) {
+//       // ask the client to refresh the token
+//     }
+//
+// The failures are reported as `*jwt.ValidationError`, which can be
+// obtained using `errors.As()` to find out which claim failed.
+var (
+	// ErrTokenExpired is reported when the "exp" claim is in the past
+	ErrTokenExpired = errors.New(`token is expired`)
+
+	// ErrTokenNotYetValid is reported when the "nbf" claim is in the future
+	ErrTokenNotYetValid = errors.New(`token is not valid yet`)
+
+	// ErrInvalidIssuedAt is reported when the "iat" claim is in the future
+	ErrInvalidIssuedAt = errors.New(`token was issued in the future`)
+
+	// ErrMaxDeltaExceeded is reported when the difference between two
+	// time based claims exceeds the limit given by `jwt.WithMaxDelta()`
+	ErrMaxDeltaExceeded = errors.New(`maximum time delta exceeded`)
+
+	// ErrInvalidIssuer is reported when the "iss" claim does not match
+	ErrInvalidIssuer = errors.New(`invalid issuer`)
+
+	// ErrInvalidSubject is reported when the "sub" claim does not match
+	ErrInvalidSubject = errors.New(`invalid subject`)
+
+	// ErrInvalidAudience is reported when the "aud" claim does not match
+	ErrInvalidAudience = errors.New(`invalid audience`)
+
+	// ErrInvalidJwtID is reported when the "jti" claim does not match
+	ErrInvalidJwtID = errors.New(`invalid JWT ID`)
+
+	// ErrInvalidClaimValue is reported when a claim does not have the
+	// value given by `jwt.WithClaimValue()`
+	ErrInvalidClaimValue = errors.New(`invalid claim value`)
+
+	// ErrInvalidClaimFormat is reported by `jwt.WithStrictClaims()` when
+	// a registered claim does not conform to RFC 7519
+	ErrInvalidClaimFormat = errors.New(`invalid claim format`)
+
+	// ErrMissingClaim is reported when a required claim is missing
+	ErrMissingClaim = errors.New(`missing required claim`)
+
+	// ErrProhibitedClaim is reported when a claim or a claim value given
+	// by `jwt.WithProhibitedClaims()` or `jwt.WithProhibitedClaimValue()`
+	// is present
+	ErrProhibitedClaim = errors.New(`prohibited claim`)
+
+	// ErrInvalidKeyBinding is reported when the "cnf" claim does not
+	// match the keys given by `jwt.WithKeyBinding()` or `jwt.WithPoP()`
+	ErrInvalidKeyBinding = errors.New(`invalid key binding`)
+
+	// ErrInsufficientAuthentication is reported when the "acr" or "amr"
+	// claims do not satisfy `jwt.WithMinimumACR()` or `jwt.WithRequiredAMR()`
+	ErrInsufficientAuthentication = errors.New(`insufficient user authentication`)
+
+	// ErrTokenReplayed is reported when the "jti" claim has already been
+	// recorded in the store given by `jwt.WithJtiStore()`
+	ErrTokenReplayed = errors.New(`token has already been used`)
+
+	// ErrSessionExpired is reported by `jwt.Refresh()` when the session
+	// has lasted longer than the MaxLifetime of the RefreshPolicy, and
+	// the user must authenticate again
+	ErrSessionExpired = errors.New(`session has expired`)
+
+	// ErrClaimsModified is reported when the claims protected by
+	// `jwt.ProtectClaims()` do not match the "claims_digest" claim given
+	// by `jwt.WithProtectedClaims()`
+	ErrClaimsModified = errors.New(`protected claims have been modified`)
+)
+
+// ValidationError describes a claim that failed validation. It matches
+// one of the `jwt.ErrXXX` categories when tested using `errors.Is()`.
+type ValidationError struct {
+	// Claim is the name of the claim that failed validation
+	Claim string
+
+	category error
+	message  string
+	cause    error
+}
+
+func newValidationError(category error, claim, message string, cause error) *ValidationError {
+	return &ValidationError{
+		Claim:    claim,
+		category: category,
+		message:  message,
+		cause:    cause,
+	}
+}
+
+func (e *ValidationError) Error() string {
+	return e.message
+}
+
+// Is returns true if `target` is the category of the error
+func (e *ValidationError) Is(target error) bool {
+	return e.category == target
+}
+
+// Unwrap returns the underlying cause of the error, if any
+func (e *ValidationError) Unwrap() error {
+	return e.cause
+}
+
+func claimNotSatisfied(category error, claim string) *ValidationError {
+	return newValidationError(category, claim, fmt.Sprintf(`%s not satisfied`, claim), nil)
+}
+
+// ValidationErrors is returned by `jwt.Validate()` when the
+// `jwt.WithMultipleErrors(true)` option is specified, and lists all of
+// the constraints that the token failed to satisfy, in the order that
+// they were checked. `errors.Is()` and `errors.As()` match if any of
+// the errors matches.
+type ValidationErrors []error
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, `; `)
+}
+
+// Is returns true if any of the errors matches `target`
+func (e ValidationErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error that matches `target`
+func (e ValidationErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// errorCollector accumulates the failures found by `jwt.Validate()`
+type errorCollector struct {
+	multiple bool
+	errs     ValidationErrors
+}
+
+// add records a failure, and returns true if validation should stop
+func (c *errorCollector) add(err error) bool {
+	c.errs = append(c.errs, err)
+	return !c.multiple
+}
+
+// err returns the result of the validation: the first failure, or all
+// of them if multiple errors were requested
+func (c *errorCollector) err() error {
+	if len(c.errs) == 0 {
+		return nil
+	}
+	if !c.multiple {
+		return c.errs[0]
+	}
+	return c.errs
+}
@@ -0,0 +1,142 @@
+package jwt
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const authorizationHeader = "Authorization"
+
+type requestSource struct {
+	kind string
+	key  string
+}
+
+// ParseRequest looks for a token in an HTTP request, and parses it
+// using `jwt.Parse()`. The same options are accepted, so the token can
+// be verified and validated in a single call:
+//
+//     token, err := jwt.ParseRequest(req,
+//       jwt.WithKeySet(keyset),
+//       jwt.WithValidate(true),
+//     )
+//
+// The locations to look for the token are specified using the
+// `jwt.WithHeaderKey()`, `jwt.WithFormKey()`, `jwt.WithQueryKey()` and
+// `jwt.WithCookieKey()` options. They are searched in that order
+// (regardless of the order of the options), and the first location
+// that contains a token is used. If none of them are specified, the
+// token is looked for in the "Authorization" header, which must use
+// the "Bearer" scheme (RFC 6750).
+//
+// If the token is found in a cookie, the cookie can be obtained using
+// the `jwt.WithCookie()` option, e.g. to refresh it in the response.
+func ParseRequest(req *http.Request, options ...ParseOption) (Token, error) {
+	var headers, forms, queries, cookies []requestSource
+	var dst **http.Cookie
+	for _, o := range options {
+		switch o.Ident() {
+		case identCookie{}:
+			dst = o.Value().(**http.Cookie)
+		case identHeaderKey{}:
+			headers = append(headers, requestSource{kind: "header", key: o.Value().(string)})
+		case identFormKey{}:
+			forms = append(forms, requestSource{kind: "form field", key: o.Value().(string)})
+		case identQueryKey{}:
+			queries = append(queries, requestSource{kind: "query parameter", key: o.Value().(string)})
+		case identCookieKey{}:
+			cookies = append(cookies, requestSource{kind: "cookie", key: o.Value().(string)})
+		}
+	}
+
+	sources := append(append(append(headers, forms...), queries...), cookies...)
+	if len(sources) == 0 {
+		sources = []requestSource{{kind: "header", key: authorizationHeader}}
+	}
+
+	names := make([]string, 0, len(sources))
+	for _, src := range sources {
+		if src.kind == "cookie" {
+			c, err := req.Cookie(src.key)
+			if err == nil && c.Value != "" {
+				return parseCookie(c, dst, options...)
+			}
+			names = append(names, src.kind+" "+src.key)
+			continue
+		}
+
+		v, err := src.lookup(req)
+		if err != nil {
+			return nil, err
+		}
+		if v != "" {
+			return ParseString(v, options...)
+		}
+		names = append(names, src.kind+" "+src.key)
+	}
+	return nil, errors.Errorf(`failed to find a token in the request (looked in %s)`, strings.Join(names, ", "))
+}
+
+func (src requestSource) lookup(req *http.Request) (string, error) {
+	switch src.kind {
+	case "header":
+		v := strings.TrimSpace(req.Header.Get(src.key))
+		if http.CanonicalHeaderKey(src.key) != authorizationHeader {
+			return v, nil
+		}
+		const scheme = "bearer "
+		if len(v) < len(scheme) || !strings.EqualFold(v[:len(scheme)], scheme) {
+			return "", nil
+		}
+		return strings.TrimSpace(v[len(scheme):]), nil
+	case "form field":
+		if err := req.ParseForm(); err != nil {
+			return "", errors.Wrap(err, `failed to parse form`)
+		}
+		return req.PostForm.Get(src.key), nil
+	case "query parameter":
+		return req.URL.Query().Get(src.key), nil
+	}
+	return "", nil
+}
+
+// ParseCookie parses the token stored in the cookie `name` of an HTTP
+// request, using `jwt.Parse()`. It is the same as calling
+// `jwt.ParseRequest()` with only `jwt.WithCookieKey(name)`, and the
+// locations specified by other options are ignored.
+//
+// Pass `jwt.WithCookie()` to obtain the cookie, e.g. so that a middleware
+// can rotate the token before it expires:
+//
+//     var cookie *http.Cookie
+//     token, err := jwt.ParseCookie(req, "session", jwt.WithKeySet(keyset), jwt.WithCookie(&cookie))
+//     ...
+//     if time.Until(token.Expiration()) < refreshWindow {
+//       cookie.Value = string(newToken)
+//       cookie.Expires = ...
+//       http.SetCookie(w, cookie)
+//     }
+func ParseCookie(req *http.Request, name string, options ...ParseOption) (Token, error) {
+	var dst **http.Cookie
+	for _, o := range options {
+		switch o.Ident() {
+		case identCookie{}:
+			dst = o.Value().(**http.Cookie)
+		}
+	}
+
+	c, err := req.Cookie(name)
+	if err != nil || c.Value == "" {
+		return nil, errors.Errorf(`failed to find a token in the request (looked in cookie %s)`, name)
+	}
+	return parseCookie(c, dst, options...)
+}
+
+func parseCookie(c *http.Cookie, dst **http.Cookie, options ...ParseOption) (Token, error) {
+	if dst != nil {
+		*dst = c
+	}
+	return ParseString(c.Value, options...)
+}
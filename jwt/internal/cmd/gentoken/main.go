@@ -39,6 +39,7 @@ type tokenField struct {
 	method     string
 	returnType string
 	key        string
+	keyRef     string // constant that defines the key in another package
 	typ        string
 	Comment    string
 	elemtyp    string
@@ -337,6 +338,71 @@ func init() {
 					hasGet:     true,
 					hasAccept:  true,
 				},
+				{
+					name:       "authTime",
+					method:     "AuthTime",
+					returnType: "time.Time",
+					typ:        "types.NumericDate",
+					key:        "auth_time",
+					keyRef:     `jwt.AuthTimeKey`,
+					Comment:    `https://openid.net/specs/openid-connect-core-1_0.html#IDToken`,
+					hasGet:     true,
+					hasAccept:  true,
+				},
+				{
+					name:       "nonce",
+					method:     "Nonce",
+					returnType: "string",
+					typ:        "string",
+					key:        "nonce",
+					Comment:    `https://openid.net/specs/openid-connect-core-1_0.html#IDToken`,
+				},
+				{
+					name:       "authContextClass",
+					method:     "AuthContextClass",
+					returnType: "string",
+					typ:        "string",
+					key:        "acr",
+					keyRef:     `jwt.AuthContextClassKey`,
+					Comment:    `https://openid.net/specs/openid-connect-core-1_0.html#IDToken`,
+				},
+				{
+					name:       "authMethods",
+					method:     "AuthMethods",
+					returnType: "[]string",
+					typ:        "types.StringList",
+					key:        "amr",
+					keyRef:     `jwt.AuthMethodsKey`,
+					Comment:    `https://openid.net/specs/openid-connect-core-1_0.html#IDToken`,
+					isList:     true,
+					hasAccept:  true,
+					hasGet:     true,
+					elemtyp:    `string`,
+				},
+				{
+					name:       "authorizedParty",
+					method:     "AuthorizedParty",
+					returnType: "string",
+					typ:        "string",
+					key:        "azp",
+					Comment:    `https://openid.net/specs/openid-connect-core-1_0.html#IDToken`,
+				},
+				{
+					name:       "accessTokenHash",
+					method:     "AccessTokenHash",
+					returnType: "string",
+					typ:        "string",
+					key:        "at_hash",
+					Comment:    `https://openid.net/specs/openid-connect-core-1_0.html#CodeIDToken`,
+				},
+				{
+					name:       "codeHash",
+					method:     "CodeHash",
+					returnType: "string",
+					typ:        "string",
+					key:        "c_hash",
+					Comment:    `https://openid.net/specs/openid-connect-core-1_0.html#HybridIDToken`,
+				},
 			}...),
 		},
 	}
@@ -352,6 +418,10 @@ func generateToken(tt tokenType) error {
 
 	fmt.Fprintf(&buf, "\n\nconst (")
 	for _, f := range fields {
+		if f.keyRef != "" {
+			fmt.Fprintf(&buf, "\n%sKey = %s", f.method, f.keyRef)
+			continue
+		}
 		fmt.Fprintf(&buf, "\n%sKey = %s", f.method, strconv.Quote(f.key))
 	}
 	fmt.Fprintf(&buf, "\n)") // end const
@@ -367,6 +437,24 @@ func generateToken(tt tokenType) error {
 		fmt.Fprintf(&buf, "\n//\n// Embedding jwt.Token into another struct is not recommended, becase")
 		fmt.Fprintf(&buf, "\n// jwt.Token needs to handle private claims, and this really does not")
 		fmt.Fprintf(&buf, "\n// work well when it is embedded in other structure")
+		fmt.Fprintf(&buf, "\n//\n// All methods on the standard implementation are safe for concurrent use,")
+		fmt.Fprintf(&buf, "\n// so a single token may be shared between goroutines (e.g. in HTTP")
+		fmt.Fprintf(&buf, "\n// middleware) and modified using `Set()` and `Remove()`.")
+		fmt.Fprintf(&buf, "\n// The only exception is the map returned by `PrivateClaims()`, which")
+		fmt.Fprintf(&buf, "\n// is the token's internal storage.")
+		fmt.Fprintf(&buf, "\n// WARNING: DO NOT USE PrivateClaims() IF YOU HAVE CONCURRENT CODE ACCESSING THEM.")
+		fmt.Fprintf(&buf, "\n// Use `AsMap()` to get a copy of the entire token, or use `Iterate()` instead")
+		fmt.Fprintf(&buf, "\n//\n// `AsMap()` returns the time based claims (\"exp\", \"iat\" and \"nbf\") as")
+		fmt.Fprintf(&buf, "\n// time.Time values, and \"aud\" as a []string. `jwt.FromMap()` performs")
+		fmt.Fprintf(&buf, "\n// the reverse conversion.")
+		fmt.Fprintf(&buf, "\n//\n// `Keys()`, `Iterate()`, `Walk()` and `MarshalJSON()` visit both standard")
+		fmt.Fprintf(&buf, "\n// and private claims in lexical order of their names, so that their output")
+		fmt.Fprintf(&buf, "\n// is deterministic.")
+		fmt.Fprintf(&buf, "\n//\n// Tokens implement encoding.TextMarshaler and encoding.BinaryMarshaler")
+		fmt.Fprintf(&buf, "\n// (and their Unmarshaler counterparts) using the JSON representation of")
+		fmt.Fprintf(&buf, "\n// the claims, so that they can be stored in caches, databases, and the like.")
+		fmt.Fprintf(&buf, "\n// Note that this is the unsigned claims set: use `jwt.Sign()` to produce")
+		fmt.Fprintf(&buf, "\n// a token that can be verified by others.")
 	}
 	fmt.Fprintf(&buf, "\ntype %s interface {", tt.ifName)
 	for _, field := range fields {
@@ -376,6 +464,7 @@ func generateToken(tt tokenType) error {
 	fmt.Fprintf(&buf, "\nGet(string) (interface{}, bool)")
 	fmt.Fprintf(&buf, "\nSet(string, interface{}) error")
 	fmt.Fprintf(&buf, "\nRemove(string) error")
+	fmt.Fprintf(&buf, "\nKeys() []string")
 	if tt.pkg != "jwt" {
 		fmt.Fprintf(&buf, "\nClone() (jwt.Token, error)")
 	} else {
@@ -384,6 +473,10 @@ func generateToken(tt tokenType) error {
 	fmt.Fprintf(&buf, "\nIterate(context.Context) Iterator")
 	fmt.Fprintf(&buf, "\nWalk(context.Context, Visitor) error")
 	fmt.Fprintf(&buf, "\nAsMap(context.Context) (map[string]interface{}, error)")
+	fmt.Fprintf(&buf, "\nMarshalText() ([]byte, error)")
+	fmt.Fprintf(&buf, "\nUnmarshalText([]byte) error")
+	fmt.Fprintf(&buf, "\nMarshalBinary() ([]byte, error)")
+	fmt.Fprintf(&buf, "\nUnmarshalBinary([]byte) error")
 	fmt.Fprintf(&buf, "\n}")
 
 	fmt.Fprintf(&buf, "\ntype %s struct {", tt.structName)
@@ -580,9 +673,23 @@ func generateToken(tt tokenType) error {
 	fmt.Fprintf(&buf, "\nfor k, v := range t.privateClaims {")
 	fmt.Fprintf(&buf, "\npairs = append(pairs, &ClaimPair{Key: k, Value: v})")
 	fmt.Fprintf(&buf, "\n}")
+	fmt.Fprintf(&buf, "\nsort.Slice(pairs, func(i, j int) bool {")
+	fmt.Fprintf(&buf, "\nreturn pairs[i].Key.(string) < pairs[j].Key.(string)")
+	fmt.Fprintf(&buf, "\n})")
 	fmt.Fprintf(&buf, "\nreturn pairs")
 	fmt.Fprintf(&buf, "\n}") // end of (h *stdHeaders) iterate(...)
 
+	fmt.Fprintf(&buf, "\n\n// Keys returns the names of all claims, both standard and private,")
+	fmt.Fprintf(&buf, "\n// stored in the token in lexical order")
+	fmt.Fprintf(&buf, "\nfunc (t *%s) Keys() []string {", tt.structName)
+	fmt.Fprintf(&buf, "\npairs := t.makePairs()")
+	fmt.Fprintf(&buf, "\nkeys := make([]string, len(pairs))")
+	fmt.Fprintf(&buf, "\nfor i, pair := range pairs {")
+	fmt.Fprintf(&buf, "\nkeys[i] = pair.Key.(string)")
+	fmt.Fprintf(&buf, "\n}")
+	fmt.Fprintf(&buf, "\nreturn keys")
+	fmt.Fprintf(&buf, "\n}")
+
 	fmt.Fprintf(&buf, "\n\nfunc (t *stdToken) UnmarshalJSON(buf []byte) error {")
 	fmt.Fprintf(&buf, "\nt.mu.Lock()")
 	fmt.Fprintf(&buf, "\ndefer t.mu.Unlock()")
@@ -668,15 +775,17 @@ func generateToken(tt tokenType) error {
 		}
 	}
 
-	fmt.Fprintf(&buf, "\n\nfunc (t %s) MarshalJSON() ([]byte, error) {", tt.structName)
-	fmt.Fprintf(&buf, "\nt.mu.RLock()")
-	fmt.Fprintf(&buf, "\ndefer t.mu.RUnlock()")
-	fmt.Fprintf(&buf, "\nctx, cancel := context.WithCancel(context.Background())")
-	fmt.Fprintf(&buf, "\ndefer cancel()")
+	// This must be a pointer receiver, as copying the token reads its
+	// fields without holding the lock.
+	//
+	// makePairs() takes a snapshot of the claims while holding the read
+	// lock, so we must not hold the lock here: recursive read locking can
+	// deadlock when another goroutine is waiting on the write lock (e.g.
+	// in Set())
+	fmt.Fprintf(&buf, "\n\nfunc (t *%s) MarshalJSON() ([]byte, error) {", tt.structName)
 	fmt.Fprintf(&buf, "\ndata := make(map[string]interface{})")
 	fmt.Fprintf(&buf, "\nfields := make([]string, 0, %d)", len(fields))
-	fmt.Fprintf(&buf, "\nfor iter := t.Iterate(ctx); iter.Next(ctx); {")
-	fmt.Fprintf(&buf, "\npair := iter.Pair()")
+	fmt.Fprintf(&buf, "\nfor _, pair := range t.makePairs() {")
 	fmt.Fprintf(&buf, "\nfields = append(fields, pair.Key.(string))")
 	fmt.Fprintf(&buf, "\ndata[pair.Key.(string)] = pair.Value")
 	fmt.Fprintf(&buf, "\n}")
@@ -709,7 +818,7 @@ func generateToken(tt tokenType) error {
 			}
 		}
 		fmt.Fprintf(&buf, ":")
-		fmt.Fprintf(&buf, "\nenc.Encode(v.Unix())")
+		fmt.Fprintf(&buf, "\nbuf.WriteString(types.FormatNumericDate(v))")
 		fmt.Fprintf(&buf, "\ndefault:")
 		fmt.Fprintf(&buf, "\nif err := enc.Encode(v); err != nil {")
 		fmt.Fprintf(&buf, "\nreturn nil, errors.Wrapf(err, `failed to marshal field %%s`, f)")
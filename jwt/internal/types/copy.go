@@ -0,0 +1,87 @@
+package types
+
+import "reflect"
+
+// DeepCopy returns a copy of v that does not share any maps, slices or
+// pointers with it. Maps, slices, arrays, pointers and the exported
+// fields of structs are copied recursively, including those held in
+// interface values. Unexported fields of structs are copied as is, so
+// types such as `openid.AddressClaim` that only replace (rather than
+// modify) the values their unexported fields point to are safe to copy.
+func DeepCopy(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	c := copier{seen: make(map[pointer]reflect.Value)}
+	return c.copy(reflect.ValueOf(v)).Interface()
+}
+
+type pointer struct {
+	typ  reflect.Type
+	addr uintptr
+}
+
+type copier struct {
+	// seen maps the pointers that have been copied to their copies, so
+	// that cyclic structures are copied as such
+	seen map[pointer]reflect.Value
+}
+
+func (c *copier) copy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		dst := reflect.New(v.Type()).Elem()
+		dst.Set(c.copy(v.Elem()))
+		return dst
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		key := pointer{typ: v.Type(), addr: v.Pointer()}
+		if dst, ok := c.seen[key]; ok {
+			return dst
+		}
+		dst := reflect.New(v.Type().Elem())
+		c.seen[key] = dst
+		dst.Elem().Set(c.copy(v.Elem()))
+		return dst
+	case reflect.Struct:
+		dst := reflect.New(v.Type()).Elem()
+		dst.Set(v)
+		for i := 0; i < dst.NumField(); i++ {
+			if f := dst.Field(i); f.CanSet() {
+				f.Set(c.copy(v.Field(i)))
+			}
+		}
+		return dst
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		dst := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			dst.SetMapIndex(iter.Key(), c.copy(iter.Value()))
+		}
+		return dst
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		dst := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			dst.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return dst
+	case reflect.Array:
+		dst := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			dst.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return dst
+	default:
+		return v
+	}
+}
@@ -2,6 +2,8 @@ package types
 
 import (
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/lestrrat-go/jwx/internal/json"
@@ -21,7 +23,131 @@ func (n *NumericDate) Get() time.Time {
 	return n.Time
 }
 
-func numericToTime(v interface{}, t *time.Time) bool {
+// MaxPrecision is the maximum number of digits after the decimal point
+// of a NumericDate, i.e. nanoseconds
+const MaxPrecision = 9
+
+var parsePrecision uint32
+var formatPrecision uint32
+var rejectFractional uint32
+
+// ParsePrecision returns the number of digits after the decimal point
+// that are retained when a NumericDate is parsed
+func ParsePrecision() int {
+	return int(atomic.LoadUint32(&parsePrecision))
+}
+
+// SetParsePrecision changes the number of digits after the decimal point
+// that are retained when a NumericDate is parsed. The remaining digits are
+// truncated.
+func SetParsePrecision(v int) {
+	atomic.StoreUint32(&parsePrecision, clampPrecision(v))
+}
+
+// FormatPrecision returns the number of digits after the decimal point
+// that are written when a NumericDate is serialized
+func FormatPrecision() int {
+	return int(atomic.LoadUint32(&formatPrecision))
+}
+
+// SetFormatPrecision changes the number of digits after the decimal point
+// that are written when a NumericDate is serialized
+func SetFormatPrecision(v int) {
+	atomic.StoreUint32(&formatPrecision, clampPrecision(v))
+}
+
+// RejectFractional reports if NumericDate values that are not integers
+// are rejected when they are parsed
+func RejectFractional() bool {
+	return atomic.LoadUint32(&rejectFractional) == 1
+}
+
+// SetRejectFractional changes whether NumericDate values that are not
+// integers are rejected when they are parsed
+func SetRejectFractional(v bool) {
+	var u uint32
+	if v {
+		u = 1
+	}
+	atomic.StoreUint32(&rejectFractional, u)
+}
+
+func clampPrecision(v int) uint32 {
+	if v < 0 {
+		return 0
+	}
+	if v > MaxPrecision {
+		return MaxPrecision
+	}
+	return uint32(v)
+}
+
+// FormatNumericDate returns the JSON representation of t as a NumericDate,
+// with FormatPrecision() digits after the decimal point
+func FormatNumericDate(t time.Time) string {
+	precision := FormatPrecision()
+	if precision == 0 {
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+
+	sec, nsec := t.Unix(), int64(t.Nanosecond())
+	var sign string
+	if sec < 0 && nsec > 0 {
+		// t.Unix() rounds towards negative infinity
+		sign = "-"
+		sec, nsec = -(sec + 1), 1e9-nsec
+	} else if sec < 0 {
+		sign = "-"
+		sec = -sec
+	}
+
+	frac := strconv.FormatInt(nsec+1e9, 10)[1 : 1+precision]
+	return sign + strconv.FormatInt(sec, 10) + "." + frac
+}
+
+// parseNumericDate parses the decimal representation of a NumericDate.
+// Exponents are accepted, as some encoders use them for large values.
+func parseNumericDate(s string) (time.Time, error) {
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, errors.Errorf(`invalid epoch value %#v`, s)
+		}
+		s = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	intpart, fracpart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intpart, fracpart = s[:i], s[i+1:]
+	}
+
+	sec, err := strconv.ParseInt(intpart, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Errorf(`invalid epoch value %#v`, s)
+	}
+	for _, c := range fracpart {
+		if c < '0' || c > '9' {
+			return time.Time{}, errors.Errorf(`invalid epoch value %#v`, s)
+		}
+	}
+	if RejectFractional() && strings.Trim(fracpart, "0") != "" {
+		return time.Time{}, errors.Errorf(`epoch value %#v is not an integer`, s)
+	}
+
+	if precision := ParsePrecision(); len(fracpart) > precision {
+		fracpart = fracpart[:precision]
+	}
+	var nsec int64
+	if fracpart != "" {
+		nsec, _ = strconv.ParseInt((fracpart + "000000000")[:MaxPrecision], 10, 64)
+	}
+	if strings.HasPrefix(intpart, "-") {
+		nsec = -nsec
+	}
+	return time.Unix(sec, nsec), nil
+}
+
+func numericToTime(v interface{}, t *time.Time) (bool, error) {
 	var n int64
 	switch x := v.(type) {
 	case int64:
@@ -35,15 +161,24 @@ func numericToTime(v interface{}, t *time.Time) bool {
 	case int:
 		n = int64(x)
 	case float32:
-		n = int64(x)
+		return floatToTime(float64(x), t)
 	case float64:
-		n = int64(x)
+		return floatToTime(x, t)
 	default:
-		return false
+		return false, nil
 	}
 
 	*t = time.Unix(n, 0)
-	return true
+	return true, nil
+}
+
+func floatToTime(f float64, t *time.Time) (bool, error) {
+	v, err := parseNumericDate(strconv.FormatFloat(f, 'f', -1, 64))
+	if err != nil {
+		return true, err
+	}
+	*t = v
+	return true, nil
 }
 
 func (n *NumericDate) Accept(v interface{}) error {
@@ -51,22 +186,25 @@ func (n *NumericDate) Accept(v interface{}) error {
 
 	switch x := v.(type) {
 	case string:
-		i, err := strconv.ParseInt(x[:], 10, 64)
+		v, err := parseNumericDate(x)
 		if err != nil {
-			return errors.Errorf(`invalid epoch value %#v`, x)
+			return err
 		}
-		t = time.Unix(i, 0)
-
+		t = v
 	case json.Number:
-		intval, err := x.Int64()
+		v, err := parseNumericDate(x.String())
 		if err != nil {
-			return errors.Wrapf(err, `failed to convert json value %#v to int64`, x)
+			return errors.Wrapf(err, `failed to convert json value %#v to time`, x)
 		}
-		t = time.Unix(intval, 0)
+		t = v
 	case time.Time:
 		t = x
 	default:
-		if !numericToTime(v, &t) {
+		ok, err := numericToTime(v, &t)
+		if err != nil {
+			return err
+		}
+		if !ok {
 			return errors.Errorf(`invalid type %T`, v)
 		}
 	}
@@ -80,7 +218,7 @@ func (n *NumericDate) MarshalJSON() ([]byte, error) {
 	if n.IsZero() {
 		return json.Marshal(nil)
 	}
-	return json.Marshal(n.Unix())
+	return []byte(FormatNumericDate(n.Time)), nil
 }
 
 func (n *NumericDate) UnmarshalJSON(data []byte) error {
@@ -0,0 +1,74 @@
+package jwt
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// IssuanceLimiter decides whether an Issuer may issue another token for
+// a subject. See `jwt.IssuerConfig`.
+//
+// Allow must atomically record an issuance for `subject` at `now` and
+// report whether it is within the limit. Implementations that share the
+// counts between servers are required to limit a fleet of issuers.
+type IssuanceLimiter interface {
+	Allow(ctx context.Context, subject string, now time.Time) (bool, error)
+}
+
+// SubjectRateLimiter is an IssuanceLimiter that allows at most a fixed
+// number of tokens per subject within a sliding window, and keeps the
+// counts in memory. It is safe for concurrent use.
+type SubjectRateLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	window   time.Duration
+	issuance map[string][]time.Time
+}
+
+// NewSubjectRateLimiter creates a new SubjectRateLimiter that allows at
+// most `limit` tokens per subject to be issued within any period of
+// length `window`. Tokens without a "sub" claim share a single limit.
+func NewSubjectRateLimiter(limit int, window time.Duration) *SubjectRateLimiter {
+	return &SubjectRateLimiter{
+		limit:    limit,
+		window:   window,
+		issuance: make(map[string][]time.Time),
+	}
+}
+
+// Allow records an issuance for `subject`, unless the limit has been
+// reached, in which case it returns false.
+func (l *SubjectRateLimiter) Allow(_ context.Context, subject string, now time.Time) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// drop the issuances that have left the window
+	times := l.issuance[subject]
+	var i int
+	for i < len(times) && !times[i].After(now.Add(-l.window)) {
+		i++
+	}
+	times = times[i:]
+
+	if len(times) >= l.limit {
+		l.issuance[subject] = times
+		return false, nil
+	}
+	l.issuance[subject] = append(times, now)
+	return true, nil
+}
+
+// Purge removes the subjects that have not been issued tokens within the
+// window, so that the memory used by inactive subjects is reclaimed. It
+// should be called periodically when there are many distinct subjects.
+func (l *SubjectRateLimiter) Purge(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for subject, times := range l.issuance {
+		if len(times) == 0 || !times[len(times)-1].After(now.Add(-l.window)) {
+			delete(l.issuance, subject)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/subtle"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/pkg/errors"
+)
+
+// IssuerKeyPolicy describes the keys that a single issuer is allowed to
+// sign tokens with. It is passed to `jwt.Parse()` using the
+// `jwt.WithIssuerKeys()` option.
+//
+// By default any key in the issuer's key set is accepted. Use the
+// `AllowKeyID()`, `AllowThumbprint()` and `AllowAlgorithm()` methods to
+// pin the acceptable keys, so that a token signed by a key that was
+// injected into the issuer's key set (e.g. a poisoned JWKS endpoint or
+// cache) is rejected even though its signature is valid.
+type IssuerKeyPolicy struct {
+	issuer      string
+	keyset      jwk.Set
+	kids        []string
+	thumbprints [][]byte
+	algorithms  []jwa.SignatureAlgorithm
+}
+
+// NewIssuerKeyPolicy creates a new IssuerKeyPolicy for tokens whose
+// "iss" claim is `issuer`, verified using the keys in `keyset`.
+func NewIssuerKeyPolicy(issuer string, keyset jwk.Set) *IssuerKeyPolicy {
+	return &IssuerKeyPolicy{
+		issuer: issuer,
+		keyset: keyset,
+	}
+}
+
+// Issuer returns the issuer that the policy applies to
+func (p *IssuerKeyPolicy) Issuer() string {
+	return p.issuer
+}
+
+// AllowKeyID restricts the acceptable keys to those with the given
+// key IDs. May be called multiple times.
+func (p *IssuerKeyPolicy) AllowKeyID(kids ...string) *IssuerKeyPolicy {
+	p.kids = append(p.kids, kids...)
+	return p
+}
+
+// AllowThumbprint restricts the acceptable keys to those whose SHA-256
+// JWK thumbprint (RFC 7638) is one of the given values. May be called
+// multiple times.
+func (p *IssuerKeyPolicy) AllowThumbprint(thumbprints ...[]byte) *IssuerKeyPolicy {
+	p.thumbprints = append(p.thumbprints, thumbprints...)
+	return p
+}
+
+// AllowAlgorithm restricts the acceptable signature algorithms. May be
+// called multiple times.
+func (p *IssuerKeyPolicy) AllowAlgorithm(algs ...jwa.SignatureAlgorithm) *IssuerKeyPolicy {
+	p.algorithms = append(p.algorithms, algs...)
+	return p
+}
+
+// check verifies that the key and algorithm are acceptable for the issuer
+func (p *IssuerKeyPolicy) check(key jwk.Key, alg jwa.SignatureAlgorithm) error {
+	if len(p.algorithms) > 0 {
+		var found bool
+		for _, v := range p.algorithms {
+			if v == alg {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf(`algorithm %q is not allowed for issuer %q`, alg, p.issuer)
+		}
+	}
+
+	if len(p.kids) > 0 {
+		kid := key.KeyID()
+		var found bool
+		for _, v := range p.kids {
+			if v == kid {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf(`key ID %q is not allowed for issuer %q`, kid, p.issuer)
+		}
+	}
+
+	if len(p.thumbprints) > 0 {
+		tp, err := key.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return errors.Wrap(err, `failed to compute JWK thumbprint`)
+		}
+		var found bool
+		for _, v := range p.thumbprints {
+			if subtle.ConstantTimeCompare(v, tp) == 1 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf(`key thumbprint is not allowed for issuer %q`, p.issuer)
+		}
+	}
+	return nil
+}
+
+// lookupIssuerKey selects the verification key for a token from the
+// policy that matches its (as of yet unverified) "iss" claim
+func lookupIssuerKey(data []byte, policies []*IssuerKeyPolicy, useDefault bool, options ...ParseOption) (jwa.SignatureAlgorithm, jwk.Key, error) {
+	// The claims are not trusted at this point: they are only used to
+	// select the policy, and are verified afterwards
+	unverified, err := parse(nil, data, false, "", nil, false, options...)
+	if err != nil {
+		return "", nil, errors.Wrap(err, `failed to parse token`)
+	}
+
+	iss := unverified.Issuer()
+	var policy *IssuerKeyPolicy
+	for _, p := range policies {
+		if p.issuer == iss {
+			policy = p
+			break
+		}
+	}
+	if policy == nil {
+		return "", nil, errors.Errorf(`issuer %q is not trusted`, iss)
+	}
+
+	msg, err := jws.Parse(data)
+	if err != nil {
+		return "", nil, errors.Wrap(err, `failed to parse token data`)
+	}
+	headers, err := protectedHeadersOf(msg)
+	if err != nil {
+		return "", nil, err
+	}
+	alg := headers.Algorithm()
+
+	key, err := lookupMatchingJWK(headers, policy.keyset, useDefault)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := policy.check(key, alg); err != nil {
+		return "", nil, err
+	}
+	return alg, key, nil
+}
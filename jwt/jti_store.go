@@ -0,0 +1,112 @@
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lestrrat-go/jwx/internal/ttlcache"
+	"github.com/pkg/errors"
+)
+
+// JtiStore records the "jti" claims of the tokens that have been
+// accepted, so that `jwt.Validate()` can reject tokens that are replayed.
+// See `jwt.WithJtiStore()`.
+//
+// Seen must atomically record `jti` and report whether it had already
+// been recorded. The record only needs to be kept until `exp`, after
+// which the token is rejected anyway. When called by `jwt.Validate()`,
+// `exp` is the expiration time of the token plus the acceptable skew,
+// expressed in terms of the wall clock even if `jwt.WithClock()` is
+// used. `exp` is the zero time if the token does not have an "exp"
+// claim.
+//
+// Implementations that share the records between servers (e.g. backed
+// by Redis using SET NX with an expiry) are required to protect a fleet
+// of servers. `jwt.MemoryJtiStore` only protects a single process.
+type JtiStore interface {
+	Seen(ctx context.Context, jti string, exp time.Time) (bool, error)
+}
+
+// MemoryJtiStore is a JtiStore that keeps the records in memory. It is
+// safe for concurrent use.
+type MemoryJtiStore struct {
+	ttl        time.Duration
+	maxEntries int
+	cache      *ttlcache.Cache
+}
+
+// NewMemoryJtiStore creates a new MemoryJtiStore.
+//
+// Records are kept until the token expires. Records of tokens without an
+// "exp" claim are kept for `ttl`, or forever if `ttl` is less than or
+// equal to 0.
+//
+// At most `maxEntries` records are retained. When the store is full, the
+// expired records are purged, and then the oldest records are evicted.
+// Note that the tokens whose records have been evicted can be replayed,
+// so `maxEntries` should be larger than the number of tokens that are
+// expected to be accepted during their lifetime. If `maxEntries` is less
+// than or equal to 0, the number of records is not limited.
+func NewMemoryJtiStore(maxEntries int, ttl time.Duration) *MemoryJtiStore {
+	return &MemoryJtiStore{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		// The TTL only applies to tokens without "exp", so it is
+		// handled by Seen instead of the cache
+		cache: ttlcache.New(maxEntries, 0),
+	}
+}
+
+// Seen records `jti` until `exp`, and returns true if it had already
+// been recorded and has not expired yet.
+func (s *MemoryJtiStore) Seen(_ context.Context, jti string, exp time.Time) (bool, error) {
+	if exp.IsZero() && s.ttl > 0 {
+		exp = time.Now().Add(s.ttl)
+	}
+
+	// Make room by purging the expired records first, so that the
+	// records of tokens that are still valid are only evicted as a
+	// last resort
+	if s.maxEntries > 0 && s.cache.Len() >= s.maxEntries {
+		s.cache.Purge()
+	}
+	return !s.cache.Add(jti, struct{}{}, exp), nil
+}
+
+// Len returns the number of records in the store, including those
+// that have expired but have not been evicted yet.
+func (s *MemoryJtiStore) Len() int {
+	return s.cache.Len()
+}
+
+// Clear removes all records from the store.
+func (s *MemoryJtiStore) Clear() {
+	s.cache.Clear()
+}
+
+// checkReplay records the "jti" claim of the token in the store, and
+// fails if it had already been recorded. The record is kept until the
+// token is no longer accepted, i.e. `grace` after "exp" as measured by
+// `clock`, converted to the wall clock that the stores use.
+func checkReplay(ctx context.Context, store JtiStore, t Token, clock Clock, grace time.Duration) error {
+	jti := t.JwtID()
+	if jti == "" {
+		return newValidationError(ErrMissingClaim, JwtIDKey, fmt.Sprintf(`required claim %s is missing`, JwtIDKey), nil)
+	}
+
+	var until time.Time
+	if exp := t.Expiration(); !exp.IsZero() {
+		until = time.Now().Add(exp.Add(grace).Sub(clock.Now()))
+	}
+
+	seen, err := store.Seen(ctx, jti, until)
+	if err != nil {
+		// Fail closed: the token may have been replayed
+		return errors.Wrapf(err, `failed to check %s`, JwtIDKey)
+	}
+	if seen {
+		return newValidationError(ErrTokenReplayed, JwtIDKey, fmt.Sprintf(`%s %q has already been used`, JwtIDKey, jti), nil)
+	}
+	return nil
+}
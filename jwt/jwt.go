@@ -8,12 +8,16 @@ import (
 	"context"
 	"io"
 	"io/ioutil"
+	"sync"
+	"time"
 
 	"github.com/lestrrat-go/jwx/internal/json"
 
 	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwe"
 	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt/internal/types"
 	"github.com/pkg/errors"
 )
 
@@ -29,6 +33,9 @@ func ParseString(s string, options ...ParseOption) (Token, error) {
 // you must pass the jwt.WithVerify(alg, key) or jwt.WithKeySet(jwk.Set) option.
 // If you do not specify these parameters, no verification will be performed.
 //
+// If the token is encrypted, e.g. a nested JWT created by `jwt.Serializer`,
+// pass the `jwt.WithDecrypt(alg, key)` option to decrypt it first.
+//
 // If you also want to assert the validity of the JWT itself (i.e. expiration
 // and such), use the `Validate()` function on the returned token, or pass the
 // `WithValidate(true)` option. Validate options can also be passed to
@@ -41,32 +48,104 @@ func Parse(s []byte, options ...ParseOption) (Token, error) {
 	return parseBytes(s, options...)
 }
 
-// ParseReader calls Parse against an io.Reader
+// ParseContext parses the token in the same way as `jwt.Parse()`, using
+// ctx for the operations performed while parsing. It is equivalent to
+// passing `jwt.WithContext(ctx)`: the context is given to the stores and
+// validators used when `jwt.WithValidate(true)` is specified (see
+// `jwt.ContextValidator`), and parsing stops with the error of the context
+// as soon as it is canceled or its deadline expires.
+func ParseContext(ctx context.Context, s []byte, options ...ParseOption) (Token, error) {
+	return parseBytes(s, append(options[:len(options):len(options)], WithContext(ctx))...)
+}
+
+// ParseReader calls Parse against an io.Reader. If `jwt.WithMaxTokenSize()`
+// is specified, reading stops as soon as the token exceeds the maximum size
 func ParseReader(src io.Reader, options ...ParseOption) (Token, error) {
+	var maxSize int64
+	for _, o := range options {
+		switch o.Ident() {
+		case identMaxTokenSize{}:
+			maxSize = o.Value().(int64)
+		}
+	}
+
+	if maxSize > 0 {
+		src = io.LimitReader(src, maxSize+1)
+	}
+
 	// We're going to need the raw bytes regardless. Read it.
 	data, err := ioutil.ReadAll(src)
 	if err != nil {
 		return nil, errors.Wrap(err, `failed to read from token data source`)
 	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, errors.Errorf(`token exceeds maximum size (%d bytes)`, maxSize)
+	}
 	return parseBytes(data, options...)
 }
 
+// ParseContent parses the nested JWT contained in the decrypted payload
+// of a JWE message (see `jwe.DecryptContent()`). An error is returned if
+// the content type of the payload is not "JWT".
+//
+// The options are the same as those for `jwt.Parse()`. Note that the
+// encryption only provides confidentiality: use `jwt.WithVerify()` or
+// `jwt.WithKeySet()` to verify the signature of the nested JWT.
+func ParseContent(content *jwe.Content, options ...ParseOption) (Token, error) {
+	if !content.IsJWT() {
+		return nil, errors.Errorf(`content type %q is not JWT`, content.ContentType())
+	}
+	return parseBytes(content.Payload(), options...)
+}
+
 func parseBytes(data []byte, options ...ParseOption) (Token, error) {
+	recorder := lookupMetrics(options)
+	if recorder == nil {
+		return parseBytesWithOptions(data, options...)
+	}
+
+	start := time.Now()
+	token, err := parseBytesWithOptions(data, options...)
+	recorder.ObserveParse(time.Since(start), err)
+	return token, err
+}
+
+func parseBytesWithOptions(data []byte, options ...ParseOption) (Token, error) {
+	options, err := expandProfiles(options)
+	if err != nil {
+		return nil, err
+	}
+
 	var params VerifyParameters
+	var decrypt *decryptParams
 	var keyset jwk.Set
+	var issuerKeys []*IssuerKeyPolicy
+	var discovery *KeyDiscovery
 	var useDefault bool
 	var token Token
 	var validate bool
+	var maxSize int64
 	var ok bool
+	ctx := context.Background()
 	for _, o := range options {
 		switch o.Ident() {
+		case identContext{}:
+			ctx = o.Value().(context.Context)
+		case identMaxTokenSize{}:
+			maxSize = o.Value().(int64)
 		case identVerify{}:
 			params = o.Value().(VerifyParameters)
+		case identDecrypt{}:
+			decrypt = o.Value().(*decryptParams)
 		case identKeySet{}:
 			keyset, ok = o.Value().(jwk.Set)
 			if !ok {
 				return nil, errors.Errorf(`invalid JWK set passed via WithKeySet() option (%T)`, o.Value())
 			}
+		case identIssuerKeys{}:
+			issuerKeys = append(issuerKeys, o.Value().([]*IssuerKeyPolicy)...)
+		case identVerifyAuto{}:
+			discovery = o.Value().(*KeyDiscovery)
 		case identToken{}:
 			token, ok = o.Value().(Token)
 			if !ok {
@@ -79,8 +158,44 @@ func parseBytes(data []byte, options ...ParseOption) (Token, error) {
 		}
 	}
 
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, errors.Errorf(`token exceeds maximum size (%d bytes)`, maxSize)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	data = bytes.TrimSpace(data)
 
+	if decrypt != nil {
+		content, err := jwe.DecryptContent(data, decrypt.alg, decrypt.key)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to decrypt token`)
+		}
+		data = bytes.TrimSpace(content.Payload())
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(issuerKeys) > 0 {
+		alg, key, err := lookupIssuerKey(data, issuerKeys, useDefault, options...)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to find matching key for verification`)
+		}
+		return parse(token, data, true, alg, key, validate, options...)
+	}
+
+	if discovery != nil {
+		alg, key, err := discovery.lookupKey(ctx, data, useDefault, options...)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to find matching key for verification`)
+		}
+		return parse(token, data, true, alg, key, validate, options...)
+	}
+
 	// If with matching kid is true, then look for the corresponding key in the
 	// given key set, by matching the "kid" key
 	if keyset != nil {
@@ -101,14 +216,63 @@ func parseBytes(data []byte, options ...ParseOption) (Token, error) {
 // verify parameter exists to make sure that we don't accidentally skip
 // over verification just because alg == ""  or key == nil or something.
 func parse(token Token, data []byte, verify bool, alg jwa.SignatureAlgorithm, key interface{}, validate bool, options ...ParseOption) (Token, error) {
+	var decompress bool
+	var maxDecompressedSize int64
+	var rejectDuplicates bool
+	var cache *ValidationCache
+	var typedClaims []typedClaim
+	var recorder MetricsRecorder
+	ctx := context.Background()
+	for _, o := range options {
+		switch o.Ident() {
+		case identContext{}:
+			ctx = o.Value().(context.Context)
+		case identMetrics{}:
+			recorder = o.Value().(MetricsRecorder)
+		case identTypedClaim{}:
+			typedClaims = append(typedClaims, o.Value().(typedClaim))
+		case identRejectDuplicateClaims{}:
+			rejectDuplicates = o.Value().(bool)
+		case identValidationCache{}:
+			cache = o.Value().(*ValidationCache)
+		case identTokenType{}:
+			if err := verifyTokenType(data, o.Value().(string)); err != nil {
+				return nil, err
+			}
+		case identAcceptableAlgorithms{}:
+			if err := verifyAlgorithm(data, verify, alg, o.Value().([]jwa.SignatureAlgorithm)); err != nil {
+				return nil, err
+			}
+		case identDecompressPayload{}:
+			decompress = true
+			maxDecompressedSize = o.Value().(int64)
+		}
+	}
+
 	var payload []byte
+	var verified []byte // payload to be stored in the cache
+	var msg *jws.Message
 	if verify {
-		// If verify is true, the data MUST be a valid jws message
-		v, err := jws.Verify(data, alg, key)
-		if err != nil {
-			return nil, errors.Wrap(err, `failed to verify jws signature`)
+		if cache != nil {
+			var hit bool
+			payload, hit = cache.lookup(data, alg, key)
+			if recorder != nil {
+				recorder.ObserveCacheLookup(hit)
+			}
+		}
+
+		if payload == nil {
+			// If verify is true, the data MUST be a valid jws message
+			v, err := jws.Verify(data, alg, key)
+			if err != nil {
+				if recorder != nil {
+					recorder.ObserveVerification(alg, "", err)
+				}
+				return nil, errors.Wrap(err, `failed to verify jws signature`)
+			}
+			payload = v
+			verified = v
 		}
-		payload = v
 	} else {
 		// 1. eyXXX.XXXX.XXXX
 		// 2. { "signatures": [ ... ] }
@@ -116,6 +280,7 @@ func parse(token Token, data []byte, verify bool, alg jwa.SignatureAlgorithm, ke
 		if len(data) > 0 && data[0] == '{' {
 			m, err := jws.Parse(data)
 			if err == nil {
+				msg = m
 				payload = m.Payload()
 			} else {
 				// It's JSON, but we don't have proper JWS fields.
@@ -127,18 +292,73 @@ func parse(token Token, data []byte, verify bool, alg jwa.SignatureAlgorithm, ke
 			if err != nil {
 				return nil, errors.Wrap(err, `invalid jws message`)
 			}
+			msg = m
 			payload = m.Payload()
 		}
 	}
 
+	if decompress && verify {
+		m, err := jws.Parse(data)
+		if err != nil {
+			return nil, errors.Wrap(err, `invalid jws message`)
+		}
+		msg = m
+	}
+
+	// msg is nil if the data is a plain JSON object
+	if decompress && msg != nil {
+		compression, err := lookupPayloadCompression(msg)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to lookup payload compression`)
+		}
+		switch compression {
+		case "":
+		case PayloadCompressionGzip:
+			v, err := decompressPayload(payload, maxDecompressedSize)
+			if err != nil {
+				return nil, errors.Wrap(err, `failed to decompress payload`)
+			}
+			payload = v
+		default:
+			return nil, errors.Errorf(`unsupported payload compression %q`, compression)
+		}
+	}
+
+	if rejectDuplicates {
+		if err := json.CheckDuplicateKeys(payload); err != nil {
+			return nil, errors.Wrap(err, `invalid claim set`)
+		}
+	}
+
 	if token == nil {
 		token = New()
 	}
 	if err := json.Unmarshal(payload, token); err != nil {
+		if verify && recorder != nil {
+			recorder.ObserveVerification(alg, "", nil)
+		}
 		return nil, errors.Wrap(err, `failed to parse token`)
 	}
 
+	// The issuer is only known once the claims have been parsed
+	if verify && recorder != nil {
+		recorder.ObserveVerification(alg, token.Issuer(), nil)
+	}
+
+	if len(typedClaims) > 0 {
+		if err := decodeTypedClaims(token, payload, typedClaims); err != nil {
+			return nil, errors.Wrap(err, `failed to decode typed claims`)
+		}
+	}
+
 	if validate {
+		// Verification may have taken a while: do not start validating,
+		// which may involve stores and custom validators, if the caller
+		// has already given up
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		var vopts []ValidateOption
 		for _, o := range options {
 			if v, ok := o.(ValidateOption); ok {
@@ -146,26 +366,106 @@ func parse(token Token, data []byte, verify bool, alg jwa.SignatureAlgorithm, ke
 			}
 		}
 
-		if err := Validate(token, vopts...); err != nil {
+		err := Validate(token, vopts...)
+		if recorder != nil {
+			recorder.ObserveValidation(err)
+		}
+		if err != nil {
 			return nil, err
 		}
 	}
+
+	if cache != nil && verified != nil {
+		cache.store(data, alg, key, verified, token.Expiration())
+	}
 	return token, nil
 }
 
-func lookupMatchingKey(data []byte, keyset jwk.Set, useDefault bool) (jwa.SignatureAlgorithm, interface{}, error) {
+// verifyAlgorithm checks that both the "alg" header of the token and
+// the algorithm used for verification are acceptable
+func verifyAlgorithm(data []byte, verify bool, alg jwa.SignatureAlgorithm, acceptable []jwa.SignatureAlgorithm) error {
+	msg, err := jws.Parse(data)
+	if err != nil {
+		return errors.Wrap(err, `invalid jws message`)
+	}
+
+	sigs := msg.Signatures()
+	if len(sigs) == 0 {
+		return errors.New(`jws message contains no signatures`)
+	}
+
+	// Every signature is checked, as any one of them may be the one that
+	// is verified
+	candidates := make([]jwa.SignatureAlgorithm, 0, len(sigs)+1)
+	for _, sig := range sigs {
+		headers := sig.ProtectedHeaders()
+		if headers == nil {
+			return errors.New(`jws message contains a signature without protected headers`)
+		}
+		candidates = append(candidates, headers.Algorithm())
+	}
+	if verify {
+		candidates = append(candidates, alg)
+	}
+
+	for _, candidate := range candidates {
+		var found bool
+		for _, v := range acceptable {
+			if v == candidate {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf(`signature algorithm %q is not acceptable`, candidate)
+		}
+	}
+	return nil
+}
+
+func lookupMatchingKey(data []byte, keyset jwk.Set, useDefault bool) (jwa.SignatureAlgorithm, jwk.Key, error) {
 	msg, err := jws.Parse(data)
 	if err != nil {
 		return "", nil, errors.Wrap(err, `failed to parse token data`)
 	}
 
-	headers := msg.Signatures()[0].ProtectedHeaders()
+	headers, err := protectedHeadersOf(msg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	key, err := lookupMatchingJWK(headers, keyset, useDefault)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The jwk.Key is returned as is (instead of its raw key) so that it
+	// can be used to identify the key in the ValidationCache
+	return headers.Algorithm(), key, nil
+}
+
+// protectedHeadersOf returns the protected headers of the signature of
+// a token. Keys are looked up using these headers, so tokens that do not
+// have exactly one signature are rejected.
+func protectedHeadersOf(msg *jws.Message) (jws.Headers, error) {
+	sigs := msg.Signatures()
+	if len(sigs) != 1 {
+		return nil, errors.Errorf(`token must have exactly one signature (got %d)`, len(sigs))
+	}
+	headers := sigs[0].ProtectedHeaders()
+	if headers == nil {
+		return nil, errors.New(`token signature does not have protected headers`)
+	}
+	return headers, nil
+}
+
+func lookupMatchingJWK(headers jws.Headers, keyset jwk.Set, useDefault bool) (jwk.Key, error) {
 	kid := headers.KeyID()
 	if kid == "" {
 		if !useDefault {
-			return "", nil, errors.New(`failed to find matching key: no key ID specified in token`)
+			return nil, errors.New(`failed to find matching key: no key ID specified in token`)
 		} else if useDefault && keyset.Len() > 1 {
-			return "", nil, errors.New(`failed to find matching key: no key ID specified in token but multiple in key set`)
+			return nil, errors.New(`failed to find matching key: no key ID specified in token but multiple in key set`)
 		}
 	}
 
@@ -174,21 +474,15 @@ func lookupMatchingKey(data []byte, keyset jwk.Set, useDefault bool) (jwa.Signat
 	if kid == "" {
 		key, ok = keyset.Get(0)
 		if !ok {
-			return "", nil, errors.New(`empty keyset`)
+			return nil, errors.New(`empty keyset`)
 		}
 	} else {
 		key, ok = keyset.LookupKeyID(kid)
 		if !ok {
-			return "", nil, errors.Errorf(`failed to find matching key for key ID %#v in key set`, kid)
+			return nil, errors.Errorf(`failed to find matching key for key ID %#v in key set`, kid)
 		}
 	}
-
-	var rawKey interface{}
-	if err := key.Raw(&rawKey); err != nil {
-		return "", nil, errors.Wrapf(err, `failed to construct raw key from keyset (key ID=%#v)`, kid)
-	}
-
-	return headers.Algorithm(), rawKey, nil
+	return key, nil
 }
 
 // Sign is a convenience function to create a signed JWT token serialized in
@@ -204,30 +498,81 @@ func lookupMatchingKey(data []byte, keyset jwk.Set, useDefault bool) (jwa.Signat
 // The algorithm specified in the `alg` parameter must be able to support
 // the type of key you provided, otherwise an error is returned.
 //
+// The token may be bound to the key of the presenter using the
+// `jwt.WithConfirmationKey()`, `jwt.WithConfirmationThumbprint()` and
+// `jwt.WithConfirmationCertificate()` options.
+//
+// Additional protected header fields (e.g. `cty`, `x5t`, or private
+// header parameters) may be specified using the `jwt.WithHeaders()`
+// option. The given headers are copied, and are not modified.
+//
 // The protected header will also automatically have the `typ` field set
-// to the literal value `JWT`.
+// to the literal value `JWT`, unless another value is specified using
+// the `jwt.WithTokenType()` option, or in the headers given to
+// `jwt.WithHeaders()`. `jwt.WithTokenType()` takes precedence.
 func Sign(t Token, alg jwa.SignatureAlgorithm, key interface{}, options ...Option) ([]byte, error) {
 	var hdr jws.Headers
+	var compress bool
+	var typ string
+	var confirmations []interface{}
+	var provider jws.SigningKeyProvider
 	for _, o := range options {
 		switch o.Ident() {
 		case identHeaders{}:
 			hdr = o.Value().(jws.Headers)
+		case identKeyProvider{}:
+			provider = o.Value().(jws.SigningKeyProvider)
+		case identConfirmation{}:
+			confirmations = append(confirmations, o.Value())
+		case identTokenType{}:
+			typ = o.Value().(string)
+		case identCompressPayload{}:
+			compress = o.Value().(bool)
 		}
 	}
 
-	buf, err := json.Marshal(t)
+	if len(confirmations) > 0 {
+		v, err := applyConfirmations(t, confirmations)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to set confirmation`)
+		}
+		t = v
+	}
+
+	buf, err := Marshal(t, options...)
 	if err != nil {
-		return nil, errors.Wrap(err, `failed to marshal token`)
+		return nil, err
 	}
 
-	if hdr == nil {
-		hdr = jws.NewHeaders()
+	hdr, err = copyHeaders(hdr)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to sign payload`)
 	}
 
-	if err := hdr.Set(`typ`, `JWT`); err != nil {
+	if typ == "" {
+		typ = hdr.Type()
+		if typ == "" {
+			typ = TokenTypeJWT
+		}
+	}
+	if err := hdr.Set(jws.TypeKey, typ); err != nil {
 		return nil, errors.Wrap(err, `failed to sign payload`)
 	}
-	sign, err := jws.Sign(buf, alg, key, jws.WithHeaders(hdr))
+
+	if compress {
+		if err := setPayloadCompression(hdr); err != nil {
+			return nil, errors.Wrap(err, `failed to sign payload`)
+		}
+		buf, err = compressPayload(buf)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to compress payload`)
+		}
+	}
+	jwsOptions := []jws.Option{jws.WithHeaders(hdr)}
+	if provider != nil {
+		jwsOptions = append(jwsOptions, jws.WithKeyProviderForSigning(provider))
+	}
+	sign, err := jws.Sign(buf, alg, key, jwsOptions...)
 	if err != nil {
 		return nil, errors.Wrap(err, `failed to sign payload`)
 	}
@@ -235,6 +580,19 @@ func Sign(t Token, alg jwa.SignatureAlgorithm, key interface{}, options ...Optio
 	return sign, nil
 }
 
+// copyHeaders returns a copy of `hdr`, so that the headers given by the
+// user are not modified. A new set of headers is returned if `hdr` is nil.
+func copyHeaders(hdr jws.Headers) (jws.Headers, error) {
+	dst := jws.NewHeaders()
+	if hdr == nil {
+		return dst, nil
+	}
+	if err := hdr.Copy(context.TODO(), dst); err != nil {
+		return nil, errors.Wrap(err, `failed to copy headers`)
+	}
+	return dst, nil
+}
+
 // Equal compares two JWT tokens. Do not use `reflect.Equal` or the like
 // to compare tokens as they will also compare extra detail such as
 // sync.Mutex objects used to control concurrent access.
@@ -260,15 +618,44 @@ func Equal(t1, t2 Token) bool {
 	return len(m1) == 0
 }
 
+// Clone returns a deep copy of the token. Maps, slices and pointers held by
+// the claims, such as "aud" and private claims, are copied as well, so the
+// new token can be modified without affecting the original.
 func (t *stdToken) Clone() (Token, error) {
 	dst := New()
 
 	ctx := context.Background()
 	for iter := t.Iterate(ctx); iter.Next(ctx); {
 		pair := iter.Pair()
-		if err := dst.Set(pair.Key.(string), pair.Value); err != nil {
+		if err := dst.Set(pair.Key.(string), types.DeepCopy(pair.Value)); err != nil {
 			return nil, errors.Wrapf(err, `failed to set %s`, pair.Key.(string))
 		}
 	}
 	return dst, nil
 }
+
+// MarshalText returns the JSON representation of the claims. It does
+// NOT sign the token: use `jwt.Sign()` for that.
+func (t *stdToken) MarshalText() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+// UnmarshalText replaces the claims of the token with those in the
+// given JSON representation. No signature verification is performed.
+func (t *stdToken) UnmarshalText(data []byte) error {
+	if t.mu == nil {
+		t.mu = &sync.RWMutex{}
+	}
+	return t.UnmarshalJSON(data)
+}
+
+// MarshalBinary is the same as MarshalText. It allows tokens to be
+// stored using encoding/gob
+func (t *stdToken) MarshalBinary() ([]byte, error) {
+	return t.MarshalText()
+}
+
+// UnmarshalBinary is the same as UnmarshalText
+func (t *stdToken) UnmarshalBinary(data []byte) error {
+	return t.UnmarshalText(data)
+}
@@ -3,9 +3,17 @@ package jwt_test
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/sha256"
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strings"
 	"sync"
 	"testing"
@@ -15,6 +23,7 @@ import (
 	"github.com/lestrrat-go/jwx/internal/jwxtest"
 
 	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwe"
 	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/lestrrat-go/jwx/jws"
 	"github.com/lestrrat-go/jwx/jwt"
@@ -79,6 +88,35 @@ func TestJWTParse(t *testing.T) {
 			return
 		}
 	})
+	t.Run("Max token size", func(t *testing.T) {
+		t.Parallel()
+		size := int64(len(signed))
+		_, err := jwt.Parse(signed, jwt.WithMaxTokenSize(size-1))
+		if !assert.Error(t, err, `jwt.Parse should fail`) {
+			return
+		}
+		_, err = jwt.ParseString(string(signed), jwt.WithMaxTokenSize(size-1))
+		if !assert.Error(t, err, `jwt.ParseString should fail`) {
+			return
+		}
+		_, err = jwt.ParseReader(bytes.NewReader(signed), jwt.WithMaxTokenSize(size-1))
+		if !assert.Error(t, err, `jwt.ParseReader should fail`) {
+			return
+		}
+		t2, err := jwt.ParseReader(bytes.NewReader(signed), jwt.WithMaxTokenSize(size))
+		if !assert.NoError(t, err, `jwt.ParseReader should succeed`) {
+			return
+		}
+		if !assert.True(t, jwt.Equal(t1, t2), `t1 == t2`) {
+			return
+		}
+
+		// ParseReader must give up without consuming the entire source
+		_, err = jwt.ParseReader(endlessReader{}, jwt.WithMaxTokenSize(size))
+		if !assert.Error(t, err, `jwt.ParseReader should fail`) {
+			return
+		}
+	})
 	t.Run("Parse (correct signature key)", func(t *testing.T) {
 		t.Parallel()
 		t2, err := jwt.Parse(signed, jwt.WithVerify(alg, &key.PublicKey))
@@ -484,3 +522,2405 @@ func TestReadFile(t *testing.T) {
 		return
 	}
 }
+
+func TestTokenType(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("abracadabra")
+	tok := jwt.New()
+	if !assert.NoError(t, tok.Set(jwt.SubjectKey, "unit test"), `tok.Set should succeed`) {
+		return
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		t.Parallel()
+		signed, err := jwt.Sign(tok, jwa.HS256, key)
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return
+		}
+
+		typ, err := jwt.LookupTokenType(signed)
+		if !assert.NoError(t, err, `jwt.LookupTokenType should succeed`) {
+			return
+		}
+		if !assert.Equal(t, jwt.TokenTypeJWT, typ, `typ should match`) {
+			return
+		}
+
+		_, err = jwt.Parse(signed, jwt.WithVerify(jwa.HS256, key), jwt.WithTokenType(jwt.TokenTypeAccessToken))
+		if !assert.Error(t, err, `jwt.Parse should fail for mismatched typ`) {
+			return
+		}
+	})
+	t.Run("Custom", func(t *testing.T) {
+		t.Parallel()
+		signed, err := jwt.Sign(tok, jwa.HS256, key, jwt.WithTokenType(jwt.TokenTypeAccessToken))
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return
+		}
+
+		for _, typ := range []string{jwt.TokenTypeAccessToken, "AT+JWT", "application/at+jwt"} {
+			_, err = jwt.Parse(signed, jwt.WithVerify(jwa.HS256, key), jwt.WithTokenType(typ))
+			if !assert.NoError(t, err, `jwt.Parse should succeed for %s`, typ) {
+				return
+			}
+		}
+	})
+	t.Run("Unprotected typ", func(t *testing.T) {
+		t.Parallel()
+		payload, err := json.Marshal(tok)
+		if !assert.NoError(t, err, `json.Marshal should succeed`) {
+			return
+		}
+		// No "typ" in the protected header
+		signed, err := jws.Sign(payload, jwa.HS256, key)
+		if !assert.NoError(t, err, `jws.Sign should succeed`) {
+			return
+		}
+		protected, encoded, signature, err := jws.SplitCompact(signed)
+		if !assert.NoError(t, err, `jws.SplitCompact should succeed`) {
+			return
+		}
+		repackaged := fmt.Sprintf(`{"payload":%q,"protected":%q,"header":{"typ":%q},"signature":%q}`, encoded, protected, jwt.TokenTypeAccessToken, signature)
+
+		typ, err := jwt.LookupTokenType([]byte(repackaged))
+		if !assert.NoError(t, err, `jwt.LookupTokenType should succeed`) {
+			return
+		}
+		if !assert.Empty(t, typ, `typ in the unprotected header should be ignored`) {
+			return
+		}
+		_, err = jwt.Parse([]byte(repackaged), jwt.WithVerify(jwa.HS256, key), jwt.WithTokenType(jwt.TokenTypeAccessToken))
+		if !assert.Error(t, err, `jwt.Parse should fail for typ in the unprotected header`) {
+			return
+		}
+	})
+}
+
+func TestSignWithHeaders(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("abracadabra")
+	tok := jwt.New()
+	if !assert.NoError(t, tok.Set(jwt.SubjectKey, "unit test"), `tok.Set should succeed`) {
+		return
+	}
+
+	hdrs := jws.NewHeaders()
+	hdrs.Set(jws.TypeKey, "dpop+jwt")
+	hdrs.Set(jws.X509CertThumbprintKey, "dGh1bWJwcmludA")
+	hdrs.Set("x-vendor", "value")
+
+	t.Run("Headers", func(t *testing.T) {
+		t.Parallel()
+		signed, err := jwt.Sign(tok, jwa.HS256, key, jwt.WithHeaders(hdrs))
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return
+		}
+
+		msg, err := jws.Parse(signed)
+		if !assert.NoError(t, err, `jws.Parse should succeed`) {
+			return
+		}
+		protected := msg.Signatures()[0].ProtectedHeaders()
+		if !assert.Equal(t, "dpop+jwt", protected.Type(), `typ should match`) {
+			return
+		}
+		if !assert.Equal(t, "dGh1bWJwcmludA", protected.X509CertThumbprint(), `x5t should match`) {
+			return
+		}
+		v, ok := protected.Get("x-vendor")
+		if !assert.True(t, ok, `x-vendor should exist`) {
+			return
+		}
+		if !assert.Equal(t, "value", v, `x-vendor should match`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HS256, protected.Algorithm(), `alg should match`) {
+			return
+		}
+
+		_, err = jwt.Parse(signed, jwt.WithVerify(jwa.HS256, key), jwt.WithTokenType("dpop+jwt"))
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+	})
+	t.Run("WithTokenType takes precedence", func(t *testing.T) {
+		t.Parallel()
+		signed, err := jwt.Sign(tok, jwa.HS256, key, jwt.WithHeaders(hdrs), jwt.WithTokenType(jwt.TokenTypeAccessToken))
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return
+		}
+		typ, err := jwt.LookupTokenType(signed)
+		if !assert.NoError(t, err, `jwt.LookupTokenType should succeed`) {
+			return
+		}
+		if !assert.Equal(t, jwt.TokenTypeAccessToken, typ, `typ should match`) {
+			return
+		}
+	})
+	t.Run("Headers are not modified", func(t *testing.T) {
+		t.Parallel()
+		_, err := jwt.Sign(tok, jwa.HS256, key, jwt.WithHeaders(hdrs), jwt.WithCompressPayload(true))
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return
+		}
+		if !assert.Equal(t, "dpop+jwt", hdrs.Type(), `typ should not be modified`) {
+			return
+		}
+		if !assert.Empty(t, hdrs.Algorithm(), `alg should not be set`) {
+			return
+		}
+	})
+}
+
+func TestCompressPayload(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("abracadabra")
+	entitlements := make([]string, 1000)
+	for i := range entitlements {
+		entitlements[i] = "urn:example:entitlement:read"
+	}
+
+	tok := jwt.New()
+	if !assert.NoError(t, tok.Set("entitlements", entitlements), `tok.Set should succeed`) {
+		return
+	}
+
+	plain, err := jwt.Sign(tok, jwa.HS256, key)
+	if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+		return
+	}
+
+	compressed, err := jwt.Sign(tok, jwa.HS256, key, jwt.WithCompressPayload(true))
+	if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+		return
+	}
+	if !assert.True(t, len(compressed) < len(plain), `compressed token should be smaller`) {
+		return
+	}
+
+	msg, err := jws.Parse(compressed)
+	if !assert.NoError(t, err, `jws.Parse should succeed`) {
+		return
+	}
+	if !assert.Equal(t, []string{jwt.PayloadCompressionKey}, msg.Signatures()[0].ProtectedHeaders().Critical(), `crit header should be set`) {
+		return
+	}
+
+	t.Run("Decompression allowed", func(t *testing.T) {
+		t.Parallel()
+		for _, data := range [][]byte{plain, compressed} {
+			parsed, err := jwt.Parse(data, jwt.WithVerify(jwa.HS256, key), jwt.WithDecompressPayload(1<<20))
+			if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+				return
+			}
+			v, ok := parsed.Get("entitlements")
+			if !assert.True(t, ok, `entitlements should exist`) {
+				return
+			}
+			if !assert.Len(t, v, len(entitlements), `entitlements should match`) {
+				return
+			}
+		}
+	})
+	t.Run("Decompression not allowed", func(t *testing.T) {
+		t.Parallel()
+		_, err := jwt.Parse(compressed, jwt.WithVerify(jwa.HS256, key))
+		if !assert.Error(t, err, `jwt.Parse should fail`) {
+			return
+		}
+	})
+	t.Run("Size exceeded", func(t *testing.T) {
+		t.Parallel()
+		_, err := jwt.Parse(compressed, jwt.WithVerify(jwa.HS256, key), jwt.WithDecompressPayload(1024))
+		if !assert.Error(t, err, `jwt.Parse should fail`) {
+			return
+		}
+	})
+}
+
+func TestIssuerKeys(t *testing.T) {
+	t.Parallel()
+
+	newKey := func(kid string) jwk.Key {
+		key, err := jwk.New(jwxtest.GenerateSymmetricKey())
+		if err != nil {
+			panic(err)
+		}
+		_ = key.Set(jwk.KeyIDKey, kid)
+		return key
+	}
+
+	trusted := newKey("trusted")
+	rogue := newKey("rogue")
+	other := newKey("other")
+
+	set := jwk.NewSet()
+	set.Add(trusted)
+	set.Add(rogue)
+	otherSet := jwk.NewSet()
+	otherSet.Add(other)
+
+	sign := func(iss string, alg jwa.SignatureAlgorithm, key jwk.Key) []byte {
+		tok := jwt.New()
+		_ = tok.Set(jwt.IssuerKey, iss)
+		signed, err := jwt.Sign(tok, alg, key)
+		if err != nil {
+			panic(err)
+		}
+		return signed
+	}
+
+	tp, err := trusted.Thumbprint(crypto.SHA256)
+	if !assert.NoError(t, err, `Thumbprint should succeed`) {
+		return
+	}
+
+	testcases := []struct {
+		Name   string
+		Token  []byte
+		Policy *jwt.IssuerKeyPolicy
+		Error  bool
+	}{
+		{
+			Name:   "No restrictions",
+			Token:  sign("https://a.example.com", jwa.HS256, rogue),
+			Policy: jwt.NewIssuerKeyPolicy("https://a.example.com", set),
+		},
+		{
+			Name:   "Pinned key ID",
+			Token:  sign("https://a.example.com", jwa.HS256, trusted),
+			Policy: jwt.NewIssuerKeyPolicy("https://a.example.com", set).AllowKeyID("trusted"),
+		},
+		{
+			Name:   "Unexpected key ID",
+			Token:  sign("https://a.example.com", jwa.HS256, rogue),
+			Policy: jwt.NewIssuerKeyPolicy("https://a.example.com", set).AllowKeyID("trusted"),
+			Error:  true,
+		},
+		{
+			Name:   "Pinned thumbprint",
+			Token:  sign("https://a.example.com", jwa.HS256, trusted),
+			Policy: jwt.NewIssuerKeyPolicy("https://a.example.com", set).AllowThumbprint(tp),
+		},
+		{
+			Name:   "Unexpected thumbprint",
+			Token:  sign("https://a.example.com", jwa.HS256, rogue),
+			Policy: jwt.NewIssuerKeyPolicy("https://a.example.com", set).AllowThumbprint(tp),
+			Error:  true,
+		},
+		{
+			Name:   "Unexpected algorithm",
+			Token:  sign("https://a.example.com", jwa.HS512, trusted),
+			Policy: jwt.NewIssuerKeyPolicy("https://a.example.com", set).AllowAlgorithm(jwa.HS256),
+			Error:  true,
+		},
+		{
+			Name:   "Key of another issuer",
+			Token:  sign("https://a.example.com", jwa.HS256, other),
+			Policy: jwt.NewIssuerKeyPolicy("https://a.example.com", set),
+			Error:  true,
+		},
+		{
+			Name:   "Untrusted issuer",
+			Token:  sign("https://c.example.com", jwa.HS256, trusted),
+			Policy: jwt.NewIssuerKeyPolicy("https://a.example.com", set),
+			Error:  true,
+		},
+		{
+			Name:   "No signatures",
+			Token:  []byte(`{"payload":"` + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"https://a.example.com"}`)) + `","signatures":[]}`),
+			Policy: jwt.NewIssuerKeyPolicy("https://a.example.com", set),
+			Error:  true,
+		},
+		{
+			Name:   "No protected headers",
+			Token:  []byte(`{"payload":"` + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"https://a.example.com"}`)) + `","signatures":[{"header":{"alg":"HS256","kid":"trusted"},"signature":"AA"}]}`),
+			Policy: jwt.NewIssuerKeyPolicy("https://a.example.com", set),
+			Error:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			parsed, err := jwt.Parse(tc.Token, jwt.WithIssuerKeys(tc.Policy, jwt.NewIssuerKeyPolicy("https://b.example.com", otherSet)))
+			if tc.Error {
+				assert.Error(t, err, `jwt.Parse should fail`)
+				return
+			}
+			if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+				return
+			}
+			if !assert.Equal(t, tc.Policy.Issuer(), parsed.Issuer(), `issuer should match`) {
+				return
+			}
+		})
+	}
+}
+
+func TestClaimPropagator(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	p, err := jwt.NewClaimPropagator(key)
+	if !assert.NoError(t, err, `jwt.NewClaimPropagator should succeed`) {
+		return
+	}
+	p.Map(jwt.SubjectKey, "X-User-Id").Map("scopes", "X-Scopes").Map("tenant", "X-Tenant")
+
+	tok := jwt.New()
+	_ = tok.Set(jwt.SubjectKey, "user-1234")
+	_ = tok.Set("scopes", []string{"read", "write"})
+	_ = tok.Set(jwt.ExpirationKey, time.Now().Add(time.Hour))
+
+	newHeader := func() http.Header {
+		h := http.Header{}
+		h.Set("X-Tenant", "spoofed")
+		if !assert.NoError(t, p.Inject(tok, h), `p.Inject should succeed`) {
+			return nil
+		}
+		return h
+	}
+
+	t.Run("Round trip", func(t *testing.T) {
+		t.Parallel()
+		h := newHeader()
+		if h == nil {
+			return
+		}
+		if !assert.Equal(t, "user-1234", h.Get("X-User-Id"), `X-User-Id should be set`) {
+			return
+		}
+		if !assert.Equal(t, "read write", h.Get("X-Scopes"), `X-Scopes should be set`) {
+			return
+		}
+		if !assert.Empty(t, h.Get("X-Tenant"), `X-Tenant should be removed`) {
+			return
+		}
+
+		principal, err := p.Extract(h)
+		if !assert.NoError(t, err, `p.Extract should succeed`) {
+			return
+		}
+		if !assert.Equal(t, "user-1234", principal.Subject(), `subject should match`) {
+			return
+		}
+		if !assert.NoError(t, jwt.Validate(principal), `jwt.Validate should succeed`) {
+			return
+		}
+	})
+	t.Run("Tampered header", func(t *testing.T) {
+		t.Parallel()
+		h := newHeader()
+		if h == nil {
+			return
+		}
+		h.Set("X-User-Id", "admin")
+		_, err := p.Extract(h)
+		if !assert.Error(t, err, `p.Extract should fail`) {
+			return
+		}
+	})
+	t.Run("Added header", func(t *testing.T) {
+		t.Parallel()
+		h := newHeader()
+		if h == nil {
+			return
+		}
+		h.Set("X-Tenant", "other")
+		_, err := p.Extract(h)
+		if !assert.Error(t, err, `p.Extract should fail`) {
+			return
+		}
+	})
+	t.Run("Wrong key", func(t *testing.T) {
+		t.Parallel()
+		h := newHeader()
+		if h == nil {
+			return
+		}
+		other, err := jwt.NewClaimPropagator(append([]byte("x"), key[1:]...))
+		if !assert.NoError(t, err, `jwt.NewClaimPropagator should succeed`) {
+			return
+		}
+		other.Map(jwt.SubjectKey, "X-User-Id")
+		_, err = other.Extract(h)
+		if !assert.Error(t, err, `Extract should fail`) {
+			return
+		}
+	})
+}
+
+func TestParseContent(t *testing.T) {
+	t.Parallel()
+
+	signingKey, err := jwxtest.GenerateEcdsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`) {
+		return
+	}
+	encryptionKey, err := jwxtest.GenerateRsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`) {
+		return
+	}
+
+	tok := jwt.New()
+	_ = tok.Set(jwt.SubjectKey, "user-1234")
+	signed, err := jwt.Sign(tok, jwa.ES256, signingKey)
+	if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+		return
+	}
+
+	t.Run("Nested JWT", func(t *testing.T) {
+		t.Parallel()
+		encrypted, err := jwe.Encrypt(signed, jwa.RSA_OAEP, &encryptionKey.PublicKey, jwa.A128CBC_HS256, jwa.NoCompress, jwe.WithContentType(jwe.ContentTypeJWT))
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+
+		content, err := jwe.DecryptContent(encrypted, jwa.RSA_OAEP, encryptionKey)
+		if !assert.NoError(t, err, `jwe.DecryptContent should succeed`) {
+			return
+		}
+
+		parsed, err := jwt.ParseContent(content, jwt.WithVerify(jwa.ES256, &signingKey.PublicKey))
+		if !assert.NoError(t, err, `jwt.ParseContent should succeed`) {
+			return
+		}
+		assert.Equal(t, "user-1234", parsed.Subject(), `subject should match`)
+	})
+	t.Run("Other content type", func(t *testing.T) {
+		t.Parallel()
+		encrypted, err := jwe.Encrypt(signed, jwa.RSA_OAEP, &encryptionKey.PublicKey, jwa.A128CBC_HS256, jwa.NoCompress)
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+
+		content, err := jwe.DecryptContent(encrypted, jwa.RSA_OAEP, encryptionKey)
+		if !assert.NoError(t, err, `jwe.DecryptContent should succeed`) {
+			return
+		}
+
+		_, err = jwt.ParseContent(content, jwt.WithVerify(jwa.ES256, &signingKey.PublicKey))
+		assert.Error(t, err, `jwt.ParseContent should fail without "cty": "JWT"`)
+	})
+}
+
+func TestValidationCache(t *testing.T) {
+	t.Parallel()
+
+	newKey := func(kid string) jwk.Key {
+		key, err := jwk.New(jwxtest.GenerateSymmetricKey())
+		if err != nil {
+			panic(err)
+		}
+		_ = key.Set(jwk.KeyIDKey, kid)
+		return key
+	}
+
+	now := time.Now()
+	sign := func(sub string, key jwk.Key) []byte {
+		tok := jwt.New()
+		_ = tok.Set(jwt.SubjectKey, sub)
+		_ = tok.Set(jwt.ExpirationKey, now.Add(time.Hour))
+		signed, err := jwt.Sign(tok, jwa.HS256, key)
+		if err != nil {
+			panic(err)
+		}
+		return signed
+	}
+
+	t.Run("Cached verification", func(t *testing.T) {
+		t.Parallel()
+		key := newKey("key")
+		set := jwk.NewSet()
+		set.Add(key)
+		signed := sign("alice", key)
+
+		cache := jwt.NewValidationCache(10, time.Minute)
+		for i := 0; i < 2; i++ {
+			tok, err := jwt.Parse(signed, jwt.WithKeySet(set), jwt.WithValidationCache(cache))
+			if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+				return
+			}
+			if !assert.Equal(t, "alice", tok.Subject(), `subject should match`) {
+				return
+			}
+			if !assert.Equal(t, 1, cache.Len(), `cache should contain the token`) {
+				return
+			}
+		}
+
+		// A new key set with a different key under the same key ID must not
+		// be able to reuse the cached outcome
+		rotated := jwk.NewSet()
+		rotated.Add(newKey("key"))
+		_, err := jwt.Parse(signed, jwt.WithKeySet(rotated), jwt.WithValidationCache(cache))
+		if !assert.Error(t, err, `jwt.Parse with rotated key should fail`) {
+			return
+		}
+
+		// A different algorithm must not reuse the cached outcome either
+		_, err = jwt.Parse(signed, jwt.WithVerify(jwa.HS512, key), jwt.WithValidationCache(cache))
+		if !assert.Error(t, err, `jwt.Parse with a different algorithm should fail`) {
+			return
+		}
+
+		cache.Clear()
+		if !assert.Equal(t, 0, cache.Len(), `cache should be empty`) {
+			return
+		}
+	})
+	t.Run("Claims are validated on every parse", func(t *testing.T) {
+		t.Parallel()
+		key := newKey("key")
+		signed := sign("alice", key)
+
+		cache := jwt.NewValidationCache(10, 0)
+		_, err := jwt.Parse(signed, jwt.WithVerify(jwa.HS256, key), jwt.WithValidationCache(cache), jwt.WithValidate(true))
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+
+		clock := jwt.ClockFunc(func() time.Time { return now.Add(2 * time.Hour) })
+		_, err = jwt.Parse(signed, jwt.WithVerify(jwa.HS256, key), jwt.WithValidationCache(cache), jwt.WithValidate(true), jwt.WithClock(clock))
+		if !assert.Error(t, err, `jwt.Parse with an expired token should fail`) {
+			return
+		}
+	})
+	t.Run("Size limit", func(t *testing.T) {
+		t.Parallel()
+		key := newKey("key")
+		cache := jwt.NewValidationCache(1, time.Minute)
+		for _, sub := range []string{"alice", "bob"} {
+			_, err := jwt.Parse(sign(sub, key), jwt.WithVerify(jwa.HS256, key), jwt.WithValidationCache(cache))
+			if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+				return
+			}
+		}
+		if !assert.Equal(t, 1, cache.Len(), `cache should contain one token`) {
+			return
+		}
+	})
+	t.Run("Raw keys that cannot be compared", func(t *testing.T) {
+		t.Parallel()
+		key := newKey("key")
+		var raw []byte
+		if !assert.NoError(t, key.Raw(&raw), `key.Raw should succeed`) {
+			return
+		}
+
+		cache := jwt.NewValidationCache(10, time.Minute)
+		_, err := jwt.Parse(sign("alice", key), jwt.WithVerify(jwa.HS256, raw), jwt.WithValidationCache(cache))
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, 0, cache.Len(), `cache should be empty`) {
+			return
+		}
+	})
+}
+
+func TestRejectDuplicateClaims(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwxtest.GenerateEcdsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`) {
+		return
+	}
+
+	testcases := []struct {
+		Name      string
+		Payload   string
+		Duplicate bool
+	}{
+		{Name: "No duplicates", Payload: `{"iss":"alice","aud":["a","b"],"nested":{"iss":"bob"}}`},
+		{Name: "Top level", Payload: `{"iss":"alice","sub":"x","iss":"bob"}`, Duplicate: true},
+		{Name: "Nested", Payload: `{"iss":"alice","nested":{"a":1,"a":2}}`, Duplicate: true},
+		{Name: "In array", Payload: `{"iss":"alice","list":[{"a":1},{"a":1,"a":2}]}`, Duplicate: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			signed, err := jws.Sign([]byte(tc.Payload), jwa.ES256, key)
+			if !assert.NoError(t, err, `jws.Sign should succeed`) {
+				return
+			}
+
+			// duplicates are accepted by default
+			_, err = jwt.Parse(signed, jwt.WithVerify(jwa.ES256, &key.PublicKey))
+			if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+				return
+			}
+
+			for _, src := range [][]byte{signed, []byte(tc.Payload)} {
+				options := []jwt.ParseOption{jwt.WithRejectDuplicateClaims(true)}
+				if src[0] != '{' {
+					options = append(options, jwt.WithVerify(jwa.ES256, &key.PublicKey))
+				}
+				_, err = jwt.Parse(src, options...)
+				if tc.Duplicate {
+					if !assert.Error(t, err, `jwt.Parse should fail`) {
+						return
+					}
+					if !assert.Contains(t, err.Error(), `duplicate key`, `error should mention the duplicate key`) {
+						return
+					}
+				} else {
+					if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+						return
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestIssuer(t *testing.T) {
+	t.Parallel()
+
+	var keys []jwk.Key
+	for i := 0; i < 2; i++ {
+		key, err := jwxtest.GenerateEcdsaJwk()
+		if !assert.NoError(t, err, `jwxtest.GenerateEcdsaJwk should succeed`) {
+			return
+		}
+		if !assert.NoError(t, key.Set(jwk.KeyIDKey, fmt.Sprintf("key-%d", i)), `key.Set should succeed`) {
+			return
+		}
+		keys = append(keys, key)
+	}
+	pubset := jwk.NewSet()
+	for _, key := range keys {
+		pubkey, err := jwk.PublicKeyOf(key)
+		if !assert.NoError(t, err, `jwk.PublicKeyOf should succeed`) {
+			return
+		}
+		pubset.Add(pubkey)
+	}
+
+	var current int
+	now := time.Unix(1600000000, 0).UTC()
+	issuer, err := jwt.NewIssuer(jwt.IssuerConfig{
+		Issuer:    "https://issuer.example.com",
+		Audience:  []string{"api"},
+		TTL:       time.Hour,
+		Algorithm: jwa.ES256,
+		KeyProvider: jws.SigningKeyProviderFunc(func() (interface{}, string, error) {
+			return keys[current], "", nil
+		}),
+		Clock: jwt.ClockFunc(func() time.Time { return now }),
+	})
+	if !assert.NoError(t, err, `jwt.NewIssuer should succeed`) {
+		return
+	}
+
+	for i := range keys {
+		current = i
+		claims := jwt.New()
+		_ = claims.Set(jwt.SubjectKey, "user-1234")
+		signed, err := issuer.Issue(context.Background(), claims)
+		if !assert.NoError(t, err, `issuer.Issue should succeed`) {
+			return
+		}
+		if !assert.Len(t, claims.PrivateClaims(), 0, `claims should not be modified`) {
+			return
+		}
+		if !assert.True(t, claims.IssuedAt().IsZero(), `claims should not be modified`) {
+			return
+		}
+
+		msg, err := jws.Parse(signed)
+		if !assert.NoError(t, err, `jws.Parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, fmt.Sprintf("key-%d", i), msg.Signatures()[0].ProtectedHeaders().KeyID(), `"kid" should match`) {
+			return
+		}
+
+		tok, err := jwt.Parse(signed, jwt.WithKeySet(pubset))
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, "https://issuer.example.com", tok.Issuer(), `"iss" should match`) {
+			return
+		}
+		if !assert.Equal(t, []string{"api"}, tok.Audience(), `"aud" should match`) {
+			return
+		}
+		if !assert.Equal(t, "user-1234", tok.Subject(), `"sub" should match`) {
+			return
+		}
+		if !assert.Equal(t, now, tok.IssuedAt(), `"iat" should match`) {
+			return
+		}
+		if !assert.Equal(t, now.Add(time.Hour), tok.Expiration(), `"exp" should match`) {
+			return
+		}
+	}
+
+	t.Run("Claims take precedence", func(t *testing.T) {
+		t.Parallel()
+		claims := jwt.New()
+		_ = claims.Set(jwt.AudienceKey, []string{"other"})
+		_ = claims.Set(jwt.ExpirationKey, now.Add(time.Minute))
+		signed, err := issuer.Issue(context.Background(), claims)
+		if !assert.NoError(t, err, `issuer.Issue should succeed`) {
+			return
+		}
+		tok, err := jwt.Parse(signed)
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, []string{"other"}, tok.Audience(), `"aud" should match`) {
+			return
+		}
+		if !assert.Equal(t, now.Add(time.Minute), tok.Expiration(), `"exp" should match`) {
+			return
+		}
+	})
+	t.Run("Invalid configuration", func(t *testing.T) {
+		t.Parallel()
+		_, err := jwt.NewIssuer(jwt.IssuerConfig{Algorithm: jwa.ES256})
+		if !assert.Error(t, err, `jwt.NewIssuer without a key provider should fail`) {
+			return
+		}
+	})
+}
+
+func TestFromMap(t *testing.T) {
+	t.Parallel()
+
+	exp := time.Unix(1600003600, 0).UTC()
+	for _, v := range []interface{}{
+		exp,
+		int64(1600003600),
+		1600003600,
+		uint32(1600003600),
+		float64(1600003600.75),
+		json.Number("1600003600"),
+		json.Number("1.6000036e9"),
+		"1600003600",
+	} {
+		tok, err := jwt.FromMap(map[string]interface{}{
+			jwt.ExpirationKey: v,
+			jwt.AudienceKey:   []interface{}{"a", "b"},
+			jwt.IssuerKey:     "github.com/lestrrat-go/jwx",
+			jwt.NotBeforeKey:  nil,
+			"scope":           "read write",
+		})
+		if !assert.NoError(t, err, `jwt.FromMap should succeed (%T)`, v) {
+			return
+		}
+		if !assert.Equal(t, exp, tok.Expiration(), `exp should match (%T)`, v) {
+			return
+		}
+		if !assert.Equal(t, []string{"a", "b"}, tok.Audience(), `aud should match`) {
+			return
+		}
+		if !assert.Equal(t, "github.com/lestrrat-go/jwx", tok.Issuer(), `iss should match`) {
+			return
+		}
+		if !assert.True(t, tok.NotBefore().IsZero(), `nil nbf should be skipped`) {
+			return
+		}
+		if !assert.Equal(t, "read write", tok.PrivateClaims()["scope"], `private claim should match`) {
+			return
+		}
+
+		// round trip through AsMap
+		m, err := tok.AsMap(context.Background())
+		if !assert.NoError(t, err, `tok.AsMap should succeed`) {
+			return
+		}
+		tok2, err := jwt.FromMap(m)
+		if !assert.NoError(t, err, `jwt.FromMap should succeed`) {
+			return
+		}
+		m2, err := tok2.AsMap(context.Background())
+		if !assert.NoError(t, err, `tok2.AsMap should succeed`) {
+			return
+		}
+		if !assert.Equal(t, m, m2, `claims should survive a round trip`) {
+			return
+		}
+	}
+
+	for _, m := range []map[string]interface{}{
+		{jwt.ExpirationKey: "tomorrow"},
+		{jwt.ExpirationKey: []int{1}},
+		{jwt.IssuedAtKey: uint64(1 << 63)},
+		{jwt.IssuedAtKey: float64(1 << 63)},
+		{jwt.IssuedAtKey: math.Inf(1)},
+		{jwt.AudienceKey: 1},
+		{jwt.IssuerKey: 1},
+	} {
+		_, err := jwt.FromMap(m)
+		if !assert.Error(t, err, `jwt.FromMap should fail (%v)`, m) {
+			return
+		}
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("abracadavra")
+	tok := jwt.New()
+	_ = tok.Set(jwt.SubjectKey, "github.com/lestrrat-go/jwx")
+	signed, err := jwt.Sign(tok, jwa.HS256, key)
+	if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+		return
+	}
+
+	testcases := []struct {
+		Name    string
+		Request func() *http.Request
+		Options []jwt.ParseOption
+		Error   bool
+	}{
+		{
+			Name: "Authorization header (default)",
+			Request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("Authorization", "Bearer "+string(signed))
+				return req
+			},
+		},
+		{
+			Name: "Authorization header with lower case scheme",
+			Request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("Authorization", "bearer "+string(signed))
+				return req
+			},
+		},
+		{
+			Name: "Authorization header with Basic scheme",
+			Request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("Authorization", "Basic "+string(signed))
+				return req
+			},
+			Error: true,
+		},
+		{
+			Name: "Custom header",
+			Request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("X-Jwt", string(signed))
+				return req
+			},
+			Options: []jwt.ParseOption{jwt.WithHeaderKey("x-jwt")},
+		},
+		{
+			Name: "Form field",
+			Request: func() *http.Request {
+				form := url.Values{"access_token": []string{string(signed)}}
+				req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			Options: []jwt.ParseOption{jwt.WithFormKey("access_token")},
+		},
+		{
+			Name: "Form field is not read from the query",
+			Request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/?access_token="+string(signed), nil)
+			},
+			Options: []jwt.ParseOption{jwt.WithFormKey("access_token")},
+			Error:   true,
+		},
+		{
+			Name: "Query parameter",
+			Request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/?access_token="+string(signed), nil)
+			},
+			Options: []jwt.ParseOption{jwt.WithQueryKey("access_token")},
+		},
+		{
+			Name: "Cookie",
+			Request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.AddCookie(&http.Cookie{Name: "session", Value: string(signed)})
+				return req
+			},
+			Options: []jwt.ParseOption{jwt.WithHeaderKey("Authorization"), jwt.WithCookieKey("session")},
+		},
+		{
+			Name: "Not found",
+			Request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/", nil)
+			},
+			Options: []jwt.ParseOption{jwt.WithHeaderKey("Authorization"), jwt.WithCookieKey("session")},
+			Error:   true,
+		},
+		{
+			Name: "Verification failure",
+			Request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("Authorization", "Bearer "+string(signed))
+				return req
+			},
+			Options: []jwt.ParseOption{jwt.WithVerify(jwa.HS256, []byte("opensesame"))},
+			Error:   true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			options := append([]jwt.ParseOption{jwt.WithVerify(jwa.HS256, key)}, tc.Options...)
+			parsed, err := jwt.ParseRequest(tc.Request(), options...)
+			if tc.Error {
+				assert.Error(t, err, `jwt.ParseRequest should fail`)
+				return
+			}
+			if !assert.NoError(t, err, `jwt.ParseRequest should succeed`) {
+				return
+			}
+			if !assert.Equal(t, tok.Subject(), parsed.Subject(), `sub should match`) {
+				return
+			}
+		})
+	}
+}
+
+func TestSerializer(t *testing.T) {
+	t.Parallel()
+
+	signKey, err := jwxtest.GenerateRsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`) {
+		return
+	}
+	encKey, err := jwxtest.GenerateRsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`) {
+		return
+	}
+
+	tok := jwt.New()
+	_ = tok.Set(jwt.SubjectKey, "github.com/lestrrat-go/jwx")
+
+	t.Run("Sign then encrypt", func(t *testing.T) {
+		t.Parallel()
+		serialized, err := jwt.NewSerializer().
+			Sign(jwa.RS256, signKey).
+			Encrypt(jwa.RSA_OAEP, &encKey.PublicKey, jwa.A256GCM, jwa.NoCompress).
+			Serialize(tok)
+		if !assert.NoError(t, err, `Serialize should succeed`) {
+			return
+		}
+
+		msg, err := jwe.Parse(serialized)
+		if !assert.NoError(t, err, `jwe.Parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, jwe.ContentTypeJWT, msg.ProtectedHeaders().ContentType(), `cty should be JWT`) {
+			return
+		}
+
+		parsed, err := jwt.Parse(serialized,
+			jwt.WithDecrypt(jwa.RSA_OAEP, encKey),
+			jwt.WithVerify(jwa.RS256, &signKey.PublicKey),
+		)
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, tok.Subject(), parsed.Subject(), `sub should match`) {
+			return
+		}
+
+		_, err = jwt.Parse(serialized,
+			jwt.WithDecrypt(jwa.RSA_OAEP, encKey),
+			jwt.WithVerify(jwa.RS256, &encKey.PublicKey),
+		)
+		if !assert.Error(t, err, `jwt.Parse should fail with the wrong verification key`) {
+			return
+		}
+
+		_, err = jwt.Parse(serialized, jwt.WithVerify(jwa.RS256, &signKey.PublicKey))
+		if !assert.Error(t, err, `jwt.Parse should fail without decryption`) {
+			return
+		}
+	})
+	t.Run("Encrypt only", func(t *testing.T) {
+		t.Parallel()
+		serialized, err := jwt.NewSerializer().
+			Encrypt(jwa.RSA_OAEP, &encKey.PublicKey, jwa.A256GCM, jwa.NoCompress).
+			Serialize(tok)
+		if !assert.NoError(t, err, `Serialize should succeed`) {
+			return
+		}
+
+		parsed, err := jwt.Parse(serialized, jwt.WithDecrypt(jwa.RSA_OAEP, encKey))
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, tok.Subject(), parsed.Subject(), `sub should match`) {
+			return
+		}
+	})
+	t.Run("Decryption required", func(t *testing.T) {
+		t.Parallel()
+		signed, err := jwt.NewSerializer().Sign(jwa.RS256, signKey).Serialize(tok)
+		if !assert.NoError(t, err, `Serialize should succeed`) {
+			return
+		}
+		if _, err := jwt.Parse(signed, jwt.WithVerify(jwa.RS256, &signKey.PublicKey)); !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+		if _, err := jwt.Parse(signed, jwt.WithDecrypt(jwa.RSA_OAEP, encKey), jwt.WithVerify(jwa.RS256, &signKey.PublicKey)); !assert.Error(t, err, `jwt.Parse should fail for unencrypted tokens`) {
+			return
+		}
+	})
+	t.Run("No steps", func(t *testing.T) {
+		t.Parallel()
+		serialized, err := jwt.NewSerializer().Serialize(tok)
+		if !assert.NoError(t, err, `Serialize should succeed`) {
+			return
+		}
+		expected, _ := json.Marshal(tok)
+		if !assert.Equal(t, expected, serialized, `output should be JSON`) {
+			return
+		}
+	})
+}
+
+func TestThumbprint(t *testing.T) {
+	t.Parallel()
+
+	t1 := jwt.New()
+	_ = t1.Set(jwt.SubjectKey, "alice")
+	_ = t1.Set(jwt.IssuedAtKey, time.Unix(1600000000, 0))
+	_ = t1.Set("events", map[string]interface{}{"b": 1, "a": "<&>"})
+
+	// Same claims, different order and representation
+	t2, err := jwt.ParseString(`{"events":{"a":"<&>","b":1},  "iat":1600000000,"sub":"alice","jti":"x"}`)
+	if !assert.NoError(t, err, `jwt.ParseString should succeed`) {
+		return
+	}
+
+	tp1, err := jwt.Thumbprint(t1, crypto.SHA256, jwt.SubjectKey, jwt.IssuedAtKey, "events", "missing")
+	if !assert.NoError(t, err, `jwt.Thumbprint should succeed`) {
+		return
+	}
+	tp2, err := jwt.Thumbprint(t2, crypto.SHA256, "events", jwt.IssuedAtKey, jwt.SubjectKey)
+	if !assert.NoError(t, err, `jwt.Thumbprint should succeed`) {
+		return
+	}
+	if !assert.Equal(t, tp1, tp2, `thumbprints should match`) {
+		return
+	}
+
+	// canonical form: {"events":{"a":"<&>","b":1},"iat":1600000000,"sub":"alice"}
+	expected := sha256.Sum256([]byte(`{"events":{"a":"<&>","b":1},"iat":1600000000,"sub":"alice"}`))
+	if !assert.Equal(t, expected[:], tp1, `thumbprint should match the canonical form`) {
+		return
+	}
+
+	// All claims
+	all1, err := jwt.Thumbprint(t1, crypto.SHA256)
+	if !assert.NoError(t, err, `jwt.Thumbprint should succeed`) {
+		return
+	}
+	all2, err := jwt.Thumbprint(t2, crypto.SHA256)
+	if !assert.NoError(t, err, `jwt.Thumbprint should succeed`) {
+		return
+	}
+	if !assert.NotEqual(t, all1, all2, `thumbprints over all claims should differ (jti)`) {
+		return
+	}
+	if !assert.Equal(t, tp1, all1, `thumbprint over all claims of t1 should match`) {
+		return
+	}
+
+	if _, err := jwt.Thumbprint(t1, crypto.Hash(0)); !assert.Error(t, err, `jwt.Thumbprint should fail for unavailable hash`) {
+		return
+	}
+}
+
+type typedClaimCart struct {
+	ID    string   `json:"id"`
+	Items []string `json:"items"`
+	Total int      `json:"total"`
+}
+
+func TestTypedClaim(t *testing.T) {
+	t.Parallel()
+
+	src := []byte(`{"sub":"github.com/lestrrat-go/jwx","cart":{"id":"c1","items":["apple","orange"],"total":300},"other":{"id":"c2"}}`)
+
+	t.Run("Value", func(t *testing.T) {
+		t.Parallel()
+		tok, err := jwt.Parse(src, jwt.WithTypedClaim("cart", typedClaimCart{}))
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+
+		v, ok := tok.Get("cart")
+		if !assert.True(t, ok, `tok.Get should succeed`) {
+			return
+		}
+		expected := typedClaimCart{ID: "c1", Items: []string{"apple", "orange"}, Total: 300}
+		if !assert.Equal(t, expected, v, `cart should be decoded into typedClaimCart`) {
+			return
+		}
+
+		// Claims that are not specified are left alone
+		v, ok = tok.Get("other")
+		if !assert.True(t, ok, `tok.Get should succeed`) {
+			return
+		}
+		if !assert.IsType(t, map[string]interface{}{}, v, `other should be a map`) {
+			return
+		}
+
+		// Typed claims are serialized as usual
+		buf, err := json.Marshal(tok)
+		if !assert.NoError(t, err, `json.Marshal should succeed`) {
+			return
+		}
+		if !assert.JSONEq(t, string(src), string(buf), `serialized token should match`) {
+			return
+		}
+	})
+	t.Run("Pointer", func(t *testing.T) {
+		t.Parallel()
+		tok, err := jwt.Parse(src, jwt.WithTypedClaim("cart", &typedClaimCart{}), jwt.WithTypedClaim("missing", typedClaimCart{}))
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+
+		v, _ := tok.Get("cart")
+		cart, ok := v.(*typedClaimCart)
+		if !assert.True(t, ok, `cart should be decoded into *typedClaimCart`) {
+			return
+		}
+		if !assert.Equal(t, "c1", cart.ID, `cart.ID should match`) {
+			return
+		}
+
+		_, ok = tok.Get("missing")
+		if !assert.False(t, ok, `missing claims should not be set`) {
+			return
+		}
+	})
+	t.Run("Type mismatch", func(t *testing.T) {
+		t.Parallel()
+		_, err := jwt.Parse(src, jwt.WithTypedClaim("sub", typedClaimCart{}))
+		if !assert.Error(t, err, `jwt.Parse should fail`) {
+			return
+		}
+		_, err = jwt.Parse(src, jwt.WithTypedClaim("cart", 0))
+		if !assert.Error(t, err, `jwt.Parse should fail`) {
+			return
+		}
+	})
+	t.Run("Registered claim", func(t *testing.T) {
+		t.Parallel()
+		_, err := jwt.Parse([]byte(`{"exp":1600000000}`), jwt.WithTypedClaim("exp", int64(0)))
+		if !assert.Error(t, err, `jwt.Parse should fail even if the type is compatible`) {
+			return
+		}
+	})
+}
+
+func TestParseCookie(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("abracadavra")
+	tok := jwt.New()
+	_ = tok.Set(jwt.SubjectKey, "github.com/lestrrat-go/jwx")
+	signed, err := jwt.Sign(tok, jwa.HS256, key)
+	if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+		return
+	}
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer garbage")
+		req.AddCookie(&http.Cookie{Name: "session", Value: string(signed), Path: "/"})
+		return req
+	}
+
+	t.Run("ParseCookie", func(t *testing.T) {
+		t.Parallel()
+		var cookie *http.Cookie
+		parsed, err := jwt.ParseCookie(newRequest(), "session", jwt.WithVerify(jwa.HS256, key), jwt.WithCookie(&cookie))
+		if !assert.NoError(t, err, `jwt.ParseCookie should succeed`) {
+			return
+		}
+		if !assert.Equal(t, tok.Subject(), parsed.Subject(), `subject should match`) {
+			return
+		}
+		if !assert.NotNil(t, cookie, `cookie should be stored`) {
+			return
+		}
+		if !assert.Equal(t, "session", cookie.Name, `cookie name should match`) {
+			return
+		}
+		if !assert.Equal(t, string(signed), cookie.Value, `cookie value should match`) {
+			return
+		}
+
+		_, err = jwt.ParseCookie(newRequest(), "missing", jwt.WithVerify(jwa.HS256, key))
+		if !assert.Error(t, err, `jwt.ParseCookie should fail`) {
+			return
+		}
+	})
+	t.Run("ParseRequest with WithCookie", func(t *testing.T) {
+		t.Parallel()
+		var cookie *http.Cookie
+		_, err := jwt.ParseRequest(newRequest(), jwt.WithQueryKey("access_token"), jwt.WithCookieKey("session"), jwt.WithVerify(jwa.HS256, key), jwt.WithCookie(&cookie))
+		if !assert.NoError(t, err, `jwt.ParseRequest should succeed`) {
+			return
+		}
+		if !assert.NotNil(t, cookie, `cookie should be stored`) {
+			return
+		}
+
+		// The token is found in the header, so the cookie is left untouched
+		cookie = nil
+		req := newRequest()
+		req.Header.Set("Authorization", "Bearer "+string(signed))
+		_, err = jwt.ParseRequest(req, jwt.WithHeaderKey("Authorization"), jwt.WithCookieKey("session"), jwt.WithVerify(jwa.HS256, key), jwt.WithCookie(&cookie))
+		if !assert.NoError(t, err, `jwt.ParseRequest should succeed`) {
+			return
+		}
+		if !assert.Nil(t, cookie, `cookie should not be stored`) {
+			return
+		}
+	})
+}
+
+func TestIssuerLimits(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwxtest.GenerateEcdsaJwk()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaJwk should succeed`) {
+		return
+	}
+
+	var mu sync.Mutex
+	// The jti store uses the system clock to expire the records
+	now := time.Now()
+	clock := jwt.ClockFunc(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	})
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+
+	issuer, err := jwt.NewIssuer(jwt.IssuerConfig{
+		TTL:       time.Hour,
+		Algorithm: jwa.ES256,
+		KeyProvider: jws.SigningKeyProviderFunc(func() (interface{}, string, error) {
+			return key, "", nil
+		}),
+		Clock:    clock,
+		Limiter:  jwt.NewSubjectRateLimiter(2, time.Minute),
+		JtiStore: jwt.NewMemoryJtiStore(0, 0),
+	})
+	if !assert.NoError(t, err, `jwt.NewIssuer should succeed`) {
+		return
+	}
+
+	issue := func(sub, jti string) ([]byte, error) {
+		claims := jwt.New()
+		_ = claims.Set(jwt.SubjectKey, sub)
+		if jti != "" {
+			_ = claims.Set(jwt.JwtIDKey, jti)
+		}
+		return issuer.Issue(context.Background(), claims)
+	}
+
+	t.Run("Rate limit", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if _, err := issue("alice", ""); !assert.NoError(t, err, `issue #%d should succeed`, i) {
+				return
+			}
+		}
+		_, err := issue("alice", "")
+		if !assert.True(t, errors.Is(err, jwt.ErrIssuanceLimitExceeded), `third issue should fail with ErrIssuanceLimitExceeded: %v`, err) {
+			return
+		}
+		if _, err := issue("bob", ""); !assert.NoError(t, err, `other subjects should not be limited`) {
+			return
+		}
+
+		advance(time.Minute)
+		if _, err := issue("alice", ""); !assert.NoError(t, err, `issue should succeed after the window has passed`) {
+			return
+		}
+	})
+	t.Run("Unique jti", func(t *testing.T) {
+		signed, err := issue("carol", "")
+		if !assert.NoError(t, err, `issue should succeed`) {
+			return
+		}
+		tok, err := jwt.Parse(signed)
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+		if !assert.NotEmpty(t, tok.JwtID(), `jti should be assigned`) {
+			return
+		}
+
+		_, err = issue("dave", tok.JwtID())
+		if !assert.True(t, errors.Is(err, jwt.ErrDuplicateJwtID), `issue should fail with ErrDuplicateJwtID: %v`, err) {
+			return
+		}
+	})
+}
+
+func TestRFC9068AccessToken(t *testing.T) {
+	t.Parallel()
+
+	const audience = "https://rs.example.com"
+	key := []byte("abracadabra")
+
+	makeToken := func(t *testing.T, skip ...string) jwt.Token {
+		now := time.Now()
+		claims := map[string]interface{}{
+			jwt.IssuerKey:     "https://as.example.com",
+			jwt.ExpirationKey: now.Add(time.Hour),
+			jwt.AudienceKey:   audience,
+			jwt.SubjectKey:    "5ba552d67",
+			jwt.ClientIDKey:   "s6BhdRkqt3",
+			jwt.IssuedAtKey:   now,
+			jwt.JwtIDKey:      "dbe39bf3a3ba4238a513f51d6e1691c4",
+		}
+		for _, name := range skip {
+			delete(claims, name)
+		}
+
+		tok := jwt.New()
+		for name, value := range claims {
+			if !assert.NoError(t, tok.Set(name, value), `tok.Set should succeed`) {
+				return nil
+			}
+		}
+		return tok
+	}
+
+	sign := func(t *testing.T, tok jwt.Token, typ string) []byte {
+		signed, err := jwt.Sign(tok, jwa.HS256, key, jwt.WithTokenType(typ))
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return nil
+		}
+		return signed
+	}
+
+	t.Run("Valid token", func(t *testing.T) {
+		t.Parallel()
+		for _, typ := range []string{jwt.TokenTypeAccessToken, "application/at+jwt"} {
+			signed := sign(t, makeToken(t), typ)
+			tok, err := jwt.Parse(signed, jwt.WithVerify(jwa.HS256, key), jwt.WithProfile(jwt.RFC9068AccessToken), jwt.WithAudience(audience))
+			if !assert.NoError(t, err, `jwt.Parse should succeed for typ %s`, typ) {
+				return
+			}
+			if !assert.Equal(t, "5ba552d67", tok.Subject(), `sub should match`) {
+				return
+			}
+		}
+	})
+	t.Run("Invalid tokens", func(t *testing.T) {
+		t.Parallel()
+		testcases := []struct {
+			Name string
+			Typ  string
+			Skip []string
+		}{
+			{Name: "typ is JWT", Typ: jwt.TokenTypeJWT},
+			{Name: "Missing iss", Skip: []string{jwt.IssuerKey}},
+			{Name: "Missing exp", Skip: []string{jwt.ExpirationKey}},
+			{Name: "Missing sub", Skip: []string{jwt.SubjectKey}},
+			{Name: "Missing client_id", Skip: []string{jwt.ClientIDKey}},
+			{Name: "Missing iat", Skip: []string{jwt.IssuedAtKey}},
+			{Name: "Missing jti", Skip: []string{jwt.JwtIDKey}},
+			{Name: "Missing aud", Skip: []string{jwt.AudienceKey}},
+		}
+		for _, tc := range testcases {
+			typ := tc.Typ
+			if typ == "" {
+				typ = jwt.TokenTypeAccessToken
+			}
+			signed := sign(t, makeToken(t, tc.Skip...), typ)
+			_, err := jwt.Parse(signed, jwt.WithVerify(jwa.HS256, key), jwt.WithProfile(jwt.RFC9068AccessToken), jwt.WithAudience(audience))
+			if !assert.Error(t, err, `jwt.Parse should fail (%s)`, tc.Name) {
+				return
+			}
+		}
+
+		// Wrong audience
+		signed := sign(t, makeToken(t), jwt.TokenTypeAccessToken)
+		_, err := jwt.Parse(signed, jwt.WithVerify(jwa.HS256, key), jwt.WithProfile(jwt.RFC9068AccessToken), jwt.WithAudience("https://other.example.com"))
+		if !assert.Error(t, err, `jwt.Parse should fail for the wrong audience`) {
+			return
+		}
+		// Validation cannot be turned off
+		_, err = jwt.Parse(signed, jwt.WithVerify(jwa.HS256, key), jwt.WithProfile(jwt.RFC9068AccessToken), jwt.WithAudience("https://other.example.com"), jwt.WithValidate(false))
+		if !assert.Error(t, err, `jwt.Parse should fail for the wrong audience`) {
+			return
+		}
+	})
+	t.Run("Unsatisfied requirements", func(t *testing.T) {
+		t.Parallel()
+		signed := sign(t, makeToken(t), jwt.TokenTypeAccessToken)
+
+		_, err := jwt.Parse(signed, jwt.WithProfile(jwt.RFC9068AccessToken), jwt.WithAudience(audience))
+		if !assert.Error(t, err, `jwt.Parse without verification should fail`) {
+			return
+		}
+		_, err = jwt.Parse(signed, jwt.WithVerify(jwa.HS256, key), jwt.WithProfile(jwt.RFC9068AccessToken))
+		if !assert.Error(t, err, `jwt.Parse without an expected audience should fail`) {
+			return
+		}
+	})
+}
+
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'a'
+	}
+	return len(p), nil
+}
+
+type testMetricsRecorder struct {
+	mu            sync.Mutex
+	parses        []error
+	verifications []string
+	validations   []error
+	cacheHits     []bool
+}
+
+func (r *testMetricsRecorder) ObserveParse(d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.parses = append(r.parses, err)
+}
+
+func (r *testMetricsRecorder) ObserveVerification(alg jwa.SignatureAlgorithm, issuer string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.verifications = append(r.verifications, fmt.Sprintf(`%s %s %t`, alg, issuer, err == nil))
+}
+
+func (r *testMetricsRecorder) ObserveValidation(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.validations = append(r.validations, err)
+}
+
+func (r *testMetricsRecorder) ObserveCacheLookup(hit bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cacheHits = append(r.cacheHits, hit)
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwk.New(jwxtest.GenerateSymmetricKey())
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+
+	now := time.Now()
+	tok := jwt.New()
+	_ = tok.Set(jwt.IssuerKey, `https://issuer.example.com`)
+	_ = tok.Set(jwt.ExpirationKey, now.Add(time.Hour))
+	signed, err := jwt.Sign(tok, jwa.HS256, key)
+	if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+		return
+	}
+
+	t.Run("Verification and cache", func(t *testing.T) {
+		t.Parallel()
+		recorder := &testMetricsRecorder{}
+		cache := jwt.NewValidationCache(10, time.Minute)
+		for i := 0; i < 2; i++ {
+			_, err := jwt.Parse(signed, jwt.WithVerify(jwa.HS256, key), jwt.WithValidationCache(cache), jwt.WithValidate(true), jwt.WithMetrics(recorder))
+			if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+				return
+			}
+		}
+
+		if !assert.Equal(t, []error{nil, nil}, recorder.parses, `parses should be recorded`) {
+			return
+		}
+		if !assert.Equal(t, []bool{false, true}, recorder.cacheHits, `cache lookups should be recorded`) {
+			return
+		}
+		expected := `HS256 https://issuer.example.com true`
+		if !assert.Equal(t, []string{expected, expected}, recorder.verifications, `verifications should be recorded`) {
+			return
+		}
+		if !assert.Equal(t, []error{nil, nil}, recorder.validations, `validations should be recorded`) {
+			return
+		}
+	})
+	t.Run("Verification failure", func(t *testing.T) {
+		t.Parallel()
+		other, err := jwk.New(jwxtest.GenerateSymmetricKey())
+		if !assert.NoError(t, err, `jwk.New should succeed`) {
+			return
+		}
+
+		recorder := &testMetricsRecorder{}
+		_, err = jwt.Parse(signed, jwt.WithVerify(jwa.HS256, other), jwt.WithValidate(true), jwt.WithMetrics(recorder))
+		if !assert.Error(t, err, `jwt.Parse should fail`) {
+			return
+		}
+		if !assert.Len(t, recorder.parses, 1, `parse should be recorded`) {
+			return
+		}
+		if !assert.Error(t, recorder.parses[0], `parse should be recorded as a failure`) {
+			return
+		}
+		// The issuer must not be reported for unauthenticated tokens
+		if !assert.Equal(t, []string{`HS256  false`}, recorder.verifications, `verification should be recorded`) {
+			return
+		}
+		if !assert.Empty(t, recorder.validations, `validation should not be recorded`) {
+			return
+		}
+	})
+	t.Run("Validation failure", func(t *testing.T) {
+		t.Parallel()
+		recorder := &testMetricsRecorder{}
+		clock := jwt.ClockFunc(func() time.Time { return now.Add(2 * time.Hour) })
+		_, err := jwt.Parse(signed, jwt.WithVerify(jwa.HS256, key), jwt.WithValidate(true), jwt.WithClock(clock), jwt.WithMetrics(recorder))
+		if !assert.Error(t, err, `jwt.Parse should fail`) {
+			return
+		}
+		if !assert.Len(t, recorder.validations, 1, `validation should be recorded`) {
+			return
+		}
+		if !assert.True(t, errors.Is(recorder.validations[0], jwt.ErrTokenExpired), `validation failure should be recorded`) {
+			return
+		}
+	})
+}
+
+func TestParseContext(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwk.New(jwxtest.GenerateSymmetricKey())
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+
+	tok := jwt.New()
+	_ = tok.Set(jwt.IssuerKey, `https://issuer.example.com`)
+	signed, err := jwt.Sign(tok, jwa.HS256, key)
+	if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+		return
+	}
+
+	t.Run("Context is passed to validators", func(t *testing.T) {
+		t.Parallel()
+		type ctxKey struct{}
+		ctx := context.WithValue(context.Background(), ctxKey{}, `value`)
+
+		var got interface{}
+		validator := jwt.ContextValidatorFunc(func(ctx context.Context, _ jwt.Token) error {
+			got = ctx.Value(ctxKey{})
+			return nil
+		})
+		_, err := jwt.ParseContext(ctx, signed, jwt.WithVerify(jwa.HS256, key), jwt.WithValidate(true), jwt.WithValidator(validator))
+		if !assert.NoError(t, err, `jwt.ParseContext should succeed`) {
+			return
+		}
+		if !assert.Equal(t, `value`, got, `validator should receive the context`) {
+			return
+		}
+	})
+	t.Run("Canceled context", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := jwt.ParseContext(ctx, signed, jwt.WithVerify(jwa.HS256, key))
+		if !assert.True(t, errors.Is(err, context.Canceled), `jwt.ParseContext should fail with context.Canceled`) {
+			return
+		}
+	})
+	t.Run("Deadline expires during validation", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		var called bool
+		slow := jwt.ContextValidatorFunc(func(ctx context.Context, _ jwt.Token) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		next := jwt.ValidatorFunc(func(jwt.Token) error {
+			called = true
+			return nil
+		})
+		_, err := jwt.ParseContext(ctx, signed, jwt.WithVerify(jwa.HS256, key), jwt.WithValidate(true), jwt.WithValidator(slow), jwt.WithValidator(next), jwt.WithMultipleErrors(true))
+		if !assert.True(t, errors.Is(err, context.DeadlineExceeded), `jwt.ParseContext should fail with context.DeadlineExceeded`) {
+			return
+		}
+		if !assert.False(t, called, `validators should not be called after the deadline`) {
+			return
+		}
+	})
+}
+
+func TestRefresh(t *testing.T) {
+	t.Parallel()
+
+	issuedAt := time.Unix(1600000000, 0).UTC()
+	newToken := func(ttl time.Duration) jwt.Token {
+		tok := jwt.New()
+		_ = tok.Set(jwt.SubjectKey, `user`)
+		_ = tok.Set(jwt.IssuedAtKey, issuedAt)
+		_ = tok.Set(jwt.ExpirationKey, issuedAt.Add(ttl))
+		_ = tok.Set(jwt.JwtIDKey, `original`)
+		return tok
+	}
+	clockAt := func(d time.Duration) jwt.Clock {
+		return jwt.ClockFunc(func() time.Time { return issuedAt.Add(d) })
+	}
+
+	t.Run("Not due yet", func(t *testing.T) {
+		t.Parallel()
+		old := newToken(time.Hour)
+		refreshed, err := jwt.Refresh(old, jwt.RefreshPolicy{TTL: time.Hour, Clock: clockAt(20 * time.Minute)})
+		if !assert.NoError(t, err, `jwt.Refresh should succeed`) {
+			return
+		}
+		if !assert.True(t, refreshed == old, `old token should be returned as is`) {
+			return
+		}
+	})
+	t.Run("Sliding expiration", func(t *testing.T) {
+		t.Parallel()
+		old := newToken(time.Hour)
+		refreshed, err := jwt.Refresh(old, jwt.RefreshPolicy{TTL: time.Hour, Clock: clockAt(40 * time.Minute)})
+		if !assert.NoError(t, err, `jwt.Refresh should succeed`) {
+			return
+		}
+		if !assert.Equal(t, issuedAt.Add(100*time.Minute), refreshed.Expiration(), `exp should be extended`) {
+			return
+		}
+		if !assert.Equal(t, issuedAt, refreshed.IssuedAt(), `iat should be preserved`) {
+			return
+		}
+		if !assert.Equal(t, `user`, refreshed.Subject(), `sub should be preserved`) {
+			return
+		}
+		if !assert.NotEqual(t, `original`, refreshed.JwtID(), `jti should be rotated`) {
+			return
+		}
+		if !assert.NotEmpty(t, refreshed.JwtID(), `jti should be rotated`) {
+			return
+		}
+		if !assert.Equal(t, issuedAt.Add(time.Hour), old.Expiration(), `old token should not be modified`) {
+			return
+		}
+		if !assert.Equal(t, `original`, old.JwtID(), `old token should not be modified`) {
+			return
+		}
+	})
+	t.Run("Maximum session lifetime", func(t *testing.T) {
+		t.Parallel()
+		policy := jwt.RefreshPolicy{TTL: time.Hour, MaxLifetime: 2 * time.Hour, Clock: clockAt(90 * time.Minute)}
+
+		old := newToken(100 * time.Minute)
+		refreshed, err := jwt.Refresh(old, policy)
+		if !assert.NoError(t, err, `jwt.Refresh should succeed`) {
+			return
+		}
+		if !assert.Equal(t, issuedAt.Add(2*time.Hour), refreshed.Expiration(), `exp should be capped at the end of the session`) {
+			return
+		}
+
+		// auth_time takes precedence over iat
+		old = newToken(100 * time.Minute)
+		_ = old.Set(`auth_time`, issuedAt.Add(-time.Hour).Unix())
+		_, err = jwt.Refresh(old, policy)
+		if !assert.True(t, errors.Is(err, jwt.ErrSessionExpired), `jwt.Refresh should fail with jwt.ErrSessionExpired`) {
+			return
+		}
+	})
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+		_, err := jwt.Refresh(newToken(time.Hour), jwt.RefreshPolicy{TTL: time.Hour, Clock: clockAt(time.Hour)})
+		if !assert.True(t, errors.Is(err, jwt.ErrTokenExpired), `expired tokens should not be refreshed`) {
+			return
+		}
+
+		_, err = jwt.Refresh(jwt.New(), jwt.RefreshPolicy{TTL: time.Hour})
+		if !assert.True(t, errors.Is(err, jwt.ErrMissingClaim), `tokens without exp should not be refreshed`) {
+			return
+		}
+
+		_, err = jwt.Refresh(newToken(time.Hour), jwt.RefreshPolicy{})
+		if !assert.Error(t, err, `TTL is required`) {
+			return
+		}
+	})
+}
+
+func TestVerifyAuto(t *testing.T) {
+	t.Parallel()
+
+	newKey := func(kid string) jwk.Key {
+		key, err := jwxtest.GenerateEcdsaJwk()
+		if err != nil {
+			panic(err)
+		}
+		_ = key.Set(jwk.KeyIDKey, kid)
+		return key
+	}
+	sign := func(t *testing.T, iss string, key jwk.Key) []byte {
+		tok := jwt.New()
+		_ = tok.Set(jwt.IssuerKey, iss)
+		signed, err := jwt.Sign(tok, jwa.ES256, key)
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			t.FailNow()
+		}
+		return signed
+	}
+
+	// issuerServer serves the key set of the given keys, optionally
+	// along with an OpenID Provider configuration
+	type issuerServer struct {
+		*httptest.Server
+		mu       sync.Mutex
+		keys     []jwk.Key
+		requests []string
+	}
+	newIssuerServer := func(discovery bool, jwksIssuer string) *issuerServer {
+		srv := &issuerServer{}
+		srv.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			srv.mu.Lock()
+			defer srv.mu.Unlock()
+			srv.requests = append(srv.requests, r.URL.Path)
+
+			switch {
+			case r.URL.Path == `/.well-known/openid-configuration` && discovery:
+				iss := srv.URL
+				if jwksIssuer != "" {
+					iss = jwksIssuer
+				}
+				_ = json.NewEncoder(w).Encode(map[string]string{
+					`issuer`:   iss,
+					`jwks_uri`: srv.URL + `/keys`,
+				})
+			case r.URL.Path == `/keys` && discovery, r.URL.Path == `/.well-known/jwks.json` && !discovery:
+				set := jwk.NewSet()
+				for _, key := range srv.keys {
+					pubkey, _ := jwk.PublicKeyOf(key)
+					set.Add(pubkey)
+				}
+				_ = json.NewEncoder(w).Encode(set)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		return srv
+	}
+
+	t.Run("OpenID Connect Discovery", func(t *testing.T) {
+		t.Parallel()
+		srv := newIssuerServer(true, "")
+		defer srv.Close()
+		key1, key2 := newKey(`key-1`), newKey(`key-2`)
+		srv.keys = []jwk.Key{key1}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		discovery, err := jwt.NewKeyDiscovery(ctx, jwt.KeyDiscoveryConfig{
+			Issuers:            []string{srv.URL},
+			HTTPClient:         srv.Client(),
+			RefreshInterval:    time.Hour,
+			MinRefreshInterval: time.Nanosecond,
+		})
+		if !assert.NoError(t, err, `jwt.NewKeyDiscovery should succeed`) {
+			return
+		}
+
+		for i := 0; i < 2; i++ {
+			parsed, err := jwt.Parse(sign(t, srv.URL, key1), jwt.WithVerifyAuto(discovery))
+			if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+				return
+			}
+			if !assert.Equal(t, srv.URL, parsed.Issuer(), `iss should match`) {
+				return
+			}
+		}
+		if !assert.Equal(t, []string{`/.well-known/openid-configuration`, `/keys`}, srv.requests, `discovery should happen once`) {
+			return
+		}
+
+		// The issuer rotates its keys
+		srv.mu.Lock()
+		srv.keys = []jwk.Key{key2}
+		srv.mu.Unlock()
+		if _, err := jwt.Parse(sign(t, srv.URL, key2), jwt.WithVerifyAuto(discovery)); !assert.NoError(t, err, `jwt.Parse with rotated key should succeed`) {
+			return
+		}
+
+		// Tokens signed by other keys are rejected
+		if _, err := jwt.Parse(sign(t, srv.URL, newKey(`key-2`)), jwt.WithVerifyAuto(discovery)); !assert.Error(t, err, `jwt.Parse should fail`) {
+			return
+		}
+
+		// Messages in JSON serialization format must have exactly one
+		// signature
+		payload := base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"` + srv.URL + `"}`))
+		for _, signatures := range []string{`[]`, `[{"header":{"alg":"ES256","kid":"key-2"},"signature":"AA"}]`} {
+			data := []byte(`{"payload":"` + payload + `","signatures":` + signatures + `}`)
+			if _, err := jwt.Parse(data, jwt.WithVerifyAuto(discovery)); !assert.Error(t, err, `jwt.Parse should fail`) {
+				return
+			}
+		}
+	})
+	t.Run("Well-known key set", func(t *testing.T) {
+		t.Parallel()
+		srv := newIssuerServer(false, "")
+		defer srv.Close()
+		key := newKey(`key-1`)
+		srv.keys = []jwk.Key{key}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		discovery, err := jwt.NewKeyDiscovery(ctx, jwt.KeyDiscoveryConfig{
+			Issuers:    []string{srv.URL},
+			HTTPClient: srv.Client(),
+		})
+		if !assert.NoError(t, err, `jwt.NewKeyDiscovery should succeed`) {
+			return
+		}
+
+		if _, err := jwt.Parse(sign(t, srv.URL, key), jwt.WithVerifyAuto(discovery), jwt.WithValidate(true)); !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+
+		// Discovery satisfies the verification requirement of profiles
+		tok := jwt.New()
+		claims := map[string]interface{}{
+			jwt.IssuerKey:     srv.URL,
+			jwt.SubjectKey:    `5ba552d67`,
+			jwt.AudienceKey:   `https://rs.example.com`,
+			jwt.ClientIDKey:   `s6BhdRkqt3`,
+			jwt.IssuedAtKey:   time.Now(),
+			jwt.ExpirationKey: time.Now().Add(time.Hour),
+			jwt.JwtIDKey:      `dbe39bf3a3ba4238a513f51d6e1691c4`,
+		}
+		for name, value := range claims {
+			if !assert.NoError(t, tok.Set(name, value), `tok.Set should succeed`) {
+				return
+			}
+		}
+		signed, err := jwt.Sign(tok, jwa.ES256, key, jwt.WithTokenType(jwt.TokenTypeAccessToken))
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return
+		}
+		if _, err := jwt.Parse(signed, jwt.WithVerifyAuto(discovery), jwt.WithProfile(jwt.RFC9068AccessToken), jwt.WithAudience(`https://rs.example.com`)); !assert.NoError(t, err, `jwt.Parse with profile should succeed`) {
+			return
+		}
+	})
+	t.Run("Untrusted issuer", func(t *testing.T) {
+		t.Parallel()
+		srv := newIssuerServer(true, "")
+		defer srv.Close()
+		key := newKey(`key-1`)
+		srv.keys = []jwk.Key{key}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		discovery, err := jwt.NewKeyDiscovery(ctx, jwt.KeyDiscoveryConfig{
+			Issuers:    []string{`https://issuer.example.com`},
+			HTTPClient: srv.Client(),
+		})
+		if !assert.NoError(t, err, `jwt.NewKeyDiscovery should succeed`) {
+			return
+		}
+
+		if _, err := jwt.Parse(sign(t, srv.URL, key), jwt.WithVerifyAuto(discovery)); !assert.Error(t, err, `jwt.Parse should fail`) {
+			return
+		}
+		if !assert.Empty(t, srv.requests, `no requests should be made`) {
+			return
+		}
+	})
+	t.Run("Issuer mismatch in discovery document", func(t *testing.T) {
+		t.Parallel()
+		srv := newIssuerServer(true, `https://issuer.example.com`)
+		defer srv.Close()
+		key := newKey(`key-1`)
+		srv.keys = []jwk.Key{key}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		discovery, err := jwt.NewKeyDiscovery(ctx, jwt.KeyDiscoveryConfig{
+			Issuers:    []string{srv.URL},
+			HTTPClient: srv.Client(),
+		})
+		if !assert.NoError(t, err, `jwt.NewKeyDiscovery should succeed`) {
+			return
+		}
+
+		if _, err := jwt.Parse(sign(t, srv.URL, key), jwt.WithVerifyAuto(discovery)); !assert.Error(t, err, `jwt.Parse should fail`) {
+			return
+		}
+	})
+	t.Run("Invalid configuration", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+		if _, err := jwt.NewKeyDiscovery(ctx, jwt.KeyDiscoveryConfig{}); !assert.Error(t, err, `issuers are required`) {
+			return
+		}
+		if _, err := jwt.NewKeyDiscovery(ctx, jwt.KeyDiscoveryConfig{Issuers: []string{`http://issuer.example.com`}}); !assert.Error(t, err, `issuers must use https`) {
+			return
+		}
+	})
+}
+
+func TestProtectClaims(t *testing.T) {
+	t.Parallel()
+
+	issuerKey, err := jwxtest.GenerateEcdsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`) {
+		return
+	}
+	intermediaryKey, err := jwxtest.GenerateRsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`) {
+		return
+	}
+
+	newToken := func(t *testing.T) jwt.Token {
+		tok := jwt.New()
+		_ = tok.Set(jwt.IssuerKey, `https://issuer.example.com`)
+		_ = tok.Set(jwt.SubjectKey, `alice`)
+		_ = tok.Set(`scope`, `read write`)
+		_ = tok.Set(jwt.AudienceKey, []string{`api-1`})
+		if !assert.NoError(t, jwt.ProtectClaims(tok, jwa.ES256, issuerKey, jwt.SubjectKey, `scope`), `jwt.ProtectClaims should succeed`) {
+			return nil
+		}
+		return tok
+	}
+
+	// resign emulates an intermediary that re-signs the token after
+	// modifying it
+	resign := func(t *testing.T, tok jwt.Token, modify func(jwt.Token)) jwt.Token {
+		modify(tok)
+		signed, err := jwt.Sign(tok, jwa.RS256, intermediaryKey)
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return nil
+		}
+		parsed, err := jwt.Parse(signed, jwt.WithVerify(jwa.RS256, &intermediaryKey.PublicKey))
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return nil
+		}
+		return parsed
+	}
+
+	t.Run("Unprotected claims may change", func(t *testing.T) {
+		t.Parallel()
+		tok := newToken(t)
+		if tok == nil {
+			return
+		}
+		tok = resign(t, tok, func(tok jwt.Token) {
+			_ = tok.Set(jwt.AudienceKey, []string{`api-2`})
+		})
+		if tok == nil {
+			return
+		}
+
+		claims, err := jwt.VerifyProtectedClaims(tok, jwa.ES256, &issuerKey.PublicKey)
+		if !assert.NoError(t, err, `jwt.VerifyProtectedClaims should succeed`) {
+			return
+		}
+		if !assert.Equal(t, []string{jwt.SubjectKey, `scope`}, claims, `protected claims should match`) {
+			return
+		}
+		if !assert.NoError(t, jwt.Validate(tok, jwt.WithProtectedClaims(jwa.ES256, &issuerKey.PublicKey)), `jwt.Validate should succeed`) {
+			return
+		}
+	})
+	t.Run("Tampering is detected", func(t *testing.T) {
+		t.Parallel()
+		testcases := map[string]func(jwt.Token){
+			"Modified": func(tok jwt.Token) { _ = tok.Set(`scope`, `read write admin`) },
+			"Removed":  func(tok jwt.Token) { _ = tok.Remove(jwt.SubjectKey) },
+		}
+		for name, modify := range testcases {
+			modify := modify
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				tok := newToken(t)
+				if tok == nil {
+					return
+				}
+				tok = resign(t, tok, modify)
+				if tok == nil {
+					return
+				}
+				_, err := jwt.VerifyProtectedClaims(tok, jwa.ES256, &issuerKey.PublicKey)
+				if !assert.True(t, errors.Is(err, jwt.ErrClaimsModified), `jwt.VerifyProtectedClaims should fail with ErrClaimsModified`) {
+					return
+				}
+				err = jwt.Validate(tok, jwt.WithProtectedClaims(jwa.ES256, &issuerKey.PublicKey))
+				if !assert.True(t, errors.Is(err, jwt.ErrClaimsModified), `error should be ErrClaimsModified`) {
+					return
+				}
+			})
+		}
+	})
+	t.Run("Digest signed by another key", func(t *testing.T) {
+		t.Parallel()
+		tok := newToken(t)
+		if tok == nil {
+			return
+		}
+		// The intermediary replaces the digest with its own
+		tok = resign(t, tok, func(tok jwt.Token) {
+			_ = tok.Set(`scope`, `read write admin`)
+			_ = jwt.ProtectClaims(tok, jwa.RS256, intermediaryKey, jwt.SubjectKey, `scope`)
+		})
+		if tok == nil {
+			return
+		}
+		err := jwt.Validate(tok, jwt.WithProtectedClaims(jwa.ES256, &issuerKey.PublicKey))
+		if !assert.True(t, errors.Is(err, jwt.ErrClaimsModified), `error should be ErrClaimsModified`) {
+			return
+		}
+
+		// The digest is removed altogether
+		tok = resign(t, newToken(t), func(tok jwt.Token) {
+			_ = tok.Set(jwt.ClaimsDigestKey, []string{})
+		})
+		if tok == nil {
+			return
+		}
+		_, err = jwt.VerifyProtectedClaims(tok, jwa.ES256, &issuerKey.PublicKey)
+		if !assert.True(t, errors.Is(err, jwt.ErrClaimsModified), `error should be ErrClaimsModified`) {
+			return
+		}
+	})
+	t.Run("Multiple issuers", func(t *testing.T) {
+		t.Parallel()
+		tok := newToken(t)
+		if tok == nil {
+			return
+		}
+		// The intermediary protects its own claims as well
+		tok = resign(t, tok, func(tok jwt.Token) {
+			_ = tok.Set(`act`, map[string]interface{}{`sub`: `https://gateway.example.com`})
+			_ = jwt.ProtectClaims(tok, jwa.RS256, intermediaryKey, `act`)
+		})
+		if tok == nil {
+			return
+		}
+
+		claims, err := jwt.VerifyProtectedClaims(tok, jwa.RS256, &intermediaryKey.PublicKey)
+		if !assert.NoError(t, err, `jwt.VerifyProtectedClaims should succeed`) {
+			return
+		}
+		if !assert.Equal(t, []string{`act`}, claims, `protected claims should match`) {
+			return
+		}
+		options := []jwt.ValidateOption{
+			jwt.WithProtectedClaims(jwa.ES256, &issuerKey.PublicKey),
+			jwt.WithProtectedClaims(jwa.RS256, &intermediaryKey.PublicKey),
+		}
+		if !assert.NoError(t, jwt.Validate(tok, options...), `jwt.Validate should succeed`) {
+			return
+		}
+
+		_ = tok.Set(`scope`, `read write admin`)
+		err = jwt.Validate(tok, options...)
+		if !assert.True(t, errors.Is(err, jwt.ErrClaimsModified), `error should be ErrClaimsModified`) {
+			return
+		}
+	})
+	t.Run("Missing digest", func(t *testing.T) {
+		t.Parallel()
+		tok := jwt.New()
+		_ = tok.Set(jwt.SubjectKey, `alice`)
+		err := jwt.Validate(tok, jwt.WithProtectedClaims(jwa.ES256, &issuerKey.PublicKey))
+		if !assert.True(t, errors.Is(err, jwt.ErrMissingClaim), `error should be ErrMissingClaim`) {
+			return
+		}
+	})
+	t.Run("Invalid arguments", func(t *testing.T) {
+		t.Parallel()
+		tok := jwt.New()
+		if !assert.Error(t, jwt.ProtectClaims(tok, jwa.ES256, issuerKey), `jwt.ProtectClaims without claims should fail`) {
+			return
+		}
+		if !assert.Error(t, jwt.ProtectClaims(tok, jwa.ES256, issuerKey, jwt.ClaimsDigestKey), `jwt.ProtectClaims should not protect its own claim`) {
+			return
+		}
+	})
+}
+
+func TestClaimOrder(t *testing.T) {
+	t.Parallel()
+
+	newToken := func() jwt.Token {
+		tok := jwt.New()
+		_ = tok.Set(`zone`, `eu`)
+		_ = tok.Set(jwt.JwtIDKey, `id-1`)
+		_ = tok.Set(`account`, map[string]interface{}{`tier`: `gold`, `id`: 42})
+		_ = tok.Set(jwt.IssuedAtKey, time.Unix(1600000000, 0))
+		_ = tok.Set(jwt.SubjectKey, `alice`)
+		_ = tok.Set(jwt.IssuerKey, `https://issuer.example.com`)
+		_ = tok.Set(jwt.AudienceKey, []string{`api`})
+		return tok
+	}
+
+	t.Run("Sorted", func(t *testing.T) {
+		t.Parallel()
+		const expected = `{"account":{"id":42,"tier":"gold"},"aud":["api"],"iat":1600000000,"iss":"https://issuer.example.com","jti":"id-1","sub":"alice","zone":"eu"}`
+		buf, err := jwt.Marshal(newToken())
+		if !assert.NoError(t, err, `jwt.Marshal should succeed`) {
+			return
+		}
+		if !assert.Equal(t, expected, string(buf), `claims should be sorted`) {
+			return
+		}
+		buf, err = json.Marshal(newToken())
+		if !assert.NoError(t, err, `json.Marshal should succeed`) {
+			return
+		}
+		if !assert.Equal(t, expected, string(buf), `json.Marshal should use the same order`) {
+			return
+		}
+	})
+	t.Run("Registered first", func(t *testing.T) {
+		t.Parallel()
+		const expected = `{"iss":"https://issuer.example.com","sub":"alice","aud":["api"],"iat":1600000000,"jti":"id-1","account":{"id":42,"tier":"gold"},"zone":"eu"}`
+		for i := 0; i < 10; i++ {
+			buf, err := jwt.Marshal(newToken(), jwt.WithClaimOrder(jwt.RegisteredFirstClaimOrder))
+			if !assert.NoError(t, err, `jwt.Marshal should succeed`) {
+				return
+			}
+			if !assert.Equal(t, expected, string(buf), `registered claims should come first`) {
+				return
+			}
+		}
+
+		key := []byte(`abracadabra-abracadabra-abracadabra`)
+		signed, err := jwt.Sign(newToken(), jwa.HS256, key, jwt.WithClaimOrder(jwt.RegisteredFirstClaimOrder))
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return
+		}
+		payload, err := jws.Verify(signed, jwa.HS256, key)
+		if !assert.NoError(t, err, `jws.Verify should succeed`) {
+			return
+		}
+		if !assert.Equal(t, expected, string(payload), `signed payload should use the same order`) {
+			return
+		}
+	})
+	t.Run("IterateOrdered", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+		var names []string
+		for iter := jwt.IterateOrdered(ctx, newToken(), jwt.RegisteredFirstClaimOrder); iter.Next(ctx); {
+			names = append(names, iter.Pair().Key.(string))
+		}
+		if !assert.Equal(t, []string{`iss`, `sub`, `aud`, `iat`, `jti`, `account`, `zone`}, names, `claims should be in order`) {
+			return
+		}
+	})
+}
+
+// TestNumericDatePrecision changes package-wide settings, so it must not
+// run in parallel with other tests
+func TestNumericDatePrecision(t *testing.T) {
+	defer jwt.Settings(
+		jwt.WithNumericDateParsePrecision(0),
+		jwt.WithNumericDateFormatPrecision(0),
+		jwt.WithNumericDateRejectFractional(false),
+	)
+
+	iat := time.Unix(1600000000, 123456789).UTC()
+
+	t.Run("Default", func(t *testing.T) {
+		tok := jwt.New()
+		_ = tok.Set(jwt.IssuedAtKey, iat)
+		buf, err := json.Marshal(tok)
+		if !assert.NoError(t, err, `json.Marshal should succeed`) {
+			return
+		}
+		if !assert.Equal(t, `{"iat":1600000000}`, string(buf), `"iat" should be an integer`) {
+			return
+		}
+
+		parsed, err := jwt.Parse([]byte(`{"iat":1600000000.987}`))
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, time.Unix(1600000000, 0).UTC(), parsed.IssuedAt(), `fractional part should be truncated`) {
+			return
+		}
+	})
+	t.Run("Milliseconds", func(t *testing.T) {
+		jwt.Settings(jwt.WithNumericDateFormatPrecision(3), jwt.WithNumericDateParsePrecision(3))
+		defer jwt.Settings(jwt.WithNumericDateFormatPrecision(0), jwt.WithNumericDateParsePrecision(0))
+
+		tok := jwt.New()
+		_ = tok.Set(jwt.IssuedAtKey, iat)
+		buf, err := json.Marshal(tok)
+		if !assert.NoError(t, err, `json.Marshal should succeed`) {
+			return
+		}
+		if !assert.Equal(t, `{"iat":1600000000.123}`, string(buf), `"iat" should have milliseconds`) {
+			return
+		}
+
+		parsed, err := jwt.Parse(buf)
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, time.Unix(1600000000, 123000000).UTC(), parsed.IssuedAt(), `milliseconds should be retained`) {
+			return
+		}
+
+		parsed, err = jwt.Parse([]byte(`{"iat":"1600000000.98765"}`))
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+		if !assert.Equal(t, time.Unix(1600000000, 987000000).UTC(), parsed.IssuedAt(), `digits beyond milliseconds should be truncated`) {
+			return
+		}
+	})
+	t.Run("Reject fractional", func(t *testing.T) {
+		jwt.Settings(jwt.WithNumericDateRejectFractional(true))
+		defer jwt.Settings(jwt.WithNumericDateRejectFractional(false))
+
+		if _, err := jwt.Parse([]byte(`{"exp":1600000000.5}`)); !assert.Error(t, err, `jwt.Parse should fail`) {
+			return
+		}
+		if _, err := jwt.Parse([]byte(`{"exp":1600000000.0}`)); !assert.NoError(t, err, `jwt.Parse should accept integral values`) {
+			return
+		}
+		if _, err := jwt.Parse([]byte(`{"exp":1600000000}`)); !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+	})
+}
+
+func TestVerifier(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwk.New([]byte(`abracadabra-abracadabra-abracadabra`))
+	if !assert.NoError(t, err, `jwk.New should succeed`) {
+		return
+	}
+	_ = key.Set(jwk.KeyIDKey, `key-1`)
+	_ = key.Set(jwk.AlgorithmKey, jwa.HS256)
+	keyset := jwk.NewSet()
+	keyset.Add(key)
+
+	sign := func(t *testing.T, aud string, exp time.Time) []byte {
+		tok := jwt.New()
+		_ = tok.Set(jwt.IssuerKey, `https://issuer.example.com`)
+		_ = tok.Set(jwt.AudienceKey, aud)
+		_ = tok.Set(jwt.ExpirationKey, exp)
+		signed, err := jwt.Sign(tok, jwa.HS256, key)
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return nil
+		}
+		return signed
+	}
+
+	if _, err := jwt.NewVerifier(jwt.WithIssuer(`https://issuer.example.com`)); !assert.Error(t, err, `jwt.NewVerifier without verification should fail`) {
+		return
+	}
+
+	verifier, err := jwt.NewVerifier(
+		jwt.WithKeySet(keyset),
+		jwt.WithIssuer(`https://issuer.example.com`),
+		jwt.WithAudience(`default`),
+	)
+	if !assert.NoError(t, err, `jwt.NewVerifier should succeed`) {
+		return
+	}
+
+	ctx := context.Background()
+	exp := time.Now().Add(time.Hour)
+	t.Run("Options of the verifier", func(t *testing.T) {
+		t.Parallel()
+		if _, err := verifier.Verify(ctx, sign(t, `default`, exp)); !assert.NoError(t, err, `verifier.Verify should succeed`) {
+			return
+		}
+		_, err := verifier.Verify(ctx, sign(t, `tenant-a`, exp))
+		if !assert.True(t, errors.Is(err, jwt.ErrInvalidAudience), `verifier.Verify should fail`) {
+			return
+		}
+		if _, err := verifier.Verify(ctx, sign(t, `default`, time.Now().Add(-time.Hour))); !assert.Error(t, err, `expired tokens should be rejected`) {
+			return
+		}
+	})
+	t.Run("Per-call overrides", func(t *testing.T) {
+		t.Parallel()
+		tenants := []string{`tenant-a`, `tenant-b`, `tenant-c`, `tenant-d`}
+		tokens := make(map[string][]byte)
+		for _, tenant := range tenants {
+			tokens[tenant] = sign(t, tenant, exp)
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 32; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				tenant := tenants[i%len(tenants)]
+				other := tenants[(i+1)%len(tenants)]
+
+				tok, err := verifier.Verify(ctx, tokens[tenant], jwt.WithAudience(tenant))
+				if !assert.NoError(t, err, `verifier.Verify should succeed`) {
+					return
+				}
+				if !assert.Equal(t, []string{tenant}, tok.Audience(), `"aud" should match`) {
+					return
+				}
+				_, err = verifier.Verify(ctx, tokens[other], jwt.WithAudience(tenant))
+				if !assert.True(t, errors.Is(err, jwt.ErrInvalidAudience), `token of another tenant should be rejected`) {
+					return
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		// Overrides do not affect subsequent calls
+		if _, err := verifier.Verify(ctx, tokens[`tenant-a`]); !assert.Error(t, err, `verifier.Verify without override should fail`) {
+			return
+		}
+	})
+	t.Run("Context", func(t *testing.T) {
+		t.Parallel()
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := verifier.Verify(canceled, sign(t, `default`, exp)); !assert.True(t, errors.Is(err, context.Canceled), `verifier.Verify should fail with canceled context`) {
+			return
+		}
+	})
+}
@@ -0,0 +1,184 @@
+// Package jwttest provides assertion helpers for tests that deal with
+// JWTs. The helpers report failures via the given TestingT (usually a
+// *testing.T), and return a boolean indicating success so that callers
+// can bail out early:
+//
+//     if !jwttest.AssertValid(t, token, jwt.WithIssuer(`github.com/lestrrat-go/jwx`)) {
+//       return
+//     }
+package jwttest
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/pkg/errors"
+)
+
+// TestingT is the subset of *testing.T that is used by the
+// assertion helpers in this package
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// VolatileClaims lists the claims that are ignored by `AssertEqual()`
+// and `AssertGolden()`, as their values usually change each time a
+// token is issued.
+var VolatileClaims = []string{jwt.IssuedAtKey, jwt.JwtIDKey}
+
+// AssertValid asserts that `jwt.Validate()` succeeds for the given
+// token and options.
+func AssertValid(t TestingT, token jwt.Token, options ...jwt.ValidateOption) bool {
+	t.Helper()
+	if err := jwt.Validate(token, options...); err != nil {
+		t.Errorf("expected token to be valid: %s", err)
+		return false
+	}
+	return true
+}
+
+// AssertInvalid asserts that `jwt.Validate()` fails for the given
+// token and options.
+func AssertInvalid(t TestingT, token jwt.Token, options ...jwt.ValidateOption) bool {
+	t.Helper()
+	if err := jwt.Validate(token, options...); err == nil {
+		t.Errorf("expected token to be invalid")
+		return false
+	}
+	return true
+}
+
+// AssertClaim asserts that the token contains the claim `name`, and
+// that its value is equal to `want`. Values of type time.Time are
+// compared using `time.Time.Equal()`, and all other values are compared
+// using `reflect.DeepEqual()`.
+func AssertClaim(t TestingT, token jwt.Token, name string, want interface{}) bool {
+	t.Helper()
+	got, ok := token.Get(name)
+	if !ok {
+		t.Errorf("expected claim %#v to exist", name)
+		return false
+	}
+
+	if !claimEqual(want, got) {
+		t.Errorf("claim %#v does not match:\n  expected: %#v\n  actual  : %#v", name, want, got)
+		return false
+	}
+	return true
+}
+
+// AssertNoClaim asserts that the token does not contain the claim `name`
+func AssertNoClaim(t TestingT, token jwt.Token, name string) bool {
+	t.Helper()
+	if v, ok := token.Get(name); ok {
+		t.Errorf("expected claim %#v to not exist (got %#v)", name, v)
+		return false
+	}
+	return true
+}
+
+// AssertEqual asserts that the two tokens contain the same claims.
+// The claims listed in `VolatileClaims`, as well as the claims given
+// in `ignore` are not compared.
+func AssertEqual(t TestingT, expected, actual jwt.Token, ignore ...string) bool {
+	t.Helper()
+	m1, err := claimsOf(expected)
+	if err != nil {
+		t.Errorf("failed to process expected token: %s", err)
+		return false
+	}
+
+	m2, err := claimsOf(actual)
+	if err != nil {
+		t.Errorf("failed to process actual token: %s", err)
+		return false
+	}
+
+	return compareClaims(t, m1, m2, ignore)
+}
+
+// AssertGolden asserts that the token contains the same claims as the
+// JSON object in `golden`, which is usually read from a file in the
+// testdata directory. The claims listed in `VolatileClaims`, as well as the
+// claims given in `ignore` are not compared.
+func AssertGolden(t TestingT, golden []byte, actual jwt.Token, ignore ...string) bool {
+	t.Helper()
+	var m1 map[string]interface{}
+	if err := json.Unmarshal(golden, &m1); err != nil {
+		t.Errorf("failed to parse golden data: %s", err)
+		return false
+	}
+
+	m2, err := claimsOf(actual)
+	if err != nil {
+		t.Errorf("failed to process actual token: %s", err)
+		return false
+	}
+
+	return compareClaims(t, m1, m2, ignore)
+}
+
+// claimsOf converts the token into a map through its JSON representation,
+// so that the values can be compared against arbitrary JSON data
+func claimsOf(token jwt.Token) (map[string]interface{}, error) {
+	buf, err := json.Marshal(token)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal token`)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return nil, errors.Wrap(err, `failed to unmarshal token`)
+	}
+	return m, nil
+}
+
+func compareClaims(t TestingT, expected, actual map[string]interface{}, ignore []string) bool {
+	t.Helper()
+	for _, name := range VolatileClaims {
+		delete(expected, name)
+		delete(actual, name)
+	}
+	for _, name := range ignore {
+		delete(expected, name)
+		delete(actual, name)
+	}
+
+	var diffs []string
+	for name, v1 := range expected {
+		v2, ok := actual[name]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("  %s: missing (expected %#v)", name, v1))
+			continue
+		}
+		if !reflect.DeepEqual(v1, v2) {
+			diffs = append(diffs, fmt.Sprintf("  %s: expected %#v, got %#v", name, v1, v2))
+		}
+	}
+	for name, v2 := range actual {
+		if _, ok := expected[name]; !ok {
+			diffs = append(diffs, fmt.Sprintf("  %s: unexpected claim (got %#v)", name, v2))
+		}
+	}
+
+	if len(diffs) > 0 {
+		sort.Strings(diffs)
+		t.Errorf("claims do not match:\n%s", strings.Join(diffs, "\n"))
+		return false
+	}
+	return true
+}
+
+func claimEqual(want, got interface{}) bool {
+	if wt, ok := want.(time.Time); ok {
+		gt, ok := got.(time.Time)
+		return ok && wt.Equal(gt)
+	}
+	return reflect.DeepEqual(want, got)
+}
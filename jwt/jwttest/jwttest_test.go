@@ -0,0 +1,75 @@
+package jwttest_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/lestrrat-go/jwx/jwt/jwttest"
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+func (r *recorder) Errorf(f string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(f, args...))
+}
+
+func TestAssertions(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(time.Now().Unix(), 0)
+	tok := jwt.New()
+	tok.Set(jwt.IssuerKey, "github.com/lestrrat-go/jwx")
+	tok.Set(jwt.SubjectKey, "unit test")
+	tok.Set(jwt.IssuedAtKey, now)
+	tok.Set(jwt.ExpirationKey, now.Add(time.Hour))
+	tok.Set(jwt.JwtIDKey, "abc")
+	tok.Set("roles", []string{"admin"})
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		var r recorder
+		ok := jwttest.AssertValid(&r, tok, jwt.WithIssuer("github.com/lestrrat-go/jwx")) &&
+			jwttest.AssertClaim(&r, tok, jwt.SubjectKey, "unit test") &&
+			jwttest.AssertClaim(&r, tok, jwt.ExpirationKey, now.Add(time.Hour)) &&
+			jwttest.AssertNoClaim(&r, tok, jwt.NotBeforeKey) &&
+			jwttest.AssertGolden(&r, []byte(fmt.Sprintf(`{"iss":"github.com/lestrrat-go/jwx","sub":"unit test","exp":%d,"roles":["admin"]}`, now.Add(time.Hour).Unix())), tok)
+		if !assert.True(t, ok, `assertions should succeed`) {
+			return
+		}
+		if !assert.Empty(t, r.errors, `no errors should be reported`) {
+			return
+		}
+	})
+	t.Run("Failure", func(t *testing.T) {
+		t.Parallel()
+		other, err := tok.Clone()
+		if !assert.NoError(t, err, `tok.Clone should succeed`) {
+			return
+		}
+		other.Set(jwt.JwtIDKey, "def")
+		other.Set(jwt.SubjectKey, "another test")
+
+		var r recorder
+		if !assert.False(t, jwttest.AssertEqual(&r, tok, other), `jwttest.AssertEqual should fail`) {
+			return
+		}
+		if !assert.True(t, jwttest.AssertEqual(&r, tok, other, jwt.SubjectKey), `jwttest.AssertEqual should succeed when sub is ignored`) {
+			return
+		}
+		if !assert.False(t, jwttest.AssertClaim(&r, tok, "roles", []string{"user"}), `jwttest.AssertClaim should fail`) {
+			return
+		}
+		if !assert.False(t, jwttest.AssertValid(&r, tok, jwt.WithIssuer("foo")), `jwttest.AssertValid should fail`) {
+			return
+		}
+		if !assert.Len(t, r.errors, 3, `errors should be reported`) {
+			return
+		}
+	})
+}
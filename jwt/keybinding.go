@@ -0,0 +1,136 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/x509"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+)
+
+// Names of the confirmation claim and its members, as described in RFC 7800,
+// RFC 8705 and RFC 9449
+const (
+	ConfirmationKey           = "cnf"
+	ConfirmationJWKKey        = "jwk"
+	ConfirmationJWKSHA256Key  = "jkt"
+	ConfirmationX509SHA256Key = "x5t#S256"
+	ConfirmationKeyIDKey      = "kid"
+	ConfirmationJWKSetURLKey  = "jku"
+)
+
+// BindToKey binds the token to the key of the presenter, by setting the
+// "jkt" member of the "cnf" (confirmation) claim to the base64url encoded
+// SHA-256 JWK thumbprint (RFC 7638) of the key.
+//
+// `key` may be a raw key (e.g. *ecdsa.PublicKey) or a jwk.Key. If a private
+// key is given, the thumbprint of the corresponding public key is used.
+// Other members of an existing "cnf" claim are preserved.
+//
+// Use `jwt.WithKeyBinding()` to verify the binding.
+// To bind the token when signing it without modifying the token, use
+// `jwt.WithConfirmationThumbprint()` instead.
+func BindToKey(t Token, key interface{}) error {
+	jkt, err := keyBindingThumbprint(key)
+	if err != nil {
+		return errors.Wrap(err, `failed to compute thumbprint`)
+	}
+
+	cnf, err := confirmationMap(t)
+	if err != nil {
+		return err
+	}
+	cnf[ConfirmationJWKSHA256Key] = jkt
+
+	if err := t.Set(ConfirmationKey, cnf); err != nil {
+		return errors.Wrapf(err, `failed to set %q claim`, ConfirmationKey)
+	}
+	return nil
+}
+
+func keyBindingThumbprint(key interface{}) (string, error) {
+	jwkKey, ok := key.(jwk.Key)
+	if !ok {
+		v, err := jwk.New(key)
+		if err != nil {
+			return "", errors.Wrap(err, `failed to create jwk.Key from raw key`)
+		}
+		jwkKey = v
+	}
+
+	tp, err := jwkKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", errors.Wrap(err, `failed to compute JWK thumbprint`)
+	}
+	return base64.EncodeToString(tp), nil
+}
+
+// verifyAnyKeyBinding checks that the "cnf" claim of the token identifies
+// at least one of the given keys or client certificates
+func verifyAnyKeyBinding(t Token, keys []interface{}) error {
+	if len(keys) == 0 {
+		return errors.New(`no keys were given to check the binding against`)
+	}
+
+	var first error
+	for _, key := range keys {
+		err := verifyKeyBinding(t, key)
+		if err == nil {
+			return nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	if len(keys) == 1 {
+		return first
+	}
+	return errors.Wrapf(first, `none of the %d keys match`, len(keys))
+}
+
+// verifyKeyBinding checks that the "cnf" claim of the token identifies the given
+// key or client certificate. Every member of the claim that can be
+// checked against the presented key must match, and at least one must.
+func verifyKeyBinding(t Token, key interface{}) error {
+	c, err := LookupConfirmation(t)
+	if err != nil {
+		return err
+	}
+
+	var matched bool
+	if cert, ok := key.(*x509.Certificate); ok {
+		if c.CertificateThumbprint != "" {
+			if !constantTimeEqual(c.CertificateThumbprint, certificateThumbprint(cert)) {
+				return errors.Errorf(`%q does not match the certificate`, ConfirmationX509SHA256Key)
+			}
+			matched = true
+		}
+		key = cert.PublicKey
+	}
+
+	if c.Key != nil || c.KeyThumbprint != "" {
+		tp, err := keyBindingThumbprint(key)
+		if err != nil {
+			return errors.Wrap(err, `failed to compute thumbprint`)
+		}
+		if c.Key != nil {
+			expected, err := keyBindingThumbprint(c.Key)
+			if err != nil {
+				return errors.Wrapf(err, `failed to compute thumbprint of %q`, ConfirmationJWKKey)
+			}
+			if !constantTimeEqual(expected, tp) {
+				return errors.Errorf(`%q does not match the key`, ConfirmationJWKKey)
+			}
+		}
+		if c.KeyThumbprint != "" && !constantTimeEqual(c.KeyThumbprint, tp) {
+			return errors.Errorf(`%q does not match the key`, ConfirmationJWKSHA256Key)
+		}
+		matched = true
+	}
+
+	if !matched {
+		return errors.Errorf(`%q claim has no member that can be verified`, ConfirmationKey)
+	}
+	return nil
+}
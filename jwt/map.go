@@ -0,0 +1,128 @@
+package jwt
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/pkg/errors"
+)
+
+// FromMap creates a new token from a map of claims, such as the
+// MapClaims used by other JWT libraries. It is the reverse of
+// `AsMap()`, and accepts the representations that are commonly found
+// in such maps:
+//
+//   - "exp", "iat" and "nbf" may be a time.Time, any integer or floating
+//     point type, a json.Number, or a string containing a number. Numbers
+//     are interpreted as seconds since the epoch, and fractions of a
+//     second are discarded.
+//   - "aud" may be a string, a []string, or a []interface{} of strings.
+//   - "iss", "sub" and "jti" must be strings.
+//   - Registered claims whose value is nil are skipped.
+//   - All other claims are stored as private claims as is.
+//
+// The map is not modified.
+func FromMap(m map[string]interface{}) (Token, error) {
+	// Process the claims in a deterministic order, so that errors
+	// are reported consistently
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	t := New()
+	for _, name := range names {
+		v := m[name]
+		switch name {
+		case AudienceKey, IssuerKey, SubjectKey, JwtIDKey:
+			if v == nil {
+				continue
+			}
+		case ExpirationKey, IssuedAtKey, NotBeforeKey:
+			if v == nil {
+				continue
+			}
+			tv, err := timeFromMapValue(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, `invalid value for %s`, name)
+			}
+			v = tv
+		}
+
+		if err := t.Set(name, v); err != nil {
+			return nil, errors.Wrapf(err, `failed to set %s`, name)
+		}
+	}
+	return t, nil
+}
+
+func timeFromMapValue(v interface{}) (time.Time, error) {
+	switch x := v.(type) {
+	case time.Time:
+		return x, nil
+	case *time.Time:
+		if x == nil {
+			return time.Time{}, errors.New(`nil *time.Time`)
+		}
+		return *x, nil
+	case json.Number:
+		return timeFromNumberString(string(x))
+	case string:
+		return timeFromNumberString(x)
+	case int:
+		return time.Unix(int64(x), 0), nil
+	case int8:
+		return time.Unix(int64(x), 0), nil
+	case int16:
+		return time.Unix(int64(x), 0), nil
+	case int32:
+		return time.Unix(int64(x), 0), nil
+	case int64:
+		return time.Unix(x, 0), nil
+	case uint:
+		return timeFromUint(uint64(x))
+	case uint8:
+		return time.Unix(int64(x), 0), nil
+	case uint16:
+		return time.Unix(int64(x), 0), nil
+	case uint32:
+		return time.Unix(int64(x), 0), nil
+	case uint64:
+		return timeFromUint(x)
+	case float32:
+		return timeFromFloat(float64(x))
+	case float64:
+		return timeFromFloat(x)
+	default:
+		return time.Time{}, errors.Errorf(`invalid type %T`, v)
+	}
+}
+
+func timeFromNumberString(s string) (time.Time, error) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(i, 0), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, errors.Errorf(`invalid number %q`, s)
+	}
+	return timeFromFloat(f)
+}
+
+func timeFromUint(u uint64) (time.Time, error) {
+	if u > math.MaxInt64 {
+		return time.Time{}, errors.Errorf(`value %d is out of range`, u)
+	}
+	return time.Unix(int64(u), 0), nil
+}
+
+func timeFromFloat(f float64) (time.Time, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) || f >= math.MaxInt64 || f < math.MinInt64 {
+		return time.Time{}, errors.Errorf(`value %v is out of range`, f)
+	}
+	return time.Unix(int64(f), 0), nil
+}
@@ -0,0 +1,61 @@
+package jwt
+
+import (
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+)
+
+// MetricsRecorder receives measurements from `jwt.Parse()` and its
+// variants, when specified using the `jwt.WithMetrics()` option. It
+// allows operators to export counters and histograms to their metrics
+// system of choice, without wrapping each call to the API. For example,
+// a recorder backed by Prometheus could look like this:
+//
+//     type promRecorder struct {
+//       parseDuration *prometheus.HistogramVec // labels: result
+//       verifications *prometheus.CounterVec   // labels: alg, iss, result
+//     }
+//
+//     func (r *promRecorder) ObserveParse(d time.Duration, err error) {
+//       r.parseDuration.WithLabelValues(result(err)).Observe(d.Seconds())
+//     }
+//     ...
+//
+// Implementations must be safe for concurrent use, and should return
+// quickly as they are called synchronously.
+type MetricsRecorder interface {
+	// ObserveParse is called once per parsed token, with the time it
+	// took to parse (and if requested, verify and validate) the token,
+	// and the error that was returned, if any.
+	ObserveParse(d time.Duration, err error)
+
+	// ObserveVerification is called when the signature of a token has
+	// been verified, including when the result was obtained from a
+	// `jwt.ValidationCache`. `issuer` is the "iss" claim of the token,
+	// and is only reported when the verification succeeded, so that
+	// unauthenticated values are never used as labels.
+	ObserveVerification(alg jwa.SignatureAlgorithm, issuer string, err error)
+
+	// ObserveValidation is called when the token has been validated,
+	// with the error that `jwt.Validate()` returned, if any. Use
+	// `errors.Is()` with the `jwt.ErrXXX` categories (e.g.
+	// `jwt.ErrTokenExpired`) to classify the failure.
+	ObserveValidation(err error)
+
+	// ObserveCacheLookup is called when the `jwt.ValidationCache` given
+	// by `jwt.WithValidationCache()` is searched for the token.
+	ObserveCacheLookup(hit bool)
+}
+
+// lookupMetrics returns the MetricsRecorder in options, if any
+func lookupMetrics(options []ParseOption) MetricsRecorder {
+	var recorder MetricsRecorder
+	for _, o := range options {
+		switch o.Ident() {
+		case identMetrics{}:
+			recorder = o.Value().(MetricsRecorder)
+		}
+	}
+	return recorder
+}
@@ -9,20 +9,52 @@ package openid
 
 import (
 	"context"
+	"sync"
 
+	"github.com/lestrrat-go/jwx/internal/json"
 	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/lestrrat-go/jwx/jwt/internal/types"
 	"github.com/pkg/errors"
 )
 
+// Clone returns a deep copy of the token. Maps, slices and pointers held by
+// the claims, such as "aud" and private claims, are copied as well, so the
+// new token can be modified without affecting the original.
 func (t *stdToken) Clone() (jwt.Token, error) {
 	var dst jwt.Token = New()
 
 	ctx := context.Background()
 	for iter := t.Iterate(ctx); iter.Next(ctx); {
 		pair := iter.Pair()
-		if err := dst.Set(pair.Key.(string), pair.Value); err != nil {
+		if err := dst.Set(pair.Key.(string), types.DeepCopy(pair.Value)); err != nil {
 			return nil, errors.Wrapf(err, `failed to set %s`, pair.Key.(string))
 		}
 	}
 	return dst, nil
 }
+
+// MarshalText returns the JSON representation of the claims. It does
+// NOT sign the token: use `jwt.Sign()` for that.
+func (t *stdToken) MarshalText() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+// UnmarshalText replaces the claims of the token with those in the
+// given JSON representation. No signature verification is performed.
+func (t *stdToken) UnmarshalText(data []byte) error {
+	if t.mu == nil {
+		t.mu = &sync.RWMutex{}
+	}
+	return t.UnmarshalJSON(data)
+}
+
+// MarshalBinary is the same as MarshalText. It allows tokens to be
+// stored using encoding/gob
+func (t *stdToken) MarshalBinary() ([]byte, error) {
+	return t.MarshalText()
+}
+
+// UnmarshalBinary is the same as UnmarshalText
+func (t *stdToken) UnmarshalBinary(data []byte) error {
+	return t.UnmarshalText(data)
+}
@@ -2,6 +2,9 @@ package openid_test
 
 import (
 	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
 	"testing"
 	"time"
@@ -118,6 +121,31 @@ func TestAdressClaim(t *testing.T) {
 	}
 }
 
+func TestCloneAddressClaim(t *testing.T) {
+	t.Parallel()
+
+	address := openid.NewAddress()
+	_ = address.Set(openid.AddressLocalityKey, `港区`)
+	tok := openid.New()
+	if !assert.NoError(t, tok.Set(openid.AddressKey, address), `tok.Set should succeed`) {
+		return
+	}
+
+	cloned, err := tok.Clone()
+	if !assert.NoError(t, err, `tok.Clone should succeed`) {
+		return
+	}
+	if !assert.NoError(t, cloned.(openid.Token).Address().Set(openid.AddressLocalityKey, `千代田区`), `address.Set should succeed`) {
+		return
+	}
+	if !assert.Equal(t, `港区`, tok.Address().Locality(), `address of the original token should not change`) {
+		return
+	}
+	if !assert.Equal(t, `千代田区`, cloned.(openid.Token).Address().Locality(), `address of the cloned token should change`) {
+		return
+	}
+}
+
 func TestOpenIDClaims(t *testing.T) {
 	getVerify := func(token openid.Token, key string, expected interface{}) bool {
 		v, ok := token.Get(key)
@@ -372,6 +400,62 @@ func TestOpenIDClaims(t *testing.T) {
 				assert.Equal(t, time.Unix(aLongLongTimeAgo, 0).UTC(), token.UpdatedAt())
 			},
 		},
+		{
+			Value: aLongLongTimeAgoString,
+			Key:   openid.AuthTimeKey,
+			Expected: func(v interface{}) interface{} {
+				var n types.NumericDate
+				if err := n.Accept(v); err != nil {
+					panic(err)
+				}
+				return n.Get()
+			},
+			Check: func(token openid.Token) {
+				assert.Equal(t, time.Unix(aLongLongTimeAgo, 0).UTC(), token.AuthTime())
+			},
+		},
+		{
+			Value: "n-0S6_WzA2Mj",
+			Key:   openid.NonceKey,
+			Check: func(token openid.Token) {
+				assert.Equal(t, "n-0S6_WzA2Mj", token.Nonce())
+			},
+		},
+		{
+			Value: "urn:mace:incommon:iap:silver",
+			Key:   openid.AuthContextClassKey,
+			Check: func(token openid.Token) {
+				assert.Equal(t, "urn:mace:incommon:iap:silver", token.AuthContextClass())
+			},
+		},
+		{
+			Value: []string{"pwd", "mfa"},
+			Key:   openid.AuthMethodsKey,
+			Check: func(token openid.Token) {
+				assert.Equal(t, []string{"pwd", "mfa"}, token.AuthMethods())
+			},
+		},
+		{
+			Value: "s6BhdRkqt3",
+			Key:   openid.AuthorizedPartyKey,
+			Check: func(token openid.Token) {
+				assert.Equal(t, "s6BhdRkqt3", token.AuthorizedParty())
+			},
+		},
+		{
+			Value: "77QmUPtjPfzWtF2AnpK9RQ",
+			Key:   openid.AccessTokenHashKey,
+			Check: func(token openid.Token) {
+				assert.Equal(t, "77QmUPtjPfzWtF2AnpK9RQ", token.AccessTokenHash())
+			},
+		},
+		{
+			Value: "LDktKdoQak3Pk0cnXxCltA",
+			Key:   openid.CodeHashKey,
+			Check: func(token openid.Token) {
+				assert.Equal(t, "LDktKdoQak3Pk0cnXxCltA", token.CodeHash())
+			},
+		},
 		{
 			Value: `dummy`,
 			Key:   `dummy`,
@@ -558,3 +642,213 @@ func TestBirthdateClaim(t *testing.T) {
 		}
 	})
 }
+
+func TestWithAzpCheck(t *testing.T) {
+	t.Parallel()
+
+	const clientID = "client-1"
+	testcases := []struct {
+		Name     string
+		Audience []string
+		Azp      string
+		Error    bool
+	}{
+		{Name: "Single audience without azp", Audience: []string{clientID}},
+		{Name: "Single audience with azp", Audience: []string{clientID}, Azp: clientID},
+		{Name: "Multiple audiences with azp", Audience: []string{clientID, "api"}, Azp: clientID},
+		{Name: "Multiple audiences without azp", Audience: []string{clientID, "api"}, Error: true},
+		{Name: "Mismatched azp", Audience: []string{clientID, "api"}, Azp: "client-2", Error: true},
+		{Name: "Client is not an audience", Audience: []string{"api"}, Azp: clientID, Error: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			tok := openid.New()
+			if !assert.NoError(t, tok.Set(jwt.AudienceKey, tc.Audience), `tok.Set should succeed`) {
+				return
+			}
+			if tc.Azp != "" {
+				if !assert.NoError(t, tok.Set(openid.AuthorizedPartyKey, tc.Azp), `tok.Set should succeed`) {
+					return
+				}
+			}
+
+			err := jwt.Validate(tok, openid.WithAzpCheck(clientID))
+			if tc.Error {
+				assert.Error(t, err, `jwt.Validate should fail`)
+				return
+			}
+			assert.NoError(t, err, `jwt.Validate should succeed`)
+		})
+	}
+}
+
+func TestValidateIDToken(t *testing.T) {
+	t.Parallel()
+
+	const (
+		issuer      = "https://op.example.com"
+		clientID    = "client-1"
+		nonce       = "n-0S6_WzA2Mj"
+		accessToken = "jHkWEdUXMU1BwAsC4vtUsZwnNzm8yK8l9ZD6t8Ztd7Y"
+		code        = "Qcb0Orv1zh30vL1MPRsbm-diHiMwcLyZvn1arpZv-Jxf_11jnpEX3Tgfvk"
+	)
+
+	// at_hash computed independently: the left-most 128 bits of SHA-256
+	sum := sha256.Sum256([]byte(accessToken))
+	atHash := base64.RawURLEncoding.EncodeToString(sum[:16])
+
+	cHash, err := openid.HalfHash(code, jwa.RS256)
+	if !assert.NoError(t, err, `openid.HalfHash should succeed`) {
+		return
+	}
+	for _, alg := range []jwa.SignatureAlgorithm{jwa.HS256, jwa.ES256, jwa.ES256K, jwa.PS256} {
+		v, err := openid.HalfHash(accessToken, alg)
+		if !assert.NoError(t, err, `openid.HalfHash should succeed for %s`, alg) {
+			return
+		}
+		if !assert.Equal(t, atHash, v, `openid.HalfHash should use SHA-256 for %s`, alg) {
+			return
+		}
+	}
+
+	makeToken := func(t *testing.T, skip ...string) openid.Token {
+		now := time.Now()
+		claims := map[string]interface{}{
+			jwt.IssuerKey:             issuer,
+			jwt.SubjectKey:            "24400320",
+			jwt.AudienceKey:           []string{clientID},
+			jwt.ExpirationKey:         now.Add(time.Hour),
+			jwt.IssuedAtKey:           now,
+			openid.NonceKey:           nonce,
+			openid.AccessTokenHashKey: atHash,
+			openid.CodeHashKey:        cHash,
+		}
+		for _, name := range skip {
+			delete(claims, name)
+		}
+
+		tok := openid.New()
+		for name, value := range claims {
+			if !assert.NoError(t, tok.Set(name, value), `tok.Set should succeed`) {
+				return nil
+			}
+		}
+		return tok
+	}
+
+	options := []jwt.ValidateOption{
+		openid.WithNonce(nonce),
+		openid.WithAccessTokenHash(accessToken, jwa.RS256),
+		openid.WithCodeHash(code, jwa.RS256),
+	}
+
+	t.Run("Valid token", func(t *testing.T) {
+		t.Parallel()
+		tok := makeToken(t)
+		if !assert.NoError(t, openid.ValidateIDToken(tok, issuer, clientID, options...), `openid.ValidateIDToken should succeed`) {
+			return
+		}
+	})
+
+	testcases := []struct {
+		Name    string
+		Skip    []string
+		Issuer  string
+		Options []jwt.ValidateOption
+	}{
+		{Name: "Missing sub", Skip: []string{jwt.SubjectKey}},
+		{Name: "Missing iat", Skip: []string{jwt.IssuedAtKey}},
+		{Name: "Missing exp", Skip: []string{jwt.ExpirationKey}},
+		{Name: "Missing nonce", Skip: []string{openid.NonceKey}},
+		{Name: "Missing at_hash", Skip: []string{openid.AccessTokenHashKey}},
+		{Name: "Wrong issuer", Issuer: "https://evil.example.com"},
+		{Name: "Wrong nonce", Options: []jwt.ValidateOption{openid.WithNonce("other")}},
+		{Name: "Wrong access token", Options: []jwt.ValidateOption{openid.WithAccessTokenHash("other", jwa.RS256)}},
+		{Name: "Wrong algorithm", Options: []jwt.ValidateOption{openid.WithAccessTokenHash(accessToken, jwa.RS512)}},
+		{Name: "Wrong code", Options: []jwt.ValidateOption{openid.WithCodeHash("other", jwa.RS256)}},
+		{Name: "Unsupported algorithm", Options: []jwt.ValidateOption{openid.WithCodeHash(code, jwa.NoSignature)}},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			iss := issuer
+			if tc.Issuer != "" {
+				iss = tc.Issuer
+			}
+			opts := options
+			if tc.Options != nil {
+				opts = tc.Options
+			}
+			tok := makeToken(t, tc.Skip...)
+			if !assert.Error(t, openid.ValidateIDToken(tok, iss, clientID, opts...), `openid.ValidateIDToken should fail`) {
+				return
+			}
+		})
+	}
+}
+
+func TestPairwiseSubject(t *testing.T) {
+	t.Parallel()
+
+	t.Run("SectorIdentifier", func(t *testing.T) {
+		t.Parallel()
+		sector, err := openid.SectorIdentifier("https://Client.Example.org:8443/callback?x=y")
+		if !assert.NoError(t, err, `openid.SectorIdentifier should succeed`) {
+			return
+		}
+		if !assert.Equal(t, "client.example.org", sector, `sector identifier should match`) {
+			return
+		}
+		_, err = openid.SectorIdentifier("/callback")
+		if !assert.Error(t, err, `openid.SectorIdentifier should fail for relative URIs`) {
+			return
+		}
+	})
+	t.Run("PairwiseSubject", func(t *testing.T) {
+		t.Parallel()
+		salt := []byte("salt")
+		sum := sha256.Sum256([]byte("client.example.org" + "alice" + "salt"))
+		if !assert.Equal(t, base64.RawURLEncoding.EncodeToString(sum[:]), openid.PairwiseSubject("client.example.org", "alice", salt), `sub should match`) {
+			return
+		}
+		if !assert.NotEqual(t, openid.PairwiseSubject("client.example.org", "alice", salt), openid.PairwiseSubject("other.example.org", "alice", salt), `sub should differ across sectors`) {
+			return
+		}
+	})
+	t.Run("PairwiseSubjectHMAC", func(t *testing.T) {
+		t.Parallel()
+		key := []byte("secret")
+		sub, err := openid.PairwiseSubjectHMAC("client.example.org", "alice", key, crypto.SHA256)
+		if !assert.NoError(t, err, `openid.PairwiseSubjectHMAC should succeed`) {
+			return
+		}
+		if !assert.Len(t, sub, 43, `sub should be a base64url encoded SHA-256 MAC`) {
+			return
+		}
+
+		ok, err := openid.VerifyPairwiseSubjectHMAC(sub, "client.example.org", "alice", key, crypto.SHA256)
+		if !assert.NoError(t, err, `openid.VerifyPairwiseSubjectHMAC should succeed`) {
+			return
+		}
+		if !assert.True(t, ok, `sub should be verified`) {
+			return
+		}
+
+		ok, err = openid.VerifyPairwiseSubjectHMAC(sub, "client.example.or", "galice", key, crypto.SHA256)
+		if !assert.NoError(t, err, `openid.VerifyPairwiseSubjectHMAC should succeed`) {
+			return
+		}
+		if !assert.False(t, ok, `shifted values should not match`) {
+			return
+		}
+
+		_, err = openid.PairwiseSubjectHMAC("client.example.org", "alice", nil, crypto.SHA256)
+		if !assert.Error(t, err, `openid.PairwiseSubjectHMAC should fail without a key`) {
+			return
+		}
+	})
+}
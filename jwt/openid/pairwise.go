@@ -0,0 +1,87 @@
+package openid
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"net/url"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/pkg/errors"
+)
+
+// SectorIdentifier returns the sector identifier of a client, which is
+// the host component of its "sector_identifier_uri", or of its
+// "redirect_uri" if it has not registered one (OpenID Connect Core 1.0
+// section 8.1). Clients that register multiple redirect URIs with
+// different hosts must register a "sector_identifier_uri".
+func SectorIdentifier(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", errors.Wrapf(err, `failed to parse URI %q`, uri)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return "", errors.Errorf(`URI %q does not have a host`, uri)
+	}
+	return strings.ToLower(host), nil
+}
+
+// PairwiseSubject computes a pairwise subject identifier for the local
+// account identifier `localSub` and the sector identifier `sectorID`,
+// using the algorithm described in OpenID Connect Core 1.0 section 8.1:
+// the SHA-256 hash of the concatenation of `sectorID`, `localSub` and
+// `salt`, encoded using base64url.
+//
+// `salt` must be kept secret, otherwise the subject identifiers of a
+// known account may be correlated across sectors.
+func PairwiseSubject(sectorID, localSub string, salt []byte) string {
+	h := sha256.New()
+	h.Write([]byte(sectorID))
+	h.Write([]byte(localSub))
+	h.Write(salt)
+	return base64.EncodeToString(h.Sum(nil))
+}
+
+// PairwiseSubjectHMAC computes a pairwise subject identifier for the
+// local account identifier `localSub` and the sector identifier
+// `sectorID`, as the HMAC of the two values using the secret `key` and
+// the hash function `hash`, encoded using base64url.
+//
+// Unlike `openid.PairwiseSubject()`, the values are length-prefixed
+// before being hashed, so that different pairs of values never produce
+// the same input (e.g. "ab" and "c", and "a" and "bc"). As the result is
+// deterministic, the identity provider may recompute the identifier of an
+// account for a sector to look up the account, instead of storing every
+// identifier it has issued.
+func PairwiseSubjectHMAC(sectorID, localSub string, key []byte, hash crypto.Hash) (string, error) {
+	if !hash.Available() {
+		return "", errors.Errorf(`hash function %v is not available`, hash)
+	}
+	if len(key) == 0 {
+		return "", errors.New(`key must not be empty`)
+	}
+
+	mac := hmac.New(hash.New, key)
+	for _, v := range []string{sectorID, localSub} {
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(v)))
+		mac.Write(l[:])
+		mac.Write([]byte(v))
+	}
+	return base64.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifyPairwiseSubjectHMAC reports whether `sub` is the pairwise subject
+// identifier of the local account identifier `localSub` for the sector
+// identifier `sectorID`, as computed by `openid.PairwiseSubjectHMAC()`.
+// The comparison is performed in constant time.
+func VerifyPairwiseSubjectHMAC(sub, sectorID, localSub string, key []byte, hash crypto.Hash) (bool, error) {
+	expected, err := PairwiseSubjectHMAC(sectorID, localSub, key, hash)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal([]byte(sub), []byte(expected)), nil
+}
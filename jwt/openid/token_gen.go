@@ -46,6 +46,13 @@ const (
 	PhoneNumberVerifiedKey = "phone_number_verified"
 	AddressKey             = "address"
 	UpdatedAtKey           = "updated_at"
+	AuthTimeKey            = jwt.AuthTimeKey
+	NonceKey               = "nonce"
+	AuthContextClassKey    = jwt.AuthContextClassKey
+	AuthMethodsKey         = jwt.AuthMethodsKey
+	AuthorizedPartyKey     = "azp"
+	AccessTokenHashKey     = "at_hash"
+	CodeHashKey            = "c_hash"
 )
 
 type Token interface {
@@ -75,14 +82,26 @@ type Token interface {
 	PhoneNumberVerified() bool
 	Address() *AddressClaim
 	UpdatedAt() time.Time
+	AuthTime() time.Time
+	Nonce() string
+	AuthContextClass() string
+	AuthMethods() []string
+	AuthorizedParty() string
+	AccessTokenHash() string
+	CodeHash() string
 	PrivateClaims() map[string]interface{}
 	Get(string) (interface{}, bool)
 	Set(string, interface{}) error
 	Remove(string) error
+	Keys() []string
 	Clone() (jwt.Token, error)
 	Iterate(context.Context) Iterator
 	Walk(context.Context, Visitor) error
 	AsMap(context.Context) (map[string]interface{}, error)
+	MarshalText() ([]byte, error)
+	UnmarshalText([]byte) error
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary([]byte) error
 }
 type stdToken struct {
 	mu                  *sync.RWMutex
@@ -112,6 +131,13 @@ type stdToken struct {
 	phoneNumberVerified *bool              //
 	address             *AddressClaim      //
 	updatedAt           *types.NumericDate //
+	authTime            *types.NumericDate // https://openid.net/specs/openid-connect-core-1_0.html#IDToken
+	nonce               *string            // https://openid.net/specs/openid-connect-core-1_0.html#IDToken
+	authContextClass    *string            // https://openid.net/specs/openid-connect-core-1_0.html#IDToken
+	authMethods         types.StringList   // https://openid.net/specs/openid-connect-core-1_0.html#IDToken
+	authorizedParty     *string            // https://openid.net/specs/openid-connect-core-1_0.html#IDToken
+	accessTokenHash     *string            // https://openid.net/specs/openid-connect-core-1_0.html#CodeIDToken
+	codeHash            *string            // https://openid.net/specs/openid-connect-core-1_0.html#HybridIDToken
 	privateClaims       map[string]interface{}
 }
 
@@ -142,10 +168,17 @@ type openidTokenMarshalProxy struct {
 	XphoneNumberVerified *bool              `json:"phone_number_verified,omitempty"`
 	Xaddress             *AddressClaim      `json:"address,omitempty"`
 	XupdatedAt           *types.NumericDate `json:"updated_at,omitempty"`
+	XauthTime            *types.NumericDate `json:"auth_time,omitempty"`
+	Xnonce               *string            `json:"nonce,omitempty"`
+	XauthContextClass    *string            `json:"acr,omitempty"`
+	XauthMethods         types.StringList   `json:"amr,omitempty"`
+	XauthorizedParty     *string            `json:"azp,omitempty"`
+	XaccessTokenHash     *string            `json:"at_hash,omitempty"`
+	XcodeHash            *string            `json:"c_hash,omitempty"`
 }
 
 // New creates a standard token, with minimal knowledge of
-// possible claims. Standard claims include"aud", "exp", "iat", "iss", "jti", "nbf", "sub", "name", "given_name", "middle_name", "family_name", "nickname", "preferred_username", "profile", "picture", "website", "email", "email_verified", "gender", "birthdate", "zoneinfo", "locale", "phone_number", "phone_number_verified", "address" and "updated_at".
+// possible claims. Standard claims include"aud", "exp", "iat", "iss", "jti", "nbf", "sub", "name", "given_name", "middle_name", "family_name", "nickname", "preferred_username", "profile", "picture", "website", "email", "email_verified", "gender", "birthdate", "zoneinfo", "locale", "phone_number", "phone_number_verified", "address", "updated_at", "auth_time", "nonce", "acr", "amr", "azp", "at_hash" and "c_hash".
 // Convenience accessors are provided for these standard claims
 func New() Token {
 	return &stdToken{
@@ -314,6 +347,48 @@ func (t *stdToken) Get(name string) (interface{}, bool) {
 		}
 		v := t.updatedAt.Get()
 		return v, true
+	case AuthTimeKey:
+		if t.authTime == nil {
+			return nil, false
+		}
+		v := t.authTime.Get()
+		return v, true
+	case NonceKey:
+		if t.nonce == nil {
+			return nil, false
+		}
+		v := *(t.nonce)
+		return v, true
+	case AuthContextClassKey:
+		if t.authContextClass == nil {
+			return nil, false
+		}
+		v := *(t.authContextClass)
+		return v, true
+	case AuthMethodsKey:
+		if t.authMethods == nil {
+			return nil, false
+		}
+		v := t.authMethods.Get()
+		return v, true
+	case AuthorizedPartyKey:
+		if t.authorizedParty == nil {
+			return nil, false
+		}
+		v := *(t.authorizedParty)
+		return v, true
+	case AccessTokenHashKey:
+		if t.accessTokenHash == nil {
+			return nil, false
+		}
+		v := *(t.accessTokenHash)
+		return v, true
+	case CodeHashKey:
+		if t.codeHash == nil {
+			return nil, false
+		}
+		v := *(t.codeHash)
+		return v, true
 	default:
 		v, ok := t.privateClaims[name]
 		return v, ok
@@ -376,6 +451,20 @@ func (t *stdToken) Remove(key string) error {
 		t.address = nil
 	case UpdatedAtKey:
 		t.updatedAt = nil
+	case AuthTimeKey:
+		t.authTime = nil
+	case NonceKey:
+		t.nonce = nil
+	case AuthContextClassKey:
+		t.authContextClass = nil
+	case AuthMethodsKey:
+		t.authMethods = nil
+	case AuthorizedPartyKey:
+		t.authorizedParty = nil
+	case AccessTokenHashKey:
+		t.accessTokenHash = nil
+	case CodeHashKey:
+		t.codeHash = nil
 	default:
 		delete(t.privateClaims, key)
 	}
@@ -549,6 +638,50 @@ func (t *stdToken) Set(name string, value interface{}) error {
 		}
 		t.updatedAt = &acceptor
 		return nil
+	case AuthTimeKey:
+		var acceptor types.NumericDate
+		if err := acceptor.Accept(value); err != nil {
+			return errors.Wrapf(err, `invalid value for %s key`, AuthTimeKey)
+		}
+		t.authTime = &acceptor
+		return nil
+	case NonceKey:
+		if v, ok := value.(string); ok {
+			t.nonce = &v
+			return nil
+		}
+		return errors.Errorf(`invalid value for %s key: %T`, NonceKey, value)
+	case AuthContextClassKey:
+		if v, ok := value.(string); ok {
+			t.authContextClass = &v
+			return nil
+		}
+		return errors.Errorf(`invalid value for %s key: %T`, AuthContextClassKey, value)
+	case AuthMethodsKey:
+		var acceptor types.StringList
+		if err := acceptor.Accept(value); err != nil {
+			return errors.Wrapf(err, `invalid value for %s key`, AuthMethodsKey)
+		}
+		t.authMethods = acceptor
+		return nil
+	case AuthorizedPartyKey:
+		if v, ok := value.(string); ok {
+			t.authorizedParty = &v
+			return nil
+		}
+		return errors.Errorf(`invalid value for %s key: %T`, AuthorizedPartyKey, value)
+	case AccessTokenHashKey:
+		if v, ok := value.(string); ok {
+			t.accessTokenHash = &v
+			return nil
+		}
+		return errors.Errorf(`invalid value for %s key: %T`, AccessTokenHashKey, value)
+	case CodeHashKey:
+		if v, ok := value.(string); ok {
+			t.codeHash = &v
+			return nil
+		}
+		return errors.Errorf(`invalid value for %s key: %T`, CodeHashKey, value)
 	default:
 		if t.privateClaims == nil {
 			t.privateClaims = map[string]interface{}{}
@@ -786,6 +919,69 @@ func (t *stdToken) UpdatedAt() time.Time {
 	return time.Time{}
 }
 
+func (t *stdToken) AuthTime() time.Time {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.authTime != nil {
+		return t.authTime.Get()
+	}
+	return time.Time{}
+}
+
+func (t *stdToken) Nonce() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.nonce != nil {
+		return *(t.nonce)
+	}
+	return ""
+}
+
+func (t *stdToken) AuthContextClass() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.authContextClass != nil {
+		return *(t.authContextClass)
+	}
+	return ""
+}
+
+func (t *stdToken) AuthMethods() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.authMethods != nil {
+		return t.authMethods.Get()
+	}
+	return nil
+}
+
+func (t *stdToken) AuthorizedParty() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.authorizedParty != nil {
+		return *(t.authorizedParty)
+	}
+	return ""
+}
+
+func (t *stdToken) AccessTokenHash() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.accessTokenHash != nil {
+		return *(t.accessTokenHash)
+	}
+	return ""
+}
+
+func (t *stdToken) CodeHash() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.codeHash != nil {
+		return *(t.codeHash)
+	}
+	return ""
+}
+
 func (t *stdToken) PrivateClaims() map[string]interface{} {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
@@ -901,12 +1097,54 @@ func (t *stdToken) makePairs() []*ClaimPair {
 		v := t.updatedAt.Get()
 		pairs = append(pairs, &ClaimPair{Key: UpdatedAtKey, Value: v})
 	}
+	if t.authTime != nil {
+		v := t.authTime.Get()
+		pairs = append(pairs, &ClaimPair{Key: AuthTimeKey, Value: v})
+	}
+	if t.nonce != nil {
+		v := *(t.nonce)
+		pairs = append(pairs, &ClaimPair{Key: NonceKey, Value: v})
+	}
+	if t.authContextClass != nil {
+		v := *(t.authContextClass)
+		pairs = append(pairs, &ClaimPair{Key: AuthContextClassKey, Value: v})
+	}
+	if t.authMethods != nil {
+		v := t.authMethods.Get()
+		pairs = append(pairs, &ClaimPair{Key: AuthMethodsKey, Value: v})
+	}
+	if t.authorizedParty != nil {
+		v := *(t.authorizedParty)
+		pairs = append(pairs, &ClaimPair{Key: AuthorizedPartyKey, Value: v})
+	}
+	if t.accessTokenHash != nil {
+		v := *(t.accessTokenHash)
+		pairs = append(pairs, &ClaimPair{Key: AccessTokenHashKey, Value: v})
+	}
+	if t.codeHash != nil {
+		v := *(t.codeHash)
+		pairs = append(pairs, &ClaimPair{Key: CodeHashKey, Value: v})
+	}
 	for k, v := range t.privateClaims {
 		pairs = append(pairs, &ClaimPair{Key: k, Value: v})
 	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.(string) < pairs[j].Key.(string)
+	})
 	return pairs
 }
 
+// Keys returns the names of all claims, both standard and private,
+// stored in the token in lexical order
+func (t *stdToken) Keys() []string {
+	pairs := t.makePairs()
+	keys := make([]string, len(pairs))
+	for i, pair := range pairs {
+		keys[i] = pair.Key.(string)
+	}
+	return keys
+}
+
 func (t *stdToken) UnmarshalJSON(buf []byte) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -936,6 +1174,13 @@ func (t *stdToken) UnmarshalJSON(buf []byte) error {
 	t.phoneNumberVerified = nil
 	t.address = nil
 	t.updatedAt = nil
+	t.authTime = nil
+	t.nonce = nil
+	t.authContextClass = nil
+	t.authMethods = nil
+	t.authorizedParty = nil
+	t.accessTokenHash = nil
+	t.codeHash = nil
 	dec := json.NewDecoder(bytes.NewReader(buf))
 LOOP:
 	for {
@@ -1076,6 +1321,38 @@ LOOP:
 					return errors.Wrapf(err, `failed to decode value for key %s`, UpdatedAtKey)
 				}
 				t.updatedAt = &decoded
+			case AuthTimeKey:
+				var decoded types.NumericDate
+				if err := dec.Decode(&decoded); err != nil {
+					return errors.Wrapf(err, `failed to decode value for key %s`, AuthTimeKey)
+				}
+				t.authTime = &decoded
+			case NonceKey:
+				if err := json.AssignNextStringToken(&t.nonce, dec); err != nil {
+					return errors.Wrapf(err, `failed to decode value for key %s`, NonceKey)
+				}
+			case AuthContextClassKey:
+				if err := json.AssignNextStringToken(&t.authContextClass, dec); err != nil {
+					return errors.Wrapf(err, `failed to decode value for key %s`, AuthContextClassKey)
+				}
+			case AuthMethodsKey:
+				var decoded types.StringList
+				if err := dec.Decode(&decoded); err != nil {
+					return errors.Wrapf(err, `failed to decode value for key %s`, AuthMethodsKey)
+				}
+				t.authMethods = decoded
+			case AuthorizedPartyKey:
+				if err := json.AssignNextStringToken(&t.authorizedParty, dec); err != nil {
+					return errors.Wrapf(err, `failed to decode value for key %s`, AuthorizedPartyKey)
+				}
+			case AccessTokenHashKey:
+				if err := json.AssignNextStringToken(&t.accessTokenHash, dec); err != nil {
+					return errors.Wrapf(err, `failed to decode value for key %s`, AccessTokenHashKey)
+				}
+			case CodeHashKey:
+				if err := json.AssignNextStringToken(&t.codeHash, dec); err != nil {
+					return errors.Wrapf(err, `failed to decode value for key %s`, CodeHashKey)
+				}
 			default:
 				var decoded interface{}
 				if err := dec.Decode(&decoded); err != nil {
@@ -1093,15 +1370,10 @@ LOOP:
 	return nil
 }
 
-func (t stdToken) MarshalJSON() ([]byte, error) {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+func (t *stdToken) MarshalJSON() ([]byte, error) {
 	data := make(map[string]interface{})
-	fields := make([]string, 0, 26)
-	for iter := t.Iterate(ctx); iter.Next(ctx); {
-		pair := iter.Pair()
+	fields := make([]string, 0, 33)
+	for _, pair := range t.makePairs() {
 		fields = append(fields, pair.Key.(string))
 		data[pair.Key.(string)] = pair.Value
 	}
@@ -1126,8 +1398,8 @@ func (t stdToken) MarshalJSON() ([]byte, error) {
 			buf.WriteRune('"')
 		case time.Time:
 			switch f {
-			case ExpirationKey, IssuedAtKey, NotBeforeKey, UpdatedAtKey:
-				enc.Encode(v.Unix())
+			case ExpirationKey, IssuedAtKey, NotBeforeKey, UpdatedAtKey, AuthTimeKey:
+				buf.WriteString(types.FormatNumericDate(v))
 			default:
 				if err := enc.Encode(v); err != nil {
 					return nil, errors.Wrapf(err, `failed to marshal field %s`, f)
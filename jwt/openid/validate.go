@@ -0,0 +1,182 @@
+package openid
+
+import (
+	"crypto"
+	_ "crypto/sha256" // for at_hash and c_hash
+	_ "crypto/sha512"
+	"crypto/subtle"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/pkg/errors"
+)
+
+type azpValidator struct {
+	clientID string
+}
+
+// WithAzpCheck returns a `jwt.ValidateOption` that enforces the rules for
+// the "azp" claim in OpenID Connect Core 1.0 section 3.1.3.7 for an ID
+// token issued to the client `clientID`:
+//
+// The client must be one of the audiences of the token. If the token has
+// multiple audiences, the "azp" claim must be present. If the "azp"
+// claim is present, its value must be `clientID`.
+func WithAzpCheck(clientID string) jwt.ValidateOption {
+	return jwt.WithValidator(&azpValidator{clientID: clientID})
+}
+
+func (v *azpValidator) Validate(t jwt.Token) error {
+	aud := t.Audience()
+
+	var found bool
+	for _, a := range aud {
+		if a == v.clientID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return errors.Errorf(`aud not satisfied: %q is not an audience of the token`, v.clientID)
+	}
+
+	raw, ok := t.Get(AuthorizedPartyKey)
+	if !ok {
+		if len(aud) > 1 {
+			return errors.New(`azp not satisfied: azp is required when the token has multiple audiences`)
+		}
+		return nil
+	}
+
+	azp, ok := raw.(string)
+	if !ok {
+		return errors.Errorf(`azp not satisfied: invalid type %T`, raw)
+	}
+	if azp != v.clientID {
+		return errors.Errorf(`azp not satisfied: %q is not the expected client`, azp)
+	}
+	return nil
+}
+
+// ValidateIDToken validates an ID token issued by `issuer` to the client
+// `clientID`, following OpenID Connect Core 1.0 section 3.1.3.7: the
+// "iss", "sub", "aud", "exp" and "iat" claims are required, "iss" must
+// be `issuer`, and the "aud" and "azp" claims are checked as done by
+// `openid.WithAzpCheck()`. The time based claims are checked as done by
+// `jwt.Validate()`.
+//
+// Additional options, such as `openid.WithNonce()` and
+// `openid.WithAccessTokenHash()`, are passed to `jwt.Validate()` as is.
+// The signature of the token must have been verified beforehand, e.g.
+// by `jwt.Parse()`.
+func ValidateIDToken(t jwt.Token, issuer, clientID string, options ...jwt.ValidateOption) error {
+	preset := []jwt.ValidateOption{
+		jwt.WithRequiredClaim(jwt.IssuerKey),
+		jwt.WithRequiredClaim(jwt.SubjectKey),
+		jwt.WithRequiredClaim(jwt.AudienceKey),
+		jwt.WithRequiredClaim(jwt.ExpirationKey),
+		jwt.WithRequiredClaim(jwt.IssuedAtKey),
+		jwt.WithIssuer(issuer),
+		WithAzpCheck(clientID),
+	}
+	return jwt.Validate(t, append(preset, options...)...)
+}
+
+type nonceValidator struct {
+	nonce string
+}
+
+// WithNonce returns a `jwt.ValidateOption` that requires the "nonce"
+// claim to be `nonce`, the value that the client sent in the
+// authentication request.
+func WithNonce(nonce string) jwt.ValidateOption {
+	return jwt.WithValidator(&nonceValidator{nonce: nonce})
+}
+
+func (v *nonceValidator) Validate(t jwt.Token) error {
+	raw, ok := t.Get(NonceKey)
+	if !ok {
+		return errors.New(`nonce not satisfied: required claim nonce is missing`)
+	}
+	nonce, ok := raw.(string)
+	if !ok {
+		return errors.Errorf(`nonce not satisfied: invalid type %T`, raw)
+	}
+	if subtle.ConstantTimeCompare([]byte(nonce), []byte(v.nonce)) != 1 {
+		return errors.New(`nonce not satisfied: values do not match`)
+	}
+	return nil
+}
+
+type halfHashValidator struct {
+	claim string
+	value string
+	alg   jwa.SignatureAlgorithm
+}
+
+// WithAccessTokenHash returns a `jwt.ValidateOption` that requires the
+// "at_hash" claim to match `accessToken`, the access token that was
+// issued along with the ID token. `alg` is the algorithm that the ID
+// token was signed with, which determines the hash function.
+//
+// The claim is required when this option is given. In flows where the
+// "at_hash" claim is optional, check for its presence before adding
+// this option.
+func WithAccessTokenHash(accessToken string, alg jwa.SignatureAlgorithm) jwt.ValidateOption {
+	return jwt.WithValidator(&halfHashValidator{claim: AccessTokenHashKey, value: accessToken, alg: alg})
+}
+
+// WithCodeHash returns a `jwt.ValidateOption` that requires the "c_hash"
+// claim to match `code`, the authorization code that was issued along
+// with the ID token. `alg` is the algorithm that the ID token was signed
+// with, which determines the hash function.
+func WithCodeHash(code string, alg jwa.SignatureAlgorithm) jwt.ValidateOption {
+	return jwt.WithValidator(&halfHashValidator{claim: CodeHashKey, value: code, alg: alg})
+}
+
+func (v *halfHashValidator) Validate(t jwt.Token) error {
+	raw, ok := t.Get(v.claim)
+	if !ok {
+		return errors.Errorf(`%s not satisfied: required claim %s is missing`, v.claim, v.claim)
+	}
+	actual, ok := raw.(string)
+	if !ok {
+		return errors.Errorf(`%s not satisfied: invalid type %T`, v.claim, raw)
+	}
+
+	expected, err := HalfHash(v.value, v.alg)
+	if err != nil {
+		return errors.Wrapf(err, `%s not satisfied`, v.claim)
+	}
+	if subtle.ConstantTimeCompare([]byte(actual), []byte(expected)) != 1 {
+		return errors.Errorf(`%s not satisfied: values do not match`, v.claim)
+	}
+	return nil
+}
+
+// HalfHash computes the value of the "at_hash" or "c_hash" claims for
+// the access token or the authorization code `value`: the base64url
+// encoding of the left-most half of the hash of `value`, where the hash
+// function is the one used by the signature algorithm `alg` of the ID
+// token. SHA-512 is used for EdDSA.
+//
+// Issuers can use this function to populate the claims.
+func HalfHash(value string, alg jwa.SignatureAlgorithm) (string, error) {
+	var h crypto.Hash
+	switch alg {
+	case jwa.HS256, jwa.RS256, jwa.ES256, jwa.ES256K, jwa.PS256, jwa.BP256R1:
+		h = crypto.SHA256
+	case jwa.HS384, jwa.RS384, jwa.ES384, jwa.PS384, jwa.BP384R1:
+		h = crypto.SHA384
+	case jwa.HS512, jwa.RS512, jwa.ES512, jwa.PS512, jwa.BP512R1, jwa.EdDSA:
+		h = crypto.SHA512
+	default:
+		return "", errors.Errorf(`unsupported signature algorithm %s`, alg)
+	}
+
+	hh := h.New()
+	hh.Write([]byte(value))
+	sum := hh.Sum(nil)
+	return base64.EncodeToString(sum[:len(sum)/2]), nil
+}
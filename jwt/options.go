@@ -1,6 +1,9 @@
 package jwt
 
 import (
+	"context"
+	"net/http"
+	"reflect"
 	"time"
 
 	"github.com/lestrrat-go/jwx/jwa"
@@ -11,19 +14,73 @@ import (
 
 type Option = option.Interface
 
+type identAcceptableAlgorithms struct{}
 type identAcceptableSkew struct{}
 type identAudience struct{}
+type identAudienceAll struct{}
 type identClaim struct{}
+type identClaimOrder struct{}
 type identClock struct{}
+type identCompressPayload struct{}
+type identConfirmation struct{}
+type identContext struct{}
+type identCookie struct{}
+type identCookieKey struct{}
+type identDecompressPayload struct{}
+type identDecrypt struct{}
 type identDefault struct{}
+type identFormKey struct{}
+type identHeaderKey struct{}
 type identHeaders struct{}
 type identIssuer struct{}
+type identIssuerKeys struct{}
+type identJtiStore struct{}
 type identJwtid struct{}
+type identKeyBinding struct{}
+type identKeyProvider struct{}
 type identKeySet struct{}
+type identMaxDelta struct{}
+type identMaxTokenSize struct{}
+type identMetrics struct{}
+type identMultipleErrors struct{}
+type identNumericDateFormatPrecision struct{}
+type identNumericDateParsePrecision struct{}
+type identNumericDateRejectFractional struct{}
+type identProfile struct{}
+type identProhibitedClaim struct{}
+type identProhibitedClaimValue struct{}
+type identProtectedClaims struct{}
+type identQueryKey struct{}
+type identRejectDuplicateClaims struct{}
+type identRequiredClaim struct{}
+type identSkewFor struct{}
+type identStrictClaims struct{}
 type identSubject struct{}
 type identToken struct{}
+type identTokenType struct{}
+type identTruncatedTimeComparison struct{}
+type identTypedClaim struct{}
 type identValidate struct{}
+type identValidationCache struct{}
+type identValidator struct{}
 type identVerify struct{}
+type identVerifyAuto struct{}
+
+// GlobalOption describes an Option that can be passed to `jwt.Settings()`
+type GlobalOption interface {
+	Option
+	globalOption()
+}
+
+type globalOption struct {
+	Option
+}
+
+func (*globalOption) globalOption() {}
+
+func newGlobalOption(n interface{}, v interface{}) GlobalOption {
+	return &globalOption{option.New(n, v)}
+}
 
 type parseOption struct {
 	Option
@@ -90,6 +147,25 @@ func WithVerify(alg jwa.SignatureAlgorithm, key interface{}) ParseOption {
 	})
 }
 
+type decryptParams struct {
+	alg jwa.KeyEncryptionAlgorithm
+	key interface{}
+}
+
+// WithDecrypt specifies that the token is encrypted in a JWE message,
+// which the Parse method decrypts using the given key before parsing
+// the payload. If the payload is a nested JWT (e.g. one created by
+// `jwt.Serializer`), it is then verified using the verification options
+// such as `jwt.WithVerify()` or `jwt.WithKeySet()`.
+//
+// Tokens that are not encrypted are rejected when this option is given.
+func WithDecrypt(alg jwa.KeyEncryptionAlgorithm, key interface{}) ParseOption {
+	return newParseOption(identDecrypt{}, &decryptParams{
+		alg: alg,
+		key: key,
+	})
+}
+
 // WithKeySet forces the Parse method to verify the JWT message
 // using one of the keys in the given key set. The key to be used
 // is chosen by matching the Key ID of the JWT and the ID of the
@@ -98,6 +174,32 @@ func WithKeySet(set jwk.Set) ParseOption {
 	return newParseOption(identKeySet{}, set)
 }
 
+// WithIssuerKeys forces the Parse method to verify the JWT message
+// using the keys of its issuer. The policy whose issuer matches the
+// "iss" claim of the token is selected, and the key is chosen from its
+// key set by matching the Key ID, as is done for `jwt.WithKeySet()`.
+// The key and algorithm must also satisfy the restrictions of the policy.
+// Tokens from issuers that do not have a policy are rejected.
+//
+// This option may be specified multiple times. `jwt.UseDefaultKey()`
+// applies to the key set of each issuer.
+func WithIssuerKeys(policies ...*IssuerKeyPolicy) ParseOption {
+	return newParseOption(identIssuerKeys{}, policies)
+}
+
+// WithVerifyAuto forces the Parse method to verify the JWT message using
+// the keys that are discovered from its issuer, as described in
+// `jwt.KeyDiscovery`. Tokens from issuers that are not trusted by the
+// KeyDiscovery are rejected before any request is made. The key is chosen
+// from the key set of the issuer by matching the Key ID, as is done for
+// `jwt.WithKeySet()`.
+//
+// Use `jwt.WithContext()` or `jwt.ParseContext()` to control the requests
+// that are made to the issuer.
+func WithVerifyAuto(d *KeyDiscovery) ParseOption {
+	return newParseOption(identVerifyAuto{}, d)
+}
+
 // UseDefaultKey is used in conjunction with the option WithKeySet
 // to instruct the Parse method to default to the single key in a key
 // set when no Key ID is included in the JWT. If the key set contains
@@ -113,11 +215,162 @@ func WithToken(t Token) ParseOption {
 }
 
 // WithHeaders is passed to `Sign()` method, to allow specifying arbitrary
-// header values to be included in the header section of the jws message
+// header values to be included in the header section of the jws message,
+// such as `typ` (e.g. "at+jwt" or "dpop+jwt"), `cty`, `x5t`, or private
+// header parameters. The given headers are not modified by `Sign()`.
 func WithHeaders(hdrs jws.Headers) ParseOption {
 	return newParseOption(identHeaders{}, hdrs)
 }
 
+// WithTokenType specifies the value of the `typ` header.
+//
+// When passed to `Sign()`, the given value is used in place of the
+// default value `JWT`.
+//
+// When passed to `Parse()`, the `typ` header of the JWS message must match
+// the given value, otherwise an error is returned. As described in RFC7515,
+// the comparison is case insensitive, and the "application/" prefix may
+// be omitted.
+func WithTokenType(typ string) ParseOption {
+	return newParseOption(identTokenType{}, typ)
+}
+
+// WithAcceptableAlgorithms is passed to `Parse()` to restrict the
+// signature algorithms that the token may be signed with. The "alg"
+// header of the token, as well as the algorithm used for verification
+// (if any), must be one of the given algorithms.
+func WithAcceptableAlgorithms(algs ...jwa.SignatureAlgorithm) ParseOption {
+	return newParseOption(identAcceptableAlgorithms{}, append([]jwa.SignatureAlgorithm(nil), algs...))
+}
+
+// WithClaimOrder is passed to `jwt.Sign()` and `jwt.Marshal()` to
+// specify the order of the claims in the JSON representation of the
+// token. See `jwt.ClaimOrder` for the available orders.
+func WithClaimOrder(o ClaimOrder) Option {
+	return option.New(identClaimOrder{}, o)
+}
+
+// WithCompressPayload is passed to `Sign()` to compress the JSON
+// representation of the claims using gzip before signing it. This
+// is useful for internal tokens that carry large claim sets, but note
+// that the resulting tokens are NOT standard JWTs: the compression is
+// recorded in the private `jwx-zip` header, and only parsers that
+// explicitly allow it via `jwt.WithDecompressPayload()` can read them.
+func WithCompressPayload(b bool) Option {
+	return option.New(identCompressPayload{}, b)
+}
+
+// WithDecompressPayload is passed to `Parse()` to allow tokens
+// created using the `jwt.WithCompressPayload()` option. `maxSize` is the
+// maximum size of the decompressed claims in bytes: tokens that inflate
+// to a larger size are rejected.
+//
+// Uncompressed tokens are parsed as usual even when this option is specified.
+func WithDecompressPayload(maxSize int64) ParseOption {
+	return newParseOption(identDecompressPayload{}, maxSize)
+}
+
+// WithMaxTokenSize specifies the maximum size in bytes of the serialized
+// token passed to `jwt.Parse()` and its variants. Larger tokens are
+// rejected before they are parsed.
+//
+// `jwt.ParseReader()` stops reading from its source as soon as the limit
+// is exceeded. There is no limit by default: pass a value <= 0 to remove
+// a limit specified by an earlier option.
+func WithMaxTokenSize(n int64) ParseOption {
+	return newParseOption(identMaxTokenSize{}, n)
+}
+
+// WithRejectDuplicateClaims is passed to `Parse()` to reject tokens
+// whose claim set contains the same member name more than once, at any
+// level. By default such tokens are accepted, and the last value is used.
+// As other JWT implementations may use the first value instead, a token
+// with duplicate claims can be interpreted differently by different
+// parties, which can be used to smuggle claim values past a validator.
+func WithRejectDuplicateClaims(b bool) ParseOption {
+	return newParseOption(identRejectDuplicateClaims{}, b)
+}
+
+// WithValidationCache is passed to `Parse()` to skip the verification
+// of signatures that have already been verified using the same key.
+// See `jwt.ValidationCache` for details.
+func WithValidationCache(cache *ValidationCache) ParseOption {
+	return newParseOption(identValidationCache{}, cache)
+}
+
+// WithMetrics specifies the recorder that receives measurements
+// from `jwt.Parse()`, such as the time it took to parse the token and
+// the outcome of the verification. See `jwt.MetricsRecorder` for details.
+func WithMetrics(r MetricsRecorder) ParseOption {
+	return newParseOption(identMetrics{}, r)
+}
+
+// WithHeaderKey is passed to `jwt.ParseRequest()` to look for the token
+// in the HTTP header `key`. The "Authorization" header must use the
+// "Bearer" scheme, while other headers must contain the token as is.
+//
+// This option may be specified multiple times.
+func WithHeaderKey(key string) ParseOption {
+	return newParseOption(identHeaderKey{}, key)
+}
+
+// WithFormKey is passed to `jwt.ParseRequest()` to look for the token
+// in the form field `key` of the request body.
+//
+// This option may be specified multiple times.
+func WithFormKey(key string) ParseOption {
+	return newParseOption(identFormKey{}, key)
+}
+
+// WithQueryKey is passed to `jwt.ParseRequest()` to look for the token
+// in the query parameter `key` of the request URL.
+//
+// This option may be specified multiple times.
+func WithQueryKey(key string) ParseOption {
+	return newParseOption(identQueryKey{}, key)
+}
+
+// WithCookieKey is passed to `jwt.ParseRequest()` to look for the token
+// in the cookie named `key`.
+//
+// This option may be specified multiple times.
+func WithCookieKey(key string) ParseOption {
+	return newParseOption(identCookieKey{}, key)
+}
+
+// WithCookie specifies the location to store the cookie that the token
+// was found in, when using `jwt.ParseRequest()` or `jwt.ParseCookie()`.
+// `*dst` is left untouched if the token was not found in a cookie.
+//
+// The cookie is stored even if parsing the token fails, so that the
+// caller can clear an invalid cookie.
+func WithCookie(dst **http.Cookie) ParseOption {
+	return newParseOption(identCookie{}, dst)
+}
+
+// WithTypedClaim specifies that the private claim `name` should be
+// decoded into a value of the same type as `object`, instead of the
+// generic types used by default (e.g. map[string]interface{} for
+// JSON objects).
+//
+//     tok, err := jwt.Parse(data, jwt.WithTypedClaim("cart", Cart{}))
+//     v, _ := tok.Get("cart")
+//     cart := v.(Cart)
+//
+// `object` is only used to determine the type, so a zero value may be
+// given. Pass a pointer (e.g. `&Cart{}`) to receive a pointer instead.
+// Only private claims can be decoded this way: parsing fails if `name`
+// is one of the registered claim names of RFC 7519, such as "aud" or
+// "exp", even if the value would be compatible with the given type.
+//
+// This option may be specified multiple times.
+func WithTypedClaim(name string, object interface{}) ParseOption {
+	return newParseOption(identTypedClaim{}, typedClaim{
+		name: name,
+		typ:  reflect.TypeOf(object),
+	})
+}
+
 // WithValidate is passed to `Parse()` method to denote that the
 // validation of the JWT token should be performed after a successful]
 // parsing of the incoming payload.
@@ -131,12 +384,52 @@ func WithClock(c Clock) ValidateOption {
 	return newValidateOption(identClock{}, c)
 }
 
+// WithTruncatedTimeComparison specifies whether `jwt.Validate()` truncates
+// times to seconds before comparing them. This applies to the current time
+// and the "exp", "iat", and "nbf" claims, as well as to time.Time values
+// given via `jwt.WithClaimValue()`.
+//
+// By default (true), times are compared at the resolution of NumericDate,
+// which is what the claims are serialized as. This makes the outcome the
+// same regardless of whether the token was validated before or after
+// being serialized: a token minted and validated within the same second
+// would otherwise be compared against sub-second values that are lost
+// once the token is signed.
+//
+// Specify false to compare times at full precision, e.g. when the
+// claims were set to times that are not truncated and never serialized.
+func WithTruncatedTimeComparison(b bool) ValidateOption {
+	return newValidateOption(identTruncatedTimeComparison{}, b)
+}
+
 // WithAcceptableSkew specifies the duration in which exp and nbf
 // claims may differ by. This value should be positive
 func WithAcceptableSkew(dur time.Duration) ValidateOption {
 	return newValidateOption(identAcceptableSkew{}, dur)
 }
 
+// WithSkewFor specifies the acceptable skew for a single time based
+// claim, which must be one of `jwt.ExpirationKey`, `jwt.NotBeforeKey`
+// or `jwt.IssuedAtKey`; other claims are ignored. For example, the
+// following allows a generous drift in "nbf" while keeping a strict
+// expiration window:
+//
+//     jwt.Validate(token,
+//       jwt.WithSkewFor(jwt.NotBeforeKey, 5*time.Minute),
+//       jwt.WithSkewFor(jwt.ExpirationKey, 30*time.Second),
+//     )
+//
+// The skew given for a claim takes precedence over `jwt.WithAcceptableSkew()`,
+// which still applies to the claims that are not specified.
+func WithSkewFor(claim string, dur time.Duration) ValidateOption {
+	return newValidateOption(identSkewFor{}, claimSkew{claim: claim, skew: dur})
+}
+
+type claimSkew struct {
+	claim string
+	skew  time.Duration
+}
+
 // WithIssuer specifies that expected issuer value. If not specified,
 // the value of issuer is not verified at all.
 func WithIssuer(s string) ValidateOption {
@@ -163,6 +456,42 @@ func WithAudience(s string) ValidateOption {
 	return newValidateOption(identAudience{}, s)
 }
 
+// WithAudienceAll specifies audience values that must ALL be present
+// in the `aud` claim, as required by some federation policies. The
+// token may contain other values as well.
+//
+// This option may be specified multiple times, and may be combined with
+// `jwt.WithAudience()`.
+func WithAudienceAll(values []string) ValidateOption {
+	return newValidateOption(identAudienceAll{}, values)
+}
+
+type delta struct {
+	max time.Duration
+	c1  string
+	c2  string
+}
+
+// WithMaxDelta specifies that the difference between two time based
+// claims, `c1` - `c2`, must be less than or equal to `max`. An empty
+// claim name denotes the current time, as reported by the `Clock`. For
+// example, the following rejects tokens that were issued more than an
+// hour ago, even if they have not expired yet:
+//
+//     jwt.WithMaxDelta(time.Hour, "", jwt.IssuedAtKey)
+//
+// and the following rejects tokens that are valid for more than a day:
+//
+//     jwt.WithMaxDelta(24*time.Hour, jwt.ExpirationKey, jwt.IssuedAtKey)
+//
+// Both claims must be present. Private claims may hold a NumericDate.
+// The acceptable skew is not applied.
+//
+// This option may be specified multiple times.
+func WithMaxDelta(max time.Duration, c1, c2 string) ValidateOption {
+	return newValidateOption(identMaxDelta{}, delta{max: max, c1: c1, c2: c2})
+}
+
 type claimValue struct {
 	name  string
 	value interface{}
@@ -172,3 +501,170 @@ type claimValue struct {
 func WithClaimValue(name string, v interface{}) ValidateOption {
 	return newValidateOption(identClaim{}, claimValue{name, v})
 }
+
+// WithRequiredClaim specifies the name of a claim that must be present
+// in the token, regardless of its value. Use `jwt.WithClaimValue()` if
+// the value is also known.
+//
+// This option may be specified multiple times.
+func WithRequiredClaim(name string) ValidateOption {
+	return newValidateOption(identRequiredClaim{}, name)
+}
+
+// WithProhibitedClaims specifies the names of claims that must NOT be
+// present in the token. This is useful to catch misconfigured upstream
+// issuers that leak sensitive information (e.g. "password") into tokens.
+func WithProhibitedClaims(names ...string) ValidateOption {
+	return newValidateOption(identProhibitedClaim{}, names)
+}
+
+// WithProhibitedClaimValue specifies that the claim `name`, if present,
+// must NOT carry the value `v`. Values are compared using `reflect.DeepEqual`.
+// If the claim holds a list of values (e.g. "aud"), validation fails if
+// any of the elements matches `v`.
+//
+//     // Reject tokens that were issued for the staging environment
+//     jwt.Validate(token,
+//       jwt.WithProhibitedClaimValue(jwt.AudienceKey, "https://staging.example.com"),
+//     )
+//
+// Only claims in the payload are checked. JOSE header parameters such
+// as "alg" are not claims: use `jwt.WithAcceptableAlgorithms()` to
+// restrict the signature algorithms instead.
+//
+// This option may be specified multiple times for the same claim.
+func WithProhibitedClaimValue(name string, v interface{}) ValidateOption {
+	return newValidateOption(identProhibitedClaimValue{}, claimValue{name, v})
+}
+
+// WithKeyBinding specifies that the token must be bound to one of the
+// given keys of the presenter (proof-of-possession), as identified by the
+// "cnf" claim (RFC 7800). See `jwt.BindToKey()`
+//
+// Each key may be a raw key, a jwk.Key, or the *x509.Certificate
+// presented by the client for mutual TLS (RFC 8705). A key must match
+// the "jwk" and "jkt" members of the claim, and a certificate must match
+// the "x5t#S256" member, if they are present. At least one of the members
+// must be checked for each key, otherwise the key does not match.
+//
+// When more than one key is given, the token must be bound to any one of
+// them. To require the token to be bound to several keys, e.g. both the
+// DPoP key and the client certificate of the presenter, specify this
+// option once for each key: each of the options must be satisfied.
+// If no keys are given, validation always fails.
+//
+// Note that this only checks the binding: the presenter must prove the
+// possession of the keys separately, e.g. using a DPoP proof or mutual
+// TLS.
+func WithKeyBinding(keys ...interface{}) ValidateOption {
+	return newValidateOption(identKeyBinding{}, append([]interface{}(nil), keys...))
+}
+
+// WithPoP specifies that the token must be bound to the given key of the
+// presenter (proof-of-possession), as identified by the "cnf" claim
+// (RFC 7800). It is the same as `jwt.WithKeyBinding(key)`.
+//
+// `key` may be a raw key, a jwk.Key, or the *x509.Certificate presented
+// by the client for mutual TLS (RFC 8705).
+func WithPoP(key interface{}) ValidateOption {
+	return WithKeyBinding(key)
+}
+
+// withKeyProvider makes `jwt.Sign()` obtain the signing key from the
+// provider via `jws.WithKeyProviderForSigning()`
+func withKeyProvider(p jws.SigningKeyProvider) Option {
+	return option.New(identKeyProvider{}, p)
+}
+
+// WithProtectedClaims specifies that the token must carry a
+// "claims_digest" claim signed using `key`, and that the claims it
+// protects must not have been modified. See `jwt.ProtectClaims()`
+//
+// This option may be specified multiple times, e.g. when more than one
+// issuer in a token exchange chain protected their claims: the claims
+// protected by each of the keys are checked.
+func WithProtectedClaims(alg jwa.SignatureAlgorithm, key interface{}) ValidateOption {
+	return newValidateOption(identProtectedClaims{}, protectedClaimsKey{alg: alg, key: key})
+}
+
+// WithStrictClaims enables strict validation of the registered claims,
+// as required by some conformance test suites:
+//
+// "iss", "sub", and each value of "aud" must be a StringOrURI, i.e.
+// values that contain a ":" must be absolute URIs. "exp", "iat", and "nbf"
+// must be within the range from 1970-01-01T00:00:00Z to
+// 9999-12-31T23:59:59Z.
+func WithStrictClaims(b bool) ValidateOption {
+	return newValidateOption(identStrictClaims{}, b)
+}
+
+// WithMultipleErrors specifies whether `jwt.Validate()` checks all of
+// the constraints, instead of stopping at the first failure. When true,
+// the failures are returned as `jwt.ValidationErrors`, so that clients
+// can be told about every problem with the token at once.
+func WithMultipleErrors(b bool) ValidateOption {
+	return newValidateOption(identMultipleErrors{}, b)
+}
+
+// WithJtiStore specifies a store that records the "jti" claims of the
+// tokens accepted by `jwt.Validate()`, in order to reject tokens that
+// are replayed. Tokens without a "jti" claim are rejected.
+//
+// The "jti" claim is recorded only if all other checks have passed, so
+// that invalid (e.g. expired) tokens do not fill up the store. If the
+// store reports an error, validation fails.
+func WithJtiStore(store JtiStore) ValidateOption {
+	return newValidateOption(identJtiStore{}, store)
+}
+
+// WithContext specifies the context that is passed to the stores used
+// during validation, such as the one given by `jwt.WithJtiStore()`, and
+// to validators that implement `jwt.ContextValidator`. When passed to
+// `jwt.Parse()`, parsing stops as soon as the context is done.
+// If not specified, `context.Background()` is used.
+//
+// See also `jwt.ParseContext()` and `jwt.ValidateContext()`.
+func WithContext(ctx context.Context) ValidateOption {
+	return newValidateOption(identContext{}, ctx)
+}
+
+// WithValidator specifies an additional check to be performed by
+// `jwt.Validate()`, after all of the standard checks have passed.
+// The error returned by the validator is returned as is.
+//
+// This option may be specified multiple times: validators are called
+// in the order that they were given.
+func WithValidator(v Validator) ValidateOption {
+	return newValidateOption(identValidator{}, v)
+}
+
+// WithNumericDateParsePrecision specifies the number of digits after the
+// decimal point that are retained when the "exp", "iat" and "nbf" claims
+// (and other NumericDate claims) are parsed, from 0 (seconds, the default)
+// to 9 (nanoseconds). The remaining digits are truncated.
+//
+// Pass it to `jwt.Settings()`.
+func WithNumericDateParsePrecision(v int) GlobalOption {
+	return newGlobalOption(identNumericDateParsePrecision{}, v)
+}
+
+// WithNumericDateFormatPrecision specifies the number of digits after the
+// decimal point that are written when NumericDate claims are serialized,
+// from 0 (integer seconds, the default) to 9 (nanoseconds). For example,
+// 3 produces values such as 1600000000.123 for peers that require
+// millisecond precision.
+//
+// Pass it to `jwt.Settings()`.
+func WithNumericDateFormatPrecision(v int) GlobalOption {
+	return newGlobalOption(identNumericDateFormatPrecision{}, v)
+}
+
+// WithNumericDateRejectFractional specifies that NumericDate claims whose
+// values are not integers (e.g. 1600000000.5) must be rejected when they
+// are parsed. By default such values are accepted, and truncated to the
+// precision given by `jwt.WithNumericDateParsePrecision()`.
+//
+// Pass it to `jwt.Settings()`.
+func WithNumericDateRejectFractional(v bool) GlobalOption {
+	return newGlobalOption(identNumericDateRejectFractional{}, v)
+}
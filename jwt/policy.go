@@ -0,0 +1,101 @@
+package jwt
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/jwa"
+)
+
+// ValidationPolicy is a named set of options for `jwt.Validate()`.
+// Use it along with `jwt.ValidateAll()` when a token may be accepted
+// under several alternative policies (e.g. user tokens vs service tokens)
+type ValidationPolicy struct {
+	name       string
+	version    string
+	algorithms []jwa.SignatureAlgorithm
+	options    []ValidateOption
+}
+
+// NewValidationPolicy creates a new ValidationPolicy.
+func NewValidationPolicy(name string, options ...ValidateOption) *ValidationPolicy {
+	return &ValidationPolicy{
+		name:    name,
+		options: append([]ValidateOption(nil), options...),
+	}
+}
+
+// Name returns the name of the policy
+func (p *ValidationPolicy) Name() string {
+	return p.name
+}
+
+// Options returns a copy of the options of the policy
+func (p *ValidationPolicy) Options() []ValidateOption {
+	return append([]ValidateOption(nil), p.options...)
+}
+
+// Version returns the version of the policy document that the policy
+// was compiled from (see `jwt.CompilePolicy()`), or an empty string
+func (p *ValidationPolicy) Version() string {
+	return p.version
+}
+
+// ParseOptions returns the options to pass to `jwt.Parse()` in order to
+// validate the token using the policy. In addition to the options of
+// the policy, these include `jwt.WithValidate(true)` and, for policies
+// that restrict the signature algorithms, `jwt.WithAcceptableAlgorithms()`.
+//
+// Note that the options do not specify the verification key.
+func (p *ValidationPolicy) ParseOptions() []ParseOption {
+	options := make([]ParseOption, 0, len(p.options)+2)
+	if len(p.algorithms) > 0 {
+		options = append(options, WithAcceptableAlgorithms(p.algorithms...))
+	}
+	options = append(options, WithValidate(true))
+	for _, o := range p.options {
+		options = append(options, o)
+	}
+	return options
+}
+
+// Validate validates the token using the options of the policy.
+//
+// The restriction of the signature algorithms can not be checked
+// against a token that has already been parsed, so policies that
+// restrict the algorithms (see `jwt.CompilePolicy()`) always fail here.
+// Use `ParseOptions()` to validate tokens using such policies.
+func (p *ValidationPolicy) Validate(t Token) error {
+	if len(p.algorithms) > 0 {
+		return errors.New(`policy restricts the signature algorithms, which can only be enforced when parsing (see ParseOptions)`)
+	}
+	return Validate(t, p.options...)
+}
+
+// ValidateAll validates an already parsed token against each of the
+// given policies, and returns the names of the policies that the token
+// satisfies, in the order that the policies were given.
+//
+// If the token does not satisfy any of the policies, an error that
+// describes why each policy failed is returned. As with `Validate()`,
+// policies that restrict the signature algorithms are never satisfied.
+func ValidateAll(t Token, policies ...*ValidationPolicy) ([]string, error) {
+	if len(policies) == 0 {
+		return nil, errors.New(`no validation policies specified`)
+	}
+
+	var passed []string
+	var failures []string
+	for _, p := range policies {
+		if err := p.Validate(t); err != nil {
+			failures = append(failures, p.name+": "+err.Error())
+			continue
+		}
+		passed = append(passed, p.name)
+	}
+
+	if len(passed) == 0 {
+		return nil, errors.New(`token does not satisfy any policy (` + strings.Join(failures, `; `) + `)`)
+	}
+	return passed, nil
+}
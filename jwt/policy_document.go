@@ -0,0 +1,163 @@
+package jwt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/pkg/errors"
+)
+
+// PolicySchemaVersion is the version of the policy document format
+// understood by `jwt.CompilePolicy()`
+const PolicySchemaVersion = 1
+
+// PolicyDocument is the serializable form of a validation policy, which
+// allows policies to be managed as configuration. In JSON it looks like
+//
+//	{
+//	  "schema": 1,
+//	  "name": "partner-api",
+//	  "version": "2021-06-01.2",
+//	  "issuers": ["https://accounts.example.com"],
+//	  "audiences": ["partner-api"],
+//	  "algorithms": ["RS256", "ES256"],
+//	  "required_claims": ["sub", "exp"],
+//	  "acceptable_skew": "30s"
+//	}
+//
+// "schema" is the version of the document format, and must be equal to
+// `jwt.PolicySchemaVersion`. "version" is an arbitrary revision chosen by
+// the operator, which is available via `(*jwt.ValidationPolicy).Version()`
+// so that the active revision can be logged while a change is rolled out.
+//
+// The struct also carries yaml tags, so documents written in YAML can be
+// decoded into it and passed to `(*jwt.PolicyDocument).Compile()`.
+type PolicyDocument struct {
+	Schema         int                      `json:"schema" yaml:"schema"`
+	Name           string                   `json:"name" yaml:"name"`
+	Version        string                   `json:"version,omitempty" yaml:"version,omitempty"`
+	Issuers        []string                 `json:"issuers,omitempty" yaml:"issuers,omitempty"`
+	Audiences      []string                 `json:"audiences,omitempty" yaml:"audiences,omitempty"`
+	Algorithms     []jwa.SignatureAlgorithm `json:"algorithms,omitempty" yaml:"algorithms,omitempty"`
+	RequiredClaims []string                 `json:"required_claims,omitempty" yaml:"required_claims,omitempty"`
+	AcceptableSkew string                   `json:"acceptable_skew,omitempty" yaml:"acceptable_skew,omitempty"`
+}
+
+// CompilePolicy parses a policy document in JSON format (see
+// `jwt.PolicyDocument`), and creates the corresponding ValidationPolicy.
+// Only JSON is supported: to use documents written in YAML, decode them
+// into a `jwt.PolicyDocument` using a YAML library, and call its
+// `Compile()` method.
+//
+// If the document restricts the signature algorithms, the policy can
+// only be enforced using the options returned by its `ParseOptions()`
+// method: its `Validate()` method and `jwt.ValidateAll()` fail, as the
+// algorithm of a token that has already been parsed is not known.
+//
+// Unknown fields are rejected rather than ignored, so that a document
+// written for a newer version of the format is not silently applied
+// with some of its restrictions missing.
+func CompilePolicy(doc []byte) (*ValidationPolicy, error) {
+	var pd PolicyDocument
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&pd); err != nil {
+		return nil, errors.Wrap(err, `failed to parse policy document`)
+	}
+	return pd.Compile()
+}
+
+// Compile creates the ValidationPolicy described by the document.
+//
+// Tokens that satisfy the policy must have an "iss" claim that matches
+// one of the issuers, and an "aud" claim that contains one of the
+// audiences (if specified), as well as all of the required claims.
+// The "exp", "iat", and "nbf" claims are checked as usual, allowing
+// for the given skew.
+func (pd *PolicyDocument) Compile() (*ValidationPolicy, error) {
+	if pd.Schema != PolicySchemaVersion {
+		return nil, errors.Errorf(`unsupported policy schema version %d (expected %d)`, pd.Schema, PolicySchemaVersion)
+	}
+	if pd.Name == "" {
+		return nil, errors.New(`policy name must be specified`)
+	}
+
+	var options []ValidateOption
+	if pd.AcceptableSkew != "" {
+		skew, err := time.ParseDuration(pd.AcceptableSkew)
+		if err != nil {
+			return nil, errors.Wrap(err, `invalid acceptable_skew`)
+		}
+		if skew < 0 {
+			return nil, errors.New(`acceptable_skew must not be negative`)
+		}
+		options = append(options, WithAcceptableSkew(skew))
+	}
+
+	if len(pd.Issuers) > 0 {
+		options = append(options, WithValidator(&oneOfValidator{
+			claim:  IssuerKey,
+			values: append([]string(nil), pd.Issuers...),
+		}))
+	}
+	if len(pd.Audiences) > 0 {
+		options = append(options, WithValidator(&oneOfValidator{
+			claim:  AudienceKey,
+			values: append([]string(nil), pd.Audiences...),
+		}))
+	}
+	for _, name := range pd.RequiredClaims {
+		options = append(options, WithRequiredClaim(name))
+	}
+
+	algorithms := make([]jwa.SignatureAlgorithm, len(pd.Algorithms))
+	for i, alg := range pd.Algorithms {
+		if err := algorithms[i].Accept(alg.String()); err != nil {
+			return nil, errors.Wrap(err, `invalid algorithm`)
+		}
+		if algorithms[i] == jwa.NoSignature {
+			return nil, errors.Errorf(`algorithm %q cannot be allowed by a policy`, jwa.NoSignature)
+		}
+	}
+
+	p := NewValidationPolicy(pd.Name, options...)
+	p.version = pd.Version
+	p.algorithms = algorithms
+	return p, nil
+}
+
+// oneOfValidator requires that the claim (iss or aud) is present and
+// that (one of) its value(s) is one of the given values
+type oneOfValidator struct {
+	claim  string
+	values []string
+}
+
+func (v *oneOfValidator) Validate(t Token) error {
+	var actual []string
+	switch v.claim {
+	case IssuerKey:
+		if iss := t.Issuer(); iss != "" {
+			actual = []string{iss}
+		}
+	case AudienceKey:
+		actual = t.Audience()
+	}
+
+	for _, a := range actual {
+		for _, expected := range v.values {
+			if a == expected {
+				return nil
+			}
+		}
+	}
+	category := ErrInvalidIssuer
+	if v.claim == AudienceKey {
+		category = ErrInvalidAudience
+	}
+	return newValidationError(category, v.claim, fmt.Sprintf(`%s not satisfied: expected one of %s`, v.claim, strings.Join(v.values, ", ")), nil)
+}
@@ -0,0 +1,224 @@
+package jwt
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/internal/keyconv"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/option"
+	"github.com/pkg/errors"
+)
+
+// Confirmation represents the "cnf" (confirmation) claim, which
+// identifies the proof-of-possession key of the presenter of the token,
+// as described in RFC 7800. Only the members that are set are included.
+type Confirmation struct {
+	// Key is the public key of the presenter ("jwk")
+	Key jwk.Key
+	// KeyThumbprint is the base64url encoded SHA-256 JWK thumbprint of
+	// the key of the presenter ("jkt", RFC 9449)
+	KeyThumbprint string
+	// CertificateThumbprint is the base64url encoded SHA-256 hash of the
+	// DER encoded client certificate ("x5t#S256", RFC 8705)
+	CertificateThumbprint string
+	// KeyID is the identifier of the key of the presenter ("kid")
+	KeyID string
+	// JWKSetURL is the URL of the JWK set containing the key of the
+	// presenter ("jku")
+	JWKSetURL string
+}
+
+// MarshalJSON serializes the confirmation as a JSON object
+func (c Confirmation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.asMap())
+}
+
+// UnmarshalJSON deserializes the confirmation from a JSON object.
+// Unknown members are ignored.
+func (c *Confirmation) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return errors.Wrapf(err, `failed to unmarshal %q claim`, ConfirmationKey)
+	}
+
+	var dst Confirmation
+	if v, ok := raw[ConfirmationJWKKey]; ok {
+		key, err := jwk.ParseKey(v)
+		if err != nil {
+			return errors.Wrapf(err, `failed to parse %q member`, ConfirmationJWKKey)
+		}
+		dst.Key = key
+	}
+	for name, ptr := range map[string]*string{
+		ConfirmationJWKSHA256Key:  &dst.KeyThumbprint,
+		ConfirmationX509SHA256Key: &dst.CertificateThumbprint,
+		ConfirmationKeyIDKey:      &dst.KeyID,
+		ConfirmationJWKSetURLKey:  &dst.JWKSetURL,
+	} {
+		v, ok := raw[name]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(v, ptr); err != nil {
+			return errors.Wrapf(err, `failed to unmarshal %q member`, name)
+		}
+	}
+	*c = dst
+	return nil
+}
+
+func (c *Confirmation) asMap() map[string]interface{} {
+	m := make(map[string]interface{})
+	if c.Key != nil {
+		m[ConfirmationJWKKey] = c.Key
+	}
+	for name, v := range map[string]string{
+		ConfirmationJWKSHA256Key:  c.KeyThumbprint,
+		ConfirmationX509SHA256Key: c.CertificateThumbprint,
+		ConfirmationKeyIDKey:      c.KeyID,
+		ConfirmationJWKSetURLKey:  c.JWKSetURL,
+	} {
+		if v != "" {
+			m[name] = v
+		}
+	}
+	return m
+}
+
+// LookupConfirmation returns the "cnf" claim of the token. An error is
+// returned if the token does not have a "cnf" claim, or if it is not a
+// JSON object.
+func LookupConfirmation(t Token) (*Confirmation, error) {
+	v, ok := t.Get(ConfirmationKey)
+	if !ok {
+		return nil, errors.Errorf(`%q claim not found`, ConfirmationKey)
+	}
+
+	switch v := v.(type) {
+	case *Confirmation:
+		return v, nil
+	case Confirmation:
+		return &v, nil
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to marshal %q claim`, ConfirmationKey)
+	}
+	var c Confirmation
+	if err := json.Unmarshal(buf, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// WithConfirmationKey is passed to `jwt.Sign()` to bind the token to the
+// key of the presenter, by embedding the public key in the "jwk" member
+// of the "cnf" claim. `key` may be a raw key or a jwk.Key. If a private
+// key is given, only the corresponding public key is embedded.
+//
+// The token itself is not modified. Other members of an existing "cnf"
+// claim are preserved.
+func WithConfirmationKey(key interface{}) Option {
+	return option.New(identConfirmation{}, confirmationKey{key})
+}
+
+// WithConfirmationThumbprint is passed to `jwt.Sign()` to bind the token
+// to the key of the presenter, by setting the "jkt" member of the "cnf"
+// claim to the SHA-256 JWK thumbprint of the key. See `jwt.BindToKey()`.
+//
+// The token itself is not modified. Other members of an existing "cnf"
+// claim are preserved.
+func WithConfirmationThumbprint(key interface{}) Option {
+	return option.New(identConfirmation{}, confirmationThumbprint{key})
+}
+
+// WithConfirmationCertificate is passed to `jwt.Sign()` to bind the token
+// to the client certificate used for mutual TLS, by setting the
+// "x5t#S256" member of the "cnf" claim (RFC 8705).
+//
+// The token itself is not modified. Other members of an existing "cnf"
+// claim are preserved.
+func WithConfirmationCertificate(cert *x509.Certificate) Option {
+	return option.New(identConfirmation{}, confirmationCertificate{cert})
+}
+
+type confirmationKey struct{ key interface{} }
+type confirmationThumbprint struct{ key interface{} }
+type confirmationCertificate struct{ cert *x509.Certificate }
+
+// applyConfirmations returns a copy of the token, with the members given
+// by the `jwt.WithConfirmationXXX()` options added to its "cnf" claim
+func applyConfirmations(t Token, values []interface{}) (Token, error) {
+	cnf, err := confirmationMap(t)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range values {
+		switch v := v.(type) {
+		case confirmationKey:
+			key, err := keyconv.PublicJWK(v.key)
+			if err != nil {
+				return nil, errors.Wrapf(err, `invalid key for %q member of %q claim`, ConfirmationJWKKey, ConfirmationKey)
+			}
+			cnf[ConfirmationJWKKey] = key
+		case confirmationThumbprint:
+			jkt, err := keyBindingThumbprint(v.key)
+			if err != nil {
+				return nil, errors.Wrap(err, `failed to compute thumbprint`)
+			}
+			cnf[ConfirmationJWKSHA256Key] = jkt
+		case confirmationCertificate:
+			if v.cert == nil {
+				return nil, errors.New(`certificate must not be nil`)
+			}
+			cnf[ConfirmationX509SHA256Key] = certificateThumbprint(v.cert)
+		}
+	}
+
+	clone, err := t.Clone()
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to clone token`)
+	}
+	if err := clone.Set(ConfirmationKey, cnf); err != nil {
+		return nil, errors.Wrapf(err, `failed to set %q claim`, ConfirmationKey)
+	}
+	return clone, nil
+}
+
+// confirmationMap returns a copy of the members of the "cnf" claim of the
+// token, or an empty map if the token does not have one
+func confirmationMap(t Token) (map[string]interface{}, error) {
+	v, ok := t.Get(ConfirmationKey)
+	if !ok {
+		return make(map[string]interface{}), nil
+	}
+
+	if existing, ok := v.(map[string]interface{}); ok {
+		cnf := make(map[string]interface{}, len(existing))
+		for k, v := range existing {
+			cnf[k] = v
+		}
+		return cnf, nil
+	}
+
+	c, err := LookupConfirmation(t)
+	if err != nil {
+		return nil, errors.Errorf(`invalid type for %q claim: %T`, ConfirmationKey, v)
+	}
+	return c.asMap(), nil
+}
+
+func certificateThumbprint(cert *x509.Certificate) string {
+	h := sha256.Sum256(cert.Raw)
+	return base64.EncodeToString(h[:])
+}
+
+func constantTimeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,105 @@
+package jwt
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ClientIDKey is the name of the "client_id" claim (RFC 8693 section 4.3),
+// which identifies the OAuth 2.0 client that the token was issued to
+const ClientIDKey = "client_id"
+
+// Profile is a prebuilt set of checks that implements a standard token
+// profile, such as `jwt.RFC9068AccessToken`. See `jwt.WithProfile()`.
+type Profile struct {
+	name            string
+	tokenType       string
+	requiredClaims  []string
+	requireAudience bool
+}
+
+// RFC9068AccessToken is the profile for OAuth 2.0 access tokens in the
+// JWT format (RFC 9068), for use by resource servers:
+//
+//   - The "typ" header must be "at+jwt" (or "application/at+jwt")
+//   - The token must be signed, and its signature must be verified
+//   - The "iss", "exp", "aud", "sub", "client_id", "iat" and "jti"
+//     claims are required
+//   - The expected audience, i.e. the identifier of the resource server,
+//     must be specified using `jwt.WithAudience()`
+//
+// The issuer should also be checked using `jwt.WithIssuer()`.
+var RFC9068AccessToken = &Profile{
+	name:      "RFC9068",
+	tokenType: TokenTypeAccessToken,
+	requiredClaims: []string{
+		IssuerKey,
+		ExpirationKey,
+		AudienceKey,
+		SubjectKey,
+		ClientIDKey,
+		IssuedAtKey,
+		JwtIDKey,
+	},
+	requireAudience: true,
+}
+
+// Name returns the name of the profile
+func (p *Profile) Name() string {
+	return p.name
+}
+
+// WithProfile specifies the profile that the token passed to `jwt.Parse()`
+// must conform to, e.g. `jwt.RFC9068AccessToken`. The token is validated
+// as if `jwt.WithValidate(true)` were specified, so other validation
+// options such as `jwt.WithIssuer()` can be given along with the profile.
+//
+// `jwt.Parse()` fails without looking at the token if the options do
+// not satisfy the requirements of the profile (e.g. no verification key
+// was specified).
+func WithProfile(p *Profile) ParseOption {
+	return newParseOption(identProfile{}, p)
+}
+
+// expandProfiles replaces the profiles in `options` with the options
+// that implement them
+func expandProfiles(options []ParseOption) ([]ParseOption, error) {
+	var profiles []*Profile
+	var verify, audience bool
+	for _, o := range options {
+		switch o.Ident() {
+		case identProfile{}:
+			profiles = append(profiles, o.Value().(*Profile))
+		case identVerify{}, identKeySet{}, identIssuerKeys{}, identVerifyAuto{}:
+			verify = true
+		case identAudience{}, identAudienceAll{}:
+			audience = true
+		}
+	}
+	if len(profiles) == 0 {
+		return options, nil
+	}
+
+	expanded := make([]ParseOption, 0, len(options))
+	for _, o := range options {
+		if o.Ident() != (identProfile{}) {
+			expanded = append(expanded, o)
+		}
+	}
+
+	for _, p := range profiles {
+		if !verify {
+			return nil, errors.Errorf(`profile %s requires the signature to be verified`, p.name)
+		}
+		if p.requireAudience && !audience {
+			return nil, errors.Errorf(`profile %s requires the expected audience to be specified (see jwt.WithAudience)`, p.name)
+		}
+
+		if p.tokenType != "" {
+			expanded = append(expanded, WithTokenType(p.tokenType))
+		}
+		for _, name := range p.requiredClaims {
+			expanded = append(expanded, WithRequiredClaim(name))
+		}
+	}
+	return append(expanded, WithValidate(true)), nil
+}
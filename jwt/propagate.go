@@ -0,0 +1,176 @@
+package jwt
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/pkg/errors"
+)
+
+// DefaultClaimsBundleHeader is the name of the HTTP header that carries
+// the signed claims bundle created by `(*jwt.ClaimPropagator).Inject()`
+const DefaultClaimsBundleHeader = "X-Jwt-Claims"
+
+type claimHeader struct {
+	claim  string
+	header string
+}
+
+// ClaimPropagator copies selected claims of a validated token into the
+// headers of an outgoing HTTP request (e.g. "sub" into "X-User-Id"), so
+// that services behind a gateway or sidecar can use them without having
+// to validate the original token themselves.
+//
+// Along with the plain headers, the claims are stored in a bundle signed
+// using HS256 with a key shared between the sender and the receiver.
+// `Extract()` verifies the bundle, checks that the plain headers have
+// not been modified, and reconstructs a token that contains only the
+// propagated claims.
+//
+// The "exp" claim, if present, is always included in the bundle, so the
+// receiver should call `jwt.Validate()` on the extracted token.
+type ClaimPropagator struct {
+	key          []byte
+	bundleHeader string
+	mappings     []claimHeader
+}
+
+// NewClaimPropagator creates a new ClaimPropagator. `key` is used to sign
+// and verify the claims bundle, and must be at least 32 bytes long.
+func NewClaimPropagator(key []byte) (*ClaimPropagator, error) {
+	if len(key) < 32 {
+		return nil, errors.Errorf(`key must be at least 32 bytes, got %d`, len(key))
+	}
+	return &ClaimPropagator{
+		key:          key,
+		bundleHeader: DefaultClaimsBundleHeader,
+	}, nil
+}
+
+// Map specifies that the claim `claim` should be propagated as the HTTP
+// header `header`. String values are copied as is, lists of strings are
+// joined with a space (e.g. "scope"), and other values are encoded in JSON.
+func (p *ClaimPropagator) Map(claim, header string) *ClaimPropagator {
+	p.mappings = append(p.mappings, claimHeader{
+		claim:  claim,
+		header: http.CanonicalHeaderKey(header),
+	})
+	return p
+}
+
+// BundleHeader changes the name of the header that carries the signed
+// claims bundle. The default is `jwt.DefaultClaimsBundleHeader`
+func (p *ClaimPropagator) BundleHeader(header string) *ClaimPropagator {
+	p.bundleHeader = http.CanonicalHeaderKey(header)
+	return p
+}
+
+// Inject sets the headers for the claims in `t`. Headers for claims that
+// are not present in the token are removed, so that values sent by the
+// client cannot be forwarded by accident.
+func (p *ClaimPropagator) Inject(t Token, h http.Header) error {
+	bundle := New()
+	if tv := t.Expiration(); !tv.IsZero() {
+		if err := bundle.Set(ExpirationKey, tv); err != nil {
+			return errors.Wrapf(err, `failed to set %q claim`, ExpirationKey)
+		}
+	}
+
+	for _, m := range p.mappings {
+		v, ok := t.Get(m.claim)
+		if !ok {
+			h.Del(m.header)
+			continue
+		}
+
+		hv, err := claimHeaderValue(v)
+		if err != nil {
+			return errors.Wrapf(err, `failed to encode claim %q`, m.claim)
+		}
+		h.Set(m.header, hv)
+		if err := bundle.Set(m.claim, v); err != nil {
+			return errors.Wrapf(err, `failed to set %q claim`, m.claim)
+		}
+	}
+
+	buf, err := json.Marshal(bundle)
+	if err != nil {
+		return errors.Wrap(err, `failed to marshal claims bundle`)
+	}
+
+	signed, err := jws.Sign(buf, jwa.HS256, p.key)
+	if err != nil {
+		return errors.Wrap(err, `failed to sign claims bundle`)
+	}
+	h.Set(p.bundleHeader, string(signed))
+	return nil
+}
+
+// Extract verifies the claims bundle in `h`, and returns a token that
+// contains the propagated claims. An error is returned if the bundle is
+// missing or invalid, or if any of the mapped headers does not match the
+// value in the bundle.
+func (p *ClaimPropagator) Extract(h http.Header) (Token, error) {
+	signed := h.Get(p.bundleHeader)
+	if signed == "" {
+		return nil, errors.Errorf(`header %q not found`, p.bundleHeader)
+	}
+
+	buf, err := jws.Verify([]byte(signed), jwa.HS256, p.key)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to verify claims bundle`)
+	}
+
+	t := New()
+	if err := json.Unmarshal(buf, t); err != nil {
+		return nil, errors.Wrap(err, `failed to parse claims bundle`)
+	}
+
+	for _, m := range p.mappings {
+		var expected string
+		if v, ok := t.Get(m.claim); ok {
+			hv, err := claimHeaderValue(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, `failed to encode claim %q`, m.claim)
+			}
+			expected = hv
+		}
+
+		if subtle.ConstantTimeCompare([]byte(h.Get(m.header)), []byte(expected)) != 1 {
+			return nil, errors.Errorf(`header %q does not match the claims bundle`, m.header)
+		}
+	}
+	return t, nil
+}
+
+func claimHeaderValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case []string:
+		return strings.Join(v, " "), nil
+	case []interface{}:
+		// lists of strings become []interface{} once the bundle is parsed
+		list := make([]string, 0, len(v))
+		for _, elem := range v {
+			s, ok := elem.(string)
+			if !ok {
+				break
+			}
+			list = append(list, s)
+		}
+		if len(list) == len(v) {
+			return strings.Join(list, " "), nil
+		}
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,108 @@
+package jwt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RefreshPolicy describes how `jwt.Refresh()` extends the lifetime of
+// tokens, for sessions that are kept alive as long as they are used
+// (sliding expiration).
+type RefreshPolicy struct {
+	// TTL is the lifetime of refreshed tokens: the "exp" claim is set to
+	// the time of refresh plus TTL. Tokens are only refreshed once less
+	// than half of TTL remains before they expire. It is required.
+	TTL time.Duration
+
+	// MaxLifetime limits the total duration of the session, measured from
+	// the "auth_time" claim or, if it is not present, the "iat" claim.
+	// The "exp" claim is never extended beyond the end of the session.
+	// If MaxLifetime is less than or equal to 0, sessions may be
+	// extended indefinitely.
+	MaxLifetime time.Duration
+
+	// Clock is used to determine the time of refresh. If not specified,
+	// the system clock is used.
+	Clock Clock
+}
+
+// Refresh implements sliding expiration: it returns a copy of `old` whose
+// expiration has been extended according to the policy, and that must
+// then be signed (e.g. using `jwt.Sign()`) and handed to the client in
+// place of the old token. `old` must have been verified and validated
+// beforehand; it is not modified.
+//
+// If more than half of the TTL of the policy remains before `old`
+// expires, refreshing is not necessary yet, and `old` itself is returned.
+// Otherwise, the "exp" claim of the copy is set to the current time plus
+// the TTL, capped at the end of the session, and the "jti" claim is set to
+// a new random value, so that the old and new tokens can be told apart
+// (e.g. for revocation). All other claims, including "iat" and
+// "auth_time", are preserved.
+//
+// Tokens that have already expired cannot be refreshed, and are reported
+// as `jwt.ErrTokenExpired`. Tokens whose session has exceeded the
+// MaxLifetime of the policy are reported as `jwt.ErrSessionExpired`.
+func Refresh(old Token, policy RefreshPolicy) (Token, error) {
+	if policy.TTL <= 0 {
+		return nil, errors.New(`refresh policy must specify a positive TTL`)
+	}
+
+	var clock Clock = ClockFunc(time.Now)
+	if policy.Clock != nil {
+		clock = policy.Clock
+	}
+	now := clock.Now()
+
+	if _, ok := old.Get(ExpirationKey); !ok {
+		return nil, newValidationError(ErrMissingClaim, ExpirationKey, `tokens without "exp" claim cannot be refreshed`, nil)
+	}
+	exp := old.Expiration()
+	if !now.Before(exp) {
+		return nil, newValidationError(ErrTokenExpired, ExpirationKey, `exp not satisfied`, nil)
+	}
+
+	var sessionEnd time.Time
+	if policy.MaxLifetime > 0 {
+		name := AuthTimeKey
+		if _, ok := old.Get(AuthTimeKey); !ok {
+			name = IssuedAtKey
+		}
+		start, err := deltaTime(old, name, clock)
+		if err != nil {
+			return nil, err
+		}
+		sessionEnd = start.Add(policy.MaxLifetime)
+		if !now.Before(sessionEnd) {
+			return nil, newValidationError(ErrSessionExpired, name, fmt.Sprintf(`session started at %s has exceeded its maximum lifetime`, name), nil)
+		}
+	}
+
+	if exp.Sub(now) > policy.TTL/2 {
+		return old, nil
+	}
+
+	newExp := now.Add(policy.TTL)
+	if !sessionEnd.IsZero() && newExp.After(sessionEnd) {
+		newExp = sessionEnd
+	}
+
+	t, err := old.Clone()
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to copy token`)
+	}
+	if err := t.Set(ExpirationKey, newExp); err != nil {
+		return nil, errors.Wrap(err, `failed to set exp`)
+	}
+
+	jti, err := newJwtID()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Set(JwtIDKey, jti); err != nil {
+		return nil, errors.Wrap(err, `failed to set jti`)
+	}
+	return t, nil
+}
@@ -0,0 +1,142 @@
+package jwt
+
+import (
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwe"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/pkg/errors"
+)
+
+type serializeStep interface {
+	// serialize transforms the payload. `nested` is true if the payload
+	// is the output of a previous step, rather than the JSON
+	// representation of the token.
+	serialize(t Token, payload []byte, nested bool) ([]byte, error)
+}
+
+// Serializer creates nested JWTs (RFC 7519 section 11.2), such as a
+// JWS that is encrypted in a JWE, in a single call:
+//
+//     serialized, err := jwt.NewSerializer().
+//       Sign(jwa.RS256, signingKey).
+//       Encrypt(jwa.RSA_OAEP, encryptionKey, jwa.A256GCM, jwa.NoCompress).
+//       Serialize(token)
+//
+// The steps are applied in the order that they were added. Each layer
+// that wraps the output of a previous step has its `cty` header set to
+// "JWT". If no steps are added, the JSON representation of the token is
+// returned.
+//
+// To parse the result, pass both `jwt.WithDecrypt()` and the
+// verification options to `jwt.Parse()`.
+type Serializer struct {
+	steps []serializeStep
+}
+
+// NewSerializer creates a new, empty Serializer
+func NewSerializer() *Serializer {
+	return &Serializer{}
+}
+
+// Reset removes all of the steps
+func (s *Serializer) Reset() *Serializer {
+	s.steps = nil
+	return s
+}
+
+type signStep struct {
+	alg     jwa.SignatureAlgorithm
+	key     interface{}
+	options []Option
+}
+
+// Sign adds a step to sign the payload. The options are the same as
+// those for `jwt.Sign()`.
+func (s *Serializer) Sign(alg jwa.SignatureAlgorithm, key interface{}, options ...Option) *Serializer {
+	s.steps = append(s.steps, &signStep{
+		alg:     alg,
+		key:     key,
+		options: options,
+	})
+	return s
+}
+
+func (step *signStep) serialize(t Token, payload []byte, nested bool) ([]byte, error) {
+	if !nested {
+		return Sign(t, step.alg, step.key, step.options...)
+	}
+
+	var hdr jws.Headers
+	for _, o := range step.options {
+		switch o.Ident() {
+		case identHeaders{}:
+			hdr = o.Value().(jws.Headers)
+		}
+	}
+	hdr, err := copyHeaders(hdr)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to sign payload`)
+	}
+
+	if err := hdr.Set(jws.ContentTypeKey, jwe.ContentTypeJWT); err != nil {
+		return nil, errors.Wrap(err, `failed to set content type`)
+	}
+
+	signed, err := jws.Sign(payload, step.alg, step.key, jws.WithHeaders(hdr))
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to sign payload`)
+	}
+	return signed, nil
+}
+
+type encryptStep struct {
+	keyalg      jwa.KeyEncryptionAlgorithm
+	key         interface{}
+	contentalg  jwa.ContentEncryptionAlgorithm
+	compressalg jwa.CompressionAlgorithm
+	options     []jwe.EncryptOption
+}
+
+// Encrypt adds a step to encrypt the payload. The parameters are the
+// same as those for `jwe.Encrypt()`.
+func (s *Serializer) Encrypt(keyalg jwa.KeyEncryptionAlgorithm, key interface{}, contentalg jwa.ContentEncryptionAlgorithm, compressalg jwa.CompressionAlgorithm, options ...jwe.EncryptOption) *Serializer {
+	s.steps = append(s.steps, &encryptStep{
+		keyalg:      keyalg,
+		key:         key,
+		contentalg:  contentalg,
+		compressalg: compressalg,
+		options:     options,
+	})
+	return s
+}
+
+func (step *encryptStep) serialize(_ Token, payload []byte, nested bool) ([]byte, error) {
+	options := step.options
+	if nested {
+		// Prepend, so that the content type can still be overridden
+		options = append([]jwe.EncryptOption{jwe.WithContentType(jwe.ContentTypeJWT)}, options...)
+	}
+
+	encrypted, err := jwe.Encrypt(payload, step.keyalg, step.key, step.contentalg, step.compressalg, options...)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to encrypt payload`)
+	}
+	return encrypted, nil
+}
+
+// Serialize applies the steps to the token, and returns the result
+func (s *Serializer) Serialize(t Token) ([]byte, error) {
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal token`)
+	}
+
+	for i, step := range s.steps {
+		payload, err = step.serialize(t, payload, i > 0)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to serialize token at step #%d`, i+1)
+		}
+	}
+	return payload, nil
+}
@@ -0,0 +1,25 @@
+package jwt
+
+import "github.com/lestrrat-go/jwx/jwt/internal/types"
+
+// Settings changes the package-wide behavior of the jwt package. The
+// settings apply to all tokens, including those of the jwt/openid package,
+// so they are usually changed once, when the program starts. Settings
+// that are not specified are left unchanged.
+//
+//     jwt.Settings(
+//       jwt.WithNumericDateFormatPrecision(3),
+//       jwt.WithNumericDateParsePrecision(3),
+//     )
+func Settings(options ...GlobalOption) {
+	for _, option := range options {
+		switch option.Ident() {
+		case identNumericDateParsePrecision{}:
+			types.SetParsePrecision(option.Value().(int))
+		case identNumericDateFormatPrecision{}:
+			types.SetFormatPrecision(option.Value().(int))
+		case identNumericDateRejectFractional{}:
+			types.SetRejectFractional(option.Value().(bool))
+		}
+	}
+}
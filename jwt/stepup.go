@@ -0,0 +1,118 @@
+package jwt
+
+import (
+	"fmt"
+)
+
+// Names of the claims that describe how the user was authenticated, as
+// defined in OpenID Connect Core 1.0 and used by RFC 9470 (OAuth 2.0
+// Step-up Authentication Challenge Protocol). The jwt/openid package
+// refers to the same constants (e.g. `openid.AuthContextClassKey`).
+const (
+	AuthContextClassKey = "acr"
+	AuthMethodsKey      = "amr"
+	AuthTimeKey         = "auth_time"
+)
+
+type minimumACR struct {
+	order []string
+	min   string
+}
+
+// WithMinimumACR specifies that the "acr" claim must denote an
+// authentication context class that is at least as strong as `min`.
+// `order` lists the classes that the resource server accepts, from the
+// weakest to the strongest:
+//
+//     jwt.WithMinimumACR([]string{"bronze", "silver", "gold"}, "silver")
+//
+// Tokens without an "acr" claim, or whose "acr" claim is not listed in
+// `order`, fail validation. The failure is reported as
+// `jwt.ErrInsufficientAuthentication`, so that the resource server can
+// respond with an "insufficient_user_authentication" challenge.
+func WithMinimumACR(order []string, min string) ValidateOption {
+	return newValidateOption(identValidator{}, &minimumACR{
+		order: append([]string(nil), order...),
+		min:   min,
+	})
+}
+
+func (v *minimumACR) Validate(t Token) error {
+	minRank := acrRank(v.order, v.min)
+	if minRank < 0 {
+		return newValidationError(ErrInsufficientAuthentication, AuthContextClassKey, fmt.Sprintf(`%s %q is not a known authentication context class`, AuthContextClassKey, v.min), nil)
+	}
+
+	raw, ok := t.Get(AuthContextClassKey)
+	if !ok {
+		return newValidationError(ErrInsufficientAuthentication, AuthContextClassKey, fmt.Sprintf(`required claim %s is missing`, AuthContextClassKey), nil)
+	}
+
+	acr, ok := raw.(string)
+	if !ok {
+		return newValidationError(ErrInvalidClaimFormat, AuthContextClassKey, fmt.Sprintf(`%s must be a string, got %T`, AuthContextClassKey, raw), nil)
+	}
+
+	if acrRank(v.order, acr) < minRank {
+		return newValidationError(ErrInsufficientAuthentication, AuthContextClassKey, fmt.Sprintf(`%s %q does not satisfy %q`, AuthContextClassKey, acr, v.min), nil)
+	}
+	return nil
+}
+
+func acrRank(order []string, acr string) int {
+	for i, v := range order {
+		if v == acr {
+			return i
+		}
+	}
+	return -1
+}
+
+type requiredAMR []string
+
+// WithRequiredAMR specifies that the "amr" claim must contain all of
+// the given authentication method references, e.g. "mfa" or "hwk".
+//
+// The failure is reported as `jwt.ErrInsufficientAuthentication`, so
+// that the resource server can respond with an
+// "insufficient_user_authentication" challenge.
+func WithRequiredAMR(methods ...string) ValidateOption {
+	return newValidateOption(identValidator{}, requiredAMR(append([]string(nil), methods...)))
+}
+
+func (v requiredAMR) Validate(t Token) error {
+	raw, ok := t.Get(AuthMethodsKey)
+	if !ok {
+		return newValidationError(ErrInsufficientAuthentication, AuthMethodsKey, fmt.Sprintf(`required claim %s is missing`, AuthMethodsKey), nil)
+	}
+
+	var methods []string
+	switch raw := raw.(type) {
+	case []string:
+		methods = raw
+	case []interface{}:
+		for _, elem := range raw {
+			s, ok := elem.(string)
+			if !ok {
+				return newValidationError(ErrInvalidClaimFormat, AuthMethodsKey, fmt.Sprintf(`%s must be an array of strings, got element of type %T`, AuthMethodsKey, elem), nil)
+			}
+			methods = append(methods, s)
+		}
+	default:
+		return newValidationError(ErrInvalidClaimFormat, AuthMethodsKey, fmt.Sprintf(`%s must be an array of strings, got %T`, AuthMethodsKey, raw), nil)
+	}
+
+	for _, required := range v {
+		var found bool
+		for _, method := range methods {
+			if method == required {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return newValidationError(ErrInsufficientAuthentication, AuthMethodsKey, fmt.Sprintf(`%s does not contain %q`, AuthMethodsKey, required), nil)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto"
+
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/pkg/errors"
+)
+
+// Thumbprint computes a stable hash over the claims `claims` of the
+// token, which can be used as an idempotency key or to deduplicate
+// tokens that carry the same information (e.g. security event tokens
+// that are delivered more than once). If no claims are given, all
+// claims of the token are used.
+//
+// The hash is computed over a canonical JSON serialization of the
+// selected claims, which is created as follows:
+//
+//   - The token is serialized to JSON as usual, e.g. "exp" is a NumericDate
+//   - Claims that are not present in the token are omitted, so a token
+//     without any of the given claims yields the hash of "{}"
+//   - The members of all objects, including nested ones, are sorted by
+//     their names in byte order
+//   - There is no whitespace between elements
+//   - Strings are escaped as done by encoding/json, without escaping the
+//     HTML characters "<", ">" and "&"
+//   - Numbers are represented exactly as they appear in the serialized token
+//
+// The result therefore does not depend on the order in which the claims
+// were set, nor on how the token was serialized when it was received.
+func Thumbprint(t Token, h crypto.Hash, claims ...string) ([]byte, error) {
+	if !h.Available() {
+		return nil, errors.Errorf(`hash function %s is not available`, h)
+	}
+
+	buf, err := json.Marshal(t)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to marshal token`)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return nil, errors.Wrap(err, `failed to decode token`)
+	}
+
+	if len(claims) > 0 {
+		selected := make(map[string]interface{})
+		for _, name := range claims {
+			if v, ok := m[name]; ok {
+				selected[name] = v
+			}
+		}
+		m = selected
+	}
+
+	var canonical bytes.Buffer
+	if err := json.WriteCanonical(&canonical, m); err != nil {
+		return nil, errors.Wrap(err, `failed to create canonical serialization`)
+	}
+
+	hh := h.New()
+	hh.Write(canonical.Bytes())
+	return hh.Sum(nil), nil
+}
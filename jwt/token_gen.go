@@ -40,6 +40,28 @@ const (
 // Embedding jwt.Token into another struct is not recommended, becase
 // jwt.Token needs to handle private claims, and this really does not
 // work well when it is embedded in other structure
+//
+// All methods on the standard implementation are safe for concurrent use,
+// so a single token may be shared between goroutines (e.g. in HTTP
+// middleware) and modified using `Set()` and `Remove()`.
+// The only exception is the map returned by `PrivateClaims()`, which
+// is the token's internal storage.
+// WARNING: DO NOT USE PrivateClaims() IF YOU HAVE CONCURRENT CODE ACCESSING THEM.
+// Use `AsMap()` to get a copy of the entire token, or use `Iterate()` instead
+//
+// `AsMap()` returns the time based claims ("exp", "iat" and "nbf") as
+// time.Time values, and "aud" as a []string. `jwt.FromMap()` performs
+// the reverse conversion.
+//
+// `Keys()`, `Iterate()`, `Walk()` and `MarshalJSON()` visit both standard
+// and private claims in lexical order of their names, so that their output
+// is deterministic.
+//
+// Tokens implement encoding.TextMarshaler and encoding.BinaryMarshaler
+// (and their Unmarshaler counterparts) using the JSON representation of
+// the claims, so that they can be stored in caches, databases, and the like.
+// Note that this is the unsigned claims set: use `jwt.Sign()` to produce
+// a token that can be verified by others.
 type Token interface {
 	Audience() []string
 	Expiration() time.Time
@@ -52,10 +74,15 @@ type Token interface {
 	Get(string) (interface{}, bool)
 	Set(string, interface{}) error
 	Remove(string) error
+	Keys() []string
 	Clone() (Token, error)
 	Iterate(context.Context) Iterator
 	Walk(context.Context, Visitor) error
 	AsMap(context.Context) (map[string]interface{}, error)
+	MarshalText() ([]byte, error)
+	UnmarshalText([]byte) error
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary([]byte) error
 }
 type stdToken struct {
 	mu            *sync.RWMutex
@@ -329,9 +356,23 @@ func (t *stdToken) makePairs() []*ClaimPair {
 	for k, v := range t.privateClaims {
 		pairs = append(pairs, &ClaimPair{Key: k, Value: v})
 	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.(string) < pairs[j].Key.(string)
+	})
 	return pairs
 }
 
+// Keys returns the names of all claims, both standard and private,
+// stored in the token in lexical order
+func (t *stdToken) Keys() []string {
+	pairs := t.makePairs()
+	keys := make([]string, len(pairs))
+	for i, pair := range pairs {
+		keys[i] = pair.Key.(string)
+	}
+	return keys
+}
+
 func (t *stdToken) UnmarshalJSON(buf []byte) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -413,15 +454,10 @@ LOOP:
 	return nil
 }
 
-func (t stdToken) MarshalJSON() ([]byte, error) {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+func (t *stdToken) MarshalJSON() ([]byte, error) {
 	data := make(map[string]interface{})
 	fields := make([]string, 0, 7)
-	for iter := t.Iterate(ctx); iter.Next(ctx); {
-		pair := iter.Pair()
+	for _, pair := range t.makePairs() {
 		fields = append(fields, pair.Key.(string))
 		data[pair.Key.(string)] = pair.Value
 	}
@@ -447,7 +483,7 @@ func (t stdToken) MarshalJSON() ([]byte, error) {
 		case time.Time:
 			switch f {
 			case ExpirationKey, IssuedAtKey, NotBeforeKey:
-				enc.Encode(v.Unix())
+				buf.WriteString(types.FormatNumericDate(v))
 			default:
 				if err := enc.Encode(v); err != nil {
 					return nil, errors.Wrapf(err, `failed to marshal field %s`, f)
@@ -0,0 +1,187 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+
+	"github.com/lestrrat-go/jwx/internal/base64"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/pkg/errors"
+)
+
+// IssuerConfig describes how an Issuer creates tokens
+type IssuerConfig struct {
+	// Issuer is the value of the "iss" claim
+	Issuer string
+
+	// Audience is the value of the "aud" claim
+	Audience []string
+
+	// TTL is the lifetime of the tokens. The "exp" claim is set to the
+	// time of issuance plus TTL. If TTL is less than or equal to 0, the
+	// "exp" claim is not set.
+	TTL time.Duration
+
+	// Algorithm is the signature algorithm. It is required.
+	Algorithm jwa.SignatureAlgorithm
+
+	// KeyProvider provides the signing key and its key ID each time a
+	// token is issued, so that rotated keys are picked up automatically.
+	// It is required.
+	KeyProvider jws.SigningKeyProvider
+
+	// Clock is used to determine the time of issuance. If not
+	// specified, the system clock is used.
+	Clock Clock
+
+	// Limiter limits the number of tokens that are issued for each
+	// subject ("sub" claim), so that compromised automation cannot mint
+	// an unbounded number of tokens. If not specified, the number of
+	// tokens is not limited. See `jwt.NewSubjectRateLimiter()`.
+	Limiter IssuanceLimiter
+
+	// JtiStore, if specified, is used as a backstop to guarantee that the
+	// "jti" claims of the issued tokens are unique. Tokens without a
+	// "jti" claim are assigned a random one.
+	JtiStore JtiStore
+}
+
+// ErrIssuanceLimitExceeded is returned by `Issue()` when the Limiter of
+// the Issuer does not allow another token to be issued for the subject
+var ErrIssuanceLimitExceeded = errors.New(`issuance limit exceeded`)
+
+// ErrDuplicateJwtID is returned by `Issue()` when the "jti" claim has
+// already been used by a previously issued token
+var ErrDuplicateJwtID = errors.New(`duplicate JWT ID`)
+
+// Issuer creates signed tokens using a fixed configuration. It is the
+// issuance side counterpart of `jwt.IssuerKeyPolicy`, and is safe for
+// concurrent use as long as the KeyProvider is.
+type Issuer struct {
+	config IssuerConfig
+}
+
+// NewIssuer creates a new Issuer. The configuration is copied, so
+// modifying it afterwards does not affect the Issuer.
+func NewIssuer(config IssuerConfig) (*Issuer, error) {
+	if config.Algorithm == "" {
+		return nil, errors.New(`signature algorithm must be specified`)
+	}
+	if config.KeyProvider == nil {
+		return nil, errors.New(`key provider must be specified`)
+	}
+	if config.Clock == nil {
+		config.Clock = ClockFunc(time.Now)
+	}
+	if len(config.Audience) > 0 {
+		aud := make([]string, len(config.Audience))
+		copy(aud, config.Audience)
+		config.Audience = aud
+	}
+	return &Issuer{config: config}, nil
+}
+
+// Issue signs a token containing the given claims, and returns it in
+// compact serialization format. `claims` is not modified, and may be nil.
+//
+// The "iss", "aud", "iat" and "exp" claims are populated from the
+// configuration of the Issuer, unless they are already present in
+// `claims`. The "kid" header is set to the key ID returned by the
+// KeyProvider, or, if it is empty and the key is a jwk.Key, to the
+// key ID of the key.
+//
+// If the configuration specifies a Limiter or a JtiStore, they are
+// consulted after the claims have been populated. Failures can be
+// tested for using `errors.Is()` with `jwt.ErrIssuanceLimitExceeded`
+// and `jwt.ErrDuplicateJwtID`.
+func (iss *Issuer) Issue(ctx context.Context, claims Token) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var t Token
+	if claims == nil {
+		t = New()
+	} else {
+		v, err := claims.Clone()
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to copy claims`)
+		}
+		t = v
+	}
+
+	now := iss.config.Clock.Now()
+	defaults := []struct {
+		name  string
+		value interface{}
+		apply bool
+	}{
+		{name: IssuerKey, value: iss.config.Issuer, apply: iss.config.Issuer != ""},
+		{name: AudienceKey, value: iss.config.Audience, apply: len(iss.config.Audience) > 0},
+		{name: IssuedAtKey, value: now, apply: true},
+		{name: ExpirationKey, value: now.Add(iss.config.TTL), apply: iss.config.TTL > 0},
+	}
+	for _, d := range defaults {
+		if !d.apply {
+			continue
+		}
+		if _, ok := t.Get(d.name); ok {
+			continue
+		}
+		if err := t.Set(d.name, d.value); err != nil {
+			return nil, errors.Wrapf(err, `failed to set %s`, d.name)
+		}
+	}
+
+	if iss.config.Limiter != nil {
+		ok, err := iss.config.Limiter.Allow(ctx, t.Subject(), now)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to check issuance limit`)
+		}
+		if !ok {
+			return nil, errors.Wrapf(ErrIssuanceLimitExceeded, `failed to issue token for subject %q`, t.Subject())
+		}
+	}
+
+	if iss.config.JtiStore != nil {
+		if err := iss.assignJwtID(ctx, t); err != nil {
+			return nil, err
+		}
+	}
+
+	return Sign(t, iss.config.Algorithm, nil, withKeyProvider(iss.config.KeyProvider))
+}
+
+func (iss *Issuer) assignJwtID(ctx context.Context, t Token) error {
+	jti := t.JwtID()
+	if jti == "" {
+		v, err := newJwtID()
+		if err != nil {
+			return err
+		}
+		jti = v
+		if err := t.Set(JwtIDKey, jti); err != nil {
+			return errors.Wrap(err, `failed to set jti`)
+		}
+	}
+
+	seen, err := iss.config.JtiStore.Seen(ctx, jti, t.Expiration())
+	if err != nil {
+		return errors.Wrap(err, `failed to check jti`)
+	}
+	if seen {
+		return errors.Wrapf(ErrDuplicateJwtID, `jti %q has already been used`, jti)
+	}
+	return nil
+}
+
+// newJwtID generates a random value for the "jti" claim
+func newJwtID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, `failed to generate jti`)
+	}
+	return base64.EncodeToString(buf), nil
+}
@@ -1,14 +1,20 @@
 package jwt_test
 
 import (
+	"bytes"
 	"context"
+	"encoding"
+	"encoding/gob"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/lestrrat-go/jwx/internal/json"
 
 	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/lestrrat-go/jwx/jwt/openid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -286,3 +292,329 @@ func TestToken(t *testing.T) {
 		}
 	})
 }
+
+func TestTokenClone(t *testing.T) {
+	t.Parallel()
+
+	template := jwt.New()
+	_ = template.Set(jwt.AudienceKey, []string{`foo`, `bar`})
+	_ = template.Set(`roles`, []interface{}{`admin`, `user`})
+	_ = template.Set(`profile`, map[string]interface{}{
+		`name`:   `John Doe`,
+		`groups`: []string{`staff`},
+	})
+
+	t.Run("Deep copy", func(t *testing.T) {
+		t.Parallel()
+		tok, err := template.Clone()
+		if !assert.NoError(t, err, `template.Clone should succeed`) {
+			return
+		}
+
+		tok.Audience()[0] = `baz`
+		roles, _ := tok.Get(`roles`)
+		roles.([]interface{})[0] = `guest`
+		profile, _ := tok.Get(`profile`)
+		profile.(map[string]interface{})[`name`] = `Jane Doe`
+		profile.(map[string]interface{})[`groups`].([]string)[0] = `admin`
+
+		if !assert.Equal(t, []string{`foo`, `bar`}, template.Audience(), `"aud" of template should not change`) {
+			return
+		}
+		v, _ := template.Get(`roles`)
+		if !assert.Equal(t, []interface{}{`admin`, `user`}, v, `"roles" of template should not change`) {
+			return
+		}
+		v, _ = template.Get(`profile`)
+		if !assert.Equal(t, map[string]interface{}{`name`: `John Doe`, `groups`: []string{`staff`}}, v, `"profile" of template should not change`) {
+			return
+		}
+	})
+	t.Run("Pointers", func(t *testing.T) {
+		t.Parallel()
+		type tenant struct {
+			Name   string
+			Scopes []string
+			Parent *tenant
+		}
+		src := jwt.New()
+		root := &tenant{Name: `root`}
+		_ = src.Set(`tenant`, &tenant{Name: `acme`, Scopes: []string{`read`}, Parent: root})
+
+		tok, err := src.Clone()
+		if !assert.NoError(t, err, `src.Clone should succeed`) {
+			return
+		}
+		v, _ := tok.Get(`tenant`)
+		cloned := v.(*tenant)
+		cloned.Name = `evil`
+		cloned.Scopes[0] = `admin`
+		cloned.Parent.Name = `evil-root`
+
+		v, _ = src.Get(`tenant`)
+		if !assert.Equal(t, &tenant{Name: `acme`, Scopes: []string{`read`}, Parent: &tenant{Name: `root`}}, v, `"tenant" of source should not change`) {
+			return
+		}
+	})
+	t.Run("Concurrent derivation", func(t *testing.T) {
+		t.Parallel()
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				tok, err := template.Clone()
+				if !assert.NoError(t, err, `template.Clone should succeed`) {
+					return
+				}
+				_ = tok.Set(jwt.AudienceKey, tok.Audience()[:1])
+				_ = tok.Remove(`roles`)
+				profile, _ := tok.Get(`profile`)
+				profile.(map[string]interface{})[`request`] = i
+			}(i)
+		}
+		wg.Wait()
+
+		if !assert.Equal(t, []string{`foo`, `bar`}, template.Audience(), `"aud" of template should not change`) {
+			return
+		}
+		if _, ok := template.Get(`roles`); !assert.True(t, ok, `"roles" of template should not be removed`) {
+			return
+		}
+		v, _ := template.Get(`profile`)
+		if _, ok := v.(map[string]interface{})[`request`]; !assert.False(t, ok, `"profile" of template should not change`) {
+			return
+		}
+	})
+}
+
+func TestTokenConcurrentAccess(t *testing.T) {
+	t.Parallel()
+
+	for _, tok := range []jwt.Token{jwt.New(), openid.New()} {
+		tok := tok
+		t.Run(fmt.Sprintf("%T", tok), func(t *testing.T) {
+			t.Parallel()
+			if !assert.NoError(t, tok.Set(jwt.IssuerKey, "github.com/lestrrat-go/jwx"), `tok.Set should succeed`) {
+				return
+			}
+
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						key := fmt.Sprintf("claim-%d", i)
+						_ = tok.Set(key, j)
+						_ = tok.Set(jwt.SubjectKey, key)
+						_ = tok.Set(jwt.ExpirationKey, time.Unix(int64(j), 0))
+						_, _ = tok.Get(key)
+						_, _ = json.Marshal(tok)
+						_, _ = tok.AsMap(context.TODO())
+						_ = tok.Remove(key)
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			if !assert.Equal(t, "github.com/lestrrat-go/jwx", tok.Issuer(), `tok.Issuer should match`) {
+				return
+			}
+		})
+	}
+}
+
+func TestTokenKeys(t *testing.T) {
+	t.Parallel()
+
+	tok := jwt.New()
+	for k, v := range map[string]interface{}{
+		"zzz":           "last",
+		jwt.SubjectKey:  "unit test",
+		"aaa":           "first",
+		jwt.IssuerKey:   "github.com/lestrrat-go/jwx",
+		jwt.AudienceKey: []string{"developers"},
+	} {
+		if !assert.NoError(t, tok.Set(k, v), `tok.Set should succeed`) {
+			return
+		}
+	}
+
+	expected := []string{"aaa", jwt.AudienceKey, jwt.IssuerKey, jwt.SubjectKey, "zzz"}
+	if !assert.Equal(t, expected, tok.Keys(), `tok.Keys should be sorted`) {
+		return
+	}
+
+	ctx := context.TODO()
+	var iterated []string
+	for iter := tok.Iterate(ctx); iter.Next(ctx); {
+		iterated = append(iterated, iter.Pair().Key.(string))
+	}
+	if !assert.Equal(t, expected, iterated, `tok.Iterate should visit claims in sorted order`) {
+		return
+	}
+}
+
+func TestTokenEncoding(t *testing.T) {
+	t.Parallel()
+
+	tok := jwt.New()
+	for k, v := range map[string]interface{}{
+		jwt.IssuerKey:     "github.com/lestrrat-go/jwx",
+		jwt.AudienceKey:   []string{"developers"},
+		jwt.ExpirationKey: expectedTokenTime,
+		"private":         "claim",
+	} {
+		if !assert.NoError(t, tok.Set(k, v), `tok.Set should succeed`) {
+			return
+		}
+	}
+
+	var _ encoding.TextMarshaler = tok
+	var _ encoding.BinaryUnmarshaler = tok
+
+	t.Run("Text", func(t *testing.T) {
+		t.Parallel()
+		text, err := tok.MarshalText()
+		if !assert.NoError(t, err, `MarshalText should succeed`) {
+			return
+		}
+		expected, err := json.Marshal(tok)
+		if !assert.NoError(t, err, `json.Marshal should succeed`) {
+			return
+		}
+		if !assert.Equal(t, expected, text, `MarshalText should produce the JSON claims`) {
+			return
+		}
+
+		decoded := jwt.New()
+		if !assert.NoError(t, decoded.UnmarshalText(text), `UnmarshalText should succeed`) {
+			return
+		}
+		if !assert.Equal(t, tok.Issuer(), decoded.Issuer(), `issuer should match`) {
+			return
+		}
+		if !assert.Equal(t, tok.Audience(), decoded.Audience(), `audience should match`) {
+			return
+		}
+		if !assert.Equal(t, tok.Expiration(), decoded.Expiration(), `expiration should match`) {
+			return
+		}
+		if !assert.Equal(t, tok.PrivateClaims(), decoded.PrivateClaims(), `private claims should match`) {
+			return
+		}
+	})
+	t.Run("gob", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		if !assert.NoError(t, gob.NewEncoder(&buf).Encode(tok), `gob.Encode should succeed`) {
+			return
+		}
+
+		decoded := jwt.New()
+		if !assert.NoError(t, gob.NewDecoder(&buf).Decode(decoded), `gob.Decode should succeed`) {
+			return
+		}
+		if !assert.Equal(t, tok.Issuer(), decoded.Issuer(), `issuer should match`) {
+			return
+		}
+		if !assert.Equal(t, tok.Audience(), decoded.Audience(), `audience should match`) {
+			return
+		}
+		if !assert.Equal(t, tok.Expiration(), decoded.Expiration(), `expiration should match`) {
+			return
+		}
+		if !assert.Equal(t, tok.PrivateClaims(), decoded.PrivateClaims(), `private claims should match`) {
+			return
+		}
+	})
+}
+
+func TestBuilder(t *testing.T) {
+	t.Parallel()
+	t.Run("Build", func(t *testing.T) {
+		t.Parallel()
+		tok, err := jwt.NewBuilder().
+			Audience([]string{"developers"}).
+			Expiration(expectedTokenTime).
+			IssuedAt(expectedTokenTime).
+			Issuer("github.com/lestrrat-go/jwx").
+			JwtID("AbCdEfG").
+			NotBefore(expectedTokenTime).
+			Subject("user").
+			Claim("role", "admin").
+			Build()
+		if !assert.NoError(t, err, `Build should succeed`) {
+			return
+		}
+
+		expected := jwt.New()
+		for name, value := range map[string]interface{}{
+			jwt.AudienceKey:   []string{"developers"},
+			jwt.ExpirationKey: expectedTokenTime,
+			jwt.IssuedAtKey:   expectedTokenTime,
+			jwt.IssuerKey:     "github.com/lestrrat-go/jwx",
+			jwt.JwtIDKey:      "AbCdEfG",
+			jwt.NotBeforeKey:  expectedTokenTime,
+			jwt.SubjectKey:    "user",
+			"role":            "admin",
+		} {
+			if !assert.NoError(t, expected.Set(name, value), `Set should succeed`) {
+				return
+			}
+		}
+		expectedJSON, err := json.Marshal(expected)
+		if !assert.NoError(t, err, `json.Marshal should succeed`) {
+			return
+		}
+		actualJSON, err := json.Marshal(tok)
+		if !assert.NoError(t, err, `json.Marshal should succeed`) {
+			return
+		}
+		if !assert.Equal(t, string(expectedJSON), string(actualJSON), `tokens should be equal`) {
+			return
+		}
+	})
+	t.Run("Reuse", func(t *testing.T) {
+		t.Parallel()
+		b := jwt.NewBuilder().Issuer("github.com/lestrrat-go/jwx")
+		t1, err := b.Build()
+		if !assert.NoError(t, err, `Build should succeed`) {
+			return
+		}
+		t2, err := b.Subject("user").Build()
+		if !assert.NoError(t, err, `Build should succeed`) {
+			return
+		}
+		if !assert.Empty(t, t1.Subject(), `previously built tokens should not be modified`) {
+			return
+		}
+		if !assert.Equal(t, "user", t2.Subject(), `"sub" should be set`) {
+			return
+		}
+	})
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+		tok, err := jwt.NewBuilder().
+			Claim(jwt.ExpirationKey, "tomorrow").
+			Issuer("github.com/lestrrat-go/jwx").
+			Claim(jwt.AudienceKey, 1234).
+			Build()
+		if !assert.Error(t, err, `Build should fail`) {
+			return
+		}
+		if !assert.Nil(t, tok, `token should be nil`) {
+			return
+		}
+		if !assert.Contains(t, err.Error(), `"exp"`, `error should mention "exp"`) {
+			return
+		}
+		if !assert.Contains(t, err.Error(), `"aud"`, `error should mention "aud"`) {
+			return
+		}
+		if !assert.NotContains(t, err.Error(), `"iss"`, `error should not mention "iss"`) {
+			return
+		}
+	})
+}
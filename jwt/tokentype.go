@@ -0,0 +1,81 @@
+package jwt
+
+import (
+	"strings"
+
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/pkg/errors"
+)
+
+// Values for the `typ` header of JWS messages that carry JWTs
+const (
+	// TokenTypeJWT is the default type, which is set by `jwt.Sign()`
+	// unless another type is specified via `jwt.WithTokenType()`
+	TokenTypeJWT = "JWT"
+	// TokenTypeAccessToken is used for OAuth 2.0 access tokens (RFC 9068)
+	TokenTypeAccessToken = "at+jwt"
+	// TokenTypeDPoP is used for DPoP proofs (RFC 9449)
+	TokenTypeDPoP = "dpop+jwt"
+	// TokenTypeSecurityEvent is used for Security Event Tokens (RFC 8417)
+	TokenTypeSecurityEvent = "secevent+jwt"
+)
+
+// Token type identifiers as defined in RFC 8693 (OAuth 2.0 Token Exchange).
+// These are not used as the value of the `typ` header, but are provided
+// for applications that need to tag tokens in their token exchange requests
+// and responses.
+const (
+	TokenTypeURNJWT          = "urn:ietf:params:oauth:token-type:jwt"
+	TokenTypeURNAccessToken  = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeURNRefreshToken = "urn:ietf:params:oauth:token-type:refresh_token"
+	TokenTypeURNIDToken      = "urn:ietf:params:oauth:token-type:id_token"
+)
+
+// LookupTokenType returns the value of the `typ` header of the given
+// JWS message. Only the protected header is consulted, as the unprotected
+// header can be modified without invalidating the signature, and messages
+// that do not have exactly one signature are rejected. The signature is
+// NOT verified, so the returned value should only be used to decide how
+// to further process the token.
+func LookupTokenType(data []byte) (string, error) {
+	msg, err := jws.Parse(data)
+	if err != nil {
+		return "", errors.Wrap(err, `failed to parse jws message`)
+	}
+
+	sigs := msg.Signatures()
+	if len(sigs) != 1 {
+		return "", errors.Errorf(`jws message must have exactly one signature (got %d)`, len(sigs))
+	}
+
+	if hdrs := sigs[0].ProtectedHeaders(); hdrs != nil {
+		return hdrs.Type(), nil
+	}
+	return "", nil
+}
+
+// tokenTypeMatches compares two values for the `typ` header.
+// As described in RFC7515 section 4.1.9, the comparison is case insensitive,
+// and the "application/" prefix is ignored.
+func tokenTypeMatches(expected, actual string) bool {
+	const prefix = "application/"
+	if len(expected) > len(prefix) && strings.EqualFold(expected[:len(prefix)], prefix) {
+		expected = expected[len(prefix):]
+	}
+	if len(actual) > len(prefix) && strings.EqualFold(actual[:len(prefix)], prefix) {
+		actual = actual[len(prefix):]
+	}
+	return strings.EqualFold(expected, actual)
+}
+
+func verifyTokenType(data []byte, expected string) error {
+	typ, err := LookupTokenType(data)
+	if err != nil {
+		return errors.Wrap(err, `failed to lookup token type`)
+	}
+
+	if !tokenTypeMatches(expected, typ) {
+		return errors.Errorf(`token type mismatch: expected %#v, got %#v`, expected, typ)
+	}
+	return nil
+}
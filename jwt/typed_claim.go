@@ -0,0 +1,49 @@
+package jwt
+
+import (
+	"reflect"
+
+	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/pkg/errors"
+)
+
+type typedClaim struct {
+	name string
+	typ  reflect.Type
+}
+
+// decodeTypedClaims decodes the claims in `claims` from the JSON
+// payload into values of their respective types, and replaces the
+// generic values (e.g. map[string]interface{}) stored in the token.
+func decodeTypedClaims(t Token, payload []byte, claims []typedClaim) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return errors.Wrap(err, `failed to unmarshal payload`)
+	}
+
+	for _, claim := range claims {
+		for _, name := range registeredClaims {
+			if claim.name == name {
+				return errors.Errorf(`registered claim %q can not be decoded as a typed claim`, claim.name)
+			}
+		}
+
+		src, ok := raw[claim.name]
+		if !ok {
+			continue
+		}
+
+		if claim.typ == nil {
+			return errors.Errorf(`invalid type for claim %q`, claim.name)
+		}
+
+		ptr := reflect.New(claim.typ)
+		if err := json.Unmarshal(src, ptr.Interface()); err != nil {
+			return errors.Wrapf(err, `failed to decode claim %q into %s`, claim.name, claim.typ)
+		}
+		if err := t.Set(claim.name, ptr.Elem().Interface()); err != nil {
+			return errors.Wrapf(err, `failed to set claim %q`, claim.name)
+		}
+	}
+	return nil
+}
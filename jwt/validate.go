@@ -1,11 +1,57 @@
 package jwt
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
 	"time"
 )
 
+// maxNumericDate is the largest NumericDate accepted in strict mode
+// (9999-12-31T23:59:59Z), which is also the largest value that can be
+// formatted as an RFC 3339 timestamp
+const maxNumericDate = 253402300799
+
+// Validator describes an additional check that is performed by
+// `jwt.Validate()` after the standard checks. See `jwt.WithValidator()`
+type Validator interface {
+	Validate(Token) error
+}
+
+// ValidatorFunc is a Validator represented by a function, which allows
+// ad-hoc checks to be passed to `jwt.WithValidator()` without declaring
+// a new type
+type ValidatorFunc func(Token) error
+
+func (f ValidatorFunc) Validate(t Token) error {
+	return f(t)
+}
+
+// ContextValidator is a Validator that receives the context given by
+// `jwt.WithContext()` (or `jwt.ParseContext()` and `jwt.ValidateContext()`),
+// for checks that should honor cancellation and deadlines, such as those
+// that consult a remote service. When a Validator passed to
+// `jwt.WithValidator()` implements this interface, `ValidateContext()`
+// is called instead of `Validate()`.
+type ContextValidator interface {
+	Validator
+	ValidateContext(context.Context, Token) error
+}
+
+// ContextValidatorFunc is a ContextValidator represented by a function.
+// When called through `Validate()`, `context.Background()` is used.
+type ContextValidatorFunc func(context.Context, Token) error
+
+func (f ContextValidatorFunc) Validate(t Token) error {
+	return f(context.Background(), t)
+}
+
+func (f ContextValidatorFunc) ValidateContext(ctx context.Context, t Token) error {
+	return f(ctx, t)
+}
+
 type Clock interface {
 	Now() time.Time
 }
@@ -19,52 +65,156 @@ func (f ClockFunc) Now() time.Time {
 //
 // See the various `WithXXX` functions for optional parameters
 // that can control the behavior of this method.
+//
+// By default, the first failure is returned. Specify the
+// `jwt.WithMultipleErrors(true)` option to collect all failures.
+//
+// Validate does not modify the token, so it may be called on the same
+// parsed token any number of times with different options. See also
+// `jwt.ValidateAll()`
 func Validate(t Token, options ...ValidateOption) error {
+	return validate(t, options...)
+}
+
+// ValidateContext validates the token in the same way as `jwt.Validate()`,
+// passing ctx to the stores and validators that are used. It is
+// equivalent to passing `jwt.WithContext(ctx)`. The error of the context
+// is returned if it is canceled or its deadline expires before
+// validation completes.
+func ValidateContext(ctx context.Context, t Token, options ...ValidateOption) error {
+	return validate(t, append(options[:len(options):len(options)], WithContext(ctx))...)
+}
+
+func validate(t Token, options ...ValidateOption) error {
 	var issuer string
 	var subject string
 	var audience string
 	var jwtid string
 	var clock Clock = ClockFunc(time.Now)
 	var skew time.Duration
-	claimValues := make(map[string]interface{})
+	skewFor := make(map[string]time.Duration)
+	var claimValues []claimValue
+	var requiredClaims []string
+	var audienceAll []string
+	var deltas []delta
+	var prohibitedClaims []string
+	var prohibitedValues []claimValue
+	var bindingKeys [][]interface{}
+	var protectedKeys []protectedClaimsKey
+	var strict bool
+	var validators []Validator
+	var multiple bool
+	var jtiStore JtiStore
+	ctx := context.Background()
+	truncate := true
 	for _, o := range options {
 		switch o.Ident() {
+		case identTruncatedTimeComparison{}:
+			truncate = o.Value().(bool)
 		case identClock{}:
 			clock = o.Value().(Clock)
 		case identAcceptableSkew{}:
 			skew = o.Value().(time.Duration)
+		case identSkewFor{}:
+			cs := o.Value().(claimSkew)
+			skewFor[cs.claim] = cs.skew
 		case identIssuer{}:
 			issuer = o.Value().(string)
 		case identSubject{}:
 			subject = o.Value().(string)
 		case identAudience{}:
 			audience = o.Value().(string)
+		case identAudienceAll{}:
+			audienceAll = append(audienceAll, o.Value().([]string)...)
 		case identJwtid{}:
 			jwtid = o.Value().(string)
 		case identClaim{}:
+			// Checked in the order in which they were specified, so that
+			// errors are reported consistently. A later value for the
+			// same claim replaces the earlier one.
 			claim := o.Value().(claimValue)
-			claimValues[claim.name] = claim.value
+			replaced := false
+			for i, cv := range claimValues {
+				if cv.name == claim.name {
+					claimValues[i] = claim
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				claimValues = append(claimValues, claim)
+			}
+		case identMaxDelta{}:
+			deltas = append(deltas, o.Value().(delta))
+		case identRequiredClaim{}:
+			requiredClaims = append(requiredClaims, o.Value().(string))
+		case identProhibitedClaim{}:
+			prohibitedClaims = append(prohibitedClaims, o.Value().([]string)...)
+		case identProhibitedClaimValue{}:
+			prohibitedValues = append(prohibitedValues, o.Value().(claimValue))
+		case identKeyBinding{}:
+			bindingKeys = append(bindingKeys, o.Value().([]interface{}))
+		case identProtectedClaims{}:
+			protectedKeys = append(protectedKeys, o.Value().(protectedClaimsKey))
+		case identStrictClaims{}:
+			strict = o.Value().(bool)
+		case identValidator{}:
+			validators = append(validators, o.Value().(Validator))
+		case identMultipleErrors{}:
+			multiple = o.Value().(bool)
+		case identJtiStore{}:
+			jtiStore = o.Value().(JtiStore)
+		case identContext{}:
+			ctx = o.Value().(context.Context)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	errs := errorCollector{multiple: multiple}
+
+	if strict {
+		if err := validateStrictClaims(t); err != nil {
+			if errs.add(err) {
+				return errs.err()
+			}
+		}
+	}
+
+	for _, name := range requiredClaims {
+		if _, ok := t.Get(name); !ok {
+			if errs.add(newValidationError(ErrMissingClaim, name, fmt.Sprintf(`required claim %s is missing`, name), nil)) {
+				return errs.err()
+			}
 		}
 	}
 
 	// check for iss
 	if len(issuer) > 0 {
 		if v := t.Issuer(); v != "" && v != issuer {
-			return errors.New(`iss not satisfied`)
+			if errs.add(claimNotSatisfied(ErrInvalidIssuer, IssuerKey)) {
+				return errs.err()
+			}
 		}
 	}
 
 	// check for jti
 	if len(jwtid) > 0 {
 		if v := t.JwtID(); v != "" && v != jwtid {
-			return errors.New(`jti not satisfied`)
+			if errs.add(claimNotSatisfied(ErrInvalidJwtID, JwtIDKey)) {
+				return errs.err()
+			}
 		}
 	}
 
 	// check for sub
 	if len(subject) > 0 {
 		if v := t.Subject(); v != "" && v != subject {
-			return errors.New(`sub not satisfied`)
+			if errs.add(claimNotSatisfied(ErrInvalidSubject, SubjectKey)) {
+				return errs.err()
+			}
 		}
 	}
 
@@ -78,43 +228,296 @@ func Validate(t Token, options ...ValidateOption) error {
 			}
 		}
 		if !found {
-			return errors.New(`aud not satisfied`)
+			if errs.add(claimNotSatisfied(ErrInvalidAudience, AudienceKey)) {
+				return errs.err()
+			}
 		}
 	}
 
+	for _, expected := range audienceAll {
+		var found bool
+		for _, v := range t.Audience() {
+			if v == expected {
+				found = true
+				break
+			}
+		}
+		if !found {
+			if errs.add(newValidationError(ErrInvalidAudience, AudienceKey, fmt.Sprintf(`%s does not contain %q`, AudienceKey, expected), nil)) {
+				return errs.err()
+			}
+		}
+	}
+
+	// skewOf returns the acceptable skew for the given claim
+	skewOf := func(claim string) time.Duration {
+		if v, ok := skewFor[claim]; ok {
+			return v
+		}
+		return skew
+	}
+
 	// check for exp
 	if tv := t.Expiration(); !tv.IsZero() {
-		now := clock.Now().Truncate(time.Second)
-		ttv := tv.Truncate(time.Second)
-		if !now.Before(ttv.Add(skew)) {
-			return errors.New(`exp not satisfied`)
+		now := timeForComparison(clock.Now(), truncate)
+		ttv := timeForComparison(tv, truncate)
+		if !now.Before(ttv.Add(skewOf(ExpirationKey))) {
+			if errs.add(claimNotSatisfied(ErrTokenExpired, ExpirationKey)) {
+				return errs.err()
+			}
 		}
 	}
 
 	// check for iat
 	if tv := t.IssuedAt(); !tv.IsZero() {
-		now := clock.Now().Truncate(time.Second)
-		ttv := tv.Truncate(time.Second)
-		if now.Before(ttv.Add(-1 * skew)) {
-			return errors.New(`iat not satisfied`)
+		now := timeForComparison(clock.Now(), truncate)
+		ttv := timeForComparison(tv, truncate)
+		if now.Before(ttv.Add(-1 * skewOf(IssuedAtKey))) {
+			if errs.add(claimNotSatisfied(ErrInvalidIssuedAt, IssuedAtKey)) {
+				return errs.err()
+			}
 		}
 	}
 
 	// check for nbf
 	if tv := t.NotBefore(); !tv.IsZero() {
-		now := clock.Now().Truncate(time.Second)
-		ttv := tv.Truncate(time.Second)
+		now := timeForComparison(clock.Now(), truncate)
+		ttv := timeForComparison(tv, truncate)
 		// now cannot be before t, so we check for now > t - skew
-		if !now.After(ttv.Add(-1 * skew)) {
-			return errors.New(`nbf not satisfied`)
+		if !now.After(ttv.Add(-1 * skewOf(NotBeforeKey))) {
+			if errs.add(claimNotSatisfied(ErrTokenNotYetValid, NotBeforeKey)) {
+				return errs.err()
+			}
+		}
+	}
+
+	for _, d := range deltas {
+		if err := validateDelta(t, d, clock, truncate); err != nil {
+			if errs.add(err) {
+				return errs.err()
+			}
+		}
+	}
+
+	for _, expected := range claimValues {
+		if v, ok := t.Get(expected.name); !ok || !claimValueEqual(v, expected.value, truncate) {
+			if errs.add(claimNotSatisfied(ErrInvalidClaimValue, expected.name)) {
+				return errs.err()
+			}
+		}
+	}
+
+	for _, name := range prohibitedClaims {
+		if _, ok := t.Get(name); ok {
+			if errs.add(newValidationError(ErrProhibitedClaim, name, fmt.Sprintf(`%v is prohibited`, name), nil)) {
+				return errs.err()
+			}
+		}
+	}
+
+	for _, prohibited := range prohibitedValues {
+		if v, ok := t.Get(prohibited.name); ok && claimValueMatches(v, prohibited.value) {
+			if errs.add(newValidationError(ErrProhibitedClaim, prohibited.name, fmt.Sprintf(`%v contains a prohibited value`, prohibited.name), nil)) {
+				return errs.err()
+			}
 		}
 	}
 
-	for name, expectedValue := range claimValues {
-		if v, ok := t.Get(name); !ok || v != expectedValue {
-			return fmt.Errorf(`%v not satisfied`, name)
+	for _, keys := range bindingKeys {
+		if err := verifyAnyKeyBinding(t, keys); err != nil {
+			if errs.add(newValidationError(ErrInvalidKeyBinding, ConfirmationKey, fmt.Sprintf(`cnf not satisfied: %s`, err), err)) {
+				return errs.err()
+			}
+		}
+	}
+
+	for _, pk := range protectedKeys {
+		if _, ok := t.Get(ClaimsDigestKey); !ok {
+			if errs.add(newValidationError(ErrMissingClaim, ClaimsDigestKey, fmt.Sprintf(`required claim %s is missing`, ClaimsDigestKey), nil)) {
+				return errs.err()
+			}
+			continue
+		}
+		if _, err := VerifyProtectedClaims(t, pk.alg, pk.key); err != nil {
+			if errs.add(newValidationError(ErrClaimsModified, ClaimsDigestKey, fmt.Sprintf(`%s not satisfied: %s`, ClaimsDigestKey, err), err)) {
+				return errs.err()
+			}
+		}
+	}
+
+	for _, v := range validators {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var err error
+		if cv, ok := v.(ContextValidator); ok {
+			err = cv.ValidateContext(ctx, t)
+		} else {
+			err = v.Validate(t)
+		}
+		if err != nil {
+			if errs.add(err) {
+				return errs.err()
+			}
 		}
 	}
 
+	// This must come last, so that the "jti" is only recorded for tokens
+	// that are otherwise valid
+	if jtiStore != nil && len(errs.errs) == 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// The token is accepted until "exp" plus the skew, and up to a
+		// second longer when the times are truncated
+		lifetime := skewOf(ExpirationKey)
+		if truncate {
+			lifetime += time.Second
+		}
+		if err := checkReplay(ctx, jtiStore, t, clock, lifetime); err != nil {
+			errs.add(err)
+		}
+	}
+
+	return errs.err()
+}
+
+// validateDelta checks that the difference between the claims given by
+// `jwt.WithMaxDelta()` is within the limit
+func validateDelta(t Token, d delta, clock Clock, truncate bool) error {
+	claim := d.c1
+	if claim == "" {
+		claim = d.c2
+	}
+
+	t1, err := deltaTime(t, d.c1, clock)
+	if err != nil {
+		return err
+	}
+	t2, err := deltaTime(t, d.c2, clock)
+	if err != nil {
+		return err
+	}
+
+	diff := timeForComparison(t1, truncate).Sub(timeForComparison(t2, truncate))
+	if diff > d.max {
+		return newValidationError(ErrMaxDeltaExceeded, claim, fmt.Sprintf(`delta between %s and %s exceeds %s`, deltaName(d.c1), deltaName(d.c2), d.max), nil)
+	}
 	return nil
 }
+
+func deltaTime(t Token, name string, clock Clock) (time.Time, error) {
+	if name == "" {
+		return clock.Now(), nil
+	}
+
+	v, ok := t.Get(name)
+	if !ok {
+		return time.Time{}, newValidationError(ErrMissingClaim, name, fmt.Sprintf(`required claim %s is missing`, name), nil)
+	}
+	tv, err := timeFromMapValue(v)
+	if err != nil {
+		return time.Time{}, newValidationError(ErrInvalidClaimFormat, name, fmt.Sprintf(`%s is not a valid time: %s`, name, err), err)
+	}
+	return tv, nil
+}
+
+func deltaName(name string) string {
+	if name == "" {
+		return "now"
+	}
+	return name
+}
+
+// timeForComparison truncates the time to the resolution of NumericDate
+// (seconds) if `truncate` is true
+func timeForComparison(tv time.Time, truncate bool) time.Time {
+	if truncate {
+		return tv.Truncate(time.Second)
+	}
+	return tv
+}
+
+// claimValueEqual compares the value of a claim against the expected
+// value given via `jwt.WithClaimValue()`. time.Time values are compared
+// as instants, at the same resolution as the exp/iat/nbf checks
+func claimValueEqual(actual, expected interface{}, truncate bool) bool {
+	if at, ok := actual.(time.Time); ok {
+		if et, ok := expected.(time.Time); ok {
+			return timeForComparison(at, truncate).Equal(timeForComparison(et, truncate))
+		}
+	}
+	return actual == expected
+}
+
+// validateStrictClaims checks that the registered claims conform to the
+// types defined in RFC 7519 section 2
+func validateStrictClaims(t Token) error {
+	if err := validateStringOrURI(IssuerKey, t.Issuer()); err != nil {
+		return err
+	}
+	if err := validateStringOrURI(SubjectKey, t.Subject()); err != nil {
+		return err
+	}
+	for _, v := range t.Audience() {
+		if err := validateStringOrURI(AudienceKey, v); err != nil {
+			return err
+		}
+	}
+
+	for _, claim := range []struct {
+		name  string
+		value time.Time
+	}{
+		{ExpirationKey, t.Expiration()},
+		{IssuedAtKey, t.IssuedAt()},
+		{NotBeforeKey, t.NotBefore()},
+	} {
+		if claim.value.IsZero() {
+			continue
+		}
+		if v := claim.value.Unix(); v < 0 || v > maxNumericDate {
+			return newValidationError(ErrInvalidClaimFormat, claim.name, fmt.Sprintf(`%s is not a valid NumericDate: %d is out of range`, claim.name, v), nil)
+		}
+	}
+	return nil
+}
+
+// validateStringOrURI checks that v is a StringOrURI as defined in
+// RFC 7519: any string is allowed, but a value that contains a ":"
+// must be an absolute URI
+func validateStringOrURI(name, v string) error {
+	if !strings.Contains(v, ":") {
+		return nil
+	}
+
+	u, err := url.Parse(v)
+	if err != nil {
+		return newValidationError(ErrInvalidClaimFormat, name, fmt.Sprintf(`%s is not a valid StringOrURI: %s`, name, err), err)
+	}
+	if u.Scheme == "" {
+		return newValidationError(ErrInvalidClaimFormat, name, fmt.Sprintf(`%s is not a valid StringOrURI: %q contains ":" but is not an absolute URI`, name, v), nil)
+	}
+	return nil
+}
+
+// claimValueMatches returns true if v is equal to target, or if v is
+// a list and one of its elements is equal to target
+func claimValueMatches(v, target interface{}) bool {
+	if reflect.DeepEqual(v, target) {
+		return true
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return false
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if reflect.DeepEqual(rv.Index(i).Interface(), target) {
+			return true
+		}
+	}
+	return false
+}
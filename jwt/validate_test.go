@@ -1,10 +1,19 @@
 package jwt_test
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/lestrrat-go/jwx/internal/json"
+	"github.com/lestrrat-go/jwx/internal/jwxtest"
+	"github.com/lestrrat-go/jwx/jwa"
 	"github.com/lestrrat-go/jwx/jwt"
 	"github.com/stretchr/testify/assert"
 )
@@ -198,3 +207,1194 @@ func TestGHIssue10(t *testing.T) {
 		}
 	})
 }
+
+func TestRequiredClaim(t *testing.T) {
+	t.Parallel()
+
+	tok := jwt.New()
+	tok.Set(jwt.SubjectKey, "github.com/lestrrat-go/jwx")
+	tok.Set("role", "")
+
+	if !assert.NoError(t, jwt.Validate(tok, jwt.WithRequiredClaim(jwt.SubjectKey), jwt.WithRequiredClaim("role")), `jwt.Validate should succeed`) {
+		return
+	}
+
+	err := jwt.Validate(tok, jwt.WithRequiredClaim(jwt.SubjectKey), jwt.WithRequiredClaim(jwt.JwtIDKey))
+	if !assert.Error(t, err, `jwt.Validate should fail`) {
+		return
+	}
+	if !assert.True(t, errors.Is(err, jwt.ErrMissingClaim), `error should be ErrMissingClaim`) {
+		return
+	}
+	var verr *jwt.ValidationError
+	if !assert.True(t, errors.As(err, &verr), `error should be a ValidationError`) {
+		return
+	}
+	if !assert.Equal(t, jwt.JwtIDKey, verr.Claim, `claim should be jti`) {
+		return
+	}
+}
+
+func TestMaxDelta(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1600000000, 0)
+	clock := jwt.ClockFunc(func() time.Time { return now })
+
+	tok := jwt.New()
+	tok.Set(jwt.IssuedAtKey, now.Add(-2*time.Hour))
+	tok.Set(jwt.ExpirationKey, now.Add(time.Hour))
+	tok.Set("auth_time", float64(now.Add(-30*time.Minute).Unix()))
+
+	testcases := []struct {
+		Name     string
+		Option   jwt.ValidateOption
+		Category error
+	}{
+		{
+			Name:   "iat within limit",
+			Option: jwt.WithMaxDelta(3*time.Hour, "", jwt.IssuedAtKey),
+		},
+		{
+			Name:     "iat too old",
+			Option:   jwt.WithMaxDelta(time.Hour, "", jwt.IssuedAtKey),
+			Category: jwt.ErrMaxDeltaExceeded,
+		},
+		{
+			Name:   "exp - iat within limit",
+			Option: jwt.WithMaxDelta(3*time.Hour, jwt.ExpirationKey, jwt.IssuedAtKey),
+		},
+		{
+			Name:     "exp - iat exceeds limit",
+			Option:   jwt.WithMaxDelta(2*time.Hour, jwt.ExpirationKey, jwt.IssuedAtKey),
+			Category: jwt.ErrMaxDeltaExceeded,
+		},
+		{
+			Name:   "private NumericDate claim",
+			Option: jwt.WithMaxDelta(time.Hour, "", "auth_time"),
+		},
+		{
+			Name:     "missing claim",
+			Option:   jwt.WithMaxDelta(time.Hour, "", jwt.NotBeforeKey),
+			Category: jwt.ErrMissingClaim,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			err := jwt.Validate(tok, jwt.WithClock(clock), tc.Option)
+			if tc.Category == nil {
+				assert.NoError(t, err, `jwt.Validate should succeed`)
+				return
+			}
+			if !assert.Error(t, err, `jwt.Validate should fail`) {
+				return
+			}
+			assert.True(t, errors.Is(err, tc.Category), `error should be %s`, tc.Category)
+		})
+	}
+}
+
+func TestSkewFor(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().Truncate(time.Second)
+	clock := jwt.ClockFunc(func() time.Time { return now })
+
+	// Expired 10 seconds ago, and not valid for another minute
+	t1 := jwt.New()
+	_ = t1.Set(jwt.ExpirationKey, now.Add(-10*time.Second))
+	t2 := jwt.New()
+	_ = t2.Set(jwt.NotBeforeKey, now.Add(time.Minute))
+
+	testcases := []struct {
+		Name    string
+		Token   jwt.Token
+		Options []jwt.ValidateOption
+		Error   error
+	}{
+		{
+			Name:    "exp within claim skew",
+			Token:   t1,
+			Options: []jwt.ValidateOption{jwt.WithSkewFor(jwt.ExpirationKey, 30*time.Second)},
+		},
+		{
+			Name:    "exp outside claim skew",
+			Token:   t1,
+			Options: []jwt.ValidateOption{jwt.WithSkewFor(jwt.ExpirationKey, 5*time.Second)},
+			Error:   jwt.ErrTokenExpired,
+		},
+		{
+			Name:    "claim skew takes precedence",
+			Token:   t1,
+			Options: []jwt.ValidateOption{jwt.WithSkewFor(jwt.ExpirationKey, 5*time.Second), jwt.WithAcceptableSkew(time.Hour)},
+			Error:   jwt.ErrTokenExpired,
+		},
+		{
+			Name:    "skew for another claim does not apply",
+			Token:   t1,
+			Options: []jwt.ValidateOption{jwt.WithSkewFor(jwt.NotBeforeKey, time.Hour)},
+			Error:   jwt.ErrTokenExpired,
+		},
+		{
+			Name:    "nbf within claim skew",
+			Token:   t2,
+			Options: []jwt.ValidateOption{jwt.WithSkewFor(jwt.NotBeforeKey, 5*time.Minute), jwt.WithSkewFor(jwt.ExpirationKey, 0)},
+		},
+		{
+			Name:    "nbf falls back to acceptable skew",
+			Token:   t2,
+			Options: []jwt.ValidateOption{jwt.WithSkewFor(jwt.ExpirationKey, 0), jwt.WithAcceptableSkew(30 * time.Second)},
+			Error:   jwt.ErrTokenNotYetValid,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			err := jwt.Validate(tc.Token, append(tc.Options, jwt.WithClock(clock))...)
+			if tc.Error == nil {
+				if !assert.NoError(t, err, `jwt.Validate should succeed`) {
+					return
+				}
+				return
+			}
+			if !assert.True(t, errors.Is(err, tc.Error), `jwt.Validate should fail with %s (got %v)`, tc.Error, err) {
+				return
+			}
+		})
+	}
+}
+
+func TestMultipleErrors(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1600000000, 0)
+	tok := jwt.New()
+	tok.Set(jwt.IssuerKey, "github.com/lestrrat-go/jwx")
+	tok.Set(jwt.ExpirationKey, now.Add(-time.Hour))
+	tok.Set("role", "admin")
+
+	options := []jwt.ValidateOption{
+		jwt.WithClock(jwt.ClockFunc(func() time.Time { return now })),
+		jwt.WithIssuer("github.com/lestrrat-go"),
+		jwt.WithRequiredClaim(jwt.SubjectKey),
+		jwt.WithProhibitedClaims("role"),
+	}
+
+	// By default, only the first failure is reported
+	err := jwt.Validate(tok, options...)
+	if !assert.Error(t, err, `jwt.Validate should fail`) {
+		return
+	}
+	var verr *jwt.ValidationError
+	if !assert.True(t, errors.As(err, &verr), `error should be a ValidationError`) {
+		return
+	}
+	if !assert.Equal(t, jwt.SubjectKey, verr.Claim, `first failure should be reported`) {
+		return
+	}
+
+	err = jwt.Validate(tok, append(options, jwt.WithMultipleErrors(true))...)
+	if !assert.Error(t, err, `jwt.Validate should fail`) {
+		return
+	}
+	var errs jwt.ValidationErrors
+	if !assert.True(t, errors.As(err, &errs), `error should be ValidationErrors`) {
+		return
+	}
+	if !assert.Len(t, errs, 4, `all failures should be reported`) {
+		return
+	}
+	for _, category := range []error{jwt.ErrMissingClaim, jwt.ErrInvalidIssuer, jwt.ErrTokenExpired, jwt.ErrProhibitedClaim} {
+		if !assert.True(t, errors.Is(err, category), `error should match %s`, category) {
+			return
+		}
+	}
+	if !assert.False(t, errors.Is(err, jwt.ErrInvalidAudience), `error should not match ErrInvalidAudience`) {
+		return
+	}
+	if !assert.Contains(t, err.Error(), `exp not satisfied`, `message should list failures`) {
+		return
+	}
+
+	// Claim values are checked in the order in which they were specified
+	claimOptions := []jwt.ValidateOption{jwt.WithMultipleErrors(true)}
+	names := []string{"zone", "tier", "region", "plan", "group", "app"}
+	for _, name := range names {
+		claimOptions = append(claimOptions, jwt.WithClaimValue(name, "expected"))
+	}
+	for i := 0; i < 10; i++ {
+		err := jwt.Validate(jwt.New(), claimOptions...)
+		var errs jwt.ValidationErrors
+		if !assert.True(t, errors.As(err, &errs), `error should be ValidationErrors`) {
+			return
+		}
+		var claims []string
+		for _, e := range errs {
+			var verr *jwt.ValidationError
+			if !assert.True(t, errors.As(e, &verr), `error should be a ValidationError`) {
+				return
+			}
+			claims = append(claims, verr.Claim)
+		}
+		if !assert.Equal(t, names, claims, `failures should be reported in order`) {
+			return
+		}
+	}
+
+	tok.Set(jwt.SubjectKey, "alice")
+	tok.Set(jwt.IssuerKey, "github.com/lestrrat-go")
+	tok.Set(jwt.ExpirationKey, now.Add(time.Hour))
+	tok.Remove("role")
+	if !assert.NoError(t, jwt.Validate(tok, append(options, jwt.WithMultipleErrors(true))...), `jwt.Validate should succeed`) {
+		return
+	}
+}
+
+func TestProhibitedClaims(t *testing.T) {
+	t.Parallel()
+
+	tok := jwt.New()
+	tok.Set(jwt.AudienceKey, []string{"foo", "bar"})
+	tok.Set("role", "admin")
+
+	t.Run("WithProhibitedClaims", func(t *testing.T) {
+		t.Parallel()
+		if !assert.NoError(t, jwt.Validate(tok, jwt.WithProhibitedClaims("password", "secret")), `jwt.Validate should succeed`) {
+			return
+		}
+		if !assert.Error(t, jwt.Validate(tok, jwt.WithProhibitedClaims("password", "role")), `jwt.Validate should fail`) {
+			return
+		}
+	})
+	t.Run("WithProhibitedClaimValue", func(t *testing.T) {
+		t.Parallel()
+		if !assert.NoError(t, jwt.Validate(tok, jwt.WithProhibitedClaimValue("role", "root"), jwt.WithProhibitedClaimValue(jwt.AudienceKey, "baz")), `jwt.Validate should succeed`) {
+			return
+		}
+		if !assert.Error(t, jwt.Validate(tok, jwt.WithProhibitedClaimValue("role", "admin")), `jwt.Validate should fail`) {
+			return
+		}
+		if !assert.Error(t, jwt.Validate(tok, jwt.WithProhibitedClaimValue(jwt.AudienceKey, "bar")), `jwt.Validate should fail for list elements`) {
+			return
+		}
+	})
+}
+
+func TestKeyBinding(t *testing.T) {
+	t.Parallel()
+
+	presenter, err := jwxtest.GenerateEcdsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`) {
+		return
+	}
+	other, err := jwxtest.GenerateEcdsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`) {
+		return
+	}
+	signingKey := jwxtest.GenerateSymmetricKey()
+
+	tok := jwt.New()
+	tok.Set(jwt.ConfirmationKey, map[string]interface{}{"kid": "presenter"})
+	if !assert.NoError(t, jwt.BindToKey(tok, presenter), `jwt.BindToKey should succeed`) {
+		return
+	}
+
+	signed, err := jwt.Sign(tok, jwa.HS256, signingKey)
+	if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+		return
+	}
+
+	parsed, err := jwt.Parse(signed, jwt.WithVerify(jwa.HS256, signingKey))
+	if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+		return
+	}
+
+	cnf, ok := parsed.Get(jwt.ConfirmationKey)
+	if !assert.True(t, ok, `cnf claim should exist`) {
+		return
+	}
+	if !assert.Equal(t, "presenter", cnf.(map[string]interface{})["kid"], `existing cnf members should be preserved`) {
+		return
+	}
+
+	if !assert.NoError(t, jwt.Validate(parsed, jwt.WithKeyBinding(&presenter.PublicKey)), `jwt.Validate should succeed`) {
+		return
+	}
+	if !assert.Error(t, jwt.Validate(parsed, jwt.WithKeyBinding(&other.PublicKey)), `jwt.Validate should fail for other keys`) {
+		return
+	}
+	if !assert.Error(t, jwt.Validate(jwt.New(), jwt.WithKeyBinding(&presenter.PublicKey)), `jwt.Validate should fail without cnf`) {
+		return
+	}
+}
+
+func TestPoP(t *testing.T) {
+	t.Parallel()
+
+	presenter, err := jwxtest.GenerateEcdsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`) {
+		return
+	}
+	other, err := jwxtest.GenerateEcdsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`) {
+		return
+	}
+	signingKey := jwxtest.GenerateSymmetricKey()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &presenter.PublicKey, presenter)
+	if !assert.NoError(t, err, `x509.CreateCertificate should succeed`) {
+		return
+	}
+	cert, err := x509.ParseCertificate(der)
+	if !assert.NoError(t, err, `x509.ParseCertificate should succeed`) {
+		return
+	}
+
+	signAndParse := func(t *testing.T, tok jwt.Token, options ...jwt.Option) jwt.Token {
+		t.Helper()
+		signed, err := jwt.Sign(tok, jwa.HS256, signingKey, options...)
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			t.FailNow()
+		}
+		parsed, err := jwt.Parse(signed, jwt.WithVerify(jwa.HS256, signingKey))
+		if !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			t.FailNow()
+		}
+		return parsed
+	}
+
+	t.Run("Embedded key", func(t *testing.T) {
+		t.Parallel()
+		tok := jwt.New()
+		parsed := signAndParse(t, tok, jwt.WithConfirmationKey(presenter))
+		if _, ok := tok.Get(jwt.ConfirmationKey); !assert.False(t, ok, `original token should not be modified`) {
+			return
+		}
+
+		cnf, err := jwt.LookupConfirmation(parsed)
+		if !assert.NoError(t, err, `jwt.LookupConfirmation should succeed`) {
+			return
+		}
+		if !assert.NotNil(t, cnf.Key, `jwk member should exist`) {
+			return
+		}
+		if _, ok := cnf.Key.Get("d"); !assert.False(t, ok, `jwk member should not contain the private key`) {
+			return
+		}
+
+		if !assert.NoError(t, jwt.Validate(parsed, jwt.WithPoP(&presenter.PublicKey)), `jwt.Validate should succeed`) {
+			return
+		}
+		if !assert.NoError(t, jwt.Validate(parsed, jwt.WithPoP(cert)), `jwt.Validate should succeed for a certificate with the same key`) {
+			return
+		}
+		err = jwt.Validate(parsed, jwt.WithPoP(&other.PublicKey))
+		if !assert.True(t, errors.Is(err, jwt.ErrInvalidKeyBinding), `jwt.Validate should fail for other keys`) {
+			return
+		}
+	})
+	t.Run("Thumbprint", func(t *testing.T) {
+		t.Parallel()
+		tok := jwt.New()
+		tok.Set(jwt.ConfirmationKey, &jwt.Confirmation{KeyID: "presenter"})
+		parsed := signAndParse(t, tok, jwt.WithConfirmationThumbprint(presenter))
+
+		cnf, err := jwt.LookupConfirmation(parsed)
+		if !assert.NoError(t, err, `jwt.LookupConfirmation should succeed`) {
+			return
+		}
+		if !assert.Equal(t, "presenter", cnf.KeyID, `existing cnf members should be preserved`) {
+			return
+		}
+		if !assert.NotEmpty(t, cnf.KeyThumbprint, `jkt member should exist`) {
+			return
+		}
+
+		if !assert.NoError(t, jwt.Validate(parsed, jwt.WithPoP(&presenter.PublicKey)), `jwt.Validate should succeed`) {
+			return
+		}
+		if !assert.Error(t, jwt.Validate(parsed, jwt.WithPoP(&other.PublicKey)), `jwt.Validate should fail for other keys`) {
+			return
+		}
+	})
+	t.Run("Certificate", func(t *testing.T) {
+		t.Parallel()
+		parsed := signAndParse(t, jwt.New(), jwt.WithConfirmationCertificate(cert))
+
+		cnf, err := jwt.LookupConfirmation(parsed)
+		if !assert.NoError(t, err, `jwt.LookupConfirmation should succeed`) {
+			return
+		}
+		if !assert.NotEmpty(t, cnf.CertificateThumbprint, `x5t#S256 member should exist`) {
+			return
+		}
+
+		if !assert.NoError(t, jwt.Validate(parsed, jwt.WithPoP(cert)), `jwt.Validate should succeed`) {
+			return
+		}
+		if !assert.Error(t, jwt.Validate(parsed, jwt.WithPoP(&presenter.PublicKey)), `jwt.Validate should fail when the certificate is not presented`) {
+			return
+		}
+	})
+	t.Run("Multiple keys", func(t *testing.T) {
+		t.Parallel()
+		parsed := signAndParse(t, jwt.New(), jwt.WithConfirmationThumbprint(presenter), jwt.WithConfirmationCertificate(cert))
+
+		if !assert.NoError(t, jwt.Validate(parsed, jwt.WithKeyBinding(&other.PublicKey, cert)), `jwt.Validate should succeed when any of the keys match`) {
+			return
+		}
+		if !assert.NoError(t, jwt.Validate(parsed, jwt.WithKeyBinding(&presenter.PublicKey), jwt.WithKeyBinding(cert)), `jwt.Validate should succeed when each of the options is satisfied`) {
+			return
+		}
+		err := jwt.Validate(parsed, jwt.WithKeyBinding(&other.PublicKey, &other.PublicKey))
+		if !assert.True(t, errors.Is(err, jwt.ErrInvalidKeyBinding), `jwt.Validate should fail unless any of the keys match`) {
+			return
+		}
+		err = jwt.Validate(parsed, jwt.WithKeyBinding(cert), jwt.WithKeyBinding(&other.PublicKey))
+		if !assert.True(t, errors.Is(err, jwt.ErrInvalidKeyBinding), `jwt.Validate should fail unless each of the options is satisfied`) {
+			return
+		}
+		err = jwt.Validate(parsed, jwt.WithKeyBinding())
+		if !assert.True(t, errors.Is(err, jwt.ErrInvalidKeyBinding), `jwt.Validate should fail without keys`) {
+			return
+		}
+	})
+	t.Run("Missing cnf", func(t *testing.T) {
+		t.Parallel()
+		if !assert.Error(t, jwt.Validate(jwt.New(), jwt.WithPoP(&presenter.PublicKey)), `jwt.Validate should fail without cnf`) {
+			return
+		}
+		_, err := jwt.LookupConfirmation(jwt.New())
+		if !assert.Error(t, err, `jwt.LookupConfirmation should fail without cnf`) {
+			return
+		}
+	})
+	t.Run("Symmetric keys", func(t *testing.T) {
+		t.Parallel()
+		_, err := jwt.Sign(jwt.New(), jwa.HS256, signingKey, jwt.WithConfirmationKey(signingKey))
+		if !assert.Error(t, err, `jwt.Sign should fail`) {
+			return
+		}
+	})
+}
+
+func TestValidateAll(t *testing.T) {
+	t.Parallel()
+
+	tok := jwt.New()
+	tok.Set(jwt.IssuerKey, "https://accounts.example.com")
+	tok.Set(jwt.AudienceKey, []string{"api"})
+	tok.Set(jwt.SubjectKey, "service-account")
+
+	user := jwt.NewValidationPolicy("user", jwt.WithIssuer("https://accounts.example.com"), jwt.WithAudience("web"))
+	service := jwt.NewValidationPolicy("service", jwt.WithIssuer("https://accounts.example.com"), jwt.WithAudience("api"))
+	permissive := jwt.NewValidationPolicy("any")
+
+	passed, err := jwt.ValidateAll(tok, user, service, permissive)
+	if !assert.NoError(t, err, `jwt.ValidateAll should succeed`) {
+		return
+	}
+	if !assert.Equal(t, []string{"service", "any"}, passed, `passed policies should match`) {
+		return
+	}
+
+	_, err = jwt.ValidateAll(tok, user)
+	if !assert.Error(t, err, `jwt.ValidateAll should fail`) {
+		return
+	}
+	if !assert.Contains(t, err.Error(), `user: aud not satisfied`, `error should describe the failure`) {
+		return
+	}
+
+	_, err = jwt.ValidateAll(tok)
+	if !assert.Error(t, err, `jwt.ValidateAll should fail without policies`) {
+		return
+	}
+}
+
+func TestStrictClaims(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		Name  string
+		Claim string
+		Value interface{}
+		Error bool
+	}{
+		{Name: "Plain string", Claim: jwt.IssuerKey, Value: "issuer"},
+		{Name: "Absolute URI", Claim: jwt.IssuerKey, Value: "https://issuer.example.com"},
+		{Name: "URN", Claim: jwt.SubjectKey, Value: "urn:example:subject"},
+		{Name: "Colon in plain string", Claim: jwt.SubjectKey, Value: ":subject", Error: true},
+		{Name: "Invalid URI", Claim: jwt.IssuerKey, Value: "https://issuer example.com:port", Error: true},
+		{Name: "Invalid audience", Claim: jwt.AudienceKey, Value: []string{"https://aud.example.com", "1:2"}, Error: true},
+		{Name: "Valid NumericDate", Claim: jwt.IssuedAtKey, Value: time.Unix(1600000000, 0)},
+		{Name: "Negative NumericDate", Claim: jwt.NotBeforeKey, Value: time.Unix(-1, 0), Error: true},
+		{Name: "Overflowing NumericDate", Claim: jwt.ExpirationKey, Value: time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC), Error: true},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			tok := jwt.New()
+			if !assert.NoError(t, tok.Set(tc.Claim, tc.Value), `tok.Set should succeed`) {
+				return
+			}
+
+			// the permissive default must not change
+			clock := jwt.ClockFunc(func() time.Time { return time.Unix(1700000000, 0) })
+			if tc.Claim != jwt.ExpirationKey {
+				if !assert.NoError(t, jwt.Validate(tok, jwt.WithClock(clock)), `jwt.Validate should succeed`) {
+					return
+				}
+			}
+
+			err := jwt.Validate(tok, jwt.WithClock(clock), jwt.WithStrictClaims(true))
+			if tc.Error {
+				if !assert.Error(t, err, `jwt.Validate should fail`) {
+					return
+				}
+				assert.Contains(t, err.Error(), tc.Claim, `error should mention the claim`)
+				return
+			}
+			assert.NoError(t, err, `jwt.Validate should succeed`)
+		})
+	}
+}
+
+type maxLifetimeValidator time.Duration
+
+func (v maxLifetimeValidator) Validate(t jwt.Token) error {
+	if t.Expiration().Sub(t.IssuedAt()) > time.Duration(v) {
+		return errors.New(`token lifetime is too long`)
+	}
+	return nil
+}
+
+func TestWithValidator(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	tok := jwt.New()
+	_ = tok.Set(jwt.IssuedAtKey, now)
+	_ = tok.Set(jwt.ExpirationKey, now.Add(48*time.Hour))
+
+	if !assert.NoError(t, jwt.Validate(tok, jwt.WithValidator(maxLifetimeValidator(72*time.Hour))), `jwt.Validate should succeed`) {
+		return
+	}
+	if !assert.Error(t, jwt.Validate(tok, jwt.WithValidator(maxLifetimeValidator(72*time.Hour)), jwt.WithValidator(maxLifetimeValidator(24*time.Hour))), `jwt.Validate should fail`) {
+		return
+	}
+
+	signed, err := jwt.Sign(tok, jwa.HS256, []byte("abracadabra"))
+	if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+		return
+	}
+	_, err = jwt.Parse(signed, jwt.WithVerify(jwa.HS256, []byte("abracadabra")), jwt.WithValidate(true), jwt.WithValidator(maxLifetimeValidator(24*time.Hour)))
+	if !assert.Error(t, err, `jwt.Parse should fail`) {
+		return
+	}
+
+	t.Run("ValidatorFunc", func(t *testing.T) {
+		t.Parallel()
+		within24h := jwt.ValidatorFunc(func(t jwt.Token) error {
+			if t.Expiration().Sub(t.IssuedAt()) > 24*time.Hour {
+				return errors.New(`exp must be within 24h of iat`)
+			}
+			return nil
+		})
+
+		err := jwt.Validate(tok, jwt.WithValidator(within24h))
+		if !assert.Error(t, err, `jwt.Validate should fail`) {
+			return
+		}
+		if !assert.Equal(t, `exp must be within 24h of iat`, err.Error(), `error should be returned as is`) {
+			return
+		}
+
+		_, err = jwt.Parse(signed, jwt.WithVerify(jwa.HS256, []byte("abracadabra")), jwt.WithValidate(true), jwt.WithValidator(within24h))
+		if !assert.Error(t, err, `jwt.Parse should fail`) {
+			return
+		}
+
+		short := jwt.New()
+		_ = short.Set(jwt.IssuedAtKey, now)
+		_ = short.Set(jwt.ExpirationKey, now.Add(time.Hour))
+		if !assert.NoError(t, jwt.Validate(short, jwt.WithValidator(within24h)), `jwt.Validate should succeed`) {
+			return
+		}
+	})
+}
+
+func TestCompilePolicy(t *testing.T) {
+	t.Parallel()
+
+	const doc = `{
+  "schema": 1,
+  "name": "partner-api",
+  "version": "2021-06-01.2",
+  "issuers": ["https://accounts.example.com", "https://legacy.example.com"],
+  "audiences": ["partner-api"],
+  "algorithms": ["ES256"],
+  "required_claims": ["sub"],
+  "acceptable_skew": "30s"
+}`
+
+	policy, err := jwt.CompilePolicy([]byte(doc))
+	if !assert.NoError(t, err, `jwt.CompilePolicy should succeed`) {
+		return
+	}
+	if !assert.Equal(t, "partner-api", policy.Name(), `name should match`) {
+		return
+	}
+	if !assert.Equal(t, "2021-06-01.2", policy.Version(), `version should match`) {
+		return
+	}
+
+	newToken := func() jwt.Token {
+		tok := jwt.New()
+		tok.Set(jwt.IssuerKey, "https://legacy.example.com")
+		tok.Set(jwt.AudienceKey, []string{"other", "partner-api"})
+		tok.Set(jwt.SubjectKey, "partner-1")
+		tok.Set(jwt.ExpirationKey, time.Now().Add(-10*time.Second))
+		return tok
+	}
+
+	t.Run("Algorithms are not checked by Validate", func(t *testing.T) {
+		t.Parallel()
+		if !assert.Error(t, policy.Validate(newToken()), `policy.Validate should fail for policies that restrict the algorithms`) {
+			return
+		}
+		if _, err := jwt.ValidateAll(newToken(), policy); !assert.Error(t, err, `jwt.ValidateAll should fail for policies that restrict the algorithms`) {
+			return
+		}
+	})
+	t.Run("Validate", func(t *testing.T) {
+		t.Parallel()
+		policy, err := jwt.CompilePolicy([]byte(strings.Replace(doc, `"algorithms": ["ES256"],`, ``, 1)))
+		if !assert.NoError(t, err, `jwt.CompilePolicy should succeed`) {
+			return
+		}
+		if !assert.NoError(t, policy.Validate(newToken()), `token should satisfy the policy`) {
+			return
+		}
+
+		for _, claim := range []string{jwt.IssuerKey, jwt.AudienceKey, jwt.SubjectKey} {
+			tok := newToken()
+			tok.Remove(claim)
+			if !assert.Error(t, policy.Validate(tok), `token without %q should not satisfy the policy`, claim) {
+				return
+			}
+		}
+
+		tok := newToken()
+		tok.Set(jwt.IssuerKey, "https://evil.example.com")
+		if !assert.Error(t, policy.Validate(tok), `token from unknown issuer should not satisfy the policy`) {
+			return
+		}
+
+		tok = newToken()
+		tok.Set(jwt.ExpirationKey, time.Now().Add(-time.Minute))
+		if !assert.Error(t, policy.Validate(tok), `token expired beyond the skew should not satisfy the policy`) {
+			return
+		}
+	})
+	t.Run("Parse", func(t *testing.T) {
+		t.Parallel()
+		key, err := jwxtest.GenerateEcdsaKey()
+		if !assert.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`) {
+			return
+		}
+
+		signed, err := jwt.Sign(newToken(), jwa.ES256, key)
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return
+		}
+		options := append(policy.ParseOptions(), jwt.WithVerify(jwa.ES256, &key.PublicKey))
+		if _, err := jwt.Parse(signed, options...); !assert.NoError(t, err, `jwt.Parse should succeed`) {
+			return
+		}
+
+		hmacKey := []byte("abracadabra-abracadabra-abracadabra")
+		signed, err = jwt.Sign(newToken(), jwa.HS256, hmacKey)
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return
+		}
+		options = append(policy.ParseOptions(), jwt.WithVerify(jwa.HS256, hmacKey))
+		if _, err := jwt.Parse(signed, options...); !assert.Error(t, err, `jwt.Parse should fail for algorithms not allowed by the policy`) {
+			return
+		}
+
+		// Every signature of a message in JSON serialization format must
+		// use an acceptable algorithm
+		tok := newToken()
+		esSigned, err := jwt.Sign(tok, jwa.ES256, key)
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return
+		}
+		hsSigned, err := jwt.Sign(tok, jwa.HS256, hmacKey)
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return
+		}
+		esParts := strings.Split(string(esSigned), ".")
+		hsParts := strings.Split(string(hsSigned), ".")
+		if !assert.Equal(t, esParts[1], hsParts[1], `payloads should match`) {
+			return
+		}
+		multi := fmt.Sprintf(`{"payload":%q,"signatures":[{"protected":%q,"signature":%q},{"protected":%q,"signature":%q}]}`, esParts[1], esParts[0], esParts[2], hsParts[0], hsParts[2])
+		options = append(policy.ParseOptions(), jwt.WithVerify(jwa.ES256, &key.PublicKey))
+		if _, err := jwt.Parse([]byte(multi), options...); !assert.Error(t, err, `jwt.Parse should fail if any signature uses an algorithm not allowed by the policy`) {
+			return
+		}
+	})
+	t.Run("Invalid documents", func(t *testing.T) {
+		t.Parallel()
+		for _, doc := range []string{
+			`{"name": "no-schema"}`,
+			`{"schema": 2, "name": "future-schema"}`,
+			`{"schema": 1}`,
+			`{"schema": 1, "name": "unknown-field", "max_lifetime": "1h"}`,
+			`{"schema": 1, "name": "bad-skew", "acceptable_skew": "soon"}`,
+			`{"schema": 1, "name": "bad-alg", "algorithms": ["XS256"]}`,
+			`{"schema": 1, "name": "none-alg", "algorithms": ["none"]}`,
+		} {
+			_, err := jwt.CompilePolicy([]byte(doc))
+			if !assert.Error(t, err, `jwt.CompilePolicy(%s) should fail`, doc) {
+				return
+			}
+		}
+	})
+}
+
+func TestTruncatedTimeComparison(t *testing.T) {
+	t.Parallel()
+
+	base := time.Unix(1600000000, 0)
+	clock := func(d time.Duration) jwt.ValidateOption {
+		return jwt.WithClock(jwt.ClockFunc(func() time.Time { return base.Add(d) }))
+	}
+
+	testcases := []struct {
+		Name    string
+		Claim   string
+		Value   time.Time
+		Options []jwt.ValidateOption
+	}{
+		{
+			Name:    "exp within the same second",
+			Claim:   jwt.ExpirationKey,
+			Value:   base.Add(500 * time.Millisecond),
+			Options: []jwt.ValidateOption{clock(300 * time.Millisecond)},
+		},
+		{
+			Name:    "nbf within the same second",
+			Claim:   jwt.NotBeforeKey,
+			Value:   base.Add(500 * time.Millisecond),
+			Options: []jwt.ValidateOption{clock(700 * time.Millisecond)},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			tok := jwt.New()
+			if !assert.NoError(t, tok.Set(tc.Claim, tc.Value), `tok.Set should succeed`) {
+				return
+			}
+
+			if !assert.Error(t, jwt.Validate(tok, tc.Options...), `jwt.Validate should fail by default`) {
+				return
+			}
+			options := append([]jwt.ValidateOption{jwt.WithTruncatedTimeComparison(false)}, tc.Options...)
+			if !assert.NoError(t, jwt.Validate(tok, options...), `jwt.Validate should succeed with full precision`) {
+				return
+			}
+		})
+	}
+
+	t.Run("WithClaimValue", func(t *testing.T) {
+		t.Parallel()
+		tok := jwt.New()
+		if !assert.NoError(t, tok.Set(jwt.IssuedAtKey, base.Add(500*time.Millisecond)), `tok.Set should succeed`) {
+			return
+		}
+
+		options := []jwt.ValidateOption{clock(time.Second), jwt.WithClaimValue(jwt.IssuedAtKey, base)}
+		if !assert.NoError(t, jwt.Validate(tok, options...), `jwt.Validate should succeed by default`) {
+			return
+		}
+		options = append(options, jwt.WithTruncatedTimeComparison(false))
+		if !assert.Error(t, jwt.Validate(tok, options...), `jwt.Validate should fail with full precision`) {
+			return
+		}
+	})
+}
+
+func TestValidationErrors(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	tok := jwt.New()
+	_ = tok.Set(jwt.IssuerKey, "github.com/lestrrat-go/jwx")
+	_ = tok.Set(jwt.SubjectKey, "user-1234")
+	_ = tok.Set(jwt.AudienceKey, []string{"api"})
+	_ = tok.Set(jwt.JwtIDKey, "abc")
+	_ = tok.Set("role", "user")
+
+	expired := jwt.New()
+	_ = expired.Set(jwt.ExpirationKey, now.Add(-time.Hour))
+	notYet := jwt.New()
+	_ = notYet.Set(jwt.NotBeforeKey, now.Add(time.Hour))
+	future := jwt.New()
+	_ = future.Set(jwt.IssuedAtKey, now.Add(time.Hour))
+	malformed := jwt.New()
+	_ = malformed.Set(jwt.IssuerKey, "not a uri:%%")
+
+	testcases := []struct {
+		Name     string
+		Token    jwt.Token
+		Options  []jwt.ValidateOption
+		Expected error
+		Claim    string
+	}{
+		{Name: "exp", Token: expired, Expected: jwt.ErrTokenExpired, Claim: jwt.ExpirationKey},
+		{Name: "nbf", Token: notYet, Expected: jwt.ErrTokenNotYetValid, Claim: jwt.NotBeforeKey},
+		{Name: "iat", Token: future, Expected: jwt.ErrInvalidIssuedAt, Claim: jwt.IssuedAtKey},
+		{Name: "iss", Token: tok, Options: []jwt.ValidateOption{jwt.WithIssuer("other")}, Expected: jwt.ErrInvalidIssuer, Claim: jwt.IssuerKey},
+		{Name: "sub", Token: tok, Options: []jwt.ValidateOption{jwt.WithSubject("other")}, Expected: jwt.ErrInvalidSubject, Claim: jwt.SubjectKey},
+		{Name: "aud", Token: tok, Options: []jwt.ValidateOption{jwt.WithAudience("other")}, Expected: jwt.ErrInvalidAudience, Claim: jwt.AudienceKey},
+		{Name: "jti", Token: tok, Options: []jwt.ValidateOption{jwt.WithJwtID("other")}, Expected: jwt.ErrInvalidJwtID, Claim: jwt.JwtIDKey},
+		{Name: "Claim value", Token: tok, Options: []jwt.ValidateOption{jwt.WithClaimValue("role", "admin")}, Expected: jwt.ErrInvalidClaimValue, Claim: "role"},
+		{Name: "Prohibited claim", Token: tok, Options: []jwt.ValidateOption{jwt.WithProhibitedClaims("role")}, Expected: jwt.ErrProhibitedClaim, Claim: "role"},
+		{Name: "Prohibited value", Token: tok, Options: []jwt.ValidateOption{jwt.WithProhibitedClaimValue("role", "user")}, Expected: jwt.ErrProhibitedClaim, Claim: "role"},
+		{Name: "Strict claims", Token: malformed, Options: []jwt.ValidateOption{jwt.WithStrictClaims(true)}, Expected: jwt.ErrInvalidClaimFormat, Claim: jwt.IssuerKey},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			err := jwt.Validate(tc.Token, tc.Options...)
+			if !assert.True(t, errors.Is(err, tc.Expected), `errors.Is should match %q (got %v)`, tc.Expected, err) {
+				return
+			}
+
+			var verr *jwt.ValidationError
+			if !assert.True(t, errors.As(err, &verr), `errors.As should succeed`) {
+				return
+			}
+			if !assert.Equal(t, tc.Claim, verr.Claim, `claim should match`) {
+				return
+			}
+		})
+	}
+
+	t.Run("Parse", func(t *testing.T) {
+		t.Parallel()
+		signed, err := jwt.Sign(expired, jwa.HS256, []byte("abracadabra"))
+		if !assert.NoError(t, err, `jwt.Sign should succeed`) {
+			return
+		}
+		_, err = jwt.Parse(signed, jwt.WithVerify(jwa.HS256, []byte("abracadabra")), jwt.WithValidate(true))
+		if !assert.True(t, errors.Is(err, jwt.ErrTokenExpired), `errors.Is should match (got %v)`, err) {
+			return
+		}
+		if !assert.False(t, errors.Is(err, jwt.ErrTokenNotYetValid), `errors.Is should not match other categories`) {
+			return
+		}
+	})
+}
+
+func TestStepUpAuthentication(t *testing.T) {
+	t.Parallel()
+
+	order := []string{"bronze", "silver", "gold"}
+
+	silver := jwt.New()
+	silver.Set(jwt.AuthContextClassKey, "silver")
+	silver.Set(jwt.AuthMethodsKey, []interface{}{"pwd", "otp", "mfa"})
+
+	unknown := jwt.New()
+	unknown.Set(jwt.AuthContextClassKey, "platinum")
+
+	testcases := []struct {
+		Name   string
+		Token  jwt.Token
+		Option jwt.ValidateOption
+		Error  error
+	}{
+		{Name: "acr equal to minimum", Token: silver, Option: jwt.WithMinimumACR(order, "silver")},
+		{Name: "acr stronger than minimum", Token: silver, Option: jwt.WithMinimumACR(order, "bronze")},
+		{Name: "acr weaker than minimum", Token: silver, Option: jwt.WithMinimumACR(order, "gold"), Error: jwt.ErrInsufficientAuthentication},
+		{Name: "acr not in order", Token: unknown, Option: jwt.WithMinimumACR(order, "bronze"), Error: jwt.ErrInsufficientAuthentication},
+		{Name: "acr missing", Token: jwt.New(), Option: jwt.WithMinimumACR(order, "bronze"), Error: jwt.ErrInsufficientAuthentication},
+		{Name: "amr contains all methods", Token: silver, Option: jwt.WithRequiredAMR("mfa", "otp")},
+		{Name: "amr lacks a method", Token: silver, Option: jwt.WithRequiredAMR("mfa", "hwk"), Error: jwt.ErrInsufficientAuthentication},
+		{Name: "amr missing", Token: unknown, Option: jwt.WithRequiredAMR("mfa"), Error: jwt.ErrInsufficientAuthentication},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			err := jwt.Validate(tc.Token, tc.Option)
+			if tc.Error == nil {
+				if !assert.NoError(t, err, `jwt.Validate should succeed`) {
+					return
+				}
+				return
+			}
+			if !assert.True(t, errors.Is(err, tc.Error), `error should be %s: %v`, tc.Error, err) {
+				return
+			}
+		})
+	}
+
+	t.Run("Arguments are copied", func(t *testing.T) {
+		t.Parallel()
+		order := []string{"bronze", "silver", "gold"}
+		methods := []string{"mfa"}
+		options := []jwt.ValidateOption{jwt.WithMinimumACR(order, "silver"), jwt.WithRequiredAMR(methods...)}
+		order[0], order[2] = order[2], order[0]
+		methods[0] = "hwk"
+		if !assert.NoError(t, jwt.Validate(silver, options...), `jwt.Validate should succeed`) {
+			return
+		}
+	})
+}
+
+func TestAudienceAll(t *testing.T) {
+	t.Parallel()
+
+	tok := jwt.New()
+	tok.Set(jwt.AudienceKey, []string{"https://rp.example.com", "https://federation.example.com", "other"})
+
+	testcases := []struct {
+		Name    string
+		Options []jwt.ValidateOption
+		Error   bool
+	}{
+		{Name: "all present", Options: []jwt.ValidateOption{jwt.WithAudienceAll([]string{"https://rp.example.com", "https://federation.example.com"})}},
+		{Name: "one missing", Options: []jwt.ValidateOption{jwt.WithAudienceAll([]string{"https://rp.example.com", "https://missing.example.com"})}, Error: true},
+		{Name: "multiple options", Options: []jwt.ValidateOption{jwt.WithAudienceAll([]string{"other"}), jwt.WithAudienceAll([]string{"missing"})}, Error: true},
+		{Name: "combined with WithAudience", Options: []jwt.ValidateOption{jwt.WithAudience("other"), jwt.WithAudienceAll([]string{"https://rp.example.com"})}},
+		{Name: "empty list", Options: []jwt.ValidateOption{jwt.WithAudienceAll(nil)}},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			err := jwt.Validate(tok, tc.Options...)
+			if !tc.Error {
+				assert.NoError(t, err, `jwt.Validate should succeed`)
+				return
+			}
+			if !assert.True(t, errors.Is(err, jwt.ErrInvalidAudience), `error should be ErrInvalidAudience: %v`, err) {
+				return
+			}
+		})
+	}
+
+	_, err := jwt.Parse([]byte(`{"aud":"https://rp.example.com"}`), jwt.WithValidate(true), jwt.WithAudienceAll([]string{"https://rp.example.com", "other"}))
+	if !assert.True(t, errors.Is(err, jwt.ErrInvalidAudience), `jwt.Parse should fail with ErrInvalidAudience`) {
+		return
+	}
+}
+
+func TestJtiStore(t *testing.T) {
+	t.Parallel()
+
+	newToken := func(jti string, exp time.Time) jwt.Token {
+		tok := jwt.New()
+		if jti != "" {
+			tok.Set(jwt.JwtIDKey, jti)
+		}
+		if !exp.IsZero() {
+			tok.Set(jwt.ExpirationKey, exp)
+		}
+		return tok
+	}
+
+	t.Run("Replay", func(t *testing.T) {
+		t.Parallel()
+		store := jwt.NewMemoryJtiStore(0, 0)
+		tok := newToken("token-1", time.Now().Add(time.Hour))
+
+		if !assert.NoError(t, jwt.Validate(tok, jwt.WithJtiStore(store)), `first use should succeed`) {
+			return
+		}
+		err := jwt.Validate(tok, jwt.WithJtiStore(store))
+		if !assert.True(t, errors.Is(err, jwt.ErrTokenReplayed), `second use should fail with ErrTokenReplayed: %v`, err) {
+			return
+		}
+		if !assert.NoError(t, jwt.Validate(newToken("token-2", time.Now().Add(time.Hour)), jwt.WithJtiStore(store)), `other token should succeed`) {
+			return
+		}
+	})
+	t.Run("Replay within the skew", func(t *testing.T) {
+		t.Parallel()
+		store := jwt.NewMemoryJtiStore(0, 0)
+		tok := newToken("token-1", time.Now().Add(-2*time.Second))
+
+		if !assert.NoError(t, jwt.Validate(tok, jwt.WithJtiStore(store), jwt.WithAcceptableSkew(time.Minute)), `first use should succeed`) {
+			return
+		}
+		err := jwt.Validate(tok, jwt.WithJtiStore(store), jwt.WithAcceptableSkew(time.Minute))
+		if !assert.True(t, errors.Is(err, jwt.ErrTokenReplayed), `second use should fail with ErrTokenReplayed: %v`, err) {
+			return
+		}
+	})
+	t.Run("Replay with a clock", func(t *testing.T) {
+		t.Parallel()
+		// The token has expired in terms of the wall clock, but not in
+		// terms of the clock used for validation
+		now := time.Now().Add(-time.Hour)
+		clock := jwt.ClockFunc(func() time.Time { return now })
+		store := jwt.NewMemoryJtiStore(0, 0)
+		tok := newToken("token-1", now.Add(time.Minute))
+
+		if !assert.NoError(t, jwt.Validate(tok, jwt.WithJtiStore(store), jwt.WithClock(clock)), `first use should succeed`) {
+			return
+		}
+		err := jwt.Validate(tok, jwt.WithJtiStore(store), jwt.WithClock(clock))
+		if !assert.True(t, errors.Is(err, jwt.ErrTokenReplayed), `second use should fail with ErrTokenReplayed: %v`, err) {
+			return
+		}
+	})
+	t.Run("Missing jti", func(t *testing.T) {
+		t.Parallel()
+		err := jwt.Validate(newToken("", time.Time{}), jwt.WithJtiStore(jwt.NewMemoryJtiStore(0, 0)))
+		if !assert.True(t, errors.Is(err, jwt.ErrMissingClaim), `error should be ErrMissingClaim: %v`, err) {
+			return
+		}
+	})
+	t.Run("Invalid tokens are not recorded", func(t *testing.T) {
+		t.Parallel()
+		store := jwt.NewMemoryJtiStore(0, 0)
+		tok := newToken("token-1", time.Now().Add(-time.Hour))
+		err := jwt.Validate(tok, jwt.WithJtiStore(store), jwt.WithMultipleErrors(true))
+		if !assert.True(t, errors.Is(err, jwt.ErrTokenExpired), `error should be ErrTokenExpired: %v`, err) {
+			return
+		}
+		if !assert.Equal(t, 0, store.Len(), `store should be empty`) {
+			return
+		}
+	})
+	t.Run("Store errors", func(t *testing.T) {
+		t.Parallel()
+		failing := jtiStoreFunc(func(context.Context, string, time.Time) (bool, error) {
+			return false, errors.New(`connection refused`)
+		})
+		err := jwt.Validate(newToken("token-1", time.Time{}), jwt.WithJtiStore(failing))
+		if !assert.Error(t, err, `jwt.Validate should fail`) {
+			return
+		}
+	})
+	t.Run("Context", func(t *testing.T) {
+		t.Parallel()
+		type ctxKey struct{}
+		var got interface{}
+		store := jtiStoreFunc(func(ctx context.Context, _ string, _ time.Time) (bool, error) {
+			got = ctx.Value(ctxKey{})
+			return false, nil
+		})
+		ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+		if !assert.NoError(t, jwt.Validate(newToken("token-1", time.Time{}), jwt.WithJtiStore(store), jwt.WithContext(ctx)), `jwt.Validate should succeed`) {
+			return
+		}
+		if !assert.Equal(t, "value", got, `context should be passed to the store`) {
+			return
+		}
+	})
+	t.Run("Expiration and eviction", func(t *testing.T) {
+		t.Parallel()
+		store := jwt.NewMemoryJtiStore(2, 0)
+		ctx := context.Background()
+
+		seen, _ := store.Seen(ctx, "expired", time.Now().Add(-time.Second))
+		if !assert.False(t, seen, `expired should not be seen`) {
+			return
+		}
+		seen, _ = store.Seen(ctx, "expired", time.Now().Add(time.Hour))
+		if !assert.False(t, seen, `expired records should not count`) {
+			return
+		}
+
+		_, _ = store.Seen(ctx, "a", time.Now().Add(time.Hour))
+		_, _ = store.Seen(ctx, "b", time.Now().Add(time.Hour))
+		if !assert.Equal(t, 2, store.Len(), `store should hold at most 2 records`) {
+			return
+		}
+		seen, _ = store.Seen(ctx, "b", time.Now().Add(time.Hour))
+		if !assert.True(t, seen, `b should be seen`) {
+			return
+		}
+	})
+}
+
+type jtiStoreFunc func(context.Context, string, time.Time) (bool, error)
+
+func (f jtiStoreFunc) Seen(ctx context.Context, jti string, exp time.Time) (bool, error) {
+	return f(ctx, jti, exp)
+}
+
+func TestValidateContext(t *testing.T) {
+	t.Parallel()
+
+	tok := jwt.New()
+	_ = tok.Set(jwt.IssuerKey, `https://issuer.example.com`)
+
+	t.Run("Validator without context", func(t *testing.T) {
+		t.Parallel()
+		var called bool
+		v := jwt.ValidatorFunc(func(jwt.Token) error {
+			called = true
+			return nil
+		})
+		if !assert.NoError(t, jwt.ValidateContext(context.Background(), tok, jwt.WithValidator(v)), `jwt.ValidateContext should succeed`) {
+			return
+		}
+		if !assert.True(t, called, `validator should be called`) {
+			return
+		}
+	})
+	t.Run("ContextValidatorFunc via Validate", func(t *testing.T) {
+		t.Parallel()
+		v := jwt.ContextValidatorFunc(func(ctx context.Context, _ jwt.Token) error {
+			return ctx.Err()
+		})
+		if !assert.NoError(t, jwt.Validate(tok, jwt.WithValidator(v)), `jwt.Validate should succeed`) {
+			return
+		}
+	})
+	t.Run("Canceled context", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var seen bool
+		store := jtiStoreFunc(func(context.Context, string, time.Time) (bool, error) {
+			seen = true
+			return false, nil
+		})
+		err := jwt.ValidateContext(ctx, tok, jwt.WithJtiStore(store))
+		if !assert.True(t, errors.Is(err, context.Canceled), `jwt.ValidateContext should fail with context.Canceled`) {
+			return
+		}
+		if !assert.False(t, seen, `store should not be consulted`) {
+			return
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package jwt
+
+import (
+	"crypto/sha256"
+	"reflect"
+	"time"
+
+	"github.com/lestrrat-go/jwx/internal/ttlcache"
+	"github.com/lestrrat-go/jwx/jwa"
+)
+
+// ValidationCache remembers tokens whose signatures have been successfully
+// verified, so that parsing the same bearer token repeatedly (e.g. on
+// every request of a session) does not require repeating the signature
+// verification.
+//
+// The cache is opt-in: pass it to `jwt.Parse()` using the
+// `jwt.WithValidationCache()` option. It is safe for concurrent use.
+//
+// Only the outcome of the signature verification is cached. The claims
+// are still parsed and, if requested, validated every time, so that
+// time based claims such as "exp" are always evaluated against the
+// current time.
+//
+// An entry is only used when the token is verified using the same
+// algorithm and the same key as when it was stored. Keys are compared
+// by identity: when the key is selected from a key set (`jwt.WithKeySet()`
+// or `jwt.WithIssuerKeys()`), the entry is bound to the `jwk.Key` object
+// in the set, so replacing the key set (as `jwk.AutoRefresh` does) or
+// the key invalidates the entries. Raw keys that cannot be compared
+// (such as `[]byte` HMAC keys) are never cached; use a `jwk.Key` instead.
+//
+// Note that modifying a key in place does NOT invalidate the entries.
+// Call `Clear()` after doing so.
+type ValidationCache struct {
+	cache *ttlcache.Cache
+}
+
+type validationCacheEntry struct {
+	alg     jwa.SignatureAlgorithm
+	key     interface{}
+	payload []byte
+}
+
+// NewValidationCache creates a new ValidationCache.
+//
+// A token is remembered until it expires ("exp" claim), but for no longer
+// than `ttl`. Up to `maxEntries` tokens are remembered, and the least
+// recently used tokens are forgotten first. If `ttl` or `maxEntries` is
+// less than or equal to 0, the corresponding limit does not apply.
+func NewValidationCache(maxEntries int, ttl time.Duration) *ValidationCache {
+	return &ValidationCache{
+		cache: ttlcache.NewLRU(maxEntries, ttl),
+	}
+}
+
+// Len returns the number of entries in the cache, including those
+// that have expired but have not been evicted yet.
+func (c *ValidationCache) Len() int {
+	return c.cache.Len()
+}
+
+// Clear removes all entries from the cache.
+func (c *ValidationCache) Clear() {
+	c.cache.Clear()
+}
+
+// cacheableKey returns false if the identity of the key cannot be
+// determined by comparing it using ==
+func cacheableKey(key interface{}) bool {
+	if key == nil {
+		return false
+	}
+	return reflect.TypeOf(key).Comparable()
+}
+
+// lookup returns the verified payload of the token, if it has been
+// verified using the same algorithm and key
+func (c *ValidationCache) lookup(data []byte, alg jwa.SignatureAlgorithm, key interface{}) ([]byte, bool) {
+	if !cacheableKey(key) {
+		return nil, false
+	}
+
+	v, ok := c.cache.Get(sha256.Sum256(data))
+	if !ok {
+		return nil, false
+	}
+
+	entry := v.(*validationCacheEntry)
+	if entry.alg != alg || entry.key != key {
+		return nil, false
+	}
+	return entry.payload, true
+}
+
+// store records that the token has been verified using the given
+// algorithm and key. `exp` is the expiration time of the token, which
+// may be zero.
+func (c *ValidationCache) store(data []byte, alg jwa.SignatureAlgorithm, key interface{}, payload []byte, exp time.Time) {
+	if !cacheableKey(key) {
+		return
+	}
+
+	entry := &validationCacheEntry{
+		alg:     alg,
+		key:     key,
+		payload: make([]byte, len(payload)),
+	}
+	copy(entry.payload, payload)
+	c.cache.Set(sha256.Sum256(data), entry, exp)
+}
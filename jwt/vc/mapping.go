@@ -0,0 +1,143 @@
+package vc
+
+import (
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/pkg/errors"
+)
+
+func copyObject(src map[string]interface{}) map[string]interface{} {
+	dst := make(map[string]interface{}, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+func stringProperty(obj map[string]interface{}, name string) string {
+	v, _ := obj[name].(string)
+	return v
+}
+
+func stringsProperty(obj map[string]interface{}, name string) []string {
+	switch v := obj[name].(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		var list []string
+		for _, elem := range v {
+			if s, ok := elem.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func timeProperty(obj map[string]interface{}, name string) time.Time {
+	switch v := obj[name].(type) {
+	case time.Time:
+		return v
+	case string:
+		if tm, err := time.Parse(time.RFC3339, v); err == nil {
+			return tm
+		}
+	}
+	return time.Time{}
+}
+
+// moveString moves the string property `name` of `obj` to the claim `claim`
+func moveString(t jwt.Token, obj map[string]interface{}, name, claim string) error {
+	v, ok := obj[name]
+	if !ok {
+		return nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return errors.Errorf(`invalid type for %s: %T`, name, v)
+	}
+	if err := t.Set(claim, s); err != nil {
+		return errors.Wrapf(err, `failed to set %s`, claim)
+	}
+	delete(obj, name)
+	return nil
+}
+
+// moveTime moves the date property `name` of `obj` to the claim `claim`.
+// Dates are encoded as RFC 3339 strings in credentials, and as
+// NumericDates in JWTs.
+func moveTime(t jwt.Token, obj map[string]interface{}, name, claim string) error {
+	v, ok := obj[name]
+	if !ok {
+		return nil
+	}
+	var tm time.Time
+	switch v := v.(type) {
+	case time.Time:
+		tm = v
+	case string:
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return errors.Wrapf(err, `invalid value for %s`, name)
+		}
+		tm = parsed
+	default:
+		return errors.Errorf(`invalid type for %s: %T`, name, v)
+	}
+	if err := t.Set(claim, tm); err != nil {
+		return errors.Wrapf(err, `failed to set %s`, claim)
+	}
+	delete(obj, name)
+	return nil
+}
+
+// restoreString sets the property `name` of `obj` to `value`, the value
+// of the claim `claim`, unless `value` is empty. If the property already
+// exists, it must have the same value.
+func restoreString(obj map[string]interface{}, name, claim, value string) error {
+	if value == "" {
+		return nil
+	}
+	if v, ok := obj[name]; ok {
+		if s, _ := v.(string); s != value {
+			return errors.Errorf(`%s %q does not match %s %v`, claim, value, name, v)
+		}
+		return nil
+	}
+	obj[name] = value
+	return nil
+}
+
+// restoreTime sets the date property `name` of `obj` to `value`, the
+// value of the claim `claim`, unless `value` is the zero time. If the
+// property already exists, it must represent the same second.
+func restoreTime(obj map[string]interface{}, name, claim string, value time.Time) error {
+	if value.IsZero() {
+		return nil
+	}
+	if _, ok := obj[name]; ok {
+		if existing := timeProperty(obj, name); !existing.Truncate(time.Second).Equal(value.Truncate(time.Second)) {
+			return errors.Errorf(`%s does not match %s`, claim, name)
+		}
+		return nil
+	}
+	obj[name] = value.UTC().Format(time.RFC3339)
+	return nil
+}
+
+// objectClaim returns a copy of the claim `name`, which must be a JSON object
+func objectClaim(t jwt.Token, name string) (map[string]interface{}, error) {
+	v, ok := t.Get(name)
+	if !ok {
+		return nil, errors.Errorf(`required claim %s is missing`, name)
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf(`invalid type for %s: %T`, name, v)
+	}
+	return copyObject(obj), nil
+}
@@ -0,0 +1,311 @@
+// Package vc implements the JWT encoding of W3C Verifiable Credentials
+// and Verifiable Presentations, as described in
+// https://www.w3.org/TR/vc-data-model/#json-web-token
+//
+// Credentials and presentations are represented as JSON objects. When
+// encoding them as JWTs, their properties are mapped to the registered
+// claims as follows, and the rest of the object is stored in the "vc"
+// (or "vp") claim:
+//
+//   - "issuer" (or "holder" for presentations) is mapped to "iss"
+//   - "issuanceDate" is mapped to "nbf"
+//   - "expirationDate" is mapped to "exp"
+//   - "id" is mapped to "jti"
+//   - "credentialSubject.id" is mapped to "sub"
+//
+// When decoding, the properties are restored from the claims.
+package vc
+
+import (
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/pkg/errors"
+)
+
+// Claims and properties used by the JWT encoding
+const (
+	CredentialKey   = "vc"
+	PresentationKey = "vp"
+	NonceKey        = "nonce"
+
+	ContextProperty           = "@context"
+	CredentialSubjectProperty = "credentialSubject"
+	ExpirationDateProperty    = "expirationDate"
+	HolderProperty            = "holder"
+	IDProperty                = "id"
+	IssuanceDateProperty      = "issuanceDate"
+	IssuerProperty            = "issuer"
+	TypeProperty              = "type"
+)
+
+// Credential is a verifiable credential, represented as a JSON object
+type Credential map[string]interface{}
+
+// Presentation is a verifiable presentation, represented as a JSON object.
+// The credentials in its "verifiableCredential" property are not
+// processed, and JWT encoded credentials must be verified separately
+// using `vc.VerifyCredential()`.
+type Presentation map[string]interface{}
+
+// ID returns the "id" property of the credential
+func (c Credential) ID() string {
+	return stringProperty(c, IDProperty)
+}
+
+// Types returns the "type" property of the credential
+func (c Credential) Types() []string {
+	return stringsProperty(c, TypeProperty)
+}
+
+// Issuer returns the identifier of the issuer of the credential. The
+// "issuer" property may be either a string, or an object with an "id"
+// property.
+func (c Credential) Issuer() string {
+	switch v := c[IssuerProperty].(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		return stringProperty(v, IDProperty)
+	}
+	return ""
+}
+
+// Subject returns the identifier of the subject of the credential, if
+// the credential has a single subject
+func (c Credential) Subject() string {
+	if v, ok := c[CredentialSubjectProperty].(map[string]interface{}); ok {
+		return stringProperty(v, IDProperty)
+	}
+	return ""
+}
+
+// IssuanceDate returns the "issuanceDate" property of the credential,
+// or the zero time if it is missing or invalid
+func (c Credential) IssuanceDate() time.Time {
+	return timeProperty(c, IssuanceDateProperty)
+}
+
+// ExpirationDate returns the "expirationDate" property of the credential,
+// or the zero time if it is missing or invalid
+func (c Credential) ExpirationDate() time.Time {
+	return timeProperty(c, ExpirationDateProperty)
+}
+
+// ID returns the "id" property of the presentation
+func (p Presentation) ID() string {
+	return stringProperty(p, IDProperty)
+}
+
+// Types returns the "type" property of the presentation
+func (p Presentation) Types() []string {
+	return stringsProperty(p, TypeProperty)
+}
+
+// Holder returns the "holder" property of the presentation
+func (p Presentation) Holder() string {
+	return stringProperty(p, HolderProperty)
+}
+
+// NewCredentialToken encodes the credential as a JWT
+func NewCredentialToken(c Credential) (jwt.Token, error) {
+	vc := copyObject(c)
+	t := jwt.New()
+
+	// The issuer is only removed if it is a plain identifier, as an
+	// object may carry other properties (e.g. "name")
+	if iss := c.Issuer(); iss != "" {
+		if err := t.Set(jwt.IssuerKey, iss); err != nil {
+			return nil, errors.Wrapf(err, `failed to set %s`, jwt.IssuerKey)
+		}
+		if _, ok := vc[IssuerProperty].(string); ok {
+			delete(vc, IssuerProperty)
+		}
+	}
+
+	if err := moveTime(t, vc, IssuanceDateProperty, jwt.NotBeforeKey); err != nil {
+		return nil, err
+	}
+	if err := moveTime(t, vc, ExpirationDateProperty, jwt.ExpirationKey); err != nil {
+		return nil, err
+	}
+	if err := moveString(t, vc, IDProperty, jwt.JwtIDKey); err != nil {
+		return nil, err
+	}
+
+	if subject, ok := vc[CredentialSubjectProperty].(map[string]interface{}); ok {
+		subject = copyObject(subject)
+		if err := moveString(t, subject, IDProperty, jwt.SubjectKey); err != nil {
+			return nil, err
+		}
+		vc[CredentialSubjectProperty] = subject
+	}
+
+	if err := t.Set(CredentialKey, map[string]interface{}(vc)); err != nil {
+		return nil, errors.Wrapf(err, `failed to set %s`, CredentialKey)
+	}
+	return t, nil
+}
+
+// ParseCredentialToken decodes the credential encoded in the JWT. An
+// error is returned if the token does not have a "vc" claim, or if the
+// claims conflict with the properties in the "vc" claim.
+func ParseCredentialToken(t jwt.Token) (Credential, error) {
+	vc, err := objectClaim(t, CredentialKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if iss := t.Issuer(); iss != "" {
+		switch v := vc[IssuerProperty].(type) {
+		case nil:
+			vc[IssuerProperty] = iss
+		case map[string]interface{}:
+			if id := stringProperty(v, IDProperty); id != "" && id != iss {
+				return nil, errors.Errorf(`%s %q does not match %s.id %q`, jwt.IssuerKey, iss, IssuerProperty, id)
+			}
+			v = copyObject(v)
+			v[IDProperty] = iss
+			vc[IssuerProperty] = v
+		default:
+			if err := restoreString(vc, IssuerProperty, jwt.IssuerKey, iss); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := restoreTime(vc, IssuanceDateProperty, jwt.NotBeforeKey, t.NotBefore()); err != nil {
+		return nil, err
+	}
+	if err := restoreTime(vc, ExpirationDateProperty, jwt.ExpirationKey, t.Expiration()); err != nil {
+		return nil, err
+	}
+	if err := restoreString(vc, IDProperty, jwt.JwtIDKey, t.JwtID()); err != nil {
+		return nil, err
+	}
+
+	if sub := t.Subject(); sub != "" {
+		subject, ok := vc[CredentialSubjectProperty].(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf(`%s requires a single %s`, jwt.SubjectKey, CredentialSubjectProperty)
+		}
+		subject = copyObject(subject)
+		if err := restoreString(subject, IDProperty, jwt.SubjectKey, sub); err != nil {
+			return nil, err
+		}
+		vc[CredentialSubjectProperty] = subject
+	}
+	return Credential(vc), nil
+}
+
+// NewPresentationToken encodes the presentation as a JWT. The audience
+// (the verifier) and the nonce that protects against replays should be
+// set on the returned token, using the "aud" and "nonce" claims.
+func NewPresentationToken(p Presentation) (jwt.Token, error) {
+	vp := copyObject(p)
+	t := jwt.New()
+
+	if err := moveString(t, vp, HolderProperty, jwt.IssuerKey); err != nil {
+		return nil, err
+	}
+	if err := moveString(t, vp, IDProperty, jwt.JwtIDKey); err != nil {
+		return nil, err
+	}
+
+	if err := t.Set(PresentationKey, map[string]interface{}(vp)); err != nil {
+		return nil, errors.Wrapf(err, `failed to set %s`, PresentationKey)
+	}
+	return t, nil
+}
+
+// ParsePresentationToken decodes the presentation encoded in the JWT.
+// An error is returned if the token does not have a "vp" claim, or if
+// the claims conflict with the properties in the "vp" claim.
+func ParsePresentationToken(t jwt.Token) (Presentation, error) {
+	vp, err := objectClaim(t, PresentationKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := restoreString(vp, HolderProperty, jwt.IssuerKey, t.Issuer()); err != nil {
+		return nil, err
+	}
+	if err := restoreString(vp, IDProperty, jwt.JwtIDKey, t.JwtID()); err != nil {
+		return nil, err
+	}
+	return Presentation(vp), nil
+}
+
+// SignCredential encodes the credential as a JWT, and signs it. The
+// options are passed to `jwt.Sign()` as is.
+func SignCredential(c Credential, alg jwa.SignatureAlgorithm, key interface{}, options ...jwt.Option) ([]byte, error) {
+	t, err := NewCredentialToken(c)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to encode credential`)
+	}
+	return jwt.Sign(t, alg, key, options...)
+}
+
+// VerifyCredential parses and validates a JWT encoded credential, and
+// decodes it. The options are passed to `jwt.Parse()`, and must include
+// the means to verify the signature, e.g. `jwt.WithVerify()`, otherwise
+// an error is returned.
+func VerifyCredential(data []byte, options ...jwt.ParseOption) (Credential, error) {
+	t, err := parseAndValidate(data, CredentialKey, options)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCredentialToken(t)
+}
+
+// SignPresentation encodes the presentation as a JWT intended for the
+// verifier `audience`, and signs it. `nonce` is set to the "nonce" claim
+// unless it is empty. The options are passed to `jwt.Sign()` as is.
+func SignPresentation(p Presentation, audience, nonce string, alg jwa.SignatureAlgorithm, key interface{}, options ...jwt.Option) ([]byte, error) {
+	t, err := NewPresentationToken(p)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to encode presentation`)
+	}
+	if err := t.Set(jwt.AudienceKey, audience); err != nil {
+		return nil, errors.Wrapf(err, `failed to set %s`, jwt.AudienceKey)
+	}
+	if nonce != "" {
+		if err := t.Set(NonceKey, nonce); err != nil {
+			return nil, errors.Wrapf(err, `failed to set %s`, NonceKey)
+		}
+	}
+	return jwt.Sign(t, alg, key, options...)
+}
+
+// VerifyPresentation parses and validates a JWT encoded presentation,
+// and decodes it. The options are passed to `jwt.Parse()`, and must
+// include the means to verify the signature, e.g. `jwt.WithVerify()`,
+// otherwise an error is returned.
+// Use `jwt.WithAudience()` and `jwt.WithClaimValue(vc.NonceKey, nonce)`
+// to check the audience and the nonce.
+func VerifyPresentation(data []byte, options ...jwt.ParseOption) (Presentation, error) {
+	t, err := parseAndValidate(data, PresentationKey, options)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePresentationToken(t)
+}
+
+func parseAndValidate(data []byte, claim string, options []jwt.ParseOption) (jwt.Token, error) {
+	// jwt.NewVerifier rejects the options unless they verify the
+	// signature, so that unsigned or forged tokens are never accepted
+	if _, err := jwt.NewVerifier(options...); err != nil {
+		return nil, errors.Wrap(err, `invalid options`)
+	}
+
+	options = append(append([]jwt.ParseOption(nil), options...),
+		jwt.WithValidate(true),
+		jwt.WithRequiredClaim(claim),
+	)
+	t, err := jwt.Parse(data, options...)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to parse token`)
+	}
+	return t, nil
+}
@@ -0,0 +1,230 @@
+package vc_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/internal/jwxtest"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/lestrrat-go/jwx/jwt/vc"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCredential() vc.Credential {
+	return vc.Credential{
+		vc.ContextProperty:        []interface{}{"https://www.w3.org/2018/credentials/v1"},
+		vc.IDProperty:             "http://example.edu/credentials/3732",
+		vc.TypeProperty:           []interface{}{"VerifiableCredential", "UniversityDegreeCredential"},
+		vc.IssuerProperty:         "https://example.edu/issuers/14",
+		vc.IssuanceDateProperty:   "2010-01-01T19:23:24Z",
+		vc.ExpirationDateProperty: "2030-01-01T19:23:24Z",
+		vc.CredentialSubjectProperty: map[string]interface{}{
+			"id":     "did:example:ebfeb1f712ebc6f1c276e12ec21",
+			"degree": map[string]interface{}{"type": "BachelorDegree"},
+		},
+	}
+}
+
+func TestCredential(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwxtest.GenerateRsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`) {
+		return
+	}
+
+	t.Run("Claim mapping", func(t *testing.T) {
+		t.Parallel()
+		c := newCredential()
+		tok, err := vc.NewCredentialToken(c)
+		if !assert.NoError(t, err, `vc.NewCredentialToken should succeed`) {
+			return
+		}
+		if !assert.Equal(t, "https://example.edu/issuers/14", tok.Issuer(), `iss should match`) {
+			return
+		}
+		if !assert.Equal(t, "did:example:ebfeb1f712ebc6f1c276e12ec21", tok.Subject(), `sub should match`) {
+			return
+		}
+		if !assert.Equal(t, "http://example.edu/credentials/3732", tok.JwtID(), `jti should match`) {
+			return
+		}
+		if !assert.Equal(t, time.Date(2010, 1, 1, 19, 23, 24, 0, time.UTC), tok.NotBefore(), `nbf should match`) {
+			return
+		}
+		if !assert.Equal(t, time.Date(2030, 1, 1, 19, 23, 24, 0, time.UTC), tok.Expiration(), `exp should match`) {
+			return
+		}
+
+		v, ok := tok.Get(vc.CredentialKey)
+		if !assert.True(t, ok, `vc claim should exist`) {
+			return
+		}
+		claim := v.(map[string]interface{})
+		for _, name := range []string{vc.IDProperty, vc.IssuerProperty, vc.IssuanceDateProperty, vc.ExpirationDateProperty} {
+			if !assert.NotContains(t, claim, name, `mapped properties should be removed`) {
+				return
+			}
+		}
+		if !assert.NotContains(t, claim[vc.CredentialSubjectProperty], "id", `credentialSubject.id should be removed`) {
+			return
+		}
+		if !assert.Contains(t, c, vc.IDProperty, `original credential should not be modified`) {
+			return
+		}
+
+		decoded, err := vc.ParseCredentialToken(tok)
+		if !assert.NoError(t, err, `vc.ParseCredentialToken should succeed`) {
+			return
+		}
+		if !assert.Equal(t, c, decoded, `decoded credential should match`) {
+			return
+		}
+	})
+	t.Run("Issuer object", func(t *testing.T) {
+		t.Parallel()
+		c := newCredential()
+		c[vc.IssuerProperty] = map[string]interface{}{"id": "https://example.edu/issuers/14", "name": "Example University"}
+		tok, err := vc.NewCredentialToken(c)
+		if !assert.NoError(t, err, `vc.NewCredentialToken should succeed`) {
+			return
+		}
+		if !assert.Equal(t, "https://example.edu/issuers/14", tok.Issuer(), `iss should match`) {
+			return
+		}
+		decoded, err := vc.ParseCredentialToken(tok)
+		if !assert.NoError(t, err, `vc.ParseCredentialToken should succeed`) {
+			return
+		}
+		if !assert.Equal(t, "https://example.edu/issuers/14", decoded.Issuer(), `issuer should match`) {
+			return
+		}
+	})
+	t.Run("Conflicting claims", func(t *testing.T) {
+		t.Parallel()
+		tok := jwt.New()
+		tok.Set(jwt.JwtIDKey, "urn:uuid:1")
+		tok.Set(vc.CredentialKey, map[string]interface{}{vc.IDProperty: "urn:uuid:2"})
+		_, err := vc.ParseCredentialToken(tok)
+		if !assert.Error(t, err, `vc.ParseCredentialToken should fail`) {
+			return
+		}
+	})
+	t.Run("Missing vc claim", func(t *testing.T) {
+		t.Parallel()
+		_, err := vc.ParseCredentialToken(jwt.New())
+		if !assert.Error(t, err, `vc.ParseCredentialToken should fail`) {
+			return
+		}
+	})
+	t.Run("Sign and verify", func(t *testing.T) {
+		t.Parallel()
+		signed, err := vc.SignCredential(newCredential(), jwa.RS256, key)
+		if !assert.NoError(t, err, `vc.SignCredential should succeed`) {
+			return
+		}
+		c, err := vc.VerifyCredential(signed, jwt.WithVerify(jwa.RS256, &key.PublicKey))
+		if !assert.NoError(t, err, `vc.VerifyCredential should succeed`) {
+			return
+		}
+		if !assert.Equal(t, "did:example:ebfeb1f712ebc6f1c276e12ec21", c.Subject(), `subject should match`) {
+			return
+		}
+		if !assert.Equal(t, []string{"VerifiableCredential", "UniversityDegreeCredential"}, c.Types(), `types should match`) {
+			return
+		}
+
+		other, err := jwxtest.GenerateRsaKey()
+		if !assert.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`) {
+			return
+		}
+		_, err = vc.VerifyCredential(signed, jwt.WithVerify(jwa.RS256, &other.PublicKey))
+		if !assert.Error(t, err, `vc.VerifyCredential with the wrong key should fail`) {
+			return
+		}
+		_, err = vc.VerifyCredential(signed)
+		if !assert.Error(t, err, `vc.VerifyCredential without verification should fail`) {
+			return
+		}
+		_, err = vc.VerifyPresentation(signed, jwt.WithAudience("https://verifier.example.com"))
+		if !assert.Error(t, err, `vc.VerifyPresentation without verification should fail`) {
+			return
+		}
+	})
+	t.Run("Expired credential", func(t *testing.T) {
+		t.Parallel()
+		c := newCredential()
+		c[vc.ExpirationDateProperty] = "2011-01-01T00:00:00Z"
+		signed, err := vc.SignCredential(c, jwa.RS256, key)
+		if !assert.NoError(t, err, `vc.SignCredential should succeed`) {
+			return
+		}
+		_, err = vc.VerifyCredential(signed, jwt.WithVerify(jwa.RS256, &key.PublicKey))
+		if !assert.Error(t, err, `vc.VerifyCredential should fail`) {
+			return
+		}
+	})
+}
+
+func TestPresentation(t *testing.T) {
+	t.Parallel()
+
+	key, err := jwxtest.GenerateRsaKey()
+	if !assert.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`) {
+		return
+	}
+
+	p := vc.Presentation{
+		vc.ContextProperty:     []interface{}{"https://www.w3.org/2018/credentials/v1"},
+		vc.IDProperty:          "urn:uuid:3978344f-8596-4c3a-a978-8fcaba3903c5",
+		vc.TypeProperty:        []interface{}{"VerifiablePresentation"},
+		vc.HolderProperty:      "did:example:ebfeb1f712ebc6f1c276e12ec21",
+		"verifiableCredential": []interface{}{"eyJhbGciOiJSUzI1NiJ9.e30.c2ln"},
+	}
+
+	signed, err := vc.SignPresentation(p, "did:example:verifier", "343s$FSFDa-", jwa.RS256, key)
+	if !assert.NoError(t, err, `vc.SignPresentation should succeed`) {
+		return
+	}
+
+	t.Run("Verify", func(t *testing.T) {
+		t.Parallel()
+		decoded, err := vc.VerifyPresentation(signed,
+			jwt.WithVerify(jwa.RS256, &key.PublicKey),
+			jwt.WithAudience("did:example:verifier"),
+			jwt.WithClaimValue(vc.NonceKey, "343s$FSFDa-"),
+		)
+		if !assert.NoError(t, err, `vc.VerifyPresentation should succeed`) {
+			return
+		}
+		if !assert.Equal(t, p, decoded, `decoded presentation should match`) {
+			return
+		}
+		if !assert.Equal(t, "did:example:ebfeb1f712ebc6f1c276e12ec21", decoded.Holder(), `holder should match`) {
+			return
+		}
+	})
+	t.Run("Wrong nonce", func(t *testing.T) {
+		t.Parallel()
+		_, err := vc.VerifyPresentation(signed,
+			jwt.WithVerify(jwa.RS256, &key.PublicKey),
+			jwt.WithAudience("did:example:verifier"),
+			jwt.WithClaimValue(vc.NonceKey, "other"),
+		)
+		if !assert.Error(t, err, `vc.VerifyPresentation should fail`) {
+			return
+		}
+	})
+	t.Run("Credential is not a presentation", func(t *testing.T) {
+		t.Parallel()
+		signed, err := vc.SignCredential(newCredential(), jwa.RS256, key)
+		if !assert.NoError(t, err, `vc.SignCredential should succeed`) {
+			return
+		}
+		_, err = vc.VerifyPresentation(signed, jwt.WithVerify(jwa.RS256, &key.PublicKey))
+		if !assert.Error(t, err, `vc.VerifyPresentation should fail`) {
+			return
+		}
+	})
+}
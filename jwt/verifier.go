@@ -0,0 +1,91 @@
+package jwt
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Verifier parses, verifies and validates tokens using a fixed set of
+// options. The options are checked when the Verifier is created, e.g. to
+// make sure that the signature of tokens is verified, and any profiles
+// (see `jwt.WithProfile()`) are expanded then:
+//
+//     verifier, err := jwt.NewVerifier(
+//       jwt.WithKeySet(keyset),
+//       jwt.WithIssuer(`https://issuer.example.com`),
+//       jwt.WithAcceptableSkew(time.Minute),
+//     )
+//     ...
+//     token, err := verifier.Verify(ctx, data, jwt.WithAudience(tenant.Audience))
+//
+// Options given to `Verify()` override those of the Verifier for that
+// call only, e.g. to check the audience of the tenant that the request
+// is addressed to. Each call processes the options of the Verifier along
+// with its overrides, as `jwt.Parse()` would.
+//
+// A Verifier is safe for concurrent use.
+type Verifier struct {
+	options []ParseOption
+	pool    sync.Pool
+}
+
+// NewVerifier creates a new Verifier. The options are the same as those
+// of `jwt.Parse()`, and must include a way to verify the signature of
+// tokens, such as `jwt.WithVerify()`, `jwt.WithKeySet()`,
+// `jwt.WithIssuerKeys()` or `jwt.WithVerifyAuto()`. Tokens are validated
+// unless `jwt.WithValidate(false)` is specified.
+func NewVerifier(options ...ParseOption) (*Verifier, error) {
+	var verify bool
+	for _, o := range options {
+		switch o.Ident() {
+		case identVerify{}, identKeySet{}, identIssuerKeys{}, identVerifyAuto{}:
+			verify = true
+		}
+	}
+	if !verify {
+		return nil, errors.New(`an option to verify the signature of tokens must be specified`)
+	}
+
+	options, err := expandProfiles(append([]ParseOption{WithValidate(true)}, options...))
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Verifier{options: options}
+	v.pool.New = func() interface{} {
+		// Room for the context, and a few overrides
+		buf := make([]ParseOption, 0, len(v.options)+4)
+		return &buf
+	}
+	return v, nil
+}
+
+// Verify parses, verifies and validates the token in `data`, using `ctx`
+// as `jwt.ParseContext()` does.
+//
+// `overrides` are processed after the options of the Verifier. Options
+// that take a single value, such as `jwt.WithAudience()`,
+// `jwt.WithIssuer()` or `jwt.WithClock()`, replace the value given to
+// the Verifier. Options that may be specified multiple times, such as
+// `jwt.WithRequiredClaim()` or `jwt.WithValidator()`, add to those of
+// the Verifier.
+func (v *Verifier) Verify(ctx context.Context, data []byte, overrides ...ParseOption) (Token, error) {
+	bufp := v.pool.Get().(*[]ParseOption)
+	options := append(*bufp, v.options...)
+	options = append(options, overrides...)
+	options = append(options, WithContext(ctx))
+
+	token, err := parseBytes(data, options...)
+
+	// Do not keep references to the overrides (e.g. keys) or to the
+	// context while the buffer is in the pool
+	for i := range options {
+		options[i] = nil
+	}
+	*bufp = options[:0]
+	v.pool.Put(bufp)
+
+	return token, err
+}